@@ -0,0 +1,232 @@
+// Package backup periodically uploads compressed backups of the contacts to
+// an S3 compatible storage and deletes the expired ones.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"io"
+	"phoneBook/definition"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	lastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "phonebook_backup_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful contacts backup.",
+	})
+	lastContacts = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "phonebook_backup_last_contacts",
+		Help: "Contacts in the last successful backup.",
+	})
+	lastDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "phonebook_backup_last_duration_seconds",
+		Help: "How long the last successful backup took.",
+	})
+	failures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_backup_failures_total",
+		Help: "Contacts backups that failed.",
+	})
+)
+
+// Tenants lists the tenants whose phone books are backed up, on top of the
+// phone book of no tenant.
+type Tenants interface {
+	ListTenants(ctx context.Context) ([]*definition.Tenant, string, error)
+}
+
+// keyTime is the timestamp format in the backup keys. Its fixed width makes
+// the keys sort by time.
+const keyTime = "20060102T150405Z"
+
+// Scheduler backs up the contacts of a phone book every interval.
+type Scheduler struct {
+	phoneBook definition.IPhoneBook
+	storage   Storage
+	tenants   Tenants
+	interval  time.Duration
+	timeout   time.Duration
+	format    string
+	prefix    string
+	keep      int
+	maxAge    time.Duration
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler uploading backups in format to storage
+// under prefix every interval, each bound by timeout. It keeps the keep most
+// recent backups, or all when 0, and deletes those older than maxAge, unless
+// 0. The most recent backup is never deleted. With tenants, the phone book of
+// every tenant is backed up too, under tenants/<id>/ in prefix.
+func NewScheduler(phoneBook definition.IPhoneBook, storage Storage, tenants Tenants, interval, timeout time.Duration, format, prefix string, keep int, maxAge time.Duration) *Scheduler {
+	return &Scheduler{
+		phoneBook: phoneBook,
+		storage:   storage,
+		tenants:   tenants,
+		interval:  interval,
+		timeout:   timeout,
+		format:    format,
+		prefix:    prefix,
+		keep:      keep,
+		maxAge:    maxAge,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start backs up in the background, the first time after an interval.
+func (s *Scheduler) Start() {
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.runOnce()
+			}
+		}
+	}()
+}
+
+// Stop cancels the backup in progress, if any, and waits for the scheduler
+// to exit.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.done.Wait()
+}
+
+func (s *Scheduler) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := s.Run(ctx); err != nil {
+		logrus.WithError(err).Error("contacts backup failed")
+	}
+}
+
+// Run uploads a backup of the phone book of no tenant now, then of every
+// tenant, and deletes the expired ones. A tenant failing to be backed up
+// doesn't stop the others.
+func (s *Scheduler) Run(ctx context.Context) error {
+	started := time.Now()
+	count, err := s.backup(ctx, s.prefix, started)
+	if err != nil {
+		failures.Inc()
+		return err
+	}
+	if s.tenants != nil {
+		tenants, _, err := s.tenants.ListTenants(ctx)
+		if err != nil {
+			failures.Inc()
+			return fmt.Errorf("failed to list the tenants to back up: %w", err)
+		}
+		var failed []string
+		for _, tenant := range tenants {
+			tenantCount, err := s.backup(definition.WithTenant(ctx, tenant.ID), s.tenantPrefix(tenant.ID), started)
+			if err != nil {
+				logrus.WithError(err).WithField("tenant", tenant.ID).Error("contacts backup of a tenant failed")
+				failed = append(failed, tenant.ID)
+				continue
+			}
+			count += tenantCount
+		}
+		if len(failed) > 0 {
+			failures.Inc()
+			return fmt.Errorf("failed to back up the tenants %s", strings.Join(failed, ", "))
+		}
+	}
+	lastSuccess.Set(float64(time.Now().Unix()))
+	lastContacts.Set(float64(count))
+	lastDuration.Set(time.Since(started).Seconds())
+	return nil
+}
+
+// tenantPrefix is the prefix of the backups of a tenant.
+func (s *Scheduler) tenantPrefix(tenant string) string {
+	return s.prefix + "tenants/" + tenant + "/"
+}
+
+// backup uploads a backup of the phone book of ctx under prefix and then
+// deletes the expired ones there.
+func (s *Scheduler) backup(ctx context.Context, prefix string, started time.Time) (int64, error) {
+	key := fmt.Sprintf("%scontacts-%s.%s.gz", prefix, started.UTC().Format(keyTime), s.format)
+	count, err := s.upload(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	logrus.Infof("backed up %d contacts to %s", count, key)
+	// a failure to clean up doesn't fail the backup, it's retried next time
+	if err := s.expire(ctx, prefix, started); err != nil {
+		logrus.WithError(err).Warn("failed to delete expired contacts backups")
+	}
+	return count, nil
+}
+
+// upload streams a gzipped backup to the storage as it's read.
+func (s *Scheduler) upload(ctx context.Context, key string) (int64, error) {
+	reader, writer := io.Pipe()
+	var count int64
+	go func() {
+		compressed := gzip.NewWriter(writer)
+		var err error
+		count, _, err = s.phoneBook.Backup(ctx, s.format, compressed)
+		if closeErr := compressed.Close(); err == nil {
+			err = closeErr
+		}
+		writer.CloseWithError(err)
+	}()
+	err := s.storage.Upload(ctx, key, reader, "application/gzip")
+	// unblocks the backup if the upload stopped reading
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// expire deletes the backups under prefix beyond the keep most recent ones and
+// those older than maxAge, sparing the most recent.
+func (s *Scheduler) expire(ctx context.Context, prefix string, now time.Time) error {
+	if s.keep == 0 && s.maxAge == 0 {
+		return nil
+	}
+	objects, err := s.storage.List(ctx, prefix+"contacts-")
+	if err != nil {
+		return err
+	}
+	// most recent first
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key > objects[j].Key })
+	for i, object := range objects {
+		if i == 0 || !strings.HasSuffix(object.Key, ".gz") {
+			continue
+		}
+		tooMany := s.keep > 0 && i >= s.keep
+		tooOld := s.maxAge > 0 && now.Sub(object.LastModified) > s.maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := s.storage.Remove(ctx, object.Key); err != nil {
+			return err
+		}
+		logrus.Infof("deleted expired contacts backup %s", object.Key)
+	}
+	return nil
+}
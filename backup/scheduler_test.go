@@ -0,0 +1,198 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"phoneBook/definition"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStorage keeps the uploads in a map.
+type memoryStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	times   map[string]time.Time
+	failing bool
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{objects: map[string][]byte{}, times: map[string]time.Time{}}
+}
+
+func (s *memoryStorage) Upload(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if s.failing {
+		return errors.New("storage is down")
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.put(key, content, time.Now())
+	return nil
+}
+
+func (s *memoryStorage) put(key string, content []byte, lastModified time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = content
+	s.times[key] = lastModified
+}
+
+func (s *memoryStorage) List(ctx context.Context, prefix string) ([]Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var objects []Object
+	for key := range s.objects {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			objects = append(objects, Object{Key: key, LastModified: s.times[key]})
+		}
+	}
+	return objects, nil
+}
+
+func (s *memoryStorage) Remove(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryStorage) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// backupPhoneBook backs up two contacts, or fails.
+type backupPhoneBook struct {
+	definition.IPhoneBook
+	err error
+}
+
+func (pb *backupPhoneBook) Backup(ctx context.Context, format string, w io.Writer) (int64, string, error) {
+	if pb.err != nil {
+		return 0, "InternalServerError", pb.err
+	}
+	if definition.TenantFromContext(ctx) == "failing" {
+		return 0, "InternalServerError", errors.New("tenant database is down")
+	}
+	_, err := io.WriteString(w, "{\"firstName\":\"Dani\"}\n{\"firstName\":\"Noa\"}\n")
+	return 2, "", err
+}
+
+type stubTenants []string
+
+func (tenants stubTenants) ListTenants(ctx context.Context) ([]*definition.Tenant, string, error) {
+	var list []*definition.Tenant
+	for _, id := range tenants {
+		list = append(list, &definition.Tenant{ID: id})
+	}
+	return list, "", nil
+}
+
+func gaugeValue(gauge interface{ Write(*dto.Metric) error }) float64 {
+	var metric dto.Metric
+	gauge.Write(&metric)
+	if metric.Gauge != nil {
+		return metric.Gauge.GetValue()
+	}
+	return metric.Counter.GetValue()
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should upload a gzipped backup", func(t *testing.T) {
+		storage := newMemoryStorage()
+		scheduler := NewScheduler(&backupPhoneBook{}, storage, nil, time.Hour, time.Minute, "json", "phonebook/", 7, 0)
+		assert.Nil(t, scheduler.Run(ctx))
+
+		keys := storage.keys()
+		assert.Len(t, keys, 1)
+		assert.Regexp(t, regexp.MustCompile(`^phonebook/contacts-\d{8}T\d{6}Z\.json\.gz$`), keys[0])
+		uncompressed, err := gzip.NewReader(bytes.NewReader(storage.objects[keys[0]]))
+		assert.Nil(t, err)
+		content, _ := io.ReadAll(uncompressed)
+		assert.Equal(t, "{\"firstName\":\"Dani\"}\n{\"firstName\":\"Noa\"}\n", string(content))
+		assert.Equal(t, float64(2), gaugeValue(lastContacts))
+		assert.InDelta(t, float64(time.Now().Unix()), gaugeValue(lastSuccess), 5)
+	})
+
+	t.Run("should back up every tenant apart", func(t *testing.T) {
+		storage := newMemoryStorage()
+		scheduler := NewScheduler(&backupPhoneBook{}, storage, stubTenants{"acme", "globex"}, time.Hour, time.Minute, "json", "phonebook/", 7, 0)
+		assert.Nil(t, scheduler.Run(ctx))
+
+		keys := storage.keys()
+		assert.Len(t, keys, 3)
+		assert.Regexp(t, regexp.MustCompile(`^phonebook/contacts-\d{8}T\d{6}Z\.json\.gz$`), keys[0])
+		assert.Regexp(t, regexp.MustCompile(`^phonebook/tenants/acme/contacts-\d{8}T\d{6}Z\.json\.gz$`), keys[1])
+		assert.Regexp(t, regexp.MustCompile(`^phonebook/tenants/globex/contacts-\d{8}T\d{6}Z\.json\.gz$`), keys[2])
+		assert.Equal(t, float64(6), gaugeValue(lastContacts))
+	})
+
+	t.Run("should back up the other tenants despite failures", func(t *testing.T) {
+		storage := newMemoryStorage()
+		scheduler := NewScheduler(&backupPhoneBook{}, storage, stubTenants{"acme", "failing", "globex"}, time.Hour, time.Minute, "json", "", 7, 0)
+		assert.ErrorContains(t, scheduler.Run(ctx), "failed to back up the tenants failing")
+		assert.Len(t, storage.keys(), 3)
+		assert.NotContains(t, strings.Join(storage.keys(), " "), "tenants/failing/")
+	})
+
+	t.Run("should count failures", func(t *testing.T) {
+		before := gaugeValue(failures)
+		storage := newMemoryStorage()
+		scheduler := NewScheduler(&backupPhoneBook{err: errors.New("mongo is down")}, storage, nil, time.Hour, time.Minute, "json", "", 7, 0)
+		assert.ErrorContains(t, scheduler.Run(ctx), "mongo is down")
+
+		storage.failing = true
+		scheduler = NewScheduler(&backupPhoneBook{}, storage, nil, time.Hour, time.Minute, "json", "", 7, 0)
+		assert.ErrorContains(t, scheduler.Run(ctx), "storage is down")
+		assert.Equal(t, before+2, gaugeValue(failures))
+		assert.Empty(t, storage.keys())
+	})
+
+	t.Run("should delete the backups beyond the retention", func(t *testing.T) {
+		storage := newMemoryStorage()
+		now := time.Now()
+		for days := 1; days <= 4; days++ {
+			day := now.Add(-time.Duration(days) * 24 * time.Hour)
+			storage.put("phonebook/contacts-"+day.UTC().Format(keyTime)+".json.gz", nil, day)
+		}
+		storage.put("phonebook/notes.txt", nil, now.Add(-100*24*time.Hour))
+
+		scheduler := NewScheduler(&backupPhoneBook{}, storage, nil, time.Hour, time.Minute, "json", "phonebook/", 3, 0)
+		assert.Nil(t, scheduler.Run(ctx))
+		assert.Len(t, storage.keys(), 4)
+		assert.Contains(t, storage.keys(), "phonebook/contacts-"+now.Add(-2*24*time.Hour).UTC().Format(keyTime)+".json.gz")
+		assert.Contains(t, storage.keys(), "phonebook/notes.txt")
+
+		scheduler = NewScheduler(&backupPhoneBook{}, storage, nil, time.Hour, time.Minute, "json", "phonebook/", 0, 36*time.Hour)
+		assert.Nil(t, scheduler.Run(ctx))
+		assert.Contains(t, storage.keys(), "phonebook/contacts-"+now.Add(-24*time.Hour).UTC().Format(keyTime)+".json.gz")
+		assert.NotContains(t, storage.keys(), "phonebook/contacts-"+now.Add(-2*24*time.Hour).UTC().Format(keyTime)+".json.gz")
+	})
+
+	t.Run("should back up every interval until stopped", func(t *testing.T) {
+		storage := newMemoryStorage()
+		scheduler := NewScheduler(&backupPhoneBook{}, storage, nil, 10*time.Millisecond, time.Minute, "bson", "", 0, 0)
+		scheduler.Start()
+		assert.Eventually(t, func() bool { return len(storage.keys()) > 0 }, time.Second, 5*time.Millisecond)
+		scheduler.Stop()
+	})
+}
@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"time"
+)
+
+// Object is a stored backup.
+type Object struct {
+	Key          string
+	LastModified time.Time
+}
+
+// Storage keeps the backups.
+type Storage interface {
+	// Upload stores the content read from r under key.
+	Upload(ctx context.Context, key string, r io.Reader, contentType string) error
+	// List returns the objects whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Remove deletes the object stored under key.
+	Remove(ctx context.Context, key string) error
+}
+
+// s3Storage keeps the backups in a bucket of an S3 compatible storage, like
+// AWS S3 or MinIO.
+type s3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Storage returns a Storage in bucket of the S3 compatible service at
+// endpoint, e.g. s3.amazonaws.com or minio:9000. The bucket must exist.
+func NewS3Storage(ctx context.Context, endpoint, region, bucket, accessKey, secretKey string, insecure bool) (Storage, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: !insecure,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("bucket %s doesn't exist", bucket)
+	}
+	return &s3Storage{client: client, bucket: bucket}, nil
+}
+
+func (s *s3Storage) Upload(ctx context.Context, key string, r io.Reader, contentType string) error {
+	// the size is unknown until the backup is written, so it's uploaded in parts
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, -1, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, Object{Key: info.Key, LastModified: info.LastModified})
+	}
+	return objects, nil
+}
+
+func (s *s3Storage) Remove(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
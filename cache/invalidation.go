@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"phoneBook/definition"
+)
+
+const invalidationChannel = "phonebook:cache:invalidate"
+
+// invalidation tells the other replicas to evict the contact with ContactID
+// of Tenant, if any, and their cached pages, or every entry when All is set.
+type invalidation struct {
+	Origin    string `json:"origin"`
+	Tenant    string `json:"tenant,omitempty"`
+	ContactID string `json:"contactId,omitempty"`
+	All       bool   `json:"all,omitempty"`
+}
+
+// ShareInvalidations publishes the invalidations of this phone book on Redis
+// and applies those published by other replicas, until ctx is done or the
+// client is closed.
+func (pb *PhoneBook) ShareInvalidations(ctx context.Context, client *redis.Client) error {
+	subscription := client.Subscribe(ctx, invalidationChannel)
+	// wait for the subscription, so no invalidation published from now on is missed
+	if _, err := subscription.Receive(ctx); err != nil {
+		subscription.Close()
+		return err
+	}
+	origin := make([]byte, 8)
+	rand.Read(origin)
+	pb.replicaID = hex.EncodeToString(origin)
+	pb.redis = client
+	go func() {
+		defer subscription.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-subscription.Channel():
+				if !ok {
+					return
+				}
+				pb.applyInvalidation(ctx, message.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+func (pb *PhoneBook) applyInvalidation(ctx context.Context, payload string) {
+	var message invalidation
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		logrus.WithError(err).Warn("ignoring invalid cache invalidation message")
+		return
+	}
+	if message.Origin == pb.replicaID {
+		return
+	}
+	if message.All {
+		pb.evictAll(ctx)
+		return
+	}
+	pb.evict(definition.WithTenant(ctx, message.Tenant), message.ContactID)
+}
+
+func (pb *PhoneBook) publishInvalidation(ctx context.Context, id string) {
+	pb.publish(ctx, invalidation{Tenant: definition.TenantFromContext(ctx), ContactID: id})
+}
+
+func (pb *PhoneBook) publish(ctx context.Context, message invalidation) {
+	if pb.redis == nil {
+		return
+	}
+	message.Origin = pb.replicaID
+	payload, _ := json.Marshal(message)
+	if err := pb.redis.Publish(ctx, invalidationChannel, payload).Err(); err != nil {
+		logrus.WithError(err).Warn("failed to publish cache invalidation")
+	}
+}
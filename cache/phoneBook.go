@@ -0,0 +1,340 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net/url"
+	"phoneBook/definition"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	hits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "phonebook_cache_hits_total",
+		Help: "Reads served from the contacts cache.",
+	}, []string{"cache"})
+	misses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "phonebook_cache_misses_total",
+		Help: "Reads the contacts cache passed to the phone book.",
+	}, []string{"cache"})
+)
+
+// PhoneBook is a read-through cache in front of a phone book. It caches
+// contacts read by id, first pages of listings, phone lookups and blocklist
+// checks in a Store, and invalidates them on every mutation made through it.
+// The other methods go straight to the phone book.
+type PhoneBook struct {
+	definition.IPhoneBook
+	store Store
+	// generation is bumped by every mutation, so reads that started before
+	// it don't cache what they got. It's kept per replica: with a shared
+	// store, a read of another replica racing a mutation here may still cache
+	// what it read before the mutation, until the entry expires.
+	generation atomic.Uint64
+	// redis publishes invalidations to the other replicas, when shared.
+	redis     *redis.Client
+	replicaID string
+}
+
+// NewPhoneBook wraps phoneBook with a cache kept in store.
+func NewPhoneBook(phoneBook definition.IPhoneBook, store Store) *PhoneBook {
+	return &PhoneBook{
+		IPhoneBook: phoneBook,
+		store:      store,
+	}
+}
+
+func (pb *PhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	key := strings.ToLower(id)
+	var contact *definition.Contact
+	if pb.get(ctx, kindContact, key, &contact) {
+		return contact, "", nil
+	}
+	generation := pb.generation.Load()
+	contact, status, err := pb.IPhoneBook.GetContact(ctx, id)
+	if err == nil {
+		pb.set(ctx, generation, kindContact, key, contact)
+	}
+	return contact, status, err
+}
+
+// GetContactWithPagination serves first pages from the cache, keyed by the
+// listing parameters. Later pages and cursor pages aren't cached.
+func (pb *PhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	if !isFirstPage(query) {
+		return pb.IPhoneBook.GetContactWithPagination(ctx, query)
+	}
+	key := query.Encode()
+	var page *definition.ContactPage
+	if pb.get(ctx, kindPage, key, &page) {
+		return page, "", nil
+	}
+	generation := pb.generation.Load()
+	page, status, err := pb.IPhoneBook.GetContactWithPagination(ctx, query)
+	if err == nil {
+		pb.set(ctx, generation, kindPage, key, page)
+	}
+	return page, status, err
+}
+
+// GetContactsByPhone serves the phone lookups, e.g. of caller ID, from the
+// cache, keyed by the number as sent.
+func (pb *PhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	key := strings.TrimSpace(number)
+	var contacts []*definition.Contact
+	if pb.get(ctx, kindPhone, key, &contacts) {
+		return contacts, "", nil
+	}
+	generation := pb.generation.Load()
+	contacts, status, err := pb.IPhoneBook.GetContactsByPhone(ctx, number)
+	if err == nil {
+		pb.set(ctx, generation, kindPhone, key, contacts)
+	}
+	return contacts, status, err
+}
+
+// CheckBlocked serves the blocklist checks of the telephony systems from the
+// cache, keyed by the number as sent.
+func (pb *PhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	key := strings.TrimSpace(number)
+	var check *definition.BlockCheck
+	if pb.get(ctx, kindBlocked, key, &check) {
+		return check, "", nil
+	}
+	generation := pb.generation.Load()
+	check, status, err := pb.IPhoneBook.CheckBlocked(ctx, number)
+	if err == nil {
+		pb.set(ctx, generation, kindBlocked, key, check)
+	}
+	return check, status, err
+}
+
+func isFirstPage(query url.Values) bool {
+	if _, ok := query["cursor"]; ok {
+		return false
+	}
+	page := query.Get("page")
+	return page == "" || page == "1"
+}
+
+// scoped prefixes key with the tenant of ctx, if any, so tenants don't read
+// each other's entries.
+func scoped(ctx context.Context, key string) string {
+	if tenant := definition.TenantFromContext(ctx); tenant != "" {
+		return tenant + "/" + key
+	}
+	return key
+}
+
+// get decodes the cached entry into value, counting the hit or miss.
+func (pb *PhoneBook) get(ctx context.Context, kind, key string, value interface{}) bool {
+	cached, ok := pb.store.Get(ctx, kind, scoped(ctx, key))
+	if ok && json.Unmarshal(cached, value) == nil {
+		hits.WithLabelValues(kind).Inc()
+		return true
+	}
+	misses.WithLabelValues(kind).Inc()
+	return false
+}
+
+// set caches value unless a mutation happened since generation.
+func (pb *PhoneBook) set(ctx context.Context, generation uint64, kind, key string, value interface{}) {
+	if pb.generation.Load() != generation {
+		return
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	pb.store.Set(ctx, kind, scoped(ctx, key), encoded)
+}
+
+func (pb *PhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, "")
+	return pb.IPhoneBook.AddContact(ctx, contact, actor)
+}
+
+func (pb *PhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	defer pb.invalidate(ctx, "")
+	return pb.IPhoneBook.AddContacts(ctx, contacts, actor)
+}
+
+func (pb *PhoneBook) UpdateContact(ctx context.Context, id string, updatedContact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.UpdateContact(ctx, id, updatedContact, expectedVersion, actor)
+}
+
+func (pb *PhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.PatchContact(ctx, id, patch, expectedVersion, actor)
+}
+
+func (pb *PhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.UpsertContact(ctx, id, contact, actor)
+}
+
+// UpsertContactByPhone evicts the contact upserted, whose id is known once
+// written.
+func (pb *PhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpsertContactByPhone(ctx, number, contact, actor)
+	id := ""
+	if err == nil {
+		id = result.ID
+	}
+	pb.invalidate(ctx, id)
+	return result, err
+}
+
+func (pb *PhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.DeleteContact(ctx, id, actor)
+}
+
+func (pb *PhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	result, err := pb.IPhoneBook.DeleteContacts(ctx, batch, actor)
+	if err == nil && !result.DryRun {
+		for _, id := range result.IDs {
+			pb.invalidate(ctx, id)
+		}
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	result, err := pb.IPhoneBook.ApplyRetention(ctx, policy, actor)
+	if err == nil && !result.DryRun {
+		for _, id := range result.IDs {
+			pb.invalidate(ctx, id)
+		}
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	result, err := pb.IPhoneBook.EraseContacts(ctx, phone, actor)
+	if err == nil {
+		for _, id := range result.IDs {
+			pb.invalidate(ctx, id)
+		}
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.UndoContact(ctx, id, actor)
+}
+
+func (pb *PhoneBook) LinkContact(ctx context.Context, id string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.LinkContact(ctx, id, relation, actor)
+}
+
+func (pb *PhoneBook) UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.UnlinkContact(ctx, id, relatedID, actor)
+}
+
+func (pb *PhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.ArchiveContact(ctx, id, archived, actor)
+}
+
+func (pb *PhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.BlockContact(ctx, id, blocked, actor)
+}
+
+func (pb *PhoneBook) AddInteraction(ctx context.Context, id string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, id)
+	return pb.IPhoneBook.AddInteraction(ctx, id, interaction, actor)
+}
+
+func (pb *PhoneBook) AssignSpeedDial(ctx context.Context, slot string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	defer pb.invalidate(ctx, dial.ContactID.Hex())
+	return pb.IPhoneBook.AssignSpeedDial(ctx, slot, dial, actor)
+}
+
+// ClearSpeedDial evicts the pages and lookups only, the contact of the slot
+// isn't known here.
+func (pb *PhoneBook) ClearSpeedDial(ctx context.Context, slot string) (*definition.WriteResult, error) {
+	defer pb.invalidate(ctx, "")
+	return pb.IPhoneBook.ClearSpeedDial(ctx, slot)
+}
+
+func (pb *PhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	defer pb.invalidateAll(ctx)
+	return pb.IPhoneBook.ReorderContacts(ctx, order, actor)
+}
+
+func (pb *PhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	defer pb.invalidateAll(ctx)
+	return pb.IPhoneBook.Restore(ctx, format, mode, r)
+}
+
+func (pb *PhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	defer pb.invalidateAll(ctx)
+	return pb.IPhoneBook.SeedContacts(ctx, count, actor)
+}
+
+func (pb *PhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	if !dryRun {
+		defer pb.invalidateAll(ctx)
+	}
+	return pb.IPhoneBook.Migrate(ctx, dryRun)
+}
+
+func (pb *PhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	if fix {
+		defer pb.invalidateAll(ctx)
+	}
+	return pb.IPhoneBook.ValidateContacts(ctx, fix, actor)
+}
+
+func (pb *PhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	defer pb.invalidateAll(ctx)
+	return pb.IPhoneBook.RebuildDerivedData(ctx, progress)
+}
+
+// invalidate evicts the cached entries a mutation of the contact with the
+// given id may change, here and on the other replicas.
+func (pb *PhoneBook) invalidate(ctx context.Context, id string) {
+	// the mutation may have happened even when the request was canceled
+	ctx = context.WithoutCancel(ctx)
+	pb.evict(ctx, id)
+	pb.publishInvalidation(ctx, id)
+}
+
+// invalidateAll evicts every cached entry, here and on the other replicas.
+func (pb *PhoneBook) invalidateAll(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	pb.evictAll(ctx)
+	pb.publish(ctx, invalidation{All: true})
+}
+
+// evict removes the contact with the given id, if any, and every cached page,
+// phone lookup and blocklist check, since any mutation can change them. Those of the other
+// tenants go too, stores can't purge the entries of a single tenant.
+func (pb *PhoneBook) evict(ctx context.Context, id string) {
+	pb.generation.Add(1)
+	if id != "" {
+		pb.store.Delete(ctx, kindContact, scoped(ctx, strings.ToLower(id)))
+	}
+	pb.store.Purge(ctx, kindPage)
+	pb.store.Purge(ctx, kindPhone)
+	pb.store.Purge(ctx, kindBlocked)
+}
+
+func (pb *PhoneBook) evictAll(ctx context.Context) {
+	pb.generation.Add(1)
+	pb.store.Purge(ctx, kindContact)
+	pb.store.Purge(ctx, kindPage)
+	pb.store.Purge(ctx, kindPhone)
+	pb.store.Purge(ctx, kindBlocked)
+}
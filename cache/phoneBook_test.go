@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+// countingPhoneBook counts the reads that reach it.
+type countingPhoneBook struct {
+	definition.IPhoneBook
+	contactReads int
+	pageReads    int
+	phoneReads   int
+	blockedReads int
+}
+
+func (pb *countingPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	pb.contactReads++
+	return &definition.Contact{FirstName: "dani"}, "", nil
+}
+
+func (pb *countingPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	pb.pageReads++
+	return &definition.ContactPage{Items: []*definition.Contact{}}, "", nil
+}
+
+func (pb *countingPhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	pb.phoneReads++
+	return []*definition.Contact{{FirstName: "dani"}}, "", nil
+}
+
+func (pb *countingPhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	pb.blockedReads++
+	return &definition.BlockCheck{Number: "972521234567", Blocked: true}, "", nil
+}
+
+func (pb *countingPhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *countingPhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	return &definition.RestoreResult{Mode: mode}, nil
+}
+
+func (pb *countingPhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	return &definition.MigrationResult{DryRun: dryRun}, nil
+}
+
+func (pb *countingPhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	return &definition.ValidationReport{}, nil
+}
+
+func (pb *countingPhoneBook) AssignSpeedDial(ctx context.Context, slot string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	return dial, nil
+}
+
+func (pb *countingPhoneBook) ClearSpeedDial(ctx context.Context, slot string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *countingPhoneBook) AddInteraction(ctx context.Context, id string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{ID: primitive.NewObjectID().Hex(), Created: true}, nil
+}
+
+func TestPhoneBook(t *testing.T) {
+	ctx := context.Background()
+	id := "65a1b2c3d4e5f60718293a4b"
+
+	t.Run("should serve repeated reads from cache", func(t *testing.T) {
+		backend := &countingPhoneBook{}
+		phoneBook := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		phoneBook.GetContact(ctx, id)
+		phoneBook.GetContact(ctx, id)
+		phoneBook.GetContactWithPagination(ctx, url.Values{"pageSize": {"5"}})
+		phoneBook.GetContactWithPagination(ctx, url.Values{"pageSize": {"5"}})
+		phoneBook.GetContactsByPhone(ctx, "0521234567")
+		phoneBook.GetContactsByPhone(ctx, "0521234567")
+		phoneBook.CheckBlocked(ctx, "0521234567")
+		check, _, _ := phoneBook.CheckBlocked(ctx, "0521234567")
+		assert.Equal(t, 1, backend.contactReads)
+		assert.Equal(t, 1, backend.pageReads)
+		assert.Equal(t, 1, backend.phoneReads)
+		assert.Equal(t, 1, backend.blockedReads)
+		assert.True(t, check.Blocked)
+	})
+
+	t.Run("should not cache later pages", func(t *testing.T) {
+		backend := &countingPhoneBook{}
+		phoneBook := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		phoneBook.GetContactWithPagination(ctx, url.Values{"page": {"2"}})
+		phoneBook.GetContactWithPagination(ctx, url.Values{"page": {"2"}})
+		phoneBook.GetContactWithPagination(ctx, url.Values{"cursor": {""}})
+		assert.Equal(t, 3, backend.pageReads)
+	})
+
+	t.Run("should invalidate on mutation", func(t *testing.T) {
+		backend := &countingPhoneBook{}
+		phoneBook := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		phoneBook.GetContact(ctx, id)
+		phoneBook.GetContactWithPagination(ctx, url.Values{})
+		phoneBook.GetContactsByPhone(ctx, "0521234567")
+		phoneBook.CheckBlocked(ctx, "0521234567")
+		phoneBook.DeleteContact(ctx, id, "")
+		phoneBook.GetContact(ctx, id)
+		phoneBook.GetContactWithPagination(ctx, url.Values{})
+		phoneBook.GetContactsByPhone(ctx, "0521234567")
+		phoneBook.CheckBlocked(ctx, "0521234567")
+		assert.Equal(t, 2, backend.contactReads)
+		assert.Equal(t, 2, backend.pageReads)
+		assert.Equal(t, 2, backend.phoneReads)
+		assert.Equal(t, 2, backend.blockedReads)
+	})
+
+	contactID, _ := primitive.ObjectIDFromHex(id)
+	mutations := []struct {
+		name string
+		// evictsContact is unset for mutations not knowing their contact
+		evictsContact bool
+		mutate        func(phoneBook *PhoneBook)
+	}{
+		{"migration", true, func(phoneBook *PhoneBook) { phoneBook.Migrate(ctx, false) }},
+		{"validation fix", true, func(phoneBook *PhoneBook) { phoneBook.ValidateContacts(ctx, true, "") }},
+		{"speed dial assignment", true, func(phoneBook *PhoneBook) {
+			phoneBook.AssignSpeedDial(ctx, "2", &definition.SpeedDial{ContactID: contactID}, "")
+		}},
+		{"speed dial clearing", false, func(phoneBook *PhoneBook) { phoneBook.ClearSpeedDial(ctx, "2") }},
+		{"interaction", true, func(phoneBook *PhoneBook) { phoneBook.AddInteraction(ctx, id, &definition.Interaction{}, "") }},
+	}
+	for _, mutation := range mutations {
+		t.Run("should invalidate on "+mutation.name, func(t *testing.T) {
+			backend := &countingPhoneBook{}
+			phoneBook := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+			phoneBook.GetContact(ctx, id)
+			phoneBook.GetContactWithPagination(ctx, url.Values{})
+			mutation.mutate(phoneBook)
+			phoneBook.GetContact(ctx, id)
+			phoneBook.GetContactWithPagination(ctx, url.Values{})
+			assert.Equal(t, 2, backend.pageReads)
+			if mutation.evictsContact {
+				assert.Equal(t, 2, backend.contactReads)
+			}
+		})
+	}
+
+	t.Run("should not invalidate on dry runs", func(t *testing.T) {
+		backend := &countingPhoneBook{}
+		phoneBook := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		phoneBook.GetContactWithPagination(ctx, url.Values{})
+		phoneBook.Migrate(ctx, true)
+		phoneBook.ValidateContacts(ctx, false, "")
+		phoneBook.GetContactWithPagination(ctx, url.Values{})
+		assert.Equal(t, 1, backend.pageReads)
+	})
+
+	t.Run("should keep the entries of tenants apart", func(t *testing.T) {
+		backend := &countingPhoneBook{}
+		phoneBook := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		acme := definition.WithTenant(ctx, "acme")
+		phoneBook.GetContact(ctx, id)
+		phoneBook.GetContact(acme, id)
+		phoneBook.GetContact(acme, id)
+		assert.Equal(t, 2, backend.contactReads)
+		phoneBook.DeleteContact(acme, id, "")
+		phoneBook.GetContact(ctx, id)
+		assert.Equal(t, 2, backend.contactReads)
+	})
+
+	t.Run("should share cache and invalidations through redis", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		backend := &countingPhoneBook{}
+		replica := NewPhoneBook(backend, NewRedisStore(client, time.Minute))
+		otherReplica := NewPhoneBook(backend, NewRedisStore(client, time.Minute))
+		replica.GetContact(ctx, id)
+		otherReplica.GetContact(ctx, id)
+		assert.Equal(t, 1, backend.contactReads)
+		otherReplica.DeleteContact(ctx, id, "")
+		replica.GetContact(ctx, id)
+		assert.Equal(t, 2, backend.contactReads)
+	})
+
+	t.Run("should apply invalidations published by other replicas", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		backend := &countingPhoneBook{}
+		replica := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		otherReplica := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		assert.Nil(t, replica.ShareInvalidations(ctx, client))
+		assert.Nil(t, otherReplica.ShareInvalidations(ctx, client))
+		replica.GetContact(ctx, id)
+		otherReplica.DeleteContact(ctx, id, "")
+		assert.Eventually(t, func() bool {
+			_, ok := replica.store.Get(ctx, kindContact, id)
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should apply invalidations of other replicas to their tenant", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		backend := &countingPhoneBook{}
+		replica := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		otherReplica := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		assert.Nil(t, replica.ShareInvalidations(ctx, client))
+		assert.Nil(t, otherReplica.ShareInvalidations(ctx, client))
+		acme := definition.WithTenant(ctx, "acme")
+		replica.GetContact(acme, id)
+		otherReplica.DeleteContact(acme, id, "")
+		assert.Eventually(t, func() bool {
+			_, ok := replica.store.Get(ctx, kindContact, "acme/"+id)
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should evict every entry on restore, also on other replicas", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		backend := &countingPhoneBook{}
+		replica := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		otherReplica := NewPhoneBook(backend, NewMemoryStore(10, time.Minute))
+		assert.Nil(t, replica.ShareInvalidations(ctx, client))
+		assert.Nil(t, otherReplica.ShareInvalidations(ctx, client))
+		replica.GetContact(ctx, id)
+		otherReplica.GetContact(ctx, id)
+		otherReplica.Restore(ctx, definition.BackupFormatJSON, definition.RestoreModeMerge, nil)
+		_, ok := otherReplica.store.Get(ctx, kindContact, id)
+		assert.False(t, ok)
+		assert.Eventually(t, func() bool {
+			_, ok := replica.store.Get(ctx, kindContact, id)
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+}
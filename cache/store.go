@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// The kinds of cached entries.
+const (
+	kindContact = "contact"
+	kindPage    = "page"
+	kindPhone   = "phone"
+	kindBlocked = "blocked"
+)
+
+// Store keeps JSON encoded cache entries of each kind for up to the cache TTL.
+// Failing stores behave as if empty, so the phone book keeps serving.
+type Store interface {
+	Get(ctx context.Context, kind, key string) ([]byte, bool)
+	Set(ctx context.Context, kind, key string, value []byte)
+	Delete(ctx context.Context, kind, key string)
+	Purge(ctx context.Context, kind string)
+}
+
+// memoryStore keeps up to size entries of each kind in an LRU of the process.
+type memoryStore struct {
+	entries map[string]*expirable.LRU[string, []byte]
+}
+
+// NewMemoryStore returns a store keeping up to size entries of each kind in
+// memory for ttl.
+func NewMemoryStore(size int, ttl time.Duration) Store {
+	return &memoryStore{
+		entries: map[string]*expirable.LRU[string, []byte]{
+			kindContact: expirable.NewLRU[string, []byte](size, nil, ttl),
+			kindPage:    expirable.NewLRU[string, []byte](size, nil, ttl),
+			kindPhone:   expirable.NewLRU[string, []byte](size, nil, ttl),
+			kindBlocked: expirable.NewLRU[string, []byte](size, nil, ttl),
+		},
+	}
+}
+
+func (s *memoryStore) Get(ctx context.Context, kind, key string) ([]byte, bool) {
+	return s.entries[kind].Get(key)
+}
+
+func (s *memoryStore) Set(ctx context.Context, kind, key string, value []byte) {
+	s.entries[kind].Add(key, value)
+}
+
+func (s *memoryStore) Delete(ctx context.Context, kind, key string) {
+	s.entries[kind].Remove(key)
+}
+
+func (s *memoryStore) Purge(ctx context.Context, kind string) {
+	s.entries[kind].Purge()
+}
+
+const redisKeyPrefix = "phonebook:cache:"
+
+// redisStore shares the cache between replicas. Every entry is a Redis key
+// of its own, under the prefix of its kind, expiring ttl after it was set.
+type redisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisStore returns a store keeping the entries in Redis for up to ttl.
+func NewRedisStore(client *redis.Client, ttl time.Duration) Store {
+	return &redisStore{client: client, ttl: ttl}
+}
+
+func redisKey(kind, key string) string {
+	return redisKeyPrefix + kind + ":" + key
+}
+
+func (s *redisStore) Get(ctx context.Context, kind, key string) ([]byte, bool) {
+	value, err := s.client.Get(ctx, redisKey(kind, key)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logrus.WithError(err).Warn("failed to read redis cache")
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (s *redisStore) Set(ctx context.Context, kind, key string, value []byte) {
+	if err := s.client.Set(ctx, redisKey(kind, key), value, s.ttl).Err(); err != nil {
+		logrus.WithError(err).Warn("failed to write redis cache")
+	}
+}
+
+func (s *redisStore) Delete(ctx context.Context, kind, key string) {
+	if err := s.client.Del(ctx, redisKey(kind, key)).Err(); err != nil {
+		logrus.WithError(err).Warn("failed to evict from redis cache")
+	}
+}
+
+// Purge scans the keys of the kind and deletes them batch by batch. Entries
+// set while it scans may survive it.
+func (s *redisStore) Purge(ctx context.Context, kind string) {
+	iter := s.client.Scan(ctx, 0, redisKey(kind, "*"), 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 100 {
+			s.unlink(ctx, keys)
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		logrus.WithError(err).Warn("failed to purge redis cache")
+	}
+	s.unlink(ctx, keys)
+}
+
+func (s *redisStore) unlink(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.client.Unlink(ctx, keys...).Err(); err != nil {
+		logrus.WithError(err).Warn("failed to purge redis cache")
+	}
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisStore(client, time.Minute)
+
+	t.Run("should expire every entry a ttl after it was set", func(t *testing.T) {
+		store.Set(ctx, kindContact, "first", []byte(`{}`))
+		server.FastForward(40 * time.Second)
+		store.Set(ctx, kindContact, "second", []byte(`{}`))
+		server.FastForward(40 * time.Second)
+		_, ok := store.Get(ctx, kindContact, "first")
+		assert.False(t, ok)
+		_, ok = store.Get(ctx, kindContact, "second")
+		assert.True(t, ok)
+	})
+
+	t.Run("should purge the entries of a kind only", func(t *testing.T) {
+		for _, key := range []string{"a", "b*", "tenant/c"} {
+			store.Set(ctx, kindPage, key, []byte(`{}`))
+		}
+		store.Set(ctx, kindPhone, "a", []byte(`{}`))
+		store.Purge(ctx, kindPage)
+		for _, key := range []string{"a", "b*", "tenant/c"} {
+			_, ok := store.Get(ctx, kindPage, key)
+			assert.False(t, ok)
+		}
+		_, ok := store.Get(ctx, kindPhone, "a")
+		assert.True(t, ok)
+	})
+
+	t.Run("should delete one entry", func(t *testing.T) {
+		store.Set(ctx, kindBlocked, "a", []byte(`{}`))
+		store.Set(ctx, kindBlocked, "b", []byte(`{}`))
+		store.Delete(ctx, kindBlocked, "a")
+		_, ok := store.Get(ctx, kindBlocked, "a")
+		assert.False(t, ok)
+		_, ok = store.Get(ctx, kindBlocked, "b")
+		assert.True(t, ok)
+	})
+}
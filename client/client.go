@@ -0,0 +1,181 @@
+// Package client calls the phone book http API.
+//
+//	c := client.New("http://localhost:8080")
+//	id, err := c.AddContact(ctx, &definition.Contact{FirstName: "Dani", Phone: "0521234567"})
+//	if errors.Is(err, client.ErrDuplicateContact) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPath is the version of the API the client speaks.
+const apiPath = "/api/v1"
+
+// Client is a phone book API client. Requests failing with a transient error
+// are retried: rate limited ones and, when the method is idempotent, those
+// that didn't get an answer or got 502, 503 or 504.
+type Client struct {
+	// HTTPClient sends the requests, http.DefaultClient by default.
+	HTTPClient *http.Client
+	// MaxRetries is the number of times a request is retried, 3 by default.
+	MaxRetries int
+	// RetryWait is the wait before the first retry, doubled before each next
+	// one. A Retry-After header sent by the server overrides it.
+	RetryWait time.Duration
+	// MaxRetryWait caps the wait between retries.
+	MaxRetryWait time.Duration
+	// User names who makes the changes in the server's audit log.
+	User string
+	// APIKey identifies the client to the server's rate limiter.
+	APIKey string
+
+	baseURL string
+}
+
+// New returns a client of the phone book served at baseURL, e.g.
+// http://localhost:8080.
+func New(baseURL string) *Client {
+	return &Client{
+		HTTPClient:   http.DefaultClient,
+		MaxRetries:   3,
+		RetryWait:    200 * time.Millisecond,
+		MaxRetryWait: 5 * time.Second,
+		baseURL:      strings.TrimSuffix(baseURL, "/") + apiPath,
+	}
+}
+
+// do sends a request, retrying transient failures, and decodes a successful
+// response body into result. header may be nil.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body interface{}, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		response, err := c.send(ctx, method, path, header, payload)
+		if err != nil {
+			if ctx.Err() != nil || !idempotent(method) || attempt >= c.MaxRetries {
+				return err
+			}
+			if err := c.wait(ctx, c.backoff(attempt)); err != nil {
+				return err
+			}
+			continue
+		}
+		respBody, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+		if response.StatusCode >= http.StatusBadRequest {
+			apiErr := newError(response, respBody)
+			if !retryable(method, response.StatusCode) || attempt >= c.MaxRetries {
+				return apiErr
+			}
+			wait := c.backoff(attempt)
+			if apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+			if err := c.wait(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(respBody, result)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.User != "" {
+		request.Header.Set("X-User", c.User)
+	}
+	if c.APIKey != "" {
+		request.Header.Set("X-API-Key", c.APIKey)
+	}
+	return c.HTTPClient.Do(request)
+}
+
+// backoff is the wait before retry number attempt+1.
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := time.Duration(float64(c.RetryWait) * math.Pow(2, float64(attempt)))
+	if c.MaxRetryWait > 0 && wait > c.MaxRetryWait {
+		return c.MaxRetryWait
+	}
+	return wait
+}
+
+func (c *Client) wait(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// idempotent reports whether sending a request twice has the effect of
+// sending it once, so it can be retried when its outcome is unknown.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a request answered with status may succeed when
+// sent again. Rate limited requests were not handled, so they are retried
+// whatever their method.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient serves the requests of a client with handler.
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := New(server.URL)
+	client.RetryWait = time.Millisecond
+	return client
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestAddContact(t *testing.T) {
+	t.Run("should return the id of the added contact", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+			var contact definition.Contact
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&contact))
+			assert.Equal(t, "Dani", contact.FirstName)
+			assert.Equal(t, "dani", r.Header.Get("X-User"))
+			writeJSON(w, http.StatusOK, "Inserted ID: 65a1b2c3d4e5f60718293a4b")
+		})
+		client := newTestClient(t, mux)
+		client.User = "dani"
+
+		id, err := client.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"})
+		assert.Nil(t, err)
+		assert.Equal(t, "65a1b2c3d4e5f60718293a4b", id)
+	})
+
+	t.Run("should return the server error matching its code", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"code": "INVALID_PHONE", "message": "invalid phone number", "field": "phone", "requestId": "9f86d081884c7d65",
+			})
+		})
+		client := newTestClient(t, mux)
+
+		_, err := client.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "phone"})
+		assert.True(t, errors.Is(err, ErrInvalidPhone))
+		assert.True(t, errors.Is(err, definition.NewError("INVALID_PHONE", "", "")))
+		assert.False(t, errors.Is(err, ErrMissingPhone))
+		var apiErr *Error
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "phone", apiErr.Field)
+		assert.Equal(t, "9f86d081884c7d65", apiErr.RequestID)
+	})
+
+	t.Run("should not retry an add that may have been handled", func(t *testing.T) {
+		var requests atomic.Int32
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+		_, err := client.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"})
+		var apiErr *Error
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Equal(t, "Service Unavailable", apiErr.Message)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("should retry a rate limited add", func(t *testing.T) {
+		var requests atomic.Int32
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				writeJSON(w, http.StatusTooManyRequests, map[string]string{"code": "RATE_LIMITED", "message": "too many requests"})
+				return
+			}
+			writeJSON(w, http.StatusOK, "Inserted ID: 65a1b2c3d4e5f60718293a4b")
+		}))
+
+		id, err := client.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"})
+		assert.Nil(t, err)
+		assert.Equal(t, "65a1b2c3d4e5f60718293a4b", id)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+}
+
+func TestGetContact(t *testing.T) {
+	t.Run("should retry transient failures up to MaxRetries", func(t *testing.T) {
+		var requests atomic.Int32
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= 2 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, definition.Contact{FirstName: "Dani", Version: 2})
+		}))
+
+		contact, err := client.GetContact(context.Background(), "65a1b2c3d4e5f60718293a4b")
+		assert.Nil(t, err)
+		assert.Equal(t, "Dani", contact.FirstName)
+		assert.Equal(t, int32(3), requests.Load())
+
+		requests.Store(0)
+		client.MaxRetries = 1
+		_, err = client.GetContact(context.Background(), "65a1b2c3d4e5f60718293a4b")
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("should stop retrying when the context is done", func(t *testing.T) {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		client.RetryWait = time.Hour
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := client.GetContact(ctx, "65a1b2c3d4e5f60718293a4b")
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+func TestSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/contact/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "firstName=Dani&pageSize=5", r.URL.RawQuery)
+		writeJSON(w, http.StatusOK, []definition.Contact{{FirstName: "Dani", LastName: "Cohen"}})
+	})
+	client := newTestClient(t, mux)
+
+	contacts, err := client.Search(context.Background(), SearchQuery{FirstName: "Dani", PageSize: 5})
+	assert.Nil(t, err)
+	assert.Len(t, contacts, 1)
+	assert.Equal(t, "Cohen", contacts[0].LastName)
+}
+
+func TestSearchText(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/contact/search/text", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "pageSize=20&q=dani+cohen", r.URL.RawQuery)
+		writeJSON(w, http.StatusOK, []definition.Contact{{FirstName: "Dani", LastName: "Cohen"}})
+	})
+	client := newTestClient(t, mux)
+
+	contacts, err := client.SearchText(context.Background(), "dani cohen", 20)
+	assert.Nil(t, err)
+	assert.Len(t, contacts, 1)
+}
+
+func TestListPage(t *testing.T) {
+	totalPages := int64(3)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "page=2&pageSize=10", r.URL.RawQuery)
+		writeJSON(w, http.StatusOK, definition.ContactPage{Items: []*definition.Contact{{FirstName: "Dani"}}, Page: 2, TotalPages: &totalPages})
+	})
+	client := newTestClient(t, mux)
+
+	page, err := client.ListPage(context.Background(), 2, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page.Page)
+	assert.Equal(t, int64(3), *page.TotalPages)
+}
+
+func TestList(t *testing.T) {
+	t.Run("should iterate over every page", func(t *testing.T) {
+		pages := map[string]definition.ContactPage{
+			"":   {Items: []*definition.Contact{{FirstName: "a"}, {FirstName: "b"}}, NextCursor: "c1"},
+			"c1": {Items: []*definition.Contact{{FirstName: "c"}, {FirstName: "d"}}, NextCursor: "c2"},
+			"c2": {Items: []*definition.Contact{{FirstName: "e"}}},
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "2", r.URL.Query().Get("pageSize"))
+			assert.Equal(t, "false", r.URL.Query().Get("count"))
+			writeJSON(w, http.StatusOK, pages[r.URL.Query().Get("cursor")])
+		})
+		client := newTestClient(t, mux)
+
+		var names []string
+		contacts := client.List(context.Background(), 2)
+		for contacts.Next() {
+			names = append(names, contacts.Contact().FirstName)
+		}
+		assert.Nil(t, contacts.Err())
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+	})
+
+	t.Run("should stop on error", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("cursor") == "" {
+				writeJSON(w, http.StatusOK, definition.ContactPage{Items: []*definition.Contact{{FirstName: "a"}}, NextCursor: "c1"})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"code": "INVALID_CURSOR", "message": "invalid cursor"})
+		})
+		client := newTestClient(t, mux)
+
+		contacts := client.List(context.Background(), 0)
+		assert.True(t, contacts.Next())
+		assert.False(t, contacts.Next())
+		assert.Nil(t, contacts.Contact())
+		assert.True(t, errors.Is(contacts.Err(), ErrInvalidCursor))
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/v1/contact/edit/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "1":
+			assert.Equal(t, "3", r.Header.Get("If-Match"))
+			writeJSON(w, http.StatusOK, "edited 1 document successfully")
+		case "2":
+			writeJSON(w, http.StatusConflict, map[string]string{"code": "VERSION_CONFLICT", "message": "contact was modified"})
+		default:
+			writeJSON(w, http.StatusOK, "not found document to edit")
+		}
+	})
+	client := newTestClient(t, mux)
+	contact := &definition.Contact{FirstName: "Dani", Phone: "0521234567"}
+
+	assert.Nil(t, client.Update(context.Background(), "1", contact, 3))
+	assert.True(t, errors.Is(client.Update(context.Background(), "2", contact, 3), ErrVersionConflict))
+	assert.True(t, errors.Is(client.Update(context.Background(), "3", contact, 0), ErrContactNotFound))
+}
+
+func TestPatch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /api/v1/contact/{id}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"phone": "0527654321", "notes": null}`, string(body))
+		assert.Empty(t, r.Header.Get("If-Match"))
+		writeJSON(w, http.StatusOK, "edited 1 document successfully")
+	})
+	client := newTestClient(t, mux)
+	phone := "0527654321"
+
+	assert.Nil(t, client.Patch(context.Background(), "1", definition.ContactPatch{"phone": &phone, "notes": nil}, 0))
+}
+
+func TestDelete(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/v1/contact/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "1" {
+			writeJSON(w, http.StatusOK, "deleted 1 document successfully")
+			return
+		}
+		writeJSON(w, http.StatusOK, "not found document to delete")
+	})
+	client := newTestClient(t, mux)
+
+	assert.Nil(t, client.Delete(context.Background(), "1"))
+	assert.True(t, errors.Is(client.Delete(context.Background(), "2"), ErrContactNotFound))
+}
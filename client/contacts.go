@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"phoneBook/definition"
+	"strconv"
+	"strings"
+)
+
+// SearchQuery selects contacts by exact field values. Empty fields are not
+// searched by.
+type SearchQuery struct {
+	FirstName string
+	LastName  string
+	Phone     string
+	Email     string
+	Company   string
+	JobTitle  string
+	Address   string
+	Notes     string
+	// PageSize caps the number of contacts returned, the server default when 0.
+	PageSize int
+}
+
+func (q SearchQuery) values() url.Values {
+	values := url.Values{}
+	for name, value := range map[string]string{
+		"firstName": q.FirstName,
+		"lastName":  q.LastName,
+		"phone":     q.Phone,
+		"email":     q.Email,
+		"company":   q.Company,
+		"jobTitle":  q.JobTitle,
+		"address":   q.Address,
+		"notes":     q.Notes,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if q.PageSize > 0 {
+		values.Set("pageSize", strconv.Itoa(q.PageSize))
+	}
+	return values
+}
+
+// AddContact adds contact and returns its ID.
+func (c *Client) AddContact(ctx context.Context, contact *definition.Contact) (string, error) {
+	var confirmation string
+	if err := c.do(ctx, http.MethodPost, "/contact", nil, contact, &confirmation); err != nil {
+		return "", err
+	}
+	// the server confirms with "Inserted ID: <id>"
+	_, id, found := strings.Cut(confirmation, ": ")
+	if !found || id == "" {
+		return "", errNoID
+	}
+	return id, nil
+}
+
+// GetContact returns the contact with id.
+func (c *Client) GetContact(ctx context.Context, id string) (*definition.Contact, error) {
+	var contact definition.Contact
+	if err := c.do(ctx, http.MethodGet, "/contact/"+url.PathEscape(id), nil, nil, &contact); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// Search returns the contacts matching every field set in query.
+func (c *Client) Search(ctx context.Context, query SearchQuery) ([]*definition.Contact, error) {
+	var contacts []*definition.Contact
+	path := "/contact/search"
+	if values := query.values(); len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &contacts); err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// SearchText returns the contacts best matching the words of text in their
+// names, address and notes, up to pageSize or the server default when 0.
+func (c *Client) SearchText(ctx context.Context, text string, pageSize int) ([]*definition.Contact, error) {
+	query := url.Values{"q": {text}}
+	if pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	}
+	var contacts []*definition.Contact
+	if err := c.do(ctx, http.MethodGet, "/contact/search/text?"+query.Encode(), nil, nil, &contacts); err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// Update replaces the contact with id. A non zero expectedVersion makes the
+// update fail with ErrVersionConflict if the contact was changed since.
+func (c *Client) Update(ctx context.Context, id string, contact *definition.Contact, expectedVersion int64) error {
+	var confirmation string
+	if err := c.do(ctx, http.MethodPut, "/contact/edit/"+url.PathEscape(id), ifMatch(expectedVersion), contact, &confirmation); err != nil {
+		return err
+	}
+	return notFoundError(confirmation)
+}
+
+// Patch sets the fields of the contact with id present in patch, clearing
+// those set to nil. A non zero expectedVersion makes it fail with
+// ErrVersionConflict if the contact was changed since.
+func (c *Client) Patch(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64) error {
+	var confirmation string
+	if err := c.do(ctx, http.MethodPatch, "/contact/"+url.PathEscape(id), ifMatch(expectedVersion), patch, &confirmation); err != nil {
+		return err
+	}
+	return notFoundError(confirmation)
+}
+
+// Delete deletes the contact with id.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	var confirmation string
+	if err := c.do(ctx, http.MethodDelete, "/contact/delete/"+url.PathEscape(id), nil, nil, &confirmation); err != nil {
+		return err
+	}
+	return notFoundError(confirmation)
+}
+
+// ifMatch makes a request conditional on the contact version, if not 0.
+func ifMatch(expectedVersion int64) http.Header {
+	if expectedVersion <= 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {strconv.FormatInt(expectedVersion, 10)}}
+}
+
+// notFoundError turns the confirmation the server sends when there was no
+// contact to delete into ErrContactNotFound. Servers answer the updates of a
+// missing contact with a 404 now, older ones with such a confirmation.
+func notFoundError(confirmation string) error {
+	if strings.HasPrefix(confirmation, "not found") {
+		return &Error{StatusCode: http.StatusOK, Code: ErrContactNotFound.Code, Message: confirmation}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"phoneBook/definition"
+	"time"
+)
+
+// Errors the server answers with, to match with errors.Is. Matching is by
+// code, so the message and field of the returned error may differ.
+var (
+	ErrMissingID          = &Error{Code: "MISSING_ID"}
+	ErrInvalidID          = &Error{Code: "INVALID_ID"}
+	ErrMissingFirstName   = &Error{Code: "MISSING_FIRST_NAME"}
+	ErrInvalidFirstName   = &Error{Code: "INVALID_FIRST_NAME"}
+	ErrInvalidLastName    = &Error{Code: "INVALID_LAST_NAME"}
+	ErrMissingPhone       = &Error{Code: "MISSING_PHONE"}
+	ErrInvalidPhone       = &Error{Code: "INVALID_PHONE"}
+	ErrEmptyPatch         = &Error{Code: "EMPTY_PATCH"}
+	ErrUnknownField       = &Error{Code: "UNKNOWN_FIELD"}
+	ErrInvalidPage        = &Error{Code: "INVALID_PAGE"}
+	ErrInvalidCursor      = &Error{Code: "INVALID_CURSOR"}
+	ErrInvalidPageSize    = &Error{Code: "INVALID_PAGE_SIZE"}
+	ErrInvalidSearchValue = &Error{Code: "INVALID_SEARCH_VALUE"}
+	ErrContactNotFound    = &Error{Code: "CONTACT_NOT_FOUND"}
+	ErrVersionConflict    = &Error{Code: "VERSION_CONFLICT"}
+	ErrDuplicateContact   = &Error{Code: "DUPLICATE_CONTACT"}
+	ErrInvalidBody        = &Error{Code: "INVALID_BODY"}
+	ErrFieldTooLong       = &Error{Code: "FIELD_TOO_LONG"}
+	ErrBodyTooLarge       = &Error{Code: "BODY_TOO_LARGE"}
+	ErrRateLimited        = &Error{Code: "RATE_LIMITED"}
+)
+
+// errNoID is returned when the server confirms an added contact without its ID.
+var errNoID = errors.New("added contact ID is missing in the response")
+
+// Error is a request the server refused or failed.
+type Error struct {
+	// StatusCode is the http status of the response.
+	StatusCode int
+	// Code is the stable error code, e.g. INVALID_PHONE.
+	Code    string
+	Message string
+	// Field is the contact field the error refers to, if any.
+	Field string
+	// RequestID identifies the request in the server logs.
+	RequestID string
+	// RetryAfter is how long the server asked to wait before retrying.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Is reports errors with the same code as equal, including the server's own
+// definition.Error values.
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case *Error:
+		return t.Code == e.Code
+	case *definition.Error:
+		return t.Code == e.Code
+	}
+	return false
+}
+
+// newError reads the error body of a failed response. Responses not sent by
+// the phone book, e.g. by a proxy, get the status text as message.
+func newError(response *http.Response, body []byte) *Error {
+	apiErr := &Error{
+		StatusCode: response.StatusCode,
+		RequestID:  response.Header.Get("X-Request-ID"),
+		RetryAfter: retryAfter(response.Header.Get("Retry-After")),
+	}
+	var decoded struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		Field     string `json:"field"`
+		RequestID string `json:"requestId"`
+	}
+	if json.Unmarshal(body, &decoded) != nil || decoded.Code == "" {
+		apiErr.Message = http.StatusText(response.StatusCode)
+		return apiErr
+	}
+	apiErr.Code = decoded.Code
+	apiErr.Message = decoded.Message
+	apiErr.Field = decoded.Field
+	if decoded.RequestID != "" {
+		apiErr.RequestID = decoded.RequestID
+	}
+	return apiErr
+}
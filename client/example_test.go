@@ -0,0 +1,49 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"phoneBook/client"
+	"phoneBook/definition"
+)
+
+func ExampleClient_AddContact() {
+	c := client.New("http://localhost:8080")
+	c.User = "dani"
+	id, err := c.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"})
+	if errors.Is(err, client.ErrDuplicateContact) {
+		log.Fatal("the contact already exists")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("added", id)
+}
+
+func ExampleClient_List() {
+	c := client.New("http://localhost:8080")
+	contacts := c.List(context.Background(), 100)
+	for contacts.Next() {
+		fmt.Println(contacts.Contact().FirstName, contacts.Contact().Phone)
+	}
+	if err := contacts.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleClient_Update() {
+	c := client.New("http://localhost:8080")
+	ctx := context.Background()
+	contact, err := c.GetContact(ctx, "65a1b2c3d4e5f60718293a4b")
+	if err != nil {
+		log.Fatal(err)
+	}
+	contact.Phone = "0527654321"
+	// fails if someone else changed the contact since it was read
+	err = c.Update(ctx, contact.ID.Hex(), contact, contact.Version)
+	if errors.Is(err, client.ErrVersionConflict) {
+		log.Fatal("the contact was changed meanwhile, reload it")
+	}
+}
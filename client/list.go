@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"phoneBook/definition"
+	"strconv"
+)
+
+// ListPage returns page number page of the contacts, most recently added
+// first, with the total number of contacts and pages. pageSize is the server
+// default when 0.
+func (c *Client) ListPage(ctx context.Context, page int, pageSize int) (*definition.ContactPage, error) {
+	query := url.Values{"page": {strconv.Itoa(page)}}
+	if pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	}
+	var contactPage definition.ContactPage
+	if err := c.do(ctx, http.MethodGet, "/contact?"+query.Encode(), nil, nil, &contactPage); err != nil {
+		return nil, err
+	}
+	return &contactPage, nil
+}
+
+// ContactIterator walks over every contact, most recently added first,
+// fetching a page at a time:
+//
+//	contacts := c.List(ctx, 100)
+//	for contacts.Next() {
+//		fmt.Println(contacts.Contact().FirstName)
+//	}
+//	if err := contacts.Err(); err != nil {
+//		...
+//	}
+type ContactIterator struct {
+	client   *Client
+	ctx      context.Context
+	pageSize int
+	page     []*definition.Contact
+	current  *definition.Contact
+	cursor   string
+	done     bool
+	err      error
+}
+
+// List iterates over every contact, pageSize contacts per request or the
+// server default when 0. Pages are fetched by cursor, so contacts added or
+// deleted meanwhile don't shift the following pages.
+func (c *Client) List(ctx context.Context, pageSize int) *ContactIterator {
+	return &ContactIterator{client: c, ctx: ctx, pageSize: pageSize}
+}
+
+// Next advances to the next contact, fetching the next page when needed. It
+// returns false at the end of the contacts or on error.
+func (it *ContactIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.current = nil
+			return false
+		}
+		it.err = it.fetch()
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Contact returns the contact Next advanced to.
+func (it *ContactIterator) Contact() *definition.Contact {
+	return it.current
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *ContactIterator) Err() error {
+	return it.err
+}
+
+func (it *ContactIterator) fetch() error {
+	query := url.Values{"cursor": {it.cursor}, "count": {"false"}}
+	if it.pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(it.pageSize))
+	}
+	var page definition.ContactPage
+	if err := it.client.do(it.ctx, http.MethodGet, "/contact?"+query.Encode(), nil, nil, &page); err != nil {
+		return err
+	}
+	it.page = page.Items
+	it.cursor = page.NextCursor
+	it.done = page.NextCursor == ""
+	return nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/brianvoe/gofakeit"
+	"math/rand/v2"
+	"phoneBook/client"
+	"phoneBook/definition"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	opAdd    = "add"
+	opSearch = "search"
+	opList   = "list"
+)
+
+// operations are the kinds of requests, in report order.
+var operations = []string{opAdd, opSearch, opList}
+
+// weight is how often a kind of request is sent relative to the others.
+type weight struct {
+	op     string
+	weight int
+}
+
+// mix is the kinds of requests sent and their weights.
+type mix []weight
+
+// parseMix parses comma separated op=weight pairs, e.g. add=20,list=80.
+func parseMix(spec string) (mix, error) {
+	var parsed mix
+	for _, field := range strings.Split(spec, ",") {
+		op, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !slices.Contains(operations, op) {
+			return nil, fmt.Errorf("unknown request %q in mix. expected one of: %s", op, strings.Join(operations, ", "))
+		}
+		w, err := strconv.Atoi(value)
+		if !found || err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight of %s in mix. expected a non negative number", op)
+		}
+		if w > 0 {
+			parsed = append(parsed, weight{op: op, weight: w})
+		}
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("empty mix")
+	}
+	return parsed, nil
+}
+
+// pick draws a kind of request by weight.
+func (m mix) pick() string {
+	total := 0
+	for _, w := range m {
+		total += w.weight
+	}
+	n := rand.IntN(total)
+	for _, w := range m {
+		if n < w.weight {
+			return w.op
+		}
+		n -= w.weight
+	}
+	return m[len(m)-1].op
+}
+
+// bench sends a mix of requests to a server.
+type bench struct {
+	client      *client.Client
+	mix         mix
+	concurrency int
+	duration    time.Duration
+	// requests stops the bench after that many requests, if not 0.
+	requests int
+}
+
+// sample is the outcome of a request.
+type sample struct {
+	op      string
+	latency time.Duration
+	failed  bool
+}
+
+// report is the outcome of a bench of a server.
+type report struct {
+	Target     string             `json:"target"`
+	Elapsed    time.Duration      `json:"elapsed"`
+	Operations []*operationReport `json:"operations"`
+}
+
+// operationReport summarizes the latencies of a kind of request. Failed
+// requests are counted as errors, not in the latencies.
+type operationReport struct {
+	Name   string        `json:"name"`
+	Count  int           `json:"count"`
+	Errors int           `json:"errors"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+}
+
+// run sends requests from b.concurrency workers until the duration elapses,
+// the request limit is reached or ctx is done.
+func (b *bench) run(ctx context.Context) *report {
+	ctx, cancel := context.WithTimeout(ctx, b.duration)
+	defer cancel()
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []sample
+		sent    int
+		names   = &namePool{}
+	)
+	// claim reserves a request against the limit
+	claim := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if b.requests > 0 && sent >= b.requests {
+			return false
+		}
+		sent++
+		return true
+	}
+	started := time.Now()
+	for i := 0; i < b.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var local []sample
+			for ctx.Err() == nil && claim() {
+				op := b.mix.pick()
+				start := time.Now()
+				err := b.send(ctx, op, names)
+				if ctx.Err() != nil {
+					// cut short by the end of the bench
+					break
+				}
+				local = append(local, sample{op: op, latency: time.Since(start), failed: err != nil})
+			}
+			mu.Lock()
+			samples = append(samples, local...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return summarize(samples, time.Since(started))
+}
+
+// send sends a request of kind op.
+func (b *bench) send(ctx context.Context, op string, names *namePool) error {
+	switch op {
+	case opAdd:
+		contact := fakeContact()
+		_, err := b.client.AddContact(ctx, contact)
+		if err == nil {
+			names.add(contact.FirstName)
+		}
+		return err
+	case opSearch:
+		_, err := b.client.Search(ctx, client.SearchQuery{FirstName: names.pick(), PageSize: 10})
+		return err
+	default:
+		_, err := b.client.ListPage(ctx, 1+rand.IntN(10), 0)
+		return err
+	}
+}
+
+func summarize(samples []sample, elapsed time.Duration) *report {
+	r := &report{Elapsed: elapsed}
+	for _, op := range operations {
+		opReport := &operationReport{Name: op}
+		var latencies []time.Duration
+		for _, s := range samples {
+			if s.op != op {
+				continue
+			}
+			opReport.Count++
+			if s.failed {
+				opReport.Errors++
+				continue
+			}
+			latencies = append(latencies, s.latency)
+		}
+		if opReport.Count == 0 {
+			continue
+		}
+		slices.Sort(latencies)
+		opReport.P50 = percentile(latencies, 50)
+		opReport.P90 = percentile(latencies, 90)
+		opReport.P99 = percentile(latencies, 99)
+		opReport.Max = percentile(latencies, 100)
+		r.Operations = append(r.Operations, opReport)
+	}
+	return r
+}
+
+// percentile returns the nearest rank p percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+// namePool holds first names of added contacts, so searches find some.
+type namePool struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// maxPoolNames bounds the names kept.
+const maxPoolNames = 1000
+
+func (p *namePool) add(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.names) < maxPoolNames {
+		p.names = append(p.names, name)
+		return
+	}
+	p.names[rand.IntN(maxPoolNames)] = name
+}
+
+// pick returns an added name, or a random one before any contact was added.
+func (p *namePool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.names) == 0 {
+		return fakeName(gofakeit.FirstName)
+	}
+	return p.names[rand.IntN(len(p.names))]
+}
+
+func fakeContact() *definition.Contact {
+	return &definition.Contact{
+		FirstName: fakeName(gofakeit.FirstName),
+		LastName:  fakeName(gofakeit.LastName),
+		Phone:     gofakeit.Phone(),
+		Email:     strings.ToLower(gofakeit.Email()),
+		Company:   gofakeit.Company(),
+		Address:   gofakeit.Street() + ", " + gofakeit.City(),
+	}
+}
+
+// fakeName generates names until one is left with letters once stripped of
+// the others, as the server accepts letters only.
+func fakeName(generate func() string) string {
+	for {
+		name := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.Is(unicode.M, r) {
+				return r
+			}
+			return -1
+		}, generate())
+		if name != "" {
+			return name
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	parsed, err := parseMix("add=1, list=3,search=0")
+	assert.Nil(t, err)
+	assert.Equal(t, mix{{op: opAdd, weight: 1}, {op: opList, weight: 3}}, parsed)
+
+	for _, spec := range []string{"delete=1", "add", "add=-1", "add=0", "list=x"} {
+		_, err := parseMix(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 100))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := parseTargets("mongo=http://a:8080, http://b:8080")
+	assert.Nil(t, err)
+	assert.Equal(t, []target{{Name: "mongo", URL: "http://a:8080"}, {Name: "http://b:8080", URL: "http://b:8080"}}, targets)
+
+	_, err = parseTargets("mongo=a:8080")
+	assert.NotNil(t, err)
+}
+
+func TestRun(t *testing.T) {
+	var adds, lists atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+		adds.Add(1)
+		json.NewEncoder(w).Encode("Inserted ID: 65a1b2c3d4e5f60718293a4b")
+	})
+	mux.HandleFunc("GET /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+		lists.Add(1)
+		json.NewEncoder(w).Encode(definition.ContactPage{})
+	})
+	mux.HandleFunc("GET /api/v1/contact/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":"INTERNAL_ERROR"}`, http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var output bytes.Buffer
+	err := run([]string{"-targets", "test=" + server.URL, "-requests", "200", "-concurrency", "4", "-o", "json"}, &output)
+	assert.Nil(t, err)
+	var reports []*report
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &reports))
+	assert.Len(t, reports, 1)
+	assert.Equal(t, "test", reports[0].Target)
+	total := 0
+	for _, op := range reports[0].Operations {
+		total += op.Count
+		switch op.Name {
+		case opAdd:
+			assert.Equal(t, int(adds.Load()), op.Count)
+			assert.Zero(t, op.Errors)
+		case opList:
+			assert.Equal(t, int(lists.Load()), op.Count)
+		case opSearch:
+			assert.Equal(t, op.Count, op.Errors)
+		}
+	}
+	assert.Equal(t, 200, total)
+}
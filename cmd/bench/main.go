@@ -0,0 +1,137 @@
+// bench fires a mix of add, search and list requests at running phone book
+// servers and reports the latency percentiles of each kind of request, to
+// compare servers running on different storage backends or settings.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"phoneBook/client"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// target is a server to benchmark, named in the report.
+type target struct {
+	Name string
+	URL  string
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	targets := flags.String("targets", "http://localhost:8080", "comma separated servers to benchmark in turn, each optionally named as name=url, e.g. mongo=http://localhost:8080")
+	mixFlag := flags.String("mix", "add=20,search=30,list=50", "comma separated relative weights of the add, search and list requests")
+	concurrency := flags.Int("concurrency", 10, "number of requests in flight")
+	duration := flags.Duration("duration", 30*time.Second, "how long to benchmark each server")
+	requests := flags.Int("requests", 0, "stop after that many requests per server instead, 0 for no limit")
+	apiKey := flags.String("api-key", "", "API key sent in X-API-Key, to tell the bench apart from the server's rate limiter")
+	output := flags.String("o", "table", "output format: table or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	requestMix, err := parseMix(*mixFlag)
+	if err != nil {
+		return err
+	}
+	servers, err := parseTargets(*targets)
+	if err != nil {
+		return err
+	}
+	if *concurrency <= 0 {
+		return fmt.Errorf("invalid concurrency %d", *concurrency)
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output %q. expected table or json", *output)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var reports []*report
+	for _, server := range servers {
+		apiClient := client.New(server.URL)
+		apiClient.APIKey = *apiKey
+		apiClient.User = "bench"
+		// a retried request would be measured as one slow request
+		apiClient.MaxRetries = 0
+		b := &bench{
+			client:      apiClient,
+			mix:         requestMix,
+			concurrency: *concurrency,
+			duration:    *duration,
+			requests:    *requests,
+		}
+		if *output == "table" {
+			fmt.Fprintf(out, "benchmarking %s (%s)\n", server.Name, server.URL)
+		}
+		result := b.run(ctx)
+		result.Target = server.Name
+		reports = append(reports, result)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if *output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+	printReports(out, reports)
+	return nil
+}
+
+// parseTargets parses the comma separated name=url targets, an unnamed
+// target being named after its url.
+func parseTargets(targets string) ([]target, error) {
+	var parsed []target
+	for _, field := range strings.Split(targets, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, url, named := strings.Cut(field, "=")
+		if !named {
+			url = name
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("invalid target %q. expected an http or https url", field)
+		}
+		parsed = append(parsed, target{Name: name, URL: url})
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no target to benchmark")
+	}
+	return parsed, nil
+}
+
+func printReports(out io.Writer, reports []*report) {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TARGET\tREQUEST\tCOUNT\tERRORS\tREQ/S\tP50\tP90\tP99\tMAX")
+	for _, r := range reports {
+		for _, op := range r.Operations {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\n", r.Target, op.Name, op.Count, op.Errors,
+				float64(op.Count)/r.Elapsed.Seconds(), round(op.P50), round(op.P90), round(op.P99), round(op.Max))
+		}
+	}
+	table.Flush()
+}
+
+// round rounds latencies to a readable precision.
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
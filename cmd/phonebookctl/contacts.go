@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"phoneBook/client"
+	"phoneBook/definition"
+)
+
+// contactFlags are the contact fields set from the command line.
+type contactFlags struct {
+	firstName string
+	lastName  string
+	phone     string
+	email     string
+	company   string
+	jobTitle  string
+	address   string
+	notes     string
+}
+
+// contactFields maps the flags to the json names of the contact fields.
+var contactFields = []struct{ flag, field string }{
+	{"first-name", "firstName"},
+	{"last-name", "lastName"},
+	{"phone", "phone"},
+	{"email", "email"},
+	{"company", "company"},
+	{"job-title", "jobTitle"},
+	{"address", "address"},
+	{"notes", "notes"},
+}
+
+func (f *contactFlags) register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.firstName, "first-name", "", "first name")
+	flags.StringVar(&f.lastName, "last-name", "", "last name")
+	flags.StringVar(&f.phone, "phone", "", "phone number")
+	flags.StringVar(&f.email, "email", "", "email address")
+	flags.StringVar(&f.company, "company", "", "company")
+	flags.StringVar(&f.jobTitle, "job-title", "", "job title")
+	flags.StringVar(&f.address, "address", "", "address")
+	flags.StringVar(&f.notes, "notes", "", "notes")
+}
+
+func (f *contactFlags) contact() *definition.Contact {
+	return &definition.Contact{
+		FirstName: f.firstName,
+		LastName:  f.lastName,
+		Phone:     f.phone,
+		Email:     f.email,
+		Company:   f.company,
+		JobTitle:  f.jobTitle,
+		Address:   f.address,
+		Notes:     f.notes,
+	}
+}
+
+// patch holds the fields whose flags were given, an empty value clearing
+// the field.
+func (f *contactFlags) patch(flags *pflag.FlagSet) definition.ContactPatch {
+	patch := definition.ContactPatch{}
+	for _, field := range contactFields {
+		if !flags.Changed(field.flag) {
+			continue
+		}
+		value := flags.Lookup(field.flag).Value.String()
+		if value == "" {
+			patch[field.field] = nil
+		} else {
+			patch[field.field] = &value
+		}
+	}
+	return patch
+}
+
+func newAddCommand(c *cli) *cobra.Command {
+	var fields contactFlags
+	cmd := &cobra.Command{
+		Use:     "add",
+		Short:   "Add a contact",
+		Example: "  phonebookctl add --first-name Dani --last-name Cohen --phone 0521234567",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			id, err := apiClient.AddContact(cmd.Context(), fields.contact())
+			if err != nil {
+				return err
+			}
+			return printResult(cmd.OutOrStdout(), c.output, "added "+id, map[string]string{"id": id})
+		},
+	}
+	fields.register(cmd.Flags())
+	return cmd
+}
+
+func newListCommand(c *cli) *cobra.Command {
+	var pageSize, limit int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List contacts, most recently added first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			contacts := []*definition.Contact{}
+			iterator := apiClient.List(cmd.Context(), pageSize)
+			for (limit <= 0 || len(contacts) < limit) && iterator.Next() {
+				contacts = append(contacts, iterator.Contact())
+			}
+			if err := iterator.Err(); err != nil {
+				return err
+			}
+			return printContacts(cmd.OutOrStdout(), c.output, contacts)
+		},
+	}
+	cmd.Flags().IntVar(&pageSize, "page-size", 100, "contacts fetched per request")
+	cmd.Flags().IntVar(&limit, "limit", 0, "maximum number of contacts to list, all when 0")
+	return cmd
+}
+
+func newSearchCommand(c *cli) *cobra.Command {
+	var fields contactFlags
+	var pageSize int
+	cmd := &cobra.Command{
+		Use:     "search",
+		Short:   "Search contacts by exact field values",
+		Example: "  phonebookctl search --first-name Dani --address 'Tel Aviv'",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			contacts, err := apiClient.Search(cmd.Context(), client.SearchQuery{
+				FirstName: fields.firstName,
+				LastName:  fields.lastName,
+				Phone:     fields.phone,
+				Email:     fields.email,
+				Company:   fields.company,
+				JobTitle:  fields.jobTitle,
+				Address:   fields.address,
+				Notes:     fields.notes,
+				PageSize:  pageSize,
+			})
+			if err != nil {
+				return err
+			}
+			return printContacts(cmd.OutOrStdout(), c.output, contacts)
+		},
+	}
+	fields.register(cmd.Flags())
+	cmd.Flags().IntVar(&pageSize, "page-size", 0, "maximum number of contacts to return, the server default when 0")
+	return cmd
+}
+
+func newEditCommand(c *cli) *cobra.Command {
+	var fields contactFlags
+	var version int64
+	cmd := &cobra.Command{
+		Use:     "edit <id>",
+		Short:   "Edit the given fields of a contact, an empty value clears the field",
+		Example: "  phonebookctl edit 65a1b2c3d4e5f60718293a4b --phone 0527654321 --notes ''",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			if err := apiClient.Patch(cmd.Context(), args[0], fields.patch(cmd.Flags()), version); err != nil {
+				return err
+			}
+			return printResult(cmd.OutOrStdout(), c.output, "edited "+args[0], map[string]string{"id": args[0]})
+		},
+	}
+	fields.register(cmd.Flags())
+	cmd.Flags().Int64Var(&version, "version", 0, "fail if the contact version changed from this one")
+	return cmd
+}
+
+func newDeleteCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>...",
+		Short: "Delete contacts",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			deleted := []string{}
+			for _, id := range args {
+				if err := apiClient.Delete(cmd.Context(), id); err != nil {
+					return err
+				}
+				deleted = append(deleted, id)
+				if c.output == outputTable {
+					fmt.Fprintln(cmd.OutOrStdout(), "deleted", id)
+				}
+			}
+			if c.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), map[string][]string{"deleted": deleted})
+			}
+			return nil
+		},
+	}
+}
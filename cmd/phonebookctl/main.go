@@ -0,0 +1,15 @@
+// phonebookctl manages the contacts of a phone book server from the command
+// line, through its http API.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
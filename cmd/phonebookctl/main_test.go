@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"phoneBook/definition"
+	"strings"
+	"testing"
+)
+
+// run executes phonebookctl with args against server and returns its output.
+func run(t *testing.T, server *httptest.Server, stdin string, args ...string) (string, error) {
+	t.Setenv("PHONEBOOK_SERVER", server.URL)
+	// no default config file
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var output bytes.Buffer
+	root := newRootCommand()
+	root.SetArgs(args)
+	root.SetOut(&output)
+	root.SetErr(&output)
+	root.SetIn(strings.NewReader(stdin))
+	err := root.Execute()
+	return output.String(), err
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestList(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, definition.ContactPage{Items: []*definition.Contact{
+			{FirstName: "Dani", LastName: "Cohen", Phone: "0521234567", Version: 2},
+			{FirstName: "Noa", Phone: "0527654321", Version: 1},
+		}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("should print a table", func(t *testing.T) {
+		output, err := run(t, server, "", "list")
+		assert.Nil(t, err)
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		assert.Len(t, lines, 3)
+		assert.Regexp(t, `^ID\s+FIRST NAME\s+LAST NAME\s+PHONE`, lines[0])
+		assert.Regexp(t, `Dani\s+Cohen\s+0521234567`, lines[1])
+	})
+
+	t.Run("should print json and stop at the limit", func(t *testing.T) {
+		output, err := run(t, server, "", "list", "-o", "json", "--limit", "1")
+		assert.Nil(t, err)
+		var contacts []*definition.Contact
+		assert.Nil(t, json.Unmarshal([]byte(output), &contacts))
+		assert.Len(t, contacts, 1)
+		assert.Equal(t, "Dani", contacts[0].FirstName)
+	})
+
+	t.Run("should reject an unknown output", func(t *testing.T) {
+		_, err := run(t, server, "", "list", "-o", "xml")
+		assert.ErrorContains(t, err, "unknown output")
+	})
+}
+
+func TestEdit(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /api/v1/contact/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var patch definition.ContactPatch
+		json.NewDecoder(r.Body).Decode(&patch)
+		assert.Equal(t, "0527654321", *patch["phone"])
+		assert.Contains(t, patch, "notes")
+		assert.Nil(t, patch["notes"])
+		assert.NotContains(t, patch, "firstName")
+		assert.Equal(t, "2", r.Header.Get("If-Match"))
+		writeJSON(w, "edited 1 document successfully")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	output, err := run(t, server, "", "edit", "1", "--phone", "0527654321", "--notes", "", "--version", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "edited 1\n", output)
+}
+
+func TestImport(t *testing.T) {
+	var added []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+		var contact definition.Contact
+		json.NewDecoder(r.Body).Decode(&contact)
+		assert.True(t, contact.ID.IsZero())
+		if contact.Phone == "0521234567" {
+			w.WriteHeader(http.StatusConflict)
+			writeJSON(w, map[string]string{"code": "DUPLICATE_CONTACT", "message": "contact already exists"})
+			return
+		}
+		added = append(added, contact.FirstName+" "+contact.LastName)
+		writeJSON(w, "Inserted ID: 65a1b2c3d4e5f60718293a4b")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	csvContacts := "phone,lastName,firstName,_id\n" +
+		"0521234567,Cohen,Dani,65a1b2c3d4e5f60718293a4b\n" +
+		"0527654321,Levi,Noa,65a1b2c3d4e5f60718293a4c\n"
+	path := filepath.Join(t.TempDir(), "contacts.csv")
+	assert.Nil(t, os.WriteFile(path, []byte(csvContacts), 0o600))
+
+	output, err := run(t, server, "", "import", path, "--skip-duplicates")
+	assert.Nil(t, err)
+	assert.Equal(t, "added 1, skipped 1, failed 0\n", output)
+	assert.Equal(t, []string{"Noa Levi"}, added)
+
+	added = nil
+	output, err = run(t, server, `[{"firstName": "Dani", "phone": "0521234567"}]`, "import", "-", "-o", "json")
+	assert.ErrorContains(t, err, "1 contacts failed to import")
+	assert.Contains(t, output, `"failed": 1`)
+	assert.Empty(t, added)
+}
+
+func TestSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("server: http://file:8080\napiKey: file-key\nuser: file-user\n"), 0o600))
+	t.Setenv("PHONEBOOK_SERVER", "")
+	t.Setenv("PHONEBOOK_API_KEY", "env-key")
+	t.Setenv("PHONEBOOK_USER", "")
+
+	s, err := (&cli{configPath: path, user: "flag-user"}).settings()
+	assert.Nil(t, err)
+	assert.Equal(t, settings{Server: "http://file:8080", APIKey: "env-key", User: "flag-user"}, s)
+
+	_, err = (&cli{configPath: filepath.Join(t.TempDir(), "missing.yaml")}).settings()
+	assert.NotNil(t, err)
+}
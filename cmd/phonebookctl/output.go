@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"phoneBook/definition"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printContacts writes contacts as a table or a JSON array.
+func printContacts(w io.Writer, output string, contacts []*definition.Contact) error {
+	if output == outputJSON {
+		return printJSON(w, contacts)
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tFIRST NAME\tLAST NAME\tPHONE\tADDRESS\tVERSION")
+	for _, contact := range contacts {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%d\n",
+			contact.ID.Hex(), contact.FirstName, contact.LastName, contact.Phone, contact.Address, contact.Version)
+	}
+	return table.Flush()
+}
+
+// printResult writes the outcome of a command, as a line of text or as JSON.
+func printResult(w io.Writer, output string, text string, result interface{}) error {
+	if output == outputJSON {
+		return printJSON(w, result)
+	}
+	_, err := fmt.Fprintln(w, text)
+	return err
+}
+
+func printJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"phoneBook/client"
+)
+
+// cli holds the global flags shared by every command.
+type cli struct {
+	configPath string
+	server     string
+	apiKey     string
+	user       string
+	output     string
+}
+
+func newRootCommand() *cobra.Command {
+	c := &cli{}
+	root := &cobra.Command{
+		Use:           "phonebookctl",
+		Short:         "Manage the contacts of a phone book server",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if c.output != outputTable && c.output != outputJSON {
+				return fmt.Errorf("unknown output %q. expected %s or %s", c.output, outputTable, outputJSON)
+			}
+			return nil
+		},
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&c.configPath, "config", "", "config file (default $XDG_CONFIG_HOME/phonebookctl/config.yaml)")
+	flags.StringVar(&c.server, "server", "", "phone book server address, e.g. http://localhost:8080 (env PHONEBOOK_SERVER)")
+	flags.StringVar(&c.apiKey, "api-key", "", "API key sent in X-API-Key (env PHONEBOOK_API_KEY)")
+	flags.StringVar(&c.user, "user", "", "user the changes are recorded as made by (env PHONEBOOK_USER)")
+	flags.StringVarP(&c.output, "output", "o", outputTable, "output format: table or json")
+	root.AddCommand(
+		newAddCommand(c),
+		newListCommand(c),
+		newSearchCommand(c),
+		newEditCommand(c),
+		newDeleteCommand(c),
+		newImportCommand(c),
+		newExportCommand(c),
+		newTUICommand(c),
+	)
+	return root
+}
+
+// client returns an API client for the server of the settings.
+func (c *cli) client() (*client.Client, error) {
+	s, err := c.settings()
+	if err != nil {
+		return nil, err
+	}
+	apiClient := client.New(s.Server)
+	apiClient.APIKey = s.APIKey
+	apiClient.User = s.User
+	return apiClient, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// settings tell the CLI which server to talk to and how.
+type settings struct {
+	Server string `yaml:"server"`
+	APIKey string `yaml:"apiKey"`
+	User   string `yaml:"user"`
+}
+
+// settings layers the flags over the environment variables, them over the
+// config file and all over the defaults.
+func (c *cli) settings() (settings, error) {
+	s, err := loadSettingsFile(c.configPath)
+	if err != nil {
+		return settings{}, err
+	}
+	for _, layer := range []settings{
+		{Server: os.Getenv("PHONEBOOK_SERVER"), APIKey: os.Getenv("PHONEBOOK_API_KEY"), User: os.Getenv("PHONEBOOK_USER")},
+		{Server: c.server, APIKey: c.apiKey, User: c.user},
+	} {
+		s.override(layer)
+	}
+	if s.Server == "" {
+		s.Server = defaultServer
+	}
+	return s, nil
+}
+
+// override replaces the settings set in layer.
+func (s *settings) override(layer settings) {
+	if layer.Server != "" {
+		s.Server = layer.Server
+	}
+	if layer.APIKey != "" {
+		s.APIKey = layer.APIKey
+	}
+	if layer.User != "" {
+		s.User = layer.User
+	}
+}
+
+// loadSettingsFile reads the config file at path, or the default one when
+// path is empty. Only a missing default file is not an error.
+func loadSettingsFile(path string) (settings, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return settings{}, nil
+		}
+		path = filepath.Join(dir, "phonebookctl", "config.yaml")
+	}
+	var s settings
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(content, &s); err != nil {
+		return s, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"os"
+	"path/filepath"
+	"phoneBook/client"
+	"phoneBook/definition"
+	"strings"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// csvColumns are the columns of exported CSV files, by contact field. Imported
+// files may hold any of them in any order, the _id column is ignored.
+var csvColumns = []string{"_id", "firstName", "lastName", "phone", "address", "notes", "email", "company", "jobTitle"}
+
+func newImportCommand(c *cli) *cobra.Command {
+	var format string
+	var skipDuplicates bool
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Add the contacts of a JSON or CSV file, - for stdin",
+		Long: "Add the contacts of a JSON array or a CSV file with a header row naming the contact fields,\n" +
+			"like those written by export. Contacts get new IDs. Every contact is tried and the failures reported.",
+		Example: "  phonebookctl import contacts.csv --skip-duplicates",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := transferFormat(format, args[0])
+			if err != nil {
+				return err
+			}
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			input := cmd.InOrStdin()
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				input = file
+			}
+			contacts, err := readContacts(input, format)
+			if err != nil {
+				return err
+			}
+			var added, skipped, failed int
+			for i, contact := range contacts {
+				contact.ID = primitive.NilObjectID
+				_, err := apiClient.AddContact(cmd.Context(), contact)
+				switch {
+				case err == nil:
+					added++
+				case skipDuplicates && errors.Is(err, client.ErrDuplicateContact):
+					skipped++
+				default:
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "contact %d (%s %s): %v\n", i+1, contact.FirstName, contact.LastName, err)
+				}
+			}
+			summary := map[string]int{"added": added, "skipped": skipped, "failed": failed}
+			text := fmt.Sprintf("added %d, skipped %d, failed %d", added, skipped, failed)
+			if err := printResult(cmd.OutOrStdout(), c.output, text, summary); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d contacts failed to import", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", "json or csv, by the file extension when not given")
+	cmd.Flags().BoolVar(&skipDuplicates, "skip-duplicates", false, "skip contacts that already exist instead of failing them")
+	return cmd
+}
+
+func newExportCommand(c *cli) *cobra.Command {
+	var format, path string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every contact as JSON or CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := transferFormat(format, path)
+			if err != nil {
+				return err
+			}
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			contacts := []*definition.Contact{}
+			iterator := apiClient.List(cmd.Context(), 100)
+			for iterator.Next() {
+				contacts = append(contacts, iterator.Contact())
+			}
+			if err := iterator.Err(); err != nil {
+				return err
+			}
+			if path == "" {
+				return writeContacts(cmd.OutOrStdout(), format, contacts)
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			if err := writeContacts(file, format, contacts); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", "json or csv, by the file extension when not given")
+	cmd.Flags().StringVar(&path, "file", "", "file to write, stdout when not given")
+	return cmd
+}
+
+// transferFormat is the format given, or the one of the file extension,
+// JSON when there's none.
+func transferFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case formatJSON, formatCSV:
+		return format, nil
+	case "", "-":
+		return formatJSON, nil
+	}
+	return "", fmt.Errorf("unknown format %q. expected %s or %s", format, formatJSON, formatCSV)
+}
+
+func readContacts(r io.Reader, format string) ([]*definition.Contact, error) {
+	if format == formatJSON {
+		var contacts []*definition.Contact
+		if err := json.NewDecoder(r).Decode(&contacts); err != nil {
+			return nil, fmt.Errorf("invalid JSON contacts: %w", err)
+		}
+		return contacts, nil
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV contacts: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	contacts := make([]*definition.Contact, 0, len(records)-1)
+	for _, record := range records[1:] {
+		contact := &definition.Contact{}
+		for i, column := range header {
+			if field := contactField(contact, column); field != nil {
+				*field = record[i]
+			}
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
+
+func writeContacts(w io.Writer, format string, contacts []*definition.Contact) error {
+	if format == formatJSON {
+		return printJSON(w, contacts)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(csvColumns)
+	for _, contact := range contacts {
+		record := []string{contact.ID.Hex()}
+		for _, column := range csvColumns[1:] {
+			record = append(record, *contactField(contact, column))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// contactField points to the string field of contact with the json name
+// column, nil for other columns.
+func contactField(contact *definition.Contact, column string) *string {
+	switch column {
+	case "firstName":
+		return &contact.FirstName
+	case "lastName":
+		return &contact.LastName
+	case "phone":
+		return &contact.Phone
+	case "address":
+		return &contact.Address
+	case "notes":
+		return &contact.Notes
+	case "email":
+		return &contact.Email
+	case "company":
+		return &contact.Company
+	case "jobTitle":
+		return &contact.JobTitle
+	}
+	return nil
+}
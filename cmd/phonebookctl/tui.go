@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"phoneBook/client"
+	"phoneBook/definition"
+	"strings"
+)
+
+func newTUICommand(c *cli) *cobra.Command {
+	var pageSize int
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Browse, search, edit and delete contacts interactively",
+		Long: "Browse the contacts page by page, type / to search them as you type and edit or delete the selected one.\n" +
+			"Keys: ↑/↓ select, n/p next and previous page, / search, e edit, d delete, r reload, q quit.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient, err := c.client()
+			if err != nil {
+				return err
+			}
+			model := newTUIModel(cmd.Context(), apiClient, pageSize)
+			_, err = tea.NewProgram(model, tea.WithAltScreen(), tea.WithContext(cmd.Context()),
+				tea.WithInput(cmd.InOrStdin()), tea.WithOutput(cmd.OutOrStdout())).Run()
+			return err
+		},
+	}
+	cmd.Flags().IntVar(&pageSize, "page-size", 20, "contacts per page")
+	return cmd
+}
+
+// tuiMode is what the keys currently act on.
+type tuiMode int
+
+const (
+	modeBrowse tuiMode = iota
+	modeSearch
+	modeEdit
+	modeConfirmDelete
+)
+
+// Messages the commands of the TUI answer with.
+type (
+	pageMsg struct {
+		seq  int
+		page *definition.ContactPage
+	}
+	searchMsg struct {
+		seq      int
+		contacts []*definition.Contact
+	}
+	changedMsg struct {
+		status string
+	}
+	errMsg struct {
+		err error
+	}
+)
+
+// tuiModel browses either the pages of every contact or the results of the
+// search text typed.
+type tuiModel struct {
+	ctx      context.Context
+	client   *client.Client
+	pageSize int
+
+	mode       tuiMode
+	table      table.Model
+	contacts   []*definition.Contact
+	page       int
+	totalPages int64
+	search     textinput.Model
+	// loadSeq numbers the pages and searches requested, so the contacts of a
+	// request overtaken by a later one, e.g. while typing, are dropped.
+	loadSeq int
+	form    []textinput.Model
+	focused int
+	editing *definition.Contact
+	status  string
+}
+
+func newTUIModel(ctx context.Context, apiClient *client.Client, pageSize int) *tuiModel {
+	search := textinput.New()
+	search.Prompt = "/ "
+	search.Placeholder = "name, address or notes"
+	return &tuiModel{
+		ctx:      ctx,
+		client:   apiClient,
+		pageSize: pageSize,
+		page:     1,
+		search:   search,
+		table: table.New(
+			table.WithColumns([]table.Column{
+				{Title: "First name", Width: 16},
+				{Title: "Last name", Width: 16},
+				{Title: "Phone", Width: 14},
+				{Title: "Address", Width: 30},
+				{Title: "Notes", Width: 30},
+			}),
+			table.WithFocused(true),
+			table.WithHeight(pageSize),
+		),
+	}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return m.loadPage(m.page)
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// leave room for the title, search, header and status lines
+		m.table.SetHeight(max(msg.Height-6, 3))
+		return m, nil
+	case pageMsg:
+		if msg.seq != m.loadSeq {
+			return m, nil
+		}
+		m.page = msg.page.Page
+		m.totalPages = 0
+		if msg.page.TotalPages != nil {
+			m.totalPages = *msg.page.TotalPages
+		}
+		m.setContacts(msg.page.Items)
+		return m, nil
+	case searchMsg:
+		if msg.seq == m.loadSeq {
+			m.setContacts(msg.contacts)
+		}
+		return m, nil
+	case changedMsg:
+		m.status = msg.status
+		return m, m.reload()
+	case errMsg:
+		m.status = "error: " + msg.err.Error()
+		return m, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case modeSearch:
+			return m.updateSearch(msg)
+		case modeEdit:
+			return m.updateEdit(msg)
+		case modeConfirmDelete:
+			return m.updateConfirmDelete(msg)
+		}
+		return m.updateBrowse(msg)
+	}
+	// cursor blinks of the focused input
+	var cmd tea.Cmd
+	switch m.mode {
+	case modeSearch:
+		m.search, cmd = m.search.Update(msg)
+	case modeEdit:
+		m.form[m.focused], cmd = m.form[m.focused].Update(msg)
+	}
+	return m, cmd
+}
+
+func (m *tuiModel) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "/":
+		m.mode = modeSearch
+		return m, m.search.Focus()
+	case "esc":
+		if m.search.Value() == "" {
+			return m, nil
+		}
+		m.search.SetValue("")
+		return m, m.reload()
+	case "n", "right":
+		if m.search.Value() != "" || (m.totalPages > 0 && int64(m.page) >= m.totalPages) {
+			return m, nil
+		}
+		return m, m.loadPage(m.page + 1)
+	case "p", "left":
+		if m.search.Value() != "" || m.page <= 1 {
+			return m, nil
+		}
+		return m, m.loadPage(m.page - 1)
+	case "r":
+		return m, m.reload()
+	case "e":
+		if contact := m.selected(); contact != nil {
+			return m, m.startEdit(contact)
+		}
+		return m, nil
+	case "d":
+		if contact := m.selected(); contact != nil {
+			m.mode = modeConfirmDelete
+			m.status = fmt.Sprintf("delete %s %s? (y/n)", contact.FirstName, contact.LastName)
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m *tuiModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "esc":
+		m.mode = modeBrowse
+		m.search.Blur()
+		if msg.String() == "esc" {
+			m.search.SetValue("")
+			return m, m.reload()
+		}
+		return m, nil
+	case "up", "down":
+		var cmd tea.Cmd
+		m.table, cmd = m.table.Update(msg)
+		return m, cmd
+	}
+	previous := m.search.Value()
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	if m.search.Value() == previous {
+		return m, cmd
+	}
+	return m, tea.Batch(cmd, m.reload())
+}
+
+func (m *tuiModel) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		m.status = "edit cancelled"
+		return m, nil
+	case "enter":
+		m.mode = modeBrowse
+		return m, m.save()
+	case "tab", "down", "shift+tab", "up":
+		m.form[m.focused].Blur()
+		step := 1
+		if msg.String() == "shift+tab" || msg.String() == "up" {
+			step = len(m.form) - 1
+		}
+		m.focused = (m.focused + step) % len(m.form)
+		return m, m.form[m.focused].Focus()
+	}
+	var cmd tea.Cmd
+	m.form[m.focused], cmd = m.form[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m *tuiModel) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeBrowse
+	contact := m.selected()
+	if msg.String() != "y" || contact == nil {
+		m.status = "delete cancelled"
+		return m, nil
+	}
+	m.status = ""
+	return m, func() tea.Msg {
+		if err := m.client.Delete(m.ctx, contact.ID.Hex()); err != nil {
+			return errMsg{err}
+		}
+		return changedMsg{fmt.Sprintf("deleted %s %s", contact.FirstName, contact.LastName)}
+	}
+}
+
+// startEdit opens a form of the fields of contact.
+func (m *tuiModel) startEdit(contact *definition.Contact) tea.Cmd {
+	m.mode = modeEdit
+	m.editing = contact
+	m.form = make([]textinput.Model, len(contactFields))
+	for i, field := range contactFields {
+		input := textinput.New()
+		input.Prompt = fmt.Sprintf("%-12s ", field.flag+":")
+		input.SetValue(*contactField(contact, field.field))
+		m.form[i] = input
+	}
+	m.focused = 0
+	return m.form[0].Focus()
+}
+
+// save patches the fields changed in the form, if the contact wasn't changed
+// by someone else meanwhile.
+func (m *tuiModel) save() tea.Cmd {
+	contact := m.editing
+	patch := definition.ContactPatch{}
+	for i, field := range contactFields {
+		value := m.form[i].Value()
+		if value == *contactField(contact, field.field) {
+			continue
+		}
+		if value == "" {
+			patch[field.field] = nil
+		} else {
+			patch[field.field] = &value
+		}
+	}
+	if len(patch) == 0 {
+		m.status = "nothing changed"
+		return nil
+	}
+	return func() tea.Msg {
+		if err := m.client.Patch(m.ctx, contact.ID.Hex(), patch, contact.Version); err != nil {
+			return errMsg{err}
+		}
+		return changedMsg{fmt.Sprintf("saved %s %s", contact.FirstName, contact.LastName)}
+	}
+}
+
+// reload fetches the contacts shown again, the search results or the
+// current page.
+func (m *tuiModel) reload() tea.Cmd {
+	text := strings.TrimSpace(m.search.Value())
+	if text == "" {
+		return m.loadPage(m.page)
+	}
+	m.loadSeq++
+	seq := m.loadSeq
+	return func() tea.Msg {
+		contacts, err := m.client.SearchText(m.ctx, text, m.pageSize)
+		if err != nil {
+			return errMsg{err}
+		}
+		return searchMsg{seq: seq, contacts: contacts}
+	}
+}
+
+func (m *tuiModel) loadPage(page int) tea.Cmd {
+	m.loadSeq++
+	seq := m.loadSeq
+	return func() tea.Msg {
+		contactPage, err := m.client.ListPage(m.ctx, page, m.pageSize)
+		if err != nil {
+			return errMsg{err}
+		}
+		return pageMsg{seq: seq, page: contactPage}
+	}
+}
+
+func (m *tuiModel) setContacts(contacts []*definition.Contact) {
+	m.contacts = contacts
+	rows := make([]table.Row, len(contacts))
+	for i, contact := range contacts {
+		rows[i] = table.Row{contact.FirstName, contact.LastName, contact.Phone, contact.Address, contact.Notes}
+	}
+	m.table.SetRows(rows)
+	// the table moves its cursor before the first row when it has none
+	if cursor := m.table.Cursor(); cursor < 0 || cursor >= len(rows) {
+		m.table.SetCursor(max(min(cursor, len(rows)-1), 0))
+	}
+}
+
+func (m *tuiModel) selected() *definition.Contact {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.contacts) {
+		return nil
+	}
+	return m.contacts[cursor]
+}
+
+func (m *tuiModel) View() string {
+	var view strings.Builder
+	if m.search.Value() != "" || m.mode == modeSearch {
+		fmt.Fprintf(&view, "Search results\n%s\n", m.search.View())
+	} else if m.totalPages > 0 {
+		fmt.Fprintf(&view, "Contacts - page %d of %d\n\n", m.page, m.totalPages)
+	} else {
+		fmt.Fprintf(&view, "Contacts - page %d\n\n", m.page)
+	}
+	if m.mode == modeEdit {
+		for _, input := range m.form {
+			view.WriteString(input.View() + "\n")
+		}
+		view.WriteString("\ntab next field, enter save, esc cancel\n")
+		return view.String()
+	}
+	view.WriteString(m.table.View() + "\n")
+	if m.status != "" {
+		view.WriteString(m.status + "\n")
+	} else {
+		view.WriteString("↑/↓ select, n/p page, / search, e edit, d delete, r reload, q quit\n")
+	}
+	return view.String()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/charmbracelet/bubbles/cursor"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/client"
+	"phoneBook/definition"
+	"strconv"
+	"testing"
+)
+
+// update feeds msg to the model and runs the commands it returns, feeding
+// back the messages of the phone book requests. Commands of other messages,
+// like cursor blinks, must not be returned.
+func update(m *tuiModel, msg tea.Msg) {
+	_, cmd := m.Update(msg)
+	runCmd(m, cmd)
+}
+
+func runCmd(m *tuiModel, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			runCmd(m, cmd)
+		}
+	case pageMsg, searchMsg, changedMsg, errMsg:
+		update(m, msg)
+	}
+}
+
+func key(keys string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)}
+}
+
+func TestTUI(t *testing.T) {
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	contacts := []*definition.Contact{
+		{ID: ids[0], FirstName: "Dani", LastName: "Cohen", Phone: "0521234567", Version: 1},
+		{ID: ids[1], FirstName: "Noa", LastName: "Levi", Phone: "0527654321", Version: 4},
+		{ID: ids[2], FirstName: "Avi", LastName: "Mor", Phone: "0501234567", Version: 1},
+	}
+	var patched definition.ContactPatch
+	var patchVersion string
+	var deleted string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/contact", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		totalPages := int64(2)
+		items := contacts[:2]
+		if page == 2 {
+			items = contacts[2:]
+		}
+		writeJSON(w, definition.ContactPage{Items: items, Page: page, PageSize: 2, TotalPages: &totalPages})
+	})
+	mux.HandleFunc("GET /api/v1/contact/search/text", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "noa" {
+			writeJSON(w, contacts[1:2])
+			return
+		}
+		writeJSON(w, []*definition.Contact{})
+	})
+	mux.HandleFunc("PATCH /api/v1/contact/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&patched)
+		patchVersion = r.Header.Get("If-Match")
+		writeJSON(w, "edited 1 document successfully")
+	})
+	mux.HandleFunc("DELETE /api/v1/contact/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+		deleted = r.PathValue("id")
+		writeJSON(w, "deleted 1 document successfully")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	newModel := func() *tuiModel {
+		m := newTUIModel(context.Background(), client.New(server.URL), 2)
+		m.search.Cursor.SetMode(cursor.CursorStatic)
+		runCmd(m, m.Init())
+		return m
+	}
+
+	t.Run("should page through the contacts", func(t *testing.T) {
+		m := newModel()
+		assert.Contains(t, m.View(), "page 1 of 2")
+		assert.Contains(t, m.View(), "Dani")
+		assert.NotContains(t, m.View(), "Avi")
+
+		update(m, key("n"))
+		assert.Contains(t, m.View(), "page 2 of 2")
+		assert.Contains(t, m.View(), "Avi")
+
+		update(m, key("n"))
+		assert.Equal(t, 2, m.page)
+		update(m, key("p"))
+		assert.Equal(t, 1, m.page)
+	})
+
+	t.Run("should search while typing", func(t *testing.T) {
+		m := newModel()
+		update(m, key("/"))
+		for _, r := range "noa" {
+			update(m, key(string(r)))
+		}
+		assert.Equal(t, modeSearch, m.mode)
+		assert.Len(t, m.contacts, 1)
+		assert.Equal(t, "Noa", m.selected().FirstName)
+
+		update(m, tea.KeyMsg{Type: tea.KeyEsc})
+		assert.Equal(t, modeBrowse, m.mode)
+		assert.Len(t, m.contacts, 2)
+	})
+
+	t.Run("should drop the contacts of an overtaken request", func(t *testing.T) {
+		m := newModel()
+		stale := m.reload()
+		m.search.SetValue("noa")
+		runCmd(m, m.reload())
+		runCmd(m, stale)
+		assert.Len(t, m.contacts, 1)
+	})
+
+	t.Run("should patch the edited fields of the selected contact", func(t *testing.T) {
+		m := newModel()
+		update(m, tea.KeyMsg{Type: tea.KeyDown})
+		m.Update(key("e"))
+		assert.Equal(t, modeEdit, m.mode)
+		m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		m.form[m.focused].Cursor.SetMode(cursor.CursorStatic)
+		m.form[m.focused].SetValue("0520000000")
+		update(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+		phone := "0520000000"
+		assert.Equal(t, definition.ContactPatch{"phone": &phone}, patched)
+		assert.Equal(t, "4", patchVersion)
+		assert.Equal(t, modeBrowse, m.mode)
+		assert.Contains(t, m.View(), "saved Noa Levi")
+	})
+
+	t.Run("should delete the selected contact once confirmed", func(t *testing.T) {
+		m := newModel()
+		update(m, key("d"))
+		update(m, key("n"))
+		assert.Empty(t, deleted)
+		assert.Contains(t, m.View(), "delete cancelled")
+
+		update(m, key("d"))
+		update(m, key("y"))
+		assert.Equal(t, ids[0].Hex(), deleted)
+		assert.Contains(t, m.View(), "deleted Dani Cohen")
+	})
+}
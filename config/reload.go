@@ -0,0 +1,135 @@
+package config
+
+import (
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// Tunable holds the settings that can be reloaded while the service runs.
+type Tunable struct {
+	LimitPerPage       int64
+	MaxPageSize        int64
+	RateLimitPerSecond float64
+	RateLimitBurst     int
+	LogLevel           string
+}
+
+var tunables atomic.Pointer[Tunable]
+
+// Tunables returns the reloadable settings in use.
+func Tunables() Tunable {
+	if current := tunables.Load(); current != nil {
+		return *current
+	}
+	return Static.Tunables()
+}
+
+// Tunables returns the reloadable settings of the configuration.
+func (c Config) Tunables() Tunable {
+	return Tunable{
+		LimitPerPage:       c.LimitPerPage,
+		MaxPageSize:        c.MaxPageSize,
+		RateLimitPerSecond: c.RateLimitPerSecond,
+		RateLimitBurst:     c.RateLimitBurst,
+		LogLevel:           c.LogLevel,
+	}
+}
+
+// SetTunables puts the reloadable settings in use, applying the log level.
+func SetTunables(tunable Tunable) {
+	if level, err := logrus.ParseLevel(tunable.LogLevel); err == nil {
+		logrus.SetLevel(level)
+	}
+	tunables.Store(&tunable)
+}
+
+// ReloadOnSignal reloads the tunable settings from the config file at path and
+// the environment whenever the process gets SIGHUP. Invalid configurations are
+// logged and the settings in use are kept.
+func ReloadOnSignal(path string) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			cfg, err := Load(path)
+			if err != nil {
+				logrus.WithError(err).Error("Could not reload configuration, keeping the current one")
+				continue
+			}
+			SetTunables(cfg.Tunables())
+			logrus.Info("Configuration reloaded")
+		}
+	}()
+}
+
+// Effective returns the configuration in use, keyed like the config file, with
+// the passwords of the Mongo, Redis and events broker URLs and the secrets
+// masked.
+func Effective() map[string]interface{} {
+	cfg := Static
+	tunable := Tunables()
+	cfg.LimitPerPage = tunable.LimitPerPage
+	cfg.MaxPageSize = tunable.MaxPageSize
+	cfg.RateLimitPerSecond = tunable.RateLimitPerSecond
+	cfg.RateLimitBurst = tunable.RateLimitBurst
+	cfg.LogLevel = tunable.LogLevel
+	cfg.MongoURI = redactURI(cfg.MongoURI)
+	cfg.RedisURI = redactURI(cfg.RedisURI)
+	cfg.EventsURL = redactURI(cfg.EventsURL)
+	if cfg.BackupS3SecretKey != "" {
+		cfg.BackupS3SecretKey = "xxxxx"
+	}
+	if cfg.AWSSecretAccessKey != "" {
+		cfg.AWSSecretAccessKey = "xxxxx"
+	}
+	if cfg.AWSSessionToken != "" {
+		cfg.AWSSessionToken = "xxxxx"
+	}
+	if cfg.SearchPassword != "" {
+		cfg.SearchPassword = "xxxxx"
+	}
+	if cfg.GoogleClientSecret != "" {
+		cfg.GoogleClientSecret = "xxxxx"
+	}
+	if cfg.LDAPBindPassword != "" {
+		cfg.LDAPBindPassword = "xxxxx"
+	}
+	if cfg.SCIMToken != "" {
+		cfg.SCIMToken = "xxxxx"
+	}
+	if cfg.AdminToken != "" {
+		cfg.AdminToken = "xxxxx"
+	}
+	if len(cfg.APIKeys) > 0 {
+		cfg.APIKeys = []string{"xxxxx"}
+	}
+	if cfg.CRMToken != "" {
+		cfg.CRMToken = "xxxxx"
+	}
+	if cfg.CRMClientSecret != "" {
+		cfg.CRMClientSecret = "xxxxx"
+	}
+	if cfg.SMTPPassword != "" {
+		cfg.SMTPPassword = "xxxxx"
+	}
+	if cfg.FieldEncryptionKey != "" {
+		cfg.FieldEncryptionKey = "xxxxx"
+	}
+	// going through yaml writes durations as text, e.g. 5s
+	content, _ := yaml.Marshal(cfg)
+	effective := map[string]interface{}{}
+	_ = yaml.Unmarshal(content, &effective)
+	return effective
+}
+
+func redactURI(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil {
+		return parsed.Redacted()
+	}
+	return uri
+}
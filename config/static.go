@@ -1,22 +1,687 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
 	"github.com/caarlos0/env"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"phoneBook/phonenumber"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var Static = struct {
-	HTTPServerPort      string `env:"HTTP_SERVER_PORT" envDefault:":8080"`
-	LimitPerPage        int64  `env:"LIMIT_PER_PAGE" envDefault:"10"`
-	MongoURI            string `env:"MONGO_URI" envDefault:"mongodb://mongo:27017"`
-	MongoDBName         string `env:"MONGO_DB" envDefault:"phoneBook"`
-	MongoCollectionName string `env:"MONGO_COLLECTION" envDefault:"contacts"`
-	MaxSizeProperty     int    `env:"MAX_SIZE_PROPERTY" envDefault:"100"`
-}{}
-
-func init() {
-	err := env.Parse(&Static)
+// Config is the service configuration. Load layers the config file over the
+// defaults and the environment variables over both. The Tunable settings can
+// also be reloaded while the service runs.
+type Config struct {
+	HTTPServerPort              string        `env:"HTTP_SERVER_PORT" yaml:"httpServerPort" toml:"httpServerPort"`
+	HTTPReadTimeout             time.Duration `env:"HTTP_READ_TIMEOUT" yaml:"httpReadTimeout" toml:"httpReadTimeout"`
+	HTTPWriteTimeout            time.Duration `env:"HTTP_WRITE_TIMEOUT" yaml:"httpWriteTimeout" toml:"httpWriteTimeout"`
+	HTTPIdleTimeout             time.Duration `env:"HTTP_IDLE_TIMEOUT" yaml:"httpIdleTimeout" toml:"httpIdleTimeout"`
+	TLSCertFile                 string        `env:"TLS_CERT_FILE" yaml:"tlsCertFile" toml:"tlsCertFile"`
+	TLSKeyFile                  string        `env:"TLS_KEY_FILE" yaml:"tlsKeyFile" toml:"tlsKeyFile"`
+	AutocertDomains             []string      `env:"AUTOCERT_DOMAINS" envSeparator:"," yaml:"autocertDomains" toml:"autocertDomains"`
+	AutocertCacheDir            string        `env:"AUTOCERT_CACHE_DIR" yaml:"autocertCacheDir" toml:"autocertCacheDir"`
+	HTTPRedirectPort            string        `env:"HTTP_REDIRECT_PORT" yaml:"httpRedirectPort" toml:"httpRedirectPort"`
+	ShutdownTimeout             time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+	RequestTimeout              time.Duration `env:"REQUEST_TIMEOUT" yaml:"requestTimeout" toml:"requestTimeout"`
+	RouteTimeouts               []string      `env:"ROUTE_TIMEOUTS" envSeparator:"," yaml:"routeTimeouts" toml:"routeTimeouts"`
+	MaxBodyBytes                int64         `env:"MAX_BODY_BYTES" yaml:"maxBodyBytes" toml:"maxBodyBytes"`
+	MaxRestoreBytes             int64         `env:"MAX_RESTORE_BYTES" yaml:"maxRestoreBytes" toml:"maxRestoreBytes"`
+	MaxImportBytes              int64         `env:"MAX_IMPORT_BYTES" yaml:"maxImportBytes" toml:"maxImportBytes"`
+	LimitPerPage                int64         `env:"LIMIT_PER_PAGE" yaml:"limitPerPage" toml:"limitPerPage"`
+	MaxPageSize                 int64         `env:"MAX_PAGE_SIZE" yaml:"maxPageSize" toml:"maxPageSize"`
+	StorageBackend              string        `env:"STORAGE_BACKEND" yaml:"storageBackend" toml:"storageBackend"`
+	DynamoDBTable               string        `env:"DYNAMODB_TABLE" yaml:"dynamoDBTable" toml:"dynamoDBTable"`
+	DynamoDBEndpoint            string        `env:"DYNAMODB_ENDPOINT" yaml:"dynamoDBEndpoint" toml:"dynamoDBEndpoint"`
+	AWSRegion                   string        `env:"AWS_REGION" yaml:"awsRegion" toml:"awsRegion"`
+	AWSAccessKeyID              string        `env:"AWS_ACCESS_KEY_ID" yaml:"awsAccessKeyID" toml:"awsAccessKeyID"`
+	AWSSecretAccessKey          string        `env:"AWS_SECRET_ACCESS_KEY" yaml:"awsSecretAccessKey" toml:"awsSecretAccessKey"`
+	AWSSessionToken             string        `env:"AWS_SESSION_TOKEN" yaml:"awsSessionToken" toml:"awsSessionToken"`
+	FirestoreProjectID          string        `env:"FIRESTORE_PROJECT_ID" yaml:"firestoreProjectID" toml:"firestoreProjectID"`
+	FirestoreDatabase           string        `env:"FIRESTORE_DATABASE" yaml:"firestoreDatabase" toml:"firestoreDatabase"`
+	FirestoreCollection         string        `env:"FIRESTORE_COLLECTION" yaml:"firestoreCollection" toml:"firestoreCollection"`
+	FirestoreCredentialsFile    string        `env:"FIRESTORE_CREDENTIALS_FILE" yaml:"firestoreCredentialsFile" toml:"firestoreCredentialsFile"`
+	FirestoreEmulatorHost       string        `env:"FIRESTORE_EMULATOR_HOST" yaml:"firestoreEmulatorHost" toml:"firestoreEmulatorHost"`
+	SearchURL                   string        `env:"SEARCH_URL" yaml:"searchURL" toml:"searchURL"`
+	SearchIndex                 string        `env:"SEARCH_INDEX" yaml:"searchIndex" toml:"searchIndex"`
+	SearchUsername              string        `env:"SEARCH_USERNAME" yaml:"searchUsername" toml:"searchUsername"`
+	SearchPassword              string        `env:"SEARCH_PASSWORD" yaml:"searchPassword" toml:"searchPassword"`
+	EmbeddedSearchEnabled       bool          `env:"EMBEDDED_SEARCH_ENABLED" yaml:"embeddedSearchEnabled" toml:"embeddedSearchEnabled"`
+	MongoURI                    string        `env:"MONGO_URI" yaml:"mongoURI" toml:"mongoURI"`
+	MongoDBName                 string        `env:"MONGO_DB" yaml:"mongoDB" toml:"mongoDB"`
+	MongoCollectionName         string        `env:"MONGO_COLLECTION" yaml:"mongoCollection" toml:"mongoCollection"`
+	MongoMaxPoolSize            uint64        `env:"MONGO_MAX_POOL_SIZE" yaml:"mongoMaxPoolSize" toml:"mongoMaxPoolSize"`
+	MongoMinPoolSize            uint64        `env:"MONGO_MIN_POOL_SIZE" yaml:"mongoMinPoolSize" toml:"mongoMinPoolSize"`
+	MongoServerSelectionTimeout time.Duration `env:"MONGO_SERVER_SELECTION_TIMEOUT" yaml:"mongoServerSelectionTimeout" toml:"mongoServerSelectionTimeout"`
+	MongoSocketTimeout          time.Duration `env:"MONGO_SOCKET_TIMEOUT" yaml:"mongoSocketTimeout" toml:"mongoSocketTimeout"`
+	MongoReadPreference         string        `env:"MONGO_READ_PREFERENCE" yaml:"mongoReadPreference" toml:"mongoReadPreference"`
+	MongoWriteConcern           string        `env:"MONGO_WRITE_CONCERN" yaml:"mongoWriteConcern" toml:"mongoWriteConcern"`
+	MongoRetryWrites            bool          `env:"MONGO_RETRY_WRITES" yaml:"mongoRetryWrites" toml:"mongoRetryWrites"`
+	MongoConnectAttempts        int           `env:"MONGO_CONNECT_ATTEMPTS" yaml:"mongoConnectAttempts" toml:"mongoConnectAttempts"`
+	MongoConnectMaxBackoff      time.Duration `env:"MONGO_CONNECT_MAX_BACKOFF" yaml:"mongoConnectMaxBackoff" toml:"mongoConnectMaxBackoff"`
+	MongoWatchdogInterval       time.Duration `env:"MONGO_WATCHDOG_INTERVAL" yaml:"mongoWatchdogInterval" toml:"mongoWatchdogInterval"`
+	MongoWatchdogFailures       int           `env:"MONGO_WATCHDOG_FAILURES" yaml:"mongoWatchdogFailures" toml:"mongoWatchdogFailures"`
+	MongoAutoIndex              bool          `env:"MONGO_AUTO_INDEX" yaml:"mongoAutoIndex" toml:"mongoAutoIndex"`
+	MongoMigrateOnStartup       bool          `env:"MONGO_MIGRATE_ON_STARTUP" yaml:"mongoMigrateOnStartup" toml:"mongoMigrateOnStartup"`
+	MongoIndexes                []string      `env:"MONGO_INDEXES" envSeparator:"," yaml:"mongoIndexes" toml:"mongoIndexes"`
+	QueryTimeout                time.Duration `env:"QUERY_TIMEOUT" yaml:"queryTimeout" toml:"queryTimeout"`
+	MaxSizeProperty             int           `env:"MAX_SIZE_PROPERTY" yaml:"maxSizeProperty" toml:"maxSizeProperty"`
+	DuplicateDetection          string        `env:"DUPLICATE_DETECTION" yaml:"duplicateDetection" toml:"duplicateDetection"`
+	PhoneCountryCode            string        `env:"PHONE_COUNTRY_CODE" yaml:"phoneCountryCode" toml:"phoneCountryCode"`
+	PhoneRegion                 string        `env:"PHONE_REGION" yaml:"phoneRegion" toml:"phoneRegion"`
+	AuditEnabled                bool          `env:"AUDIT_ENABLED" yaml:"auditEnabled" toml:"auditEnabled"`
+	MongoAuditCollectionName    string        `env:"MONGO_AUDIT_COLLECTION" yaml:"mongoAuditCollection" toml:"mongoAuditCollection"`
+	MongoInteractionsCollection string        `env:"MONGO_INTERACTIONS_COLLECTION" yaml:"mongoInteractionsCollection" toml:"mongoInteractionsCollection"`
+	MongoRemindersCollection    string        `env:"MONGO_REMINDERS_COLLECTION" yaml:"mongoRemindersCollection" toml:"mongoRemindersCollection"`
+	MongoSpeedDialsCollection   string        `env:"MONGO_SPEED_DIALS_COLLECTION" yaml:"mongoSpeedDialsCollection" toml:"mongoSpeedDialsCollection"`
+	MongoTenantsCollection      string        `env:"MONGO_TENANTS_COLLECTION" yaml:"mongoTenantsCollection" toml:"mongoTenantsCollection"`
+	MongoErasuresCollection     string        `env:"MONGO_ERASURES_COLLECTION" yaml:"mongoErasuresCollection" toml:"mongoErasuresCollection"`
+	MongoTombstonesCollection   string        `env:"MONGO_TOMBSTONES_COLLECTION" yaml:"mongoTombstonesCollection" toml:"mongoTombstonesCollection"`
+	MongoJobsCollection         string        `env:"MONGO_JOBS_COLLECTION" yaml:"mongoJobsCollection" toml:"mongoJobsCollection"`
+	FieldEncryptionKey          string        `env:"FIELD_ENCRYPTION_KEY" yaml:"fieldEncryptionKey" toml:"fieldEncryptionKey"`
+	FieldEncryptionKeyFile      string        `env:"FIELD_ENCRYPTION_KEY_FILE" yaml:"fieldEncryptionKeyFile" toml:"fieldEncryptionKeyFile"`
+	CacheEnabled                bool          `env:"CACHE_ENABLED" yaml:"cacheEnabled" toml:"cacheEnabled"`
+	CacheBackend                string        `env:"CACHE_BACKEND" yaml:"cacheBackend" toml:"cacheBackend"`
+	CacheSize                   int           `env:"CACHE_SIZE" yaml:"cacheSize" toml:"cacheSize"`
+	CacheTTL                    time.Duration `env:"CACHE_TTL" yaml:"cacheTTL" toml:"cacheTTL"`
+	RedisURI                    string        `env:"REDIS_URI" yaml:"redisURI" toml:"redisURI"`
+	EventsBackend               string        `env:"EVENTS_BACKEND" yaml:"eventsBackend" toml:"eventsBackend"`
+	EventsURL                   string        `env:"EVENTS_URL" yaml:"eventsURL" toml:"eventsURL"`
+	EventsTopic                 string        `env:"EVENTS_TOPIC" yaml:"eventsTopic" toml:"eventsTopic"`
+	EventsFormat                string        `env:"EVENTS_FORMAT" yaml:"eventsFormat" toml:"eventsFormat"`
+	WSMutationsEnabled          bool          `env:"WS_MUTATIONS_ENABLED" yaml:"wsMutationsEnabled" toml:"wsMutationsEnabled"`
+	ChangeStreamEnabled         bool          `env:"CHANGE_STREAM_ENABLED" yaml:"changeStreamEnabled" toml:"changeStreamEnabled"`
+	ChangeStreamRetryInterval   time.Duration `env:"CHANGE_STREAM_RETRY_INTERVAL" yaml:"changeStreamRetryInterval" toml:"changeStreamRetryInterval"`
+	SMTPHost                    string        `env:"SMTP_HOST" yaml:"smtpHost" toml:"smtpHost"`
+	SMTPPort                    int           `env:"SMTP_PORT" yaml:"smtpPort" toml:"smtpPort"`
+	SMTPUsername                string        `env:"SMTP_USERNAME" yaml:"smtpUsername" toml:"smtpUsername"`
+	SMTPPassword                string        `env:"SMTP_PASSWORD" yaml:"smtpPassword" toml:"smtpPassword"`
+	EmailFrom                   string        `env:"EMAIL_FROM" yaml:"emailFrom" toml:"emailFrom"`
+	EmailTo                     []string      `env:"EMAIL_TO" envSeparator:"," yaml:"emailTo" toml:"emailTo"`
+	EmailEvents                 []string      `env:"EMAIL_EVENTS" envSeparator:"," yaml:"emailEvents" toml:"emailEvents"`
+	EmailSubjectTemplate        string        `env:"EMAIL_SUBJECT_TEMPLATE" yaml:"emailSubjectTemplate" toml:"emailSubjectTemplate"`
+	EmailBodyTemplate           string        `env:"EMAIL_BODY_TEMPLATE" yaml:"emailBodyTemplate" toml:"emailBodyTemplate"`
+	EmailQueueSize              int           `env:"EMAIL_QUEUE_SIZE" yaml:"emailQueueSize" toml:"emailQueueSize"`
+	BackupInterval              time.Duration `env:"BACKUP_INTERVAL" yaml:"backupInterval" toml:"backupInterval"`
+	BackupTimeout               time.Duration `env:"BACKUP_TIMEOUT" yaml:"backupTimeout" toml:"backupTimeout"`
+	BackupFormat                string        `env:"BACKUP_FORMAT" yaml:"backupFormat" toml:"backupFormat"`
+	BackupPrefix                string        `env:"BACKUP_PREFIX" yaml:"backupPrefix" toml:"backupPrefix"`
+	BackupKeep                  int           `env:"BACKUP_KEEP" yaml:"backupKeep" toml:"backupKeep"`
+	BackupMaxAge                time.Duration `env:"BACKUP_MAX_AGE" yaml:"backupMaxAge" toml:"backupMaxAge"`
+	BackupS3Endpoint            string        `env:"BACKUP_S3_ENDPOINT" yaml:"backupS3Endpoint" toml:"backupS3Endpoint"`
+	BackupS3Bucket              string        `env:"BACKUP_S3_BUCKET" yaml:"backupS3Bucket" toml:"backupS3Bucket"`
+	BackupS3Region              string        `env:"BACKUP_S3_REGION" yaml:"backupS3Region" toml:"backupS3Region"`
+	BackupS3AccessKey           string        `env:"BACKUP_S3_ACCESS_KEY" yaml:"backupS3AccessKey" toml:"backupS3AccessKey"`
+	BackupS3SecretKey           string        `env:"BACKUP_S3_SECRET_KEY" yaml:"backupS3SecretKey" toml:"backupS3SecretKey"`
+	BackupS3Insecure            bool          `env:"BACKUP_S3_INSECURE" yaml:"backupS3Insecure" toml:"backupS3Insecure"`
+	GoogleClientID              string        `env:"GOOGLE_CLIENT_ID" yaml:"googleClientID" toml:"googleClientID"`
+	GoogleClientSecret          string        `env:"GOOGLE_CLIENT_SECRET" yaml:"googleClientSecret" toml:"googleClientSecret"`
+	GoogleRedirectURL           string        `env:"GOOGLE_REDIRECT_URL" yaml:"googleRedirectURL" toml:"googleRedirectURL"`
+	CallerIDMaxAge              time.Duration `env:"CALLERID_MAX_AGE" yaml:"callerIDMaxAge" toml:"callerIDMaxAge"`
+	ListCacheMaxAge             time.Duration `env:"LIST_CACHE_MAX_AGE" yaml:"listCacheMaxAge" toml:"listCacheMaxAge"`
+	SeedEnabled                 bool          `env:"SEED_ENABLED" yaml:"seedEnabled" toml:"seedEnabled"`
+	MaintenanceMode             bool          `env:"MAINTENANCE_MODE" yaml:"maintenanceMode" toml:"maintenanceMode"`
+	TenancyEnabled              bool          `env:"TENANCY_ENABLED" yaml:"tenancyEnabled" toml:"tenancyEnabled"`
+	TenantDomain                string        `env:"TENANT_DOMAIN" yaml:"tenantDomain" toml:"tenantDomain"`
+	TenantMaxContacts           int64         `env:"TENANT_MAX_CONTACTS" yaml:"tenantMaxContacts" toml:"tenantMaxContacts"`
+	TenantMaxRequestsPerMinute  int           `env:"TENANT_MAX_REQUESTS_PER_MINUTE" yaml:"tenantMaxRequestsPerMinute" toml:"tenantMaxRequestsPerMinute"`
+	ReminderWebhookURL          string        `env:"REMINDER_WEBHOOK_URL" yaml:"reminderWebhookURL" toml:"reminderWebhookURL"`
+	ReminderInterval            time.Duration `env:"REMINDER_INTERVAL" yaml:"reminderInterval" toml:"reminderInterval"`
+	RetentionDays               int           `env:"RETENTION_DAYS" yaml:"retentionDays" toml:"retentionDays"`
+	RetentionAction             string        `env:"RETENTION_ACTION" yaml:"retentionAction" toml:"retentionAction"`
+	RetentionInterval           time.Duration `env:"RETENTION_INTERVAL" yaml:"retentionInterval" toml:"retentionInterval"`
+	SyncConflictPolicy          string        `env:"SYNC_CONFLICT_POLICY" yaml:"syncConflictPolicy" toml:"syncConflictPolicy"`
+	SyncTombstoneTTL            time.Duration `env:"SYNC_TOMBSTONE_TTL" yaml:"syncTombstoneTTL" toml:"syncTombstoneTTL"`
+	ImportBatchSize             int           `env:"IMPORT_BATCH_SIZE" yaml:"importBatchSize" toml:"importBatchSize"`
+	ImportFlushInterval         time.Duration `env:"IMPORT_FLUSH_INTERVAL" yaml:"importFlushInterval" toml:"importFlushInterval"`
+	ImportConcurrency           int           `env:"IMPORT_CONCURRENCY" yaml:"importConcurrency" toml:"importConcurrency"`
+	ImportUploadDir             string        `env:"IMPORT_UPLOAD_DIR" yaml:"importUploadDir" toml:"importUploadDir"`
+	JobConcurrency              int           `env:"JOB_CONCURRENCY" yaml:"jobConcurrency" toml:"jobConcurrency"`
+	JobRetention                time.Duration `env:"JOB_RETENTION" yaml:"jobRetention" toml:"jobRetention"`
+	LDAPEnabled                 bool          `env:"LDAP_ENABLED" yaml:"ldapEnabled" toml:"ldapEnabled"`
+	LDAPAddr                    string        `env:"LDAP_ADDR" yaml:"ldapAddr" toml:"ldapAddr"`
+	LDAPBaseDN                  string        `env:"LDAP_BASE_DN" yaml:"ldapBaseDN" toml:"ldapBaseDN"`
+	LDAPBindDN                  string        `env:"LDAP_BIND_DN" yaml:"ldapBindDN" toml:"ldapBindDN"`
+	LDAPBindPassword            string        `env:"LDAP_BIND_PASSWORD" yaml:"ldapBindPassword" toml:"ldapBindPassword"`
+	SCIMToken                   string        `env:"SCIM_TOKEN" yaml:"scimToken" toml:"scimToken"`
+	AdminToken                  string        `env:"ADMIN_TOKEN" yaml:"adminToken" toml:"adminToken"`
+	TrustedProxies              []string      `env:"TRUSTED_PROXIES" envSeparator:"," yaml:"trustedProxies" toml:"trustedProxies"`
+	CRMProvider                 string        `env:"CRM_PROVIDER" yaml:"crmProvider" toml:"crmProvider"`
+	CRMURL                      string        `env:"CRM_URL" yaml:"crmURL" toml:"crmURL"`
+	CRMToken                    string        `env:"CRM_TOKEN" yaml:"crmToken" toml:"crmToken"`
+	CRMClientID                 string        `env:"CRM_CLIENT_ID" yaml:"crmClientID" toml:"crmClientID"`
+	CRMClientSecret             string        `env:"CRM_CLIENT_SECRET" yaml:"crmClientSecret" toml:"crmClientSecret"`
+	CRMIDProperty               string        `env:"CRM_ID_PROPERTY" yaml:"crmIDProperty" toml:"crmIDProperty"`
+	CRMFieldMapping             []string      `env:"CRM_FIELD_MAPPING" envSeparator:"," yaml:"crmFieldMapping" toml:"crmFieldMapping"`
+	CRMRequestsPerSecond        float64       `env:"CRM_REQUESTS_PER_SECOND" yaml:"crmRequestsPerSecond" toml:"crmRequestsPerSecond"`
+	CRMQueueSize                int           `env:"CRM_QUEUE_SIZE" yaml:"crmQueueSize" toml:"crmQueueSize"`
+	CRMMaxAttempts              int           `env:"CRM_MAX_ATTEMPTS" yaml:"crmMaxAttempts" toml:"crmMaxAttempts"`
+	CRMRetryBackoff             time.Duration `env:"CRM_RETRY_BACKOFF" yaml:"crmRetryBackoff" toml:"crmRetryBackoff"`
+	CRMMaxDeadLetters           int           `env:"CRM_MAX_DEAD_LETTERS" yaml:"crmMaxDeadLetters" toml:"crmMaxDeadLetters"`
+	RateLimitPerSecond          float64       `env:"RATE_LIMIT_PER_SECOND" yaml:"rateLimitPerSecond" toml:"rateLimitPerSecond"`
+	RateLimitBurst              int           `env:"RATE_LIMIT_BURST" yaml:"rateLimitBurst" toml:"rateLimitBurst"`
+	APIKeys                     []string      `env:"API_KEYS" envSeparator:"," yaml:"apiKeys" toml:"apiKeys"`
+	DeleteGuardLimit            int64         `env:"DELETE_GUARD_LIMIT" yaml:"deleteGuardLimit" toml:"deleteGuardLimit"`
+	DeleteGuardBlock            time.Duration `env:"DELETE_GUARD_BLOCK" yaml:"deleteGuardBlock" toml:"deleteGuardBlock"`
+	DeleteGuardWebhookURL       string        `env:"DELETE_GUARD_WEBHOOK_URL" yaml:"deleteGuardWebhookURL" toml:"deleteGuardWebhookURL"`
+	TracingEnabled              bool          `env:"TRACING_ENABLED" yaml:"tracingEnabled" toml:"tracingEnabled"`
+	OTLPEndpoint                string        `env:"OTLP_ENDPOINT" yaml:"otlpEndpoint" toml:"otlpEndpoint"`
+	OTLPInsecure                bool          `env:"OTLP_INSECURE" yaml:"otlpInsecure" toml:"otlpInsecure"`
+	LogLevel                    string        `env:"LOG_LEVEL" yaml:"logLevel" toml:"logLevel"`
+	LogRedaction                bool          `env:"LOG_REDACTION" yaml:"logRedaction" toml:"logRedaction"`
+	LogRedactFields             []string      `env:"LOG_REDACT_FIELDS" envSeparator:"," yaml:"logRedactFields" toml:"logRedactFields"`
+}
+
+// phoneCountryCodeRegex matches a calling code without its + prefix.
+var phoneCountryCodeRegex = regexp.MustCompile(`^([1-9][0-9]{0,2})?$`)
+
+// mongoReadPreferences are the read preference modes, lower cased, empty
+// keeping the one of the URI.
+var mongoReadPreferences = map[string]bool{
+	"":                   true,
+	"primary":            true,
+	"primarypreferred":   true,
+	"secondary":          true,
+	"secondarypreferred": true,
+	"nearest":            true,
+}
+
+// fieldNameRegex matches the field names redacted from the logs.
+var fieldNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// webhookURLRegex matches the absolute http and https urls webhooks are
+// posted to.
+var webhookURLRegex = regexp.MustCompile(`^https?://[^\s/]+`)
+
+// crmFields are the contact fields that can be mapped to CRM properties.
+var crmFields = map[string]bool{
+	"firstName": true,
+	"lastName":  true,
+	"phone":     true,
+	"email":     true,
+	"company":   true,
+	"jobTitle":  true,
+	"address":   true,
+	"notes":     true,
+}
+
+// Static is the configuration in use. It holds the defaults until main
+// replaces it with the loaded configuration, so tests get predictable values
+// and can set their own.
+var Static = Default()
+
+// Default returns the built-in configuration.
+func Default() Config {
+	return Config{
+		HTTPServerPort:              ":8080",
+		HTTPReadTimeout:             10 * time.Second,
+		HTTPWriteTimeout:            15 * time.Second,
+		RequestTimeout:              10 * time.Second,
+		HTTPIdleTimeout:             60 * time.Second,
+		AutocertCacheDir:            "certs",
+		ShutdownTimeout:             30 * time.Second,
+		MaxBodyBytes:                1 << 20,
+		MaxRestoreBytes:             64 << 20,
+		MaxImportBytes:              256 << 20,
+		LimitPerPage:                10,
+		MaxPageSize:                 100,
+		StorageBackend:              "mongo",
+		DynamoDBTable:               "contacts",
+		FirestoreDatabase:           "(default)",
+		FirestoreCollection:         "contacts",
+		SearchIndex:                 "contacts",
+		MongoURI:                    "mongodb://mongo:27017",
+		MongoDBName:                 "phoneBook",
+		MongoCollectionName:         "contacts",
+		MongoServerSelectionTimeout: 10 * time.Second,
+		MongoRetryWrites:            true,
+		MongoConnectAttempts:        10,
+		MongoConnectMaxBackoff:      30 * time.Second,
+		MongoWatchdogInterval:       10 * time.Second,
+		MongoWatchdogFailures:       3,
+		MongoAutoIndex:              true,
+		MongoMigrateOnStartup:       true,
+		MongoIndexes:                []string{"phone", "lastName+firstName", "displayName", "company", "owner"},
+		QueryTimeout:                5 * time.Second,
+		MaxSizeProperty:             100,
+		DuplicateDetection:          "phone",
+		PhoneCountryCode:            "972",
+		PhoneRegion:                 "IL",
+		MongoAuditCollectionName:    "contactsHistory",
+		MongoInteractionsCollection: "interactions",
+		MongoRemindersCollection:    "reminders",
+		MongoSpeedDialsCollection:   "speedDials",
+		MongoTenantsCollection:      "tenants",
+		MongoErasuresCollection:     "erasures",
+		MongoTombstonesCollection:   "tombstones",
+		MongoJobsCollection:         "jobs",
+		CacheBackend:                "memory",
+		CacheSize:                   1000,
+		CacheTTL:                    10 * time.Second,
+		EventsTopic:                 "phonebook.contacts",
+		EventsFormat:                "json",
+		ChangeStreamRetryInterval:   5 * time.Second,
+		SMTPPort:                    587,
+		EmailEvents:                 []string{"contact.deleted", "contacts.imported"},
+		EmailQueueSize:              100,
+		BackupTimeout:               30 * time.Minute,
+		BackupFormat:                "json",
+		BackupPrefix:                "phonebook/",
+		BackupKeep:                  7,
+		CallerIDMaxAge:              5 * time.Minute,
+		ReminderInterval:            time.Minute,
+		RetentionAction:             "anonymize",
+		RetentionInterval:           24 * time.Hour,
+		SyncConflictPolicy:          "merge",
+		SyncTombstoneTTL:            30 * 24 * time.Hour,
+		ImportBatchSize:             500,
+		ImportFlushInterval:         100 * time.Millisecond,
+		ImportConcurrency:           4,
+		JobConcurrency:              2,
+		JobRetention:                7 * 24 * time.Hour,
+		LDAPAddr:                    ":3389",
+		LDAPBaseDN:                  "ou=contacts,dc=phonebook,dc=local",
+		CRMRequestsPerSecond:        5,
+		CRMQueueSize:                10000,
+		CRMMaxAttempts:              5,
+		CRMRetryBackoff:             time.Second,
+		CRMMaxDeadLetters:           1000,
+		RateLimitBurst:              20,
+		DeleteGuardBlock:            15 * time.Minute,
+		LogLevel:                    "info",
+		LogRedaction:                true,
+		LogRedactFields:             []string{"firstName", "lastName", "displayName", "phone", "normalizedPhone", "address"},
+		OTLPEndpoint:                "localhost:4318",
+	}
+}
+
+// Load returns the defaults overridden by the YAML or TOML file at path, if
+// path isn't empty, and then by the environment variables, validated.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := env.Parse(&cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid environment variable: %w", err)
+	}
+	if err := loadKeyFile(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	case ".toml":
+		err = toml.Unmarshal(content, cfg)
+	default:
+		return fmt.Errorf("unsupported config file %s, expected .yaml, .yml or .toml", path)
+	}
 	if err != nil {
-		logrus.WithError(err).Fatal("Could not load static configuration")
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadKeyFile reads the field encryption key from FIELD_ENCRYPTION_KEY_FILE,
+// e.g. as written by a KMS or secrets manager agent.
+func loadKeyFile(cfg *Config) error {
+	if cfg.FieldEncryptionKeyFile == "" {
+		return nil
+	}
+	if cfg.FieldEncryptionKey != "" {
+		return errors.New("fieldEncryptionKey and fieldEncryptionKeyFile should not be set together")
+	}
+	content, err := os.ReadFile(cfg.FieldEncryptionKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read field encryption key file: %w", err)
+	}
+	cfg.FieldEncryptionKey = strings.TrimSpace(string(content))
+	return nil
+}
+
+// DecodeEncryptionKey decodes a base64 field encryption key, which must be
+// 32 bytes long.
+func DecodeEncryptionKey(key string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 32 {
+		return nil, errors.New("fieldEncryptionKey should be 32 random bytes in base64")
+	}
+	return decoded, nil
+}
+
+// ParseRouteTimeouts parses route timeouts given as path=duration, e.g.
+// /contact/search=2s, by route path. A zero duration lifts the timeout.
+func ParseRouteTimeouts(specs []string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, spec := range specs {
+		path, value, found := strings.Cut(strings.TrimSpace(spec), "=")
+		if !found || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route timeout %q. expected path=duration, e.g. /contact/search=2s", spec)
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid timeout of %s: %q", path, value)
+		}
+		timeouts[path] = timeout
+	}
+	return timeouts, nil
+}
+
+// ParseTrustedProxies parses the addresses of the trusted proxies, given as
+// CIDRs, e.g. 10.0.0.0/8, or single IPs.
+func ParseTrustedProxies(specs []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if !strings.Contains(spec, "/") {
+			address, err := netip.ParseAddr(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q. expected an IP or a CIDR, e.g. 10.0.0.0/8", spec)
+			}
+			proxies = append(proxies, netip.PrefixFrom(address.Unmap(), address.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q. expected an IP or a CIDR, e.g. 10.0.0.0/8", spec)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// ParseCRMFieldMapping parses the mapping of contact fields to CRM properties
+// given as field=property, e.g. jobTitle=jobtitle, by contact field.
+func ParseCRMFieldMapping(specs []string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, spec := range specs {
+		field, property, found := strings.Cut(strings.TrimSpace(spec), "=")
+		if !found || property == "" {
+			return nil, fmt.Errorf("invalid crm field mapping %q. expected field=property, e.g. jobTitle=jobtitle", spec)
+		}
+		if !crmFields[field] {
+			return nil, fmt.Errorf("unknown contact field %q in the crm field mapping", field)
+		}
+		mapping[field] = property
+	}
+	return mapping, nil
+}
+
+// Validate reports every setting the service can't run with.
+func (c Config) Validate() error {
+	var errs []error
+	if c.LimitPerPage <= 0 {
+		errs = append(errs, errors.New("limitPerPage should be positive"))
+	}
+	if c.MaxPageSize < c.LimitPerPage {
+		errs = append(errs, errors.New("maxPageSize should be at least limitPerPage"))
+	}
+	if c.MaxSizeProperty <= 0 {
+		errs = append(errs, errors.New("maxSizeProperty should be positive"))
+	}
+	if c.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("maxBodyBytes should be positive"))
+	}
+	if c.MaxRestoreBytes <= 0 {
+		errs = append(errs, errors.New("maxRestoreBytes should be positive"))
+	}
+	if c.MaxImportBytes <= 0 {
+		errs = append(errs, errors.New("maxImportBytes should be positive"))
+	}
+	if c.QueryTimeout <= 0 {
+		errs = append(errs, errors.New("queryTimeout should be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdownTimeout should be positive"))
+	}
+	if c.RequestTimeout < 0 {
+		errs = append(errs, errors.New("requestTimeout should not be negative"))
+	}
+	if _, err := ParseRouteTimeouts(c.RouteTimeouts); err != nil {
+		errs = append(errs, fmt.Errorf("routeTimeouts: %w", err))
+	}
+	if !phoneCountryCodeRegex.MatchString(c.PhoneCountryCode) {
+		errs = append(errs, errors.New("phoneCountryCode should be 1 to 3 digits, or empty"))
+	}
+	if !phonenumber.KnownRegion(c.PhoneRegion) {
+		errs = append(errs, fmt.Errorf("phoneRegion should be one of %s", strings.Join(phonenumber.Regions(), ", ")))
+	}
+	if c.CacheEnabled && c.CacheSize <= 0 {
+		errs = append(errs, errors.New("cacheSize should be positive when the cache is enabled"))
+	}
+	if c.CacheEnabled && c.CacheTTL <= 0 {
+		errs = append(errs, errors.New("cacheTTL should be positive when the cache is enabled"))
+	}
+	if c.CacheBackend != "memory" && c.CacheBackend != "redis" {
+		errs = append(errs, errors.New("cacheBackend should be memory or redis"))
+	}
+	if c.CacheEnabled && c.CacheBackend == "redis" && c.RedisURI == "" {
+		errs = append(errs, errors.New("redisURI is required with the redis cache backend"))
+	}
+	if c.EventsBackend != "" && c.EventsBackend != "nats" && c.EventsBackend != "kafka-rest" {
+		errs = append(errs, errors.New("eventsBackend should be nats or kafka-rest"))
+	}
+	if c.EventsBackend != "" && c.EventsURL == "" {
+		errs = append(errs, errors.New("eventsURL is required when an events backend is set"))
+	}
+	if c.ChangeStreamEnabled && c.ChangeStreamRetryInterval <= 0 {
+		errs = append(errs, errors.New("changeStreamRetryInterval should be positive"))
+	}
+	if c.EventsFormat != "json" && c.EventsFormat != "avro" {
+		errs = append(errs, errors.New("eventsFormat should be json or avro"))
+	}
+	if c.SMTPHost != "" && (c.EmailFrom == "" || len(c.EmailTo) == 0) {
+		errs = append(errs, errors.New("emailFrom and emailTo are required when smtpHost is set"))
+	}
+	if c.SMTPHost != "" && (c.SMTPPort <= 0 || c.SMTPPort > 65535) {
+		errs = append(errs, errors.New("smtpPort should be a port number"))
+	}
+	if c.SMTPHost != "" && c.EmailQueueSize <= 0 {
+		errs = append(errs, errors.New("emailQueueSize should be positive when emails are sent"))
+	}
+	if c.CRMProvider != "" && c.CRMProvider != "hubspot" && c.CRMProvider != "salesforce" {
+		errs = append(errs, errors.New("crmProvider should be hubspot or salesforce"))
+	}
+	if c.CRMProvider == "hubspot" && c.CRMToken == "" {
+		errs = append(errs, errors.New("crmToken is required with the hubspot crm provider"))
+	}
+	if c.CRMProvider == "salesforce" && (c.CRMURL == "" || c.CRMClientID == "" || c.CRMClientSecret == "") {
+		errs = append(errs, errors.New("crmURL, crmClientID and crmClientSecret are required with the salesforce crm provider"))
+	}
+	if c.CRMURL != "" && !webhookURLRegex.MatchString(c.CRMURL) {
+		errs = append(errs, errors.New("crmURL should be an http or https url"))
+	}
+	if _, err := ParseTrustedProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trustedProxies: %w", err))
+	}
+	if _, err := ParseCRMFieldMapping(c.CRMFieldMapping); err != nil {
+		errs = append(errs, fmt.Errorf("crmFieldMapping: %w", err))
+	}
+	if c.CRMProvider != "" && c.CRMRequestsPerSecond <= 0 {
+		errs = append(errs, errors.New("crmRequestsPerSecond should be positive when syncing to a crm"))
+	}
+	if c.CRMProvider != "" && (c.CRMQueueSize <= 0 || c.CRMMaxAttempts <= 0 || c.CRMMaxDeadLetters <= 0 || c.CRMRetryBackoff <= 0) {
+		errs = append(errs, errors.New("crmQueueSize, crmMaxAttempts, crmMaxDeadLetters and crmRetryBackoff should be positive when syncing to a crm"))
+	}
+	if c.BackupInterval < 0 {
+		errs = append(errs, errors.New("backupInterval should not be negative"))
+	}
+	if c.BackupInterval > 0 && (c.BackupS3Endpoint == "" || c.BackupS3Bucket == "") {
+		errs = append(errs, errors.New("backupS3Endpoint and backupS3Bucket are required when backups are scheduled"))
+	}
+	if c.BackupInterval > 0 && c.BackupTimeout <= 0 {
+		errs = append(errs, errors.New("backupTimeout should be positive when backups are scheduled"))
+	}
+	if c.BackupFormat != "json" && c.BackupFormat != "bson" {
+		errs = append(errs, errors.New("backupFormat should be json or bson"))
+	}
+	if c.BackupKeep < 0 {
+		errs = append(errs, errors.New("backupKeep should not be negative"))
+	}
+	if c.BackupMaxAge < 0 {
+		errs = append(errs, errors.New("backupMaxAge should not be negative"))
+	}
+	if c.GoogleClientID != "" && (c.GoogleClientSecret == "" || c.GoogleRedirectURL == "") {
+		errs = append(errs, errors.New("googleClientSecret and googleRedirectURL are required with googleClientID"))
+	}
+	if c.CallerIDMaxAge < 0 {
+		errs = append(errs, errors.New("callerIDMaxAge should not be negative"))
+	}
+	if c.ListCacheMaxAge < 0 {
+		errs = append(errs, errors.New("listCacheMaxAge should not be negative"))
+	}
+	if c.TenantDomain != "" && !c.TenancyEnabled {
+		errs = append(errs, errors.New("tenantDomain requires tenancyEnabled"))
+	}
+	if c.TenantMaxContacts < 0 || c.TenantMaxRequestsPerMinute < 0 {
+		errs = append(errs, errors.New("tenantMaxContacts and tenantMaxRequestsPerMinute should not be negative"))
+	}
+	if c.ReminderWebhookURL != "" && !webhookURLRegex.MatchString(c.ReminderWebhookURL) {
+		errs = append(errs, errors.New("reminderWebhookURL should be an http or https url"))
+	}
+	if c.ReminderWebhookURL != "" && c.ReminderInterval <= 0 {
+		errs = append(errs, errors.New("reminderInterval should be positive when reminders are sent"))
+	}
+	if c.RetentionDays < 0 {
+		errs = append(errs, errors.New("retentionDays should not be negative"))
+	}
+	if c.RetentionAction != "anonymize" && c.RetentionAction != "purge" {
+		errs = append(errs, errors.New("retentionAction should be anonymize or purge"))
+	}
+	if c.RetentionDays > 0 && c.RetentionInterval <= 0 {
+		errs = append(errs, errors.New("retentionInterval should be positive when a retention policy is set"))
+	}
+	if c.SyncConflictPolicy != "lww" && c.SyncConflictPolicy != "merge" {
+		errs = append(errs, errors.New("syncConflictPolicy should be lww or merge"))
+	}
+	if c.SyncTombstoneTTL <= 0 {
+		errs = append(errs, errors.New("syncTombstoneTTL should be positive"))
+	}
+	if c.ImportBatchSize <= 0 || c.ImportFlushInterval <= 0 || c.ImportConcurrency <= 0 {
+		errs = append(errs, errors.New("importBatchSize, importFlushInterval and importConcurrency should be positive"))
+	}
+	if c.JobConcurrency <= 0 || c.JobRetention <= 0 {
+		errs = append(errs, errors.New("jobConcurrency and jobRetention should be positive"))
+	}
+	if c.LDAPEnabled && (c.LDAPAddr == "" || c.LDAPBaseDN == "") {
+		errs = append(errs, errors.New("ldapAddr and ldapBaseDN are required when ldap is enabled"))
+	}
+	if (c.LDAPBindDN == "") != (c.LDAPBindPassword == "") {
+		errs = append(errs, errors.New("ldapBindDN and ldapBindPassword should be set together"))
+	}
+	if c.RateLimitPerSecond < 0 {
+		errs = append(errs, errors.New("rateLimitPerSecond should not be negative"))
+	}
+	if c.RateLimitPerSecond > 0 && c.RateLimitBurst <= 0 {
+		errs = append(errs, errors.New("rateLimitBurst should be positive when rate limiting is enabled"))
+	}
+	if c.DeleteGuardLimit < 0 {
+		errs = append(errs, errors.New("deleteGuardLimit should not be negative"))
+	}
+	if c.DeleteGuardLimit > 0 && c.DeleteGuardBlock <= 0 {
+		errs = append(errs, errors.New("deleteGuardBlock should be positive when the delete guard is enabled"))
+	}
+	if c.DeleteGuardWebhookURL != "" && !webhookURLRegex.MatchString(c.DeleteGuardWebhookURL) {
+		errs = append(errs, errors.New("deleteGuardWebhookURL should be an http or https url"))
+	}
+	if c.DeleteGuardWebhookURL != "" && c.DeleteGuardLimit == 0 {
+		errs = append(errs, errors.New("deleteGuardWebhookURL needs a deleteGuardLimit"))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tlsCertFile and tlsKeyFile should be set together"))
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	for _, field := range c.LogRedactFields {
+		if !fieldNameRegex.MatchString(field) {
+			errs = append(errs, fmt.Errorf("logRedactFields: invalid field %q", field))
+		}
+	}
+	if c.MongoMaxPoolSize > 0 && c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		errs = append(errs, errors.New("mongoMinPoolSize should not exceed mongoMaxPoolSize"))
+	}
+	if c.MongoServerSelectionTimeout <= 0 {
+		errs = append(errs, errors.New("mongoServerSelectionTimeout should be positive"))
+	}
+	if c.MongoSocketTimeout < 0 {
+		errs = append(errs, errors.New("mongoSocketTimeout should not be negative"))
+	}
+	if !mongoReadPreferences[strings.ToLower(c.MongoReadPreference)] {
+		errs = append(errs, errors.New("mongoReadPreference should be primary, primaryPreferred, secondary, secondaryPreferred or nearest"))
+	}
+	if nodes, err := strconv.Atoi(c.MongoWriteConcern); c.MongoWriteConcern != "" && c.MongoWriteConcern != "majority" && (err != nil || nodes < 0) {
+		errs = append(errs, errors.New("mongoWriteConcern should be majority or a number of nodes"))
+	}
+	if c.MongoConnectAttempts <= 0 {
+		errs = append(errs, errors.New("mongoConnectAttempts should be positive"))
+	}
+	if c.MongoConnectMaxBackoff <= 0 {
+		errs = append(errs, errors.New("mongoConnectMaxBackoff should be positive"))
+	}
+	if c.MongoWatchdogInterval < 0 {
+		errs = append(errs, errors.New("mongoWatchdogInterval should not be negative"))
+	}
+	if c.MongoWatchdogInterval > 0 && c.MongoWatchdogFailures <= 0 {
+		errs = append(errs, errors.New("mongoWatchdogFailures should be positive when the watchdog is enabled"))
+	}
+	if c.StorageBackend != "mongo" && c.StorageBackend != "dynamodb" && c.StorageBackend != "firestore" {
+		errs = append(errs, errors.New("storageBackend should be mongo, dynamodb or firestore"))
+	}
+	if c.StorageBackend == "mongo" && c.MongoURI == "" {
+		errs = append(errs, errors.New("mongoURI is required"))
+	}
+	if c.StorageBackend == "dynamodb" {
+		if c.DynamoDBTable == "" || c.AWSRegion == "" {
+			errs = append(errs, errors.New("dynamoDBTable and awsRegion are required with the dynamodb storage backend"))
+		}
+		if c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "" {
+			errs = append(errs, errors.New("awsAccessKeyID and awsSecretAccessKey are required with the dynamodb storage backend"))
+		}
+	}
+	if c.StorageBackend == "firestore" {
+		if c.FirestoreProjectID == "" || c.FirestoreDatabase == "" || c.FirestoreCollection == "" {
+			errs = append(errs, errors.New("firestoreProjectID, firestoreDatabase and firestoreCollection are required with the firestore storage backend"))
+		}
+		if c.FirestoreCredentialsFile == "" && c.FirestoreEmulatorHost == "" {
+			errs = append(errs, errors.New("firestoreCredentialsFile is required with the firestore storage backend, unless firestoreEmulatorHost is set"))
+		}
+	}
+	if c.SearchURL != "" && !webhookURLRegex.MatchString(c.SearchURL) {
+		errs = append(errs, errors.New("searchURL should be an http or https url"))
+	}
+	if c.SearchURL != "" && c.SearchIndex == "" {
+		errs = append(errs, errors.New("searchIndex is required with searchURL"))
+	}
+	if c.FieldEncryptionKey != "" {
+		if _, err := DecodeEncryptionKey(c.FieldEncryptionKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.StorageBackend != "mongo" && (c.TenancyEnabled || c.BackupInterval > 0 || c.ReminderWebhookURL != "" || c.AuditEnabled || c.RetentionDays > 0 || c.FieldEncryptionKey != "" || c.ChangeStreamEnabled) {
+		errs = append(errs, errors.New("tenancy, backups, reminders, the audit log, retention, field encryption and the change stream need the mongo storage backend"))
 	}
+	return errors.Join(errs...)
 }
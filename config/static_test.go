@@ -0,0 +1,165 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("should return defaults without file", func(t *testing.T) {
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		assert.Equal(t, Default(), cfg)
+	})
+
+	t.Run("should override defaults from yaml file", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", "limitPerPage: 20\nqueryTimeout: 2s\n")
+		cfg, err := Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(20), cfg.LimitPerPage)
+		assert.Equal(t, 2*time.Second, cfg.QueryTimeout)
+		assert.Equal(t, Default().MaxPageSize, cfg.MaxPageSize)
+	})
+
+	t.Run("should override file from environment", func(t *testing.T) {
+		path := writeConfigFile(t, "config.toml", "limitPerPage = 20\nmongoDB = \"fromFile\"\n")
+		t.Setenv("LIMIT_PER_PAGE", "30")
+		cfg, err := Load(path)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(30), cfg.LimitPerPage)
+		assert.Equal(t, "fromFile", cfg.MongoDBName)
+	})
+
+	t.Run("should reject invalid values", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", "limitPerPage: 0\n")
+		_, err := Load(path)
+		assert.ErrorContains(t, err, "limitPerPage should be positive")
+	})
+
+	t.Run("should reject min pool size over max pool size", func(t *testing.T) {
+		t.Setenv("MONGO_MAX_POOL_SIZE", "5")
+		t.Setenv("MONGO_MIN_POOL_SIZE", "10")
+		_, err := Load("")
+		assert.ErrorContains(t, err, "mongoMinPoolSize should not exceed mongoMaxPoolSize")
+	})
+
+	t.Run("should require redis uri with redis cache", func(t *testing.T) {
+		t.Setenv("CACHE_ENABLED", "true")
+		t.Setenv("CACHE_BACKEND", "redis")
+		_, err := Load("")
+		assert.ErrorContains(t, err, "redisURI is required")
+	})
+
+	t.Run("should require aws settings with the dynamodb backend", func(t *testing.T) {
+		t.Setenv("STORAGE_BACKEND", "dynamodb")
+		t.Setenv("TENANCY_ENABLED", "true")
+		_, err := Load("")
+		assert.ErrorContains(t, err, "awsAccessKeyID and awsSecretAccessKey are required")
+		assert.ErrorContains(t, err, "need the mongo storage backend")
+	})
+
+	t.Run("should require a project and credentials with the firestore backend", func(t *testing.T) {
+		t.Setenv("STORAGE_BACKEND", "firestore")
+		t.Setenv("AUDIT_ENABLED", "true")
+		_, err := Load("")
+		assert.ErrorContains(t, err, "firestoreProjectID, firestoreDatabase and firestoreCollection are required")
+		assert.ErrorContains(t, err, "firestoreCredentialsFile is required")
+		assert.ErrorContains(t, err, "need the mongo storage backend")
+
+		t.Setenv("AUDIT_ENABLED", "false")
+		t.Setenv("FIRESTORE_PROJECT_ID", "phone-book")
+		t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8081")
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		assert.Equal(t, "(default)", cfg.FirestoreDatabase)
+	})
+
+	t.Run("should read the field encryption key from a file", func(t *testing.T) {
+		key := "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		t.Setenv("FIELD_ENCRYPTION_KEY_FILE", writeConfigFile(t, "key", key+"\n"))
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		assert.Equal(t, key, cfg.FieldEncryptionKey)
+
+		t.Setenv("FIELD_ENCRYPTION_KEY_FILE", "")
+		t.Setenv("FIELD_ENCRYPTION_KEY", "c2hvcnQ=")
+		_, err = Load("")
+		assert.ErrorContains(t, err, "fieldEncryptionKey should be 32 random bytes in base64")
+	})
+
+	t.Run("should validate the read preference and write concern", func(t *testing.T) {
+		t.Setenv("MONGO_READ_PREFERENCE", "secondaryPreferred")
+		t.Setenv("MONGO_WRITE_CONCERN", "2")
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		assert.Equal(t, "secondaryPreferred", cfg.MongoReadPreference)
+		assert.True(t, cfg.MongoRetryWrites)
+
+		t.Setenv("MONGO_READ_PREFERENCE", "fastest")
+		t.Setenv("MONGO_WRITE_CONCERN", "all")
+		_, err = Load("")
+		assert.ErrorContains(t, err, "mongoReadPreference should be primary")
+		assert.ErrorContains(t, err, "mongoWriteConcern should be majority or a number of nodes")
+	})
+
+	t.Run("should parse route timeouts", func(t *testing.T) {
+		t.Setenv("ROUTE_TIMEOUTS", "/contact/search=2s,/admin/seed=0")
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		timeouts, err := ParseRouteTimeouts(cfg.RouteTimeouts)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]time.Duration{"/contact/search": 2 * time.Second, "/admin/seed": 0}, timeouts)
+
+		t.Setenv("ROUTE_TIMEOUTS", "search=2s")
+		_, err = Load("")
+		assert.ErrorContains(t, err, "routeTimeouts")
+	})
+
+	t.Run("should parse the trusted proxies", func(t *testing.T) {
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.7,::1")
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		proxies, err := ParseTrustedProxies(cfg.TrustedProxies)
+		assert.Nil(t, err)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.7/32"), netip.MustParsePrefix("::1/128")}, proxies)
+
+		t.Setenv("TRUSTED_PROXIES", "proxy.local")
+		_, err = Load("")
+		assert.ErrorContains(t, err, "trustedProxies")
+	})
+
+	t.Run("should validate the crm sync", func(t *testing.T) {
+		t.Setenv("CRM_PROVIDER", "hubspot")
+		t.Setenv("CRM_TOKEN", "token")
+		t.Setenv("CRM_FIELD_MAPPING", "jobTitle=jobtitle, notes=hs_content")
+		cfg, err := Load("")
+		assert.Nil(t, err)
+		mapping, err := ParseCRMFieldMapping(cfg.CRMFieldMapping)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"jobTitle": "jobtitle", "notes": "hs_content"}, mapping)
+
+		t.Setenv("CRM_PROVIDER", "salesforce")
+		t.Setenv("CRM_FIELD_MAPPING", "owner=OwnerId")
+		_, err = Load("")
+		assert.ErrorContains(t, err, "crmURL, crmClientID and crmClientSecret are required")
+		assert.ErrorContains(t, err, `crmFieldMapping: unknown contact field "owner"`)
+	})
+
+	t.Run("should reject unsupported file", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", "{}")
+		_, err := Load(path)
+		assert.ErrorContains(t, err, "unsupported config file")
+	})
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+	return path
+}
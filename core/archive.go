@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"phoneBook/definition"
+	"strconv"
+)
+
+// includeArchivedParam is the parameter of the listings and searches
+// returning the archived contacts too.
+const includeArchivedParam = "includeArchived"
+
+// ArchiveContact archives the contact with id, hiding it from the listings
+// and searches without deleting it, or unarchives it when archived is false.
+// Nothing is modified when the contact already was in that state.
+func (pb *MongoPhoneBook) ArchiveContact(ctx context.Context, idParam string, archived bool, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	contact, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, ErrContactNotFound
+	}
+	if contact.Archived == archived {
+		return &definition.WriteResult{Matched: 1}, nil
+	}
+	update := bson.M{"$set": bson.M{"archived": true}}
+	if !archived {
+		update = bson.M{"$unset": bson.M{"archived": ""}}
+	}
+	return pb.updateVersioned(ctx, id, update, contact.Version, false, actor)
+}
+
+// validateIncludeArchivedParam tells whether query includes the archived
+// contacts, false by default.
+func validateIncludeArchivedParam(query url.Values) (bool, error) {
+	value := query.Get(includeArchivedParam)
+	if value == "" {
+		return false, nil
+	}
+	included, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, ErrInvalidArchived
+	}
+	return included, nil
+}
+
+// excludeArchived leaves the archived contacts out of filter, unless query
+// includes them.
+func excludeArchived(filter bson.M, query url.Values) error {
+	included, err := validateIncludeArchivedParam(query)
+	if err != nil {
+		return err
+	}
+	if !included {
+		filter["archived"] = bson.M{"$ne": true}
+	}
+	return nil
+}
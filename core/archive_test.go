@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestArchiveContact(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+
+	mt.Run("should archive the contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "firstName", Value: "Dani"}, {Key: "version", Value: int64(3)}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+		result, err := phoneBookMock.ArchiveContact(context.Background(), id.Hex(), true, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{Matched: 1, Modified: 1}, result)
+		update := mt.GetStartedEvent()
+		for update != nil && update.CommandName != "update" {
+			update = mt.GetStartedEvent()
+		}
+		assert.NotNil(t, update)
+		statement := update.Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, int64(3), statement.Lookup("q", "version").Int64(), "the archive should be guarded by the version read")
+		assert.True(t, statement.Lookup("u", "$set", "archived").Boolean())
+	})
+
+	mt.Run("should leave a contact archived already", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "archived", Value: true}}))
+		result, err := phoneBookMock.ArchiveContact(context.Background(), id.Hex(), true, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{Matched: 1}, result)
+	})
+
+	mt.Run("should not archive an unknown contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		_, err := phoneBookMock.ArchiveContact(context.Background(), id.Hex(), false, "tester")
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, StatusOf(err))
+
+		_, err = phoneBookMock.ArchiveContact(context.Background(), "not an id", true, "tester")
+		assert.ErrorIs(t, err, ErrInvalidID)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+}
+
+func TestExcludeArchived(t *testing.T) {
+	filter := bson.M{"company": "Acme"}
+	assert.Nil(t, excludeArchived(filter, url.Values{}))
+	assert.Equal(t, bson.M{"company": "Acme", "archived": bson.M{"$ne": true}}, filter)
+
+	filter = bson.M{}
+	assert.Nil(t, excludeArchived(filter, url.Values{"includeArchived": {"true"}}))
+	assert.Empty(t, filter)
+
+	assert.ErrorIs(t, excludeArchived(bson.M{}, url.Values{"includeArchived": {"yes"}}), ErrInvalidArchived)
+
+	search, err := buildSearchFilter(url.Values{"company": {"Acme"}, "includeArchived": {"true"}})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"company": "Acme"}, search, "includeArchived shouldn't be searched as a field")
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"phoneBook/definition"
+	"phoneBook/logging"
+)
+
+const (
+	// restoreBatchSize is the number of contacts written per bulk write.
+	restoreBatchSize = 1000
+	// maxRestoreErrors is the number of failures a restore reports in detail.
+	maxRestoreErrors = 10
+	// maxBSONDocumentSize is the largest document MongoDB stores.
+	maxBSONDocumentSize = 16 * 1024 * 1024
+)
+
+// backupDocument is a contact of a backup, kept as read so it's restored
+// with every field it was backed up with.
+type backupDocument struct {
+	id  primitive.ObjectID
+	raw bson.Raw
+}
+
+// Backup writes every contact to w in format, ordered by ID, and returns how
+// many were written. Unlike the other operations it isn't bound by the query
+// timeout, only by ctx.
+func (pb *MongoPhoneBook) Backup(ctx context.Context, format string, w io.Writer) (int64, string, error) {
+	if format != definition.BackupFormatJSON && format != definition.BackupFormatBSON {
+		return 0, BadRequest, ErrBackupFormat
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, InternalServerError, err
+	}
+	defer cursor.Close(ctx)
+	var count int64
+	for cursor.Next(ctx) {
+		if err := writeBackupDocument(w, format, cursor.Current); err != nil {
+			return count, InternalServerError, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, InternalServerError, err
+	}
+	return count, "", nil
+}
+
+func writeBackupDocument(w io.Writer, format string, document bson.Raw) error {
+	if format == definition.BackupFormatBSON {
+		_, err := w.Write(document)
+		return err
+	}
+	line, err := bson.MarshalExtJSON(document, true, false)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// Restore writes the contacts of a backup read from r in format. The whole
+// backup is read and validated before any contact is written, so an invalid
+// backup changes nothing. A contact failing to be written, e.g. as a
+// duplicate, doesn't stop the others. Contacts whose phone number was erased
+// since the backup are left out. With field encryption, backups hold the
+// encrypted fields and restored contacts are encrypted whether or not the backup was.
+func (pb *MongoPhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	if format != definition.BackupFormatJSON && format != definition.BackupFormatBSON {
+		return nil, ErrBackupFormat
+	}
+	if mode != definition.RestoreModeMerge && mode != definition.RestoreModeReplace {
+		return nil, ErrRestoreMode
+	}
+	documents, err := readBackup(r, format)
+	if err != nil {
+		return nil, ErrInvalidBackup.WithMessage(fmt.Sprintf("%s: %s", ErrorInvalidBackup, err))
+	}
+	erased, err := pb.erasedPhones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &definition.RestoreResult{Mode: mode}
+	kept := documents[:0]
+	for _, document := range documents {
+		raw, err := pb.cipher.openDocument(document.raw)
+		if err != nil {
+			return nil, ErrInvalidBackup.WithMessage(fmt.Sprintf("%s: contact %s: %s", ErrorInvalidBackup, document.id.Hex(), err))
+		}
+		if isErased(raw, erased) {
+			result.Erased++
+			continue
+		}
+		if document.raw, err = pb.cipher.sealDocument(raw); err != nil {
+			return nil, err
+		}
+		kept = append(kept, document)
+	}
+	documents = kept
+	// the restored contacts keep their update time, which the sync clients
+	// may have synced past
+	defer pb.recordTombstones(ctx, "", syncResetID)
+	if mode == definition.RestoreModeReplace {
+		deleteResult, err := pb.contactsCollection.DeleteMany(ctx, bson.D{})
+		if err != nil {
+			return nil, err
+		}
+		result.Deleted = deleteResult.DeletedCount
+	}
+	for start := 0; start < len(documents); start += restoreBatchSize {
+		batch := documents[start:min(start+restoreBatchSize, len(documents))]
+		models := make([]mongo.WriteModel, len(batch))
+		for i, document := range batch {
+			if mode == definition.RestoreModeReplace {
+				models[i] = mongo.NewInsertOneModel().SetDocument(document.raw)
+			} else {
+				models[i] = mongo.NewReplaceOneModel().SetFilter(bson.D{{Key: "_id", Value: document.id}}).
+					SetReplacement(document.raw).SetUpsert(true)
+			}
+		}
+		bulkResult, err := pb.contactsCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		var bulkErr mongo.BulkWriteException
+		if err != nil && !errors.As(err, &bulkErr) {
+			return nil, err
+		}
+		if bulkResult != nil {
+			result.Restored += bulkResult.InsertedCount + bulkResult.MatchedCount + bulkResult.UpsertedCount
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			result.Failed++
+			if len(result.Errors) < maxRestoreErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("contact %s: %s", batch[writeErr.Index].id.Hex(), logging.Redact(writeErr.Message)))
+			}
+		}
+	}
+	return result, nil
+}
+
+// readBackup reads every contact of a backup, each of which must have an
+// ObjectID _id. Contacts backed up before a derived field existed get it.
+func readBackup(r io.Reader, format string) ([]backupDocument, error) {
+	next := bsonDocumentReader(r)
+	if format == definition.BackupFormatJSON {
+		next = jsonDocumentReader(r)
+	}
+	var documents []backupDocument
+	for {
+		raw, err := next()
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("contact %d: %w", len(documents)+1, err)
+		}
+		id, ok := raw.Lookup("_id").ObjectIDOK()
+		if !ok {
+			return nil, fmt.Errorf("contact %d: missing ObjectID _id", len(documents)+1)
+		}
+		if raw, err = withDerivedFields(raw); err != nil {
+			return nil, fmt.Errorf("contact %d: %w", len(documents)+1, err)
+		}
+		documents = append(documents, backupDocument{id: id, raw: raw})
+	}
+}
+
+// jsonDocumentReader reads extended JSON documents one after another, as
+// written one per line by Backup.
+func jsonDocumentReader(r io.Reader) func() (bson.Raw, error) {
+	decoder := json.NewDecoder(r)
+	return func() (bson.Raw, error) {
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err != nil {
+			return nil, err
+		}
+		var document bson.Raw
+		if err := bson.UnmarshalExtJSON(line, true, &document); err != nil {
+			return nil, err
+		}
+		return document, nil
+	}
+}
+
+// bsonDocumentReader reads BSON documents one after another, each starting
+// with its length.
+func bsonDocumentReader(r io.Reader) func() (bson.Raw, error) {
+	return func() (bson.Raw, error) {
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, errors.New("truncated document")
+			}
+			return nil, err
+		}
+		size := binary.LittleEndian.Uint32(length[:])
+		if size < 5 || size > maxBSONDocumentSize {
+			return nil, fmt.Errorf("invalid document size %d", size)
+		}
+		document := make(bson.Raw, size)
+		copy(document, length[:])
+		if _, err := io.ReadFull(r, document[4:]); err != nil {
+			return nil, errors.New("truncated document")
+		}
+		if err := document.Validate(); err != nil {
+			return nil, err
+		}
+		return document, nil
+	}
+}
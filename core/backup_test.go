@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"strings"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	contacts := func(mt *mtest.T) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: firstID}, {Key: "firstName", Value: "Dani"}, {Key: "version", Value: int64(2)}},
+			bson.D{{Key: "_id", Value: secondID}, {Key: "firstName", Value: "Noa"}})
+	}
+
+	mt.Run("should write a JSON line per contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(contacts(mt))
+		var backup bytes.Buffer
+		count, _, err := phoneBookMock.Backup(context.Background(), definition.BackupFormatJSON, &backup)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+		lines := strings.Split(strings.TrimSpace(backup.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Equal(t, fmt.Sprintf(`{"_id":{"$oid":"%s"},"firstName":"Dani","version":{"$numberLong":"2"}}`, firstID.Hex()), lines[0])
+	})
+
+	mt.Run("should write BSON documents restorable as read", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(contacts(mt))
+		var backup bytes.Buffer
+		_, _, err := phoneBookMock.Backup(context.Background(), definition.BackupFormatBSON, &backup)
+		assert.Nil(t, err)
+		documents, err := readBackup(&backup, definition.BackupFormatBSON)
+		assert.Nil(t, err)
+		assert.Len(t, documents, 2)
+		assert.Equal(t, secondID, documents[1].id)
+		assert.Equal(t, "Noa", documents[1].raw.Lookup("firstName").StringValue())
+	})
+
+	mt.Run("should reject an unknown format", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.Backup(context.Background(), "xml", &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrBackupFormat)
+		assert.Equal(t, BadRequest, status)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	backup := fmt.Sprintf("{\"_id\":{\"$oid\":\"%s\"},\"firstName\":\"Dani\"}\n{\"_id\":{\"$oid\":\"%s\"},\"firstName\":\"Noa\"}\n",
+		firstID.Hex(), secondID.Hex())
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	erasures := func(mt *mtest.T, hashes ...string) bson.D {
+		var documents []bson.D
+		for _, hash := range hashes {
+			documents = append(documents, bson.D{{Key: "_id", Value: hash}})
+		}
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.erasures", mt.DB.Name()), mtest.FirstBatch, documents...)
+	}
+
+	mt.Run("should upsert the contacts by id when merging", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(erasures(mt), bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}, {Key: "nModified", Value: 1},
+			{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 1}, {Key: "_id", Value: secondID}}}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+		result, err := phoneBookMock.Restore(context.Background(), definition.BackupFormatJSON, definition.RestoreModeMerge, strings.NewReader(backup))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result.Restored)
+		assert.Equal(t, int64(0), result.Failed)
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent()
+		assert.Equal(t, "update", update.CommandName)
+		reset := mt.GetStartedEvent()
+		assert.Equal(t, "tombstones", reset.Command.Lookup("update").StringValue())
+		assert.Equal(t, syncResetID, reset.Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q", "_id").ObjectID())
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("should delete every contact first when replacing and report failures", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			erasures(mt),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 5}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "writeErrors", Value: bson.A{
+				bson.D{{Key: "index", Value: 0}, {Key: "code", Value: 11000}, {Key: "errmsg", Value: "duplicate key"}},
+			}}})
+		result, err := phoneBookMock.Restore(context.Background(), definition.BackupFormatJSON, definition.RestoreModeReplace, strings.NewReader(backup))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), result.Deleted)
+		assert.Equal(t, int64(1), result.Restored)
+		assert.Equal(t, int64(1), result.Failed)
+		assert.Equal(t, []string{fmt.Sprintf("contact %s: duplicate key", firstID.Hex())}, result.Errors)
+		assert.Equal(t, "find", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "delete", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "insert", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("should leave out the contacts whose phone was erased", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		withPhones := fmt.Sprintf("{\"_id\":{\"$oid\":\"%s\"},\"firstName\":\"Dani\",\"phone\":\"0521234567\"}\n"+
+			"{\"_id\":{\"$oid\":\"%s\"},\"firstName\":\"Noa\",\"phone\":\"0541111111\"}\n", firstID.Hex(), secondID.Hex())
+		mt.AddMockResponses(erasures(mt, hashPhone("972521234567")), bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		result, err := phoneBookMock.Restore(context.Background(), definition.BackupFormatJSON, definition.RestoreModeMerge, strings.NewReader(withPhones))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), result.Erased)
+		assert.Equal(t, int64(1), result.Restored)
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent()
+		assert.NotContains(t, update.Command.String(), firstID.Hex())
+		assert.Contains(t, update.Command.String(), secondID.Hex())
+	})
+
+	mt.Run("should reject an invalid backup without writing", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for _, invalid := range []string{backup + "{\"firstName\":\"Avi\"}\n", backup + "{\"_id\":"} {
+			_, err := phoneBookMock.Restore(context.Background(), definition.BackupFormatJSON, definition.RestoreModeReplace, strings.NewReader(invalid))
+			assert.ErrorIs(t, err, ErrInvalidBackup)
+			assert.Contains(t, err.Error(), "contact 3")
+			assert.Equal(t, BadRequest, StatusOf(err))
+		}
+		_, err := phoneBookMock.Restore(context.Background(), definition.BackupFormatBSON, definition.RestoreModeMerge, strings.NewReader("\x05\x00"))
+		assert.ErrorIs(t, err, ErrInvalidBackup)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("should reject an unknown mode", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.Restore(context.Background(), definition.BackupFormatJSON, "append", strings.NewReader(backup))
+		assert.ErrorIs(t, err, ErrRestoreMode)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+}
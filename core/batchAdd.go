@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+// AddContacts adds contacts through the import writer, for bulk ingestion,
+// and returns the result of each in order: the contacts failing validation or
+// duplicating existing ones are rejected, the others added like AddContact
+// does. Callers bound the batches, which are limited to the contact limit as
+// a whole. It waits while the writes of the phone book are all busy.
+func (pb *MongoPhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	results := make([]*definition.AddResult, len(contacts))
+	var valid []int
+	now := time.Now().UTC()
+	for i, contact := range contacts {
+		if err := validateContact(contact); err != nil {
+			results[i] = rejectedAdd(err)
+			continue
+		}
+		if contact.ID.IsZero() {
+			contact.ID = primitive.NewObjectID()
+		}
+		contact.Version = 1
+		contact.Relations = nil
+		contact.Archived = false
+		contact.SortWeight = 0
+		contact.Blocked = false
+		contact.Owner = definition.UserFromContext(ctx)
+		contact.SchemaVersion = schemaVersion
+		deriveFields(contact)
+		contact.CreatedAt = &now
+		contact.UpdatedAt = &now
+		results[i] = &definition.AddResult{ID: contact.ID.Hex()}
+		valid = append(valid, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+	limitCtx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if err := pb.checkContactLimit(limitCtx, int64(len(valid))); err != nil {
+		return nil, err
+	}
+	var written sync.WaitGroup
+	var mu sync.Mutex
+	var failure error
+	for _, i := range valid {
+		written.Add(1)
+		err := pb.importer.add(ctx, contacts[i], func(err error) {
+			defer written.Done()
+			switch {
+			case err == nil:
+				if pb.auditLog != nil {
+					pb.auditLog.Record(ctx, definition.AuditActionAdd, actor, contacts[i].ID, nil, contacts[i])
+				}
+			case errors.Is(err, ErrDuplicateContact):
+				results[i] = rejectedAdd(err)
+			default:
+				mu.Lock()
+				failure = err
+				mu.Unlock()
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	written.Wait()
+	if failure != nil {
+		return nil, failure
+	}
+	return results, nil
+}
+
+// rejectedAdd returns the result of a contact rejected with err.
+func rejectedAdd(err error) *definition.AddResult {
+	result := &definition.AddResult{Error: err.Error()}
+	var typedErr *definition.Error
+	if errors.As(err, &typedErr) {
+		result.Code = typedErr.Code
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestAddContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	newContacts := func() []*definition.Contact {
+		return []*definition.Contact{
+			{FirstName: "Dani", Phone: "0521234567"},
+			{FirstName: "Noa"},
+			{FirstName: "Avi", Phone: "0541111111"},
+		}
+	}
+
+	mt.Run("should add the valid contacts in one write", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		contacts := newContacts()
+		results, err := phoneBookMock.AddContacts(context.Background(), contacts, "etl")
+		assert.Nil(t, err)
+		assert.Equal(t, []*definition.AddResult{
+			{ID: contacts[0].ID.Hex()},
+			{Code: "MISSING_PHONE", Error: ErrorMissingPhone},
+			{ID: contacts[2].ID.Hex()},
+		}, results)
+		documents, _ := mt.GetStartedEvent().Command.Lookup("documents").Array().Values()
+		assert.Len(t, documents, 2)
+		assert.Equal(t, contacts[2].ID, documents[1].Document().Lookup("_id").ObjectID())
+		assert.Equal(t, "avi", documents[1].Document().Lookup("displayName").StringValue())
+	})
+
+	mt.Run("should reject the duplicates", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+		contacts := newContacts()
+		results, err := phoneBookMock.AddContacts(context.Background(), contacts, "etl")
+		assert.Nil(t, err)
+		assert.Equal(t, contacts[0].ID.Hex(), results[0].ID)
+		assert.Equal(t, &definition.AddResult{Code: "DUPLICATE_CONTACT", Error: ErrorDuplicateContact}, results[2])
+	})
+
+	mt.Run("should not write when every contact is invalid", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		results, err := phoneBookMock.AddContacts(context.Background(), []*definition.Contact{{Phone: "0521234567"}}, "etl")
+		assert.Nil(t, err)
+		assert.Equal(t, "MISSING_FIRST_NAME", results[0].Code)
+	})
+}
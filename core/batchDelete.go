@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"phoneBook/definition"
+)
+
+// maxBatchDelete is the most contacts a single batch delete may remove.
+const maxBatchDelete = 1000
+
+// DeleteContacts deletes the contacts selected by IDs or by filter, at most
+// maxBatchDelete of them, or only reports them in a dry run.
+func (pb *MongoPhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	filter, err := buildBatchDeleteFilter(batch)
+	if err != nil {
+		return nil, err
+	}
+	if err := pb.cipher.sealSearch(filter); err != nil {
+		return nil, err
+	}
+	// the selected contacts are found first, so exactly those are deleted and audited
+	findOptions := options.Find().SetLimit(maxBatchDelete + 1)
+	if pb.auditLog == nil || batch.DryRun {
+		findOptions.SetProjection(bson.M{"_id": 1})
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) > maxBatchDelete {
+		return nil, ErrBatchTooLarge
+	}
+	result := &definition.BatchDeleteResult{IDs: []string{}, DryRun: batch.DryRun}
+	ids := make([]primitive.ObjectID, len(contacts))
+	for i, contact := range contacts {
+		ids[i] = contact.ID
+		result.IDs = append(result.IDs, contact.ID.Hex())
+	}
+	if batch.DryRun {
+		result.Deleted = int64(len(ids))
+		return result, nil
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	deleteResult, err := pb.contactsCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	result.Deleted = deleteResult.DeletedCount
+	if pb.auditLog != nil {
+		for _, contact := range contacts {
+			pb.auditLog.Record(ctx, definition.AuditActionDelete, actor, contact.ID, contact, nil)
+		}
+	}
+	pb.recordTombstones(ctx, actor, ids...)
+	return result, nil
+}
+
+// buildBatchDeleteFilter returns the filter of the contacts a batch selects.
+// Exactly one of IDs and filter is accepted, and the filter can't be empty,
+// so a batch never deletes every contact by mistake.
+func buildBatchDeleteFilter(batch *definition.BatchDelete) (bson.M, error) {
+	if (len(batch.IDs) == 0) == (len(batch.Filter) == 0) {
+		return nil, ErrInvalidBatch
+	}
+	if len(batch.IDs) > 0 {
+		if len(batch.IDs) > maxBatchDelete {
+			return nil, ErrBatchTooLarge
+		}
+		ids := make([]primitive.ObjectID, len(batch.IDs))
+		for i, idParam := range batch.IDs {
+			id, err := primitive.ObjectIDFromHex(idParam)
+			if err != nil {
+				return nil, ErrInvalidID.WithMessage(fmt.Sprintf("%s: %s", ErrInvalidID.Error(), idParam))
+			}
+			ids[i] = id
+		}
+		return bson.M{"_id": bson.M{"$in": ids}}, nil
+	}
+	query := url.Values{}
+	for field, value := range batch.Filter {
+		if !searchableFields[field] {
+			return nil, ErrUnknownField.WithField(field).WithMessage(fmt.Sprintf("%s: %s", ErrorUnknownField, field))
+		}
+		query.Set(field, value)
+	}
+	return buildSearchFilter(query)
+}
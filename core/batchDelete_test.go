@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestDeleteContacts(t *testing.T) {
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	matchingContacts := func(mt *mtest.T) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: firstID}}, bson.D{{Key: "_id", Value: secondID}})
+	}
+
+	mt.Run("should delete contacts matching filter", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(matchingContacts(mt), bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}})
+		batch := &definition.BatchDelete{Filter: map[string]string{"address": "Tel Aviv"}}
+		result, err := phoneBookMock.DeleteContacts(context.Background(), batch, "")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result.Deleted)
+		assert.Equal(t, []string{firstID.Hex(), secondID.Hex()}, result.IDs)
+		mt.GetStartedEvent()
+		deleteCommand := mt.GetStartedEvent()
+		assert.Equal(t, "delete", deleteCommand.CommandName)
+	})
+
+	mt.Run("should only report contacts in dry run", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(matchingContacts(mt))
+		batch := &definition.BatchDelete{IDs: []string{firstID.Hex(), secondID.Hex()}, DryRun: true}
+		result, err := phoneBookMock.DeleteContacts(context.Background(), batch, "")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result.Deleted)
+		assert.True(t, result.DryRun)
+		mt.GetStartedEvent()
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("should reject batch without ids or filter", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.DeleteContacts(context.Background(), &definition.BatchDelete{}, "")
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+
+	mt.Run("should reject filter on unknown field", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		batch := &definition.BatchDelete{Filter: map[string]string{"tag": "friends"}}
+		_, err := phoneBookMock.DeleteContacts(context.Background(), batch, "")
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+// queuedContact is a contact queued by an import, with the callback told
+// once it's written.
+type queuedContact struct {
+	contact *definition.Contact
+	done    func(err error)
+}
+
+// batchWriter inserts the contacts of the import paths in ordered InsertMany
+// batches rather than one by one. The contacts queued by the concurrent
+// imports of a phone book are written together, a batch once it holds size
+// contacts or interval after its first contact was queued, by up to
+// concurrency writes at once. Queuing blocks while every write is busy, which
+// holds the imports back, and the requests feeding them, rather than
+// buffering their contacts.
+type batchWriter struct {
+	collection *mongo.Collection
+	size       int
+	interval   time.Duration
+	timeout    time.Duration
+	// slots holds a token per write in flight
+	slots   chan struct{}
+	mu      sync.Mutex
+	pending []queuedContact
+	// batch counts the batches flushed, for the timer of a batch flushed
+	// already to leave the next one be
+	batch int
+}
+
+func newBatchWriter(collection *mongo.Collection, size int, interval time.Duration, concurrency int, timeout time.Duration) *batchWriter {
+	return &batchWriter{
+		collection: collection,
+		size:       max(size, 1),
+		interval:   interval,
+		timeout:    timeout,
+		slots:      make(chan struct{}, max(concurrency, 1)),
+	}
+}
+
+// add queues contact, calling done once its batch is written, with
+// ErrDuplicateContact when it duplicates an existing contact, or the error the
+// batch failed with. done is called from another goroutine. add fails when ctx
+// is done while waiting for a write, contact being written still.
+func (w *batchWriter) add(ctx context.Context, contact *definition.Contact, done func(err error)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, queuedContact{contact: contact, done: done})
+	if len(w.pending) == 1 {
+		batch := w.batch
+		time.AfterFunc(w.interval, func() { w.flushBatch(batch) })
+	}
+	if len(w.pending) < w.size {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+// flushBatch writes the pending contacts once their interval passed, unless
+// they filled a batch meanwhile.
+func (w *batchWriter) flushBatch(batch int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.batch != batch || len(w.pending) == 0 {
+		return
+	}
+	w.flush(context.Background())
+}
+
+// flush starts writing the pending contacts once a write is free. w.mu must be
+// held.
+func (w *batchWriter) flush(ctx context.Context) error {
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	batch := w.pending
+	w.pending = nil
+	w.batch++
+	go func() {
+		defer func() { <-w.slots }()
+		w.write(batch)
+	}()
+	return nil
+}
+
+// write inserts batch in order. The insert stops at the first failure, so
+// past a duplicate the contacts after it are inserted again.
+func (w *batchWriter) write(batch []queuedContact) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if w.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), w.timeout)
+	}
+	defer cancel()
+	for len(batch) > 0 {
+		documents := make([]interface{}, len(batch))
+		for i, queued := range batch {
+			documents[i] = queued.contact
+		}
+		_, err := w.collection.InsertMany(ctx, documents)
+		if err == nil {
+			written(batch, nil)
+			return
+		}
+		var writeErr mongo.BulkWriteException
+		if !errors.As(err, &writeErr) || writeErr.WriteConcernError != nil || len(writeErr.WriteErrors) == 0 {
+			written(batch, err)
+			return
+		}
+		failed := writeErr.WriteErrors[0]
+		written(batch[:failed.Index], nil)
+		if !mongo.IsDuplicateKeyError(failed.WriteError) {
+			written(batch[failed.Index:], err)
+			return
+		}
+		batch[failed.Index].done(ErrDuplicateContact)
+		batch = batch[failed.Index+1:]
+	}
+}
+
+func written(batch []queuedContact, err error) {
+	for _, queued := range batch {
+		queued.done(err)
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	// write queues count contacts and returns the error each was written with
+	write := func(w *batchWriter, count int) []error {
+		errs := make([]error, count)
+		var written sync.WaitGroup
+		for i := range errs {
+			written.Add(1)
+			w.add(context.Background(), &definition.Contact{ID: primitive.NewObjectID()}, func(err error) {
+				errs[i] = err
+				written.Done()
+			})
+		}
+		written.Wait()
+		return errs
+	}
+
+	mt.Run("should write full batches in order and the rest after the interval", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		started := time.Now()
+		errs := write(newBatchWriter(mt.Coll, 2, 50*time.Millisecond, 1, time.Second), 3)
+		assert.Equal(t, []error{nil, nil, nil}, errs)
+		assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+		first := mt.GetStartedEvent().Command
+		documents, _ := first.Lookup("documents").Array().Values()
+		assert.Len(t, documents, 2)
+		assert.True(t, first.Lookup("ordered").Boolean())
+		documents, _ = mt.GetStartedEvent().Command.Lookup("documents").Array().Values()
+		assert.Len(t, documents, 1)
+	})
+
+	mt.Run("should go on past a duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}), mtest.CreateSuccessResponse())
+		errs := write(newBatchWriter(mt.Coll, 4, time.Minute, 1, time.Second), 4)
+		assert.Equal(t, []error{nil, ErrDuplicateContact, nil, nil}, errs)
+		mt.GetStartedEvent()
+		documents, _ := mt.GetStartedEvent().Command.Lookup("documents").Array().Values()
+		assert.Len(t, documents, 2)
+	})
+
+	mt.Run("should fail the contacts from a failed one on", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 121, Message: "validation failed"}))
+		errs := write(newBatchWriter(mt.Coll, 3, time.Minute, 1, time.Second), 3)
+		assert.Nil(t, errs[0])
+		assert.NotNil(t, errs[1])
+		assert.NotErrorIs(t, errs[1], ErrDuplicateContact)
+		assert.Equal(t, errs[1], errs[2])
+	})
+
+	mt.Run("should hold the imports back while the writes are busy", func(mt *mtest.T) {
+		w := newBatchWriter(mt.Coll, 1, time.Minute, 1, time.Second)
+		w.slots <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := w.add(ctx, &definition.Contact{}, func(error) {})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
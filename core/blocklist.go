@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"strings"
+)
+
+const blocklistIndexName = "contacts_blocklist"
+
+// ensureBlocklistIndex creates the index the blocklist is listed and checked
+// by. It holds the blocked contacts alone, so it stays small and the checks
+// fast.
+func (pb *MongoPhoneBook) ensureBlocklistIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys: bson.D{{Key: "blocked", Value: 1}, {Key: "normalizedPhone", Value: 1}},
+		Options: options.Index().SetName(blocklistIndexName).
+			SetPartialFilterExpression(bson.M{"blocked": true}),
+	}
+	_, err := pb.contactsCollection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// BlockContact puts the phone of the contact with id on the blocklist, or
+// takes it off when blocked is false. Nothing is modified when the contact
+// already was in that state.
+func (pb *MongoPhoneBook) BlockContact(ctx context.Context, idParam string, blocked bool, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	contact, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, ErrContactNotFound
+	}
+	if contact.Blocked == blocked {
+		return &definition.WriteResult{Matched: 1}, nil
+	}
+	update := bson.M{"$set": bson.M{"blocked": true}}
+	if !blocked {
+		update = bson.M{"$unset": bson.M{"blocked": ""}}
+	}
+	return pb.updateVersioned(ctx, id, update, contact.Version, false, actor)
+}
+
+// GetBlocklist returns the numbers of the blocked contacts, archived ones
+// included, in the order they were added.
+func (pb *MongoPhoneBook) GetBlocklist(ctx context.Context) ([]*definition.BlockedNumber, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	findOptions := options.Find().
+		SetProjection(bson.M{"firstName": 1, "lastName": 1, "phone": 1}).
+		SetSort(bson.M{"_id": 1})
+	cursor, err := pb.contactsCollection.Find(ctx, bson.M{"blocked": true}, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	numbers := make([]*definition.BlockedNumber, 0, len(contacts))
+	for _, contact := range contacts {
+		numbers = append(numbers, &definition.BlockedNumber{
+			Number:    normalizePhone(contact.Phone),
+			Phone:     contact.Phone,
+			ContactID: contact.ID,
+			Name:      strings.Join(strings.Fields(contact.FirstName+" "+contact.LastName), " "),
+		})
+	}
+	return numbers, "", nil
+}
+
+// CheckBlocked tells whether number, normalized like for the phone lookup,
+// is the phone of a blocked contact.
+func (pb *MongoPhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(number) > config.Static.MaxSizeProperty || !formattedPhoneRegex.MatchString(number) {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	normalized := normalizePhone(number)
+	if normalized == "" {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	filter := bson.M{"blocked": true, "normalizedPhone": pb.cipher.phoneIndex(normalized)}
+	err := pb.contactsCollection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, InternalServerError, err
+	}
+	return &definition.BlockCheck{Number: normalized, Blocked: err == nil}, "", nil
+}
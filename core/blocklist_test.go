@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestBlockContact(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+
+	mt.Run("should block the contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "version", Value: int64(2)}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+		result, err := phoneBookMock.BlockContact(context.Background(), id.Hex(), true, "reception")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{Matched: 1, Modified: 1}, result)
+		update := mt.GetStartedEvent()
+		for update != nil && update.CommandName != "update" {
+			update = mt.GetStartedEvent()
+		}
+		assert.NotNil(t, update)
+		statement := update.Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.True(t, statement.Lookup("u", "$set", "blocked").Boolean())
+	})
+
+	mt.Run("should leave a contact blocked already", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "blocked", Value: true}}))
+		result, err := phoneBookMock.BlockContact(context.Background(), id.Hex(), true, "reception")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{Matched: 1}, result)
+	})
+
+	mt.Run("should not block an unknown contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		_, err := phoneBookMock.BlockContact(context.Background(), id.Hex(), true, "reception")
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, StatusOf(err))
+
+		_, err = phoneBookMock.BlockContact(context.Background(), "not an id", true, "reception")
+		assert.ErrorIs(t, err, ErrInvalidID)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+}
+
+func TestCheckBlocked(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should find a blocked number however formatted", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}}))
+		check, _, err := phoneBookMock.CheckBlocked(context.Background(), "+972 52-123-4567")
+		assert.Nil(t, err)
+		assert.Equal(t, "972521234567", check.Number)
+		assert.True(t, check.Blocked)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.True(t, filter.Lookup("blocked").Boolean())
+		assert.Equal(t, "972521234567", filter.Lookup("normalizedPhone").StringValue())
+	})
+
+	mt.Run("should not find a number not blocked", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		check, _, err := phoneBookMock.CheckBlocked(context.Background(), "0521234567")
+		assert.Nil(t, err)
+		assert.False(t, check.Blocked)
+	})
+
+	mt.Run("should reject an invalid number", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.CheckBlocked(context.Background(), "dani")
+		assert.ErrorIs(t, err, ErrInvalidLookup)
+		assert.Equal(t, BadRequest, status)
+	})
+}
+
+func TestGetBlocklist(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+
+	mt.Run("should list the normalized numbers of the blocked contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: id}, {Key: "firstName", Value: "Spam"}, {Key: "lastName", Value: "Caller"}, {Key: "phone", Value: "052-1234567"}}))
+		numbers, _, err := phoneBookMock.GetBlocklist(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, numbers, 1)
+		assert.Equal(t, "972521234567", numbers[0].Number)
+		assert.Equal(t, "052-1234567", numbers[0].Phone)
+		assert.Equal(t, "Spam Caller", numbers[0].Name)
+		assert.Equal(t, id, numbers[0].ContactID)
+	})
+}
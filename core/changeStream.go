@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// changeStreamHistoryLostCode is the error of a change stream resumed from
+// a token the oplog no longer holds.
+const changeStreamHistoryLostCode = 286
+
+// contactChangeEvent is a change event of the contacts collection, the
+// fields the phone book reads of it.
+type contactChangeEvent struct {
+	OperationType string `bson:"operationType"`
+	NS            struct {
+		DB string `bson:"db"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument bson.RawValue       `bson:"fullDocument"`
+	ClusterTime  primitive.Timestamp `bson:"clusterTime"`
+	// WallTime is set by MongoDB 6.0 and later.
+	WallTime time.Time `bson:"wallTime"`
+}
+
+// WatchContacts tails the changes of the contacts, by any writer, calling
+// handle with each in order until ctx is done or the stream fails. With
+// tenants it watches the contacts of the tenant databases too, which needs
+// the changeStream privilege on the whole deployment. It resumes after
+// resumeToken, unless it's nil or the oplog no longer holds it, in which
+// case the changes since are lost. Change streams need a replica set.
+func WatchContacts(ctx context.Context, client *mongo.Client, tenants bool, resumeToken []byte, handle func(change *definition.StreamedChange)) error {
+	stream, err := openContactStream(ctx, client, tenants, resumeToken)
+	var commandErr mongo.CommandError
+	if resumeToken != nil && errors.As(err, &commandErr) && commandErr.Code == changeStreamHistoryLostCode {
+		logrus.Warn("the contacts change stream can't resume where it stopped, the changes since are lost")
+		stream, err = openContactStream(ctx, client, tenants, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to watch the contacts: %w", err)
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+	registry := contactRegistry()
+	for stream.Next(ctx) {
+		change, err := decodeStreamedChange(stream.Current, registry)
+		if err != nil {
+			logrus.WithError(err).Warn("skipping an unreadable change of the contacts")
+			continue
+		}
+		change.ResumeToken = append([]byte(nil), stream.ResumeToken()...)
+		handle(change)
+	}
+	return stream.Err()
+}
+
+func openContactStream(ctx context.Context, client *mongo.Client, tenants bool, resumeToken []byte) (*mongo.ChangeStream, error) {
+	streamOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != nil {
+		streamOptions.SetResumeAfter(bson.Raw(resumeToken))
+	}
+	match := bson.D{{Key: "operationType", Value: bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}
+	if !tenants {
+		collection := client.Database(config.Static.MongoDBName).Collection(config.Static.MongoCollectionName)
+		return collection.Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, streamOptions)
+	}
+	// the main database and those of the tenants, named after it
+	databases := "^" + regexp.QuoteMeta(config.Static.MongoDBName) + "(_.+)?$"
+	match = append(match,
+		bson.E{Key: "ns.db", Value: bson.M{"$regex": databases}},
+		bson.E{Key: "ns.coll", Value: config.Static.MongoCollectionName})
+	return client.Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, streamOptions)
+}
+
+// contactRegistry returns the registry the contacts are decoded with,
+// decrypting their fields with field encryption.
+func contactRegistry() *bsoncodec.Registry {
+	if registry := fieldCipherFromConfig().collectionOptions().Registry; registry != nil {
+		return registry
+	}
+	return bson.DefaultRegistry
+}
+
+func decodeStreamedChange(raw bson.Raw, registry *bsoncodec.Registry) (*definition.StreamedChange, error) {
+	var event contactChangeEvent
+	if err := bson.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+	change := &definition.StreamedChange{
+		ID:         event.DocumentKey.ID.Hex(),
+		Created:    event.OperationType == "insert",
+		Deleted:    event.OperationType == "delete",
+		OccurredAt: event.WallTime.UTC(),
+	}
+	if event.WallTime.IsZero() {
+		change.OccurredAt = time.Unix(int64(event.ClusterTime.T), 0).UTC()
+	}
+	if event.NS.DB != config.Static.MongoDBName {
+		change.Tenant = strings.TrimPrefix(event.NS.DB, config.Static.MongoDBName+"_")
+	}
+	// updated contacts are looked up when the change is read, so they may
+	// have been deleted since
+	if event.FullDocument.Type == bson.TypeEmbeddedDocument {
+		var contact definition.Contact
+		if err := bson.UnmarshalWithRegistry(registry, event.FullDocument.Value, &contact); err != nil {
+			return nil, err
+		}
+		change.Contact = &contact
+	}
+	return change, nil
+}
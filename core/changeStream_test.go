@@ -0,0 +1,68 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestDecodeStreamedChange(t *testing.T) {
+	config.Static.FieldEncryptionKey = testEncryptionKey
+	defer func() { config.Static.FieldEncryptionKey = "" }()
+	registry := contactRegistry()
+	id := primitive.NewObjectID()
+	wallTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should decrypt the contact of an insert", func(t *testing.T) {
+		contact, err := bson.MarshalWithRegistry(registry, &definition.Contact{ID: id, FirstName: "Dani", Phone: "0521234567", Version: 1})
+		assert.Nil(t, err)
+		raw, _ := bson.Marshal(bson.M{
+			"operationType": "insert",
+			"ns":            bson.M{"db": config.Static.MongoDBName, "coll": config.Static.MongoCollectionName},
+			"documentKey":   bson.M{"_id": id},
+			"fullDocument":  bson.Raw(contact),
+			"wallTime":      wallTime,
+		})
+		change, err := decodeStreamedChange(raw, registry)
+		assert.Nil(t, err)
+		assert.True(t, change.Created)
+		assert.Equal(t, "", change.Tenant)
+		assert.Equal(t, id.Hex(), change.ID)
+		assert.Equal(t, "0521234567", change.Contact.Phone)
+		assert.Equal(t, int64(1), change.Contact.Version)
+		assert.Equal(t, wallTime, change.OccurredAt)
+	})
+
+	t.Run("should read the tenant of the database of a delete", func(t *testing.T) {
+		raw, _ := bson.Marshal(bson.M{
+			"operationType": "delete",
+			"ns":            bson.M{"db": TenantDatabase("acme"), "coll": config.Static.MongoCollectionName},
+			"documentKey":   bson.M{"_id": id},
+			"clusterTime":   primitive.Timestamp{T: uint32(wallTime.Unix()), I: 1},
+		})
+		change, err := decodeStreamedChange(raw, registry)
+		assert.Nil(t, err)
+		assert.True(t, change.Deleted)
+		assert.Equal(t, "acme", change.Tenant)
+		assert.Nil(t, change.Contact)
+		assert.Equal(t, wallTime, change.OccurredAt)
+	})
+
+	t.Run("should leave out the contact of an update deleted since", func(t *testing.T) {
+		raw, _ := bson.Marshal(bson.M{
+			"operationType": "update",
+			"ns":            bson.M{"db": config.Static.MongoDBName, "coll": config.Static.MongoCollectionName},
+			"documentKey":   bson.M{"_id": id},
+			"fullDocument":  nil,
+			"wallTime":      wallTime,
+		})
+		change, err := decodeStreamedChange(raw, registry)
+		assert.Nil(t, err)
+		assert.False(t, change.Created || change.Deleted)
+		assert.Nil(t, change.Contact)
+	})
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GetCompanyContacts returns a page of the contacts working at the company
+// named name, paginated like GetContactWithPagination.
+func (pb *MongoPhoneBook) GetCompanyContacts(ctx context.Context, name string, query url.Values) (*definition.ContactPage, string, error) {
+	if !isText(name) || len(name) > config.Static.MaxSizeProperty {
+		return nil, BadRequest, ErrInvalidCompany
+	}
+	filter := bson.M{"company": name}
+	if err := excludeArchived(filter, query); err != nil {
+		return nil, BadRequest, err
+	}
+	return pb.listContacts(ctx, filter, query)
+}
+
+// ListCompanies returns the distinct companies of the contacts starting with
+// prefix, ignoring case, in alphabetical order, for autocompletion. Up to
+// MaxPageSize companies are returned.
+func (pb *MongoPhoneBook) ListCompanies(ctx context.Context, prefix string) ([]string, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(prefix) > config.Static.MaxSizeProperty {
+		return nil, BadRequest, ErrInvalidSearchValue.WithField("prefix")
+	}
+	filter := bson.M{"company": bson.M{"$exists": true}}
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		filter["company"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix), "$options": "i"}
+	}
+	values, err := pb.contactsCollection.Distinct(ctx, "company", filter)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	companies := []string{}
+	for _, value := range values {
+		if company, ok := value.(string); ok {
+			companies = append(companies, company)
+		}
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return strings.ToLower(companies[i]) < strings.ToLower(companies[j])
+	})
+	if limit := int(pb.tunables().MaxPageSize); len(companies) > limit {
+		companies = companies[:limit]
+	}
+	return companies, "", nil
+}
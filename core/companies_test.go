@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"testing"
+)
+
+func TestCompanies(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should list the contacts of a company", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Dani"}, {Key: "company", Value: "Acme"}}))
+		page, _, err := phoneBookMock.GetCompanyContacts(context.Background(), "Acme", url.Values{"count": {"false"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "Acme", filter.Lookup("company").StringValue())
+	})
+
+	mt.Run("should reject a blank company", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.GetCompanyContacts(context.Background(), " ", url.Values{})
+		assert.ErrorIs(t, err, ErrInvalidCompany)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should list the distinct companies alphabetically", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{"acme labs", "Acme", "Ace"}}})
+		companies, _, err := phoneBookMock.ListCompanies(context.Background(), "ac")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Ace", "Acme", "acme labs"}, companies)
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, "company", command.Lookup("key").StringValue())
+		assert.Equal(t, "^ac", command.Lookup("query", "company", "$regex").StringValue())
+	})
+}
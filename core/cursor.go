@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+)
+
+// getContactsAfterCursor returns the page of up to limit contacts matching
+// filter following lastID, the contact the previous page ended with, in _id
+// order. A nil lastID starts from the first contact, and a nil projection
+// returns every field.
+func (pb *MongoPhoneBook) getContactsAfterCursor(ctx context.Context, filter bson.M, lastID primitive.ObjectID, limit int64, projection bson.M) (*definition.ContactPage, string, error) {
+	if !lastID.IsZero() {
+		after := bson.M{"_id": bson.M{"$gt": lastID}}
+		for key, value := range filter {
+			after[key] = value
+		}
+		filter = after
+	}
+	// one extra contact tells whether another page exists
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit + 1)
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	page := &definition.ContactPage{Items: contacts}
+	if int64(len(contacts)) > limit {
+		page.Items = contacts[:limit]
+		page.NextCursor = encodeCursor(page.Items[limit-1].ID)
+	}
+	return page, "", nil
+}
+
+func encodeCursor(lastID primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString(lastID[:])
+}
+
+// decodeCursor returns the contact id a cursor token was issued for. An empty
+// token decodes to the nil id.
+func decodeCursor(token string) (primitive.ObjectID, error) {
+	var lastID primitive.ObjectID
+	if token == "" {
+		return lastID, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != len(lastID) {
+		return lastID, ErrInvalidCursor
+	}
+	copy(lastID[:], raw)
+	return lastID, nil
+}
+
+func decodeContacts(ctx context.Context, cursor *mongo.Cursor) ([]*definition.Contact, error) {
+	defer cursor.Close(ctx)
+	var contacts []*definition.Contact
+	for cursor.Next(ctx) {
+		var contact *definition.Contact
+		err := cursor.Decode(&contact)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+)
+
+// backfillBatchSize is the number of contacts written per bulk write.
+const backfillBatchSize = 1000
+
+// deriveFields sets the fields of contact derived from the others.
+func deriveFields(contact *definition.Contact) {
+	contact.DisplayName = displayName(contact)
+	contact.NormalizedPhone = normalizePhone(contact.Phone)
+	contact.Initial = initial(contact)
+	setPhoneInfo(contact)
+}
+
+// withDerivedFields returns the contact document with the derived fields it
+// is missing set.
+func withDerivedFields(raw bson.Raw) (bson.Raw, error) {
+	var contact definition.Contact
+	if err := bson.Unmarshal(raw, &contact); err != nil {
+		return nil, err
+	}
+	var document bson.D
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	missing := missingDerivedFields(&contact)
+	if len(missing) == 0 {
+		return raw, nil
+	}
+	return bson.Marshal(append(document, missing...))
+}
+
+// missingDerivedFields returns the derived fields contact is stored without.
+func missingDerivedFields(contact *definition.Contact) bson.D {
+	var missing bson.D
+	if contact.DisplayName == "" {
+		missing = append(missing, bson.E{Key: "displayName", Value: displayName(contact)})
+	}
+	if contact.NormalizedPhone == "" && contact.Phone != "" {
+		missing = append(missing, bson.E{Key: "normalizedPhone", Value: normalizePhone(contact.Phone)})
+	}
+	if contact.Initial == "" {
+		missing = append(missing, bson.E{Key: "initial", Value: initial(contact)})
+	}
+	if contact.PhoneCountry == "" && contact.Phone != "" {
+		derived := *contact
+		setPhoneInfo(&derived)
+		info := phoneInfo(&derived)
+		for _, field := range phoneInfoFields {
+			if value := info[field]; value != "" {
+				missing = append(missing, bson.E{Key: field, Value: value})
+			}
+		}
+	}
+	return missing
+}
+
+// BackfillDerivedFields sets the derived fields of the contacts stored
+// without them, like contacts written before a field existed, and returns how
+// many contacts were updated.
+func (pb *MongoPhoneBook) BackfillDerivedFields(ctx context.Context) (int64, error) {
+	missing := bson.M{"$or": bson.A{
+		bson.M{"displayName": bson.M{"$exists": false}},
+		bson.M{"normalizedPhone": bson.M{"$exists": false}},
+		bson.M{"initial": bson.M{"$exists": false}},
+		bson.M{"phone": bson.M{"$exists": true}, "phoneCountry": bson.M{"$exists": false}},
+	}}
+	cursor, err := pb.contactsCollection.Find(ctx, missing,
+		options.Find().SetProjection(bson.M{"firstName": 1, "lastName": 1, "phone": 1, "displayName": 1, "normalizedPhone": 1, "initial": 1, "phoneCountry": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var updated int64
+	var models []mongo.WriteModel
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		result, err := pb.contactsCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			updated += result.ModifiedCount
+		}
+		models = models[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var contact definition.Contact
+		if err := cursor.Decode(&contact); err != nil {
+			return updated, err
+		}
+		set := missingDerivedFields(&contact)
+		if len(set) == 0 {
+			continue
+		}
+		// a contact changed meanwhile already has its derived fields
+		filter := bson.M{"_id": contact.ID}
+		for i, field := range set {
+			filter[field.Key] = bson.M{"$exists": false}
+			if pb.cipher != nil {
+				if set[i].Value, err = pb.cipher.seal(field.Key, field.Value.(string)); err != nil {
+					return updated, err
+				}
+			}
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": set}))
+		if len(models) == backfillBatchSize {
+			if err := flush(); err != nil {
+				return updated, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return updated, err
+	}
+	return updated, flush()
+}
+
+// RebuildDerivedData recomputes the derived fields of every contact, setting
+// those gone stale, like after a change to how phones are normalized, then
+// drops and creates anew the text and secondary indexes. progress is told
+// the contacts gone through after every batch. Contacts changed meanwhile
+// derive their fields on their own, and are left alone.
+func (pb *MongoPhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	total, err := pb.contactsCollection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"firstName": 1, "lastName": 1, "phone": 1, "displayName": 1, "normalizedPhone": 1, "initial": 1,
+			"phoneCountry": 1, "phoneType": 1, "phoneArea": 1, "version": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	result := &definition.RebuildResult{}
+	var models []mongo.WriteModel
+	flush := func() error {
+		progress(result.Contacts, total)
+		if len(models) == 0 {
+			return nil
+		}
+		written, err := pb.contactsCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if written != nil {
+			result.Updated += written.ModifiedCount
+		}
+		models = models[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var contact definition.Contact
+		if err := cursor.Decode(&contact); err != nil {
+			return nil, err
+		}
+		result.Contacts++
+		if set := staleDerivedFields(&contact, pb.cipher); len(set) > 0 {
+			filter := bson.M{"_id": contact.ID, "version": contact.Version}
+			if contact.Version == 0 {
+				filter["version"] = bson.M{"$exists": false}
+			}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": set}))
+		}
+		if result.Contacts%backfillBatchSize == 0 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if result.Indexes, err = pb.rebuildIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// staleDerivedFields returns the derived fields of contact whose stored
+// value isn't the one its fields derive, the normalized phone as cipher
+// indexes it.
+func staleDerivedFields(contact *definition.Contact, cipher *fieldCipher) bson.D {
+	normalizedPhone := normalizePhone(contact.Phone)
+	if normalizedPhone != "" {
+		normalizedPhone = cipher.phoneIndex(normalizedPhone)
+	}
+	derived := *contact
+	setPhoneInfo(&derived)
+	var stale bson.D
+	for _, field := range []struct {
+		name   string
+		stored string
+		value  string
+	}{
+		{"displayName", contact.DisplayName, displayName(contact)},
+		{"normalizedPhone", contact.NormalizedPhone, normalizedPhone},
+		{"initial", contact.Initial, initial(contact)},
+		{"phoneCountry", contact.PhoneCountry, derived.PhoneCountry},
+		{"phoneType", contact.PhoneType, derived.PhoneType},
+		{"phoneArea", contact.PhoneArea, derived.PhoneArea},
+	} {
+		if field.stored != field.value {
+			stale = append(stale, bson.E{Key: field.name, Value: field.value})
+		}
+	}
+	return stale
+}
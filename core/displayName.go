@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"phoneBook/definition"
+	"strings"
+	"unicode"
+)
+
+// normalizeDisplayName lower cases the words of name and strips their
+// diacritics, so "Émile Zola" and "emile zola" sort and match alike.
+func normalizeDisplayName(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+	return strings.Join(strings.Fields(strings.ToLower(stripped)), " ")
+}
+
+// displayName is the normalized first and last name of contact.
+func displayName(contact *definition.Contact) string {
+	return normalizeDisplayName(contact.FirstName + " " + contact.LastName)
+}
+
+// refreshDisplayName derives the display name and initial of contact, as
+// read after an update that may have renamed it. They are only stored if the
+// names are still those read, otherwise the update that changed them
+// refreshes them.
+func (pb *MongoPhoneBook) refreshDisplayName(ctx context.Context, contact *definition.Contact) error {
+	name, letter := displayName(contact), initial(contact)
+	if name == contact.DisplayName && letter == contact.Initial {
+		return nil
+	}
+	_, err := pb.contactsCollection.UpdateOne(ctx, namesFilter(contact), bson.M{"$set": bson.M{"displayName": name, "initial": letter}})
+	if err != nil {
+		return err
+	}
+	contact.DisplayName, contact.Initial = name, letter
+	return nil
+}
+
+// namesFilter matches the contact while its names are those of contact.
+func namesFilter(contact *definition.Contact) bson.M {
+	filter := bson.M{"_id": contact.ID, "firstName": contact.FirstName, "lastName": nil}
+	if contact.LastName != "" {
+		filter["lastName"] = contact.LastName
+	}
+	return filter
+}
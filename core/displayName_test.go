@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestNormalizeDisplayName(t *testing.T) {
+	assert.Equal(t, "emile zola", normalizeDisplayName("Émile  Zola"))
+	assert.Equal(t, "francois", normalizeDisplayName("FRANÇOIS "))
+	assert.Equal(t, "dani", displayName(&definition.Contact{FirstName: "Dani"}))
+}
+
+func TestDisplayName(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should store the display name of added contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := phoneBookMock.AddContact(context.Background(), &definition.Contact{FirstName: "Émile", LastName: "Zola", Phone: "0521234567", DisplayName: "ignored"}, "tester")
+		assert.Nil(t, err)
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "emile zola", inserted.Lookup("displayName").StringValue())
+	})
+
+	mt.Run("should search display names by prefix, in order", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(searchResponse(mt,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Émile"}, {Key: "displayName", Value: "emile"}}))
+		contacts, _, err := phoneBookMock.SearchContact(context.Background(), url.Values{"namePrefix": {"ÉMI."}})
+		assert.Nil(t, err)
+		assert.Len(t, contacts.Items, 1)
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		assert.Equal(t, `^emi\.`, pipeline.Index(0).Value().Document().Lookup("$match", "displayName", "$regex").StringValue())
+		assert.Equal(t, "displayName", pipeline.Index(1).Value().Document().Lookup("$sort").Document().Index(0).Key())
+	})
+
+	mt.Run("should reject an empty prefix", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.SearchContact(context.Background(), url.Values{"namePrefix": {" "}})
+		assert.ErrorIs(t, err, ErrInvalidSearchValue)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should derive the display name of restored contacts without one", func(mt *mtest.T) {
+		raw, err := bson.Marshal(bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Noa"}, {Key: "lastName", Value: "Lévi"}})
+		assert.Nil(t, err)
+		document, err := withDerivedFields(raw)
+		assert.Nil(t, err)
+		assert.Equal(t, "noa levi", document.Lookup("displayName").StringValue())
+	})
+}
+
+func TestSortByDisplayName(t *testing.T) {
+	sort, err := validateSortParam([]string{"displayName"})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.D{{Key: "displayName", Value: 1}, {Key: "_id", Value: 1}}, sort)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+)
+
+// distinctFields are the fields GetDistinctValues lists the values of, those
+// the contacts share.
+var distinctFields = map[string]bool{
+	"address":  true,
+	"company":  true,
+	"jobTitle": true,
+	"lastName": true,
+}
+
+// GetDistinctValues returns the distinct values of field, with the number of
+// contacts holding each, in alphabetical order ignoring case, for filter
+// dropdowns. Values differing in case only are counted together. Contacts
+// without a value are left out, and up to MaxPageSize values are returned.
+// Addresses can't be listed with field encryption.
+func (pb *MongoPhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if !distinctFields[field] {
+		return nil, BadRequest, ErrInvalidDistinct
+	}
+	if deterministic, encrypted := encryptedFields[field]; pb.cipher != nil && encrypted && !deterministic {
+		return nil, BadRequest, ErrEncryptedField.WithField(field)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{field: bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: pb.tunables().MaxPageSize}},
+	}
+	// the collation sorts ignoring case
+	aggregateOptions := options.Aggregate().SetCollation(&options.Collation{Locale: "en", Strength: 2})
+	cursor, err := pb.contactsCollection.Aggregate(ctx, pipeline, aggregateOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	var groups []struct {
+		Value string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, InternalServerError, err
+	}
+	values := make([]*definition.ValueCount, len(groups))
+	for i, group := range groups {
+		values[i] = &definition.ValueCount{Value: group.Value, Count: group.Count}
+	}
+	return values, "", nil
+}
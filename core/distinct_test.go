@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestGetDistinctValues(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should count the contacts of each value", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "Haifa"}, {Key: "count", Value: int32(12)}},
+			bson.D{{Key: "_id", Value: "Tel Aviv"}, {Key: "count", Value: int32(1284)}}))
+		values, _, err := phoneBookMock.GetDistinctValues(context.Background(), "address")
+		assert.Nil(t, err)
+		assert.Equal(t, []*definition.ValueCount{{Value: "Haifa", Count: 12}, {Value: "Tel Aviv", Count: 1284}}, values)
+		command := mt.GetStartedEvent().Command
+		group := command.Lookup("pipeline").Array().Index(1).Value().Document()
+		assert.Equal(t, "$address", group.Lookup("$group", "_id").StringValue())
+		assert.Equal(t, int32(2), command.Lookup("collation", "strength").Int32())
+	})
+
+	mt.Run("should reject the fields not listed", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for _, field := range []string{"", "phone", "address.city"} {
+			_, status, err := phoneBookMock.GetDistinctValues(context.Background(), field)
+			assert.ErrorIs(t, err, ErrInvalidDistinct)
+			assert.Equal(t, BadRequest, status)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+	"strings"
+)
+
+const (
+	DuplicateDetectionNone      = "none"
+	DuplicateDetectionPhone     = "phone"
+	DuplicateDetectionNamePhone = "namePhone"
+	uniquePhoneIndex            = "unique_phone"
+	uniqueNamePhoneIndex        = "unique_name_phone"
+)
+
+// ensureDuplicateIndex creates the unique index backing the configured
+// duplicate detection mode, and drops the index of the other mode, if present.
+func (pb *MongoPhoneBook) ensureDuplicateIndex(ctx context.Context) error {
+	indexes := pb.contactsCollection.Indexes()
+	for mode, name := range map[string]string{
+		DuplicateDetectionPhone:     uniquePhoneIndex,
+		DuplicateDetectionNamePhone: uniqueNamePhoneIndex,
+	} {
+		if mode == pb.duplicateDetection {
+			continue
+		}
+		if _, err := indexes.DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return err
+		}
+	}
+	keys := duplicateIndexKeys(pb.duplicateDetection)
+	if keys == nil {
+		return nil
+	}
+	model := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(true).SetName(duplicateIndexName(pb.duplicateDetection)),
+	}
+	_, err := indexes.CreateOne(ctx, model)
+	if err != nil {
+		return fmt.Errorf("failed to create duplicate detection index, existing duplicates must be removed first: %w", err)
+	}
+	return nil
+}
+
+func duplicateIndexKeys(mode string) bson.D {
+	switch mode {
+	case DuplicateDetectionPhone:
+		return bson.D{{Key: "phone", Value: 1}}
+	case DuplicateDetectionNamePhone:
+		return bson.D{{Key: "firstName", Value: 1}, {Key: "lastName", Value: 1}, {Key: "phone", Value: 1}}
+	}
+	return nil
+}
+
+func duplicateIndexName(mode string) string {
+	if mode == DuplicateDetectionNamePhone {
+		return uniqueNamePhoneIndex
+	}
+	return uniquePhoneIndex
+}
+
+func validateDuplicateDetection(mode string) string {
+	switch mode {
+	case DuplicateDetectionNone, DuplicateDetectionPhone, DuplicateDetectionNamePhone:
+		return mode
+	}
+	logrus.Warnf("unknown duplicate detection mode %q, duplicate detection is disabled", mode)
+	return DuplicateDetectionNone
+}
+
+// duplicateError builds the conflict error of a contact rejected by the unique
+// index, naming the existing contact when it can be found.
+func (pb *MongoPhoneBook) duplicateError(ctx context.Context, contact *definition.Contact) error {
+	filter := bson.M{"phone": contact.Phone}
+	if pb.duplicateDetection == DuplicateDetectionNamePhone {
+		filter["firstName"] = contact.FirstName
+		filter["lastName"] = contact.LastName
+	}
+	if err := pb.cipher.sealValues(filter); err != nil {
+		return ErrDuplicateContact
+	}
+	var existing *definition.Contact
+	err := pb.contactsCollection.FindOne(ctx, filter).Decode(&existing)
+	if err != nil || existing == nil {
+		return ErrDuplicateContact
+	}
+	return ErrDuplicateContact.WithMessage(fmt.Sprintf("%s. existing contact ID: %s", ErrorDuplicateContact, existing.ID.Hex()))
+}
+
+// isDuplicateContact reports whether err is a write rejected by the unique
+// index of the duplicate detection, rather than by another unique index like
+// the one of the ids.
+func (pb *MongoPhoneBook) isDuplicateContact(err error) bool {
+	if pb.duplicateDetection == DuplicateDetectionNone || !mongo.IsDuplicateKeyError(err) {
+		return false
+	}
+	return strings.Contains(err.Error(), "index: "+duplicateIndexName(pb.duplicateDetection)+" ")
+}
+
+func isIndexNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && (commandErr.Code == 27 || commandErr.Name == "IndexNotFound" || commandErr.Name == "NamespaceNotFound")
+}
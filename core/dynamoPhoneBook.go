@@ -0,0 +1,748 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/dynamodb"
+	"time"
+)
+
+// The contacts table keeps every phone book in a partition, keyed by tenant,
+// and every contact under its id:
+//
+//	pk           partition key, the tenant, or _default without one
+//	sk           sort key, the contact id in hex
+//	phoneKey     pk#normalizedPhone, partition key of the phone index
+//	lastNameKey  lastName#id, sort key of the lastName index, by pk
+//
+// Contacts without a last name are left out of the lastName index.
+const (
+	dynamoDefaultPartition = "_default"
+	dynamoPhoneIndex       = "phone"
+	dynamoLastNameIndex    = "lastName"
+)
+
+// DynamoPhoneBook keeps the contacts in a DynamoDB table. It serves the
+// contact CRUD, cursor listings, search by phone or last name and caller ID
+// lookups; the other operations answer NotImplemented.
+type DynamoPhoneBook struct {
+	client       *dynamodb.Client
+	table        string
+	queryTimeout time.Duration
+	tunables     func() config.Tunable
+}
+
+func NewDynamoPhoneBook(client *dynamodb.Client, table string) *DynamoPhoneBook {
+	return &DynamoPhoneBook{
+		client:       client,
+		table:        table,
+		queryTimeout: config.Static.QueryTimeout,
+		tunables:     config.Tunables,
+	}
+}
+
+// SetTunables makes the phone book take its page sizes from tunables, e.g. the
+// settings of a configuration other than the one of the process.
+func (pb *DynamoPhoneBook) SetTunables(tunables func() config.Tunable) {
+	pb.tunables = tunables
+}
+
+func (pb *DynamoPhoneBook) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pb.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, pb.queryTimeout)
+}
+
+// EnsureTable creates the contacts table and its indexes, billed per
+// request, unless it exists.
+func (pb *DynamoPhoneBook) EnsureTable(ctx context.Context) error {
+	err := pb.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: pb.table,
+		AttributeDefinitions: []dynamodb.AttributeDefinition{
+			{AttributeName: "pk", AttributeType: "S"},
+			{AttributeName: "sk", AttributeType: "S"},
+			{AttributeName: "phoneKey", AttributeType: "S"},
+			{AttributeName: "lastNameKey", AttributeType: "S"},
+		},
+		KeySchema: []dynamodb.KeySchemaElement{
+			{AttributeName: "pk", KeyType: "HASH"},
+			{AttributeName: "sk", KeyType: "RANGE"},
+		},
+		GlobalSecondaryIndexes: []dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName:  dynamoPhoneIndex,
+				KeySchema:  []dynamodb.KeySchemaElement{{AttributeName: "phoneKey", KeyType: "HASH"}, {AttributeName: "sk", KeyType: "RANGE"}},
+				Projection: dynamodb.Projection{ProjectionType: "ALL"},
+			},
+			{
+				IndexName:  dynamoLastNameIndex,
+				KeySchema:  []dynamodb.KeySchemaElement{{AttributeName: "pk", KeyType: "HASH"}, {AttributeName: "lastNameKey", KeyType: "RANGE"}},
+				Projection: dynamodb.Projection{ProjectionType: "ALL"},
+			},
+		},
+		BillingMode: "PAY_PER_REQUEST",
+	})
+	if dynamodb.IsResourceInUse(err) {
+		return nil
+	}
+	return err
+}
+
+// dynamoPartition returns the partition of the phone book ctx is scoped to.
+func dynamoPartition(ctx context.Context) string {
+	if tenant := definition.TenantFromContext(ctx); tenant != "" {
+		return tenant
+	}
+	return dynamoDefaultPartition
+}
+
+func dynamoKey(partition string, id primitive.ObjectID) dynamodb.Item {
+	return dynamodb.Item{"pk": dynamodb.String(partition), "sk": dynamodb.String(id.Hex())}
+}
+
+// contactItem returns the item of contact in partition, with the keys of the
+// indexes it belongs to.
+func contactItem(partition string, contact *definition.Contact) dynamodb.Item {
+	item := dynamoKey(partition, contact.ID)
+	for name, value := range map[string]string{
+		"firstName":       contact.FirstName,
+		"lastName":        contact.LastName,
+		"phone":           contact.Phone,
+		"email":           contact.Email,
+		"company":         contact.Company,
+		"jobTitle":        contact.JobTitle,
+		"address":         contact.Address,
+		"notes":           contact.Notes,
+		"displayName":     contact.DisplayName,
+		"normalizedPhone": contact.NormalizedPhone,
+		"initial":         contact.Initial,
+		"owner":           contact.Owner,
+		"userName":        contact.UserName,
+		"phoneCountry":    contact.PhoneCountry,
+		"phoneType":       contact.PhoneType,
+		"phoneArea":       contact.PhoneArea,
+	} {
+		if value != "" {
+			item[name] = dynamodb.String(value)
+		}
+	}
+	item["version"] = dynamodb.Number(contact.Version)
+	if contact.CreatedAt != nil {
+		item["createdAt"] = dynamodb.String(contact.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if contact.UpdatedAt != nil {
+		item["updatedAt"] = dynamodb.String(contact.UpdatedAt.Format(time.RFC3339Nano))
+	}
+	if contact.NormalizedPhone != "" {
+		item["phoneKey"] = dynamodb.String(partition + "#" + contact.NormalizedPhone)
+	}
+	if contact.LastName != "" {
+		item["lastNameKey"] = dynamodb.String(contact.LastName + "#" + contact.ID.Hex())
+	}
+	return item
+}
+
+func itemContact(item dynamodb.Item) *definition.Contact {
+	id, _ := primitive.ObjectIDFromHex(item.String("sk"))
+	contact := &definition.Contact{
+		ID:              id,
+		FirstName:       item.String("firstName"),
+		LastName:        item.String("lastName"),
+		Phone:           item.String("phone"),
+		Email:           item.String("email"),
+		Company:         item.String("company"),
+		JobTitle:        item.String("jobTitle"),
+		Address:         item.String("address"),
+		Notes:           item.String("notes"),
+		Version:         item.Int("version"),
+		DisplayName:     item.String("displayName"),
+		NormalizedPhone: item.String("normalizedPhone"),
+		Initial:         item.String("initial"),
+		Owner:           item.String("owner"),
+		UserName:        item.String("userName"),
+		PhoneCountry:    item.String("phoneCountry"),
+		PhoneType:       item.String("phoneType"),
+		PhoneArea:       item.String("phoneArea"),
+	}
+	if createdAt, err := time.Parse(time.RFC3339Nano, item.String("createdAt")); err == nil {
+		contact.CreatedAt = &createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339Nano, item.String("updatedAt")); err == nil {
+		contact.UpdatedAt = &updatedAt
+	}
+	return contact
+}
+
+func (pb *DynamoPhoneBook) getContact(ctx context.Context, id primitive.ObjectID) (*definition.Contact, error) {
+	output, err := pb.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      pb.table,
+		Key:            dynamoKey(dynamoPartition(ctx), id),
+		ConsistentRead: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Item) == 0 {
+		return nil, nil
+	}
+	return itemContact(output.Item), nil
+}
+
+func (pb *DynamoPhoneBook) GetContact(ctx context.Context, idParam string) (*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, BadRequest, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidID
+	}
+	contact, err := pb.getContact(ctx, id)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	if contact == nil {
+		return nil, NotFound, ErrContactNotFound
+	}
+	return contact, "", nil
+}
+
+// GetContactWithPagination returns a page of contacts in id order, following
+// the cursor of the previous page, which wraps the LastEvaluatedKey of its
+// query. Only the first page can be asked by number, and the listing is
+// counted only when count=true is sent, since counting reads the whole
+// partition.
+func (pb *DynamoPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	for _, param := range []string{"startsWith", "sort", "fields"} {
+		if query.Get(param) != "" {
+			return nil, NotImplemented, ErrUnsupported.WithField(param)
+		}
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	if pageNumber > 1 {
+		return nil, NotImplemented, ErrUnsupported.WithField("page")
+	}
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	withCount, err := validateDynamoCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	lastID, err := decodeCursor(query.Get("cursor"))
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	partition := dynamoPartition(ctx)
+	input := &dynamodb.QueryInput{
+		TableName:                 pb.table,
+		KeyConditionExpression:    "#pk = :pk",
+		ExpressionAttributeNames:  map[string]string{"#pk": "pk"},
+		ExpressionAttributeValues: dynamodb.Item{":pk": dynamodb.String(partition)},
+	}
+	var startKey dynamodb.Item
+	if !lastID.IsZero() {
+		startKey = dynamoKey(partition, lastID)
+	}
+	return pb.queryPage(ctx, input, startKey, limit, withCount)
+}
+
+// validateDynamoCountParam validates the count parameter like the Mongo phone
+// book, but doesn't count unless asked to.
+func validateDynamoCountParam(countParam []string) (bool, error) {
+	if len(countParam) == 0 || countParam[0] == "" {
+		return false, nil
+	}
+	return validateCountParam(countParam)
+}
+
+// queryPage runs input for up to limit contacts from startKey, the key of the
+// contact the previous page ended with, if any. The next cursor is set while
+// DynamoDB reports a LastEvaluatedKey, so the last page may be followed by an
+// empty one.
+func (pb *DynamoPhoneBook) queryPage(ctx context.Context, input *dynamodb.QueryInput, startKey dynamodb.Item, limit int64, withCount bool) (*definition.ContactPage, string, error) {
+	page := &definition.ContactPage{Items: []*definition.Contact{}, PageSize: limit}
+	if withCount {
+		totalItems, err := pb.count(ctx, *input)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		totalPages := (totalItems + limit - 1) / limit
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
+	}
+	input.ExclusiveStartKey = startKey
+	input.Limit = limit
+	output, err := pb.client.Query(ctx, input)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	for _, item := range output.Items {
+		page.Items = append(page.Items, itemContact(item))
+	}
+	if output.LastEvaluatedKey != nil {
+		if lastID, err := primitive.ObjectIDFromHex(output.LastEvaluatedKey.String("sk")); err == nil {
+			page.NextCursor = encodeCursor(lastID)
+		}
+	}
+	return page, "", nil
+}
+
+// count returns the number of items input matches, reading every page of
+// keys.
+func (pb *DynamoPhoneBook) count(ctx context.Context, input dynamodb.QueryInput) (int64, error) {
+	input.Select = "COUNT"
+	var total int64
+	for {
+		output, err := pb.client.Query(ctx, &input)
+		if err != nil {
+			return 0, err
+		}
+		total += output.Count
+		if output.LastEvaluatedKey == nil {
+			return total, nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+func (pb *DynamoPhoneBook) countContacts(ctx context.Context) (int64, error) {
+	return pb.count(ctx, dynamodb.QueryInput{
+		TableName:                 pb.table,
+		KeyConditionExpression:    "#pk = :pk",
+		ExpressionAttributeNames:  map[string]string{"#pk": "pk"},
+		ExpressionAttributeValues: dynamodb.Item{":pk": dynamodb.String(dynamoPartition(ctx))},
+	})
+}
+
+func (pb *DynamoPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if err := validateContact(contact); err != nil {
+		return nil, err
+	}
+	if max := definition.ContactLimitFromContext(ctx); max > 0 {
+		contacts, err := pb.countContacts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if contacts+1 > max {
+			return nil, ErrContactLimit
+		}
+	}
+	now := time.Now().UTC()
+	contact.ID = primitive.NewObjectID()
+	contact.Version = 1
+	contact.Relations = nil
+	contact.Owner = definition.UserFromContext(ctx)
+	deriveFields(contact)
+	contact.CreatedAt = &now
+	contact.UpdatedAt = &now
+	err := pb.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                pb.table,
+		Item:                     contactItem(dynamoPartition(ctx), contact),
+		ConditionExpression:      "attribute_not_exists(#sk)",
+		ExpressionAttributeNames: map[string]string{"#sk": "sk"},
+	})
+	if dynamodb.IsConditionalCheckFailed(err) {
+		return nil, ErrContactExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{ID: contact.ID.Hex(), Created: true}, nil
+}
+
+// UpdateContact sets the fields sent in contact, keeping the others, like the
+// Mongo phone book.
+func (pb *DynamoPhoneBook) UpdateContact(ctx context.Context, idParam string, contact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	if err := validateContactUpdate(contact); err != nil {
+		return nil, err
+	}
+	return pb.updateVersioned(ctx, idParam, expectedVersion, func(current *definition.Contact) {
+		setContactFields(current, contact)
+	})
+}
+
+func (pb *DynamoPhoneBook) PatchContact(ctx context.Context, idParam string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	if len(patch) == 0 {
+		return nil, ErrEmptyPatch
+	}
+	if err := validatePatch(patch); err != nil {
+		return nil, err
+	}
+	return pb.updateVersioned(ctx, idParam, expectedVersion, func(current *definition.Contact) {
+		applyContactPatch(current, patch)
+	})
+}
+
+// updateVersioned applies update to the stored contact and writes it back
+// with its version bumped, unless another write got there first. When an
+// expected version is given, the update only applies if the stored version
+// still matches it, otherwise ErrVersionConflict is returned. Like the Mongo
+// phone book, nothing is updated, and nothing matched, when the contact
+// doesn't exist.
+func (pb *DynamoPhoneBook) updateVersioned(ctx context.Context, idParam string, expectedVersion int64, update func(current *definition.Contact)) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	current, err := pb.getContact(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return &definition.WriteResult{}, nil
+	}
+	if expectedVersion > 0 && current.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	storedVersion := current.Version
+	update(current)
+	now := time.Now().UTC()
+	current.Version = storedVersion + 1
+	current.UpdatedAt = &now
+	deriveFields(current)
+	err = pb.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 pb.table,
+		Item:                      contactItem(dynamoPartition(ctx), current),
+		ConditionExpression:       "#version = :version",
+		ExpressionAttributeNames:  map[string]string{"#version": "version"},
+		ExpressionAttributeValues: dynamodb.Item{":version": dynamodb.Number(storedVersion)},
+	})
+	if dynamodb.IsConditionalCheckFailed(err) {
+		return nil, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *DynamoPhoneBook) DeleteContact(ctx context.Context, idParam string, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	output, err := pb.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    pb.table,
+		Key:          dynamoKey(dynamoPartition(ctx), id),
+		ReturnValues: "ALL_OLD",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Attributes) == 0 {
+		return &definition.WriteResult{}, nil
+	}
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+// SearchContact returns the first page of the contacts with the phone, once
+// normalized, or the last name searched, through their indexes. The other
+// search fields can't be queried without scanning the table.
+func (pb *DynamoPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if query.Get("fields") != "" {
+		return nil, NotImplemented, ErrUnsupported.WithField("fields")
+	}
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	if pageNumber > 1 {
+		return nil, NotImplemented, ErrUnsupported.WithField("page")
+	}
+	withCount, err := validateDynamoCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	filter, err := buildSearchFilter(query)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	partition := dynamoPartition(ctx)
+	input := &dynamodb.QueryInput{TableName: pb.table, ExpressionAttributeNames: map[string]string{}}
+	switch {
+	case len(filter) == 0:
+		input.KeyConditionExpression = "#pk = :pk"
+		input.ExpressionAttributeNames["#pk"] = "pk"
+		input.ExpressionAttributeValues = dynamodb.Item{":pk": dynamodb.String(partition)}
+	case len(filter) == 1 && filter["phone"] != nil:
+		input.IndexName = dynamoPhoneIndex
+		input.KeyConditionExpression = "#phoneKey = :phoneKey"
+		input.ExpressionAttributeNames["#phoneKey"] = "phoneKey"
+		phoneKey := partition + "#" + normalizePhone(filter["phone"].(string))
+		input.ExpressionAttributeValues = dynamodb.Item{":phoneKey": dynamodb.String(phoneKey)}
+	case len(filter) == 1 && filter["lastName"] != nil:
+		input.IndexName = dynamoLastNameIndex
+		input.KeyConditionExpression = "#pk = :pk AND begins_with(#lastNameKey, :lastNameKey)"
+		input.ExpressionAttributeNames["#pk"] = "pk"
+		input.ExpressionAttributeNames["#lastNameKey"] = "lastNameKey"
+		input.ExpressionAttributeValues = dynamodb.Item{
+			":pk":          dynamodb.String(partition),
+			":lastNameKey": dynamodb.String(filter["lastName"].(string) + "#"),
+		}
+	default:
+		for key := range filter {
+			if key != "phone" && key != "lastName" {
+				return nil, NotImplemented, ErrUnsupported.WithField(key)
+			}
+		}
+		return nil, NotImplemented, ErrUnsupported
+	}
+	page, status, err := pb.queryPage(ctx, input, nil, limit, withCount)
+	if err != nil {
+		return nil, status, err
+	}
+	page.Page = pageNumber
+	return page, "", nil
+}
+
+// GetContactsByPhone returns the contacts whose phone is number once both are
+// normalized, through the phone index.
+func (pb *DynamoPhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(number) > config.Static.MaxSizeProperty || !formattedPhoneRegex.MatchString(number) {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	normalized := normalizePhone(number)
+	if normalized == "" {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	output, err := pb.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 pb.table,
+		IndexName:                 dynamoPhoneIndex,
+		KeyConditionExpression:    "#phoneKey = :phoneKey",
+		ExpressionAttributeNames:  map[string]string{"#phoneKey": "phoneKey"},
+		ExpressionAttributeValues: dynamodb.Item{":phoneKey": dynamodb.String(dynamoPartition(ctx) + "#" + normalized)},
+		Limit:                     pb.tunables().MaxPageSize,
+	})
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	if len(output.Items) == 0 {
+		return nil, NotFound, ErrContactNotFound
+	}
+	contacts := make([]*definition.Contact, 0, len(output.Items))
+	for _, item := range output.Items {
+		contacts = append(contacts, itemContact(item))
+	}
+	return contacts, "", nil
+}
+
+// GetUsage counts the contacts of the phone book. The table keeps nothing
+// else, and DynamoDB only measures the size of whole tables.
+func (pb *DynamoPhoneBook) GetUsage(ctx context.Context) (*definition.Usage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	contacts, err := pb.countContacts(ctx)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	return &definition.Usage{Contacts: contacts}, "", nil
+}
+
+func (pb *DynamoPhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) SearchContactText(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetContactIndex(ctx context.Context) ([]*definition.LetterCount, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetRecentContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetRandomContact(ctx context.Context) (*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) SampleContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetCompanyContacts(ctx context.Context, name string, query url.Values) (*definition.ContactPage, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ListCompanies(ctx context.Context, prefix string) ([]string, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	return 0, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetContactHistory(ctx context.Context, id string) ([]*definition.AuditEntry, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) LinkContact(ctx context.Context, id string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetBlocklist(ctx context.Context) ([]*definition.BlockedNumber, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetRelatedContacts(ctx context.Context, id string) ([]*definition.RelatedContact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) AddInteraction(ctx context.Context, id string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetInteractions(ctx context.Context, id string, query url.Values) (*definition.InteractionPage, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) DeleteInteraction(ctx context.Context, id string, interactionID string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) AddReminder(ctx context.Context, id string, reminder *definition.Reminder, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetReminders(ctx context.Context, due string) ([]*definition.Reminder, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ClaimDueReminder(ctx context.Context, now time.Time) (*definition.Reminder, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) AssignSpeedDial(ctx context.Context, slot string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetSpeedDials(ctx context.Context) ([]*definition.SpeedDial, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ClearSpeedDial(ctx context.Context, slot string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ListIndexes(ctx context.Context) ([]*definition.Index, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) Backup(ctx context.Context, format string, w io.Writer) (int64, string, error) {
+	return 0, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	return 0, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetChanges(ctx context.Context, query url.Values) (*definition.ChangePage, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	return time.Time{}, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	return 0, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *DynamoPhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	return nil, ErrUnsupported
+}
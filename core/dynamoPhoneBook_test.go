@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/definition"
+	"phoneBook/dynamodb"
+	"strings"
+	"testing"
+)
+
+// dynamoRequest is an operation the phone book called, with its input.
+type dynamoRequest struct {
+	operation string
+	input     map[string]interface{}
+}
+
+// fakeDynamo answers the operations with the responses queued, in order, and
+// records them.
+type fakeDynamo struct {
+	server    *httptest.Server
+	responses []string
+	requests  []dynamoRequest
+}
+
+func newFakeDynamo(t *testing.T) *fakeDynamo {
+	fake := &fakeDynamo{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := dynamoRequest{operation: strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")}
+		_ = json.NewDecoder(r.Body).Decode(&request.input)
+		fake.requests = append(fake.requests, request)
+		response := "{}"
+		if len(fake.responses) > 0 {
+			response, fake.responses = fake.responses[0], fake.responses[1:]
+		}
+		if strings.Contains(response, "__type") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeDynamo) phoneBook() *DynamoPhoneBook {
+	return NewDynamoPhoneBook(dynamodb.NewClient(f.server.URL, "us-east-1", "key", "secret", ""), "contacts")
+}
+
+const conditionFailed = `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`
+
+func TestDynamoPhoneBook(t *testing.T) {
+	id := primitive.NewObjectID()
+	stored := `{"Item":{"pk":{"S":"_default"},"sk":{"S":"` + id.Hex() + `"},"firstName":{"S":"Dani"},"lastName":{"S":"Cohen"},"phone":{"S":"0521234567"},"version":{"N":"2"}}}`
+
+	t.Run("should key contacts by tenant and id", func(t *testing.T) {
+		fake := newFakeDynamo(t)
+		ctx := definition.WithTenant(context.Background(), "acme")
+		result, err := fake.phoneBook().AddContact(ctx, &definition.Contact{FirstName: "Dani", LastName: "Cohen", Phone: "0521234567"}, "tester")
+		assert.Nil(t, err)
+		item := fake.requests[0].input["Item"].(map[string]interface{})
+		sk := item["sk"].(map[string]interface{})["S"].(string)
+		assert.Equal(t, sk, result.ID)
+		assert.Equal(t, map[string]interface{}{"S": "acme"}, item["pk"])
+		assert.Equal(t, map[string]interface{}{"S": "acme#972521234567"}, item["phoneKey"])
+		assert.Equal(t, map[string]interface{}{"S": "Cohen#" + sk}, item["lastNameKey"])
+		assert.Equal(t, map[string]interface{}{"N": "1"}, item["version"])
+		assert.Equal(t, "attribute_not_exists(#sk)", fake.requests[0].input["ConditionExpression"])
+	})
+
+	t.Run("should get a contact", func(t *testing.T) {
+		fake := newFakeDynamo(t)
+		fake.responses = []string{stored, `{}`}
+		contact, _, err := fake.phoneBook().GetContact(context.Background(), id.Hex())
+		assert.Nil(t, err)
+		assert.Equal(t, id, contact.ID)
+		assert.Equal(t, "Cohen", contact.LastName)
+		assert.Equal(t, int64(2), contact.Version)
+
+		_, status, err := fake.phoneBook().GetContact(context.Background(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, status)
+	})
+
+	t.Run("should map LastEvaluatedKey onto the cursor", func(t *testing.T) {
+		fake := newFakeDynamo(t)
+		next := primitive.NewObjectID()
+		fake.responses = []string{`{"Items":[` + strings.TrimSuffix(strings.TrimPrefix(stored, `{"Item":`), "}") + `],"Count":1,
+			"LastEvaluatedKey":{"pk":{"S":"_default"},"sk":{"S":"` + next.Hex() + `"}}}`}
+		page, _, err := fake.phoneBook().GetContactWithPagination(context.Background(), url.Values{"cursor": {encodeCursor(id)}, "pageSize": {"1"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, encodeCursor(next), page.NextCursor)
+		assert.Nil(t, page.TotalItems)
+		input := fake.requests[0].input
+		assert.Equal(t, float64(1), input["Limit"])
+		assert.Equal(t, map[string]interface{}{"S": id.Hex()}, input["ExclusiveStartKey"].(map[string]interface{})["sk"])
+	})
+
+	t.Run("should search by last name through its index", func(t *testing.T) {
+		fake := newFakeDynamo(t)
+		fake.responses = []string{`{"Items":[],"Count":0}`}
+		page, _, err := fake.phoneBook().SearchContact(context.Background(), url.Values{"lastName": {"Cohen"}})
+		assert.Nil(t, err)
+		assert.Empty(t, page.Items)
+		input := fake.requests[0].input
+		assert.Equal(t, dynamoLastNameIndex, input["IndexName"])
+		assert.Equal(t, map[string]interface{}{"S": "Cohen#"}, input["ExpressionAttributeValues"].(map[string]interface{})[":lastNameKey"])
+
+		_, status, err := fake.phoneBook().SearchContact(context.Background(), url.Values{"company": {"Acme"}})
+		assert.ErrorIs(t, err, ErrUnsupported)
+		assert.Equal(t, NotImplemented, status)
+	})
+
+	t.Run("should reject stale versions", func(t *testing.T) {
+		fake := newFakeDynamo(t)
+		fake.responses = []string{stored}
+		_, err := fake.phoneBook().UpdateContact(context.Background(), id.Hex(), &definition.Contact{FirstName: "Dan"}, 1, "tester")
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, Conflict, StatusOf(err))
+
+		fake.responses = []string{stored, conditionFailed}
+		_, err = fake.phoneBook().PatchContact(context.Background(), id.Hex(), definition.ContactPatch{"lastName": nil}, 0, "tester")
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, Conflict, StatusOf(err))
+		item := fake.requests[2].input["Item"].(map[string]interface{})
+		assert.Nil(t, item["lastName"])
+		assert.Nil(t, item["lastNameKey"])
+		assert.Equal(t, map[string]interface{}{"N": "3"}, item["version"])
+	})
+
+	t.Run("should report deleting a missing contact", func(t *testing.T) {
+		fake := newFakeDynamo(t)
+		deleted, err := fake.phoneBook().DeleteContact(context.Background(), id.Hex(), "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), deleted.Matched)
+	})
+}
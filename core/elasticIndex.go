@@ -0,0 +1,355 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/elastic"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// elasticMaxWindow is the deepest hit a search can page to, the default
+// index.max_result_window of the cluster.
+const elasticMaxWindow = 10000
+
+// elasticSearchFields are the fields q matches, the names weighing most.
+var elasticSearchFields = []string{"firstName^3", "lastName^3", "displayName^2", "company^2", "jobTitle", "email", "phone", "address", "notes"}
+
+// elasticMappings index the contact fields as text for fuzzy matching, keep
+// the display name whole for namePrefix and the phone information for exact
+// matches, and scope the documents by tenant and archived state.
+// The other fields are kept in the source only.
+var elasticMappings = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"dynamic": false,
+		"properties": map[string]interface{}{
+			"contactId":   map[string]string{"type": "keyword"},
+			"tenant":      map[string]string{"type": "keyword"},
+			"indexedAt":   map[string]string{"type": "date"},
+			"archived":    map[string]string{"type": "boolean"},
+			"firstName":   map[string]string{"type": "text"},
+			"lastName":    map[string]string{"type": "text"},
+			"displayName": map[string]interface{}{"type": "text", "fields": map[string]interface{}{"raw": map[string]string{"type": "keyword"}}},
+			"phone":       map[string]string{"type": "text"},
+			"email":       map[string]string{"type": "text"},
+			"company":     map[string]string{"type": "text"},
+			"jobTitle":    map[string]string{"type": "text"},
+			"address":     map[string]string{"type": "text"},
+			"notes":       map[string]string{"type": "text"},
+			// the phone information
+			"phoneCountry": map[string]string{"type": "keyword"},
+			"phoneType":    map[string]string{"type": "keyword"},
+			"phoneArea":    map[string]string{"type": "keyword"},
+		},
+	},
+}
+
+// ElasticIndex mirrors the contacts to an Elasticsearch or OpenSearch index
+// for fuzzy, relevance ranked search over several fields. The phone book
+// stays the source of truth: every change replaces the whole document of the
+// contact, and Reindex rebuilds the documents of a phone book from it.
+type ElasticIndex struct {
+	client       *elastic.Client
+	index        string
+	queryTimeout time.Duration
+	tunables     func() config.Tunable
+}
+
+func NewElasticIndex(client *elastic.Client, index string) *ElasticIndex {
+	return &ElasticIndex{
+		client:       client,
+		index:        index,
+		queryTimeout: config.Static.QueryTimeout,
+		tunables:     config.Tunables,
+	}
+}
+
+// SetTunables makes the index take its page sizes from tunables, e.g. the
+// settings of a configuration other than the one of the process.
+func (ix *ElasticIndex) SetTunables(tunables func() config.Tunable) {
+	ix.tunables = tunables
+}
+
+func (ix *ElasticIndex) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ix.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, ix.queryTimeout)
+}
+
+// EnsureIndex creates the index unless it exists, and reports whether it
+// did, in which case it has to be filled by Reindex.
+func (ix *ElasticIndex) EnsureIndex(ctx context.Context) (bool, error) {
+	err := ix.client.CreateIndex(ctx, ix.index, elasticMappings)
+	if elastic.IsIndexExists(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// elasticDocumentID names the document of the contact with the given id in
+// the phone book ctx is scoped to, as restores may reuse ids across tenants.
+func elasticDocumentID(ctx context.Context, id string) string {
+	if tenant := definition.TenantFromContext(ctx); tenant != "" {
+		return tenant + ":" + id
+	}
+	return id
+}
+
+// elasticDocument is the document of contact: its fields by their JSON
+// names, but the id, which the cluster reserves, kept as contactId.
+func elasticDocument(ctx context.Context, contact *definition.Contact, indexedAt time.Time) (map[string]interface{}, error) {
+	content, err := json.Marshal(contact)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	delete(document, "_id")
+	document["contactId"] = contact.ID.Hex()
+	document["tenant"] = definition.TenantFromContext(ctx)
+	document["indexedAt"] = indexedAt.UTC().Format(time.RFC3339Nano)
+	return document, nil
+}
+
+// IndexContact replaces the document of contact.
+func (ix *ElasticIndex) IndexContact(ctx context.Context, contact *definition.Contact) error {
+	ctx, cancel := ix.withQueryTimeout(ctx)
+	defer cancel()
+	document, err := elasticDocument(ctx, contact, time.Now())
+	if err != nil {
+		return err
+	}
+	return ix.client.IndexDocument(ctx, ix.index, elasticDocumentID(ctx, contact.ID.Hex()), document)
+}
+
+// RemoveContacts deletes the documents of the contacts with the given ids,
+// those already gone included.
+func (ix *ElasticIndex) RemoveContacts(ctx context.Context, ids ...string) error {
+	ctx, cancel := ix.withQueryTimeout(ctx)
+	defer cancel()
+	if len(ids) == 1 {
+		err := ix.client.DeleteDocument(ctx, ix.index, elasticDocumentID(ctx, ids[0]))
+		if elastic.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	actions := make([]elastic.BulkAction, len(ids))
+	for i, id := range ids {
+		actions[i] = elastic.BulkAction{ID: elasticDocumentID(ctx, id)}
+	}
+	return ix.client.Bulk(ctx, ix.index, actions)
+}
+
+// Reindex replaces the documents of the phone book ctx is scoped to with its
+// contacts, read from phoneBook a page at a time, and returns their number.
+// Documents of contacts phoneBook no longer has are deleted once all were
+// indexed, so searches keep answering meanwhile.
+func (ix *ElasticIndex) Reindex(ctx context.Context, phoneBook definition.IPhoneBook) (int64, error) {
+	started := time.Now()
+	var indexed int64
+	query := url.Values{"cursor": {""}, "pageSize": {strconv.FormatInt(ix.tunables().MaxPageSize, 10)}, "count": {"false"}, includeArchivedParam: {"true"}}
+	for {
+		page, _, err := phoneBook.GetContactWithPagination(ctx, query)
+		if err != nil {
+			return indexed, err
+		}
+		if len(page.Items) > 0 {
+			actions := make([]elastic.BulkAction, len(page.Items))
+			for i, contact := range page.Items {
+				document, err := elasticDocument(ctx, contact, time.Now())
+				if err != nil {
+					return indexed, err
+				}
+				actions[i] = elastic.BulkAction{ID: elasticDocumentID(ctx, contact.ID.Hex()), Document: document}
+			}
+			if err := ix.bulk(ctx, actions); err != nil {
+				return indexed, err
+			}
+			indexed += int64(len(actions))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+	stale := map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{
+		ix.tenantFilter(ctx),
+		map[string]interface{}{"range": map[string]interface{}{"indexedAt": map[string]string{"lt": started.UTC().Format(time.RFC3339Nano)}}},
+	}}}
+	ctx, cancel := ix.withQueryTimeout(ctx)
+	defer cancel()
+	return indexed, ix.client.DeleteByQuery(ctx, ix.index, stale)
+}
+
+func (ix *ElasticIndex) bulk(ctx context.Context, actions []elastic.BulkAction) error {
+	ctx, cancel := ix.withQueryTimeout(ctx)
+	defer cancel()
+	return ix.client.Bulk(ctx, ix.index, actions)
+}
+
+func (ix *ElasticIndex) tenantFilter(ctx context.Context) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]string{"tenant": definition.TenantFromContext(ctx)}}
+}
+
+// SearchContacts returns a page of the contacts of the phone book ctx is
+// scoped to matching the search, best matches first. q matches the words of
+// every searchable field, allowing typos, and the searchable fields each
+// match their own value the same way. namePrefix matches the start of the
+// display name. Without any, every contact matches.
+func (ix *ElasticIndex) SearchContacts(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	ctx, cancel := ix.withQueryTimeout(ctx)
+	defer cancel()
+	limit, err := validatePageSizeParam(query["pageSize"], ix.tunables().LimitPerPage, ix.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	from := int64(pageNumber-1) * limit
+	if from+limit > elasticMaxWindow {
+		return nil, BadRequest, ErrInvalidPage.WithMessage(fmt.Sprintf("%s. search pages end at result %d", ErrorInvalidPage, elasticMaxWindow))
+	}
+	withCount, err := validateCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	must, err := buildElasticQuery(query)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	includeArchived, err := validateIncludeArchivedParam(query)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	boolQuery := map[string]interface{}{
+		"must":   must,
+		"filter": []interface{}{ix.tenantFilter(ctx)},
+	}
+	if !includeArchived {
+		boolQuery["must_not"] = []interface{}{map[string]interface{}{"term": map[string]bool{"archived": true}}}
+	}
+	body := map[string]interface{}{
+		"query":            map[string]interface{}{"bool": boolQuery},
+		"from":             from,
+		"size":             limit,
+		"track_total_hits": withCount,
+	}
+	if projection != nil {
+		includes := []string{"contactId"}
+		for field := range projection {
+			if field != "_id" {
+				includes = append(includes, field)
+			}
+		}
+		sort.Strings(includes)
+		body["_source"] = includes
+	}
+	result, err := ix.client.Search(ctx, ix.index, body)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	page := &definition.ContactPage{Items: []*definition.Contact{}, Page: pageNumber, PageSize: limit}
+	for _, hit := range result.Hits.Hits {
+		contact, err := elasticContact(hit.Source)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		page.Items = append(page.Items, contact)
+	}
+	if withCount {
+		totalItems := result.Hits.Total.Value
+		totalPages := (totalItems + limit - 1) / limit
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
+	}
+	return page, "", nil
+}
+
+// buildElasticQuery turns the search parameters into the clauses a contact
+// has to match.
+func buildElasticQuery(query url.Values) ([]interface{}, error) {
+	terms, err := validateSearchTerms(query)
+	if err != nil {
+		return nil, err
+	}
+	must := []interface{}{}
+	for key, value := range terms {
+		switch key {
+		case "q":
+			must = append(must, map[string]interface{}{"multi_match": map[string]interface{}{
+				"query":     value,
+				"fields":    elasticSearchFields,
+				"fuzziness": "AUTO",
+			}})
+		case "namePrefix":
+			must = append(must, map[string]interface{}{"prefix": map[string]interface{}{"displayName.raw": value}})
+		case "phoneCountry", "phoneType", "phoneArea":
+			must = append(must, map[string]interface{}{"term": map[string]interface{}{key: value}})
+		default:
+			must = append(must, map[string]interface{}{"match": map[string]interface{}{
+				key: map[string]interface{}{"query": value, "fuzziness": "AUTO"},
+			}})
+		}
+	}
+	return must, nil
+}
+
+// validateSearchTerms returns the terms of a search index query by key: q,
+// matched against every field, namePrefix, normalized like the display
+// names, and the searchable fields. Unknown keys are rejected like the
+// database search does.
+func validateSearchTerms(query url.Values) (map[string]string, error) {
+	terms := map[string]string{}
+	for key, values := range query {
+		if key == "pageSize" || key == "page" || key == "count" || key == "fields" || key == includeArchivedParam {
+			continue
+		}
+		if key != "q" && key != "namePrefix" && !searchableFields[key] {
+			return nil, ErrUnknownField.WithField(key).WithMessage(fmt.Sprintf("%s: %s", ErrorUnknownField, key))
+		}
+		value := strings.TrimSpace(values[0])
+		if key == "namePrefix" {
+			value = normalizeDisplayName(value)
+		}
+		if value == "" || len(value) > config.Static.MaxSizeProperty || strings.ContainsRune(value, 0) {
+			return nil, ErrInvalidSearchValue.WithField(key)
+		}
+		terms[key] = value
+	}
+	return terms, nil
+}
+
+// elasticContact returns the contact of the source of a document.
+func elasticContact(source json.RawMessage) (*definition.Contact, error) {
+	var document struct {
+		definition.Contact
+		ContactID string `json:"contactId"`
+	}
+	if err := json.Unmarshal(source, &document); err != nil {
+		return nil, err
+	}
+	contact := document.Contact
+	id, err := primitive.ObjectIDFromHex(document.ContactID)
+	if err != nil {
+		return nil, err
+	}
+	contact.ID = id
+	return &contact, nil
+}
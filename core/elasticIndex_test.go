@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/definition"
+	"phoneBook/elastic"
+	"strings"
+	"testing"
+)
+
+// elasticRequest is a call of the index to the cluster.
+type elasticRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+// pagedPhoneBook serves its contacts a page of one at a time by cursor.
+type pagedPhoneBook struct {
+	definition.IPhoneBook
+	contacts []*definition.Contact
+}
+
+func (pb *pagedPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	next := 0
+	for i, contact := range pb.contacts {
+		if query.Get("cursor") == encodeCursor(contact.ID) {
+			next = i + 1
+		}
+	}
+	page := &definition.ContactPage{Items: pb.contacts[next : next+1]}
+	if next+1 < len(pb.contacts) {
+		page.NextCursor = encodeCursor(pb.contacts[next].ID)
+	}
+	return page, "", nil
+}
+
+func TestElasticIndex(t *testing.T) {
+	var requests []elasticRequest
+	id := primitive.NewObjectID()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, elasticRequest{method: r.Method, path: r.URL.Path, body: string(body)})
+		switch r.URL.Path {
+		case "/contacts/_search":
+			w.Write([]byte(`{"hits":{"total":{"value":21},"hits":[{"_id":"acme:` + id.Hex() + `","_score":3.2,` +
+				`"_source":{"contactId":"` + id.Hex() + `","tenant":"acme","firstName":"Dani","phone":"0521234567"}}]}}`))
+		default:
+			w.Write([]byte(`{"errors":false,"items":[]}`))
+		}
+	}))
+	defer server.Close()
+	index := NewElasticIndex(elastic.NewClient(server.URL, "", ""), "contacts")
+	ctx := definition.WithTenant(context.Background(), "acme")
+
+	t.Run("should search the fields allowing typos, within the tenant", func(t *testing.T) {
+		requests = nil
+		page, _, err := index.SearchContacts(ctx, url.Values{"q": {"dnai"}, "namePrefix": {"Émi"}, "pageSize": {"10"}, "page": {"2"}, "fields": {"phone,firstName"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, id, page.Items[0].ID)
+		assert.Equal(t, "Dani", page.Items[0].FirstName)
+		assert.Equal(t, int64(21), *page.TotalItems)
+		assert.Equal(t, int64(3), *page.TotalPages)
+		var body struct {
+			Query struct {
+				Bool struct {
+					Must   []map[string]json.RawMessage `json:"must"`
+					Filter []json.RawMessage            `json:"filter"`
+				} `json:"bool"`
+			} `json:"query"`
+			From   int64    `json:"from"`
+			Source []string `json:"_source"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(requests[0].body), &body))
+		assert.Equal(t, int64(10), body.From)
+		assert.Equal(t, []string{"contactId", "firstName", "phone"}, body.Source)
+		assert.JSONEq(t, `{"term":{"tenant":"acme"}}`, string(body.Query.Bool.Filter[0]))
+		assert.Len(t, body.Query.Bool.Must, 2)
+		for _, clause := range body.Query.Bool.Must {
+			if prefix, ok := clause["prefix"]; ok {
+				assert.JSONEq(t, `{"displayName.raw":"emi"}`, string(prefix))
+			} else {
+				assert.Contains(t, string(clause["multi_match"]), `"fuzziness":"AUTO"`)
+			}
+		}
+	})
+
+	t.Run("should reject unknown fields and pages past the result window", func(t *testing.T) {
+		_, status, err := index.SearchContacts(ctx, url.Values{"version": {"2"}})
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, BadRequest, status)
+		_, status, err = index.SearchContacts(ctx, url.Values{"q": {"dani"}, "pageSize": {"100"}, "page": {"101"}})
+		assert.ErrorIs(t, err, ErrInvalidPage)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	t.Run("should reindex every page, then delete the stale documents", func(t *testing.T) {
+		requests = nil
+		first, second := primitive.NewObjectID(), primitive.NewObjectID()
+		phoneBook := &pagedPhoneBook{contacts: []*definition.Contact{{ID: first, FirstName: "Dani"}, {ID: second, FirstName: "Noa"}}}
+		indexed, err := index.Reindex(ctx, phoneBook)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), indexed)
+		assert.Len(t, requests, 3)
+		assert.Equal(t, "/_bulk", requests[0].path)
+		assert.True(t, strings.HasPrefix(requests[0].body, `{"index":{"_id":"acme:`+first.Hex()+`","_index":"contacts"}}`))
+		assert.Contains(t, requests[0].body, `"contactId":"`+first.Hex()+`"`)
+		assert.NotContains(t, requests[0].body, `"_id":"`+first.Hex())
+		assert.Contains(t, requests[1].body, second.Hex())
+		assert.Equal(t, "/contacts/_delete_by_query", requests[2].path)
+		assert.Contains(t, requests[2].body, `{"term":{"tenant":"acme"}}`)
+		assert.Contains(t, requests[2].body, `"indexedAt":{"lt":`)
+	})
+}
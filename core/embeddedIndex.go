@@ -0,0 +1,237 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/fulltext"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// embeddedBoosts weigh the matches of the names most, then of the company.
+var embeddedBoosts = map[string]float64{"firstName": 3, "lastName": 3, "company": 2}
+
+// EmbeddedIndex mirrors the contacts to a full-text index kept in memory,
+// for fuzzy and phrase search without a search engine. Each replica indexes
+// its own writes only, so it suits single node deployments. The phone book
+// stays the source of truth, and Reindex rebuilds a phone book from it.
+type EmbeddedIndex struct {
+	mu sync.Mutex
+	// phoneBooks are the indexes of the phone books by tenant, the default
+	// one under the empty tenant.
+	phoneBooks map[string]*embeddedPhoneBook
+	tunables   func() config.Tunable
+}
+
+// embeddedPhoneBook is the index of a phone book, and its contacts as
+// indexed, to answer searches without reading the database.
+type embeddedPhoneBook struct {
+	index    *fulltext.Index
+	mu       sync.RWMutex
+	contacts map[string]embeddedContact
+}
+
+type embeddedContact struct {
+	contact   *definition.Contact
+	indexedAt time.Time
+}
+
+func NewEmbeddedIndex() *EmbeddedIndex {
+	return &EmbeddedIndex{phoneBooks: map[string]*embeddedPhoneBook{}, tunables: config.Tunables}
+}
+
+// SetTunables makes the index take its page sizes from tunables, e.g. the
+// settings of a configuration other than the one of the process.
+func (ix *EmbeddedIndex) SetTunables(tunables func() config.Tunable) {
+	ix.tunables = tunables
+}
+
+// phoneBook returns the index of the phone book ctx is scoped to.
+func (ix *EmbeddedIndex) phoneBook(ctx context.Context) *embeddedPhoneBook {
+	tenant := definition.TenantFromContext(ctx)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	phoneBook, ok := ix.phoneBooks[tenant]
+	if !ok {
+		phoneBook = &embeddedPhoneBook{index: fulltext.New(embeddedBoosts), contacts: map[string]embeddedContact{}}
+		ix.phoneBooks[tenant] = phoneBook
+	}
+	return phoneBook
+}
+
+func (pb *embeddedPhoneBook) put(contact *definition.Contact, indexedAt time.Time) {
+	id := contact.ID.Hex()
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.contacts[id] = embeddedContact{contact: contact, indexedAt: indexedAt}
+	pb.index.Put(id, map[string]string{
+		"firstName": contact.FirstName,
+		"lastName":  contact.LastName,
+		// the digits alone match however the number was formatted
+		"phone":    contact.Phone + " " + digitsOnly(contact.Phone),
+		"email":    contact.Email,
+		"company":  contact.Company,
+		"jobTitle": contact.JobTitle,
+		"address":  contact.Address,
+		"notes":    contact.Notes,
+	})
+}
+
+func (pb *embeddedPhoneBook) remove(id string) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	delete(pb.contacts, id)
+	pb.index.Delete(id)
+}
+
+// IndexContact replaces the document of contact.
+func (ix *EmbeddedIndex) IndexContact(ctx context.Context, contact *definition.Contact) error {
+	ix.phoneBook(ctx).put(contact, time.Now())
+	return nil
+}
+
+// RemoveContacts deletes the documents of the contacts with the given ids.
+func (ix *EmbeddedIndex) RemoveContacts(ctx context.Context, ids ...string) error {
+	phoneBook := ix.phoneBook(ctx)
+	for _, id := range ids {
+		phoneBook.remove(id)
+	}
+	return nil
+}
+
+// Reindex replaces the documents of the phone book ctx is scoped to with its
+// contacts, read from phoneBook a page at a time, and returns their number.
+// Documents of contacts phoneBook no longer has are deleted once all were
+// indexed, so searches keep answering meanwhile.
+func (ix *EmbeddedIndex) Reindex(ctx context.Context, phoneBook definition.IPhoneBook) (int64, error) {
+	index := ix.phoneBook(ctx)
+	started := time.Now()
+	var indexed int64
+	query := url.Values{"cursor": {""}, "pageSize": {strconv.FormatInt(ix.tunables().MaxPageSize, 10)}, "count": {"false"}, includeArchivedParam: {"true"}}
+	for {
+		page, _, err := phoneBook.GetContactWithPagination(ctx, query)
+		if err != nil {
+			return indexed, err
+		}
+		for _, contact := range page.Items {
+			index.put(contact, time.Now())
+		}
+		indexed += int64(len(page.Items))
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+	index.mu.Lock()
+	var stale []string
+	for id, entry := range index.contacts {
+		if entry.indexedAt.Before(started) {
+			stale = append(stale, id)
+		}
+	}
+	index.mu.Unlock()
+	for _, id := range stale {
+		index.remove(id)
+	}
+	return indexed, nil
+}
+
+// SearchContacts returns a page of the contacts of the phone book ctx is
+// scoped to matching the search, best matches first, like the Elasticsearch
+// index. Besides words, q and the searchable fields match "quoted phrases"
+// exactly.
+func (ix *EmbeddedIndex) SearchContacts(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	limit, err := validatePageSizeParam(query["pageSize"], ix.tunables().LimitPerPage, ix.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	withCount, err := validateCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	terms, err := validateSearchTerms(query)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	includeArchived, err := validateIncludeArchivedParam(query)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	search := fulltext.Query{Text: terms["q"], Fields: map[string]string{}}
+	for key, value := range terms {
+		if searchableFields[key] && !isPhoneInfoField(key) {
+			search.Fields[key] = value
+		}
+	}
+	phoneBook := ix.phoneBook(ctx)
+	phoneBook.mu.RLock()
+	var matches []*definition.Contact
+	for _, hit := range phoneBook.index.Search(search) {
+		contact := phoneBook.contacts[hit.ID].contact
+		if contact.Archived && !includeArchived {
+			continue
+		}
+		if prefix, ok := terms["namePrefix"]; ok && !strings.HasPrefix(contact.DisplayName, prefix) {
+			continue
+		}
+		if !matchesPhoneInfo(contact, terms) {
+			continue
+		}
+		matches = append(matches, contact)
+	}
+	phoneBook.mu.RUnlock()
+	page := &definition.ContactPage{Items: []*definition.Contact{}, Page: pageNumber, PageSize: limit}
+	total := int64(len(matches))
+	for i := int64(pageNumber-1) * limit; i < total && int64(len(page.Items)) < limit; i++ {
+		page.Items = append(page.Items, projectContact(matches[i], projection))
+	}
+	if withCount {
+		totalPages := (total + limit - 1) / limit
+		page.TotalItems = &total
+		page.TotalPages = &totalPages
+	}
+	return page, "", nil
+}
+
+// projectContact returns contact with the fields of projection only, and its
+// id, or contact itself without a projection.
+func projectContact(contact *definition.Contact, projection bson.M) *definition.Contact {
+	if projection == nil {
+		return contact
+	}
+	content, _ := json.Marshal(contact)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(content, &fields)
+	for field := range fields {
+		if _, ok := projection[field]; !ok && field != "_id" {
+			delete(fields, field)
+		}
+	}
+	content, _ = json.Marshal(fields)
+	projected := &definition.Contact{}
+	json.Unmarshal(content, projected)
+	return projected
+}
+
+func digitsOnly(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, text)
+}
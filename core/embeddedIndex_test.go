@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestEmbeddedIndex(t *testing.T) {
+	index := NewEmbeddedIndex()
+	ctx := definition.WithTenant(context.Background(), "acme")
+	dani := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Dani", LastName: "Cohen", DisplayName: "dani cohen", Phone: "052-123-4567", Address: "Herzl 5, Tel Aviv"}
+	emile := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Émile", LastName: "Zola", DisplayName: "emile zola", Phone: "0541111111", Address: "Aviv Tel 3, Haifa"}
+	index.IndexContact(ctx, dani)
+	index.IndexContact(ctx, emile)
+	search := func(query url.Values) []*definition.Contact {
+		page, _, err := index.SearchContacts(ctx, query)
+		assert.Nil(t, err)
+		return page.Items
+	}
+
+	t.Run("should match words allowing typos, and quoted phrases", func(t *testing.T) {
+		assert.Equal(t, []*definition.Contact{dani}, search(url.Values{"q": {"dnai"}}))
+		assert.Equal(t, []*definition.Contact{emile}, search(url.Values{"lastName": {"zol"}}))
+		assert.Len(t, search(url.Values{"q": {"tel aviv"}}), 2)
+		assert.Equal(t, []*definition.Contact{dani}, search(url.Values{"address": {`"tel aviv"`}}))
+		assert.Equal(t, []*definition.Contact{dani}, search(url.Values{"phone": {"0521234567"}}))
+		assert.Equal(t, []*definition.Contact{emile}, search(url.Values{"namePrefix": {"Émi"}}))
+	})
+
+	t.Run("should page and project the matches", func(t *testing.T) {
+		page, _, err := index.SearchContacts(ctx, url.Values{"q": {"aviv"}, "pageSize": {"1"}, "page": {"2"}, "fields": {"phone"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Empty(t, page.Items[0].FirstName)
+		assert.NotEmpty(t, page.Items[0].Phone)
+		assert.False(t, page.Items[0].ID.IsZero())
+		assert.Equal(t, int64(2), *page.TotalItems)
+		assert.Equal(t, int64(2), *page.TotalPages)
+	})
+
+	t.Run("should keep the tenants apart", func(t *testing.T) {
+		page, _, err := index.SearchContacts(context.Background(), url.Values{"q": {"dani"}})
+		assert.Nil(t, err)
+		assert.Empty(t, page.Items)
+	})
+
+	t.Run("should reject unknown fields", func(t *testing.T) {
+		_, status, err := index.SearchContacts(ctx, url.Values{"version": {"2"}})
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	t.Run("should reindex every page, then drop the stale contacts", func(t *testing.T) {
+		noa := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Noa", DisplayName: "noa"}
+		indexed, err := index.Reindex(ctx, &pagedPhoneBook{contacts: []*definition.Contact{dani, noa}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), indexed)
+		assert.Equal(t, []*definition.Contact{noa}, search(url.Values{"q": {"noa"}}))
+		assert.Empty(t, search(url.Values{"q": {"zola"}}))
+		index.RemoveContacts(ctx, noa.ID.Hex())
+		assert.Equal(t, []*definition.Contact{dani}, search(url.Values{}))
+	})
+	t.Run("should leave out the archived contacts unless included", func(t *testing.T) {
+		archived := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Dana", DisplayName: "dana", Archived: true}
+		index.IndexContact(ctx, archived)
+		assert.Equal(t, []*definition.Contact{dani}, search(url.Values{}))
+		assert.Equal(t, []*definition.Contact{dani, archived}, search(url.Values{"includeArchived": {"true"}}))
+		_, status, err := index.SearchContacts(ctx, url.Values{"includeArchived": {"maybe"}})
+		assert.ErrorIs(t, err, ErrInvalidArchived)
+		assert.Equal(t, BadRequest, status)
+	})
+}
@@ -0,0 +1,411 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"reflect"
+	"strings"
+)
+
+const (
+	// sealedPrefix marks the encrypted values and indexedPrefix the blind
+	// indexes, so values written before encryption was enabled are told
+	// apart and read as they are.
+	sealedPrefix  = "enc1:"
+	indexedPrefix = "idx1:"
+)
+
+// encryptedFields are the contact fields encrypted at rest, with whether
+// their encryption is deterministic, so equal values can still be matched.
+var encryptedFields = map[string]bool{
+	"phone":   true,
+	"address": false,
+}
+
+var contactType = reflect.TypeOf(definition.Contact{})
+
+// fieldCipher encrypts the phone and address of the contacts with AES-GCM
+// before they are written, and decrypts them when read. The phone is
+// encrypted deterministically, its nonce derived from the number, so exact
+// search and the unique phone index work on the encrypted values. The
+// normalized phone is replaced by a blind index, a keyed hash of the number,
+// which phone lookups match against. The address is encrypted with random
+// nonces and can't be searched.
+type fieldCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+	indexKey []byte
+}
+
+func newFieldCipher(key []byte) (*fieldCipher, error) {
+	block, err := aes.NewCipher(deriveKey(key, "encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldCipher{aead: aead, nonceKey: deriveKey(key, "nonce"), indexKey: deriveKey(key, "blind index")}, nil
+}
+
+// fieldCipherFromConfig returns the cipher of FIELD_ENCRYPTION_KEY, or nil
+// when field encryption is disabled.
+func fieldCipherFromConfig() *fieldCipher {
+	if config.Static.FieldEncryptionKey == "" {
+		return nil
+	}
+	key, err := config.DecodeEncryptionKey(config.Static.FieldEncryptionKey)
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid field encryption key")
+	}
+	fieldCipher, err := newFieldCipher(key)
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid field encryption key")
+	}
+	return fieldCipher
+}
+
+// deriveKey derives the key of a purpose from the configured key, so no key
+// serves two purposes.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("phonebook " + purpose))
+	return mac.Sum(nil)
+}
+
+// collectionOptions returns the options of the collections holding
+// contacts, which encode and decode them through the cipher.
+func (c *fieldCipher) collectionOptions() *options.CollectionOptions {
+	if c == nil {
+		return options.Collection()
+	}
+	registry := bson.NewRegistry()
+	registry.RegisterTypeEncoder(contactType, bsoncodec.ValueEncoderFunc(c.encodeContact))
+	registry.RegisterTypeDecoder(contactType, bsoncodec.ValueDecoderFunc(c.decodeContact))
+	return options.Collection().SetRegistry(registry)
+}
+
+func (c *fieldCipher) encodeContact(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	raw, err := bson.Marshal(val.Interface())
+	if err != nil {
+		return err
+	}
+	if raw, err = c.sealDocument(raw); err != nil {
+		return err
+	}
+	return bsonrw.Copier{}.CopyDocumentFromBytes(vw, raw)
+}
+
+func (c *fieldCipher) decodeContact(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	raw, err := bsonrw.Copier{}.CopyDocumentToBytes(vr)
+	if err != nil {
+		return err
+	}
+	if raw, err = c.openDocument(raw); err != nil {
+		return err
+	}
+	var contact definition.Contact
+	if err := bson.Unmarshal(raw, &contact); err != nil {
+		return err
+	}
+	val.Set(reflect.ValueOf(contact))
+	return nil
+}
+
+// sealDocument returns the contact document with its sensitive fields
+// encrypted, and its normalized phone replaced by the blind index.
+func (c *fieldCipher) sealDocument(raw bson.Raw) (bson.Raw, error) {
+	if c == nil {
+		return raw, nil
+	}
+	var document bson.D
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	for i, element := range document {
+		value, ok := element.Value.(string)
+		if !ok {
+			continue
+		}
+		sealed, err := c.seal(element.Key, value)
+		if err != nil {
+			return nil, err
+		}
+		document[i].Value = sealed
+	}
+	return bson.Marshal(document)
+}
+
+// openDocument returns the contact document with its sensitive fields
+// decrypted. The blind index can't be decrypted, so the normalized phone of
+// an encrypted phone is derived from the decrypted phone again.
+func (c *fieldCipher) openDocument(raw bson.Raw) (bson.Raw, error) {
+	if c == nil {
+		return raw, nil
+	}
+	var document bson.D
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	var phone string
+	var phoneSealed bool
+	for i, element := range document {
+		value, ok := element.Value.(string)
+		if _, encrypted := encryptedFields[element.Key]; !ok || !encrypted {
+			continue
+		}
+		opened, err := c.open(element.Key, value)
+		if err != nil {
+			return nil, err
+		}
+		if element.Key == "phone" {
+			phone, phoneSealed = opened, opened != value
+		}
+		document[i].Value = opened
+	}
+	for i, element := range document {
+		value, ok := element.Value.(string)
+		if ok && element.Key == "normalizedPhone" && (phoneSealed || strings.HasPrefix(value, indexedPrefix)) {
+			document[i].Value = normalizePhone(phone)
+		}
+	}
+	return bson.Marshal(document)
+}
+
+// seal returns the value of a contact field as stored: encrypted for the
+// sensitive fields, the blind index for the normalized phone, and as it is
+// for the others or when already sealed.
+func (c *fieldCipher) seal(field string, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	if field == "normalizedPhone" {
+		if strings.HasPrefix(value, indexedPrefix) {
+			return value, nil
+		}
+		return c.index(value), nil
+	}
+	deterministic, encrypted := encryptedFields[field]
+	if !encrypted || strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if deterministic {
+		mac := hmac.New(sha256.New, c.nonceKey)
+		mac.Write([]byte(field + "\x00" + value))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// the field name is authenticated, so a value can't be moved to another field
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// open returns the decrypted value of a sensitive field, or the value as it
+// is when it wasn't encrypted.
+func (c *fieldCipher) open(field string, value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(value[len(sealedPrefix):])
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted %s", field)
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	opened, err := c.aead.Open(nil, nonce, ciphertext, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s, it may be encrypted with another key", field)
+	}
+	return string(opened), nil
+}
+
+// index returns the blind index of a normalized phone.
+func (c *fieldCipher) index(normalized string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(normalized))
+	return indexedPrefix + base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// phoneIndex returns the stored value of a normalized phone, to match
+// lookups against.
+func (c *fieldCipher) phoneIndex(normalized string) string {
+	if c == nil {
+		return normalized
+	}
+	return c.index(normalized)
+}
+
+// sealValues seals the contact field values of an update or a filter.
+func (c *fieldCipher) sealValues(values bson.M) error {
+	if c == nil {
+		return nil
+	}
+	for field, value := range values {
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		sealed, err := c.seal(field, text)
+		if err != nil {
+			return err
+		}
+		values[field] = sealed
+	}
+	return nil
+}
+
+// sealSearch seals the values of an exact match search filter. Only
+// deterministically encrypted fields can be matched.
+func (c *fieldCipher) sealSearch(filter bson.M) error {
+	if c == nil {
+		return nil
+	}
+	for field := range filter {
+		if deterministic, encrypted := encryptedFields[field]; encrypted && !deterministic {
+			return ErrEncryptedField.WithField(field)
+		}
+	}
+	return c.sealValues(filter)
+}
+
+// sealChanges returns the changes of an audit entry with the sensitive
+// values encrypted. Changes of the normalized phone are left out, as the
+// blind index can't be shown and the phone changes with it.
+func (c *fieldCipher) sealChanges(changes []*definition.FieldChange) ([]*definition.FieldChange, error) {
+	if c == nil {
+		return changes, nil
+	}
+	var sealed []*definition.FieldChange
+	for _, change := range changes {
+		if change.Field == "normalizedPhone" {
+			continue
+		}
+		if _, encrypted := encryptedFields[change.Field]; encrypted {
+			before, err := c.sealChange(change.Field, change.Before)
+			if err != nil {
+				return nil, err
+			}
+			after, err := c.sealChange(change.Field, change.After)
+			if err != nil {
+				return nil, err
+			}
+			change = &definition.FieldChange{Field: change.Field, Before: before, After: after}
+		}
+		sealed = append(sealed, change)
+	}
+	return sealed, nil
+}
+
+func (c *fieldCipher) sealChange(field string, value interface{}) (interface{}, error) {
+	if text, ok := value.(string); ok {
+		return c.seal(field, text)
+	}
+	return value, nil
+}
+
+// openChanges decrypts the sensitive values of the changes of an audit entry.
+func (c *fieldCipher) openChanges(changes []*definition.FieldChange) error {
+	if c == nil {
+		return nil
+	}
+	for _, change := range changes {
+		if _, encrypted := encryptedFields[change.Field]; !encrypted {
+			continue
+		}
+		for _, value := range []*interface{}{&change.Before, &change.After} {
+			text, ok := (*value).(string)
+			if !ok {
+				continue
+			}
+			opened, err := c.open(change.Field, text)
+			if err != nil {
+				return err
+			}
+			*value = opened
+		}
+	}
+	return nil
+}
+
+// EncryptFields encrypts the phone and address of the contacts stored before
+// field encryption was enabled, and returns how many contacts were encrypted.
+// It does nothing unless FIELD_ENCRYPTION_KEY is set.
+func (pb *MongoPhoneBook) EncryptFields(ctx context.Context) (int64, error) {
+	if pb.cipher == nil {
+		return 0, nil
+	}
+	notSealed := bson.M{"$exists": true, "$not": primitive.Regex{Pattern: "^" + sealedPrefix}}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"phone": notSealed},
+		bson.M{"address": notSealed},
+		bson.M{"normalizedPhone": bson.M{"$exists": true, "$not": primitive.Regex{Pattern: "^" + indexedPrefix}}},
+	}}
+	fields := []string{"phone", "address", "normalizedPhone"}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"phone": 1, "address": 1, "normalizedPhone": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var encrypted int64
+	var models []mongo.WriteModel
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		result, err := pb.contactsCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			encrypted += result.ModifiedCount
+		}
+		models = models[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		// a contact changed meanwhile is encrypted already
+		filter := bson.M{"_id": cursor.Current.Lookup("_id")}
+		set := bson.M{}
+		for _, field := range fields {
+			value, ok := cursor.Current.Lookup(field).StringValueOK()
+			if !ok {
+				continue
+			}
+			sealed, err := pb.cipher.seal(field, value)
+			if err != nil {
+				return encrypted, err
+			}
+			if sealed != value {
+				filter[field] = value
+				set[field] = sealed
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": set}))
+		if len(models) == backfillBatchSize {
+			if err := flush(); err != nil {
+				return encrypted, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return encrypted, err
+	}
+	return encrypted, flush()
+}
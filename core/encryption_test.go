@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"strings"
+	"testing"
+)
+
+const testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestFieldCipher(t *testing.T) {
+	fieldCipher, err := newFieldCipher([]byte("0123456789abcdef0123456789abcdef"))
+	assert.Nil(t, err)
+
+	t.Run("should encrypt the phone deterministically and the address randomly", func(t *testing.T) {
+		phone, err := fieldCipher.seal("phone", "0521234567")
+		assert.Nil(t, err)
+		again, _ := fieldCipher.seal("phone", "0521234567")
+		assert.Equal(t, phone, again)
+		assert.True(t, strings.HasPrefix(phone, sealedPrefix))
+		address, err := fieldCipher.seal("address", "Tel Aviv")
+		assert.Nil(t, err)
+		again, _ = fieldCipher.seal("address", "Tel Aviv")
+		assert.NotEqual(t, address, again)
+		opened, err := fieldCipher.open("address", address)
+		assert.Nil(t, err)
+		assert.Equal(t, "Tel Aviv", opened)
+	})
+
+	t.Run("should leave the other fields and values stored in plain text", func(t *testing.T) {
+		name, _ := fieldCipher.seal("firstName", "Dani")
+		assert.Equal(t, "Dani", name)
+		opened, err := fieldCipher.open("phone", "0521234567")
+		assert.Nil(t, err)
+		assert.Equal(t, "0521234567", opened)
+	})
+
+	t.Run("should not decrypt a value moved to another field or sealed with another key", func(t *testing.T) {
+		phone, _ := fieldCipher.seal("phone", "0521234567")
+		_, err := fieldCipher.open("address", phone)
+		assert.NotNil(t, err)
+		other, _ := newFieldCipher([]byte("fedcba9876543210fedcba9876543210"))
+		_, err = other.open("phone", phone)
+		assert.ErrorContains(t, err, "another key")
+	})
+}
+
+func TestFieldEncryption(t *testing.T) {
+	config.Static.FieldEncryptionKey = testEncryptionKey
+	defer func() { config.Static.FieldEncryptionKey = "" }()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should store the phone and address encrypted", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		contact := &definition.Contact{FirstName: "Dani", Phone: "0521234567", Address: "Tel Aviv"}
+		_, err := phoneBookMock.AddContact(context.Background(), contact, "")
+		assert.Nil(t, err)
+		assert.Equal(t, "0521234567", contact.Phone)
+		document := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "Dani", document.Lookup("firstName").StringValue())
+		assert.True(t, strings.HasPrefix(document.Lookup("phone").StringValue(), sealedPrefix))
+		assert.True(t, strings.HasPrefix(document.Lookup("address").StringValue(), sealedPrefix))
+		assert.Equal(t, phoneBookMock.cipher.index("972521234567"), document.Lookup("normalizedPhone").StringValue())
+	})
+
+	mt.Run("should decrypt the contacts read", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		id := primitive.NewObjectID()
+		phone, _ := phoneBookMock.cipher.seal("phone", "0521234567")
+		address, _ := phoneBookMock.cipher.seal("address", "Tel Aviv")
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "firstName", Value: "Dani"},
+			{Key: "phone", Value: phone},
+			{Key: "address", Value: address},
+			{Key: "normalizedPhone", Value: phoneBookMock.cipher.index("972521234567")},
+		}))
+		contact, _, err := phoneBookMock.GetContact(context.Background(), id.Hex())
+		assert.Nil(t, err)
+		assert.Equal(t, "0521234567", contact.Phone)
+		assert.Equal(t, "Tel Aviv", contact.Address)
+		assert.Equal(t, "972521234567", contact.NormalizedPhone)
+	})
+
+	mt.Run("should match the phone encrypted and refuse to search the address", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{}))
+		_, _, err := phoneBookMock.SearchContact(context.Background(), url.Values{"phone": {"0521234567"}})
+		assert.Nil(t, err)
+		phone, _ := phoneBookMock.cipher.seal("phone", "0521234567")
+		match := mt.GetStartedEvent().Command.Lookup("pipeline").Array().Index(0).Value().Document().Lookup("$match")
+		assert.Equal(t, phone, match.Document().Lookup("phone").StringValue())
+		_, status, err := phoneBookMock.SearchContact(context.Background(), url.Values{"address": {"Tel Aviv"}})
+		assert.ErrorIs(t, err, ErrEncryptedField)
+		assert.Equal(t, BadRequest, status)
+		_, status, err = phoneBookMock.GetDistinctValues(context.Background(), "address")
+		assert.ErrorIs(t, err, ErrEncryptedField)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should look phones up by their blind index", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		_, status, _ := phoneBookMock.GetContactsByPhone(context.Background(), "+972 52-123-4567")
+		assert.Equal(t, NotFound, status)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, phoneBookMock.cipher.index("972521234567"), filter.Lookup("normalizedPhone").StringValue())
+	})
+
+	mt.Run("should encrypt the contacts stored in plain text", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		id := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: id}, {Key: "phone", Value: "0521234567"}, {Key: "normalizedPhone", Value: "972521234567"}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		encrypted, err := phoneBookMock.EncryptFields(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), encrypted)
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "0521234567", update.Lookup("q", "phone").StringValue())
+		phone, _ := phoneBookMock.cipher.seal("phone", "0521234567")
+		assert.Equal(t, phone, update.Lookup("u", "$set", "phone").StringValue())
+	})
+}
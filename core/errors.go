@@ -0,0 +1,121 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"phoneBook/definition"
+)
+
+var (
+	ErrMissingID           = definition.NewError("MISSING_ID", ErrorMissingID, "_id")
+	ErrInvalidID           = definition.NewError("INVALID_ID", primitive.ErrInvalidHex.Error(), "_id")
+	ErrMissingFirstName    = definition.NewError("MISSING_FIRST_NAME", ErrorMissingFirstName, "firstName")
+	ErrInvalidFirstName    = definition.NewError("INVALID_FIRST_NAME", ErrorInvalidFirstName, "firstName")
+	ErrInvalidLastName     = definition.NewError("INVALID_LAST_NAME", ErrorInvalidLastName, "lastName")
+	ErrInvalidEmail        = definition.NewError("INVALID_EMAIL", ErrorInvalidEmail, "email")
+	ErrInvalidCompany      = definition.NewError("INVALID_COMPANY", ErrorInvalidCompany, "company")
+	ErrInvalidJobTitle     = definition.NewError("INVALID_JOB_TITLE", ErrorInvalidJobTitle, "jobTitle")
+	ErrMissingPhone        = definition.NewError("MISSING_PHONE", ErrorMissingPhone, "phone")
+	ErrInvalidPhone        = definition.NewError("INVALID_PHONE", ErrorInvalidPhone, "phone")
+	ErrClearFirstName      = definition.NewError("MISSING_FIRST_NAME", ErrorClearFirstName, "firstName")
+	ErrClearPhone          = definition.NewError("MISSING_PHONE", ErrorClearPhone, "phone")
+	ErrEmptyPatch          = definition.NewError("EMPTY_PATCH", ErrorEmptyPatch, "")
+	ErrUnknownField        = definition.NewError("UNKNOWN_FIELD", ErrorUnknownField, "")
+	ErrInvalidPage         = definition.NewError("INVALID_PAGE", ErrorInvalidPage, "page")
+	ErrInvalidCursor       = definition.NewError("INVALID_CURSOR", ErrorInvalidCursor, "cursor")
+	ErrCursorWithSort      = definition.NewError("CURSOR_WITH_SORT", ErrorCursorWithSort, "cursor")
+	ErrInvalidPageSize     = definition.NewError("INVALID_PAGE_SIZE", ErrorInvalidPageSize, "pageSize")
+	ErrInvalidCount        = definition.NewError("INVALID_COUNT", ErrorInvalidCount, "count")
+	ErrMissingSearchText   = definition.NewError("MISSING_SEARCH_TEXT", ErrorMissingSearch, "q")
+	ErrInvalidSearchValue  = definition.NewError("INVALID_SEARCH_VALUE", ErrorInvalidSearch, "")
+	ErrInvalidSort         = definition.NewError("INVALID_SORT", ErrorInvalidSort, "sort")
+	ErrContactNotFound     = definition.NewError("CONTACT_NOT_FOUND", ErrorContactNotFound, "_id")
+	ErrVersionConflict     = definition.NewError("VERSION_CONFLICT", ErrorVersionConflict, "version")
+	ErrDuplicateContact    = definition.NewError("DUPLICATE_CONTACT", ErrorDuplicateContact, "")
+	ErrInvalidBatch        = definition.NewError("INVALID_BATCH", ErrorInvalidBatch, "")
+	ErrBatchTooLarge       = definition.NewError("BATCH_TOO_LARGE", fmt.Sprintf("%s. a batch deletes up to %d contacts", ErrorBatchTooLarge, maxBatchDelete), "")
+	ErrBackupFormat        = definition.NewError("INVALID_BACKUP_FORMAT", ErrorBackupFormat, "format")
+	ErrRestoreMode         = definition.NewError("INVALID_RESTORE_MODE", ErrorRestoreMode, "mode")
+	ErrInvalidBackup       = definition.NewError("INVALID_BACKUP", ErrorInvalidBackup, "")
+	ErrInvalidRelation     = definition.NewError("INVALID_RELATION", ErrorInvalidRelation, "type")
+	ErrSelfRelation        = definition.NewError("SELF_RELATION", ErrorSelfRelation, "contactId")
+	ErrRelationNotFound    = definition.NewError("RELATION_NOT_FOUND", ErrorRelationNotFound, "contactId")
+	ErrInvalidInteraction  = definition.NewError("INVALID_INTERACTION", ErrorInvalidInteraction, "type")
+	ErrMissingSummary      = definition.NewError("MISSING_SUMMARY", ErrorMissingSummary, "summary")
+	ErrFutureInteraction   = definition.NewError("FUTURE_INTERACTION", ErrorFutureInteraction, "occurredAt")
+	ErrInteractionNotFound = definition.NewError("INTERACTION_NOT_FOUND", ErrorInteractionNotFound, "interactionId")
+	ErrMissingDueAt        = definition.NewError("MISSING_DUE_AT", ErrorMissingDueAt, "dueAt")
+	ErrPastReminder        = definition.NewError("PAST_REMINDER", ErrorPastReminder, "dueAt")
+	ErrMissingNote         = definition.NewError("MISSING_NOTE", ErrorMissingNote, "note")
+	ErrInvalidDue          = definition.NewError("INVALID_DUE", ErrorInvalidDue, "due")
+	ErrInvalidStartsWith   = definition.NewError("INVALID_STARTS_WITH", ErrorInvalidStartsWith, "startsWith")
+	ErrInvalidRecentBy     = definition.NewError("INVALID_RECENT_BY", ErrorInvalidRecentBy, "by")
+	ErrInvalidLimit        = definition.NewError("INVALID_LIMIT", ErrorInvalidLimit, "limit")
+	ErrInvalidSeedCount    = definition.NewError("INVALID_SEED_COUNT", ErrorInvalidSeedCount, "count")
+	ErrInvalidSampleSize   = definition.NewError("INVALID_SAMPLE_SIZE", ErrorInvalidSampleSize, "size")
+	ErrInvalidTenantID     = definition.NewError("INVALID_TENANT_ID", ErrorInvalidTenantID, "id")
+	ErrTenantExists        = definition.NewError("TENANT_EXISTS", ErrorTenantExists, "id")
+	ErrTenantNotFound      = definition.NewError("TENANT_NOT_FOUND", ErrorTenantNotFound, "")
+	ErrJobNotFound         = definition.NewError("JOB_NOT_FOUND", ErrorJobNotFound, "id")
+	ErrInvalidLimits       = definition.NewError("INVALID_LIMITS", ErrorInvalidLimits, "limits")
+	ErrContactLimit        = definition.NewError("CONTACT_LIMIT_REACHED", ErrorContactLimit, "")
+	ErrUnsupported         = definition.NewError("UNSUPPORTED", ErrorUnsupported, "")
+	ErrInvalidLookup       = definition.NewError("INVALID_PHONE", ErrorInvalidLookup, "number")
+	ErrRetentionAction     = definition.NewError("INVALID_RETENTION_ACTION", ErrorRetentionAction, "action")
+	ErrRetentionDays       = definition.NewError("INVALID_RETENTION_DAYS", ErrorRetentionDays, "days")
+	ErrEncryptedField      = definition.NewError("ENCRYPTED_FIELD", ErrorEncryptedField, "")
+	ErrFieldTooLong        = definition.NewError("FIELD_TOO_LONG", ErrorFieldTooLong, "")
+	ErrInvalidSyncToken    = definition.NewError("INVALID_SYNC_TOKEN", ErrorInvalidSyncToken, "token")
+	ErrSyncTokenExpired    = definition.NewError("SYNC_TOKEN_EXPIRED", ErrorSyncTokenExpired, "token")
+	ErrInvalidDistinct     = definition.NewError("INVALID_DISTINCT_FIELD", ErrorInvalidDistinct, "field")
+	ErrPhoneMismatch       = definition.NewError("PHONE_MISMATCH", ErrorPhoneMismatch, "phone")
+	ErrContactExists       = definition.NewError("CONTACT_EXISTS", ErrorContactExists, "_id")
+	ErrAuditDisabled       = definition.NewError("AUDIT_DISABLED", ErrorAuditDisabled, "")
+	ErrNothingToUndo       = definition.NewError("NOTHING_TO_UNDO", ErrorNothingToUndo, "")
+	ErrAlreadyUndone       = definition.NewError("ALREADY_UNDONE", ErrorAlreadyUndone, "")
+	ErrDeletesBlocked      = definition.NewError("DELETES_BLOCKED", ErrorDeletesBlocked, "")
+	ErrInvalidArchived     = definition.NewError("INVALID_INCLUDE_ARCHIVED", ErrorInvalidArchived, "includeArchived")
+	ErrInvalidOrder        = definition.NewError("INVALID_ORDER", ErrorInvalidOrder, "ids")
+	ErrInvalidSlot         = definition.NewError("INVALID_SLOT", ErrorInvalidSlot, "slot")
+	ErrSlotTaken           = definition.NewError("SLOT_TAKEN", ErrorSlotTaken, "slot")
+	ErrContactHasSlot      = definition.NewError("CONTACT_HAS_SLOT", ErrorContactHasSlot, "contactId")
+)
+
+// statusErrors are the errors reported with a status other than BadRequest.
+var statusErrors = map[*definition.Error]string{
+	ErrVersionConflict:     Conflict,
+	ErrDuplicateContact:    Conflict,
+	ErrContactExists:       Conflict,
+	ErrSlotTaken:           Conflict,
+	ErrContactHasSlot:      Conflict,
+	ErrContactNotFound:     NotFound,
+	ErrRelationNotFound:    NotFound,
+	ErrInteractionNotFound: NotFound,
+	ErrNothingToUndo:       NotFound,
+	ErrContactLimit:        PaymentRequired,
+	ErrUnsupported:         NotImplemented,
+	ErrSyncTokenExpired:    Gone,
+	ErrDeletesBlocked:      TooManyRequests,
+}
+
+// StatusOf returns the status of an error of the phone book methods
+// returning errors alone, like those returned with a status by the others:
+// the status of the errors of statusErrors, BadRequest for the other
+// definition errors and InternalServerError for the rest. It's empty
+// without an error.
+func StatusOf(err error) string {
+	if err == nil {
+		return ""
+	}
+	for statusErr, status := range statusErrors {
+		if errors.Is(err, statusErr) {
+			return status
+		}
+	}
+	var typedErr *definition.Error
+	if errors.As(err, &typedErr) {
+		return BadRequest
+	}
+	return InternalServerError
+}
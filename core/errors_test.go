@@ -0,0 +1,20 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatusOf(t *testing.T) {
+	assert.Equal(t, "", StatusOf(nil))
+	assert.Equal(t, Conflict, StatusOf(ErrVersionConflict))
+	assert.Equal(t, Conflict, StatusOf(ErrDuplicateContact.WithMessage("a contact with the same phone already exists")))
+	assert.Equal(t, NotFound, StatusOf(fmt.Errorf("failed to link: %w", ErrContactNotFound)))
+	assert.Equal(t, PaymentRequired, StatusOf(ErrContactLimit))
+	assert.Equal(t, NotFound, StatusOf(ErrRelationNotFound))
+	assert.Equal(t, NotFound, StatusOf(ErrNothingToUndo))
+	assert.Equal(t, BadRequest, StatusOf(ErrInvalidPhone))
+	assert.Equal(t, InternalServerError, StatusOf(context.DeadlineExceeded))
+}
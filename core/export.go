@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"net/url"
+	"phoneBook/definition"
+)
+
+// ExportContacts writes the contacts matching the search parameters of query
+// to w as newline delimited JSON, ordered by ID, and returns how many were
+// written. after resumes an interrupted export past the last ID received and
+// fields selects the fields written, like search. Like Backup it's only bound
+// by ctx, not by the query timeout.
+func (pb *MongoPhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	searchQuery := url.Values{}
+	for key, values := range query {
+		if key != "after" {
+			searchQuery[key] = values
+		}
+	}
+	filter, err := buildSearchFilter(searchQuery)
+	if err != nil {
+		return 0, BadRequest, err
+	}
+	if err := pb.cipher.sealSearch(filter); err != nil {
+		return 0, BadRequest, err
+	}
+	if after := query.Get("after"); after != "" {
+		id, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return 0, BadRequest, ErrInvalidID.WithField("after")
+		}
+		filter["_id"] = bson.M{"$gt": id}
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return 0, BadRequest, err
+	}
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return 0, InternalServerError, err
+	}
+	defer cursor.Close(ctx)
+	encoder := json.NewEncoder(w)
+	var count int64
+	for cursor.Next(ctx) {
+		var contact definition.Contact
+		if err := cursor.Decode(&contact); err != nil {
+			return count, InternalServerError, err
+		}
+		if err := encoder.Encode(&contact); err != nil {
+			return count, InternalServerError, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, InternalServerError, err
+	}
+	return count, "", nil
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestExportContacts(t *testing.T) {
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	contacts := func(mt *mtest.T) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: firstID}, {Key: "firstName", Value: "Dani"}, {Key: "address", Value: "Tel Aviv"}},
+			bson.D{{Key: "_id", Value: secondID}, {Key: "firstName", Value: "Noa"}, {Key: "address", Value: "Tel Aviv"}})
+	}
+
+	mt.Run("should write a JSON line per matching contact after the watermark", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(contacts(mt))
+		var export bytes.Buffer
+		query := url.Values{"address": {"Tel Aviv"}, "after": {primitive.NilObjectID.Hex()}}
+		count, _, err := phoneBookMock.ExportContacts(context.Background(), query, &export)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+		lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Equal(t, fmt.Sprintf(`{"_id":"%s","firstName":"Noa","address":"Tel Aviv"}`, secondID.Hex()), lines[1])
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "Tel Aviv", filter.Lookup("address").StringValue())
+		assert.Equal(t, primitive.NilObjectID, filter.Lookup("_id", "$gt").ObjectID())
+	})
+
+	mt.Run("should reject an invalid watermark", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.ExportContacts(context.Background(), url.Values{"after": {"last"}}, &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrInvalidID)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should reject filters on unknown fields", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.ExportContacts(context.Background(), url.Values{"tag": {"friends"}}, &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, BadRequest, status)
+	})
+}
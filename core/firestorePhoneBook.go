@@ -0,0 +1,678 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/firestore"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Firestore has no regex queries, so prefix searches run as ranges over
+// fields normalized for them on every write: displayName for namePrefix, and
+// companyLower, the lower cased company, for the companies autocompletion.
+// prefixEnd closes those ranges, sorting after every character of a prefix.
+const prefixEnd = "\uf8ff"
+
+// FirestorePhoneBook keeps the contacts in a Firestore collection, named by
+// their id. The contacts of a tenant are kept in the collection of the same
+// name under tenants/{tenant}. It serves the contact CRUD, listings, search,
+// the company directory and caller ID lookups; the other operations answer
+// NotImplemented.
+type FirestorePhoneBook struct {
+	client       *firestore.Client
+	collection   string
+	queryTimeout time.Duration
+	tunables     func() config.Tunable
+}
+
+func NewFirestorePhoneBook(client *firestore.Client, collection string) *FirestorePhoneBook {
+	return &FirestorePhoneBook{
+		client:       client,
+		collection:   collection,
+		queryTimeout: config.Static.QueryTimeout,
+		tunables:     config.Tunables,
+	}
+}
+
+// SetTunables makes the phone book take its page sizes from tunables, e.g. the
+// settings of a configuration other than the one of the process.
+func (pb *FirestorePhoneBook) SetTunables(tunables func() config.Tunable) {
+	pb.tunables = tunables
+}
+
+func (pb *FirestorePhoneBook) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pb.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, pb.queryTimeout)
+}
+
+// parent returns the document the contacts collection of the phone book ctx
+// is scoped to is kept under.
+func (pb *FirestorePhoneBook) parent(ctx context.Context) string {
+	if tenant := definition.TenantFromContext(ctx); tenant != "" {
+		return pb.client.Documents("tenants", tenant)
+	}
+	return pb.client.Documents()
+}
+
+func (pb *FirestorePhoneBook) documentName(ctx context.Context, id primitive.ObjectID) string {
+	return pb.parent(ctx) + "/" + pb.collection + "/" + id.Hex()
+}
+
+// firestoreDocument returns the document of contact, with the fields
+// normalized for prefix searches.
+func firestoreDocument(contact *definition.Contact) *firestore.Document {
+	document := &firestore.Document{Fields: map[string]firestore.Value{}}
+	for name, value := range editableFields(contact) {
+		if *value != "" {
+			document.Fields[name] = firestore.String(*value)
+		}
+	}
+	for name, value := range map[string]string{
+		"displayName":     contact.DisplayName,
+		"normalizedPhone": contact.NormalizedPhone,
+		"initial":         contact.Initial,
+		"companyLower":    strings.ToLower(contact.Company),
+		"owner":           contact.Owner,
+		"userName":        contact.UserName,
+		"phoneCountry":    contact.PhoneCountry,
+		"phoneType":       contact.PhoneType,
+		"phoneArea":       contact.PhoneArea,
+	} {
+		if value != "" {
+			document.Fields[name] = firestore.String(value)
+		}
+	}
+	document.Fields["version"] = firestore.Integer(contact.Version)
+	if contact.CreatedAt != nil {
+		document.Fields["createdAt"] = firestore.Timestamp(*contact.CreatedAt)
+	}
+	if contact.UpdatedAt != nil {
+		document.Fields["updatedAt"] = firestore.Timestamp(*contact.UpdatedAt)
+	}
+	return document
+}
+
+func firestoreContact(document *firestore.Document) *definition.Contact {
+	id, _ := primitive.ObjectIDFromHex(document.ID())
+	contact := &definition.Contact{
+		ID:              id,
+		Version:         document.Int("version"),
+		DisplayName:     document.String("displayName"),
+		NormalizedPhone: document.String("normalizedPhone"),
+		Initial:         document.String("initial"),
+		Owner:           document.String("owner"),
+		UserName:        document.String("userName"),
+		PhoneCountry:    document.String("phoneCountry"),
+		PhoneType:       document.String("phoneType"),
+		PhoneArea:       document.String("phoneArea"),
+		CreatedAt:       document.Time("createdAt"),
+		UpdatedAt:       document.Time("updatedAt"),
+	}
+	for name, value := range editableFields(contact) {
+		*value = document.String(name)
+	}
+	return contact
+}
+
+func firestoreContacts(documents []*firestore.Document) []*definition.Contact {
+	contacts := []*definition.Contact{}
+	for _, document := range documents {
+		contacts = append(contacts, firestoreContact(document))
+	}
+	return contacts
+}
+
+// getDocument returns the document of the contact with the given id, nil
+// when there is none.
+func (pb *FirestorePhoneBook) getDocument(ctx context.Context, id primitive.ObjectID) (*firestore.Document, error) {
+	document, err := pb.client.Get(ctx, pb.documentName(ctx, id))
+	if firestore.IsNotFound(err) {
+		return nil, nil
+	}
+	return document, err
+}
+
+func (pb *FirestorePhoneBook) GetContact(ctx context.Context, idParam string) (*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, BadRequest, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidID
+	}
+	document, err := pb.getDocument(ctx, id)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	if document == nil {
+		return nil, NotFound, ErrContactNotFound
+	}
+	return firestoreContact(document), "", nil
+}
+
+// GetContactWithPagination returns a page of contacts like the Mongo phone
+// book.
+func (pb *FirestorePhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	var filters []firestore.Filter
+	if query.Has("startsWith") {
+		letter, err := validateStartsWithParam(query.Get("startsWith"))
+		if err != nil {
+			return nil, BadRequest, err
+		}
+		filters = append(filters, firestore.Where("initial", "EQUAL", firestore.String(letter)))
+	}
+	return pb.listContacts(ctx, filters, query)
+}
+
+// listContacts returns a page of the contacts matching filters, by page
+// number or cursor, sorted and counted by the parameters of query like
+// GetContactWithPagination. Pages are skipped with the query offset, which
+// Firestore bills as reads. Sorting a filtered listing needs a composite
+// index, Firestore errors name the one to create.
+func (pb *FirestorePhoneBook) listContacts(ctx context.Context, filters []firestore.Filter, query url.Values) (*definition.ContactPage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	sort, err := validateSortParam(query["sort"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	withCount, err := validateCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	_, byCursor := query["cursor"]
+	if byCursor && sort != nil {
+		return nil, BadRequest, ErrCursorWithSort
+	}
+	lastID, err := decodeCursor(query.Get("cursor"))
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	structured := pb.query(filters...)
+	page := &definition.ContactPage{PageSize: limit}
+	if withCount {
+		totalItems, err := pb.client.Count(ctx, pb.parent(ctx), structured)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		totalPages := (totalItems + limit - 1) / limit
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
+	}
+	if projection != nil {
+		structured.Select = &firestore.Projection{}
+		for field := range projection {
+			// the id is the name of the document, which is always returned
+			if field == "_id" {
+				continue
+			}
+			structured.Select.Fields = append(structured.Select.Fields, firestore.FieldReference{FieldPath: field})
+		}
+	}
+	if byCursor {
+		structured.OrderBy = []firestore.Order{{Field: firestore.FieldReference{FieldPath: "__name__"}, Direction: "ASCENDING"}}
+		if !lastID.IsZero() {
+			structured.StartAt = &firestore.Cursor{Values: []firestore.Value{firestore.Reference(pb.documentName(ctx, lastID))}}
+		}
+		// one extra contact tells whether another page exists
+		structured.Limit = limit + 1
+	} else {
+		for _, key := range sort {
+			order := firestore.Order{Field: firestore.FieldReference{FieldPath: key.Key}, Direction: "ASCENDING"}
+			if key.Key == "_id" {
+				order.Field.FieldPath = "__name__"
+			}
+			if key.Value == -1 {
+				order.Direction = "DESCENDING"
+			}
+			structured.OrderBy = append(structured.OrderBy, order)
+		}
+		structured.Offset = int64(pageNumber-1) * limit
+		structured.Limit = limit
+		page.Page = pageNumber
+	}
+	documents, err := pb.client.RunQuery(ctx, pb.parent(ctx), structured)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	page.Items = firestoreContacts(documents)
+	if byCursor && int64(len(page.Items)) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = encodeCursor(page.Items[limit-1].ID)
+	}
+	return page, "", nil
+}
+
+// query returns the query of the contacts matching every filter of filters.
+func (pb *FirestorePhoneBook) query(filters ...firestore.Filter) *firestore.StructuredQuery {
+	return &firestore.StructuredQuery{
+		From:  []firestore.CollectionSelector{{CollectionID: pb.collection}},
+		Where: firestore.And(filters...),
+	}
+}
+
+func (pb *FirestorePhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if err := validateContact(contact); err != nil {
+		return nil, err
+	}
+	if max := definition.ContactLimitFromContext(ctx); max > 0 {
+		contacts, err := pb.client.Count(ctx, pb.parent(ctx), pb.query())
+		if err != nil {
+			return nil, err
+		}
+		if contacts+1 > max {
+			return nil, ErrContactLimit
+		}
+	}
+	now := time.Now().UTC()
+	contact.ID = primitive.NewObjectID()
+	contact.Version = 1
+	contact.Relations = nil
+	contact.Owner = definition.UserFromContext(ctx)
+	deriveFields(contact)
+	contact.CreatedAt = &now
+	contact.UpdatedAt = &now
+	err := pb.client.Create(ctx, pb.parent(ctx), pb.collection, contact.ID.Hex(), firestoreDocument(contact))
+	if firestore.IsAlreadyExists(err) {
+		return nil, ErrContactExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{ID: contact.ID.Hex(), Created: true}, nil
+}
+
+// UpdateContact sets the fields sent in contact, keeping the others, like the
+// Mongo phone book.
+func (pb *FirestorePhoneBook) UpdateContact(ctx context.Context, idParam string, contact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	if err := validateContactUpdate(contact); err != nil {
+		return nil, err
+	}
+	return pb.updateVersioned(ctx, idParam, expectedVersion, func(current *definition.Contact) {
+		setContactFields(current, contact)
+	})
+}
+
+func (pb *FirestorePhoneBook) PatchContact(ctx context.Context, idParam string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	if len(patch) == 0 {
+		return nil, ErrEmptyPatch
+	}
+	if err := validatePatch(patch); err != nil {
+		return nil, err
+	}
+	return pb.updateVersioned(ctx, idParam, expectedVersion, func(current *definition.Contact) {
+		applyContactPatch(current, patch)
+	})
+}
+
+// updateVersioned applies update to the stored contact and writes it back
+// with its version bumped, provided the document wasn't updated since it was
+// read. When an expected version is given, the update only applies if the
+// stored version still matches it, otherwise ErrVersionConflict is returned.
+// Like the Mongo phone book, nothing is updated, and nothing matched, when the
+// contact doesn't exist.
+func (pb *FirestorePhoneBook) updateVersioned(ctx context.Context, idParam string, expectedVersion int64, update func(current *definition.Contact)) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	document, err := pb.getDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return &definition.WriteResult{}, nil
+	}
+	current := firestoreContact(document)
+	if expectedVersion > 0 && current.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	update(current)
+	now := time.Now().UTC()
+	current.Version++
+	current.UpdatedAt = &now
+	deriveFields(current)
+	err = pb.client.Replace(ctx, document.Name, firestoreDocument(current), document.UpdateTime)
+	if firestore.IsFailedPrecondition(err) {
+		return nil, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *FirestorePhoneBook) DeleteContact(ctx context.Context, idParam string, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	err = pb.client.Delete(ctx, pb.documentName(ctx, id))
+	if firestore.IsNotFound(err) {
+		return &definition.WriteResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+// SearchContact returns a page of the contacts matching the search
+// parameters like the Mongo phone book. namePrefix runs as a range over the
+// display name, sorted by it.
+func (pb *FirestorePhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	if _, err := buildSearchFilter(query); err != nil {
+		return nil, BadRequest, err
+	}
+	var filters []firestore.Filter
+	listing := url.Values{"pageSize": {strconv.FormatInt(pb.tunables().MaxPageSize, 10)}}
+	for key, values := range query {
+		switch {
+		case key == "pageSize" || key == "page" || key == "count" || key == "fields":
+			listing[key] = values
+		case key == "namePrefix":
+			prefix := normalizeDisplayName(values[0])
+			filters = append(filters,
+				firestore.Where("displayName", "GREATER_THAN_OR_EQUAL", firestore.String(prefix)),
+				firestore.Where("displayName", "LESS_THAN", firestore.String(prefix+prefixEnd)))
+			listing.Set("sort", "displayName")
+		default:
+			filters = append(filters, firestore.Where(key, "EQUAL", firestore.String(strings.TrimSpace(values[0]))))
+		}
+	}
+	return pb.listContacts(ctx, filters, listing)
+}
+
+// GetCompanyContacts returns a page of the contacts working at the company
+// named name, paginated like GetContactWithPagination.
+func (pb *FirestorePhoneBook) GetCompanyContacts(ctx context.Context, name string, query url.Values) (*definition.ContactPage, string, error) {
+	if !isText(name) || len(name) > config.Static.MaxSizeProperty {
+		return nil, BadRequest, ErrInvalidCompany
+	}
+	return pb.listContacts(ctx, []firestore.Filter{firestore.Where("company", "EQUAL", firestore.String(name))}, query)
+}
+
+// ListCompanies returns the distinct companies of the contacts starting with
+// prefix, ignoring case, in alphabetical order. Firestore has no distinct
+// query, so the contacts are read by companyLower until MaxPageSize companies
+// were found.
+func (pb *FirestorePhoneBook) ListCompanies(ctx context.Context, prefix string) ([]string, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(prefix) > config.Static.MaxSizeProperty {
+		return nil, BadRequest, ErrInvalidSearchValue.WithField("prefix")
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	limit := pb.tunables().MaxPageSize
+	// ordering by companyLower leaves out the contacts without a company
+	structured := pb.query()
+	if prefix != "" {
+		structured.Where = firestore.And(
+			firestore.Where("companyLower", "GREATER_THAN_OR_EQUAL", firestore.String(prefix)),
+			firestore.Where("companyLower", "LESS_THAN", firestore.String(prefix+prefixEnd)))
+	}
+	structured.Select = &firestore.Projection{Fields: []firestore.FieldReference{{FieldPath: "company"}, {FieldPath: "companyLower"}}}
+	structured.OrderBy = []firestore.Order{
+		{Field: firestore.FieldReference{FieldPath: "companyLower"}, Direction: "ASCENDING"},
+		{Field: firestore.FieldReference{FieldPath: "__name__"}, Direction: "ASCENDING"},
+	}
+	structured.Limit = limit
+	companies := []string{}
+	seen := map[string]bool{}
+	for int64(len(companies)) < limit {
+		documents, err := pb.client.RunQuery(ctx, pb.parent(ctx), structured)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		for _, document := range documents {
+			if company := document.String("company"); !seen[company] && int64(len(companies)) < limit {
+				seen[company] = true
+				companies = append(companies, company)
+			}
+		}
+		if int64(len(documents)) < limit {
+			break
+		}
+		last := documents[len(documents)-1]
+		structured.StartAt = &firestore.Cursor{Values: []firestore.Value{firestore.String(last.String("companyLower")), firestore.Reference(last.Name)}}
+	}
+	return companies, "", nil
+}
+
+// GetContactsByPhone returns the contacts whose phone is number once both are
+// normalized.
+func (pb *FirestorePhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(number) > config.Static.MaxSizeProperty || !formattedPhoneRegex.MatchString(number) {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	normalized := normalizePhone(number)
+	if normalized == "" {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	structured := pb.query(firestore.Where("normalizedPhone", "EQUAL", firestore.String(normalized)))
+	structured.Limit = pb.tunables().MaxPageSize
+	documents, err := pb.client.RunQuery(ctx, pb.parent(ctx), structured)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	if len(documents) == 0 {
+		return nil, NotFound, ErrContactNotFound
+	}
+	return firestoreContacts(documents), "", nil
+}
+
+// GetUsage counts the contacts of the phone book. Firestore doesn't measure
+// the storage of a collection.
+func (pb *FirestorePhoneBook) GetUsage(ctx context.Context) (*definition.Usage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	contacts, err := pb.client.Count(ctx, pb.parent(ctx), pb.query())
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	return &definition.Usage{Contacts: contacts}, "", nil
+}
+
+func (pb *FirestorePhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) SearchContactText(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetContactIndex(ctx context.Context) ([]*definition.LetterCount, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetRecentContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetRandomContact(ctx context.Context) (*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) SampleContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	return 0, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetContactHistory(ctx context.Context, id string) ([]*definition.AuditEntry, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) LinkContact(ctx context.Context, id string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetBlocklist(ctx context.Context) ([]*definition.BlockedNumber, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetRelatedContacts(ctx context.Context, id string) ([]*definition.RelatedContact, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) AddInteraction(ctx context.Context, id string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetInteractions(ctx context.Context, id string, query url.Values) (*definition.InteractionPage, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) DeleteInteraction(ctx context.Context, id string, interactionID string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) AddReminder(ctx context.Context, id string, reminder *definition.Reminder, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetReminders(ctx context.Context, due string) ([]*definition.Reminder, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ClaimDueReminder(ctx context.Context, now time.Time) (*definition.Reminder, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) AssignSpeedDial(ctx context.Context, slot string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetSpeedDials(ctx context.Context) ([]*definition.SpeedDial, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ClearSpeedDial(ctx context.Context, slot string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ListIndexes(ctx context.Context) ([]*definition.Index, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) Backup(ctx context.Context, format string, w io.Writer) (int64, string, error) {
+	return 0, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	return 0, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetChanges(ctx context.Context, query url.Values) (*definition.ChangePage, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	return time.Time{}, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	return 0, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	return nil, NotImplemented, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (pb *FirestorePhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	return nil, ErrUnsupported
+}
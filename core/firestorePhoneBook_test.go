@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/definition"
+	"phoneBook/firestore"
+	"strings"
+	"testing"
+)
+
+// firestoreRequest is a call of the phone book to the Firestore API.
+type firestoreRequest struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+// fakeFirestore answers the calls with the responses queued, in order, and
+// records them.
+type fakeFirestore struct {
+	server    *httptest.Server
+	responses []string
+	requests  []firestoreRequest
+}
+
+func newFakeFirestore(t *testing.T) *fakeFirestore {
+	fake := &fakeFirestore{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := firestoreRequest{method: r.Method, path: r.URL.Path}
+		_ = json.NewDecoder(r.Body).Decode(&request.body)
+		fake.requests = append(fake.requests, request)
+		response := "{}"
+		if len(fake.responses) > 0 {
+			response, fake.responses = fake.responses[0], fake.responses[1:]
+		}
+		if strings.Contains(response, `"error"`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeFirestore) phoneBook(t *testing.T) *FirestorePhoneBook {
+	client, err := firestore.NewClient(context.Background(), "p", "(default)", "", strings.TrimPrefix(f.server.URL, "http://"))
+	assert.Nil(t, err)
+	return NewFirestorePhoneBook(client, "contacts")
+}
+
+// queryResponse is the runQuery response of the contacts with the given ids.
+func queryResponse(ids ...primitive.ObjectID) string {
+	var results []string
+	for _, id := range ids {
+		results = append(results, `{"document":{"name":"projects/p/databases/(default)/documents/contacts/`+id.Hex()+
+			`","fields":{"firstName":{"stringValue":"Dani"},"company":{"stringValue":"Acme"},"companyLower":{"stringValue":"acme"}}}}`)
+	}
+	return "[" + strings.Join(results, ",") + `,{"readTime":"2024-01-01T00:00:00Z"}]`
+}
+
+func TestFirestorePhoneBook(t *testing.T) {
+	id := primitive.NewObjectID()
+	stored := `{"name":"projects/p/databases/(default)/documents/contacts/` + id.Hex() + `","updateTime":"2024-01-01T00:00:00.123456Z",
+		"fields":{"firstName":{"stringValue":"Dani"},"lastName":{"stringValue":"Cohen"},"phone":{"stringValue":"0521234567"},"version":{"integerValue":"2"}}}`
+
+	t.Run("should keep the contacts of a tenant under it, normalized for prefix searches", func(t *testing.T) {
+		fake := newFakeFirestore(t)
+		ctx := definition.WithTenant(context.Background(), "acme")
+		_, err := fake.phoneBook(t).AddContact(ctx, &definition.Contact{FirstName: "Émile", Phone: "0521234567", Company: "ACME Corp"}, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, "/v1/projects/p/databases/(default)/documents/tenants/acme/contacts", fake.requests[0].path)
+		fields := fake.requests[0].body["fields"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"stringValue": "emile"}, fields["displayName"])
+		assert.Equal(t, map[string]interface{}{"stringValue": "acme corp"}, fields["companyLower"])
+		assert.Equal(t, map[string]interface{}{"integerValue": "1"}, fields["version"])
+	})
+
+	t.Run("should search name prefixes by range", func(t *testing.T) {
+		fake := newFakeFirestore(t)
+		fake.responses = []string{`[{"result":{"aggregateFields":{"count":{"integerValue":"1"}}}}]`, queryResponse(id)}
+		page, _, err := fake.phoneBook(t).SearchContact(context.Background(), url.Values{"namePrefix": {"Émi"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, int64(1), *page.TotalItems)
+		query := fake.requests[1].body["structuredQuery"].(map[string]interface{})
+		filters := query["where"].(map[string]interface{})["compositeFilter"].(map[string]interface{})["filters"].([]interface{})
+		assert.Len(t, filters, 2)
+		assert.Equal(t, map[string]interface{}{"stringValue": "emi"}, filters[0].(map[string]interface{})["fieldFilter"].(map[string]interface{})["value"])
+		assert.Equal(t, "displayName", query["orderBy"].([]interface{})[0].(map[string]interface{})["field"].(map[string]interface{})["fieldPath"])
+	})
+
+	t.Run("should page by cursor", func(t *testing.T) {
+		fake := newFakeFirestore(t)
+		first, second := primitive.NewObjectID(), primitive.NewObjectID()
+		fake.responses = []string{queryResponse(first, second)}
+		page, _, err := fake.phoneBook(t).GetContactWithPagination(context.Background(), url.Values{"cursor": {encodeCursor(id)}, "pageSize": {"1"}, "count": {"false"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, encodeCursor(first), page.NextCursor)
+		query := fake.requests[0].body["structuredQuery"].(map[string]interface{})
+		assert.Equal(t, float64(2), query["limit"])
+		startAt := query["startAt"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "projects/p/databases/(default)/documents/contacts/"+id.Hex(), startAt["referenceValue"])
+	})
+
+	t.Run("should reject updates of contacts changed since read", func(t *testing.T) {
+		fake := newFakeFirestore(t)
+		fake.responses = []string{stored, `{"error":{"code":400,"status":"FAILED_PRECONDITION","message":"the stored version does not match"}}`}
+		_, err := fake.phoneBook(t).PatchContact(context.Background(), id.Hex(), definition.ContactPatch{"lastName": nil}, 2, "tester")
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, Conflict, StatusOf(err))
+		assert.Equal(t, http.MethodPatch, fake.requests[1].method)
+		fields := fake.requests[1].body["fields"].(map[string]interface{})
+		assert.Nil(t, fields["lastName"])
+		assert.Equal(t, map[string]interface{}{"integerValue": "3"}, fields["version"])
+
+		fake.responses = []string{stored}
+		_, err = fake.phoneBook(t).UpdateContact(context.Background(), id.Hex(), &definition.Contact{FirstName: "Dan"}, 1, "tester")
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, Conflict, StatusOf(err))
+	})
+
+	t.Run("should list each company once", func(t *testing.T) {
+		fake := newFakeFirestore(t)
+		fake.responses = []string{queryResponse(primitive.NewObjectID(), primitive.NewObjectID())}
+		companies, _, err := fake.phoneBook(t).ListCompanies(context.Background(), "ac")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Acme"}, companies)
+	})
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"phoneBook/definition"
+	"reflect"
+	"strings"
+)
+
+// EnsureIndexes creates the indexes the phone book queries rely on: the
+// full-text search index, the unique index backing the configured duplicate
+// detection mode, the phone lookup, recent contacts, alphabetical, pinned
+// contacts and blocklist indexes, the interactions timeline, pending reminders, speed dials
+// and sync tombstones indexes and, when automatic indexing is enabled, the
+// secondary indexes of MONGO_INDEXES.
+func (pb *MongoPhoneBook) EnsureIndexes(ctx context.Context) error {
+	if err := pb.ensureTextIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create text search index: %w", err)
+	}
+	if err := pb.ensureDuplicateIndex(ctx); err != nil {
+		return err
+	}
+	if err := pb.ensurePhoneIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create phone lookup index: %w", err)
+	}
+	if err := pb.ensureRecentIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create recent contacts indexes: %w", err)
+	}
+	if err := pb.ensureLetterIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create alphabetical index: %w", err)
+	}
+	if err := pb.ensurePinnedIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create pinned contacts index: %w", err)
+	}
+	if err := pb.ensureBlocklistIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create blocklist index: %w", err)
+	}
+	if err := pb.ensureInteractionsIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create interactions index: %w", err)
+	}
+	if err := pb.ensureRemindersIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create reminders index: %w", err)
+	}
+	if err := pb.ensureSpeedDialsIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create speed dials index: %w", err)
+	}
+	if err := pb.ensureTombstonesIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create tombstones index: %w", err)
+	}
+	if err := pb.ensureSecondaryIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create secondary indexes: %w", err)
+	}
+	return nil
+}
+
+// ensureSecondaryIndexes creates an ascending index for each configured
+// index, skipping the one the unique duplicate detection index already covers.
+func (pb *MongoPhoneBook) ensureSecondaryIndexes(ctx context.Context) error {
+	duplicateKeys := duplicateIndexKeys(pb.duplicateDetection)
+	var models []mongo.IndexModel
+	for _, keys := range pb.secondaryIndexes {
+		if reflect.DeepEqual(keys, duplicateKeys) {
+			continue
+		}
+		models = append(models, mongo.IndexModel{Keys: keys})
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := pb.contactsCollection.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// rebuildIndexes drops the text and secondary indexes, then creates every
+// index anew, and returns the names of those dropped.
+func (pb *MongoPhoneBook) rebuildIndexes(ctx context.Context) ([]string, error) {
+	names := []string{textIndexName}
+	duplicateKeys := duplicateIndexKeys(pb.duplicateDetection)
+	for _, keys := range pb.secondaryIndexes {
+		if !reflect.DeepEqual(keys, duplicateKeys) {
+			names = append(names, indexName(keys))
+		}
+	}
+	for _, name := range names {
+		var commandErr mongo.CommandError
+		if _, err := pb.contactsCollection.Indexes().DropOne(ctx, name); err != nil &&
+			!(errors.As(err, &commandErr) && commandErr.Code == indexNotFoundCode) {
+			return nil, fmt.Errorf("failed to drop index %s: %w", name, err)
+		}
+	}
+	return names, pb.EnsureIndexes(ctx)
+}
+
+// indexNotFoundCode is the code of the error dropping a missing index.
+const indexNotFoundCode = 27
+
+// indexName is the name MongoDB gives the index over keys, e.g.
+// lastName_1_firstName_1.
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// parseIndexSpecs returns the keys of the indexes given as contact fields
+// joined by +, e.g. lastName+firstName. The fields returned or searched by
+// can be indexed, indexes over other fields are skipped with a warning.
+func parseIndexSpecs(specs []string) []bson.D {
+	var indexes []bson.D
+	for _, spec := range specs {
+		keys, err := parseIndexSpec(spec)
+		if err != nil {
+			logrus.WithError(err).Warnf("skipping index %q", spec)
+			continue
+		}
+		indexes = append(indexes, keys)
+	}
+	return indexes
+}
+
+func parseIndexSpec(spec string) (bson.D, error) {
+	var keys bson.D
+	for _, field := range strings.Split(spec, "+") {
+		field = strings.TrimSpace(field)
+		if !projectableFields[field] && !searchableFields[field] {
+			return nil, fmt.Errorf("%s: %s", ErrorUnknownField, field)
+		}
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return keys, nil
+}
+
+// ListIndexes returns the indexes of the contacts collection.
+func (pb *MongoPhoneBook) ListIndexes(ctx context.Context) ([]*definition.Index, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	cursor, err := pb.contactsCollection.Indexes().List(ctx)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	defer cursor.Close(ctx)
+	indexes := []*definition.Index{}
+	for cursor.Next(ctx) {
+		var spec struct {
+			Name   string `bson:"name"`
+			Key    bson.D `bson:"key"`
+			Unique bool   `bson:"unique"`
+		}
+		if err := cursor.Decode(&spec); err != nil {
+			return nil, InternalServerError, err
+		}
+		index := &definition.Index{Name: spec.Name, Unique: spec.Unique}
+		for _, key := range spec.Key {
+			index.Keys = append(index.Keys, definition.IndexKey{Field: key.Key, Order: key.Value})
+		}
+		indexes = append(indexes, index)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, InternalServerError, err
+	}
+	return indexes, "", nil
+}
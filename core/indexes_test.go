@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+)
+
+func TestParseIndexSpecs(t *testing.T) {
+	indexes := parseIndexSpecs([]string{"phone", "lastName+firstName", "owner", "normalizedPhone"})
+	assert.Equal(t, []bson.D{
+		{{Key: "phone", Value: 1}},
+		{{Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}},
+		{{Key: "owner", Value: 1}},
+	}, indexes)
+}
+
+func TestIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should skip index covered by duplicate detection", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		phoneBookMock.secondaryIndexes = parseIndexSpecs([]string{"phone", "lastName+firstName"})
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		assert.Nil(t, phoneBookMock.ensureSecondaryIndexes(context.Background()))
+		indexes := mt.GetStartedEvent().Command.Lookup("indexes").Array()
+		values, _ := indexes.Values()
+		assert.Len(t, values, 1)
+		assert.Equal(t, "lastName_1_firstName_1", values[0].Document().Lookup("name").StringValue())
+	})
+
+	mt.Run("should list indexes", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "name", Value: "_id_"}, {Key: "key", Value: bson.D{{Key: "_id", Value: 1}}}},
+			bson.D{{Key: "name", Value: uniquePhoneIndex}, {Key: "key", Value: bson.D{{Key: "phone", Value: 1}}}, {Key: "unique", Value: true}}))
+		indexes, _, err := phoneBookMock.ListIndexes(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, indexes, 2)
+		assert.Equal(t, uniquePhoneIndex, indexes[1].Name)
+		assert.True(t, indexes[1].Unique)
+		assert.Equal(t, "phone", indexes[1].Keys[0].Field)
+	})
+}
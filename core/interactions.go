@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"phoneBook/definition"
+	"time"
+)
+
+const interactionsIndexName = "interactions_contact_timeline"
+
+// interactionTypes are the kinds of interactions a contact timeline records.
+var interactionTypes = map[string]bool{
+	definition.InteractionCall:    true,
+	definition.InteractionMeeting: true,
+	definition.InteractionNote:    true,
+}
+
+// ensureInteractionsIndex creates the index the contact timelines are read
+// by, most recent first.
+func (pb *MongoPhoneBook) ensureInteractionsIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "contactId", Value: 1}, {Key: "occurredAt", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName(interactionsIndexName),
+	}
+	_, err := pb.interactions.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// AddInteraction records an interaction on the timeline of the contact with
+// id and returns the id of the interaction in the result. An interaction without a time is
+// recorded as occurring now.
+func (pb *MongoPhoneBook) AddInteraction(ctx context.Context, idParam string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	contactID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	now := time.Now().UTC()
+	if err := validateInteraction(interaction, now); err != nil {
+		return nil, err
+	}
+	count, err := pb.contactsCollection.CountDocuments(ctx, bson.M{"_id": contactID})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrContactNotFound
+	}
+	interaction.ID = primitive.NewObjectID()
+	interaction.ContactID = contactID
+	interaction.Actor = actor
+	interaction.CreatedAt = now
+	if interaction.OccurredAt.IsZero() {
+		interaction.OccurredAt = now
+	}
+	interaction.OccurredAt = interaction.OccurredAt.UTC()
+	_, err = pb.interactions.InsertOne(ctx, interaction)
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{ID: interaction.ID.Hex(), Created: true}, nil
+}
+
+func validateInteraction(interaction *definition.Interaction, now time.Time) error {
+	if !interactionTypes[interaction.Type] {
+		return ErrInvalidInteraction
+	}
+	if !isText(interaction.Summary) {
+		return ErrMissingSummary
+	}
+	// a little slack for the clocks of the clients
+	if interaction.OccurredAt.After(now.Add(time.Minute)) {
+		return ErrFutureInteraction
+	}
+	return nil
+}
+
+// GetInteractions returns a page of the timeline of the contact with id, most
+// recent first, paginated by page and pageSize like the contacts. The
+// interactions of a deleted contact are kept, so its timeline comes back
+// when the delete is undone.
+func (pb *MongoPhoneBook) GetInteractions(ctx context.Context, idParam string, query url.Values) (*definition.InteractionPage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	contactID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidID
+	}
+	withCount, err := validateCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	filter := bson.M{"contactId": contactID}
+	page := &definition.InteractionPage{Items: []*definition.Interaction{}, Page: pageNumber, PageSize: limit}
+	if withCount {
+		totalItems, err := pb.interactions.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		totalPages := (totalItems + limit - 1) / limit
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "occurredAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(pageNumber-1) * limit).
+		SetLimit(limit)
+	cursor, err := pb.interactions.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var interaction *definition.Interaction
+		if err := cursor.Decode(&interaction); err != nil {
+			return nil, InternalServerError, err
+		}
+		page.Items = append(page.Items, interaction)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, InternalServerError, err
+	}
+	return page, "", nil
+}
+
+// DeleteInteraction removes an interaction from the timeline of the contact
+// with id.
+func (pb *MongoPhoneBook) DeleteInteraction(ctx context.Context, idParam string, interactionIDParam string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	contactID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	interactionID, err := primitive.ObjectIDFromHex(interactionIDParam)
+	if err != nil {
+		return nil, ErrInvalidID.WithField("interactionId")
+	}
+	deleteResult, err := pb.interactions.DeleteOne(ctx, bson.M{"_id": interactionID, "contactId": contactID})
+	if err != nil {
+		return nil, err
+	}
+	if deleteResult.DeletedCount == 0 {
+		return nil, ErrInteractionNotFound
+	}
+	return &definition.WriteResult{Matched: deleteResult.DeletedCount, Modified: deleteResult.DeletedCount}, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestAddInteraction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	contactID := primitive.NewObjectID()
+
+	mt.Run("should record the interaction", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+			mtest.CreateSuccessResponse(),
+		)
+		result, err := phoneBookMock.AddInteraction(context.Background(), contactID.Hex(), &definition.Interaction{Type: definition.InteractionCall, Summary: "Talked about the renewal"}, "tester")
+		assert.Nil(t, err)
+		assert.Len(t, result.ID, 24)
+		assert.True(t, result.Created)
+		mt.GetStartedEvent() // the contact lookup
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, config.Static.MongoInteractionsCollection, command.Lookup("insert").StringValue())
+		inserted := command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, contactID, inserted.Lookup("contactId").ObjectID())
+		assert.Equal(t, "tester", inserted.Lookup("actor").StringValue())
+		assert.NotZero(t, inserted.Lookup("occurredAt").Time())
+	})
+
+	mt.Run("should reject invalid interactions", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for interaction, expected := range map[*definition.Interaction]error{
+			{Type: "email", Summary: "Sent the offer"}:                                                     ErrInvalidInteraction,
+			{Type: definition.InteractionNote, Summary: " "}:                                               ErrMissingSummary,
+			{Type: definition.InteractionMeeting, Summary: "Lunch", OccurredAt: time.Now().Add(time.Hour)}: ErrFutureInteraction,
+		} {
+			_, err := phoneBookMock.AddInteraction(context.Background(), contactID.Hex(), interaction, "tester")
+			assert.ErrorIs(t, err, expected)
+			assert.Equal(t, BadRequest, StatusOf(err))
+		}
+	})
+
+	mt.Run("should not record interactions of unknown contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{{Key: "n", Value: int32(0)}}))
+		_, err := phoneBookMock.AddInteraction(context.Background(), contactID.Hex(), &definition.Interaction{Type: definition.InteractionCall, Summary: "Voicemail"}, "tester")
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, StatusOf(err))
+	})
+}
+
+func TestGetInteractions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should return a page of the timeline, most recent first", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), config.Static.MongoInteractionsCollection)
+		contactID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "contactId", Value: contactID}, {Key: "type", Value: "call"}, {Key: "summary", Value: "Follow up"}}))
+		page, _, err := phoneBookMock.GetInteractions(context.Background(), contactID.Hex(), url.Values{"page": {"2"}, "pageSize": {"5"}, "count": {"false"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, 2, page.Page)
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, int64(5), command.Lookup("skip").AsInt64())
+		assert.Equal(t, "occurredAt", command.Lookup("sort").Document().Index(0).Key())
+	})
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	jobsListIndexName = "jobs_list"
+	jobsTTLIndexName  = "jobs_ttl"
+)
+
+// maxMemoryJobs is the number of jobs MemoryJobs keeps, the oldest
+// forgotten first.
+const maxMemoryJobs = 100
+
+// MongoJobs keeps the records of the jobs in a collection of the main
+// database, shared by the replicas, until they're finished for the job
+// retention.
+type MongoJobs struct {
+	collection   *mongo.Collection
+	queryTimeout time.Duration
+	retention    time.Duration
+}
+
+func NewMongoJobs(mongoClient *mongo.Client) *MongoJobs {
+	return &MongoJobs{
+		collection:   mongoClient.Database(config.Static.MongoDBName).Collection(config.Static.MongoJobsCollection),
+		queryTimeout: config.Static.QueryTimeout,
+		retention:    config.Static.JobRetention,
+	}
+}
+
+func (j *MongoJobs) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if j.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, j.queryTimeout)
+}
+
+// EnsureIndexes creates the index the jobs are listed by, and the one
+// expiring them, updating its TTL when the retention changed since.
+func (j *MongoJobs) EnsureIndexes(ctx context.Context) error {
+	list := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant", Value: 1}, {Key: "createdAt", Value: -1}},
+		Options: options.Index().SetName(jobsListIndexName),
+	}
+	if _, err := j.collection.Indexes().CreateOne(ctx, list); err != nil {
+		return fmt.Errorf("failed to create jobs index: %w", err)
+	}
+	expireAfter := int32(j.retention.Seconds())
+	ttl := mongo.IndexModel{
+		Keys:    bson.D{{Key: "finishedAt", Value: 1}},
+		Options: options.Index().SetName(jobsTTLIndexName).SetExpireAfterSeconds(expireAfter),
+	}
+	_, err := j.collection.Indexes().CreateOne(ctx, ttl)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Name == "IndexOptionsConflict" {
+		return j.collection.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: j.collection.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: jobsTTLIndexName}, {Key: "expireAfterSeconds", Value: expireAfter}}},
+		}).Err()
+	}
+	return err
+}
+
+func (j *MongoJobs) SaveJob(ctx context.Context, job *definition.Job) error {
+	ctx, cancel := j.withQueryTimeout(ctx)
+	defer cancel()
+	_, err := j.collection.ReplaceOne(ctx, bson.M{"_id": job.ID}, job, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (j *MongoJobs) GetJob(ctx context.Context, id string) (*definition.Job, string, error) {
+	ctx, cancel := j.withQueryTimeout(ctx)
+	defer cancel()
+	var job definition.Job
+	err := j.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, NotFound, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	return &job, "", nil
+}
+
+// ListJobs returns the jobs of filter, most recent first, without their
+// reports.
+func (j *MongoJobs) ListJobs(ctx context.Context, filter definition.JobFilter) ([]*definition.Job, string, error) {
+	ctx, cancel := j.withQueryTimeout(ctx)
+	defer cancel()
+	query := bson.M{"tenant": filter.Tenant}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	// the reports are only downloaded one job at a time
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(filter.Limit).SetProjection(bson.M{"report": 0})
+	cursor, err := j.collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	defer cursor.Close(ctx)
+	jobs := []*definition.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, InternalServerError, err
+	}
+	return jobs, "", nil
+}
+
+// MemoryJobs keeps the records of the last jobs of the replica in memory,
+// for the storage backends other than MongoDB. They're lost on restart.
+type MemoryJobs struct {
+	mu   sync.Mutex
+	jobs []*definition.Job
+}
+
+func NewMemoryJobs() *MemoryJobs {
+	return &MemoryJobs{}
+}
+
+func (j *MemoryJobs) SaveJob(ctx context.Context, job *definition.Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	saved := *job
+	if i := slices.IndexFunc(j.jobs, func(kept *definition.Job) bool { return kept.ID == job.ID }); i >= 0 {
+		j.jobs[i] = &saved
+		return nil
+	}
+	j.jobs = append(j.jobs, &saved)
+	if len(j.jobs) > maxMemoryJobs {
+		j.jobs = slices.Delete(j.jobs, 0, len(j.jobs)-maxMemoryJobs)
+	}
+	return nil
+}
+
+func (j *MemoryJobs) GetJob(ctx context.Context, id string) (*definition.Job, string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, job := range j.jobs {
+		if job.ID == id {
+			found := *job
+			return &found, "", nil
+		}
+	}
+	return nil, NotFound, ErrJobNotFound
+}
+
+// ListJobs returns the jobs of filter, most recent first, without their
+// reports.
+func (j *MemoryJobs) ListJobs(ctx context.Context, filter definition.JobFilter) ([]*definition.Job, string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	jobs := []*definition.Job{}
+	for i := len(j.jobs) - 1; i >= 0 && (filter.Limit <= 0 || int64(len(jobs)) < filter.Limit); i-- {
+		job := *j.jobs[i]
+		job.Report = nil
+		if job.Tenant != filter.Tenant || filter.Type != "" && job.Type != filter.Type || filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, "", nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestMongoJobs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should not find unknown jobs", func(mt *mtest.T) {
+		jobs := NewMongoJobs(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		_, status, err := jobs.GetJob(context.Background(), "unknown")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+		assert.Equal(t, NotFound, status)
+	})
+
+	mt.Run("should list the jobs of the tenant by filter", func(mt *mtest.T) {
+		jobs := NewMongoJobs(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "1"}, {Key: "type", Value: "reindex"}, {Key: "status", Value: definition.JobRunning}, {Key: "createdAt", Value: time.Now()}}))
+		found, _, err := jobs.ListJobs(context.Background(), definition.JobFilter{Tenant: "acme", Type: "reindex", Limit: 10})
+		assert.Nil(t, err)
+		assert.Len(t, found, 1)
+		assert.Equal(t, "1", found[0].ID)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "acme", filter.Lookup("tenant").StringValue())
+		assert.Equal(t, "reindex", filter.Lookup("type").StringValue())
+		_, err = filter.LookupErr("status")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMemoryJobs(t *testing.T) {
+	jobs := NewMemoryJobs()
+	for i := 1; i <= maxMemoryJobs+1; i++ {
+		job := &definition.Job{ID: fmt.Sprint(i), Type: "reindex", Status: definition.JobSucceeded}
+		if i%2 == 0 {
+			job.Type = "import"
+		}
+		assert.Nil(t, jobs.SaveJob(context.Background(), job))
+	}
+	_, status, err := jobs.GetJob(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrJobNotFound, "the oldest job should be forgotten")
+	assert.Equal(t, NotFound, status)
+
+	assert.Nil(t, jobs.SaveJob(context.Background(), &definition.Job{ID: "101", Type: "reindex", Status: definition.JobFailed}))
+	job, _, err := jobs.GetJob(context.Background(), "101")
+	assert.Nil(t, err)
+	assert.Equal(t, definition.JobFailed, job.Status)
+
+	found, _, err := jobs.ListJobs(context.Background(), definition.JobFilter{Type: "reindex", Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"101", "99"}, []string{found[0].ID, found[1].ID})
+	found, _, _ = jobs.ListJobs(context.Background(), definition.JobFilter{Tenant: "acme"})
+	assert.Empty(t, found)
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// GetLastModified returns when the contacts last changed: the latest update
+// of a contact or deletion, restores included, the zero time when they never
+// changed. Both are found on the recent contacts and tombstones indexes.
+func (pb *MongoPhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	updatedAt, err := latest(ctx, pb.contactsCollection, "updatedAt")
+	if err != nil {
+		return time.Time{}, InternalServerError, err
+	}
+	deletedAt, err := latest(ctx, pb.tombstones, "deletedAt")
+	if err != nil {
+		return time.Time{}, InternalServerError, err
+	}
+	if deletedAt.After(updatedAt) {
+		return deletedAt, "", nil
+	}
+	return updatedAt, "", nil
+}
+
+// latest returns the latest time of field in collection.
+func latest(ctx context.Context, collection *mongo.Collection, field string) (time.Time, error) {
+	var document bson.M
+	err := collection.FindOne(ctx, bson.M{field: bson.M{"$type": "date"}}, options.FindOne().
+		SetSort(bson.D{{Key: field, Value: -1}}).SetProjection(bson.M{"_id": 0, field: 1})).Decode(&document)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	at, _ := document[field].(primitive.DateTime)
+	return at.Time().UTC(), nil
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+func TestGetLastModified(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	updatedAt := time.Date(2024, 1, 12, 10, 0, 0, 0, time.UTC)
+	latest := func(mt *mtest.T, collection string, documents ...bson.D) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), collection), mtest.FirstBatch, documents...)
+	}
+
+	mt.Run("should return the last update", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			latest(mt, "contacts", bson.D{{Key: "updatedAt", Value: updatedAt}}),
+			latest(mt, "tombstones", bson.D{{Key: "deletedAt", Value: updatedAt.Add(-time.Minute)}}))
+		lastModified, _, err := phoneBookMock.GetLastModified(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, updatedAt, lastModified)
+		find := mt.GetStartedEvent()
+		assert.Equal(t, int32(-1), find.Command.Lookup("sort", "updatedAt").Int32())
+	})
+
+	mt.Run("should return the last deletion", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			latest(mt, "contacts", bson.D{{Key: "updatedAt", Value: updatedAt}}),
+			latest(mt, "tombstones", bson.D{{Key: "deletedAt", Value: updatedAt.Add(time.Minute)}}))
+		lastModified, _, err := phoneBookMock.GetLastModified(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, updatedAt.Add(time.Minute), lastModified)
+	})
+
+	mt.Run("should return the zero time for an empty phone book", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(latest(mt, "contacts"), latest(mt, "tombstones"))
+		lastModified, _, err := phoneBookMock.GetLastModified(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, lastModified.IsZero())
+	})
+}
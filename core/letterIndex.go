@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	letterIndexName = "contacts_initial"
+	// otherInitial is the index letter of the names not starting with a
+	// letter, listed last like on phones.
+	otherInitial = "#"
+)
+
+// initial is the letter contact is indexed under: the first letter of its
+// last name, or of its first name when it has none, upper cased and without
+// diacritics.
+func initial(contact *definition.Contact) string {
+	name := normalizeDisplayName(contact.LastName)
+	if name == "" {
+		name = normalizeDisplayName(contact.FirstName)
+	}
+	return initialOf(name)
+}
+
+func initialOf(name string) string {
+	letter, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsLetter(letter) {
+		return otherInitial
+	}
+	return string(unicode.ToUpper(letter))
+}
+
+// validateStartsWithParam returns the index letter startsWith selects,
+// ignoring case and diacritics.
+func validateStartsWithParam(startsWith string) (string, error) {
+	if startsWith == otherInitial {
+		return otherInitial, nil
+	}
+	name := normalizeDisplayName(startsWith)
+	letter := initialOf(name)
+	if utf8.RuneCountInString(name) != 1 || letter == otherInitial {
+		return "", ErrInvalidStartsWith
+	}
+	return letter, nil
+}
+
+// ensureLetterIndex creates the index the alphabetical index is counted by
+// and its letters are listed by, in display name order.
+func (pb *MongoPhoneBook) ensureLetterIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "initial", Value: 1}, {Key: "displayName", Value: 1}},
+		Options: options.Index().SetName(letterIndexName),
+	}
+	_, err := pb.contactsCollection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// GetContactIndex returns the number of contacts per letter of the
+// alphabetical index, for jump to letter lists. Letters without contacts are
+// left out and # comes last.
+func (pb *MongoPhoneBook) GetContactIndex(ctx context.Context) ([]*definition.LetterCount, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"initial": bson.M{"$exists": true}, "archived": bson.M{"$ne": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$initial", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := pb.contactsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	var groups []struct {
+		Letter string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, InternalServerError, err
+	}
+	letters := make([]*definition.LetterCount, 0, len(groups))
+	for _, group := range groups {
+		letters = append(letters, &definition.LetterCount{Letter: group.Letter, Count: group.Count})
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if (letters[i].Letter == otherInitial) != (letters[j].Letter == otherInitial) {
+			return letters[j].Letter == otherInitial
+		}
+		return strings.Compare(letters[i].Letter, letters[j].Letter) < 0
+	})
+	return letters, "", nil
+}
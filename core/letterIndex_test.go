@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestInitial(t *testing.T) {
+	assert.Equal(t, "Z", initial(&definition.Contact{FirstName: "Émile", LastName: "Zola"}))
+	assert.Equal(t, "A", initial(&definition.Contact{FirstName: "Noa", LastName: "ávila"}))
+	assert.Equal(t, "D", initial(&definition.Contact{FirstName: "dani"}))
+	assert.Equal(t, "#", initial(&definition.Contact{FirstName: "Dani", LastName: "3M"}))
+
+	for startsWith, letter := range map[string]string{"b": "B", "É": "E", "#": "#"} {
+		found, err := validateStartsWithParam(startsWith)
+		assert.Nil(t, err)
+		assert.Equal(t, letter, found)
+	}
+	for _, startsWith := range []string{"", "ab", "1"} {
+		_, err := validateStartsWithParam(startsWith)
+		assert.ErrorIs(t, err, ErrInvalidStartsWith, startsWith)
+	}
+}
+
+func TestGetContactIndex(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should count the contacts per letter, # last", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "C"}, {Key: "count", Value: int32(2)}},
+			bson.D{{Key: "_id", Value: "#"}, {Key: "count", Value: int32(1)}},
+			bson.D{{Key: "_id", Value: "A"}, {Key: "count", Value: int32(5)}}))
+		letters, _, err := phoneBookMock.GetContactIndex(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []*definition.LetterCount{{Letter: "A", Count: 5}, {Letter: "C", Count: 2}, {Letter: "#", Count: 1}}, letters)
+	})
+
+	mt.Run("should list the contacts of a letter", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		_, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"startsWith": {"b"}, "count": {"false"}})
+		assert.Nil(t, err)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "B", filter.Lookup("initial").StringValue())
+	})
+
+	mt.Run("should reject an invalid letter", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"startsWith": {"Bo"}})
+		assert.ErrorIs(t, err, ErrInvalidStartsWith)
+		assert.Equal(t, BadRequest, status)
+	})
+}
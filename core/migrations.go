@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"phoneBook/definition"
+)
+
+// migration upgrades the contacts written before the shape of Contact
+// changed. up returns how many contacts it changed, and must be safe to run
+// again, since an interrupted migration is run over.
+type migration struct {
+	version     int
+	description string
+	up          func(pb *MongoPhoneBook, ctx context.Context) (int64, error)
+}
+
+// migrations are the steps of the contact schema, in version order. Add one
+// with the next version when the shape of Contact changes, and write the
+// contacts in the new shape from then on.
+var migrations = []migration{
+	{version: 1, description: "set the display name, normalized phone and initial", up: (*MongoPhoneBook).BackfillDerivedFields},
+	{version: 2, description: "set the phone country, type and area", up: (*MongoPhoneBook).BackfillDerivedFields},
+}
+
+// schemaVersion is the version of the contacts the phone book writes.
+var schemaVersion = migrations[len(migrations)-1].version
+
+// Migrate applies in order the migrations of the contacts written at older
+// schema versions, or only reports them in a dry run, and stamps the
+// contacts with the version of each migration applied.
+func (pb *MongoPhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	result := &definition.MigrationResult{SchemaVersion: schemaVersion, Migrations: []definition.MigrationStep{}, DryRun: dryRun}
+	for _, m := range migrations {
+		// contacts without a version predate the migrations
+		outdated := bson.M{"schemaVersion": bson.M{"$not": bson.M{"$gte": m.version}}}
+		pending, err := pb.contactsCollection.CountDocuments(ctx, outdated)
+		if err != nil {
+			return nil, err
+		}
+		if pending == 0 {
+			continue
+		}
+		step := definition.MigrationStep{Version: m.version, Description: m.description, Contacts: pending}
+		if !dryRun {
+			if _, err := m.up(pb, ctx); err != nil {
+				return nil, fmt.Errorf("migration %d failed: %w", m.version, err)
+			}
+			if _, err := pb.contactsCollection.UpdateMany(ctx, outdated, bson.M{"$set": bson.M{"schemaVersion": m.version}}); err != nil {
+				return nil, fmt.Errorf("migration %d failed: %w", m.version, err)
+			}
+		}
+		result.Migrations = append(result.Migrations, step)
+	}
+	return result, nil
+}
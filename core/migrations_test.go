@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should only report the pending migrations in a dry run", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(countResponse(mt, 3), countResponse(mt, 5))
+		result, err := phoneBookMock.Migrate(context.Background(), true)
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.MigrationResult{SchemaVersion: schemaVersion, Migrations: []definition.MigrationStep{
+			{Version: 1, Description: migrations[0].description, Contacts: 3},
+			{Version: 2, Description: migrations[1].description, Contacts: 5},
+		}, DryRun: true}, result)
+		assert.Equal(t, "aggregate", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "aggregate", mt.GetStartedEvent().CommandName)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("should upgrade the outdated contacts and stamp their version", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			countResponse(mt, 1),
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Dani"}, {Key: "phone", Value: "0521234567"}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			countResponse(mt, 0))
+		result, err := phoneBookMock.Migrate(context.Background(), false)
+		assert.Nil(t, err)
+		assert.Len(t, result.Migrations, 1)
+		assert.False(t, result.DryRun)
+		mt.GetStartedEvent()
+		mt.GetStartedEvent()
+		backfill := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "dani", backfill.Lookup("u", "$set", "displayName").StringValue())
+		assert.Equal(t, "IL", backfill.Lookup("u", "$set", "phoneCountry").StringValue())
+		assert.Equal(t, "mobile", backfill.Lookup("u", "$set", "phoneType").StringValue())
+		stamp := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, int32(1), stamp.Lookup("u", "$set", "schemaVersion").Int32())
+		assert.True(t, stamp.Lookup("multi").Boolean())
+	})
+
+	mt.Run("should apply nothing to contacts up to date", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(countResponse(mt, 0), countResponse(mt, 0))
+		result, err := phoneBookMock.Migrate(context.Background(), false)
+		assert.Nil(t, err)
+		assert.Empty(t, result.Migrations)
+	})
+}
+
+func TestRebuildDerivedData(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should set the stale derived fields and rebuild the indexes", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		phoneBookMock.secondaryIndexes = parseIndexSpecs([]string{"company"})
+		fresh := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Noa", Phone: "0541234567", Version: 2}
+		deriveFields(fresh)
+		stale := primitive.NewObjectID()
+		responses := []bson.D{
+			countResponse(mt, 2),
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: stale}, {Key: "firstName", Value: "Dani"}, {Key: "phone", Value: "0521234567"},
+					{Key: "displayName", Value: "old"}, {Key: "normalizedPhone", Value: "972521234567"}, {Key: "initial", Value: "D"}, {Key: "version", Value: int64(3)}},
+				bson.D{{Key: "_id", Value: fresh.ID}, {Key: "firstName", Value: "Noa"}, {Key: "phone", Value: "0541234567"},
+					{Key: "displayName", Value: fresh.DisplayName}, {Key: "normalizedPhone", Value: fresh.NormalizedPhone}, {Key: "initial", Value: fresh.Initial},
+					{Key: "phoneCountry", Value: fresh.PhoneCountry}, {Key: "phoneType", Value: fresh.PhoneType}, {Key: "version", Value: int64(2)}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		}
+		// dropping the text and secondary indexes, then creating every index
+		for i := 0; i < 20; i++ {
+			responses = append(responses, mtest.CreateSuccessResponse())
+		}
+		mt.AddMockResponses(responses...)
+		var progress []int64
+		result, err := phoneBookMock.RebuildDerivedData(context.Background(), func(done int64, total int64) {
+			assert.Equal(t, int64(2), total)
+			progress = append(progress, done)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.RebuildResult{Contacts: 2, Updated: 1, Indexes: []string{textIndexName, "company_1"}}, result)
+		assert.Equal(t, []int64{2}, progress)
+		mt.GetStartedEvent()
+		mt.GetStartedEvent()
+		updates, _ := mt.GetStartedEvent().Command.Lookup("updates").Array().Values()
+		assert.Len(t, updates, 1)
+		update := updates[0].Document()
+		assert.Equal(t, stale, update.Lookup("q", "_id").ObjectID())
+		assert.Equal(t, int64(3), update.Lookup("q", "version").Int64())
+		assert.Equal(t, "dani", update.Lookup("u", "$set", "displayName").StringValue())
+		assert.Equal(t, "IL", update.Lookup("u", "$set", "phoneCountry").StringValue())
+		assert.Equal(t, textIndexName, mt.GetStartedEvent().Command.Lookup("index").StringValue())
+	})
+}
+
+func TestIndexName(t *testing.T) {
+	assert.Equal(t, "lastName_1_firstName_1", indexName(bson.D{{Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}}))
+}
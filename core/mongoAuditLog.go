@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// fields that change on every write and are therefore left out of the diff
+var auditIgnoredFields = map[string]bool{"_id": true, "version": true, "createdAt": true, "updatedAt": true}
+
+type MongoAuditLog struct {
+	auditCollection *mongo.Collection
+	cipher          *fieldCipher
+}
+
+func NewMongoAuditLog(mongoClient *mongo.Client) *MongoAuditLog {
+	return NewMongoAuditLogIn(mongoClient, config.Static.MongoDBName)
+}
+
+// NewMongoAuditLogIn returns an audit log kept in the given database.
+func NewMongoAuditLogIn(mongoClient *mongo.Client, database string) *MongoAuditLog {
+	cipher := fieldCipherFromConfig()
+	auditCollection := mongoClient.Database(database, mongoDatabaseOptions()).Collection(config.Static.MongoAuditCollectionName, cipher.collectionOptions())
+	return &MongoAuditLog{
+		auditCollection: auditCollection,
+		cipher:          cipher,
+	}
+}
+
+// Record stores an audit entry for a contact mutation. Failures are only
+// logged since the mutation itself has already been applied.
+func (al *MongoAuditLog) Record(ctx context.Context, action string, actor string, contactID primitive.ObjectID, before *definition.Contact, after *definition.Contact) {
+	changes, err := al.cipher.sealChanges(diffContacts(before, after))
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to record %s of contact %s", action, contactID.Hex())
+		return
+	}
+	entry := &definition.AuditEntry{
+		ContactID:    contactID,
+		Action:       action,
+		Actor:        actor,
+		ClaimedActor: definition.ClaimedUserFromContext(ctx),
+		Timestamp:    time.Now().UTC(),
+		Before:       before,
+		After:        after,
+		Changes:      changes,
+	}
+	// the mutation is already applied, so the entry is written even if the
+	// request that triggered it was canceled meanwhile
+	insertCtx, cancel := context.WithTimeout(context.Background(), config.Static.QueryTimeout)
+	defer cancel()
+	_, err = al.auditCollection.InsertOne(insertCtx, entry)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to record %s of contact %s", action, contactID.Hex())
+	}
+}
+
+func (al *MongoAuditLog) GetHistory(ctx context.Context, contactID primitive.ObjectID) ([]*definition.AuditEntry, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := al.auditCollection.Find(ctx, bson.M{"contactId": contactID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var entries []*definition.AuditEntry
+	for cursor.Next(ctx) {
+		var entry *definition.AuditEntry
+		err := cursor.Decode(&entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := al.cipher.openChanges(entry.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetLatest returns the most recent audit entry of a contact, or nil if it has none.
+func (al *MongoAuditLog) GetLatest(ctx context.Context, contactID primitive.ObjectID) (*definition.AuditEntry, error) {
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}})
+	var entry *definition.AuditEntry
+	err := al.auditCollection.FindOne(ctx, bson.M{"contactId": contactID}, findOptions).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := al.cipher.openChanges(entry.Changes); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Erase deletes the entries of the contacts with the given ids, and those
+// holding normalizedPhone, unless empty, e.g. of contacts deleted earlier.
+// It returns how many entries were deleted.
+func (al *MongoAuditLog) Erase(ctx context.Context, contactIDs []primitive.ObjectID, normalizedPhone string) (int64, error) {
+	filters := bson.A{bson.M{"contactId": bson.M{"$in": contactIDs}}}
+	if normalizedPhone != "" {
+		stored := al.cipher.phoneIndex(normalizedPhone)
+		filters = append(filters, bson.M{"before.normalizedPhone": stored}, bson.M{"after.normalizedPhone": stored})
+	}
+	result, err := al.auditCollection.DeleteMany(ctx, bson.M{"$or": filters})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// diffContacts lists the fields whose values differ between two versions of a
+// contact. A nil contact is treated as having no fields.
+func diffContacts(before *definition.Contact, after *definition.Contact) []*definition.FieldChange {
+	beforeFields := contactFields(before)
+	afterFields := contactFields(after)
+	var changes []*definition.FieldChange
+	for _, field := range unionFieldNames(beforeFields, afterFields) {
+		beforeValue, afterValue := beforeFields[field], afterFields[field]
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		changes = append(changes, &definition.FieldChange{
+			Field:  field,
+			Before: beforeValue,
+			After:  afterValue,
+		})
+	}
+	return changes
+}
+
+func contactFields(contact *definition.Contact) bson.M {
+	fields := bson.M{}
+	if contact == nil {
+		return fields
+	}
+	raw, err := bson.Marshal(contact)
+	if err != nil {
+		return fields
+	}
+	_ = bson.Unmarshal(raw, &fields)
+	for field := range auditIgnoredFields {
+		delete(fields, field)
+	}
+	return fields
+}
+
+func unionFieldNames(first bson.M, second bson.M) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, fields := range []bson.M{first, second} {
+		for name := range fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
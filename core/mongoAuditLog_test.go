@@ -0,0 +1,181 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestDiffContacts(t *testing.T) {
+	before := &definition.Contact{
+		ID:        primitive.NewObjectID(),
+		FirstName: "bobo",
+		LastName:  "dag",
+		Phone:     "0545454524",
+		Version:   1,
+	}
+	after := &definition.Contact{
+		ID:        before.ID,
+		FirstName: "bobo",
+		Phone:     "0541112223",
+		Address:   "Tel Aviv",
+		Version:   2,
+	}
+
+	changes := diffContacts(before, after)
+	assert.Equal(t, 3, len(changes), "Should report only changed fields")
+	assert.Equal(t, &definition.FieldChange{Field: "address", After: "Tel Aviv"}, changes[0])
+	assert.Equal(t, &definition.FieldChange{Field: "lastName", Before: "dag"}, changes[1])
+	assert.Equal(t, &definition.FieldChange{Field: "phone", Before: "0545454524", After: "0541112223"}, changes[2])
+
+	assert.Equal(t, 3, len(diffContacts(nil, before)), "Should report every field of an added contact")
+}
+
+func TestContactHistory(t *testing.T) {
+	config.Static.AuditEnabled = true
+	defer func() { config.Static.AuditEnabled = false }()
+	contact := &definition.Contact{
+		ID:        primitive.NewObjectID(),
+		FirstName: "bobo",
+		Phone:     "0545454524",
+	}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should record contact update", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		namespace := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: contact.ID},
+				{Key: "firstName", Value: contact.FirstName},
+				{Key: "phone", Value: contact.Phone},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: contact.ID},
+				{Key: "firstName", Value: "changed"},
+				{Key: "phone", Value: contact.Phone},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		updated, err := phoneBookMock.UpdateContact(context.Background(), contact.ID.Hex(), &definition.Contact{FirstName: "changed"}, 0, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), updated.Modified)
+
+		events := mt.GetAllStartedEvents()
+		assert.Equal(t, "insert", events[len(events)-1].CommandName, "Should record an audit entry")
+		entry := events[len(events)-1].Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, definition.AuditActionUpdate, entry.Lookup("action").StringValue())
+		assert.Equal(t, "tester", entry.Lookup("actor").StringValue())
+		assert.Equal(t, "changed", entry.Lookup("after", "firstName").StringValue())
+		assert.Equal(t, "changed", entry.Lookup("after", "displayName").StringValue())
+	})
+
+	mt.Run("should record the user a client claims apart from the actor", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		ctx := definition.WithClaimedUser(context.Background(), "noy")
+		_, err := phoneBookMock.AddContact(ctx, &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "203.0.113.9")
+		assert.Nil(t, err)
+
+		events := mt.GetAllStartedEvents()
+		assert.Equal(t, "insert", events[len(events)-1].CommandName, "Should record an audit entry")
+		entry := events[len(events)-1].Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, definition.AuditActionAdd, entry.Lookup("action").StringValue())
+		assert.Equal(t, "203.0.113.9", entry.Lookup("actor").StringValue())
+		assert.Equal(t, "noy", entry.Lookup("claimedActor").StringValue())
+	})
+
+	mt.Run("should return contact history", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), config.Static.MongoAuditCollectionName), mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "contactId", Value: contact.ID},
+				{Key: "action", Value: definition.AuditActionAdd},
+			}))
+
+		entries, _, err := phoneBookMock.GetContactHistory(context.Background(), contact.ID.Hex())
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(entries))
+		assert.Equal(t, contact.ID, entries[0].ContactID)
+	})
+
+	mt.Run("should restore deleted contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		namespace := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "contactId", Value: contact.ID},
+				{Key: "action", Value: definition.AuditActionDelete},
+				{Key: "before", Value: bson.D{
+					{Key: "_id", Value: contact.ID},
+					{Key: "firstName", Value: contact.FirstName},
+					{Key: "phone", Value: contact.Phone},
+					{Key: "version", Value: int64(2)},
+				}},
+			}),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		restored, err := phoneBookMock.UndoContact(context.Background(), contact.ID.Hex(), "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, contact.ID, restored.ID)
+		assert.Equal(t, contact.FirstName, restored.FirstName)
+		assert.Equal(t, int64(3), restored.Version, "Should bump the restored contact version")
+	})
+
+	mt.Run("should not undo twice", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		namespace := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "contactId", Value: contact.ID},
+				{Key: "action", Value: definition.AuditActionRestore},
+			}),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: contact.ID},
+				{Key: "firstName", Value: contact.FirstName},
+			}),
+		)
+
+		_, err := phoneBookMock.UndoContact(context.Background(), contact.ID.Hex(), "tester")
+		assert.EqualErrorf(t, err, ErrorAlreadyUndone, "already undone")
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+
+	mt.Run("should not undo contact without history", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+
+		_, err := phoneBookMock.UndoContact(context.Background(), contact.ID.Hex(), "tester")
+		assert.EqualErrorf(t, err, ErrorNothingToUndo, "nothing to undo")
+		assert.Equal(t, NotFound, StatusOf(err))
+	})
+
+	mt.Run("should not return history when audit is disabled", func(mt *mtest.T) {
+		config.Static.AuditEnabled = false
+		defer func() { config.Static.AuditEnabled = true }()
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+
+		_, status, err := phoneBookMock.GetContactHistory(context.Background(), contact.ID.Hex())
+		assert.EqualErrorf(t, err, ErrorAuditDisabled, "audit disabled")
+		assert.Equal(t, BadRequest, status)
+	})
+}
@@ -9,64 +9,323 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 	"net/url"
 	"phoneBook/config"
 	"phoneBook/definition"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
 var (
-	onlyDigitsRegex       = regexp.MustCompile(`^[0-9]+$`)
-	onlyLettersRegex      = regexp.MustCompile(`^[a-zA-Z]+$`)
-	ErrorMissingFirstName = "can't add contact without first name"
-	ErrorMissingPhone     = "can't add contact without phone number"
-	ErrorMissingID        = "doesn't sent contact id"
-	ErrorInvalidPhone     = "invalid phone number. phone should include digits only"
-	ErrorInvalidFirstName = "invalid first name. name should include letters only"
-	ErrorInvalidLastName  = "invalid last name. name should include letters only"
-	BadRequest            = "BadRequest"
-	InternalServerError   = "InternalServerError"
+	onlyDigitsRegex          = regexp.MustCompile(`^[0-9]+$`)
+	onlyLettersRegex         = regexp.MustCompile(`^[\p{L}\p{M}]+$`)
+	emailRegex               = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	ErrorMissingFirstName    = "can't add contact without first name"
+	ErrorMissingPhone        = "can't add contact without phone number"
+	ErrorMissingID           = "doesn't sent contact id"
+	ErrorInvalidPhone        = "invalid phone number. phone should include digits only"
+	ErrorInvalidFirstName    = "invalid first name. name should include letters only"
+	ErrorInvalidLastName     = "invalid last name. name should include letters only"
+	ErrorInvalidEmail        = "invalid email address"
+	ErrorInvalidCompany      = "invalid company. company should not be blank or include control characters"
+	ErrorInvalidJobTitle     = "invalid job title. job title should not be blank or include control characters"
+	ErrorInvalidPage         = "page number must be positive"
+	ErrorEmptyPatch          = "doesn't sent any field to update"
+	ErrorUnknownField        = "unknown contact field"
+	ErrorClearFirstName      = "can't clear contact first name"
+	ErrorClearPhone          = "can't clear contact phone number"
+	ErrorVersionConflict     = "contact was modified by another client, reload it and try again"
+	ErrorInvalidSort         = "invalid sort. sort should be one of: updatedAt, createdAt, displayName"
+	ErrorAuditDisabled       = "contact history is not recorded, audit log is disabled"
+	ErrorNothingToUndo       = "contact has no change to undo"
+	ErrorAlreadyUndone       = "the last change of this contact was already undone"
+	ErrorDeletesBlocked      = "too many contacts deleted, deletes are blocked"
+	ErrorInvalidArchived     = "invalid includeArchived. includeArchived should be true or false"
+	ErrorInvalidOrder        = "invalid order. send the ids of the contacts to pin once each, up to 100"
+	ErrorContactExists       = "contact already exists"
+	ErrorDuplicateContact    = "a contact with the same details already exists"
+	ErrorInvalidCursor       = "invalid cursor. use the nextCursor returned by the previous page"
+	ErrorCursorWithSort      = "cursor pagination can't be combined with sort"
+	ErrorInvalidCount        = "invalid count. count should be true or false"
+	ErrorInvalidPageSize     = "invalid pageSize. pageSize should be a positive number"
+	ErrorMissingSearch       = "missing search text. send the words to search for in q"
+	ErrorContactNotFound     = "contact not found"
+	ErrorInvalidSearch       = "invalid search value. values should be non empty and up to the maximum field size"
+	ErrorInvalidBatch        = "send either ids or a filter of the contacts to delete"
+	ErrorBatchTooLarge       = "too many contacts to delete at once"
+	ErrorBackupFormat        = "invalid format. format should be one of: json, bson"
+	ErrorRestoreMode         = "invalid mode. mode should be one of: merge, replace"
+	ErrorInvalidBackup       = "invalid backup"
+	ErrorInvalidRelation     = "invalid relation type. type should be one of: spouse, assistant, manager, colleague"
+	ErrorSelfRelation        = "a contact can't be related to itself"
+	ErrorRelationNotFound    = "the contacts are not related"
+	ErrorInvalidInteraction  = "invalid interaction type. type should be one of: call, meeting, note"
+	ErrorMissingSummary      = "can't record interaction without summary"
+	ErrorFutureInteraction   = "interaction can't occur in the future"
+	ErrorInteractionNotFound = "interaction not found"
+	ErrorMissingDueAt        = "can't add reminder without due time"
+	ErrorPastReminder        = "reminder can't be due in the past"
+	ErrorMissingNote         = "can't add reminder without note"
+	ErrorInvalidSlot         = "invalid slot. slot should be a number from 1 to 99"
+	ErrorSlotTaken           = "the slot is assigned to another contact, clear it first"
+	ErrorContactHasSlot      = "the contact is assigned to another slot, clear it first"
+	ErrorInvalidDue          = "invalid due. due should be one of: today, overdue"
+	ErrorInvalidStartsWith   = "invalid startsWith. startsWith should be a single letter or #"
+	ErrorInvalidRecentBy     = "invalid by. by should be one of: created, updated"
+	ErrorInvalidLimit        = "invalid limit. limit should be a positive number"
+	ErrorInvalidSeedCount    = "invalid count. count should be a positive number up to 10000"
+	ErrorInvalidSampleSize   = "invalid size. size should be a positive number"
+	ErrorInvalidTenantID     = "invalid tenant id. id should be up to 32 lowercase letters, digits and dashes, starting with a letter or digit"
+	ErrorTenantExists        = "tenant already exists"
+	ErrorTenantNotFound      = "tenant not found"
+	ErrorJobNotFound         = "job not found"
+	ErrorInvalidLimits       = "invalid limits. limits should not be negative"
+	ErrorContactLimit        = "the phone book reached its contact limit, delete contacts or raise the limit"
+	ErrorUnsupported         = "not supported by the storage backend"
+	ErrorInvalidLookup       = "invalid phone number. number should include digits, optionally formatted with +, spaces, dashes, dots or parentheses"
+	ErrorRetentionAction     = "invalid action. action should be one of: anonymize, purge"
+	ErrorRetentionDays       = "invalid days. days should be a positive number"
+	ErrorEncryptedField      = "the field is encrypted and can't be searched"
+	ErrorFieldTooLong        = "too big contact field"
+	ErrorInvalidSyncToken    = "invalid sync token"
+	ErrorSyncTokenExpired    = "the sync token expired, sync all the contacts again without a token"
+	ErrorInvalidDistinct     = "invalid field. field should be one of: address, company, jobTitle, lastName"
+	ErrorPhoneMismatch       = "phone mismatch. the phone of the contact should be the number it is upserted by"
+	BadRequest               = "BadRequest"
+	InternalServerError      = "InternalServerError"
+	Conflict                 = "Conflict"
+	NotFound                 = "NotFound"
+	PaymentRequired          = "PaymentRequired"
+	NotImplemented           = "NotImplemented"
+	TooManyRequests          = "TooManyRequests"
+	Gone                     = "Gone"
 )
 
 type MongoPhoneBook struct {
 	client             *mongo.Client
 	contactsCollection *mongo.Collection
-	limitPerPage       int64
+	interactions       *mongo.Collection
+	reminders          *mongo.Collection
+	speedDials         *mongo.Collection
+	erasures           *mongo.Collection
+	tombstones         *mongo.Collection
+	// tunables returns the page sizes in use, those of the process, which
+	// follow the reloads, unless set otherwise.
+	tunables           func() config.Tunable
+	queryTimeout       time.Duration
+	tombstoneTTL       time.Duration
+	duplicateDetection string
+	secondaryIndexes   []bson.D
+	auditLog           *MongoAuditLog
+	cipher             *fieldCipher
+	importer           *batchWriter
 }
 
 func NewMongoPhoneBook(mongoClient *mongo.Client) *MongoPhoneBook {
-	contactsCollection := mongoClient.Database(config.Static.MongoDBName).Collection(config.Static.MongoCollectionName)
+	return NewMongoPhoneBookIn(mongoClient, config.Static.MongoDBName)
+}
+
+// NewMongoPhoneBookIn returns a phone book keeping its collections in the
+// given database, the database of a tenant.
+func NewMongoPhoneBookIn(mongoClient *mongo.Client, database string) *MongoPhoneBook {
+	db := mongoClient.Database(database, mongoDatabaseOptions())
+	cipher := fieldCipherFromConfig()
+	var auditLog *MongoAuditLog
+	if config.Static.AuditEnabled {
+		auditLog = NewMongoAuditLogIn(mongoClient, database)
+	}
+	var secondaryIndexes []bson.D
+	if config.Static.MongoAutoIndex {
+		secondaryIndexes = parseIndexSpecs(config.Static.MongoIndexes)
+	}
+	contactsCollection := db.Collection(config.Static.MongoCollectionName, cipher.collectionOptions())
 	return &MongoPhoneBook{
 		client:             mongoClient,
 		contactsCollection: contactsCollection,
-		limitPerPage:       config.Static.LimitPerPage,
+		interactions:       db.Collection(config.Static.MongoInteractionsCollection),
+		reminders:          db.Collection(config.Static.MongoRemindersCollection),
+		speedDials:         db.Collection(config.Static.MongoSpeedDialsCollection),
+		erasures:           db.Collection(config.Static.MongoErasuresCollection),
+		tombstones:         db.Collection(config.Static.MongoTombstonesCollection),
+		tunables:           config.Tunables,
+		queryTimeout:       config.Static.QueryTimeout,
+		tombstoneTTL:       config.Static.SyncTombstoneTTL,
+		duplicateDetection: validateDuplicateDetection(config.Static.DuplicateDetection),
+		secondaryIndexes:   secondaryIndexes,
+		auditLog:           auditLog,
+		cipher:             cipher,
+		importer: newBatchWriter(contactsCollection, config.Static.ImportBatchSize, config.Static.ImportFlushInterval,
+			config.Static.ImportConcurrency, config.Static.QueryTimeout),
+	}
+}
+
+// SetTunables makes the phone book take its page sizes from tunables, e.g.
+// the settings of a configuration other than the one of the process.
+func (pb *MongoPhoneBook) SetTunables(tunables func() config.Tunable) {
+	pb.tunables = tunables
+}
+
+// mongoDatabaseOptions returns the read preference and write concern of the
+// phone book databases, MONGO_READ_PREFERENCE and MONGO_WRITE_CONCERN, those
+// of the URI when not set.
+func mongoDatabaseOptions() *options.DatabaseOptions {
+	databaseOptions := options.Database()
+	if config.Static.MongoReadPreference != "" {
+		// the mode was validated with the configuration
+		mode, _ := readpref.ModeFromString(config.Static.MongoReadPreference)
+		readPreference, _ := readpref.New(mode)
+		databaseOptions.SetReadPreference(readPreference)
 	}
+	switch config.Static.MongoWriteConcern {
+	case "":
+	case "majority":
+		databaseOptions.SetWriteConcern(writeconcern.Majority())
+	default:
+		nodes, _ := strconv.Atoi(config.Static.MongoWriteConcern)
+		databaseOptions.SetWriteConcern(&writeconcern.WriteConcern{W: nodes})
+	}
+	return databaseOptions
 }
 
-func (pb *MongoPhoneBook) GetContactWithPagination(pageParam []string) ([]*definition.Contact, string, error) {
-	page, err := validatePageParam(pageParam)
+// withQueryTimeout bounds the queries of a single operation by the configured
+// query timeout, on top of the caller's cancellation.
+func (pb *MongoPhoneBook) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pb.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, pb.queryTimeout)
+}
+
+// GetContactWithPagination returns a page of contacts by page number, or by
+// the cursor token of the previous page when the cursor parameter is present.
+// The listing is counted unless count=false is sent. startsWith limits it to
+// the contacts of a letter of the alphabetical index.
+func (pb *MongoPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	filter := bson.M{}
+	if query.Has("startsWith") {
+		letter, err := validateStartsWithParam(query.Get("startsWith"))
+		if err != nil {
+			return nil, BadRequest, err
+		}
+		filter["initial"] = letter
+	}
+	if err := excludeArchived(filter, query); err != nil {
+		return nil, BadRequest, err
+	}
+	return pb.listContacts(ctx, filter, query)
+}
+
+// listContacts returns a page of the contacts matching filter, paginated by
+// the parameters of query like GetContactWithPagination.
+func (pb *MongoPhoneBook) listContacts(ctx context.Context, filter bson.M, query url.Values) (*definition.ContactPage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	sort, err := validateSortParam(query["sort"])
 	if err != nil {
 		return nil, BadRequest, err
 	}
-	findOptions := *options.Find()
-	findOptions.SetLimit(config.Static.LimitPerPage)
-	findOptions.SetSkip(int64(page-1) * config.Static.LimitPerPage)
-	cursor, err := pb.contactsCollection.Find(context.TODO(), bson.M{}, &findOptions)
+	withCount, err := validateCountParam(query["count"])
 	if err != nil {
-		return nil, InternalServerError, err
+		return nil, BadRequest, err
+	}
+	_, byCursor := query["cursor"]
+	if byCursor && sort != nil {
+		return nil, BadRequest, ErrCursorWithSort
+	}
+	lastID, err := decodeCursor(query.Get("cursor"))
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
 	}
-	defer cursor.Close(context.TODO())
-	var contacts []*definition.Contact
-	for cursor.Next(context.Background()) {
-		var contact *definition.Contact
-		err := cursor.Decode(&contact)
+	var totalItems int64
+	if withCount {
+		totalItems, err = pb.contactsCollection.CountDocuments(ctx, filter)
 		if err != nil {
-			return nil, BadRequest, err
+			return nil, InternalServerError, err
+		}
+	}
+	var page *definition.ContactPage
+	if byCursor {
+		var status string
+		page, status, err = pb.getContactsAfterCursor(ctx, filter, lastID, limit, projection)
+		if err != nil {
+			return nil, status, err
+		}
+	} else {
+		findOptions := *options.Find()
+		findOptions.SetLimit(limit)
+		findOptions.SetSkip(int64(pageNumber-1) * limit)
+		if sort == nil {
+			sort = pinnedOrder
+		}
+		findOptions.SetSort(sort)
+		if projection != nil {
+			findOptions.SetProjection(projection)
+		}
+		cursor, err := pb.contactsCollection.Find(ctx, filter, &findOptions)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		contacts, err := decodeContacts(ctx, cursor)
+		if err != nil {
+			return nil, InternalServerError, err
 		}
-		contacts = append(contacts, contact)
+		page = &definition.ContactPage{Items: contacts, Page: pageNumber}
+	}
+	if page.Items == nil {
+		page.Items = []*definition.Contact{}
+	}
+	page.PageSize = limit
+	if withCount {
+		totalPages := (totalItems + limit - 1) / limit
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
 	}
-	return contacts, "", nil
+	return page, "", nil
+}
+
+func validateCountParam(countParam []string) (bool, error) {
+	if len(countParam) == 0 || countParam[0] == "" {
+		return true, nil
+	}
+	withCount, err := strconv.ParseBool(countParam[0])
+	if err != nil {
+		return false, ErrInvalidCount
+	}
+	return withCount, nil
+}
+
+// validatePageSizeParam returns the requested page size clamped to
+// maxPageSize, or defaultSize when none was sent.
+func validatePageSizeParam(pageSizeParam []string, defaultSize int64, maxPageSize int64) (int64, error) {
+	if len(pageSizeParam) == 0 || pageSizeParam[0] == "" {
+		return defaultSize, nil
+	}
+	pageSize, err := strconv.ParseInt(pageSizeParam[0], 10, 64)
+	if err != nil || pageSize <= 0 {
+		return 0, ErrInvalidPageSize
+	}
+	if pageSize > maxPageSize {
+		return maxPageSize, nil
+	}
+	return pageSize, nil
 }
 
 func validatePageParam(pageParam []string) (int, error) {
@@ -77,112 +336,785 @@ func validatePageParam(pageParam []string) (int, error) {
 	if pageStr == "" {
 		return 1, nil
 	}
-	if pageStr == "0" {
-		return -1, errors.New("page number must be positive")
-	}
 	page, err := strconv.Atoi(pageStr)
 	if err != nil {
-		return -1, err
+		return -1, ErrInvalidPage.WithMessage(err.Error())
+	}
+	if page <= 0 {
+		return -1, ErrInvalidPage
 	}
 	return page, nil
 }
 
-func (pb *MongoPhoneBook) SearchContact(query url.Values) ([]*definition.Contact, string, error) {
-	filter := bson.M{}
-	for key, value := range query {
-		filter[key] = value[0]
+// validateSortParam returns the sort document for the requested listing order,
+// or nil for the pinned order. Timestamps are sorted most recent first and
+// display names alphabetically.
+func validateSortParam(sortParam []string) (bson.D, error) {
+	if len(sortParam) == 0 || sortParam[0] == "" {
+		return nil, nil
 	}
-	if len(query) == 0 {
-		return pb.GetContactWithPagination([]string{"1"})
+	switch sortParam[0] {
+	case "updatedAt", "createdAt":
+		return bson.D{{Key: sortParam[0], Value: -1}, {Key: "_id", Value: -1}}, nil
+	case "displayName":
+		return bson.D{{Key: "displayName", Value: 1}, {Key: "_id", Value: 1}}, nil
+	}
+	return nil, ErrInvalidSort
+}
+
+// SearchContact returns a page of the contacts matching the search
+// parameters, counted unless count=false is sent. The page and the count come
+// back from a single aggregation, $facet splitting the matches in two.
+func (pb *MongoPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	pageNumber, err := validatePageParam(query["page"])
+	if err != nil {
+		return nil, BadRequest, err
 	}
-	cursor, err := pb.contactsCollection.Find(context.TODO(), filter)
+	withCount, err := validateCountParam(query["count"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	filter, err := buildSearchFilter(query)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	if err := pb.cipher.sealSearch(filter); err != nil {
+		return nil, BadRequest, err
+	}
+	if err := excludeArchived(filter, query); err != nil {
+		return nil, BadRequest, err
+	}
+	// skipping pages needs a stable order
+	sort := bson.D{{Key: "_id", Value: 1}}
+	if query.Has("namePrefix") {
+		sort = bson.D{{Key: "displayName", Value: 1}, {Key: "_id", Value: 1}}
+	}
+	items := bson.A{bson.M{"$skip": int64(pageNumber-1) * limit}, bson.M{"$limit": limit}}
+	if projection != nil {
+		items = append(items, bson.M{"$project": projection})
+	}
+	facets := bson.M{"items": items}
+	if withCount {
+		facets["totalCount"] = bson.A{bson.M{"$count": "count"}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$facet", Value: facets}},
+	}
+	cursor, err := pb.contactsCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, InternalServerError, err
 	}
-	defer cursor.Close(context.TODO())
-	var contacts []*definition.Contact
-	for cursor.Next(context.Background()) {
-		var contact *definition.Contact
-		err := cursor.Decode(&contact)
-		if err != nil {
+	defer cursor.Close(ctx)
+	var result struct {
+		Items      []*definition.Contact `bson:"items"`
+		TotalCount []struct {
+			Count int64 `bson:"count"`
+		} `bson:"totalCount"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
 			return nil, BadRequest, err
 		}
-		contacts = append(contacts, contact)
 	}
-	return contacts, "", nil
+	if err := cursor.Err(); err != nil {
+		return nil, InternalServerError, err
+	}
+	page := &definition.ContactPage{Items: result.Items, Page: pageNumber, PageSize: limit}
+	if page.Items == nil {
+		page.Items = []*definition.Contact{}
+	}
+	if withCount {
+		// $count outputs nothing when nothing matched
+		var totalItems int64
+		if len(result.TotalCount) > 0 {
+			totalItems = result.TotalCount[0].Count
+		}
+		totalPages := (totalItems + limit - 1) / limit
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
+	}
+	return page, "", nil
+}
+
+// searchableFields are the contact fields SearchContact filters by exact match.
+var searchableFields = map[string]bool{
+	"firstName": true,
+	"lastName":  true,
+	"phone":     true,
+	"email":     true,
+	"company":   true,
+	"jobTitle":  true,
+	"address":   true,
+	"notes":     true,
+	"owner":     true,
+	"userName":  true,
+	// the phone information, e.g. phoneCountry=IL
+	"phoneCountry": true,
+	"phoneType":    true,
+	"phoneArea":    true,
 }
 
-func (pb *MongoPhoneBook) DeleteContact(idParam string) (int64, string, error) {
+// CountContacts returns the number of contacts matching the search
+// parameters, counted by the database without reading them.
+func (pb *MongoPhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	filter, err := buildSearchFilter(query)
+	if err != nil {
+		return 0, BadRequest, err
+	}
+	if err := pb.cipher.sealSearch(filter); err != nil {
+		return 0, BadRequest, err
+	}
+	if err := excludeArchived(filter, query); err != nil {
+		return 0, BadRequest, err
+	}
+	count, err := pb.contactsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, InternalServerError, err
+	}
+	return count, "", nil
+}
+
+// buildSearchFilter turns the search parameters into an exact match filter,
+// rejecting unknown keys so callers can't query internal fields or operators.
+// namePrefix matches the start of the normalized display name instead.
+func buildSearchFilter(query url.Values) (bson.M, error) {
+	filter := bson.M{}
+	for key, values := range query {
+		if key == "pageSize" || key == "page" || key == "count" || key == "fields" || key == includeArchivedParam {
+			continue
+		}
+		if key == "namePrefix" {
+			prefix := normalizeDisplayName(values[0])
+			if prefix == "" || len(prefix) > config.Static.MaxSizeProperty || strings.ContainsRune(prefix, 0) {
+				return nil, ErrInvalidSearchValue.WithField(key)
+			}
+			filter["displayName"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+			continue
+		}
+		if !searchableFields[key] {
+			return nil, ErrUnknownField.WithField(key).WithMessage(fmt.Sprintf("%s: %s", ErrorUnknownField, key))
+		}
+		value := strings.TrimSpace(values[0])
+		if value == "" || len(value) > config.Static.MaxSizeProperty || strings.ContainsRune(value, 0) {
+			return nil, ErrInvalidSearchValue.WithField(key)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+func (pb *MongoPhoneBook) DeleteContact(ctx context.Context, idParam string, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
 	if idParam == "" {
 		logrus.Println("doesn't sent contact id to delete")
-		return 0, BadRequest, errors.New(ErrorMissingID)
+		return nil, ErrMissingID
 	}
 	id, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return -1, BadRequest, err
+		return nil, ErrInvalidID
+	}
+	before, err := pb.findAuditedContact(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 	filter := bson.M{"_id": id}
-	deleteResult, err := pb.contactsCollection.DeleteOne(context.Background(), filter)
+	deleteResult, err := pb.contactsCollection.DeleteOne(ctx, filter)
 	if err != nil {
-		return -1, InternalServerError, err
+		return nil, err
 	}
+	result := &definition.WriteResult{Matched: deleteResult.DeletedCount, Modified: deleteResult.DeletedCount}
 	if deleteResult.DeletedCount == 0 {
-		return 0, "", nil
+		return result, nil
 	}
-	return deleteResult.DeletedCount, "", nil
+	if pb.auditLog != nil {
+		pb.auditLog.Record(ctx, definition.AuditActionDelete, actor, id, before, nil)
+	}
+	pb.recordTombstones(ctx, actor, id)
+	return result, nil
 }
 
-func (pb *MongoPhoneBook) UpdateContact(idParam string, contact *definition.Contact) (int64, string, error) {
+func (pb *MongoPhoneBook) UpdateContact(ctx context.Context, idParam string, contact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
 	if idParam == "" {
 		logrus.Println("doesn't sent contact id to delete")
-		return 0, BadRequest, errors.New(ErrorMissingID)
+		return nil, ErrMissingID
 	}
 	id, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return -1, BadRequest, err
+		return nil, ErrInvalidID
+	}
+	if err := validateContactUpdate(contact); err != nil {
+		return nil, err
+	}
+	contact.Version = 0
+	contact.DisplayName = ""
+	contact.Initial = ""
+	contact.Relations = nil
+	contact.Archived = false
+	contact.SortWeight = 0
+	contact.Blocked = false
+	contact.Owner = ""
+	contact.NormalizedPhone = normalizePhone(contact.Phone)
+	setPhoneInfo(contact)
+	contact.CreatedAt = nil
+	contact.UpdatedAt = nil
+	update := bson.M{"$set": contact}
+	unsetMissingPhoneInfo(update, contact)
+	return pb.updateVersioned(ctx, id, update, expectedVersion, true, actor)
+}
+
+// updateVersioned applies the update, bumps the contact version and stamps
+// updatedAt. When an expected version is given, the update only applies if
+// the stored version still matches it, otherwise ErrVersionConflict is returned.
+// An update leaving the contact as it is doesn't apply, the contact is
+// reported matched but not modified.
+// The display name is derived again when the update may rename the contact.
+func (pb *MongoPhoneBook) updateVersioned(ctx context.Context, id primitive.ObjectID, update bson.M, expectedVersion int64, renames bool, actor string) (*definition.WriteResult, error) {
+	before, err := pb.findAuditedContact(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 	filter := bson.M{"_id": id}
-	updatedCount, err := pb.contactsCollection.UpdateOne(context.Background(), filter, bson.M{"$set": contact})
+	if expectedVersion > 0 {
+		filter["version"] = expectedVersion
+	}
+	unchanged, err := pb.unchangedFilter(update)
+	if err != nil {
+		return nil, err
+	}
+	if unchanged != nil {
+		filter["$nor"] = bson.A{unchanged}
+	}
+	update["$inc"] = bson.M{"version": 1}
+	update["$currentDate"] = bson.M{"updatedAt": true}
+	updatedCount, err := pb.contactsCollection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateContact
+	}
+	if err != nil {
+		return nil, err
+	}
+	if updatedCount.MatchedCount == 0 && (expectedVersion > 0 || unchanged != nil) {
+		matched, err := pb.unmatchedUpdate(ctx, id, expectedVersion, unchanged != nil)
+		if err != nil {
+			return nil, err
+		}
+		return &definition.WriteResult{Matched: matched}, nil
+	}
+	if updatedCount.ModifiedCount > 0 {
+		pb.recordUpdate(ctx, id, before, renames, actor)
+	}
+	return &definition.WriteResult{Matched: updatedCount.MatchedCount, Modified: updatedCount.ModifiedCount}, nil
+}
+
+// unchangedFilter returns the filter of the contacts update would leave as
+// they are, every field it sets holding the value already and every field it
+// unsets missing, or nil for updates with other operators. The values are
+// matched as stored, so fields encrypted with random nonces never match and
+// setting them always changes the contact.
+func (pb *MongoPhoneBook) unchangedFilter(update bson.M) (bson.M, error) {
+	unchanged := bson.M{}
+	for operator, fields := range update {
+		switch operator {
+		case "$set":
+			values, err := pb.storedValues(fields)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range values {
+				unchanged[value.Key] = value.Value
+			}
+		case "$unset":
+			for field := range fields.(bson.M) {
+				unchanged[field] = bson.M{"$exists": false}
+			}
+		default:
+			return nil, nil
+		}
+	}
+	if len(unchanged) == 0 {
+		return nil, nil
+	}
+	return unchanged, nil
+}
+
+// storedValues returns the fields a $set writes as they are stored, the
+// contacts encrypted as the collection encodes them. The values of the
+// other updates are sealed by their callers already.
+func (pb *MongoPhoneBook) storedValues(fields interface{}) (bson.D, error) {
+	if values, ok := fields.(bson.M); ok {
+		document := make(bson.D, 0, len(values))
+		for field, value := range values {
+			document = append(document, bson.E{Key: field, Value: value})
+		}
+		return document, nil
+	}
+	raw, err := bson.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fields.(*definition.Contact); ok {
+		if raw, err = pb.cipher.sealDocument(raw); err != nil {
+			return nil, err
+		}
+	}
+	var document bson.D
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// unmatchedUpdate tells why an update of the contact with id matched
+// nothing: the contact doesn't exist, its version isn't expectedVersion
+// anymore, or, when the update was guarded by unchangedFilter, it holds the
+// update already, reported as one matched contact.
+func (pb *MongoPhoneBook) unmatchedUpdate(ctx context.Context, id primitive.ObjectID, expectedVersion int64, guarded bool) (int64, error) {
+	existing, err := pb.contactsCollection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return 0, err
+	}
+	if existing == 0 {
+		return 0, nil
+	}
+	if expectedVersion > 0 {
+		current := int64(0)
+		if guarded {
+			current, err = pb.contactsCollection.CountDocuments(ctx, bson.M{"_id": id, "version": expectedVersion})
+			if err != nil {
+				return 0, err
+			}
+		}
+		if current == 0 {
+			return 0, ErrVersionConflict
+		}
+	}
+	return existing, nil
+}
+
+// recordUpdate follows an update of the contact with id: its display name is
+// derived again when the update may rename it, and the update is recorded in
+// the audit log, from the contact before it.
+func (pb *MongoPhoneBook) recordUpdate(ctx context.Context, id primitive.ObjectID, before *definition.Contact, renames bool, actor string) {
+	if !renames && pb.auditLog == nil {
+		return
+	}
+	after, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to load updated contact %s", id.Hex())
+	}
+	if renames && after != nil {
+		if err := pb.refreshDisplayName(ctx, after); err != nil {
+			logrus.WithError(err).Errorf("failed to refresh the display name of contact %s", id.Hex())
+		}
+	}
+	if pb.auditLog != nil {
+		pb.auditLog.Record(ctx, definition.AuditActionUpdate, actor, id, before, after)
+	}
+}
+
+// findAuditedContact loads the current state of a contact before it is
+// mutated, only when the audit log needs it.
+func (pb *MongoPhoneBook) findAuditedContact(ctx context.Context, id primitive.ObjectID) (*definition.Contact, error) {
+	if pb.auditLog == nil {
+		return nil, nil
+	}
+	return pb.findContactByID(ctx, id)
+}
+
+// findContactByID returns the contact with the given id, or nil if it doesn't exist.
+func (pb *MongoPhoneBook) findContactByID(ctx context.Context, id primitive.ObjectID) (*definition.Contact, error) {
+	var contact *definition.Contact
+	err := pb.contactsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&contact)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+func (pb *MongoPhoneBook) PatchContact(ctx context.Context, idParam string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		logrus.Println("doesn't sent contact id to patch")
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
-		return -1, InternalServerError, err
+		return nil, ErrInvalidID
 	}
-	if updatedCount.ModifiedCount == 0 {
-		return 0, "", nil
+	update, err := buildPatchUpdate(patch)
+	if err != nil {
+		return nil, err
+	}
+	if set, ok := update["$set"].(bson.M); ok {
+		if err := pb.cipher.sealValues(set); err != nil {
+			return nil, err
+		}
+	}
+	_, firstName := patch["firstName"]
+	_, lastName := patch["lastName"]
+	return pb.updateVersioned(ctx, id, update, expectedVersion, firstName || lastName, actor)
+}
+
+// buildPatchUpdate translates a patch into a $set/$unset update document,
+// validating every field that is present in the patch.
+func buildPatchUpdate(patch definition.ContactPatch) (bson.M, error) {
+	if len(patch) == 0 {
+		return nil, ErrEmptyPatch
+	}
+	if err := validatePatch(patch); err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	unset := bson.M{}
+	for field, value := range patch {
+		if value == nil {
+			unset[field] = ""
+		} else {
+			set[field] = *value
+		}
+		if field == "phone" && value != nil {
+			set["normalizedPhone"] = normalizePhone(*value)
+			patchPhoneInfo(*value, set, unset)
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
-	return updatedCount.ModifiedCount, "", nil
+	return update, nil
 }
 
-func (pb *MongoPhoneBook) AddContact(contact *definition.Contact) (string, string, error) {
+// editableFields returns the editable fields of contact by their json name.
+func editableFields(contact *definition.Contact) map[string]*string {
+	return map[string]*string{
+		"firstName": &contact.FirstName,
+		"lastName":  &contact.LastName,
+		"phone":     &contact.Phone,
+		"email":     &contact.Email,
+		"company":   &contact.Company,
+		"jobTitle":  &contact.JobTitle,
+		"address":   &contact.Address,
+		"notes":     &contact.Notes,
+	}
+}
+
+// setContactFields sets the fields of contact sent in update, keeping the
+// others, like the $set of UpdateContact does. It serves the backends that
+// write whole contacts.
+func setContactFields(contact *definition.Contact, update *definition.Contact) {
+	fields := editableFields(contact)
+	for field, value := range editableFields(update) {
+		if *value != "" {
+			*fields[field] = *value
+		}
+	}
+	if update.UserName != "" {
+		contact.UserName = update.UserName
+	}
+}
+
+// applyContactPatch applies a validated patch to contact, for the backends
+// that write whole contacts.
+func applyContactPatch(contact *definition.Contact, patch definition.ContactPatch) {
+	fields := editableFields(contact)
+	for field, value := range patch {
+		*fields[field] = ""
+		if value != nil {
+			*fields[field] = *value
+		}
+	}
+}
+
+// validatePatch validates every field of patch, reporting the issues of all
+// of them by the order of their names.
+func validatePatch(patch definition.ContactPatch) error {
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	var errs definition.Errors
+	for _, field := range fields {
+		var typedErr *definition.Error
+		if err := validatePatchField(field, patch[field]); errors.As(err, &typedErr) {
+			errs = append(errs, typedErr)
+		} else if err != nil {
+			return err
+		}
+	}
+	return errs.Err()
+}
+
+func validatePatchField(field string, value *string) error {
+	switch field {
+	case "firstName":
+		if value == nil || *value == "" {
+			return ErrClearFirstName
+		}
+		if !onlyLettersRegex.MatchString(*value) {
+			return ErrInvalidFirstName
+		}
+	case "lastName":
+		if value != nil && *value != "" && !onlyLettersRegex.MatchString(*value) {
+			return ErrInvalidLastName
+		}
+	case "phone":
+		if value == nil || *value == "" {
+			return ErrClearPhone
+		}
+		if !onlyDigitsRegex.MatchString(*value) {
+			return ErrInvalidPhone
+		}
+	case "email":
+		if value != nil && *value != "" && !emailRegex.MatchString(*value) {
+			return ErrInvalidEmail
+		}
+	case "company":
+		if value != nil && !isText(*value) {
+			return ErrInvalidCompany
+		}
+	case "jobTitle":
+		if value != nil && !isText(*value) {
+			return ErrInvalidJobTitle
+		}
+	case "address", "notes":
+	default:
+		return ErrUnknownField.WithField(field).WithMessage(fmt.Sprintf("%s: %s", ErrorUnknownField, field))
+	}
+	return nil
+}
+
+func (pb *MongoPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
 	err := validateContact(contact)
 	if err != nil {
-		return "", BadRequest, err
+		return nil, err
+	}
+	if err := pb.checkContactLimit(ctx, 1); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	contact.Version = 1
+	contact.Relations = nil
+	contact.Archived = false
+	contact.SortWeight = 0
+	contact.Blocked = false
+	contact.Owner = definition.UserFromContext(ctx)
+	contact.SchemaVersion = schemaVersion
+	deriveFields(contact)
+	contact.CreatedAt = &now
+	contact.UpdatedAt = &now
+	result, err := pb.contactsCollection.InsertOne(ctx, contact)
+	if mongo.IsDuplicateKeyError(err) && pb.duplicateDetection != DuplicateDetectionNone {
+		return nil, pb.duplicateError(ctx, contact)
 	}
-	result, err := pb.contactsCollection.InsertOne(context.Background(), contact)
 	if err != nil {
-		return "", InternalServerError, err
+		return nil, err
 	}
 	id, ok := result.InsertedID.(primitive.ObjectID)
 	if !ok {
-		return "", InternalServerError, err
+		return nil, fmt.Errorf("unexpected inserted id %v", result.InsertedID)
+	}
+	if pb.auditLog != nil {
+		contact.ID = id
+		pb.auditLog.Record(ctx, definition.AuditActionAdd, actor, id, nil, contact)
 	}
-	return fmt.Sprintf("Inserted ID: %s", id.String()[10:34]), "", nil
+	return &definition.WriteResult{ID: id.Hex(), Created: true}, nil
+}
+
+// GetContact returns a single contact by its id.
+func (pb *MongoPhoneBook) GetContact(ctx context.Context, idParam string) (*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, BadRequest, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidID
+	}
+	contact, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	if contact == nil {
+		return nil, NotFound, ErrContactNotFound
+	}
+	return contact, "", nil
+}
+
+func (pb *MongoPhoneBook) GetContactHistory(ctx context.Context, idParam string) ([]*definition.AuditEntry, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, BadRequest, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidID
+	}
+	if pb.auditLog == nil {
+		return nil, BadRequest, ErrAuditDisabled
+	}
+	entries, err := pb.auditLog.GetHistory(ctx, id)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	return entries, "", nil
+}
+
+// UndoContact reverts the most recent update of a contact, or restores it if
+// its most recent change was a delete. The restored contact is returned.
+func (pb *MongoPhoneBook) UndoContact(ctx context.Context, idParam string, actor string) (*definition.Contact, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	if pb.auditLog == nil {
+		return nil, ErrAuditDisabled
+	}
+	latest, err := pb.auditLog.GetLatest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, ErrNothingToUndo
+	}
+	current, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch latest.Action {
+	case definition.AuditActionUpdate:
+		if current == nil {
+			return nil, ErrNothingToUndo
+		}
+		return pb.revertContact(ctx, current, latest.Before, actor)
+	case definition.AuditActionDelete:
+		if current != nil {
+			return nil, ErrContactExists
+		}
+		return pb.restoreContact(ctx, latest.Before, actor)
+	case definition.AuditActionRevert, definition.AuditActionRestore:
+		return nil, ErrAlreadyUndone
+	}
+	return nil, ErrNothingToUndo
+}
+
+func (pb *MongoPhoneBook) revertContact(ctx context.Context, current *definition.Contact, before *definition.Contact, actor string) (*definition.Contact, error) {
+	now := time.Now().UTC()
+	reverted := *before
+	reverted.Version = current.Version + 1
+	deriveFields(&reverted)
+	reverted.UpdatedAt = &now
+	filter := bson.M{"_id": current.ID, "version": current.Version}
+	result, err := pb.contactsCollection.ReplaceOne(ctx, filter, &reverted)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateContact
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrVersionConflict
+	}
+	pb.auditLog.Record(ctx, definition.AuditActionRevert, actor, current.ID, current, &reverted)
+	return &reverted, nil
+}
+
+func (pb *MongoPhoneBook) restoreContact(ctx context.Context, deleted *definition.Contact, actor string) (*definition.Contact, error) {
+	now := time.Now().UTC()
+	restored := *deleted
+	restored.Version = deleted.Version + 1
+	deriveFields(&restored)
+	restored.UpdatedAt = &now
+	_, err := pb.contactsCollection.InsertOne(ctx, &restored)
+	if pb.isDuplicateContact(err) {
+		return nil, pb.duplicateError(ctx, &restored)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		// restored meanwhile by another request
+		return nil, ErrContactExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	pb.auditLog.Record(ctx, definition.AuditActionRestore, actor, restored.ID, nil, &restored)
+	return &restored, nil
 }
 
 func validateContact(contact *definition.Contact) error {
+	var errs definition.Errors
 	if contact.FirstName == "" {
-		return errors.New(ErrorMissingFirstName)
-	}
-	if !onlyLettersRegex.MatchString(contact.FirstName) {
-		return errors.New(ErrorInvalidFirstName)
+		errs = append(errs, ErrMissingFirstName)
+	} else if !onlyLettersRegex.MatchString(contact.FirstName) {
+		errs = append(errs, ErrInvalidFirstName)
 	}
 	if contact.LastName != "" && !onlyLettersRegex.MatchString(contact.LastName) {
-		return errors.New(ErrorInvalidLastName)
+		errs = append(errs, ErrInvalidLastName)
 	}
 	if contact.Phone == "" {
-		return errors.New(ErrorMissingPhone)
+		errs = append(errs, ErrMissingPhone)
+	} else if !onlyDigitsRegex.MatchString(contact.Phone) {
+		errs = append(errs, ErrInvalidPhone)
 	}
-	if !onlyDigitsRegex.MatchString(contact.Phone) {
-		return errors.New(ErrorInvalidPhone)
+	if contact.Email != "" && !emailRegex.MatchString(contact.Email) {
+		errs = append(errs, ErrInvalidEmail)
 	}
-	return nil
+	if contact.Company != "" && !isText(contact.Company) {
+		errs = append(errs, ErrInvalidCompany)
+	}
+	if contact.JobTitle != "" && !isText(contact.JobTitle) {
+		errs = append(errs, ErrInvalidJobTitle)
+	}
+	return errs.Err()
+}
+
+// validateContactUpdate validates the fields sent in an update of a contact
+// like those of a patch. The fields left empty are kept as they are, and
+// aren't validated.
+func validateContactUpdate(contact *definition.Contact) error {
+	patch := definition.ContactPatch{}
+	for field, value := range editableFields(contact) {
+		if *value != "" {
+			patch[field] = value
+		}
+	}
+	return validatePatch(patch)
+}
+
+// isText reports whether value holds more than blanks, and no control
+// characters, e.g. line breaks.
+func isText(value string) bool {
+	return strings.TrimSpace(value) != "" && strings.IndexFunc(value, unicode.IsControl) < 0
 }
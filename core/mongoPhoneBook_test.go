@@ -51,36 +51,81 @@ func TestAddContact(t *testing.T) {
 	mt.Run("should add valid contact", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(validContact)
+		_, err := phoneBookMock.AddContact(context.Background(), validContact, "")
 		assert.Nil(t, err)
+		assert.NotNil(t, validContact.CreatedAt, "Should stamp creation time")
+		assert.Equal(t, validContact.CreatedAt, validContact.UpdatedAt)
 	})
 
 	mt.Run("should add contact without last name and address", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(validContactWithoutLastNameAndAddress)
+		_, err := phoneBookMock.AddContact(context.Background(), validContactWithoutLastNameAndAddress, "")
 		assert.Nil(t, err)
 	})
 
+	mt.Run("should not add contact with existing phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		existingID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: existingID},
+				{Key: "firstName", Value: "other"},
+				{Key: "phone", Value: validContact.Phone},
+			}))
+		_, err := phoneBookMock.AddContact(context.Background(), validContactWithoutLastNameAndAddress, "")
+		assert.ErrorContains(t, err, ErrorDuplicateContact)
+		assert.ErrorContains(t, err, existingID.Hex(), "Should name the existing contact")
+		assert.Equal(t, Conflict, StatusOf(err))
+	})
+
 	mt.Run("should not add contact without phone", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(invalidContactWithoutPhone)
+		_, err := phoneBookMock.AddContact(context.Background(), invalidContactWithoutPhone, "")
 		assert.EqualErrorf(t, err, ErrorMissingPhone, "Error should be: %v, got: %v", ErrorMissingPhone, err)
 	})
 
 	mt.Run("should not add contact with invalid phone", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(invalidContactPhone)
+		_, err := phoneBookMock.AddContact(context.Background(), invalidContactPhone, "")
 		assert.EqualErrorf(t, err, ErrorInvalidPhone, "Error should be: %v, got: %v", ErrorInvalidPhone, err)
+		assert.ErrorIs(t, err, ErrInvalidPhone, "Should return a typed error")
+	})
+
+	mt.Run("should not add contact with invalid email", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		contact := &definition.Contact{FirstName: "Dani", Phone: "0521234567", Email: "dani.example.com"}
+		_, err := phoneBookMock.AddContact(context.Background(), contact, "")
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+
+	mt.Run("should not add contact with a multiline company", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		contact := &definition.Contact{FirstName: "Dani", Phone: "0521234567", Company: "Acme\nLtd"}
+		_, err := phoneBookMock.AddContact(context.Background(), contact, "")
+		assert.ErrorIs(t, err, ErrInvalidCompany)
+		assert.Equal(t, BadRequest, StatusOf(err))
 	})
 
 	mt.Run("should not add contact with invalid name", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(invalidContactLastName)
-		assert.EqualErrorf(t, err, ErrorInvalidLastName, "Error should be: %v, got: %v", ErrorInvalidLastName, err)
+		_, err := phoneBookMock.AddContact(context.Background(), invalidContactLastName, "")
+		assert.ErrorIs(t, err, ErrInvalidLastName)
+	})
+
+	mt.Run("should report every invalid field", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		contact := &definition.Contact{LastName: "C0hen", Phone: "052-abc"}
+		_, err := phoneBookMock.AddContact(context.Background(), contact, "")
+		var errs definition.Errors
+		assert.ErrorAs(t, err, &errs)
+		assert.Equal(t, definition.Errors{ErrMissingFirstName, ErrInvalidLastName, ErrInvalidPhone}, errs)
+		assert.Equal(t, BadRequest, StatusOf(err))
 	})
 }
 
@@ -99,7 +144,7 @@ func TestDeleteContact(t *testing.T) {
 	mt.Run("should delete existing contact", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(validContact)
+		_, err := phoneBookMock.AddContact(context.Background(), validContact, "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
@@ -107,9 +152,9 @@ func TestDeleteContact(t *testing.T) {
 			{Key: "ok", Value: 1},
 			{Key: "acknowledged", Value: true},
 			{Key: "n", Value: expectedDeleted}})
-		deletedCount, _, err := phoneBookMock.DeleteContact(validContact.ID.String()[10:34])
+		deleted, err := phoneBookMock.DeleteContact(context.Background(), validContact.ID.Hex(), "")
 		assert.Nil(t, err)
-		assert.Equal(t, expectedDeleted, deletedCount, "Should delete exactly one contact")
+		assert.Equal(t, expectedDeleted, deleted.Modified, "Should delete exactly one contact")
 	})
 
 	mt.Run("should not delete wrong ID format", func(mt *mtest.T) {
@@ -118,9 +163,10 @@ func TestDeleteContact(t *testing.T) {
 			{Key: "ok", Value: 1},
 			{Key: "acknowledged", Value: false},
 			{Key: "n", Value: nothingDeleted}})
-		deletedCount, _, err := phoneBookMock.DeleteContact("1234567")
+		deleted, err := phoneBookMock.DeleteContact(context.Background(), "1234567", "")
 		assert.EqualErrorf(t, err, "the provided hex string is not a valid ObjectID", "wrong ID format")
-		assert.Equal(t, -1, int(deletedCount), "got wrong ID format")
+		assert.Equal(t, BadRequest, StatusOf(err))
+		assert.Nil(t, deleted, "got wrong ID format")
 	})
 
 	mt.Run("should not delete not existing contact", func(mt *mtest.T) {
@@ -129,9 +175,9 @@ func TestDeleteContact(t *testing.T) {
 			{Key: "ok", Value: 1},
 			{Key: "acknowledged", Value: false},
 			{Key: "n", Value: nothingDeleted}})
-		deletedCount, _, err := phoneBookMock.DeleteContact("123412341234123412341234")
+		deleted, err := phoneBookMock.DeleteContact(context.Background(), "123412341234123412341234", "")
 		assert.Nil(t, err)
-		assert.Equal(t, deletedCount, nothingDeleted, "Should not delete not existing contact")
+		assert.Equal(t, nothingDeleted, deleted.Matched, "Should not delete not existing contact")
 	})
 }
 
@@ -151,7 +197,7 @@ func TestEditContact(t *testing.T) {
 	mt.Run("should edit existing contact", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contact)
+		_, err := phoneBookMock.AddContact(context.Background(), contact, "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
@@ -160,9 +206,9 @@ func TestEditContact(t *testing.T) {
 			bson.E{Key: "nModified", Value: expectedUpdated},
 		))
 
-		updatedCount, _, err := phoneBookMock.UpdateContact(contact.ID.String()[10:34], &definition.Contact{FirstName: "changed"})
+		updated, err := phoneBookMock.UpdateContact(context.Background(), contact.ID.Hex(), &definition.Contact{FirstName: "changed"}, 0, "")
 		assert.Nil(t, err)
-		assert.Equal(t, expectedUpdated, updatedCount, "Should update exactly one contact")
+		assert.Equal(t, &definition.WriteResult{Matched: expectedUpdated, Modified: expectedUpdated}, updated, "Should update exactly one contact")
 	})
 
 	mt.Run("should not edit wrong format ID", func(mt *mtest.T) {
@@ -172,9 +218,9 @@ func TestEditContact(t *testing.T) {
 			bson.E{Key: "nModified", Value: nothingUpdated},
 		))
 
-		deletedCount, _, err := phoneBookMock.UpdateContact("1234567", &definition.Contact{FirstName: "changed"})
+		updated, err := phoneBookMock.UpdateContact(context.Background(), "1234567", &definition.Contact{FirstName: "changed"}, 0, "")
 		assert.EqualErrorf(t, err, "the provided hex string is not a valid ObjectID", "wrong ID format")
-		assert.Equal(t, -1, int(deletedCount), "got wrong ID format")
+		assert.Nil(t, updated, "got wrong ID format")
 	})
 
 	mt.Run("should not edit not existing contact", func(mt *mtest.T) {
@@ -184,9 +230,73 @@ func TestEditContact(t *testing.T) {
 			bson.E{Key: "nModified", Value: nothingUpdated},
 		))
 
-		updatedCount, _, err := phoneBookMock.UpdateContact("123412341234123412341234", &definition.Contact{FirstName: "changed"})
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 0}}))
+
+		updated, err := phoneBookMock.UpdateContact(context.Background(), "123412341234123412341234", &definition.Contact{FirstName: "changed"}, 0, "")
+		assert.Nil(t, err)
+		assert.Equal(t, nothingUpdated, updated.Matched, "Should not delete not existing contact")
+	})
+
+	mt.Run("should not edit contact modified by another client", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: nothingUpdated},
+			bson.E{Key: "nModified", Value: nothingUpdated},
+		))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 1}}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 0}}))
+
+		updated, err := phoneBookMock.UpdateContact(context.Background(), contact.ID.Hex(), &definition.Contact{FirstName: "changed"}, 3, "")
+		assert.EqualErrorf(t, err, ErrorVersionConflict, "version conflict")
+		assert.Equal(t, Conflict, StatusOf(err))
+		assert.Nil(t, updated)
+	})
+
+	mt.Run("should edit contact with matching version", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: expectedUpdated},
+			bson.E{Key: "nModified", Value: expectedUpdated},
+		))
+
+		updated, err := phoneBookMock.UpdateContact(context.Background(), contact.ID.Hex(), &definition.Contact{FirstName: "changed"}, 1, "")
+		assert.Nil(t, err)
+		assert.Equal(t, expectedUpdated, updated.Modified, "Should update exactly one contact")
+	})
+
+	mt.Run("should not bump the version of contact holding the update", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: nothingUpdated},
+			bson.E{Key: "nModified", Value: nothingUpdated},
+		))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 1}}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 1}}))
+
+		updated, err := phoneBookMock.UpdateContact(context.Background(), contact.ID.Hex(), &definition.Contact{FirstName: contact.FirstName}, 1, "")
 		assert.Nil(t, err)
-		assert.Equal(t, updatedCount, nothingUpdated, "Should not delete not existing contact")
+		assert.Equal(t, &definition.WriteResult{Matched: expectedUpdated, Modified: nothingUpdated}, updated)
+		filter := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document()
+		assert.Equal(t, int64(1), filter.Lookup("version").Int64())
+		unchanged := filter.Lookup("$nor").Array().Index(0).Value().Document()
+		assert.Equal(t, contact.FirstName, unchanged.Lookup("firstName").StringValue())
+	})
+
+	mt.Run("should not edit with invalid fields", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		id := primitive.NewObjectID().Hex()
+
+		_, err := phoneBookMock.UpdateContact(context.Background(), id, &definition.Contact{Phone: "052-abc"}, 0, "")
+		assert.ErrorIs(t, err, ErrInvalidPhone)
+		assert.Equal(t, BadRequest, StatusOf(err))
+
+		_, err = phoneBookMock.UpdateContact(context.Background(), id, &definition.Contact{Email: "dani"}, 0, "")
+		assert.ErrorIs(t, err, ErrInvalidEmail)
 	})
 
 	mt.Run("should not edit without id", func(mt *mtest.T) {
@@ -196,9 +306,68 @@ func TestEditContact(t *testing.T) {
 			bson.E{Key: "nModified", Value: nothingUpdated},
 		))
 
-		updatedCount, _, err := phoneBookMock.UpdateContact("", &definition.Contact{FirstName: "changed"})
+		updated, err := phoneBookMock.UpdateContact(context.Background(), "", &definition.Contact{FirstName: "changed"}, 0, "")
 		assert.EqualErrorf(t, err, ErrorMissingID, "missing ID")
-		assert.Equal(t, updatedCount, nothingUpdated, "Should not delete not existing contact")
+		assert.Nil(t, updated, "Should not update without an id")
+	})
+}
+
+func TestPatchContact(t *testing.T) {
+	const expectedUpdated int64 = 1
+	newName := "changed"
+	invalidPhone := "05abc"
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should patch existing contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: expectedUpdated},
+			bson.E{Key: "nModified", Value: expectedUpdated},
+		))
+
+		patch := definition.ContactPatch{"firstName": &newName, "address": nil}
+		updated, err := phoneBookMock.PatchContact(context.Background(), "123412341234123412341234", patch, 0, "")
+		assert.Nil(t, err)
+		assert.Equal(t, expectedUpdated, updated.Modified, "Should update exactly one contact")
+	})
+
+	mt.Run("should not patch unknown field", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		patch := definition.ContactPatch{"fristName": &newName}
+		_, err := phoneBookMock.PatchContact(context.Background(), "123412341234123412341234", patch, 0, "")
+		assert.ErrorContains(t, err, ErrorUnknownField)
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, "fristName", err.(*definition.Error).Field, "Should name the unknown field")
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+
+	mt.Run("should not clear phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		patch := definition.ContactPatch{"phone": nil}
+		_, err := phoneBookMock.PatchContact(context.Background(), "123412341234123412341234", patch, 0, "")
+		assert.EqualErrorf(t, err, ErrorClearPhone, "Error should be: %v, got: %v", ErrorClearPhone, err)
+	})
+
+	mt.Run("should not patch invalid phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		patch := definition.ContactPatch{"phone": &invalidPhone}
+		_, err := phoneBookMock.PatchContact(context.Background(), "123412341234123412341234", patch, 0, "")
+		assert.EqualErrorf(t, err, ErrorInvalidPhone, "Error should be: %v, got: %v", ErrorInvalidPhone, err)
+	})
+
+	mt.Run("should not patch invalid email", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		invalidEmail := "dani@"
+		patch := definition.ContactPatch{"email": &invalidEmail}
+		_, err := phoneBookMock.PatchContact(context.Background(), "123412341234123412341234", patch, 0, "")
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+	})
+
+	mt.Run("should not patch without fields", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.PatchContact(context.Background(), "123412341234123412341234", definition.ContactPatch{}, 0, "")
+		assert.EqualErrorf(t, err, ErrorEmptyPatch, "Error should be: %v, got: %v", ErrorEmptyPatch, err)
 	})
 }
 
@@ -242,11 +411,11 @@ func TestSearchContact(t *testing.T) {
 	mt.Run("should find one contact by name", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contacts[1])
+		_, err := phoneBookMock.AddContact(context.Background(), contacts[1], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(searchResponse(mt,
 			bson.D{
 				{Key: "ID", Value: contacts[1].ID},
 				{Key: "FirstName", Value: contacts[1].FirstName},
@@ -257,19 +426,19 @@ func TestSearchContact(t *testing.T) {
 		values := url.Values{
 			"firstName": []string{"jojo"},
 		}
-		foundedContacts, _, err := phoneBookMock.SearchContact(values)
+		foundedContacts, _, err := phoneBookMock.SearchContact(context.Background(), values)
 		assert.Nil(t, err)
-		assert.Equal(t, expectedFound, len(foundedContacts), "Should find exactly one contact")
+		assert.Equal(t, expectedFound, len(foundedContacts.Items), "Should find exactly one contact")
 	})
 
 	mt.Run("should find one contact by phone", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contacts[3])
+		_, err := phoneBookMock.AddContact(context.Background(), contacts[3], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(searchResponse(mt,
 			bson.D{
 				{Key: "_id", Value: contacts[3].ID},
 				{Key: "firstName", Value: contacts[3].FirstName},
@@ -280,29 +449,29 @@ func TestSearchContact(t *testing.T) {
 		values := url.Values{
 			"phone": []string{"0525425452"},
 		}
-		foundedContacts, _, err := phoneBookMock.SearchContact(values)
+		foundedContacts, _, err := phoneBookMock.SearchContact(context.Background(), values)
 		assert.Nil(t, err)
-		assert.Equal(t, expectedFound, len(foundedContacts), "Should find exactly one contact")
-		assert.Equal(t, foundedContacts[0].ID, contacts[3].ID)
-		assert.Equal(t, foundedContacts[0].FirstName, contacts[3].FirstName)
-		assert.Equal(t, foundedContacts[0].LastName, contacts[3].LastName)
-		assert.Equal(t, foundedContacts[0].Phone, contacts[3].Phone)
-		assert.Equal(t, foundedContacts[0].Address, contacts[3].Address)
+		assert.Equal(t, expectedFound, len(foundedContacts.Items), "Should find exactly one contact")
+		assert.Equal(t, foundedContacts.Items[0].ID, contacts[3].ID)
+		assert.Equal(t, foundedContacts.Items[0].FirstName, contacts[3].FirstName)
+		assert.Equal(t, foundedContacts.Items[0].LastName, contacts[3].LastName)
+		assert.Equal(t, foundedContacts.Items[0].Phone, contacts[3].Phone)
+		assert.Equal(t, foundedContacts.Items[0].Address, contacts[3].Address)
 	})
 
 	mt.Run("should find multiple contact", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contacts[2])
+		_, err := phoneBookMock.AddContact(context.Background(), contacts[2], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err = phoneBookMock.AddContact(contacts[3])
+		_, err = phoneBookMock.AddContact(context.Background(), contacts[3], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(searchResponse(mt,
 			bson.D{
 				{Key: "_id", Value: contacts[2].ID},
 				{Key: "firstName", Value: contacts[2].FirstName},
@@ -319,29 +488,29 @@ func TestSearchContact(t *testing.T) {
 		values := url.Values{
 			"firstName": []string{"gogo"},
 		}
-		foundedContacts, _, err := phoneBookMock.SearchContact(values)
+		foundedContacts, _, err := phoneBookMock.SearchContact(context.Background(), values)
 		assert.Nil(t, err)
-		assert.Equal(t, 2, len(foundedContacts), "Should find two contact")
-		assert.Equal(t, foundedContacts[0].ID, contacts[2].ID)
-		assert.Equal(t, foundedContacts[0].FirstName, contacts[2].FirstName)
-		assert.Equal(t, foundedContacts[0].LastName, contacts[2].LastName)
-		assert.Equal(t, foundedContacts[0].Phone, contacts[2].Phone)
-		assert.Equal(t, foundedContacts[0].Address, contacts[2].Address)
-		assert.Equal(t, foundedContacts[1].ID, contacts[3].ID)
-		assert.Equal(t, foundedContacts[1].FirstName, contacts[3].FirstName)
-		assert.Equal(t, foundedContacts[1].LastName, contacts[3].LastName)
-		assert.Equal(t, foundedContacts[1].Phone, contacts[3].Phone)
-		assert.Equal(t, foundedContacts[1].Address, contacts[3].Address)
+		assert.Equal(t, 2, len(foundedContacts.Items), "Should find two contact")
+		assert.Equal(t, foundedContacts.Items[0].ID, contacts[2].ID)
+		assert.Equal(t, foundedContacts.Items[0].FirstName, contacts[2].FirstName)
+		assert.Equal(t, foundedContacts.Items[0].LastName, contacts[2].LastName)
+		assert.Equal(t, foundedContacts.Items[0].Phone, contacts[2].Phone)
+		assert.Equal(t, foundedContacts.Items[0].Address, contacts[2].Address)
+		assert.Equal(t, foundedContacts.Items[1].ID, contacts[3].ID)
+		assert.Equal(t, foundedContacts.Items[1].FirstName, contacts[3].FirstName)
+		assert.Equal(t, foundedContacts.Items[1].LastName, contacts[3].LastName)
+		assert.Equal(t, foundedContacts.Items[1].Phone, contacts[3].Phone)
+		assert.Equal(t, foundedContacts.Items[1].Address, contacts[3].Address)
 	})
 
 	mt.Run("should find one contact by phone and address", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contacts[0])
+		_, err := phoneBookMock.AddContact(context.Background(), contacts[0], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(searchResponse(mt,
 			bson.D{
 				{Key: "_id", Value: contacts[0].ID},
 				{Key: "firstName", Value: contacts[0].FirstName},
@@ -353,47 +522,223 @@ func TestSearchContact(t *testing.T) {
 			"phone":   []string{"0545454524"},
 			"address": []string{"Tel Aviv"},
 		}
-		foundedContacts, _, err := phoneBookMock.SearchContact(values)
+		foundedContacts, _, err := phoneBookMock.SearchContact(context.Background(), values)
 		assert.Nil(t, err)
-		assert.Equal(t, expectedFound, len(foundedContacts), "Should find exactly one contact")
-		assert.Equal(t, foundedContacts[0].ID, contacts[0].ID)
-		assert.Equal(t, foundedContacts[0].FirstName, contacts[0].FirstName)
-		assert.Equal(t, foundedContacts[0].LastName, contacts[0].LastName)
-		assert.Equal(t, foundedContacts[0].Phone, contacts[0].Phone)
-		assert.Equal(t, foundedContacts[0].Address, contacts[0].Address)
+		assert.Equal(t, expectedFound, len(foundedContacts.Items), "Should find exactly one contact")
+		assert.Equal(t, foundedContacts.Items[0].ID, contacts[0].ID)
+		assert.Equal(t, foundedContacts.Items[0].FirstName, contacts[0].FirstName)
+		assert.Equal(t, foundedContacts.Items[0].LastName, contacts[0].LastName)
+		assert.Equal(t, foundedContacts.Items[0].Phone, contacts[0].Phone)
+		assert.Equal(t, foundedContacts.Items[0].Address, contacts[0].Address)
 	})
 
 	mt.Run("should not find contact by address and not existing phone", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contacts[0])
+		_, err := phoneBookMock.AddContact(context.Background(), contacts[0], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		mt.AddMockResponses(searchResponse(mt))
 		values := url.Values{
 			"phone":   []string{"0000000000"},
 			"address": []string{"Tel Aviv"},
 		}
-		foundedContacts, _, err := phoneBookMock.SearchContact(values)
+		foundedContacts, _, err := phoneBookMock.SearchContact(context.Background(), values)
+		assert.Nil(t, err)
+		assert.Equal(t, nothingFound, len(foundedContacts.Items), "Should not found contact")
+	})
+
+	mt.Run("should limit results to the page size", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(searchResponse(mt))
+		_, _, err := phoneBookMock.SearchContact(context.Background(), url.Values{"firstName": []string{"gogo"}, "pageSize": []string{"2"}})
+		assert.Nil(t, err)
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		_, hasPageSize := pipeline.Index(0).Value().Document().Lookup("$match", "pageSize").StringValueOK()
+		assert.False(t, hasPageSize, "Should not filter by page size")
+		items := pipeline.Index(2).Value().Document().Lookup("$facet", "items").Array()
+		assert.Equal(t, int64(2), items.Index(1).Value().Document().Lookup("$limit").AsInt64())
+	})
+
+	mt.Run("should default to the page size of the list", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(searchResponse(mt))
+		result, _, err := phoneBookMock.SearchContact(context.Background(), url.Values{"firstName": []string{"gogo"}})
 		assert.Nil(t, err)
-		assert.Equal(t, nothingFound, len(foundedContacts), "Should not found contact")
+		assert.Equal(t, config.Static.LimitPerPage, result.PageSize)
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		items := pipeline.Index(2).Value().Document().Lookup("$facet", "items").Array()
+		assert.Equal(t, config.Static.LimitPerPage, items.Index(1).Value().Document().Lookup("$limit").AsInt64())
+	})
+
+	mt.Run("should return the page and the count in one query", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(searchResponse(mt, contactDocument(contacts[2])))
+		page, _, err := phoneBookMock.SearchContact(context.Background(), url.Values{"firstName": {"gogo"}, "pageSize": {"1"}, "page": {"2"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, 2, page.Page)
+		assert.Equal(t, int64(2), *page.TotalItems)
+		assert.Equal(t, int64(2), *page.TotalPages)
+		facet := mt.GetStartedEvent().Command.Lookup("pipeline").Array().Index(2).Value().Document().Lookup("$facet").Document()
+		assert.Equal(t, int64(1), facet.Lookup("items").Array().Index(0).Value().Document().Lookup("$skip").AsInt64())
+		assert.Equal(t, "count", facet.Lookup("totalCount").Array().Index(0).Value().Document().Lookup("$count").StringValue())
+	})
+
+	mt.Run("should skip counting when asked", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(searchResponse(mt))
+		page, _, err := phoneBookMock.SearchContact(context.Background(), url.Values{"firstName": {"gogo"}, "count": {"false"}})
+		assert.Nil(t, err)
+		assert.Nil(t, page.TotalItems)
+		assert.Empty(t, page.Items)
+		_, counted := mt.GetStartedEvent().Command.Lookup("pipeline").Array().Index(2).Value().Document().Lookup("$facet").Document().LookupErr("totalCount")
+		assert.NotNil(t, counted)
+	})
+
+	mt.Run("should not search by unknown field", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.SearchContact(context.Background(), url.Values{"$where": []string{"sleep(1000)"}})
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result)
+	})
+
+	mt.Run("should not search by empty value", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.SearchContact(context.Background(), url.Values{"firstName": []string{"  "}})
+		assert.ErrorIs(t, err, ErrInvalidSearchValue)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result)
 	})
 
 	mt.Run("should not found not existing contact", func(mt *mtest.T) {
 		phoneBookMock := NewMongoPhoneBook(mt.Client)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
-		_, _, err := phoneBookMock.AddContact(contacts[0])
+		_, err := phoneBookMock.AddContact(context.Background(), contacts[0], "")
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		mt.AddMockResponses(searchResponse(mt))
 		values := url.Values{
 			"firstName": []string{"baba"},
 		}
-		foundedContacts, _, err := phoneBookMock.SearchContact(values)
+		foundedContacts, _, err := phoneBookMock.SearchContact(context.Background(), values)
 		assert.Nil(t, err)
-		assert.Equal(t, nothingFound, len(foundedContacts), "Should not found contact")
+		assert.Equal(t, nothingFound, len(foundedContacts.Items), "Should not found contact")
+	})
+}
+
+// searchResponse is the single document a search aggregation returns, the
+// contacts of the page and a count of two matches when there are any.
+func searchResponse(mt *mtest.T, contacts ...bson.D) bson.D {
+	items := bson.A{}
+	for _, contact := range contacts {
+		items = append(items, contact)
+	}
+	result := bson.D{{Key: "items", Value: items}}
+	if len(contacts) > 0 {
+		result = append(result, bson.E{Key: "totalCount", Value: bson.A{bson.D{{Key: "count", Value: int64(2)}}}})
+	}
+	return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, result)
+}
+
+func TestCountContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should count the matching contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "n", Value: int64(1284)}}))
+		count, _, err := phoneBookMock.CountContacts(context.Background(), url.Values{"address": {"Tel Aviv"}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1284), count)
+		match := mt.GetStartedEvent().Command.Lookup("pipeline").Array().Index(0).Value().Document()
+		assert.Equal(t, "Tel Aviv", match.Lookup("$match", "address").StringValue())
+	})
+
+	mt.Run("should count nothing when nothing matches", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		count, _, err := phoneBookMock.CountContacts(context.Background(), url.Values{"company": {"Acme"}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	mt.Run("should reject unknown fields", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.CountContacts(context.Background(), url.Values{"city": {"Tel Aviv"}})
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.Equal(t, BadRequest, status)
+	})
+}
+
+func TestGetContactByID(t *testing.T) {
+	contact := &definition.Contact{
+		ID:        primitive.NewObjectID(),
+		FirstName: "dani",
+		Phone:     "0521212126",
+	}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should return existing contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			append(contactDocument(contact), bson.E{Key: "version", Value: int64(3)})))
+		result, _, err := phoneBookMock.GetContact(context.Background(), contact.ID.Hex())
+		assert.Nil(t, err)
+		assert.Equal(t, contact.ID, result.ID)
+		assert.Equal(t, int64(3), result.Version)
+	})
+
+	mt.Run("should not return not existing contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		result, status, err := phoneBookMock.GetContact(context.Background(), contact.ID.Hex())
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, status)
+		assert.Nil(t, result)
+	})
+
+	mt.Run("should not return contact with invalid id", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.GetContact(context.Background(), "123")
+		assert.ErrorIs(t, err, ErrInvalidID)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result)
+	})
+}
+
+func TestSearchContactText(t *testing.T) {
+	contact := &definition.Contact{
+		ID:        primitive.NewObjectID(),
+		FirstName: "dani",
+		LastName:  "cohen",
+		Phone:     "0521212126",
+		Address:   "Tel Aviv",
+	}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should search text ranked by score", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			append(contactDocument(contact), bson.E{Key: "score", Value: 2.5})))
+		result, _, err := phoneBookMock.SearchContactText(context.Background(), url.Values{"q": []string{"dani cohen tel aviv"}})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(result))
+		assert.Equal(t, contact.ID, result[0].ID)
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, "dani cohen tel aviv", command.Lookup("filter", "$text", "$search").StringValue())
+		assert.Equal(t, "textScore", command.Lookup("sort", "score", "$meta").StringValue())
+	})
+
+	mt.Run("should not search without text", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.SearchContactText(context.Background(), url.Values{"q": []string{""}})
+		assert.ErrorIs(t, err, ErrMissingSearchText)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result)
 	})
 }
 
@@ -498,7 +843,8 @@ func TestGetContact(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(countResponse(mt, int32(len(contacts))))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
 			bson.D{
 				{Key: "_id", Value: contacts[0].ID},
 				{Key: "firstName", Value: contacts[0].FirstName},
@@ -570,9 +916,12 @@ func TestGetContact(t *testing.T) {
 				{Key: "address", Value: contacts[9].Address},
 			},
 		))
-		result, _, err := phoneBookMock.GetContactWithPagination([]string{"1"})
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"page": []string{"1"}})
 		assert.Nil(t, err)
-		assert.Equal(t, config.Static.LimitPerPage, int64(len(result)), "Should returns 10 contacts")
+		assert.Equal(t, config.Static.LimitPerPage, int64(len(result.Items)), "Should returns 10 contacts")
+		assert.Equal(t, 1, result.Page)
+		assert.Equal(t, int64(12), *result.TotalItems)
+		assert.Equal(t, int64(2), *result.TotalPages)
 	})
 
 	mt.Run("should return 2 contacts from the last page", func(mt *mtest.T) {
@@ -582,7 +931,8 @@ func TestGetContact(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(countResponse(mt, int32(len(contacts))))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
 			bson.D{
 				{Key: "_id", Value: contacts[10].ID},
 				{Key: "firstName", Value: contacts[10].FirstName},
@@ -598,9 +948,9 @@ func TestGetContact(t *testing.T) {
 				{Key: "address", Value: contacts[11].Address},
 			},
 		))
-		result, _, err := phoneBookMock.GetContactWithPagination([]string{"2"})
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"page": []string{"2"}})
 		assert.Nil(t, err)
-		assert.Equal(t, 2, len(result), "Should returns 2 contacts")
+		assert.Equal(t, 2, len(result.Items), "Should returns 2 contacts")
 	})
 
 	mt.Run("should return 10 first contacts when mention an empty page", func(mt *mtest.T) {
@@ -610,7 +960,8 @@ func TestGetContact(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		mt.AddMockResponses(countResponse(mt, int32(len(contacts))))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
 			bson.D{
 				{Key: "_id", Value: contacts[0].ID},
 				{Key: "firstName", Value: contacts[0].FirstName},
@@ -682,9 +1033,9 @@ func TestGetContact(t *testing.T) {
 				{Key: "address", Value: contacts[9].Address},
 			},
 		))
-		result, _, err := phoneBookMock.GetContactWithPagination([]string{""})
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"page": []string{""}})
 		assert.Nil(t, err)
-		assert.Equal(t, config.Static.LimitPerPage, int64(len(result)), "Should returns 10 contacts")
+		assert.Equal(t, config.Static.LimitPerPage, int64(len(result.Items)), "Should returns 10 contacts")
 	})
 
 	mt.Run("should not return contacts from non existing page", func(mt *mtest.T) {
@@ -694,10 +1045,178 @@ func TestGetContact(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
-		result, _, err := phoneBookMock.GetContactWithPagination([]string{"4"})
+		mt.AddMockResponses(countResponse(mt, int32(len(contacts))))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"page": []string{"4"}})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(result.Items), "Should not return contacts")
+	})
+
+	mt.Run("should return recently updated contacts first", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: contacts[0].ID},
+				{Key: "firstName", Value: contacts[0].FirstName},
+				{Key: "phone", Value: contacts[0].Phone},
+			}))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"sort": []string{"updatedAt"}, "count": []string{"false"}})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(result.Items))
+		assert.Nil(t, result.TotalItems, "Should skip counting")
+		started := mt.GetStartedEvent()
+		assert.Equal(t, "find", started.CommandName)
+		sort := started.Command.Lookup("sort").Document()
+		assert.Equal(t, int32(-1), sort.Lookup("updatedAt").Int32())
+	})
+
+	mt.Run("should not return contacts with invalid sort", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"sort": []string{"phone"}})
+		assert.EqualErrorf(t, err, ErrorInvalidSort, "invalid sort")
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result, "Should not return contacts")
+	})
+
+	mt.Run("should return first page with next cursor", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		var documents []bson.D
+		for _, contact := range contacts[:11] {
+			documents = append(documents, contactDocument(contact))
+		}
+		mt.AddMockResponses(countResponse(mt, int32(len(contacts))))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, documents...))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"cursor": []string{""}})
 		assert.Nil(t, err)
-		assert.Equal(t, 0, len(result), "Should not return contacts")
+		assert.Equal(t, config.Static.LimitPerPage, int64(len(result.Items)), "Should returns 10 contacts")
+		assert.Equal(t, encodeCursor(contacts[9].ID), result.NextCursor, "Should point after the last returned contact")
+	})
+
+	mt.Run("should return last page by cursor", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(countResponse(mt, int32(len(contacts))))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			contactDocument(contacts[10]), contactDocument(contacts[11])))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"cursor": []string{encodeCursor(contacts[9].ID)}})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(result.Items), "Should returns 2 contacts")
+		assert.Empty(t, result.NextCursor, "Should not point to another page")
+		assert.Equal(t, "aggregate", mt.GetStartedEvent().CommandName, "Should count the contacts first")
+		filter := mt.GetStartedEvent().Command.Lookup("filter", "_id", "$gt").ObjectID()
+		assert.Equal(t, contacts[9].ID, filter)
+	})
+
+	mt.Run("should not return contacts from invalid cursor", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"cursor": []string{"not-a-cursor"}})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result, "Should not return contacts")
+	})
+
+	mt.Run("should not return contacts when the request is canceled", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, status, err := phoneBookMock.GetContactWithPagination(ctx, url.Values{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, InternalServerError, status)
+		assert.Nil(t, result, "Should not return contacts")
+	})
+
+	mt.Run("should not return contacts when the cursor fails midway", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		namespace := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, namespace, mtest.FirstBatch, contactDocument(contacts[0])),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 43, Name: "CursorNotFound", Message: "cursor not found"}))
+		result, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"count": []string{"false"}})
+		assert.NotNil(t, err)
+		assert.Equal(t, InternalServerError, status)
+		assert.Nil(t, result, "Should not return a partial page")
+	})
+
+	mt.Run("should clamp page size to the maximum", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			contactDocument(contacts[0])))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"pageSize": []string{"5000"}, "count": []string{"false"}})
+		assert.Nil(t, err)
+		assert.Equal(t, config.Static.MaxPageSize, result.PageSize)
+		limit := mt.GetStartedEvent().Command.Lookup("limit").AsInt64()
+		assert.Equal(t, config.Static.MaxPageSize, limit)
+	})
+
+	mt.Run("should clamp page size to the maximum of each phone book", func(mt *mtest.T) {
+		small := config.Default()
+		small.MaxPageSize = 20
+		smallPhoneBook := NewMongoPhoneBook(mt.Client)
+		smallPhoneBook.SetTunables(small.Tunables)
+		defaultPhoneBook := NewMongoPhoneBook(mt.Client)
+		query := url.Values{"pageSize": []string{"5000"}, "count": []string{"false"}}
+		for _, phoneBook := range []*MongoPhoneBook{smallPhoneBook, defaultPhoneBook} {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+				contactDocument(contacts[0])))
+			_, _, err := phoneBook.GetContactWithPagination(context.Background(), query)
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, int64(20), mt.GetStartedEvent().Command.Lookup("limit").AsInt64())
+		assert.Equal(t, config.Static.MaxPageSize, mt.GetStartedEvent().Command.Lookup("limit").AsInt64())
+	})
+
+	mt.Run("should not return contacts with invalid page size", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"pageSize": []string{"0"}})
+		assert.ErrorIs(t, err, ErrInvalidPageSize)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result, "Should not return contacts")
+	})
+
+	mt.Run("should project requested fields", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: contacts[0].ID}, {Key: "firstName", Value: contacts[0].FirstName}, {Key: "phone", Value: contacts[0].Phone}}))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"fields": []string{"firstName, phone"}, "count": []string{"false"}})
+		assert.Nil(t, err)
+		assert.Equal(t, contacts[0].Phone, result.Items[0].Phone)
+		assert.Empty(t, result.Items[0].Address)
+		projection := mt.GetStartedEvent().Command.Lookup("projection").Document()
+		assert.Equal(t, int32(1), projection.Lookup("firstName").Int32())
+		assert.Equal(t, int32(1), projection.Lookup("phone").Int32())
+		assert.Equal(t, int32(1), projection.Lookup("_id").Int32())
+		_, hasAddress := projection.Lookup("address").Int32OK()
+		assert.False(t, hasAddress, "Should not project address")
+	})
+
+	mt.Run("should project only the ids when asked for the id alone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: contacts[0].ID}}))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"fields": []string{"_id"}, "count": []string{"false"}})
+		assert.Nil(t, err)
+		assert.Equal(t, contacts[0].ID, result.Items[0].ID)
+		projection := mt.GetStartedEvent().Command.Lookup("projection").Document()
+		elements, _ := projection.Elements()
+		assert.Len(t, elements, 1, "Should not send an empty projection")
+		assert.Equal(t, int32(1), projection.Lookup("_id").Int32())
+	})
+
+	mt.Run("should not return contacts with unknown fields", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"fields": []string{"firstName,nickname"}})
+		assert.ErrorIs(t, err, ErrUnknownField)
+		assert.ErrorContains(t, err, "nickname")
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result, "Should not return contacts")
+	})
+
+	mt.Run("should not return contacts with invalid count", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		result, status, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"count": []string{"maybe"}})
+		assert.ErrorIs(t, err, ErrInvalidCount)
+		assert.Equal(t, BadRequest, status)
+		assert.Nil(t, result, "Should not return contacts")
 	})
 
 	mt.Run("should not return contacts from invalid page", func(mt *mtest.T) {
@@ -707,9 +1226,75 @@ func TestGetContact(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Error inserting document: %v", err)
 		}
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
-		result, _, err := phoneBookMock.GetContactWithPagination([]string{"a"})
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"page": []string{"a"}})
 		assert.NotNil(t, err)
-		assert.Equal(t, 0, len(result), "Should not return contacts")
+		assert.Nil(t, result, "Should not return contacts")
+	})
+}
+
+func countResponse(mt *mtest.T, n int32) bson.D {
+	return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+		bson.D{{Key: "n", Value: n}})
+}
+
+func TestRestoreContact(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	deleted := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Dani", Phone: "0545454524", Version: 2}
+
+	mt.Run("should name the contact holding the phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		existingID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000,
+			Message: "E11000 duplicate key error collection: test.contacts index: unique_phone dup key: { phone: \"0545454524\" }"}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: existingID}, {Key: "firstName", Value: "other"}, {Key: "phone", Value: deleted.Phone}}))
+		restored, err := phoneBookMock.restoreContact(context.Background(), deleted, "")
+		assert.ErrorIs(t, err, ErrDuplicateContact)
+		assert.ErrorContains(t, err, existingID.Hex(), "Should name the existing contact")
+		assert.Equal(t, Conflict, StatusOf(err))
+		assert.Nil(t, restored)
+	})
+
+	mt.Run("should not blame the phone for a contact restored meanwhile", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000,
+			Message: "E11000 duplicate key error collection: test.contacts index: _id_ dup key: { _id: ObjectId('" + deleted.ID.Hex() + "') }"}))
+		restored, err := phoneBookMock.restoreContact(context.Background(), deleted, "")
+		assert.ErrorIs(t, err, ErrContactExists)
+		assert.Equal(t, Conflict, StatusOf(err))
+		assert.Nil(t, restored)
+		assert.Equal(t, "insert", mt.GetStartedEvent().CommandName)
+		assert.Nil(t, mt.GetStartedEvent(), "Should not look for a contact with the phone")
+	})
+}
+
+func contactDocument(contact *definition.Contact) bson.D {
+	return bson.D{
+		{Key: "_id", Value: contact.ID},
+		{Key: "firstName", Value: contact.FirstName},
+		{Key: "lastName", Value: contact.LastName},
+		{Key: "phone", Value: contact.Phone},
+		{Key: "address", Value: contact.Address},
+	}
+}
+
+func TestMongoDatabaseOptions(t *testing.T) {
+	config.Static.MongoReadPreference = "secondaryPreferred"
+	config.Static.MongoWriteConcern = "majority"
+	defer func() {
+		config.Static.MongoReadPreference = ""
+		config.Static.MongoWriteConcern = ""
+	}()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should apply the configured read preference and write concern", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		assert.Equal(t, "secondaryPreferred", phoneBookMock.contactsCollection.Database().ReadPreference().Mode().String())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := phoneBookMock.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "")
+		assert.Nil(t, err)
+		writeConcern := mt.GetStartedEvent().Command.Lookup("writeConcern").Document()
+		assert.Equal(t, "majority", writeConcern.Lookup("w").StringValue())
 	})
 }
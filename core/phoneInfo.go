@@ -0,0 +1,85 @@
+package core
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"phoneBook/definition"
+	"phoneBook/phonenumber"
+)
+
+// phoneInfoFields are the contact fields derived from the phone number
+// metadata.
+var phoneInfoFields = []string{"phoneCountry", "phoneType", "phoneArea"}
+
+// setPhoneInfo sets the region, type and area of the phone of contact, or
+// clears them when the phone isn't a number of a known region.
+func setPhoneInfo(contact *definition.Contact) {
+	contact.PhoneCountry, contact.PhoneType, contact.PhoneArea = "", "", ""
+	if number, ok := phonenumber.Parse(normalizePhone(contact.Phone)); ok {
+		contact.PhoneCountry, contact.PhoneType, contact.PhoneArea = number.Region, number.Type, number.Area
+	}
+}
+
+// phoneInfo returns the phone information fields of contact by name.
+func phoneInfo(contact *definition.Contact) map[string]string {
+	return map[string]string{
+		"phoneCountry": contact.PhoneCountry,
+		"phoneType":    contact.PhoneType,
+		"phoneArea":    contact.PhoneArea,
+	}
+}
+
+// unsetMissingPhoneInfo unsets in update the phone information fields that
+// contact, written with $set, is without. A phone changed to a number of an
+// unknown region, or without an area, doesn't keep the information of the
+// number before. Contacts without a phone leave it unchanged, and keep it.
+func unsetMissingPhoneInfo(update bson.M, contact *definition.Contact) {
+	if contact.Phone == "" {
+		return
+	}
+	unset := bson.M{}
+	for field, value := range phoneInfo(contact) {
+		if value == "" {
+			unset[field] = ""
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+}
+
+// patchPhoneInfo adds to the $set and $unset of a patch the phone
+// information of phone, the patched phone.
+func patchPhoneInfo(phone string, set, unset bson.M) {
+	contact := &definition.Contact{Phone: phone}
+	setPhoneInfo(contact)
+	for field, value := range phoneInfo(contact) {
+		if value == "" {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+}
+
+// isPhoneInfoField reports whether field is one of the phone information
+// fields.
+func isPhoneInfoField(field string) bool {
+	for _, name := range phoneInfoFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPhoneInfo reports whether the phone information of contact is the
+// one searched by terms, matched exactly like the database does. It is kept
+// out of the embedded full-text index, so q doesn't match it.
+func matchesPhoneInfo(contact *definition.Contact, terms map[string]string) bool {
+	for field, value := range phoneInfo(contact) {
+		if searched, ok := terms[field]; ok && searched != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestSetPhoneInfo(t *testing.T) {
+	contact := &definition.Contact{Phone: "02-123-4567"}
+	setPhoneInfo(contact)
+	assert.Equal(t, "IL", contact.PhoneCountry)
+	assert.Equal(t, "fixed", contact.PhoneType)
+	assert.Equal(t, "Jerusalem", contact.PhoneArea)
+
+	contact.Phone = "+44 7400 123456"
+	setPhoneInfo(contact)
+	assert.Equal(t, "GB", contact.PhoneCountry)
+	assert.Equal(t, "mobile", contact.PhoneType)
+	assert.Empty(t, contact.PhoneArea, "the area of the number before should be cleared")
+
+	contact.Phone = "120"
+	setPhoneInfo(contact)
+	assert.Empty(t, contact.PhoneCountry)
+	assert.Empty(t, contact.PhoneType)
+}
+
+func TestUnsetMissingPhoneInfo(t *testing.T) {
+	contact := &definition.Contact{Phone: "0521234567"}
+	setPhoneInfo(contact)
+	update := bson.M{"$set": contact}
+	unsetMissingPhoneInfo(update, contact)
+	assert.Equal(t, bson.M{"phoneArea": ""}, update["$unset"])
+
+	update = bson.M{"$set": &definition.Contact{FirstName: "Dani"}}
+	unsetMissingPhoneInfo(update, &definition.Contact{FirstName: "Dani"})
+	assert.NotContains(t, update, "$unset", "an update leaving the phone should keep its information")
+}
+
+func TestPatchPhoneInfo(t *testing.T) {
+	phone := "039876543"
+	update, err := buildPatchUpdate(definition.ContactPatch{"phone": &phone})
+	assert.Nil(t, err)
+	set := update["$set"].(bson.M)
+	assert.Equal(t, "IL", set["phoneCountry"])
+	assert.Equal(t, "fixed", set["phoneType"])
+	assert.Equal(t, "Tel Aviv", set["phoneArea"])
+
+	phone = "999"
+	update, err = buildPatchUpdate(definition.ContactPatch{"phone": &phone})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"phoneCountry": "", "phoneType": "", "phoneArea": ""}, update["$unset"])
+}
+
+func TestMatchesPhoneInfo(t *testing.T) {
+	contact := &definition.Contact{Phone: "0521234567"}
+	setPhoneInfo(contact)
+	assert.True(t, matchesPhoneInfo(contact, map[string]string{"phoneCountry": "IL", "firstName": "Dani"}))
+	assert.True(t, matchesPhoneInfo(contact, map[string]string{"phoneType": "mobile"}))
+	assert.False(t, matchesPhoneInfo(contact, map[string]string{"phoneCountry": "US"}))
+	assert.False(t, matchesPhoneInfo(contact, map[string]string{"phoneArea": "Jerusalem"}))
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/phonenumber"
+	"regexp"
+	"strings"
+)
+
+const phoneIndexName = "contacts_normalized_phone"
+
+// formattedPhoneRegex matches a phone number as dialed or displayed, e.g.
+// +972 (52) 123-4567.
+var formattedPhoneRegex = regexp.MustCompile(`^\+?[0-9 ().-]+$`)
+
+// normalizePhone strips the formatting of number and prefixes national
+// numbers, starting with the 0 trunk prefix, with PHONE_COUNTRY_CODE. So
+// +972 52-123-4567, 00972521234567 and 0521234567 all normalize to
+// 972521234567. Other numbers are kept as they are, as they either carry
+// their country code already or are internal extensions.
+func normalizePhone(number string) string {
+	number = strings.TrimSpace(number)
+	international := strings.HasPrefix(number, "+")
+	digits := phoneDigits(number)
+	if !international && strings.HasPrefix(digits, "00") {
+		return digits[2:]
+	}
+	countryCode := config.Static.PhoneCountryCode
+	if !international && countryCode != "" && strings.HasPrefix(digits, "0") {
+		return countryCode + digits[1:]
+	}
+	return digits
+}
+
+// FormatPhone returns the E.164 form of phone, normalized like for the phone
+// lookup, and its display form in region. Both are empty when phone isn't a
+// valid number of a region the formatting knows.
+func FormatPhone(phone, region string) (e164, formatted string) {
+	number, ok := phonenumber.Parse(normalizePhone(phone))
+	if !ok {
+		return "", ""
+	}
+	return number.E164(), number.Format(region)
+}
+
+// phoneDigits strips the formatting of number, keeping its digits.
+func phoneDigits(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+}
+
+// ensurePhoneIndex creates the index reverse phone lookups run against.
+func (pb *MongoPhoneBook) ensurePhoneIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "normalizedPhone", Value: 1}},
+		Options: options.Index().SetName(phoneIndexName),
+	}
+	_, err := pb.contactsCollection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// GetContactsByPhone returns the contacts whose phone is number once both are
+// normalized, for caller ID lookups. NotFound is returned when none matches.
+func (pb *MongoPhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(number) > config.Static.MaxSizeProperty || !formattedPhoneRegex.MatchString(number) {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	normalized := normalizePhone(number)
+	if normalized == "" {
+		return nil, BadRequest, ErrInvalidLookup
+	}
+	findOptions := options.Find().SetLimit(pb.tunables().MaxPageSize)
+	cursor, err := pb.contactsCollection.Find(ctx, bson.M{"normalizedPhone": pb.cipher.phoneIndex(normalized)}, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	var contacts []*definition.Contact
+	if err := cursor.All(ctx, &contacts); err != nil {
+		return nil, InternalServerError, err
+	}
+	if len(contacts) == 0 {
+		return nil, NotFound, ErrContactNotFound
+	}
+	return contacts, "", nil
+}
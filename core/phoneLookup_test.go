@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	for number, normalized := range map[string]string{
+		"+972 52-123-4567":  "972521234567",
+		"00972521234567":    "972521234567",
+		"052.123.4567":      "972521234567",
+		"972521234567":      "972521234567",
+		"+1 (212) 555-0100": "12125550100",
+		"1234":              "1234",
+	} {
+		assert.Equal(t, normalized, normalizePhone(number), number)
+	}
+
+	config.Static.PhoneCountryCode = ""
+	defer func() { config.Static.PhoneCountryCode = config.Default().PhoneCountryCode }()
+	assert.Equal(t, "0521234567", normalizePhone("052-1234567"))
+}
+
+func TestGetContactsByPhone(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should find the contacts by normalized phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Dani"}, {Key: "phone", Value: "0521234567"}}))
+		contacts, _, err := phoneBookMock.GetContactsByPhone(context.Background(), "+972 (52) 123-4567")
+		assert.Nil(t, err)
+		assert.Len(t, contacts, 1)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "972521234567", filter.Lookup("normalizedPhone").StringValue())
+	})
+
+	mt.Run("should not find an unknown phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		_, status, err := phoneBookMock.GetContactsByPhone(context.Background(), "0500000000")
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, status)
+	})
+
+	mt.Run("should reject an invalid phone", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for _, number := range []string{"call-me", "+", "--"} {
+			_, status, err := phoneBookMock.GetContactsByPhone(context.Background(), number)
+			assert.ErrorIs(t, err, ErrInvalidLookup, number)
+			assert.Equal(t, BadRequest, status)
+		}
+	})
+
+	mt.Run("should store the normalized phone of added contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := phoneBookMock.AddContact(context.Background(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "tester")
+		assert.Nil(t, err)
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "972521234567", inserted.Lookup("normalizedPhone").StringValue())
+	})
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+)
+
+// projectableFields are the contact fields the fields parameter can select.
+var projectableFields = map[string]bool{
+	"firstName":   true,
+	"lastName":    true,
+	"displayName": true,
+	"phone":       true,
+	"email":       true,
+	"company":     true,
+	"jobTitle":    true,
+	"address":     true,
+	"notes":       true,
+	"version":     true,
+	"createdAt":   true,
+	"updatedAt":   true,
+	"archived":    true,
+	"sortWeight":  true,
+	"blocked":     true,
+	// the phone information
+	"phoneCountry": true,
+	"phoneType":    true,
+	"phoneArea":    true,
+}
+
+// validateFieldsParam returns the projection selecting the comma separated
+// contact fields, or nil when no fields were requested. The id is always
+// selected, so asking for the id alone returns just the ids rather than an
+// empty projection, which MongoDB reads as every field.
+func validateFieldsParam(fieldsParam []string) (bson.M, error) {
+	if len(fieldsParam) == 0 || fieldsParam[0] == "" {
+		return nil, nil
+	}
+	projection := bson.M{"_id": 1}
+	for _, field := range strings.Split(fieldsParam[0], ",") {
+		field = strings.TrimSpace(field)
+		if field == "_id" || field == "id" {
+			continue
+		}
+		if !projectableFields[field] {
+			return nil, ErrUnknownField.WithField("fields").WithMessage(fmt.Sprintf("%s: %s", ErrorUnknownField, field))
+		}
+		projection[field] = 1
+	}
+	return projection, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"phoneBook/definition"
+)
+
+// recentFields are the timestamps the recent contacts are listed by, keyed
+// by the by parameter.
+var recentFields = map[string]string{
+	"created": "createdAt",
+	"updated": "updatedAt",
+}
+
+// ensureRecentIndexes creates the indexes listing the most recently added
+// and updated contacts first, which also back the createdAt and updatedAt
+// sorts.
+func (pb *MongoPhoneBook) ensureRecentIndexes(ctx context.Context) error {
+	var models []mongo.IndexModel
+	for _, field := range []string{"createdAt", "updatedAt"} {
+		models = append(models, mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("contacts_recent_" + field),
+		})
+	}
+	_, err := pb.contactsCollection.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// GetRecentContacts returns the limit most recently added contacts, or the
+// most recently updated ones with by=updated, most recent first. limit
+// defaults to the page size and is clamped to MaxPageSize. Contacts stored
+// before their timestamps existed come last.
+func (pb *MongoPhoneBook) GetRecentContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	by := query.Get("by")
+	if by == "" {
+		by = "created"
+	}
+	field, ok := recentFields[by]
+	if !ok {
+		return nil, BadRequest, ErrInvalidRecentBy
+	}
+	limit, err := validatePageSizeParam(query["limit"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidLimit
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	filter := bson.M{}
+	if err := excludeArchived(filter, query); err != nil {
+		return nil, BadRequest, err
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: field, Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(limit)
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	if contacts == nil {
+		contacts = []*definition.Contact{}
+	}
+	return contacts, "", nil
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"testing"
+)
+
+func TestGetRecentContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should list the recently updated contacts first", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Dani"}}))
+		contacts, _, err := phoneBookMock.GetRecentContacts(context.Background(), url.Values{"by": {"updated"}, "limit": {"5"}})
+		assert.Nil(t, err)
+		assert.Len(t, contacts, 1)
+		command := mt.GetStartedEvent().Command
+		sort := command.Lookup("sort").Document()
+		assert.Equal(t, "updatedAt", sort.Index(0).Key())
+		assert.Equal(t, int32(-1), sort.Index(0).Value().Int32())
+		assert.Equal(t, int64(5), command.Lookup("limit").AsInt64())
+	})
+
+	mt.Run("should reject invalid parameters", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.GetRecentContacts(context.Background(), url.Values{"by": {"deleted"}})
+		assert.ErrorIs(t, err, ErrInvalidRecentBy)
+		assert.Equal(t, BadRequest, status)
+		_, status, err = phoneBookMock.GetRecentContacts(context.Background(), url.Values{"limit": {"0"}})
+		assert.ErrorIs(t, err, ErrInvalidLimit)
+		assert.Equal(t, BadRequest, status)
+	})
+}
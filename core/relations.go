@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"phoneBook/definition"
+)
+
+// relationTypes are the types contacts can be linked by.
+var relationTypes = map[string]bool{
+	definition.RelationSpouse:    true,
+	definition.RelationAssistant: true,
+	definition.RelationManager:   true,
+	definition.RelationColleague: true,
+}
+
+// LinkContact links the contact with id to the contact of relation, replacing
+// the type of an existing link between them. Links are one way, e.g. a
+// manager link tells who the manager of the contact with id is.
+func (pb *MongoPhoneBook) LinkContact(ctx context.Context, idParam string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	if !relationTypes[relation.Type] {
+		return nil, ErrInvalidRelation
+	}
+	if relation.ContactID.IsZero() {
+		return nil, ErrInvalidID.WithField("contactId")
+	}
+	if relation.ContactID == id {
+		return nil, ErrSelfRelation
+	}
+	contact, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, ErrContactNotFound
+	}
+	related, err := pb.contactsCollection.CountDocuments(ctx, bson.M{"_id": relation.ContactID})
+	if err != nil {
+		return nil, err
+	}
+	if related == 0 {
+		return nil, ErrContactNotFound.WithField("contactId")
+	}
+	relations := []definition.Relation{*relation}
+	for _, existing := range contact.Relations {
+		if existing.ContactID != relation.ContactID {
+			relations = append(relations, existing)
+		}
+	}
+	// the version read guards the relations against concurrent links
+	update := bson.M{"$set": bson.M{"relations": relations}}
+	return pb.updateVersioned(ctx, id, update, contact.Version, false, actor)
+}
+
+// UnlinkContact removes the link of the contact with id to the contact with
+// relatedID.
+func (pb *MongoPhoneBook) UnlinkContact(ctx context.Context, idParam string, relatedIDParam string, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	relatedID, err := primitive.ObjectIDFromHex(relatedIDParam)
+	if err != nil {
+		return nil, ErrInvalidID.WithField("contactId")
+	}
+	update := bson.M{"$pull": bson.M{"relations": bson.M{"contactId": relatedID}}}
+	result, err := pb.updateVersioned(ctx, id, update, 0, false, actor)
+	if err != nil {
+		return nil, err
+	}
+	if result.Modified > 0 {
+		return result, nil
+	}
+	contact, err := pb.findContactByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, ErrContactNotFound
+	}
+	return nil, ErrRelationNotFound
+}
+
+// GetRelatedContacts returns the contacts the contact with id is linked to,
+// in the order they were linked, most recent first. Links to contacts
+// deleted since are skipped, and come back if they are restored.
+func (pb *MongoPhoneBook) GetRelatedContacts(ctx context.Context, idParam string) ([]*definition.RelatedContact, string, error) {
+	contact, status, err := pb.GetContact(ctx, idParam)
+	if err != nil {
+		return nil, status, err
+	}
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	related := []*definition.RelatedContact{}
+	if len(contact.Relations) == 0 {
+		return related, "", nil
+	}
+	ids := make([]primitive.ObjectID, len(contact.Relations))
+	for i, relation := range contact.Relations {
+		ids[i] = relation.ContactID
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	byID := make(map[primitive.ObjectID]*definition.Contact, len(contacts))
+	for _, c := range contacts {
+		byID[c.ID] = c
+	}
+	for _, relation := range contact.Relations {
+		if c, ok := byID[relation.ContactID]; ok {
+			related = append(related, &definition.RelatedContact{Type: relation.Type, Contact: c})
+		}
+	}
+	return related, "", nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestLinkContact(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+	managerID := primitive.NewObjectID()
+
+	mt.Run("should link the contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "firstName", Value: "Dani"}, {Key: "version", Value: int64(3)}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+		result, err := phoneBookMock.LinkContact(context.Background(), id.Hex(), &definition.Relation{ContactID: managerID, Type: definition.RelationManager}, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{Matched: 1, Modified: 1}, result)
+	})
+
+	mt.Run("should reject invalid links", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.LinkContact(context.Background(), id.Hex(), &definition.Relation{ContactID: managerID, Type: "friend"}, "tester")
+		assert.ErrorIs(t, err, ErrInvalidRelation)
+		assert.Equal(t, BadRequest, StatusOf(err))
+
+		_, err = phoneBookMock.LinkContact(context.Background(), id.Hex(), &definition.Relation{ContactID: id, Type: definition.RelationSpouse}, "tester")
+		assert.ErrorIs(t, err, ErrSelfRelation)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+
+	mt.Run("should not link to an unknown contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "firstName", Value: "Dani"}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(0)}}),
+		)
+		_, err := phoneBookMock.LinkContact(context.Background(), id.Hex(), &definition.Relation{ContactID: managerID, Type: definition.RelationManager}, "tester")
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, StatusOf(err))
+	})
+}
+
+func TestGetRelatedContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should resolve the linked contacts in link order", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		id, spouseID, managerID, deletedID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "firstName", Value: "Dani"}, {Key: "relations", Value: bson.A{
+				bson.D{{Key: "contactId", Value: spouseID}, {Key: "type", Value: definition.RelationSpouse}},
+				bson.D{{Key: "contactId", Value: deletedID}, {Key: "type", Value: definition.RelationColleague}},
+				bson.D{{Key: "contactId", Value: managerID}, {Key: "type", Value: definition.RelationManager}},
+			}}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: managerID}, {Key: "firstName", Value: "Yael"}},
+				bson.D{{Key: "_id", Value: spouseID}, {Key: "firstName", Value: "Noa"}}),
+		)
+		related, _, err := phoneBookMock.GetRelatedContacts(context.Background(), id.Hex())
+		assert.Nil(t, err)
+		if assert.Len(t, related, 2) {
+			assert.Equal(t, definition.RelationSpouse, related[0].Type)
+			assert.Equal(t, "Noa", related[0].Contact.FirstName)
+			assert.Equal(t, definition.RelationManager, related[1].Type)
+			assert.Equal(t, "Yael", related[1].Contact.FirstName)
+		}
+	})
+}
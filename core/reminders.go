@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+	"time"
+)
+
+const remindersIndexName = "reminders_pending_due"
+
+// ensureRemindersIndex creates the index the pending reminders are listed
+// and claimed by, in due order.
+func (pb *MongoPhoneBook) ensureRemindersIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "firedAt", Value: 1}, {Key: "dueAt", Value: 1}},
+		Options: options.Index().SetName(remindersIndexName),
+	}
+	_, err := pb.reminders.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// AddReminder schedules a reminder to follow up with the contact with id and
+// returns the id of the reminder in the result.
+func (pb *MongoPhoneBook) AddReminder(ctx context.Context, idParam string, reminder *definition.Reminder, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	contactID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	now := time.Now().UTC()
+	if err := validateReminder(reminder, now); err != nil {
+		return nil, err
+	}
+	count, err := pb.contactsCollection.CountDocuments(ctx, bson.M{"_id": contactID})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrContactNotFound
+	}
+	reminder.ID = primitive.NewObjectID()
+	reminder.ContactID = contactID
+	reminder.DueAt = reminder.DueAt.UTC()
+	reminder.Actor = actor
+	reminder.CreatedAt = now
+	reminder.FiredAt = nil
+	_, err = pb.reminders.InsertOne(ctx, reminder)
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{ID: reminder.ID.Hex(), Created: true}, nil
+}
+
+func validateReminder(reminder *definition.Reminder, now time.Time) error {
+	if reminder.DueAt.IsZero() {
+		return ErrMissingDueAt
+	}
+	// a little slack for the clocks of the clients
+	if reminder.DueAt.Before(now.Add(-time.Minute)) {
+		return ErrPastReminder
+	}
+	if !isText(reminder.Note) {
+		return ErrMissingNote
+	}
+	return nil
+}
+
+// GetReminders returns the pending reminders in due order: those due by the
+// end of today, in the time zone of the server, for due=today, those already
+// due for due=overdue, or all of them. Up to MaxPageSize reminders are
+// returned.
+func (pb *MongoPhoneBook) GetReminders(ctx context.Context, due string) ([]*definition.Reminder, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	filter := bson.M{"firedAt": nil}
+	now := time.Now()
+	switch due {
+	case "":
+	case definition.ReminderDueToday:
+		year, month, day := now.Date()
+		filter["dueAt"] = bson.M{"$lt": time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())}
+	case definition.ReminderDueOverdue:
+		filter["dueAt"] = bson.M{"$lte": now}
+	default:
+		return nil, BadRequest, ErrInvalidDue
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "dueAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(pb.tunables().MaxPageSize)
+	cursor, err := pb.reminders.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	reminders := []*definition.Reminder{}
+	if err := cursor.All(ctx, &reminders); err != nil {
+		return nil, InternalServerError, err
+	}
+	return reminders, "", nil
+}
+
+// ClaimDueReminder marks the earliest reminder due by now as fired and
+// returns it, or nil when none is due. A reminder is claimed once, so
+// replicas sending reminders concurrently don't send it twice.
+func (pb *MongoPhoneBook) ClaimDueReminder(ctx context.Context, now time.Time) (*definition.Reminder, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	filter := bson.M{"firedAt": nil, "dueAt": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"firedAt": now.UTC()}}
+	findOptions := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "dueAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+	var reminder *definition.Reminder
+	err := pb.reminders.FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&reminder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
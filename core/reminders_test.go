@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestAddReminder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	contactID := primitive.NewObjectID()
+
+	mt.Run("should schedule the reminder", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+			mtest.CreateSuccessResponse(),
+		)
+		result, err := phoneBookMock.AddReminder(context.Background(), contactID.Hex(), &definition.Reminder{DueAt: time.Now().Add(time.Hour), Note: "Call back"}, "tester")
+		assert.Nil(t, err)
+		assert.Len(t, result.ID, 24)
+		assert.True(t, result.Created)
+		mt.GetStartedEvent() // the contact lookup
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, contactID, inserted.Lookup("contactId").ObjectID())
+		_, fired := inserted.LookupErr("firedAt")
+		assert.NotNil(t, fired)
+	})
+
+	mt.Run("should reject invalid reminders", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for reminder, expected := range map[*definition.Reminder]error{
+			{Note: "Call back"}: ErrMissingDueAt,
+			{DueAt: time.Now().Add(-time.Hour), Note: "Call back"}: ErrPastReminder,
+			{DueAt: time.Now().Add(time.Hour)}:                     ErrMissingNote,
+		} {
+			_, err := phoneBookMock.AddReminder(context.Background(), contactID.Hex(), reminder, "tester")
+			assert.ErrorIs(t, err, expected)
+			assert.Equal(t, BadRequest, StatusOf(err))
+		}
+	})
+}
+
+func TestGetReminders(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should list the reminders due today", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "note", Value: "Call back"}}))
+		reminders, _, err := phoneBookMock.GetReminders(context.Background(), definition.ReminderDueToday)
+		assert.Nil(t, err)
+		assert.Len(t, reminders, 1)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, bson.TypeNull, filter.Lookup("firedAt").Type)
+		endOfToday := filter.Lookup("dueAt", "$lt").Time()
+		assert.True(t, endOfToday.After(time.Now()))
+		assert.True(t, endOfToday.Before(time.Now().Add(24*time.Hour)))
+	})
+
+	mt.Run("should reject an unknown due", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.GetReminders(context.Background(), "tomorrow")
+		assert.ErrorIs(t, err, ErrInvalidDue)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should find no due reminder to claim", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+		reminder, err := phoneBookMock.ClaimDueReminder(context.Background(), time.Now())
+		assert.Nil(t, err)
+		assert.Nil(t, reminder)
+	})
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+)
+
+const (
+	// maxPinned is the most contacts pinned to the top of the listings.
+	maxPinned = 100
+
+	pinnedIndexName = "contacts_pinned"
+)
+
+// pinnedOrder is the order of the listings without a sort: the pinned
+// contacts first, the heaviest first, then the others in insertion order.
+var pinnedOrder = bson.D{{Key: "sortWeight", Value: -1}, {Key: "_id", Value: 1}}
+
+// ensurePinnedIndex creates the index backing the pinned order.
+func (pb *MongoPhoneBook) ensurePinnedIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys:    pinnedOrder,
+		Options: options.Index().SetName(pinnedIndexName),
+	}
+	_, err := pb.contactsCollection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// ReorderContacts pins the contacts of order to the top of the listings, in
+// that order, and unpins the contacts pinned before and left out of it. The
+// first contact gets the heaviest sort weight, and only the contacts whose
+// weight changes are updated.
+func (pb *MongoPhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(order.IDs) > maxPinned {
+		return nil, ErrInvalidOrder
+	}
+	ids := make([]primitive.ObjectID, 0, len(order.IDs))
+	weights := make(map[primitive.ObjectID]int64, len(order.IDs))
+	for i, idParam := range order.IDs {
+		id, err := primitive.ObjectIDFromHex(idParam)
+		if err != nil {
+			return nil, ErrInvalidID
+		}
+		if _, ok := weights[id]; ok {
+			return nil, ErrInvalidOrder
+		}
+		ids = append(ids, id)
+		weights[id] = int64(len(order.IDs) - i)
+	}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"sortWeight": bson.M{"$exists": true}},
+		bson.M{"_id": bson.M{"$in": ids}},
+	}}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"sortWeight": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var current []*definition.Contact
+	if err := cursor.All(ctx, &current); err != nil {
+		return nil, err
+	}
+	currentWeights := make(map[primitive.ObjectID]int64, len(current))
+	var unpinned []primitive.ObjectID
+	for _, contact := range current {
+		currentWeights[contact.ID] = contact.SortWeight
+		if _, ok := weights[contact.ID]; !ok {
+			unpinned = append(unpinned, contact.ID)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := currentWeights[id]; !ok {
+			return nil, ErrContactNotFound.WithMessage("contact not found: " + id.Hex())
+		}
+	}
+	result := &definition.ReorderResult{IDs: []string{}}
+	for _, id := range ids {
+		if currentWeights[id] == weights[id] {
+			continue
+		}
+		update := bson.M{"$set": bson.M{"sortWeight": weights[id]}}
+		if err := pb.reorderContact(ctx, id, update, actor, result); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range unpinned {
+		update := bson.M{"$unset": bson.M{"sortWeight": ""}}
+		if err := pb.reorderContact(ctx, id, update, actor, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// reorderContact changes the sort weight of the contact with id by update,
+// and reports it in result once modified.
+func (pb *MongoPhoneBook) reorderContact(ctx context.Context, id primitive.ObjectID, update bson.M, actor string, result *definition.ReorderResult) error {
+	written, err := pb.updateVersioned(ctx, id, update, 0, false, actor)
+	if err != nil {
+		return err
+	}
+	if written.Modified > 0 {
+		result.Updated++
+		result.IDs = append(result.IDs, id.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestReorderContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	first, second, unpinned := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	mt.Run("should pin the contacts in order and unpin the others", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		updated := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: first}},
+				bson.D{{Key: "_id", Value: second}, {Key: "sortWeight", Value: int64(1)}},
+				bson.D{{Key: "_id", Value: unpinned}, {Key: "sortWeight", Value: int64(2)}},
+			),
+			updated, updated,
+		)
+		order := &definition.ContactOrder{IDs: []string{first.Hex(), second.Hex()}}
+		result, err := phoneBookMock.ReorderContacts(context.Background(), order, "reception")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.ReorderResult{Updated: 2, IDs: []string{first.Hex(), unpinned.Hex()}}, result, "the second contact keeps its weight")
+
+		var updates []bson.Raw
+		for started := mt.GetStartedEvent(); started != nil; started = mt.GetStartedEvent() {
+			if started.CommandName == "update" {
+				updates = append(updates, started.Command.Lookup("updates").Array().Index(0).Value().Document())
+			}
+		}
+		assert.Equal(t, 2, len(updates))
+		assert.Equal(t, int64(2), updates[0].Lookup("u", "$set", "sortWeight").Int64())
+		assert.NotNil(t, updates[1].Lookup("u", "$unset", "sortWeight").Value)
+	})
+
+	mt.Run("should not reorder unknown contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: first}}))
+		order := &definition.ContactOrder{IDs: []string{first.Hex(), second.Hex()}}
+		_, err := phoneBookMock.ReorderContacts(context.Background(), order, "reception")
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, StatusOf(err))
+	})
+
+	mt.Run("should not reorder an invalid order", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		order := &definition.ContactOrder{IDs: []string{first.Hex(), first.Hex()}}
+		_, err := phoneBookMock.ReorderContacts(context.Background(), order, "reception")
+		assert.ErrorIs(t, err, ErrInvalidOrder)
+		assert.Equal(t, BadRequest, StatusOf(err))
+
+		order = &definition.ContactOrder{IDs: []string{"not an id"}}
+		_, err = phoneBookMock.ReorderContacts(context.Background(), order, "reception")
+		assert.ErrorIs(t, err, ErrInvalidID)
+
+		order = &definition.ContactOrder{IDs: make([]string, maxPinned+1)}
+		_, err = phoneBookMock.ReorderContacts(context.Background(), order, "reception")
+		assert.ErrorIs(t, err, ErrInvalidOrder)
+	})
+
+	mt.Run("should list the pinned contacts first without a sort", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: first}, {Key: "sortWeight", Value: int64(1)}}))
+		result, _, err := phoneBookMock.GetContactWithPagination(context.Background(), url.Values{"count": {"false"}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), result.Items[0].SortWeight)
+		sort := mt.GetStartedEvent().Command.Lookup("sort").Document()
+		assert.Equal(t, "sortWeight", sort.Index(0).Key())
+		assert.Equal(t, int32(-1), sort.Lookup("sortWeight").Int32())
+	})
+}
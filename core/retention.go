@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math/big"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"time"
+)
+
+// anonymizedName replaces the first name of the anonymized contacts.
+const anonymizedName = "Anonymized"
+
+// erasure records that the contacts of a phone number were erased, so
+// restores leave them out. It keeps the hash of the normalized number only.
+type erasure struct {
+	PhoneHash string    `bson:"_id"`
+	ErasedAt  time.Time `bson:"erasedAt"`
+}
+
+// ApplyRetention anonymizes or purges the contacts not updated for
+// policy.Days days, or only reports them in a dry run. Anonymizing clears
+// every personal field but the company and job title, and purging erases the
+// contacts like EraseContacts, except the phone numbers aren't recorded as
+// erased. Either way the history, interactions and reminders of the contacts
+// are deleted. Like Backup it isn't bound by the query timeout, only by ctx.
+func (pb *MongoPhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	if policy.Action != definition.RetentionActionAnonymize && policy.Action != definition.RetentionActionPurge {
+		return nil, ErrRetentionAction
+	}
+	if policy.Days <= 0 {
+		return nil, ErrRetentionDays
+	}
+	before := time.Now().UTC().AddDate(0, 0, -policy.Days)
+	filter := bson.M{"$or": bson.A{
+		bson.M{"updatedAt": bson.M{"$lt": before}},
+		// contacts written before updatedAt existed
+		bson.M{"updatedAt": bson.M{"$exists": false}, "createdAt": bson.M{"$lt": before}},
+	}}
+	cursor, err := pb.contactsCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	result := &definition.RetentionResult{Action: policy.Action, IDs: []string{}, DryRun: policy.DryRun}
+	ids := make([]primitive.ObjectID, len(contacts))
+	for i, contact := range contacts {
+		ids[i] = contact.ID
+		result.IDs = append(result.IDs, contact.ID.Hex())
+	}
+	result.Affected = int64(len(ids))
+	if policy.DryRun {
+		return result, nil
+	}
+	for start := 0; start < len(ids); start += restoreBatchSize {
+		batch := ids[start:min(start+restoreBatchSize, len(ids))]
+		if policy.Action == definition.RetentionActionPurge {
+			_, err = pb.eraseContacts(ctx, batch, "", actor)
+		} else {
+			err = pb.anonymizeContacts(ctx, batch)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// anonymizeContacts clears the personal fields of the contacts with the
+// given ids, and deletes their history, interactions and reminders.
+func (pb *MongoPhoneBook) anonymizeContacts(ctx context.Context, ids []primitive.ObjectID) error {
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		anonymized := &definition.Contact{FirstName: anonymizedName, Phone: anonymizedPhone(id)}
+		deriveFields(anonymized)
+		set := bson.M{
+			"firstName":       anonymized.FirstName,
+			"phone":           anonymized.Phone,
+			"displayName":     anonymized.DisplayName,
+			"normalizedPhone": anonymized.NormalizedPhone,
+			"initial":         anonymized.Initial,
+		}
+		if err := pb.cipher.sealValues(set); err != nil {
+			return err
+		}
+		models[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(bson.M{
+			"$set":         set,
+			"$unset":       bson.M{"lastName": "", "email": "", "address": "", "notes": "", "relations": ""},
+			"$inc":         bson.M{"version": 1},
+			"$currentDate": bson.M{"updatedAt": true},
+		})
+	}
+	if _, err := pb.contactsCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+	return pb.deleteContactRecords(ctx, ids, "", &definition.ErasureResult{})
+}
+
+// anonymizedPhone is the phone of an anonymized contact: the digits of its
+// id, unique as duplicate detection may require, and too long to be dialed.
+func anonymizedPhone(id primitive.ObjectID) string {
+	return new(big.Int).SetBytes(id[:]).String()
+}
+
+// EraseContacts deletes the contacts whose phone is phone once both are
+// normalized, for requests to be forgotten. Their history, interactions and
+// reminders are deleted with them, as are the audit entries holding the
+// number, like those of the contacts deleted earlier undo would bring back.
+// The number is recorded as erased first, so restoring a backup taken before
+// leaves its contacts out. Erasures aren't audited.
+func (pb *MongoPhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(phone) > config.Static.MaxSizeProperty || !formattedPhoneRegex.MatchString(phone) {
+		return nil, ErrInvalidLookup.WithField("phone")
+	}
+	normalized := normalizePhone(phone)
+	if normalized == "" {
+		return nil, ErrInvalidLookup.WithField("phone")
+	}
+	upsert := options.Replace().SetUpsert(true)
+	record := &erasure{PhoneHash: hashPhone(normalized), ErasedAt: time.Now().UTC()}
+	if _, err := pb.erasures.ReplaceOne(ctx, bson.M{"_id": record.PhoneHash}, record, upsert); err != nil {
+		return nil, err
+	}
+	cursor, err := pb.contactsCollection.Find(ctx, bson.M{"normalizedPhone": pb.cipher.phoneIndex(normalized)}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(contacts))
+	for i, contact := range contacts {
+		ids[i] = contact.ID
+	}
+	result, err := pb.eraseContacts(ctx, ids, normalized, actor)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// eraseContacts deletes the contacts with the given ids and their records,
+// and the audit entries holding normalizedPhone, unless empty, on behalf of
+// actor.
+func (pb *MongoPhoneBook) eraseContacts(ctx context.Context, ids []primitive.ObjectID, normalizedPhone string, actor string) (*definition.ErasureResult, error) {
+	result := &definition.ErasureResult{IDs: []string{}}
+	if len(ids) > 0 {
+		deleteResult, err := pb.contactsCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return nil, err
+		}
+		result.Contacts = deleteResult.DeletedCount
+		for _, id := range ids {
+			result.IDs = append(result.IDs, id.Hex())
+		}
+	}
+	if err := pb.deleteContactRecords(ctx, ids, normalizedPhone, result); err != nil {
+		return nil, err
+	}
+	pb.recordTombstones(ctx, actor, ids...)
+	return result, nil
+}
+
+// deleteContactRecords deletes the history, interactions, reminders and speed
+// dials of the contacts with the given ids, and the audit entries holding
+// normalizedPhone, unless empty, counting them in result.
+func (pb *MongoPhoneBook) deleteContactRecords(ctx context.Context, ids []primitive.ObjectID, normalizedPhone string, result *definition.ErasureResult) error {
+	if pb.auditLog != nil {
+		deleted, err := pb.auditLog.Erase(ctx, ids, normalizedPhone)
+		if err != nil {
+			return err
+		}
+		result.AuditEntries = deleted
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	filter := bson.M{"contactId": bson.M{"$in": ids}}
+	deleteResult, err := pb.interactions.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+	result.Interactions = deleteResult.DeletedCount
+	deleteResult, err = pb.reminders.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+	result.Reminders = deleteResult.DeletedCount
+	deleteResult, err = pb.speedDials.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+	result.SpeedDials = deleteResult.DeletedCount
+	return nil
+}
+
+// erasedPhones returns the hashes of the erased phone numbers.
+func (pb *MongoPhoneBook) erasedPhones(ctx context.Context) (map[string]bool, error) {
+	cursor, err := pb.erasures.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var erasures []*erasure
+	if err := cursor.All(ctx, &erasures); err != nil {
+		return nil, err
+	}
+	hashes := map[string]bool{}
+	for _, erased := range erasures {
+		hashes[erased.PhoneHash] = true
+	}
+	return hashes, nil
+}
+
+// isErased reports whether the phone of a backed up contact was erased.
+func isErased(raw bson.Raw, erased map[string]bool) bool {
+	normalized, ok := raw.Lookup("normalizedPhone").StringValueOK()
+	return ok && erased[hashPhone(normalized)]
+}
+
+func hashPhone(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestApplyRetention(t *testing.T) {
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	staleContacts := func(mt *mtest.T) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: firstID}}, bson.D{{Key: "_id", Value: secondID}})
+	}
+	deleted := func(n int) bson.D {
+		return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: n}}
+	}
+
+	mt.Run("should only report the contacts in dry run", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(staleContacts(mt))
+		policy := &definition.RetentionPolicy{Action: definition.RetentionActionPurge, Days: 365, DryRun: true}
+		result, err := phoneBookMock.ApplyRetention(context.Background(), policy, "")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result.Affected)
+		assert.Equal(t, []string{firstID.Hex(), secondID.Hex()}, result.IDs)
+		mt.GetStartedEvent()
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("should clear the personal fields when anonymizing", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(staleContacts(mt), bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}, {Key: "nModified", Value: 2}}, deleted(3), deleted(1), deleted(1))
+		policy := &definition.RetentionPolicy{Action: definition.RetentionActionAnonymize, Days: 365}
+		result, err := phoneBookMock.ApplyRetention(context.Background(), policy, "")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result.Affected)
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent()
+		assert.Equal(t, "update", update.CommandName)
+		updates := update.Command.Lookup("updates").Array()
+		first := updates.Index(0).Value().Document().Lookup("u").Document()
+		assert.Equal(t, anonymizedName, first.Lookup("$set", "firstName").StringValue())
+		assert.Equal(t, anonymizedPhone(firstID), first.Lookup("$set", "phone").StringValue())
+		assert.Len(t, anonymizedPhone(firstID), 29)
+		_, err = first.LookupErr("$unset", "email")
+		assert.Nil(t, err)
+		assert.Equal(t, "delete", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "delete", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "delete", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("should delete the contacts and their records when purging", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(staleContacts(mt), deleted(2), deleted(3), deleted(1), deleted(1), bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}})
+		policy := &definition.RetentionPolicy{Action: definition.RetentionActionPurge, Days: 365}
+		result, err := phoneBookMock.ApplyRetention(context.Background(), policy, "")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result.Affected)
+		mt.GetStartedEvent()
+		for _, collection := range []string{"contacts", "interactions", "reminders", "speedDials"} {
+			event := mt.GetStartedEvent()
+			assert.Equal(t, "delete", event.CommandName)
+			assert.Equal(t, collection, event.Command.Lookup("delete").StringValue())
+		}
+		tombstones := mt.GetStartedEvent()
+		assert.Equal(t, "tombstones", tombstones.Command.Lookup("update").StringValue())
+		updates, _ := tombstones.Command.Lookup("updates").Array().Values()
+		assert.Len(t, updates, 2)
+	})
+
+	mt.Run("should reject an invalid policy", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.ApplyRetention(context.Background(), &definition.RetentionPolicy{Action: "archive", Days: 30}, "")
+		assert.ErrorIs(t, err, ErrRetentionAction)
+		assert.Equal(t, BadRequest, StatusOf(err))
+		_, err = phoneBookMock.ApplyRetention(context.Background(), &definition.RetentionPolicy{Action: definition.RetentionActionPurge}, "")
+		assert.ErrorIs(t, err, ErrRetentionDays)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+}
+
+func TestEraseContacts(t *testing.T) {
+	id := primitive.NewObjectID()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should record the erasure, then delete the contacts of the number", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{{Key: "_id", Value: id}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 4}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+		result, err := phoneBookMock.EraseContacts(context.Background(), "+972 52-123-4567", "")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), result.Contacts)
+		assert.Equal(t, []string{id.Hex()}, result.IDs)
+		assert.Equal(t, int64(4), result.Interactions)
+		assert.Equal(t, int64(1), result.SpeedDials)
+		upsert := mt.GetStartedEvent()
+		assert.Equal(t, "erasures", upsert.Command.Lookup("update").StringValue())
+		assert.NotContains(t, upsert.Command.String(), "521234567")
+		assert.Contains(t, upsert.Command.String(), hashPhone("972521234567"))
+		find := mt.GetStartedEvent()
+		assert.Equal(t, "972521234567", find.Command.Lookup("filter", "normalizedPhone").StringValue())
+	})
+
+	mt.Run("should reject an invalid phone number", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.EraseContacts(context.Background(), "dani", "")
+		assert.ErrorIs(t, err, ErrInvalidLookup)
+		assert.Equal(t, BadRequest, StatusOf(err))
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"phoneBook/definition"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxSeed is the most contacts a single seed may generate.
+const maxSeed = 10000
+
+// SeedContacts adds count generated contacts, for demos and load tests, and
+// returns how many were added. Generated contacts duplicating existing ones
+// are skipped. Seeded contacts aren't audited.
+func (pb *MongoPhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	if count <= 0 || count > maxSeed {
+		return 0, ErrInvalidSeedCount
+	}
+	if err := pb.checkContactLimit(ctx, int64(count)); err != nil {
+		return 0, err
+	}
+	var seeded int64
+	for count > 0 {
+		batch := min(count, backfillBatchSize)
+		inserted, err := pb.insertFakeContacts(ctx, batch)
+		seeded += inserted
+		if err != nil {
+			return seeded, err
+		}
+		count -= batch
+	}
+	return seeded, nil
+}
+
+// insertFakeContacts inserts a batch of count generated contacts, each batch
+// bound by the query timeout.
+func (pb *MongoPhoneBook) insertFakeContacts(ctx context.Context, count int) (int64, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	now := time.Now().UTC()
+	documents := make([]interface{}, count)
+	for i := range documents {
+		contact := fakeContact()
+		contact.Version = 1
+		contact.SchemaVersion = schemaVersion
+		deriveFields(contact)
+		contact.CreatedAt = &now
+		contact.UpdatedAt = &now
+		documents[i] = contact
+	}
+	_, err := pb.contactsCollection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	var writeErr mongo.BulkWriteException
+	if errors.As(err, &writeErr) && writeErr.WriteConcernError == nil && onlyDuplicates(writeErr.WriteErrors) {
+		return int64(count - len(writeErr.WriteErrors)), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(count), nil
+}
+
+func onlyDuplicates(writeErrors []mongo.BulkWriteError) bool {
+	for _, writeErr := range writeErrors {
+		if !mongo.IsDuplicateKeyError(writeErr.WriteError) {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeContact generates a contact that passes the contact validation.
+func fakeContact() *definition.Contact {
+	return &definition.Contact{
+		FirstName: fakeName(gofakeit.FirstName),
+		LastName:  fakeName(gofakeit.LastName),
+		Phone:     gofakeit.Phone(),
+		Email:     strings.ToLower(gofakeit.Email()),
+		Company:   gofakeit.Company(),
+		JobTitle:  gofakeit.JobTitle(),
+		Address:   gofakeit.Street() + ", " + gofakeit.City(),
+		Notes:     gofakeit.HipsterSentence(6),
+	}
+}
+
+// fakeName generates names until one is left with letters once stripped of
+// the others, like the apostrophe of O'Keefe.
+func fakeName(generate func() string) string {
+	for {
+		name := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.Is(unicode.M, r) {
+				return r
+			}
+			return -1
+		}, generate())
+		if name != "" {
+			return name
+		}
+	}
+}
+
+// SampleContacts returns size random contacts, sampled by Mongo, size
+// defaulting to the page size and clamped to MaxPageSize. The sample holds
+// fewer contacts when the phone book does.
+func (pb *MongoPhoneBook) SampleContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	size, err := validatePageSizeParam(query["size"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidSampleSize
+	}
+	pipeline := mongo.Pipeline{{{Key: "$sample", Value: bson.M{"size": size}}}}
+	cursor, err := pb.contactsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts := []*definition.Contact{}
+	if err := cursor.All(ctx, &contacts); err != nil {
+		return nil, InternalServerError, err
+	}
+	return contacts, "", nil
+}
+
+// GetRandomContact returns a random contact, or NotFound when the phone book
+// is empty.
+func (pb *MongoPhoneBook) GetRandomContact(ctx context.Context) (*definition.Contact, string, error) {
+	contacts, status, err := pb.SampleContacts(ctx, url.Values{"size": {"1"}})
+	if err != nil {
+		return nil, status, err
+	}
+	if len(contacts) == 0 {
+		return nil, NotFound, ErrContactNotFound
+	}
+	return contacts[0], "", nil
+}
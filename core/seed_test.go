@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"testing"
+)
+
+func TestFakeContact(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		contact := fakeContact()
+		assert.Nil(t, validateContact(contact), contact)
+	}
+}
+
+func TestSeedContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should insert the generated contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		seeded, err := phoneBookMock.SeedContacts(context.Background(), 3, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), seeded)
+		documents := mt.GetStartedEvent().Command.Lookup("documents").Array()
+		values, _ := documents.Values()
+		assert.Len(t, values, 3)
+		assert.NotEmpty(t, values[0].Document().Lookup("displayName").StringValue())
+	})
+
+	mt.Run("should skip the duplicates", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+		seeded, err := phoneBookMock.SeedContacts(context.Background(), 3, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), seeded)
+	})
+
+	mt.Run("should reject an invalid count", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for _, count := range []int{0, -1, maxSeed + 1} {
+			_, err := phoneBookMock.SeedContacts(context.Background(), count, "tester")
+			assert.ErrorIs(t, err, ErrInvalidSeedCount)
+			assert.Equal(t, BadRequest, StatusOf(err))
+		}
+	})
+}
+
+func TestSampleContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should sample the contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Dani"}}))
+		contacts, _, err := phoneBookMock.SampleContacts(context.Background(), url.Values{"size": {"5"}})
+		assert.Nil(t, err)
+		assert.Len(t, contacts, 1)
+		stage := mt.GetStartedEvent().Command.Lookup("pipeline").Array().Index(0).Value().Document()
+		assert.Equal(t, int64(5), stage.Lookup("$sample", "size").AsInt64())
+	})
+
+	mt.Run("should reject an invalid size", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.SampleContacts(context.Background(), url.Values{"size": {"-1"}})
+		assert.ErrorIs(t, err, ErrInvalidSampleSize)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should not find a random contact in an empty phone book", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		_, status, err := phoneBookMock.GetRandomContact(context.Background())
+		assert.ErrorIs(t, err, ErrContactNotFound)
+		assert.Equal(t, NotFound, status)
+	})
+}
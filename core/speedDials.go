@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/definition"
+	"strconv"
+	"time"
+)
+
+const speedDialsIndexName = "speedDials_contact"
+
+// ensureSpeedDialsIndex creates the unique index assigning a contact to a
+// single slot, the slots themselves are unique as the ids of the speed dials.
+func (pb *MongoPhoneBook) ensureSpeedDialsIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "contactId", Value: 1}},
+		Options: options.Index().SetName(speedDialsIndexName).SetUnique(true),
+	}
+	_, err := pb.speedDials.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// AssignSpeedDial assigns the speed dial slot to the contact of dial and
+// returns the speed dial. Assigning a slot to the contact it is assigned to
+// already changes nothing, a slot assigned to another contact, or a contact
+// assigned to another slot, have to be cleared first.
+func (pb *MongoPhoneBook) AssignSpeedDial(ctx context.Context, slotParam string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	slot, err := validateSlotParam(slotParam)
+	if err != nil {
+		return nil, err
+	}
+	if dial.ContactID.IsZero() {
+		return nil, ErrInvalidID.WithField("contactId")
+	}
+	count, err := pb.contactsCollection.CountDocuments(ctx, bson.M{"_id": dial.ContactID})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrContactNotFound
+	}
+	filter := bson.M{"$or": bson.A{bson.M{"_id": slot}, bson.M{"contactId": dial.ContactID}}}
+	cursor, err := pb.speedDials.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var assigned []*definition.SpeedDial
+	if err := cursor.All(ctx, &assigned); err != nil {
+		return nil, err
+	}
+	for _, existing := range assigned {
+		switch {
+		case existing.Slot == slot && existing.ContactID == dial.ContactID:
+			return existing, nil
+		case existing.Slot == slot:
+			return nil, ErrSlotTaken
+		default:
+			return nil, ErrContactHasSlot.WithMessage(ErrorContactHasSlot + ": " + strconv.Itoa(existing.Slot))
+		}
+	}
+	dial.Slot = slot
+	dial.Actor = actor
+	dial.AssignedAt = time.Now().UTC()
+	dial.Contact = nil
+	_, err = pb.speedDials.InsertOne(ctx, dial)
+	if mongo.IsDuplicateKeyError(err) {
+		// assigned meanwhile by another request
+		return nil, ErrSlotTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dial, nil
+}
+
+// GetSpeedDials returns the assigned speed dial slots in order, each with its
+// contact. The slots of deleted contacts are kept, so undoing the delete
+// gets the contact its slot back, and listed without a contact.
+func (pb *MongoPhoneBook) GetSpeedDials(ctx context.Context) ([]*definition.SpeedDial, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	cursor, err := pb.speedDials.Find(ctx, bson.D{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	dials := []*definition.SpeedDial{}
+	if err := cursor.All(ctx, &dials); err != nil {
+		return nil, InternalServerError, err
+	}
+	if len(dials) == 0 {
+		return dials, "", nil
+	}
+	ids := make(bson.A, 0, len(dials))
+	for _, dial := range dials {
+		ids = append(ids, dial.ContactID)
+	}
+	cursor, err = pb.contactsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	byID := make(map[string]*definition.Contact, len(contacts))
+	for _, contact := range contacts {
+		byID[contact.ID.Hex()] = contact
+	}
+	for _, dial := range dials {
+		dial.Contact = byID[dial.ContactID.Hex()]
+	}
+	return dials, "", nil
+}
+
+// ClearSpeedDial unassigns the speed dial slot. Nothing is modified when it
+// wasn't assigned.
+func (pb *MongoPhoneBook) ClearSpeedDial(ctx context.Context, slotParam string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	slot, err := validateSlotParam(slotParam)
+	if err != nil {
+		return nil, err
+	}
+	deleteResult, err := pb.speedDials.DeleteOne(ctx, bson.M{"_id": slot})
+	if err != nil {
+		return nil, err
+	}
+	return &definition.WriteResult{Matched: deleteResult.DeletedCount, Modified: deleteResult.DeletedCount}, nil
+}
+
+func validateSlotParam(slotParam string) (int, error) {
+	slot, err := strconv.Atoi(slotParam)
+	if err != nil || slot < 1 || slot > definition.MaxSpeedDialSlot {
+		return 0, ErrInvalidSlot
+	}
+	return slot, nil
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestAssignSpeedDial(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	contactID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+	found := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}}
+	assigned := func(mt *mtest.T, dials ...bson.D) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, dials...)
+	}
+
+	mt.Run("should assign the slot to the contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.contacts", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}), assigned(mt), found)
+		dial, err := phoneBookMock.AssignSpeedDial(context.Background(), "7", &definition.SpeedDial{ContactID: contactID}, "reception")
+		assert.Nil(t, err)
+		assert.Equal(t, 7, dial.Slot)
+		assert.Equal(t, "reception", dial.Actor)
+		insert := mt.GetStartedEvent()
+		for insert != nil && insert.CommandName != "insert" {
+			insert = mt.GetStartedEvent()
+		}
+		assert.NotNil(t, insert)
+		document := insert.Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, int32(7), document.Lookup("_id").Int32())
+		assert.Equal(t, contactID, document.Lookup("contactId").ObjectID())
+	})
+
+	mt.Run("should not assign a slot taken or a contact with a slot", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.contacts", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			assigned(mt, bson.D{{Key: "_id", Value: 7}, {Key: "contactId", Value: otherID}}),
+		)
+		_, err := phoneBookMock.AssignSpeedDial(context.Background(), "7", &definition.SpeedDial{ContactID: contactID}, "reception")
+		assert.ErrorIs(t, err, ErrSlotTaken)
+		assert.Equal(t, Conflict, StatusOf(err))
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.contacts", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			assigned(mt, bson.D{{Key: "_id", Value: 3}, {Key: "contactId", Value: contactID}}),
+		)
+		_, err = phoneBookMock.AssignSpeedDial(context.Background(), "7", &definition.SpeedDial{ContactID: contactID}, "reception")
+		assert.ErrorIs(t, err, ErrContactHasSlot)
+		assert.Equal(t, Conflict, StatusOf(err))
+	})
+
+	mt.Run("should leave a slot assigned to the contact already", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.contacts", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			assigned(mt, bson.D{{Key: "_id", Value: 7}, {Key: "contactId", Value: contactID}, {Key: "actor", Value: "dani"}}),
+		)
+		dial, err := phoneBookMock.AssignSpeedDial(context.Background(), "7", &definition.SpeedDial{ContactID: contactID}, "reception")
+		assert.Nil(t, err)
+		assert.Equal(t, "dani", dial.Actor)
+	})
+
+	mt.Run("should reject an invalid slot", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for _, slot := range []string{"0", "100", "one", ""} {
+			_, err := phoneBookMock.AssignSpeedDial(context.Background(), slot, &definition.SpeedDial{ContactID: contactID}, "reception")
+			assert.ErrorIs(t, err, ErrInvalidSlot, slot)
+			assert.Equal(t, BadRequest, StatusOf(err))
+		}
+		_, err := phoneBookMock.AssignSpeedDial(context.Background(), "7", &definition.SpeedDial{}, "reception")
+		assert.ErrorIs(t, err, ErrInvalidID)
+	})
+}
+
+func TestGetSpeedDials(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	contactID, deletedID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	mt.Run("should list the slots with their contacts", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ns := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: 1}, {Key: "contactId", Value: contactID}},
+				bson.D{{Key: "_id", Value: 2}, {Key: "contactId", Value: deletedID}},
+			),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: contactID}, {Key: "firstName", Value: "Security"}}),
+		)
+		dials, _, err := phoneBookMock.GetSpeedDials(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, dials, 2)
+		assert.Equal(t, "Security", dials[0].Contact.FirstName)
+		assert.Nil(t, dials[1].Contact, "the slot of a deleted contact should be listed without it")
+	})
+}
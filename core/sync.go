@@ -0,0 +1,242 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"phoneBook/definition"
+	"time"
+)
+
+const tombstonesIndexName = "tombstones_ttl"
+
+// syncResetID is the id of the tombstone marking the last restore. Restored
+// contacts keep the time they were last updated, so the clients that synced
+// before have to sync all the contacts again.
+var syncResetID = primitive.NilObjectID
+
+// tombstone records that a contact was deleted and by whom, for the sync
+// clients to delete their copy. Tombstones expire after SYNC_TOMBSTONE_TTL.
+type tombstone struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	DeletedAt time.Time          `bson:"deletedAt"`
+	DeletedBy string             `bson:"deletedBy,omitempty"`
+}
+
+// syncToken is a position in the changes to the contacts, ordered by the
+// time of the change and the contact id: the last change a client synced,
+// and when the client started syncing. A first sync goes through contacts
+// changed before it started, so the client knows the deletions since the
+// later of both.
+type syncToken struct {
+	At    time.Time
+	ID    primitive.ObjectID
+	Since time.Time
+}
+
+func (t *syncToken) encode() string {
+	raw := binary.BigEndian.AppendUint64(nil, uint64(t.At.UnixMilli()))
+	raw = append(raw, t.ID[:]...)
+	raw = binary.BigEndian.AppendUint64(raw, uint64(t.Since.UnixMilli()))
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeSyncToken(token string) (*syncToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 28 {
+		return nil, ErrInvalidSyncToken
+	}
+	position := &syncToken{
+		At:    time.UnixMilli(int64(binary.BigEndian.Uint64(raw[:8]))).UTC(),
+		Since: time.UnixMilli(int64(binary.BigEndian.Uint64(raw[20:]))).UTC(),
+	}
+	copy(position.ID[:], raw[8:20])
+	return position, nil
+}
+
+// after returns a token resuming after the change at time at to the contact
+// with id.
+func (t *syncToken) after(at time.Time, id primitive.ObjectID) *syncToken {
+	return &syncToken{At: at, ID: id, Since: t.Since}
+}
+
+// precedes reports whether the change at time at to the contact with id
+// comes before the change of t.
+func (t *syncToken) precedes(at time.Time, id primitive.ObjectID) bool {
+	return at.Before(t.At) || at.Equal(t.At) && bytes.Compare(id[:], t.ID[:]) < 0
+}
+
+// ensureTombstonesIndex creates the index the tombstones are listed by,
+// which expires them after the tombstone TTL, and updates the TTL when it was
+// changed since.
+func (pb *MongoPhoneBook) ensureTombstonesIndex(ctx context.Context) error {
+	expireAfter := int32(pb.tombstoneTTL.Seconds())
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+		Options: options.Index().SetName(tombstonesIndexName).SetExpireAfterSeconds(expireAfter),
+	}
+	_, err := pb.tombstones.Indexes().CreateOne(ctx, model)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Name == "IndexOptionsConflict" {
+		return pb.tombstones.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: pb.tombstones.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: tombstonesIndexName}, {Key: "expireAfterSeconds", Value: expireAfter}}},
+		}).Err()
+	}
+	return err
+}
+
+// recordTombstones records that actor deleted the contacts with ids, at the
+// time of the database like the updates. The contacts are deleted already,
+// so a failure is only logged: the clients keep their copy until they sync
+// all the contacts again.
+func (pb *MongoPhoneBook) recordTombstones(ctx context.Context, actor string, ids ...primitive.ObjectID) {
+	if len(ids) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pb.queryTimeout)
+	defer cancel()
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		models[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$currentDate": bson.M{"deletedAt": true}, "$set": bson.M{"deletedBy": actor}}).SetUpsert(true)
+	}
+	if _, err := pb.tombstones.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		logrus.WithError(err).Errorf("failed to record the deletion of %d contacts for the sync clients", len(ids))
+	}
+}
+
+// GetChanges returns the changes to the contacts since the sync token of
+// query, or since a time given as an RFC 3339 since, oldest first: the
+// contacts added or updated since, as they are now, and the tombstones of
+// those deleted. Without a token every contact is returned, for the first sync of
+// a client. Up to limit changes are returned, the page size unless given,
+// and the sync token of the page resumes after them. Tokens older than the
+// tombstone TTL, or issued before a restore, expire, as the deletions since
+// may be forgotten, and the client has to sync all the contacts again.
+func (pb *MongoPhoneBook) GetChanges(ctx context.Context, query url.Values) (*definition.ChangePage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	limit, err := validatePageSizeParam(query["limit"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, ErrInvalidLimit
+	}
+	now := time.Now().UTC()
+	position := &syncToken{Since: now}
+	token := query.Get("token")
+	if token == "" {
+		token = query.Get("since")
+	}
+	if token != "" {
+		if since, err := time.Parse(time.RFC3339, token); err == nil {
+			position = &syncToken{At: since.UTC(), Since: since.UTC()}
+		} else if position, err = decodeSyncToken(token); err != nil {
+			return nil, BadRequest, err
+		}
+		expired, err := pb.syncTokenExpired(ctx, position, now)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		if expired {
+			return nil, Gone, ErrSyncTokenExpired
+		}
+	}
+	// one extra change of each kind tells whether more changes follow
+	cursor, err := pb.contactsCollection.Find(ctx, changedSince("updatedAt", position), options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit+1))
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	filter := bson.M{
+		"$and": bson.A{changedSince("deletedAt", position), bson.M{"deletedAt": bson.M{"$gte": position.Since}}},
+		"_id":  bson.M{"$ne": syncResetID},
+	}
+	cursor, err = pb.tombstones.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "deletedAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit+1))
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	var tombstones []*tombstone
+	if err := cursor.All(ctx, &tombstones); err != nil {
+		return nil, InternalServerError, err
+	}
+	page := &definition.ChangePage{Changes: []*definition.ContactChange{}, SyncToken: position.encode()}
+	for int64(len(page.Changes)) < limit && len(contacts)+len(tombstones) > 0 {
+		var change *definition.ContactChange
+		if len(tombstones) == 0 || len(contacts) > 0 && position.after(tombstones[0].DeletedAt, tombstones[0].ID).precedes(contactChangedAt(contacts[0]), contacts[0].ID) {
+			contact := contacts[0]
+			contacts = contacts[1:]
+			change = &definition.ContactChange{ID: contact.ID.Hex(), Contact: contact, SyncToken: position.after(contactChangedAt(contact), contact.ID).encode()}
+			change.Created = token == "" || contact.CreatedAt != nil && !contact.CreatedAt.Before(position.At)
+		} else {
+			deleted := tombstones[0]
+			tombstones = tombstones[1:]
+			deletedAt := deleted.DeletedAt.UTC()
+			change = &definition.ContactChange{ID: deleted.ID.Hex(), Deleted: true, DeletedAt: &deletedAt, DeletedBy: deleted.DeletedBy,
+				SyncToken: position.after(deleted.DeletedAt, deleted.ID).encode()}
+		}
+		page.Changes = append(page.Changes, change)
+		page.SyncToken = change.SyncToken
+	}
+	page.More = len(contacts)+len(tombstones) > 0
+	return page, "", nil
+}
+
+// syncTokenExpired reports whether the deletions since position may have
+// been forgotten, the tombstones having expired or a restore replaced the
+// contacts since the client started syncing.
+func (pb *MongoPhoneBook) syncTokenExpired(ctx context.Context, position *syncToken, now time.Time) (bool, error) {
+	synced := position.At
+	if position.Since.After(synced) {
+		synced = position.Since
+	}
+	if synced.Before(now.Add(-pb.tombstoneTTL)) {
+		return true, nil
+	}
+	var reset tombstone
+	err := pb.tombstones.FindOne(ctx, bson.M{"_id": syncResetID}).Decode(&reset)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return reset.DeletedAt.After(position.Since), nil
+}
+
+// changedSince returns the filter of the changes after position, by the
+// time field of the change. Contacts written before updatedAt existed come
+// first.
+func changedSince(field string, position *syncToken) bson.M {
+	if position.At.IsZero() {
+		return bson.M{"$or": bson.A{
+			bson.M{field: nil, "_id": bson.M{"$gt": position.ID}},
+			bson.M{field: bson.M{"$gt": position.At}},
+		}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{field: position.At, "_id": bson.M{"$gt": position.ID}},
+		bson.M{field: bson.M{"$gt": position.At}},
+	}}
+}
+
+// contactChangedAt returns when contact was last changed, the zero time for
+// contacts written before updatedAt existed.
+func contactChangedAt(contact *definition.Contact) time.Time {
+	if contact.UpdatedAt == nil {
+		return time.Time{}
+	}
+	return contact.UpdatedAt.UTC()
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/url"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestSyncToken(t *testing.T) {
+	t.Run("should decode the token it encoded", func(t *testing.T) {
+		for _, position := range []*syncToken{
+			{At: time.UnixMilli(1700000000123).UTC(), ID: primitive.NewObjectID(), Since: time.UnixMilli(1700000000000).UTC()},
+			{Since: time.UnixMilli(1700000000000).UTC()},
+		} {
+			decoded, err := decodeSyncToken(position.encode())
+			assert.Nil(t, err)
+			assert.Equal(t, position, decoded)
+		}
+	})
+
+	t.Run("should reject an invalid token", func(t *testing.T) {
+		_, err := decodeSyncToken("not a token")
+		assert.ErrorIs(t, err, ErrInvalidSyncToken)
+		_, err = decodeSyncToken(encodeCursor(primitive.NewObjectID()))
+		assert.ErrorIs(t, err, ErrInvalidSyncToken)
+	})
+}
+
+func TestGetChanges(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	legacy := primitive.NewObjectID()
+	updated := primitive.NewObjectID()
+	deleted := primitive.NewObjectID()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	changes := func(mt *mtest.T, collection string, documents ...bson.D) bson.D {
+		return mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), collection), mtest.FirstBatch, documents...)
+	}
+
+	mt.Run("should merge the updated and deleted contacts in change order", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(
+			changes(mt, "contacts", bson.D{{Key: "_id", Value: legacy}}, bson.D{{Key: "_id", Value: updated}, {Key: "updatedAt", Value: now}}),
+			changes(mt, "tombstones", bson.D{{Key: "_id", Value: deleted}, {Key: "deletedAt", Value: now.Add(-time.Second)}, {Key: "deletedBy", Value: "dani"}}))
+		page, _, err := phoneBookMock.GetChanges(context.Background(), url.Values{"limit": {"2"}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Changes, 2)
+		assert.Equal(t, legacy.Hex(), page.Changes[0].ID)
+		deletedAt := now.Add(-time.Second)
+		assert.Equal(t, &definition.ContactChange{ID: deleted.Hex(), Deleted: true, DeletedAt: &deletedAt, DeletedBy: "dani", SyncToken: page.SyncToken}, page.Changes[1])
+		assert.True(t, page.More)
+
+		position, err := decodeSyncToken(page.SyncToken)
+		assert.Nil(t, err)
+		assert.Equal(t, deleted, position.ID)
+		assert.Equal(t, now.Add(-time.Second), position.At)
+		mt.GetStartedEvent()
+		tombstones := mt.GetStartedEvent()
+		assert.Equal(t, position.Since, tombstones.Command.Lookup("filter", "$and").Array().Index(1).Value().Document().Lookup("deletedAt", "$gte").Time().UTC())
+	})
+
+	mt.Run("should resume after the token", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		token := (&syncToken{At: now.Add(-time.Second), ID: deleted, Since: now.Add(-time.Minute)}).encode()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.tombstones", mt.DB.Name()), mtest.FirstBatch),
+			changes(mt, "contacts", bson.D{{Key: "_id", Value: updated}, {Key: "updatedAt", Value: now}}),
+			changes(mt, "tombstones"))
+		page, _, err := phoneBookMock.GetChanges(context.Background(), url.Values{"token": {token}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Changes, 1)
+		assert.Equal(t, updated, page.Changes[0].Contact.ID)
+		assert.False(t, page.More)
+		mt.GetStartedEvent()
+		find := mt.GetStartedEvent()
+		after := find.Command.Lookup("filter", "$or").Array().Index(0).Value().Document()
+		assert.Equal(t, deleted, after.Lookup("_id", "$gt").ObjectID())
+	})
+
+	mt.Run("should tell the created contacts since a time", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		since := now.Add(-time.Hour)
+		mt.AddMockResponses(
+			changes(mt, "tombstones"),
+			changes(mt, "contacts",
+				bson.D{{Key: "_id", Value: legacy}, {Key: "createdAt", Value: now.Add(-2 * time.Hour)}, {Key: "updatedAt", Value: now.Add(-time.Minute)}},
+				bson.D{{Key: "_id", Value: updated}, {Key: "createdAt", Value: now}, {Key: "updatedAt", Value: now}}),
+			changes(mt, "tombstones"))
+		page, _, err := phoneBookMock.GetChanges(context.Background(), url.Values{"since": {since.Format(time.RFC3339Nano)}})
+		assert.Nil(t, err)
+		assert.Len(t, page.Changes, 2)
+		assert.False(t, page.Changes[0].Created)
+		assert.True(t, page.Changes[1].Created)
+		mt.GetStartedEvent()
+		find := mt.GetStartedEvent()
+		assert.Equal(t, since, find.Command.Lookup("filter", "$or").Array().Index(1).Value().Document().Lookup("updatedAt", "$gt").Time().UTC())
+	})
+
+	mt.Run("should expire the tokens older than the tombstones", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		old := now.Add(-31 * 24 * time.Hour)
+		token := (&syncToken{At: old, ID: updated, Since: old}).encode()
+		_, status, err := phoneBookMock.GetChanges(context.Background(), url.Values{"token": {token}})
+		assert.ErrorIs(t, err, ErrSyncTokenExpired)
+		assert.Equal(t, Gone, status)
+	})
+
+	mt.Run("should expire the tokens issued before a restore", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		token := (&syncToken{At: now, ID: updated, Since: now.Add(-time.Hour)}).encode()
+		mt.AddMockResponses(changes(mt, "tombstones", bson.D{{Key: "_id", Value: syncResetID}, {Key: "deletedAt", Value: now.Add(-time.Minute)}}))
+		_, status, err := phoneBookMock.GetChanges(context.Background(), url.Values{"token": {token}})
+		assert.ErrorIs(t, err, ErrSyncTokenExpired)
+		assert.Equal(t, Gone, status)
+	})
+
+	mt.Run("should reject an invalid token", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, status, err := phoneBookMock.GetChanges(context.Background(), url.Values{"token": {"not a token"}})
+		assert.ErrorIs(t, err, ErrInvalidSyncToken)
+		assert.Equal(t, BadRequest, status)
+	})
+
+	mt.Run("should record a tombstone of the deleted contact", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}}, bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+		_, err := phoneBookMock.DeleteContact(context.Background(), deleted.Hex(), "dani")
+		assert.Nil(t, err)
+		mt.GetStartedEvent()
+		upsert := mt.GetStartedEvent()
+		assert.Equal(t, "tombstones", upsert.Command.Lookup("update").StringValue())
+		update := upsert.Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, deleted, update.Lookup("q", "_id").ObjectID())
+		assert.Equal(t, "dani", update.Lookup("u", "$set", "deletedBy").StringValue())
+	})
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// tenantIDRegex matches the tenant ids, which have to be valid subdomain
+// labels and keep the name of their database short.
+var tenantIDRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// knownTenantTTL is how long a tenant found by LookupTenant is trusted
+// without asking MongoDB again. Tenants deleted, or whose limits changed, on
+// another replica keep resolving as they were here for up to that long.
+const knownTenantTTL = time.Minute
+
+// MongoTenants keeps the tenants in a collection of the main database, and
+// the phone book of each in a database of its own.
+type MongoTenants struct {
+	client       *mongo.Client
+	collection   *mongo.Collection
+	queryTimeout time.Duration
+	// known holds the tenants found, by id, until they should be looked up
+	// again.
+	known sync.Map
+}
+
+type knownTenant struct {
+	tenant *definition.Tenant
+	expiry time.Time
+}
+
+func NewMongoTenants(mongoClient *mongo.Client) *MongoTenants {
+	return &MongoTenants{
+		client:       mongoClient,
+		collection:   mongoClient.Database(config.Static.MongoDBName).Collection(config.Static.MongoTenantsCollection),
+		queryTimeout: config.Static.QueryTimeout,
+	}
+}
+
+// TenantDatabase returns the name of the database of the tenant with the
+// given id.
+func TenantDatabase(id string) string {
+	return config.Static.MongoDBName + "_" + id
+}
+
+func (t *MongoTenants) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, t.queryTimeout)
+}
+
+// CreateTenant provisions a tenant and the indexes of its phone book.
+func (t *MongoTenants) CreateTenant(ctx context.Context, tenant *definition.Tenant) (*definition.Tenant, string, error) {
+	if !tenantIDRegex.MatchString(tenant.ID) {
+		return nil, BadRequest, ErrInvalidTenantID
+	}
+	if !validLimits(&tenant.Limits) {
+		return nil, BadRequest, ErrInvalidLimits
+	}
+	ctx, cancel := t.withQueryTimeout(ctx)
+	defer cancel()
+	tenant.CreatedAt = time.Now().UTC()
+	if _, err := t.collection.InsertOne(ctx, tenant); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, Conflict, ErrTenantExists
+		}
+		return nil, InternalServerError, err
+	}
+	if err := NewMongoPhoneBookIn(t.client, TenantDatabase(tenant.ID)).EnsureIndexes(ctx); err != nil {
+		return nil, InternalServerError, err
+	}
+	return tenant, "", nil
+}
+
+func (t *MongoTenants) GetTenant(ctx context.Context, id string) (*definition.Tenant, string, error) {
+	ctx, cancel := t.withQueryTimeout(ctx)
+	defer cancel()
+	var tenant definition.Tenant
+	err := t.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, NotFound, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	return &tenant, "", nil
+}
+
+// ListTenants returns every tenant, by id.
+func (t *MongoTenants) ListTenants(ctx context.Context) ([]*definition.Tenant, string, error) {
+	ctx, cancel := t.withQueryTimeout(ctx)
+	defer cancel()
+	cursor, err := t.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	defer cursor.Close(ctx)
+	tenants := []*definition.Tenant{}
+	if err := cursor.All(ctx, &tenants); err != nil {
+		return nil, InternalServerError, err
+	}
+	return tenants, "", nil
+}
+
+// DeleteTenant deletes the tenant and drops the database of its phone book.
+func (t *MongoTenants) DeleteTenant(ctx context.Context, id string) (int64, string, error) {
+	ctx, cancel := t.withQueryTimeout(ctx)
+	defer cancel()
+	result, err := t.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return 0, InternalServerError, err
+	}
+	if result.DeletedCount == 0 {
+		return 0, NotFound, ErrTenantNotFound
+	}
+	t.known.Delete(id)
+	if err := t.client.Database(TenantDatabase(id)).Drop(ctx); err != nil {
+		return 0, InternalServerError, err
+	}
+	return result.DeletedCount, "", nil
+}
+
+// SetTenantLimits replaces the limits of the tenant with the given id.
+func (t *MongoTenants) SetTenantLimits(ctx context.Context, id string, limits *definition.TenantLimits) (*definition.Tenant, string, error) {
+	if !validLimits(limits) {
+		return nil, BadRequest, ErrInvalidLimits
+	}
+	ctx, cancel := t.withQueryTimeout(ctx)
+	defer cancel()
+	var tenant definition.Tenant
+	update := bson.M{"$set": bson.M{"limits": limits}}
+	err := t.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, NotFound, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	t.known.Delete(id)
+	return &tenant, "", nil
+}
+
+func validLimits(limits *definition.TenantLimits) bool {
+	return limits.MaxContacts >= 0 && limits.MaxRequestsPerMinute >= 0
+}
+
+// LookupTenant returns the tenant with the given id, nil when there is none,
+// remembering the tenants found for knownTenantTTL since every request asks.
+func (t *MongoTenants) LookupTenant(ctx context.Context, id string) (*definition.Tenant, error) {
+	if !tenantIDRegex.MatchString(id) {
+		return nil, nil
+	}
+	now := time.Now()
+	if known, ok := t.known.Load(id); ok && now.Before(known.(*knownTenant).expiry) {
+		return known.(*knownTenant).tenant, nil
+	}
+	tenant, status, err := t.GetTenant(ctx, id)
+	if status == NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.known.Store(id, &knownTenant{tenant: tenant, expiry: now.Add(knownTenantTTL)})
+	return tenant, nil
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestTenants(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should reject invalid tenant ids", func(mt *mtest.T) {
+		tenants := NewMongoTenants(mt.Client)
+		for _, id := range []string{"", "Acme", "-acme", "acme.corp", "a23456789012345678901234567890123"} {
+			_, status, err := tenants.CreateTenant(context.Background(), &definition.Tenant{ID: id})
+			assert.ErrorIs(t, err, ErrInvalidTenantID, id)
+			assert.Equal(t, BadRequest, status)
+		}
+	})
+
+	mt.Run("should not create a tenant twice", func(mt *mtest.T) {
+		tenants := NewMongoTenants(mt.Client)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}))
+		_, status, err := tenants.CreateTenant(context.Background(), &definition.Tenant{ID: "acme"})
+		assert.ErrorIs(t, err, ErrTenantExists)
+		assert.Equal(t, Conflict, status)
+	})
+
+	mt.Run("should not find unknown tenants", func(mt *mtest.T) {
+		tenants := NewMongoTenants(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		_, status, err := tenants.GetTenant(context.Background(), "acme")
+		assert.ErrorIs(t, err, ErrTenantNotFound)
+		assert.Equal(t, NotFound, status)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		_, status, err = tenants.DeleteTenant(context.Background(), "acme")
+		assert.ErrorIs(t, err, ErrTenantNotFound)
+		assert.Equal(t, NotFound, status)
+	})
+
+	mt.Run("should remember the tenants found", func(mt *mtest.T) {
+		tenants := NewMongoTenants(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "acme"}, {Key: "createdAt", Value: time.Now()}}))
+		for i := 0; i < 2; i++ {
+			tenant, err := tenants.LookupTenant(context.Background(), "acme")
+			assert.Nil(t, err)
+			assert.Equal(t, "acme", tenant.ID)
+		}
+		assert.NotNil(t, mt.GetStartedEvent())
+		assert.Nil(t, mt.GetStartedEvent())
+
+		tenant, err := tenants.LookupTenant(context.Background(), "Not A Tenant")
+		assert.Nil(t, err)
+		assert.Nil(t, tenant)
+	})
+
+	mt.Run("should set the limits of a tenant", func(mt *mtest.T) {
+		tenants := NewMongoTenants(mt.Client)
+		_, status, err := tenants.SetTenantLimits(context.Background(), "acme", &definition.TenantLimits{MaxContacts: -1})
+		assert.ErrorIs(t, err, ErrInvalidLimits)
+		assert.Equal(t, BadRequest, status)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: "acme"}, {Key: "limits", Value: bson.D{{Key: "maxContacts", Value: int64(100)}}}}}))
+		tenant, _, err := tenants.SetTenantLimits(context.Background(), "acme", &definition.TenantLimits{MaxContacts: 100})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(100), tenant.Limits.MaxContacts)
+		update := mt.GetStartedEvent().Command.Lookup("update").Document()
+		assert.Equal(t, int64(100), update.Lookup("$set", "limits", "maxContacts").Int64())
+	})
+
+	mt.Run("should keep the phone book of a tenant in its database", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBookIn(mt.Client, TenantDatabase("acme"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch))
+		phoneBookMock.GetContactsByPhone(context.Background(), "0521234567")
+		assert.Equal(t, config.Static.MongoDBName+"_acme", mt.GetStartedEvent().DatabaseName)
+	})
+}
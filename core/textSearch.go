@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"phoneBook/definition"
+)
+
+const textIndexName = "contacts_text"
+
+// ensureTextIndex creates the text index full-text search runs against.
+func (pb *MongoPhoneBook) ensureTextIndex(ctx context.Context) error {
+	model := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "firstName", Value: "text"},
+			{Key: "lastName", Value: "text"},
+			{Key: "address", Value: "text"},
+			{Key: "notes", Value: "text"},
+		},
+		Options: options.Index().SetName(textIndexName),
+	}
+	_, err := pb.contactsCollection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// SearchContactText returns the contacts matching the words of the q
+// parameter in any of their text fields, best matches first.
+func (pb *MongoPhoneBook) SearchContactText(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	text := query.Get("q")
+	if text == "" {
+		return nil, BadRequest, ErrMissingSearchText
+	}
+	limit, err := validatePageSizeParam(query["pageSize"], pb.tunables().LimitPerPage, pb.tunables().MaxPageSize)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	projection, err := validateFieldsParam(query["fields"])
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	filter := bson.M{"$text": bson.M{"$search": text}}
+	if err := excludeArchived(filter, query); err != nil {
+		return nil, BadRequest, err
+	}
+	score := bson.M{"$meta": "textScore"}
+	if projection == nil {
+		projection = bson.M{}
+	}
+	projection["score"] = score
+	findOptions := options.Find().
+		SetProjection(projection).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(limit)
+	cursor, err := pb.contactsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, InternalServerError, err
+	}
+	contacts, err := decodeContacts(ctx, cursor)
+	if err != nil {
+		return nil, BadRequest, err
+	}
+	if contacts == nil {
+		contacts = []*definition.Contact{}
+	}
+	return contacts, "", nil
+}
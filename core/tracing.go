@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+)
+
+// commandTracer records a client span for every Mongo command, as a child of
+// the span found in the context the command was run with.
+type commandTracer struct {
+	tracer trace.Tracer
+	spans  sync.Map
+}
+
+// NewCommandMonitor returns the Mongo command monitor tracing every command
+// sent by the client it is set on.
+func NewCommandMonitor() *event.CommandMonitor {
+	ct := &commandTracer{tracer: otel.Tracer("phoneBook/core")}
+	return &event.CommandMonitor{
+		Started:   ct.started,
+		Succeeded: ct.succeeded,
+		Failed:    ct.failed,
+	}
+}
+
+func (ct *commandTracer) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	attributes := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBNamespace(evt.DatabaseName),
+		semconv.DBOperationName(evt.CommandName),
+	}
+	if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+		attributes = append(attributes, semconv.DBCollectionName(collection))
+	}
+	_, span := ct.tracer.Start(ctx, "mongo."+evt.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...))
+	ct.spans.Store(evt.RequestID, span)
+}
+
+func (ct *commandTracer) succeeded(_ context.Context, evt *event.CommandSucceededEvent) {
+	if span, ok := ct.spans.LoadAndDelete(evt.RequestID); ok {
+		span.(trace.Span).End()
+	}
+}
+
+func (ct *commandTracer) failed(_ context.Context, evt *event.CommandFailedEvent) {
+	if span, ok := ct.spans.LoadAndDelete(evt.RequestID); ok {
+		span.(trace.Span).SetStatus(codes.Error, evt.Failure)
+		span.(trace.Span).End()
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"testing"
+)
+
+func TestCommandTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ct := &commandTracer{tracer: provider.Tracer("test")}
+	command, _ := bson.Marshal(bson.D{{Key: "find", Value: "contacts"}})
+
+	t.Run("should record command span under the request span", func(t *testing.T) {
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "GET /contact")
+		ct.started(ctx, &event.CommandStartedEvent{Command: command, DatabaseName: "phoneBook", CommandName: "find", RequestID: 1})
+		ct.succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1}})
+		parent.End()
+		spans := recorder.Ended()
+		assert.Equal(t, "mongo.find", spans[0].Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Contains(t, spans[0].Attributes(), semconv.DBCollectionName("contacts"))
+	})
+
+	t.Run("should mark failed command span as error", func(t *testing.T) {
+		ct.started(context.Background(), &event.CommandStartedEvent{Command: command, DatabaseName: "phoneBook", CommandName: "find", RequestID: 2})
+		ct.failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 2}, Failure: "timeout"})
+		spans := recorder.Ended()
+		last := spans[len(spans)-1]
+		assert.Equal(t, codes.Error, last.Status().Code)
+		assert.Equal(t, "timeout", last.Status().Description)
+	})
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"time"
+)
+
+// UpsertContact sets the fields sent in contact on the contact with id like
+// UpdateContact, adding the contact with id when it doesn't exist.
+func (pb *MongoPhoneBook) UpsertContact(ctx context.Context, idParam string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if idParam == "" {
+		return nil, ErrMissingID
+	}
+	id, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	return pb.upsertContact(ctx, bson.M{"_id": id}, id, contact, actor)
+}
+
+// UpsertContactByPhone sets the fields sent in contact on the contact whose
+// phone is number once both are normalized, adding the contact when none is.
+// The phone of contact defaults to the digits of number, and may only write
+// it otherwise.
+func (pb *MongoPhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	if len(number) > config.Static.MaxSizeProperty || !formattedPhoneRegex.MatchString(number) {
+		return nil, ErrInvalidLookup
+	}
+	normalized := normalizePhone(number)
+	if normalized == "" {
+		return nil, ErrInvalidLookup
+	}
+	if contact.Phone == "" {
+		contact.Phone = phoneDigits(number)
+	}
+	if normalizePhone(contact.Phone) != normalized {
+		return nil, ErrPhoneMismatch
+	}
+	filter := bson.M{"normalizedPhone": pb.cipher.phoneIndex(normalized)}
+	return pb.upsertContact(ctx, filter, primitive.NewObjectID(), contact, actor)
+}
+
+// upsertContact sets the fields sent in contact on the contact filter
+// matches, or adds contact with id when none does, for the sync clients
+// keeping contacts by keys of their own. As it may be added, contact is
+// validated whole like by AddContact, and counted against the contact limit
+// when no contact matches.
+func (pb *MongoPhoneBook) upsertContact(ctx context.Context, filter bson.M, id primitive.ObjectID, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	if err := validateContact(contact); err != nil {
+		return nil, err
+	}
+	if definition.ContactLimitFromContext(ctx) > 0 {
+		existing, err := pb.contactsCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		if existing == 0 {
+			if err := pb.checkContactLimit(ctx, 1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	now := time.Now().UTC()
+	contact.ID = primitive.NilObjectID
+	contact.Version = 0
+	contact.Relations = nil
+	contact.Archived = false
+	contact.SortWeight = 0
+	contact.Blocked = false
+	contact.Owner = ""
+	contact.CreatedAt = nil
+	contact.UpdatedAt = &now
+	deriveFields(contact)
+	onInsert := bson.M{"createdAt": now, "owner": definition.UserFromContext(ctx), "schemaVersion": schemaVersion}
+	if _, ok := filter["_id"]; !ok {
+		onInsert["_id"] = id
+	}
+	update := bson.M{"$set": contact, "$setOnInsert": onInsert, "$inc": bson.M{"version": 1}}
+	unsetMissingPhoneInfo(update, contact)
+	upsert := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	var before *definition.Contact
+	err := pb.contactsCollection.FindOneAndUpdate(ctx, filter, update, upsert).Decode(&before)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateContact
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if pb.auditLog != nil {
+			contact.ID = id
+			contact.Version = 1
+			contact.Owner = definition.UserFromContext(ctx)
+			contact.CreatedAt = &now
+			pb.auditLog.Record(ctx, definition.AuditActionAdd, actor, id, nil, contact)
+		}
+		return &definition.WriteResult{ID: id.Hex(), Created: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pb.recordUpdate(ctx, before.ID, before, true, actor)
+	return &definition.WriteResult{ID: before.ID.Hex(), Matched: 1, Modified: 1}, nil
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestUpsertContact(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+
+	mt.Run("should add the contact with the id when it doesn't exist", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+		result, err := phoneBookMock.UpsertContact(context.Background(), id.Hex(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{ID: id.Hex(), Created: true}, result)
+		command := mt.GetStartedEvent().Command
+		assert.True(t, command.Lookup("upsert").Boolean())
+		assert.Equal(t, id, command.Lookup("query", "_id").ObjectID())
+		assert.Equal(t, "dani", command.Lookup("update", "$set", "displayName").StringValue())
+		assert.Equal(t, int32(1), command.Lookup("update", "$inc", "version").Int32())
+	})
+
+	mt.Run("should update the contact with the id", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		stored := bson.D{{Key: "_id", Value: id}, {Key: "firstName", Value: "Dani"}, {Key: "phone", Value: "0521234567"}, {Key: "version", Value: int64(2)}}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: stored}),
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, stored),
+			mtest.CreateSuccessResponse())
+		result, err := phoneBookMock.UpsertContact(context.Background(), id.Hex(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.WriteResult{ID: id.Hex(), Matched: 1, Modified: 1}, result)
+	})
+
+	mt.Run("should validate the contact whole", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.UpsertContact(context.Background(), id.Hex(), &definition.Contact{LastName: "Cohen"}, "tester")
+		assert.ErrorIs(t, err, ErrMissingFirstName)
+		assert.Equal(t, BadRequest, StatusOf(err))
+	})
+
+	mt.Run("should refuse adding a contact over the limit", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ctx := definition.WithContactLimit(context.Background(), 10)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch, bson.D{{Key: "n", Value: int32(0)}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: int32(10)}))
+		_, err := phoneBookMock.UpsertContact(ctx, id.Hex(), &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "tester")
+		assert.ErrorIs(t, err, ErrContactLimit)
+	})
+}
+
+func TestUpsertContactByPhone(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should add the contact with the number", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+		contact := &definition.Contact{FirstName: "Dani"}
+		result, err := phoneBookMock.UpsertContactByPhone(context.Background(), "+972 52-123-4567", contact, "tester")
+		assert.Nil(t, err)
+		assert.True(t, result.Created)
+		assert.Equal(t, "972521234567", contact.Phone)
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, "972521234567", command.Lookup("query", "normalizedPhone").StringValue())
+		assert.Equal(t, result.ID, command.Lookup("update", "$setOnInsert", "_id").ObjectID().Hex())
+	})
+
+	mt.Run("should keep the phone to the number", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		_, err := phoneBookMock.UpsertContactByPhone(context.Background(), "0521234567", &definition.Contact{FirstName: "Dani", Phone: "0541234567"}, "tester")
+		assert.ErrorIs(t, err, ErrPhoneMismatch)
+		_, err = phoneBookMock.UpsertContactByPhone(context.Background(), "phone", &definition.Contact{FirstName: "Dani"}, "tester")
+		assert.ErrorIs(t, err, ErrInvalidLookup)
+	})
+}
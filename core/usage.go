@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"phoneBook/config"
+	"phoneBook/definition"
+)
+
+// GetUsage counts the documents of the phone book and measures its database.
+// Counts come from the collections metadata, so they may be slightly off
+// after an unclean shutdown.
+func (pb *MongoPhoneBook) GetUsage(ctx context.Context) (*definition.Usage, string, error) {
+	ctx, cancel := pb.withQueryTimeout(ctx)
+	defer cancel()
+	db := pb.contactsCollection.Database()
+	usage := &definition.Usage{}
+	for _, counted := range []struct {
+		collection string
+		count      *int64
+	}{
+		{config.Static.MongoCollectionName, &usage.Contacts},
+		{config.Static.MongoInteractionsCollection, &usage.Interactions},
+		{config.Static.MongoRemindersCollection, &usage.Reminders},
+		{config.Static.MongoAuditCollectionName, &usage.HistoryEntries},
+	} {
+		n, err := db.Collection(counted.collection).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, InternalServerError, err
+		}
+		*counted.count = n
+	}
+	var stats struct {
+		DataSize    float64 `bson:"dataSize"`
+		StorageSize float64 `bson:"storageSize"`
+		IndexSize   float64 `bson:"indexSize"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "dbStats", Value: 1}}).Decode(&stats); err != nil {
+		return nil, InternalServerError, err
+	}
+	usage.DataBytes = int64(stats.DataSize)
+	usage.StorageBytes = int64(stats.StorageSize)
+	usage.IndexBytes = int64(stats.IndexSize)
+	return usage, "", nil
+}
+
+// checkContactLimit refuses adding count contacts when they would take the
+// phone book over the contact limit of ctx, if any.
+func (pb *MongoPhoneBook) checkContactLimit(ctx context.Context, count int64) error {
+	max := definition.ContactLimitFromContext(ctx)
+	if max <= 0 {
+		return nil
+	}
+	contacts, err := pb.contactsCollection.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return err
+	}
+	if contacts+count > max {
+		return ErrContactLimit
+	}
+	return nil
+}
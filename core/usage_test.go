@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestGetUsage(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should count the documents and measure the database", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		for _, n := range []int32{12, 3, 2, 20} {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}))
+		}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "dataSize", Value: 4096.0}, bson.E{Key: "storageSize", Value: int64(8192)}, bson.E{Key: "indexSize", Value: int32(1024)}))
+		usage, _, err := phoneBookMock.GetUsage(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.Usage{Contacts: 12, Interactions: 3, Reminders: 2, HistoryEntries: 20,
+			DataBytes: 4096, StorageBytes: 8192, IndexBytes: 1024}, usage)
+	})
+}
+
+func TestContactLimit(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should refuse contacts over the limit", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ctx := definition.WithContactLimit(context.Background(), 10)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: int32(10)}))
+		_, err := phoneBookMock.AddContact(ctx, &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "tester")
+		assert.ErrorIs(t, err, ErrContactLimit)
+		assert.Equal(t, PaymentRequired, StatusOf(err))
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: int32(5)}))
+		_, err = phoneBookMock.SeedContacts(ctx, 6, "tester")
+		assert.ErrorIs(t, err, ErrContactLimit)
+		assert.Equal(t, PaymentRequired, StatusOf(err))
+	})
+
+	mt.Run("should add contacts under the limit", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		ctx := definition.WithContactLimit(context.Background(), 10)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: int32(9)}), mtest.CreateSuccessResponse())
+		_, err := phoneBookMock.AddContact(ctx, &definition.Contact{FirstName: "Dani", Phone: "0521234567"}, "tester")
+		assert.Nil(t, err)
+	})
+}
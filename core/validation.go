@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"strings"
+)
+
+// maxReportedIssues bounds the issues a validation report lists.
+const maxReportedIssues = 1000
+
+// validatedFields are the contact fields a validation checks, in the order
+// their issues are reported.
+var validatedFields = []string{"firstName", "lastName", "phone", "email", "company", "jobTitle", "address", "notes"}
+
+// ValidateContacts checks every stored contact against the rules new
+// contacts are validated by, like contacts imported from legacy systems or
+// stored before a rule existed. With fix, the fields normalizing repairs are
+// patched, so the repairs are versioned and audited like any update. A
+// contact changed meanwhile, or whose repair would duplicate another, is
+// left as is and reported unfixed.
+func (pb *MongoPhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	report := &definition.ValidationReport{Issues: []definition.ContactIssue{}}
+	cursor, err := pb.contactsCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var contact definition.Contact
+		if err := cursor.Decode(&contact); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+		issues, patch := contactIssues(&contact)
+		if len(issues) == 0 {
+			continue
+		}
+		report.Invalid++
+		if fix && len(patch) > 0 {
+			updated, err := pb.PatchContact(ctx, contact.ID.Hex(), patch, contact.Version, actor)
+			if err != nil && StatusOf(err) != Conflict {
+				return nil, err
+			}
+			if updated != nil && updated.Modified > 0 {
+				report.Fixed++
+				for i := range issues {
+					issues[i].Fixed = issues[i].Fixable
+				}
+			}
+		}
+		for _, issue := range issues {
+			if len(report.Issues) == maxReportedIssues {
+				report.Truncated = true
+				break
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// contactIssues returns the validation issues of contact, and the patch
+// repairing the fixable ones.
+func contactIssues(contact *definition.Contact) ([]definition.ContactIssue, definition.ContactPatch) {
+	var issues []definition.ContactIssue
+	patch := definition.ContactPatch{}
+	fields := editableFields(contact)
+	for _, field := range validatedFields {
+		value := *fields[field]
+		rule := fieldIssue(field, value)
+		if rule == nil {
+			continue
+		}
+		issue := definition.ContactIssue{ID: contact.ID.Hex(), Field: field, Code: rule.Code, Message: rule.Message}
+		if normalized := normalizeField(field, value); normalized != value && fieldIssue(field, normalized) == nil {
+			issue.Fixable = true
+			patch[field] = &normalized
+		}
+		issues = append(issues, issue)
+	}
+	return issues, patch
+}
+
+// fieldIssue returns the rule value breaks as the field of a contact, nil
+// when valid.
+func fieldIssue(field string, value string) *definition.Error {
+	if len(value) > config.Static.MaxSizeProperty {
+		return ErrFieldTooLong.WithField(field)
+	}
+	switch {
+	case value == "" && field == "firstName":
+		return ErrMissingFirstName
+	case value == "" && field == "phone":
+		return ErrMissingPhone
+	case value == "":
+		return nil
+	}
+	var rule *definition.Error
+	if errors.As(validatePatchField(field, &value), &rule) {
+		return rule
+	}
+	return nil
+}
+
+// normalizeField returns value without the formatting legacy systems tend
+// to add: surrounding blanks, line breaks in single line fields and the
+// separators of phone numbers.
+func normalizeField(field string, value string) string {
+	switch field {
+	case "phone":
+		value = strings.TrimSpace(value)
+		if !formattedPhoneRegex.MatchString(value) {
+			return value
+		}
+		return strings.Map(func(r rune) rune {
+			if r < '0' || r > '9' {
+				return -1
+			}
+			return r
+		}, value)
+	case "address", "notes":
+		return strings.TrimSpace(value)
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
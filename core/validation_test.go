@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"phoneBook/definition"
+	"testing"
+)
+
+func TestContactIssues(t *testing.T) {
+	t.Run("should report the broken rules and repair the normalizable ones", func(t *testing.T) {
+		contact := &definition.Contact{FirstName: " Dani\n", LastName: "Co hen", Phone: "+972 52-123-4567", Email: "dani@example.com"}
+		issues, patch := contactIssues(contact)
+		assert.Equal(t, []definition.ContactIssue{
+			{Field: "firstName", Code: "INVALID_FIRST_NAME", Message: ErrorInvalidFirstName, Fixable: true},
+			{Field: "lastName", Code: "INVALID_LAST_NAME", Message: ErrorInvalidLastName},
+			{Field: "phone", Code: "INVALID_PHONE", Message: ErrorInvalidPhone, Fixable: true},
+		}, stripIDs(issues))
+		dani, phone := "Dani", "972521234567"
+		assert.Equal(t, definition.ContactPatch{"firstName": &dani, "phone": &phone}, patch)
+	})
+
+	t.Run("should report missing required fields as unfixable", func(t *testing.T) {
+		issues, patch := contactIssues(&definition.Contact{FirstName: "Dani"})
+		assert.Equal(t, []definition.ContactIssue{{Field: "phone", Code: "MISSING_PHONE", Message: ErrorMissingPhone}}, stripIDs(issues))
+		assert.Empty(t, patch)
+	})
+
+	t.Run("should find nothing in a valid contact", func(t *testing.T) {
+		issues, _ := contactIssues(&definition.Contact{FirstName: "Dani", Phone: "0521234567", Company: "Acme"})
+		assert.Empty(t, issues)
+	})
+}
+
+func stripIDs(issues []definition.ContactIssue) []definition.ContactIssue {
+	for i := range issues {
+		issues[i].ID = ""
+	}
+	return issues
+}
+
+func TestValidateContacts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("should repair the fixable contacts through a patch", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		valid, invalid := primitive.NewObjectID(), primitive.NewObjectID()
+		namespace := fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: valid}, {Key: "firstName", Value: "Dani"}, {Key: "phone", Value: "0521234567"}},
+				bson.D{{Key: "_id", Value: invalid}, {Key: "firstName", Value: "Noa"}, {Key: "phone", Value: "052-765-4321"}, {Key: "version", Value: int64(3)}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		report, err := phoneBookMock.ValidateContacts(context.Background(), true, "admin")
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.ValidationReport{Scanned: 2, Invalid: 1, Fixed: 1, Issues: []definition.ContactIssue{
+			{ID: invalid.Hex(), Field: "phone", Code: "INVALID_PHONE", Message: ErrorInvalidPhone, Fixable: true, Fixed: true},
+		}}, report)
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, int64(3), update.Lookup("q", "version").Int64())
+		assert.Equal(t, "0527654321", update.Lookup("u", "$set", "phone").StringValue())
+	})
+
+	mt.Run("should only report without fix", func(mt *mtest.T) {
+		phoneBookMock := NewMongoPhoneBook(mt.Client)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, fmt.Sprintf("%s.%s", mt.DB.Name(), mt.Coll.Name()), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "firstName", Value: "Noa"}, {Key: "phone", Value: "052-765-4321"}}))
+		report, err := phoneBookMock.ValidateContacts(context.Background(), false, "admin")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), report.Fixed)
+		assert.False(t, report.Issues[0].Fixed)
+		mt.GetStartedEvent()
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
@@ -0,0 +1,120 @@
+// Package crm pushes the contact changes of the phone book to external CRMs,
+// HubSpot or Salesforce, through their REST APIs.
+package crm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	ProviderHubSpot    = "hubspot"
+	ProviderSalesforce = "salesforce"
+)
+
+// Connector writes contacts to the records of a CRM, identified by the id of
+// the contact kept in a unique property of the records. Properties are keyed
+// by their name in the CRM, an empty value clearing the property.
+type Connector interface {
+	Upsert(ctx context.Context, id string, properties map[string]string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// DefaultFieldMappings map the contact fields to the properties of the
+// contact records of each CRM, unless a mapping is configured.
+var DefaultFieldMappings = map[string]map[string]string{
+	ProviderHubSpot: {
+		"firstName": "firstname",
+		"lastName":  "lastname",
+		"phone":     "phone",
+		"email":     "email",
+		"company":   "company",
+		"jobTitle":  "jobtitle",
+		"address":   "address",
+	},
+	ProviderSalesforce: {
+		"firstName": "FirstName",
+		"lastName":  "LastName",
+		"phone":     "Phone",
+		"email":     "Email",
+		"jobTitle":  "Title",
+		"address":   "MailingStreet",
+		"notes":     "Description",
+	},
+}
+
+// DefaultIDProperties are the properties of the CRM records holding the
+// contact id, unless another is configured. They have to be created in the
+// CRM as unique properties, an external id field in Salesforce.
+var DefaultIDProperties = map[string]string{
+	ProviderHubSpot:    "phonebook_id",
+	ProviderSalesforce: "PhoneBook_Id__c",
+}
+
+// ResponseError is a request the CRM refused. RetryAfter is how long it asked
+// to wait before retrying, when rate limited.
+type ResponseError struct {
+	Status     int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("crm responded %d: %s", e.Status, e.Message)
+}
+
+// RateLimited reports whether the CRM refused the request for exceeding its
+// rate limit.
+func (e *ResponseError) RateLimited() bool {
+	return e.Status == http.StatusTooManyRequests
+}
+
+// Permanent reports whether retrying the request would fail again, the CRM
+// refusing the request itself rather than being unavailable.
+func (e *ResponseError) Permanent() bool {
+	return e.Status >= 400 && e.Status < 500 && e.Status != http.StatusRequestTimeout && !e.RateLimited()
+}
+
+// do sends a JSON request, decoding the response into result unless it's
+// nil. Responses without a 2xx status fail with a ResponseError.
+func do(ctx context.Context, client *http.Client, method, url string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		responseErr := &ResponseError{Status: response.StatusCode, Message: string(bytes.TrimSpace(message))}
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			responseErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return responseErr
+	}
+	if result == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
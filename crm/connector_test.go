@@ -0,0 +1,114 @@
+package crm
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// request is a request received by a fake CRM.
+type request struct {
+	method, path, authorization, body string
+}
+
+// fakeCRM records the requests and answers them with handle.
+func fakeCRM(t *testing.T, handle func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, *[]request) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), string(body)})
+		handle(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHubSpot(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should upsert by the id property", func(t *testing.T) {
+		server, requests := fakeCRM(t, func(w http.ResponseWriter, r *http.Request) {})
+		connector := NewHubSpot(server.URL, "pat-token", "phonebook_id")
+		assert.Nil(t, connector.Upsert(ctx, "65a1", map[string]string{"firstname": "Dani", "jobtitle": ""}))
+		assert.Equal(t, []request{{"POST", "/crm/v3/objects/contacts/batch/upsert", "Bearer pat-token",
+			`{"inputs":[{"id":"65a1","idProperty":"phonebook_id","properties":{"firstname":"Dani","jobtitle":""}}]}`}}, *requests)
+	})
+
+	t.Run("should archive the contact found by the id property", func(t *testing.T) {
+		server, requests := fakeCRM(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`{"id":"901","properties":{}}`))
+			} else {
+				w.WriteHeader(http.StatusNoContent)
+			}
+		})
+		connector := NewHubSpot(server.URL, "pat-token", "phonebook_id")
+		assert.Nil(t, connector.Delete(ctx, "65a1"))
+		assert.Len(t, *requests, 2)
+		assert.Equal(t, "/crm/v3/objects/contacts/65a1?idProperty=phonebook_id&properties=phonebook_id", (*requests)[0].path)
+		assert.Equal(t, request{"DELETE", "/crm/v3/objects/contacts/901", "Bearer pat-token", ""}, (*requests)[1])
+	})
+
+	t.Run("should take missing contacts as deleted", func(t *testing.T) {
+		server, _ := fakeCRM(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		assert.Nil(t, NewHubSpot(server.URL, "pat-token", "phonebook_id").Delete(ctx, "65a1"))
+	})
+
+	t.Run("should report the rate limit", func(t *testing.T) {
+		server, _ := fakeCRM(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "10")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		err := NewHubSpot(server.URL, "pat-token", "phonebook_id").Upsert(ctx, "65a1", nil)
+		assert.Equal(t, &ResponseError{Status: 429, RetryAfter: 10 * time.Second, Message: ""}, err)
+		assert.True(t, err.(*ResponseError).RateLimited())
+		assert.False(t, err.(*ResponseError).Permanent())
+	})
+}
+
+func TestSalesforce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should upsert by the external id, renewing expired tokens", func(t *testing.T) {
+		tokens := 0
+		server, requests := fakeCRM(t, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/services/oauth2/token":
+				tokens++
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]string{"access_token": "token-" + strconv.Itoa(tokens), "token_type": "Bearer"})
+			case r.Header.Get("Authorization") == "Bearer token-1":
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				w.WriteHeader(http.StatusCreated)
+			}
+		})
+		connector := NewSalesforce(server.URL+"/", "client", "secret", "PhoneBook_Id__c")
+		assert.Nil(t, connector.Upsert(ctx, "65a1", map[string]string{"FirstName": "Dani", "Title": ""}))
+		assert.Len(t, *requests, 4)
+		upsert := request{"PATCH", "/services/data/v59.0/sobjects/Contact/PhoneBook_Id__c/65a1", "Bearer token-2", `{"FirstName":"Dani","Title":null}`}
+		assert.Equal(t, upsert, (*requests)[3])
+	})
+
+	t.Run("should take the exhausted api requests as a rate limit", func(t *testing.T) {
+		server, _ := fakeCRM(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/services/oauth2/token" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"token","token_type":"Bearer"}`))
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`[{"errorCode":"REQUEST_LIMIT_EXCEEDED","message":"TotalRequests Limit exceeded."}]`))
+		})
+		err := NewSalesforce(server.URL, "client", "secret", "PhoneBook_Id__c").Delete(ctx, "65a1")
+		assert.True(t, err.(*ResponseError).RateLimited())
+	})
+}
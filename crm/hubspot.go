@@ -0,0 +1,68 @@
+package crm
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultHubSpotURL is the base url of the HubSpot API.
+const DefaultHubSpotURL = "https://api.hubapi.com"
+
+// hubSpotConnector writes the contacts to the contact objects of HubSpot
+// through the CRM API v3, authenticated with the access token of a private
+// app.
+type hubSpotConnector struct {
+	baseURL    string
+	idProperty string
+	client     *http.Client
+}
+
+// NewHubSpot returns a connector to the HubSpot API at baseURL, keeping the
+// contact ids in the unique property idProperty.
+func NewHubSpot(baseURL, token, idProperty string) Connector {
+	client := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	return &hubSpotConnector{baseURL: strings.TrimSuffix(baseURL, "/"), idProperty: idProperty, client: client}
+}
+
+type hubSpotUpsert struct {
+	Inputs []hubSpotInput `json:"inputs"`
+}
+
+type hubSpotInput struct {
+	ID         string            `json:"id"`
+	IDProperty string            `json:"idProperty"`
+	Properties map[string]string `json:"properties"`
+}
+
+// Upsert creates or updates the contact object whose id property is id.
+func (c *hubSpotConnector) Upsert(ctx context.Context, id string, properties map[string]string) error {
+	body := hubSpotUpsert{Inputs: []hubSpotInput{{ID: id, IDProperty: c.idProperty, Properties: properties}}}
+	return do(ctx, c.client, http.MethodPost, c.baseURL+"/crm/v3/objects/contacts/batch/upsert", body, nil)
+}
+
+// Delete archives the contact object whose id property is id, found first
+// as archiving takes the object id.
+func (c *hubSpotConnector) Delete(ctx context.Context, id string) error {
+	var object struct {
+		ID string `json:"id"`
+	}
+	query := url.Values{"idProperty": {c.idProperty}, "properties": {c.idProperty}}
+	err := do(ctx, c.client, http.MethodGet, c.baseURL+"/crm/v3/objects/contacts/"+url.PathEscape(id)+"?"+query.Encode(), nil, &object)
+	if err == nil {
+		err = do(ctx, c.client, http.MethodDelete, c.baseURL+"/crm/v3/objects/contacts/"+url.PathEscape(object.ID), nil, nil)
+	}
+	return ignoreNotFound(err)
+}
+
+// ignoreNotFound treats the records missing from the CRM as deleted.
+func ignoreNotFound(err error) error {
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) && responseErr.Status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package crm
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/oauth2/clientcredentials"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SalesforceAPIVersion is the version of the Salesforce REST API called.
+const SalesforceAPIVersion = "v59.0"
+
+// salesforceConnector writes the contacts to the Contact objects of a
+// Salesforce org through the REST API, authenticated by the OAuth client
+// credentials flow of a connected app.
+type salesforceConnector struct {
+	instanceURL string
+	idField     string
+	credentials *clientcredentials.Config
+
+	mu     sync.Mutex
+	client *http.Client
+}
+
+// NewSalesforce returns a connector to the Salesforce org at instanceURL,
+// keeping the contact ids in the external id field idField.
+func NewSalesforce(instanceURL, clientID, clientSecret, idField string) Connector {
+	instanceURL = strings.TrimSuffix(instanceURL, "/")
+	credentials := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     instanceURL + "/services/oauth2/token",
+	}
+	return &salesforceConnector{instanceURL: instanceURL, idField: idField, credentials: credentials}
+}
+
+// Upsert creates or updates the Contact whose external id is id, the empty
+// properties cleared.
+func (c *salesforceConnector) Upsert(ctx context.Context, id string, properties map[string]string) error {
+	fields := map[string]interface{}{}
+	for name, value := range properties {
+		if value == "" {
+			fields[name] = nil
+		} else {
+			fields[name] = value
+		}
+	}
+	return c.do(ctx, http.MethodPatch, id, fields)
+}
+
+// Delete deletes the Contact whose external id is id.
+func (c *salesforceConnector) Delete(ctx context.Context, id string) error {
+	return ignoreNotFound(c.do(ctx, http.MethodDelete, id, nil))
+}
+
+// do sends a request for the Contact whose external id is id. Salesforce
+// issues access tokens without an expiry, so an expired one is only noticed
+// by a 401, which gets a new token and sends the request again.
+func (c *salesforceConnector) do(ctx context.Context, method, id string, body interface{}) error {
+	contactURL := c.instanceURL + "/services/data/" + SalesforceAPIVersion + "/sobjects/Contact/" + url.PathEscape(c.idField) + "/" + url.PathEscape(id)
+	err := do(ctx, c.httpClient(false), method, contactURL, body, nil)
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) && responseErr.Status == http.StatusUnauthorized {
+		err = do(ctx, c.httpClient(true), method, contactURL, body, nil)
+	}
+	if errors.As(err, &responseErr) && responseErr.Status == http.StatusForbidden && strings.Contains(responseErr.Message, "REQUEST_LIMIT_EXCEEDED") {
+		// the API requests of the org ran out, like a rate limit
+		responseErr.Status = http.StatusTooManyRequests
+	}
+	return err
+}
+
+// httpClient returns the client authenticating the requests, with a new
+// token when renew is set.
+func (c *salesforceConnector) httpClient(renew bool) *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil || renew {
+		c.client = c.credentials.Client(context.Background())
+	}
+	return c.client
+}
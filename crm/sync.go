@@ -0,0 +1,259 @@
+package crm
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"sync"
+	"time"
+)
+
+// maxBackoff bounds the wait between the attempts of a contact, and is the
+// pause of the sync when rate limited without being told how long for.
+const maxBackoff = time.Minute
+
+var (
+	synced = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_crm_synced_total",
+		Help: "Contact changes pushed to the CRM.",
+	})
+	deadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_crm_dead_letters_total",
+		Help: "Contacts that failed to sync to the CRM and were dead lettered.",
+	})
+	rateLimited = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_crm_rate_limited_total",
+		Help: "Requests the CRM refused for exceeding its rate limit.",
+	})
+)
+
+// Options tune the sync to a CRM.
+type Options struct {
+	// Mapping maps the contact fields to the properties of the CRM records.
+	Mapping map[string]string
+	// RequestsPerSecond is the rate of requests to the CRM.
+	RequestsPerSecond float64
+	// QueueSize is the number of contacts waiting to be synced, beyond which
+	// changes are dead lettered.
+	QueueSize int
+	// MaxAttempts is the number of attempts to sync a contact before it's
+	// dead lettered, Backoff the wait after the first failure, doubling
+	// after each.
+	MaxAttempts int
+	Backoff     time.Duration
+	// Timeout bounds each request.
+	Timeout time.Duration
+	// MaxDeadLetters is the number of dead letters kept, the oldest dropped
+	// beyond.
+	MaxDeadLetters int
+}
+
+// Sync pushes the changes of the contacts of the default phone book to a
+// CRM. It is a sink of the phone book events, queueing the ids of the changed
+// contacts, and pushes each contact as stored when its turn comes, so several
+// changes to a contact in a row sync once. Deleted contacts are deleted from
+// the CRM. Contacts that fail to sync are retried with a backoff, and dead
+// lettered once the attempts run out or the CRM refuses them, until they are
+// retried by hand.
+type Sync struct {
+	phoneBook definition.IPhoneBook
+	connector Connector
+	options   Options
+	limiter   *rate.Limiter
+
+	mu          sync.Mutex
+	queued      map[string]bool
+	deadLetters []*definition.CRMDeadLetter
+	queue       chan string
+
+	// ctx is canceled by Stop.
+	ctx  context.Context
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// NewSync returns a sync of the contacts of phoneBook to the CRM of
+// connector. Call Start to start syncing.
+func NewSync(phoneBook definition.IPhoneBook, connector Connector, options Options) *Sync {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Sync{
+		phoneBook: phoneBook,
+		connector: connector,
+		options:   options,
+		limiter:   rate.NewLimiter(rate.Limit(options.RequestsPerSecond), 1),
+		queued:    map[string]bool{},
+		queue:     make(chan string, options.QueueSize),
+		ctx:       ctx,
+		stop:      stop,
+	}
+}
+
+// Send queues the contact of the event to sync. Tenants have their own
+// contacts, not synced, and imports name no contacts.
+func (s *Sync) Send(ctx context.Context, event *events.Event) {
+	if event.Tenant != "" || event.ContactID == "" {
+		return
+	}
+	s.enqueue(event.ContactID)
+}
+
+func (s *Sync) enqueue(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queued[id] {
+		return true
+	}
+	select {
+	case s.queue <- id:
+		s.queued[id] = true
+		return true
+	default:
+		s.deadLetter(id, errors.New("the sync queue is full"), 0)
+		return false
+	}
+}
+
+// Start syncs the queued contacts in the background, one at a time.
+func (s *Sync) Start() {
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case id := <-s.queue:
+				s.mu.Lock()
+				delete(s.queued, id)
+				s.mu.Unlock()
+				s.sync(id)
+			}
+		}
+	}()
+}
+
+// Stop waits for the contact being synced, if any, and stops the sync. The
+// contacts still queued are not synced.
+func (s *Sync) Stop() {
+	s.stop()
+	s.done.Wait()
+	if pending := len(s.queue); pending > 0 {
+		logrus.Warnf("stopped syncing to the CRM with %d contacts queued", pending)
+	}
+}
+
+// sync pushes the contact with id to the CRM, until it succeeds, fails for
+// good or the sync is stopped. The attempts the CRM rate limited don't count.
+func (s *Sync) sync(id string) {
+	backoff := s.options.Backoff
+	for attempt := 1; ; {
+		if err := s.limiter.Wait(s.ctx); err != nil {
+			return
+		}
+		err := s.push(id)
+		if err == nil {
+			synced.Inc()
+			return
+		}
+		var responseErr *ResponseError
+		isResponseErr := errors.As(err, &responseErr)
+		wait := backoff
+		if isResponseErr && responseErr.RateLimited() {
+			rateLimited.Inc()
+			wait = responseErr.RetryAfter
+			if wait == 0 {
+				wait = maxBackoff
+			}
+		} else {
+			if (isResponseErr && responseErr.Permanent()) || attempt >= s.options.MaxAttempts {
+				s.mu.Lock()
+				s.deadLetter(id, err, attempt)
+				s.mu.Unlock()
+				return
+			}
+			attempt++
+			backoff = min(2*backoff, maxBackoff)
+		}
+		logrus.WithError(err).WithField("contactId", id).Warnf("failed to sync contact to the CRM, retrying in %s", wait)
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// push writes the contact with id to the CRM as stored, or deletes it from
+// the CRM when it was deleted.
+func (s *Sync) push(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.Timeout)
+	defer cancel()
+	contact, status, err := s.phoneBook.GetContact(ctx, id)
+	if status == core.NotFound {
+		return s.connector.Delete(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	fields := map[string]string{
+		"firstName": contact.FirstName,
+		"lastName":  contact.LastName,
+		"phone":     contact.Phone,
+		"email":     contact.Email,
+		"company":   contact.Company,
+		"jobTitle":  contact.JobTitle,
+		"address":   contact.Address,
+		"notes":     contact.Notes,
+	}
+	properties := map[string]string{}
+	for field, property := range s.options.Mapping {
+		properties[property] = fields[field]
+	}
+	return s.connector.Upsert(ctx, id, properties)
+}
+
+// deadLetter keeps the contact with id as failed to sync, replacing its
+// former dead letter. Call it holding mu.
+func (s *Sync) deadLetter(id string, err error, attempts int) {
+	deadLettered.Inc()
+	logrus.WithError(err).WithField("contactId", id).Error("failed to sync contact to the CRM, dead lettered")
+	for i, letter := range s.deadLetters {
+		if letter.ContactID == id {
+			s.deadLetters = append(s.deadLetters[:i], s.deadLetters[i+1:]...)
+			break
+		}
+	}
+	s.deadLetters = append(s.deadLetters, &definition.CRMDeadLetter{ContactID: id, Error: err.Error(), Attempts: attempts, FailedAt: time.Now().UTC()})
+	if len(s.deadLetters) > s.options.MaxDeadLetters {
+		s.deadLetters = s.deadLetters[len(s.deadLetters)-s.options.MaxDeadLetters:]
+	}
+}
+
+// DeadLetters returns the contacts that failed to sync, oldest first.
+func (s *Sync) DeadLetters() []*definition.CRMDeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*definition.CRMDeadLetter{}, s.deadLetters...)
+}
+
+// RetryDeadLetters queues the dead lettered contacts to sync again, as many
+// as the queue has room for.
+func (s *Sync) RetryDeadLetters() int {
+	letters := s.DeadLetters()
+	s.mu.Lock()
+	s.deadLetters = nil
+	s.mu.Unlock()
+	retried := 0
+	for _, letter := range letters {
+		if s.enqueue(letter.ContactID) {
+			retried++
+		}
+	}
+	return retried
+}
@@ -0,0 +1,146 @@
+package crm
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+// syncPhoneBook serves a contact, the others being deleted.
+type syncPhoneBook struct {
+	definition.IPhoneBook
+	contact *definition.Contact
+}
+
+func (pb *syncPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	if id != pb.contact.ID.Hex() {
+		return nil, core.NotFound, core.ErrContactNotFound
+	}
+	return pb.contact, "", nil
+}
+
+// recordingConnector records the requests, failing them with errs first.
+type recordingConnector struct {
+	errs     []error
+	upserts  []map[string]string
+	deletes  []string
+	attempts int
+}
+
+func (c *recordingConnector) fail() error {
+	c.attempts++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *recordingConnector) Upsert(ctx context.Context, id string, properties map[string]string) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	c.upserts = append(c.upserts, properties)
+	return nil
+}
+
+func (c *recordingConnector) Delete(ctx context.Context, id string) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	c.deletes = append(c.deletes, id)
+	return nil
+}
+
+func newTestSync(connector Connector, contact *definition.Contact) *Sync {
+	return NewSync(&syncPhoneBook{contact: contact}, connector, Options{
+		Mapping:           map[string]string{"firstName": "firstname", "phone": "phone", "jobTitle": "jobtitle"},
+		RequestsPerSecond: 1000,
+		QueueSize:         2,
+		MaxAttempts:       3,
+		Backoff:           time.Millisecond,
+		Timeout:           time.Second,
+		MaxDeadLetters:    10,
+	})
+}
+
+func TestSync(t *testing.T) {
+	contact := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Dani", Phone: "0521234567", Company: "Acme"}
+	id := contact.ID.Hex()
+
+	t.Run("should push the mapped fields of the contact", func(t *testing.T) {
+		connector := &recordingConnector{}
+		newTestSync(connector, contact).sync(id)
+		assert.Equal(t, []map[string]string{{"firstname": "Dani", "phone": "0521234567", "jobtitle": ""}}, connector.upserts)
+	})
+
+	t.Run("should delete the deleted contacts", func(t *testing.T) {
+		connector := &recordingConnector{}
+		deleted := primitive.NewObjectID().Hex()
+		newTestSync(connector, contact).sync(deleted)
+		assert.Equal(t, []string{deleted}, connector.deletes)
+	})
+
+	t.Run("should queue each contact once and skip the tenants", func(t *testing.T) {
+		sync := newTestSync(&recordingConnector{}, contact)
+		sync.Send(context.Background(), &events.Event{Type: events.ContactCreated, ContactID: id})
+		sync.Send(context.Background(), &events.Event{Type: events.ContactPatched, ContactID: id})
+		sync.Send(context.Background(), &events.Event{Type: events.ContactCreated, ContactID: id, Tenant: "acme"})
+		sync.Send(context.Background(), &events.Event{Type: events.ContactsImported})
+		assert.Len(t, sync.queue, 1)
+	})
+
+	t.Run("should dead letter the changes overflowing the queue", func(t *testing.T) {
+		sync := newTestSync(&recordingConnector{}, contact)
+		for i := 0; i < 3; i++ {
+			sync.Send(context.Background(), &events.Event{Type: events.ContactDeleted, ContactID: primitive.NewObjectID().Hex()})
+		}
+		assert.Len(t, sync.queue, 2)
+		letters := sync.DeadLetters()
+		assert.Len(t, letters, 1)
+		assert.Equal(t, "the sync queue is full", letters[0].Error)
+	})
+
+	t.Run("should retry the transient failures", func(t *testing.T) {
+		connector := &recordingConnector{errs: []error{errors.New("connection reset"), &ResponseError{Status: 503}}}
+		sync := newTestSync(connector, contact)
+		sync.sync(id)
+		assert.Equal(t, 3, connector.attempts)
+		assert.Len(t, connector.upserts, 1)
+		assert.Empty(t, sync.DeadLetters())
+	})
+
+	t.Run("should not count the rate limited attempts", func(t *testing.T) {
+		limited := &ResponseError{Status: 429, RetryAfter: time.Millisecond}
+		connector := &recordingConnector{errs: []error{limited, limited, limited, errors.New("timeout")}}
+		sync := newTestSync(connector, contact)
+		sync.sync(id)
+		assert.Len(t, connector.upserts, 1)
+	})
+
+	t.Run("should dead letter the contacts failing for good", func(t *testing.T) {
+		connector := &recordingConnector{errs: []error{&ResponseError{Status: 400, Message: "INVALID_EMAIL"}}}
+		sync := newTestSync(connector, contact)
+		sync.sync(id)
+		assert.Equal(t, 1, connector.attempts)
+
+		connector.errs = []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")}
+		sync.sync(id)
+		letters := sync.DeadLetters()
+		assert.Len(t, letters, 1)
+		assert.Equal(t, id, letters[0].ContactID)
+		assert.Equal(t, 3, letters[0].Attempts)
+		assert.Equal(t, "timeout", letters[0].Error)
+
+		assert.Equal(t, 1, sync.RetryDeadLetters())
+		assert.Empty(t, sync.DeadLetters())
+		assert.Equal(t, id, <-sync.queue)
+	})
+}
@@ -0,0 +1,36 @@
+package definition
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	AuditActionAdd     = "add"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRevert  = "revert"
+	AuditActionRestore = "restore"
+)
+
+// AuditEntry records a single mutation of a contact.
+type AuditEntry struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ContactID primitive.ObjectID `json:"contactId" bson:"contactId"`
+	Action    string             `json:"action" bson:"action"`
+	Actor     string             `json:"actor,omitempty" bson:"actor,omitempty"`
+	// ClaimedActor is the user the client named itself, unverified, as no
+	// trusted proxy vouched for it.
+	ClaimedActor string         `json:"claimedActor,omitempty" bson:"claimedActor,omitempty"`
+	Timestamp    time.Time      `json:"timestamp" bson:"timestamp"`
+	Before       *Contact       `json:"before,omitempty" bson:"before,omitempty"`
+	After        *Contact       `json:"after,omitempty" bson:"after,omitempty"`
+	Changes      []*FieldChange `json:"changes,omitempty" bson:"changes,omitempty"`
+}
+
+// FieldChange describes how a single contact field changed.
+type FieldChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
@@ -0,0 +1,27 @@
+package definition
+
+// Formats of a contacts backup: newline delimited canonical extended JSON, or
+// concatenated BSON documents like mongodump writes.
+const (
+	BackupFormatJSON = "json"
+	BackupFormatBSON = "bson"
+)
+
+// Modes of a restore: merge adds the contacts of the backup and overwrites
+// those with the same ID, replace deletes every contact first.
+const (
+	RestoreModeMerge   = "merge"
+	RestoreModeReplace = "replace"
+)
+
+// RestoreResult reports the contacts a restore wrote, the contacts a replace
+// deleted first, the contacts skipped as their phone number was erased since
+// the backup and the first errors of the contacts that failed.
+type RestoreResult struct {
+	Mode     string   `json:"mode"`
+	Restored int64    `json:"restored"`
+	Deleted  int64    `json:"deleted,omitempty"`
+	Erased   int64    `json:"erased,omitempty"`
+	Failed   int64    `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
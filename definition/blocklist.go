@@ -0,0 +1,19 @@
+package definition
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// BlockedNumber is a number of the blocklist, the phone of a blocked contact.
+// Number is the phone normalized with its country code, as it is checked.
+type BlockedNumber struct {
+	Number    string             `json:"number"`
+	Phone     string             `json:"phone"`
+	ContactID primitive.ObjectID `json:"contactId"`
+	Name      string             `json:"name,omitempty"`
+}
+
+// BlockCheck tells whether a number, normalized with its country code, is on
+// the blocklist.
+type BlockCheck struct {
+	Number  string `json:"number"`
+	Blocked bool   `json:"blocked"`
+}
@@ -1,11 +1,152 @@
 package definition
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
 
 type Contact struct {
 	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	FirstName string             `json:"firstName,omitempty" bson:"firstName,omitempty"`
 	LastName  string             `json:"lastName,omitempty" bson:"lastName,omitempty"`
 	Phone     string             `json:"phone,omitempty" bson:"phone,omitempty"`
+	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
+	Company   string             `json:"company,omitempty" bson:"company,omitempty"`
+	JobTitle  string             `json:"jobTitle,omitempty" bson:"jobTitle,omitempty"`
 	Address   string             `json:"address,omitempty" bson:"address,omitempty"`
+	Notes     string             `json:"notes,omitempty" bson:"notes,omitempty"`
+	Version   int64              `json:"version,omitempty" bson:"version,omitempty"`
+	CreatedAt *time.Time         `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+
+	// Relations link the contact to others. They are changed through the
+	// relations endpoints only, and ignored when sent.
+	Relations []Relation `json:"relations,omitempty" bson:"relations,omitempty"`
+
+	// Archived hides the contact from the listings and searches, unless
+	// they include the archived contacts. It is changed through the archive
+	// endpoints only, and ignored when sent.
+	Archived bool `json:"archived,omitempty" bson:"archived,omitempty"`
+
+	// SortWeight pins the contact to the top of the listings, the heaviest
+	// first. It is changed through the order endpoint only, and ignored when
+	// sent.
+	SortWeight int64 `json:"sortWeight,omitempty" bson:"sortWeight,omitempty"`
+
+	// Blocked puts the phone of the contact on the blocklist, the numbers
+	// not to call or to reject calls from. It is changed through the block
+	// endpoints only, and ignored when sent.
+	Blocked bool `json:"blocked,omitempty" bson:"blocked,omitempty"`
+
+	// Owner is the user who added the contact, stamped by the phone book from
+	// the request and ignored when sent.
+	Owner string `json:"owner,omitempty" bson:"owner,omitempty"`
+
+	// UserName is the user name of a contact provisioned by an identity
+	// provider over SCIM, set through the SCIM endpoints only.
+	UserName string `json:"-" bson:"userName,omitempty"`
+
+	// The derived fields are set by the phone book on every write, and
+	// ignored when sent. DisplayName is the names normalized for sorting and
+	// prefix search, NormalizedPhone the phone with its country code for
+	// reverse lookups and Initial the letter of the alphabetical index.
+	DisplayName     string `json:"displayName,omitempty" bson:"displayName,omitempty"`
+	NormalizedPhone string `json:"-" bson:"normalizedPhone,omitempty"`
+	Initial         string `json:"-" bson:"initial,omitempty"`
+
+	// PhoneCountry, PhoneType and PhoneArea are derived from the phone number
+	// metadata too: the ISO 3166-1 alpha-2 code of the region of the phone,
+	// its type, like mobile or fixed, and the area of a fixed line. They are
+	// left out for the numbers of an unknown region.
+	PhoneCountry string `json:"phoneCountry,omitempty" bson:"phoneCountry,omitempty"`
+	PhoneType    string `json:"phoneType,omitempty" bson:"phoneType,omitempty"`
+	PhoneArea    string `json:"phoneArea,omitempty" bson:"phoneArea,omitempty"`
+
+	// PhoneE164 and PhoneFormatted are the phone in E.164 form and as
+	// displayed in the region of the request. They are set in the responses
+	// only, never stored, and left out when the phone isn't a number of a
+	// known region.
+	PhoneE164      string `json:"phoneE164,omitempty" bson:"-"`
+	PhoneFormatted string `json:"phoneFormatted,omitempty" bson:"-"`
+
+	// SchemaVersion is the version of the schema the contact was written
+	// with, so the migrations upgrade the contacts written before them.
+	SchemaVersion int `json:"-" bson:"schemaVersion,omitempty"`
+}
+
+// WriteResult is the outcome of a write to a contact: the id of the contact
+// added or upserted, and how many contacts an update or delete matched and
+// modified. An update matching a contact leaves it unmodified when it already
+// holds the values sent. Created tells the writes adding the contact, like
+// the upserts matching none, apart.
+type WriteResult struct {
+	ID       string `json:"id,omitempty"`
+	Created  bool   `json:"created,omitempty"`
+	Matched  int64  `json:"matched"`
+	Modified int64  `json:"modified"`
+}
+
+// LetterCount is the number of contacts indexed under a letter.
+type LetterCount struct {
+	Letter string `json:"letter"`
+	Count  int64  `json:"count"`
+}
+
+// ValueCount is the number of contacts holding a value of a field.
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// ContactPatch holds the fields of a partial update keyed by their json name.
+// A nil value clears the field, a non nil value sets it.
+type ContactPatch map[string]*string
+
+// ContactPage is a page of a contacts listing. Totals are left out when
+// counting was skipped, and NextCursor is set when cursor pagination was
+// requested and more contacts follow.
+type ContactPage struct {
+	Items      []*Contact `json:"items"`
+	Page       int        `json:"page,omitempty"`
+	PageSize   int64      `json:"pageSize"`
+	TotalItems *int64     `json:"totalItems,omitempty"`
+	TotalPages *int64     `json:"totalPages,omitempty"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// BatchDelete selects the contacts a batch delete removes, either by IDs or
+// by a filter of exact field values, like search. A dry run only reports the
+// selected contacts.
+type BatchDelete struct {
+	IDs    []string          `json:"ids,omitempty"`
+	Filter map[string]string `json:"filter,omitempty"`
+	DryRun bool              `json:"dryRun,omitempty"`
+}
+
+// BatchDeleteResult reports the contacts a batch delete removed, or would
+// remove in a dry run.
+type BatchDeleteResult struct {
+	Deleted int64    `json:"deleted"`
+	IDs     []string `json:"ids"`
+	DryRun  bool     `json:"dryRun,omitempty"`
+}
+
+// ContactOrder lists the contacts pinned to the top of the listings, in the
+// order they are listed. The contacts left out are unpinned.
+type ContactOrder struct {
+	IDs []string `json:"ids"`
+}
+
+// ReorderResult reports the contacts a reorder pinned, moved or unpinned.
+type ReorderResult struct {
+	Updated int64    `json:"updated"`
+	IDs     []string `json:"ids"`
+}
+
+// AddResult reports a contact of a batch add: the id it was added with, or
+// the error it was rejected with.
+type AddResult struct {
+	ID    string `json:"id,omitempty"`
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
 }
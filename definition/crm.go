@@ -0,0 +1,19 @@
+package definition
+
+import "time"
+
+// CRMDeadLetter is a contact that failed to sync to the CRM, kept until it's
+// retried.
+type CRMDeadLetter struct {
+	ContactID string    `json:"contactId"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	FailedAt  time.Time `json:"failedAt"`
+}
+
+// ICRMSync pushes the contact changes to a CRM. RetryDeadLetters queues the
+// dead letters to sync again and returns how many were.
+type ICRMSync interface {
+	DeadLetters() []*CRMDeadLetter
+	RetryDeadLetters() int
+}
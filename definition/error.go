@@ -0,0 +1,74 @@
+package definition
+
+import "strings"
+
+// Error is an error with a stable code clients can branch on, optionally
+// naming the contact field it refers to.
+type Error struct {
+	Code    string
+	Message string
+	Field   string
+}
+
+func NewError(code string, message string, field string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Field:   field,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports errors with the same code as equal, so errors.Is matches
+// copies created with WithMessage or WithField.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+func (e *Error) WithField(field string) *Error {
+	copied := *e
+	copied.Field = field
+	return &copied
+}
+
+// Errors gathers the errors of several fields, to report them all at once.
+// errors.Is and errors.As look through each of them.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns nil when e is empty, its error when it holds one, and e
+// otherwise.
+func (e Errors) Err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	}
+	return e
+}
@@ -0,0 +1,11 @@
+package definition
+
+// ImportResult reports the contacts an import added, the contacts skipped as
+// duplicates of existing or earlier imported ones, and the first errors of the
+// contacts that failed.
+type ImportResult struct {
+	Imported   int64    `json:"imported"`
+	Duplicates int64    `json:"duplicates"`
+	Failed     int64    `json:"failed"`
+	Errors     []string `json:"errors,omitempty"`
+}
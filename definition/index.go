@@ -0,0 +1,15 @@
+package definition
+
+// Index describes an index of the contacts collection.
+type Index struct {
+	Name   string     `json:"name"`
+	Keys   []IndexKey `json:"keys"`
+	Unique bool       `json:"unique,omitempty"`
+}
+
+// IndexKey is an indexed field with its order, 1 or -1, or its index type,
+// e.g. text.
+type IndexKey struct {
+	Field string      `json:"field"`
+	Order interface{} `json:"order"`
+}
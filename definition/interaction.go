@@ -0,0 +1,33 @@
+package definition
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	InteractionCall    = "call"
+	InteractionMeeting = "meeting"
+	InteractionNote    = "note"
+)
+
+// Interaction is a call, meeting or note recorded on the timeline of a
+// contact.
+type Interaction struct {
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ContactID  primitive.ObjectID `json:"contactId" bson:"contactId"`
+	Type       string             `json:"type" bson:"type"`
+	Summary    string             `json:"summary" bson:"summary"`
+	OccurredAt time.Time          `json:"occurredAt" bson:"occurredAt"`
+	Actor      string             `json:"actor,omitempty" bson:"actor,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// InteractionPage is a page of the timeline of a contact, most recent first.
+type InteractionPage struct {
+	Items      []*Interaction `json:"items"`
+	Page       int            `json:"page"`
+	PageSize   int64          `json:"pageSize"`
+	TotalItems *int64         `json:"totalItems,omitempty"`
+	TotalPages *int64         `json:"totalPages,omitempty"`
+}
@@ -0,0 +1,69 @@
+package definition
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// The states of a job. A job is queued until a slot frees to run it, and
+// ends succeeded, failed or canceled.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Job is an admin operation running in the background, like a reindex, kept
+// after it ends for its result to be looked up. Done of Total are the items
+// it went through so far, for the steps counting them.
+type Job struct {
+	ID         string          `json:"id" bson:"_id"`
+	Type       string          `json:"type" bson:"type"`
+	Tenant     string          `json:"tenant,omitempty" bson:"tenant"`
+	User       string          `json:"user,omitempty" bson:"user,omitempty"`
+	Replica    string          `json:"replica" bson:"replica"`
+	Status     string          `json:"status" bson:"status"`
+	Step       string          `json:"step,omitempty" bson:"step,omitempty"`
+	Done       int64           `json:"done" bson:"done"`
+	Total      int64           `json:"total" bson:"total"`
+	Result     json.RawMessage `json:"result,omitempty" bson:"result,omitempty" swaggertype:"object"`
+	Error      *JobError       `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt" bson:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt,omitempty" bson:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+	// Report is a CSV report of the items the job failed on, downloaded
+	// apart, for the jobs writing one.
+	Report []byte `json:"-" bson:"report,omitempty"`
+}
+
+// Finished reports whether the job ended, whatever its outcome.
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+// JobError is the error a job failed with, as the API reports errors.
+type JobError struct {
+	Code    string `json:"code,omitempty" bson:"code,omitempty"`
+	Message string `json:"message" bson:"message"`
+	Field   string `json:"field,omitempty" bson:"field,omitempty"`
+}
+
+// JobFilter selects the jobs of a tenant to list, of a type and status when
+// they aren't empty, the Limit most recent first.
+type JobFilter struct {
+	Tenant string
+	Type   string
+	Status string
+	Limit  int64
+}
+
+// IJobs keeps the records of the jobs. SaveJob writes the whole record,
+// adding it the first time. ListJobs leaves the reports out.
+type IJobs interface {
+	SaveJob(ctx context.Context, job *Job) error
+	GetJob(ctx context.Context, id string) (*Job, string, error)
+	ListJobs(ctx context.Context, filter JobFilter) ([]*Job, string, error)
+}
@@ -0,0 +1,30 @@
+package definition
+
+// MigrationStep is a migration of the contact schema and the contacts it
+// upgraded, or would in a dry run.
+type MigrationStep struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Contacts    int64  `json:"contacts"`
+}
+
+// MigrationResult reports the migrations the contacts were upgraded by to
+// the schema version of the service, or would be in a dry run.
+type MigrationResult struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Migrations    []MigrationStep `json:"migrations"`
+	DryRun        bool            `json:"dryRun,omitempty"`
+}
+
+// RebuildResult reports a rebuild of the data derived from the contacts: the
+// contacts gone through and those whose derived fields were stale, and the
+// indexes created anew.
+type RebuildResult struct {
+	Contacts int64    `json:"contacts"`
+	Updated  int64    `json:"updated"`
+	Indexes  []string `json:"indexes"`
+}
+
+// RebuildProgress is told the number of contacts a rebuild went through so
+// far, of total.
+type RebuildProgress func(done int64, total int64)
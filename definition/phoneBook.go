@@ -1,13 +1,72 @@
 package definition
 
 import (
+	"context"
+	"io"
 	"net/url"
+	"time"
 )
 
 type IPhoneBook interface {
-	GetContactWithPagination(pageParam []string) ([]*Contact, string, error)
-	AddContact(contact *Contact) (string, string, error)
-	UpdateContact(id string, updatedContact *Contact) (int64, string, error)
-	DeleteContact(id string) (int64, string, error)
-	SearchContact(query url.Values) ([]*Contact, string, error)
+	GetContact(ctx context.Context, id string) (*Contact, string, error)
+	GetContactWithPagination(ctx context.Context, query url.Values) (*ContactPage, string, error)
+	AddContact(ctx context.Context, contact *Contact, actor string) (*WriteResult, error)
+	UpdateContact(ctx context.Context, id string, updatedContact *Contact, expectedVersion int64, actor string) (*WriteResult, error)
+	PatchContact(ctx context.Context, id string, patch ContactPatch, expectedVersion int64, actor string) (*WriteResult, error)
+	DeleteContact(ctx context.Context, id string, actor string) (*WriteResult, error)
+	DeleteContacts(ctx context.Context, batch *BatchDelete, actor string) (*BatchDeleteResult, error)
+	SearchContact(ctx context.Context, query url.Values) (*ContactPage, string, error)
+	SearchContactText(ctx context.Context, query url.Values) ([]*Contact, string, error)
+	GetContactIndex(ctx context.Context) ([]*LetterCount, string, error)
+	GetRecentContacts(ctx context.Context, query url.Values) ([]*Contact, string, error)
+	GetRandomContact(ctx context.Context) (*Contact, string, error)
+	SampleContacts(ctx context.Context, query url.Values) ([]*Contact, string, error)
+	GetContactsByPhone(ctx context.Context, number string) ([]*Contact, string, error)
+	GetCompanyContacts(ctx context.Context, name string, query url.Values) (*ContactPage, string, error)
+	ListCompanies(ctx context.Context, prefix string) ([]string, string, error)
+	ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error)
+	GetContactHistory(ctx context.Context, id string) ([]*AuditEntry, string, error)
+	UndoContact(ctx context.Context, id string, actor string) (*Contact, error)
+	LinkContact(ctx context.Context, id string, relation *Relation, actor string) (*WriteResult, error)
+	UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*WriteResult, error)
+	ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*WriteResult, error)
+	ReorderContacts(ctx context.Context, order *ContactOrder, actor string) (*ReorderResult, error)
+	BlockContact(ctx context.Context, id string, blocked bool, actor string) (*WriteResult, error)
+	GetBlocklist(ctx context.Context) ([]*BlockedNumber, string, error)
+	CheckBlocked(ctx context.Context, number string) (*BlockCheck, string, error)
+	GetRelatedContacts(ctx context.Context, id string) ([]*RelatedContact, string, error)
+	AddInteraction(ctx context.Context, id string, interaction *Interaction, actor string) (*WriteResult, error)
+	GetInteractions(ctx context.Context, id string, query url.Values) (*InteractionPage, string, error)
+	DeleteInteraction(ctx context.Context, id string, interactionID string) (*WriteResult, error)
+	AddReminder(ctx context.Context, id string, reminder *Reminder, actor string) (*WriteResult, error)
+	GetReminders(ctx context.Context, due string) ([]*Reminder, string, error)
+	ClaimDueReminder(ctx context.Context, now time.Time) (*Reminder, error)
+	AssignSpeedDial(ctx context.Context, slot string, dial *SpeedDial, actor string) (*SpeedDial, error)
+	GetSpeedDials(ctx context.Context) ([]*SpeedDial, string, error)
+	ClearSpeedDial(ctx context.Context, slot string) (*WriteResult, error)
+	ListIndexes(ctx context.Context) ([]*Index, string, error)
+	Backup(ctx context.Context, format string, w io.Writer) (int64, string, error)
+	Restore(ctx context.Context, format string, mode string, r io.Reader) (*RestoreResult, error)
+	SeedContacts(ctx context.Context, count int, actor string) (int64, error)
+	GetUsage(ctx context.Context) (*Usage, string, error)
+	ApplyRetention(ctx context.Context, policy *RetentionPolicy, actor string) (*RetentionResult, error)
+	EraseContacts(ctx context.Context, phone string, actor string) (*ErasureResult, error)
+	Migrate(ctx context.Context, dryRun bool) (*MigrationResult, error)
+	ValidateContacts(ctx context.Context, fix bool, actor string) (*ValidationReport, error)
+	GetChanges(ctx context.Context, query url.Values) (*ChangePage, string, error)
+	GetLastModified(ctx context.Context) (time.Time, string, error)
+	AddContacts(ctx context.Context, contacts []*Contact, actor string) ([]*AddResult, error)
+	CountContacts(ctx context.Context, query url.Values) (int64, string, error)
+	GetDistinctValues(ctx context.Context, field string) ([]*ValueCount, string, error)
+	UpsertContact(ctx context.Context, id string, contact *Contact, actor string) (*WriteResult, error)
+	UpsertContactByPhone(ctx context.Context, number string, contact *Contact, actor string) (*WriteResult, error)
+	RebuildDerivedData(ctx context.Context, progress RebuildProgress) (*RebuildResult, error)
+}
+
+// ISearchIndex searches the contacts in a search engine the phone book is
+// mirrored to. Rebuild indexes the phone book anew and returns the number of
+// contacts indexed.
+type ISearchIndex interface {
+	SearchContacts(ctx context.Context, query url.Values) (*ContactPage, string, error)
+	Rebuild(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,23 @@
+package definition
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+const (
+	RelationSpouse    = "spouse"
+	RelationAssistant = "assistant"
+	RelationManager   = "manager"
+	RelationColleague = "colleague"
+)
+
+// Relation links a contact to another one, e.g. a manager relation names the
+// manager of the contact holding it.
+type Relation struct {
+	ContactID primitive.ObjectID `json:"contactId" bson:"contactId"`
+	Type      string             `json:"type" bson:"type"`
+}
+
+// RelatedContact is a contact linked to another one, by the type of the link.
+type RelatedContact struct {
+	Type    string   `json:"type"`
+	Contact *Contact `json:"contact"`
+}
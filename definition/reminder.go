@@ -0,0 +1,23 @@
+package definition
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	ReminderDueToday   = "today"
+	ReminderDueOverdue = "overdue"
+)
+
+// Reminder is a follow-up with a contact due at a given time. FiredAt is set
+// once the reminder was sent, a pending reminder has none.
+type Reminder struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ContactID primitive.ObjectID `json:"contactId" bson:"contactId"`
+	DueAt     time.Time          `json:"dueAt" bson:"dueAt"`
+	Note      string             `json:"note" bson:"note"`
+	Actor     string             `json:"actor,omitempty" bson:"actor,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	FiredAt   *time.Time         `json:"firedAt,omitempty" bson:"firedAt,omitempty"`
+}
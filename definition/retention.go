@@ -0,0 +1,37 @@
+package definition
+
+// Actions of a retention policy: anonymize clears the personal details of
+// the contacts it selects, purge erases them.
+const (
+	RetentionActionAnonymize = "anonymize"
+	RetentionActionPurge     = "purge"
+)
+
+// RetentionPolicy selects the contacts not updated for Days days. A dry run
+// only reports them.
+type RetentionPolicy struct {
+	Action string `json:"action"`
+	Days   int    `json:"days"`
+	DryRun bool   `json:"dryRun,omitempty"`
+}
+
+// RetentionResult reports the contacts a policy anonymized or purged, or
+// would in a dry run.
+type RetentionResult struct {
+	Action   string   `json:"action"`
+	Affected int64    `json:"affected"`
+	IDs      []string `json:"ids"`
+	DryRun   bool     `json:"dryRun,omitempty"`
+}
+
+// ErasureResult reports what erasing the contacts of a phone number removed:
+// the contacts, including the deleted ones the audit log kept, and their
+// history, interactions and reminders.
+type ErasureResult struct {
+	Contacts     int64    `json:"contacts"`
+	IDs          []string `json:"ids"`
+	AuditEntries int64    `json:"auditEntries"`
+	Interactions int64    `json:"interactions"`
+	Reminders    int64    `json:"reminders"`
+	SpeedDials   int64    `json:"speedDials"`
+}
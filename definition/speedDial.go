@@ -0,0 +1,20 @@
+package definition
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// MaxSpeedDialSlot is the last speed dial slot, the slots run from 1.
+const MaxSpeedDialSlot = 99
+
+// SpeedDial assigns a speed dial slot of the phones to a contact. A slot is
+// assigned to a single contact and a contact to a single slot. Contact is
+// the contact of the slot as listed, none once the contact was deleted.
+type SpeedDial struct {
+	Slot       int                `json:"slot" bson:"_id"`
+	ContactID  primitive.ObjectID `json:"contactId" bson:"contactId"`
+	Actor      string             `json:"actor,omitempty" bson:"actor,omitempty"`
+	AssignedAt time.Time          `json:"assignedAt" bson:"assignedAt"`
+	Contact    *Contact           `json:"contact,omitempty" bson:"-"`
+}
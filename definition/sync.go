@@ -0,0 +1,87 @@
+package definition
+
+import "time"
+
+// Conflict policies of the two-way sync. Last writer wins keeps whichever
+// change was made last, merge keeps the changes of both sides to different
+// fields and the server value of the fields both changed.
+const (
+	SyncPolicyLastWriterWins = "lww"
+	SyncPolicyMerge          = "merge"
+)
+
+// Statuses of the client changes a sync applied.
+const (
+	SyncStatusApplied  = "applied"
+	SyncStatusMerged   = "merged"
+	SyncStatusConflict = "conflict"
+	SyncStatusRejected = "rejected"
+)
+
+// ContactChange is a change to a contact since a sync token: the contact as
+// it is now, or its tombstone when it was deleted, telling when and by whom.
+// Created tells whether the contact was added since. SyncToken resumes the
+// sync right after the change.
+type ContactChange struct {
+	ID        string     `json:"id"`
+	Created   bool       `json:"created,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy string     `json:"deletedBy,omitempty"`
+	Contact   *Contact   `json:"contact,omitempty"`
+	SyncToken string     `json:"syncToken"`
+}
+
+// ChangePage is a page of the changes to the contacts since a sync token,
+// oldest first. SyncToken resumes the sync after the page, and More tells
+// whether more changes follow.
+type ChangePage struct {
+	Changes   []*ContactChange `json:"changes"`
+	SyncToken string           `json:"syncToken"`
+	More      bool             `json:"more"`
+}
+
+// ClientChange is a change a sync client made to its copy of a contact.
+// Changes to existing contacts carry the id and the version the client last
+// synced, new contacts a ClientID the client knows them by. Fields holds the
+// changed fields like a patch, and Base their values as last synced, which
+// the merge policy tells the fields changed on both sides by. ModifiedAt is
+// when the client made the change, compared by the last writer wins policy.
+type ClientChange struct {
+	ID         string            `json:"id,omitempty"`
+	ClientID   string            `json:"clientId,omitempty"`
+	Version    int64             `json:"version,omitempty"`
+	Deleted    bool              `json:"deleted,omitempty"`
+	Fields     ContactPatch      `json:"fields,omitempty"`
+	Base       map[string]string `json:"base,omitempty"`
+	ModifiedAt time.Time         `json:"modifiedAt"`
+}
+
+// ChangeResult reports how a client change was applied. Conflicts lists the
+// fields the server kept its own value of, and Contact is the contact as
+// stored after the change, unless it was deleted. Rejected changes report
+// the error they failed with.
+type ChangeResult struct {
+	ID        string   `json:"id,omitempty"`
+	ClientID  string   `json:"clientId,omitempty"`
+	Status    string   `json:"status"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Contact   *Contact `json:"contact,omitempty"`
+	Code      string   `json:"code,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// StreamedChange is a change to a contact seen on the change stream of the
+// database, by whichever writer made it: the contact as it is now, unless it
+// was deleted or deleted since. Tenant is the tenant whose phone book
+// changed, empty for the default one. ResumeToken resumes the stream right
+// after the change.
+type StreamedChange struct {
+	Tenant      string
+	ID          string
+	Created     bool
+	Deleted     bool
+	Contact     *Contact
+	OccurredAt  time.Time
+	ResumeToken []byte
+}
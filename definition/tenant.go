@@ -0,0 +1,114 @@
+package definition
+
+import (
+	"context"
+	"time"
+)
+
+// Tenant is an isolated phone book of its own, provisioned through the admin
+// API. Its ID names it in the X-Tenant-ID header and subdomains.
+type Tenant struct {
+	ID        string       `json:"id" bson:"_id"`
+	Name      string       `json:"name,omitempty" bson:"name,omitempty"`
+	Limits    TenantLimits `json:"limits" bson:"limits"`
+	CreatedAt time.Time    `json:"createdAt" bson:"createdAt"`
+}
+
+// TenantLimits are the quotas of a tenant. A zero limit falls back to the
+// configured default, which is unlimited when zero as well.
+type TenantLimits struct {
+	MaxContacts          int64 `json:"maxContacts,omitempty" bson:"maxContacts,omitempty"`
+	MaxRequestsPerMinute int   `json:"maxRequestsPerMinute,omitempty" bson:"maxRequestsPerMinute,omitempty"`
+}
+
+// Usage is what a phone book stores: the number of documents of each kind
+// and the bytes they take in the database.
+type Usage struct {
+	Contacts       int64 `json:"contacts"`
+	Interactions   int64 `json:"interactions"`
+	Reminders      int64 `json:"reminders"`
+	HistoryEntries int64 `json:"historyEntries"`
+	// DataBytes is the size of the documents uncompressed, StorageBytes and
+	// IndexBytes the disk space of the documents and of the indexes.
+	DataBytes    int64 `json:"dataBytes"`
+	StorageBytes int64 `json:"storageBytes"`
+	IndexBytes   int64 `json:"indexBytes"`
+}
+
+// ITenants provisions the tenants and looks up those requests are scoped to.
+type ITenants interface {
+	CreateTenant(ctx context.Context, tenant *Tenant) (*Tenant, string, error)
+	GetTenant(ctx context.Context, id string) (*Tenant, string, error)
+	ListTenants(ctx context.Context) ([]*Tenant, string, error)
+	SetTenantLimits(ctx context.Context, id string, limits *TenantLimits) (*Tenant, string, error)
+	DeleteTenant(ctx context.Context, id string) (int64, string, error)
+	LookupTenant(ctx context.Context, id string) (*Tenant, error)
+}
+
+type (
+	tenantKey       struct{}
+	contactLimitKey struct{}
+	userKey         struct{}
+	claimedUserKey  struct{}
+	clientKey       struct{}
+)
+
+// WithTenant returns ctx scoped to the tenant with the given id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, empty for the
+// default phone book.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// WithContactLimit returns ctx limiting the phone book to max contacts, so
+// additions over it are refused.
+func WithContactLimit(ctx context.Context, max int64) context.Context {
+	return context.WithValue(ctx, contactLimitKey{}, max)
+}
+
+// ContactLimitFromContext returns the contact limit of ctx, 0 when unlimited.
+func ContactLimitFromContext(ctx context.Context) int64 {
+	max, _ := ctx.Value(contactLimitKey{}).(int64)
+	return max
+}
+
+// WithUser returns ctx on behalf of the authenticated user.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the authenticated user of ctx, empty when anonymous.
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// WithClaimedUser returns ctx naming the user the client claims to be,
+// without anything vouching for it.
+func WithClaimedUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, claimedUserKey{}, user)
+}
+
+// ClaimedUserFromContext returns the unverified user ctx names, empty when
+// the client named none.
+func ClaimedUserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(claimedUserKey{}).(string)
+	return user
+}
+
+// WithClient returns ctx on behalf of the client with the given identity,
+// its API key or address, so its deletes can be guarded.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client of ctx, empty outside of a request.
+func ClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
@@ -0,0 +1,24 @@
+package definition
+
+// ContactIssue is a validation rule a stored contact breaks. Fixable issues
+// are normalized by a repair, e.g. a formatted phone number, and Fixed tells
+// whether the repair was applied.
+type ContactIssue struct {
+	ID      string `json:"id"`
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Fixable bool   `json:"fixable"`
+	Fixed   bool   `json:"fixed,omitempty"`
+}
+
+// ValidationReport reports the stored contacts breaking the validation rules
+// of new contacts, and those repaired. Issues lists the first issues found,
+// Truncated tells whether there were more.
+type ValidationReport struct {
+	Scanned   int64          `json:"scanned"`
+	Invalid   int64          `json:"invalid"`
+	Fixed     int64          `json:"fixed"`
+	Issues    []ContactIssue `json:"issues"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
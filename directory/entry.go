@@ -0,0 +1,201 @@
+package directory
+
+import (
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"phoneBook/definition"
+	"strings"
+)
+
+// entry is a directory entry: a contact, the base entry holding them or the
+// root DSE.
+type entry struct {
+	dn         string
+	attributes []attribute
+}
+
+type attribute struct {
+	name   string
+	values []string
+}
+
+func (e *entry) add(name string, values ...string) {
+	if len(values) == 0 || values[0] == "" {
+		return
+	}
+	e.attributes = append(e.attributes, attribute{name: name, values: values})
+}
+
+// get returns the values of the attribute with name, ignoring case.
+func (e *entry) get(name string) []string {
+	for _, attribute := range e.attributes {
+		if strings.EqualFold(attribute.name, name) {
+			return attribute.values
+		}
+	}
+	return nil
+}
+
+// contactEntry maps a contact to an inetOrgPerson entry named by its id.
+func (s *Server) contactEntry(contact *definition.Contact) *entry {
+	id := contact.ID.Hex()
+	e := &entry{dn: "uid=" + id + "," + s.baseDN.String()}
+	e.add("objectClass", "top", "person", "organizationalPerson", "inetOrgPerson")
+	e.add("uid", id)
+	e.add("cn", strings.TrimSpace(contact.FirstName+" "+contact.LastName))
+	e.add("givenName", contact.FirstName)
+	// person requires a surname
+	surname := contact.LastName
+	if surname == "" {
+		surname = contact.FirstName
+	}
+	e.add("sn", surname)
+	e.add("telephoneNumber", contact.Phone)
+	e.add("mail", contact.Email)
+	e.add("o", contact.Company)
+	e.add("title", contact.JobTitle)
+	e.add("postalAddress", contact.Address)
+	e.add("description", contact.Notes)
+	return e
+}
+
+// baseEntry is the organizational unit holding the contacts.
+func (s *Server) baseEntry() *entry {
+	e := &entry{dn: s.baseDN.String()}
+	e.add("objectClass", "top", "organizationalUnit")
+	if rdn := s.baseDN.RDNs[0].Attributes[0]; strings.EqualFold(rdn.Type, "ou") {
+		e.add("ou", rdn.Value)
+	}
+	return e
+}
+
+// rootEntry is the root DSE clients read to discover the base DN.
+func (s *Server) rootEntry() *entry {
+	e := &entry{}
+	e.add("objectClass", "top")
+	e.add("namingContexts", s.baseDN.String())
+	e.add("supportedLDAPVersion", "3")
+	e.add("vendorName", "phoneBook")
+	return e
+}
+
+// matches evaluates an LDAP filter on the entry. Values are compared ignoring
+// case, and unsupported filters, like extensible matches, match nothing.
+func (e *entry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !e.matches(child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if e.matches(child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !e.matches(filter.Children[0])
+	case ldap.FilterPresent:
+		return len(e.get(filter.Data.String())) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		assertion := strings.ToLower(filter.Children[1].Data.String())
+		return e.anyValue(filter.Children[0].Data.String(), func(value string) bool {
+			switch filter.Tag {
+			case ldap.FilterGreaterOrEqual:
+				return value >= assertion
+			case ldap.FilterLessOrEqual:
+				return value <= assertion
+			}
+			return value == assertion
+		})
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		return e.anyValue(filter.Children[0].Data.String(), func(value string) bool {
+			return matchesSubstrings(value, filter.Children[1].Children)
+		})
+	}
+	return false
+}
+
+// anyValue reports whether a value of the attribute, lower cased, satisfies
+// match.
+func (e *entry) anyValue(name string, match func(value string) bool) bool {
+	for _, value := range e.get(name) {
+		if match(strings.ToLower(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSubstrings reports whether value starts with the initial substring,
+// contains the any substrings in order and ends with the final one.
+func matchesSubstrings(value string, substrings []*ber.Packet) bool {
+	for _, substring := range substrings {
+		part := strings.ToLower(substring.Data.String())
+		switch substring.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, part) {
+				return false
+			}
+			value = value[len(part):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value, part)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(part):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, part) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// packet encodes the entry as a SearchResultEntry with the requested
+// attributes: all of them when none or * is requested.
+func (e *entry) packet(requested []string, typesOnly bool) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "Object Name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, attribute := range e.attributes {
+		if !isRequested(attribute.name, requested) {
+			continue
+		}
+		encoded := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		encoded.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attribute.name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		if !typesOnly {
+			for _, value := range attribute.values {
+				values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+		}
+		encoded.AppendChild(values)
+		attributes.AppendChild(encoded)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+func isRequested(name string, requested []string) bool {
+	if len(requested) == 0 {
+		return true
+	}
+	for _, candidate := range requested {
+		if candidate == "*" || strings.EqualFold(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
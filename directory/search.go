@@ -0,0 +1,235 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchTimeout bounds searches that set no time limit.
+const searchTimeout = 30 * time.Second
+
+// searchFields maps the entry attributes, lower cased, to the contact fields
+// SearchContact matches exactly, so equality filters on them are pushed down.
+var searchFields = map[string]string{
+	"givenname":       "firstName",
+	"telephonenumber": "phone",
+	"mail":            "email",
+	"o":               "company",
+	"title":           "jobTitle",
+	"postaladdress":   "address",
+	"description":     "notes",
+}
+
+// errNoSuchObject reports a search base that isn't in the directory.
+var errNoSuchObject = errors.New("no such object")
+
+// searchRequest is a decoded SearchRequest.
+type searchRequest struct {
+	base       string
+	scope      int64
+	sizeLimit  int64
+	timeLimit  int64
+	typesOnly  bool
+	filter     *ber.Packet
+	attributes []string
+}
+
+func decodeSearchRequest(op *ber.Packet) (*searchRequest, bool) {
+	if len(op.Children) < 8 {
+		return nil, false
+	}
+	req := &searchRequest{base: op.Children[0].Data.String(), filter: op.Children[6]}
+	var ok [4]bool
+	req.scope, ok[0] = op.Children[1].Value.(int64)
+	req.sizeLimit, ok[1] = op.Children[3].Value.(int64)
+	req.timeLimit, ok[2] = op.Children[4].Value.(int64)
+	req.typesOnly, ok[3] = op.Children[5].Value.(bool)
+	if ok != [4]bool{true, true, true, true} {
+		return nil, false
+	}
+	for _, attribute := range op.Children[7].Children {
+		req.attributes = append(req.attributes, attribute.Data.String())
+	}
+	return req, true
+}
+
+// search answers a search with the matching entries, then the result.
+func (s *Server) search(sess *session, messageID int64, op *ber.Packet) bool {
+	req, ok := decodeSearchRequest(op)
+	if !ok {
+		return sess.send(messageID, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "invalid search request"))
+	}
+	timeout := searchTimeout
+	if req.timeLimit > 0 {
+		timeout = time.Duration(req.timeLimit) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	limit := s.maxResults
+	if req.sizeLimit > 0 && int(req.sizeLimit) < limit {
+		limit = int(req.sizeLimit)
+	}
+	entries, err := s.find(ctx, req, limit+1)
+	code, message := uint16(ldap.LDAPResultSuccess), ""
+	switch {
+	case errors.Is(err, errNoSuchObject):
+		code, message = ldap.LDAPResultNoSuchObject, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		code, message = ldap.LDAPResultTimeLimitExceeded, "time limit exceeded"
+	case err != nil:
+		code, message = ldap.LDAPResultOperationsError, err.Error()
+	case len(entries) > limit:
+		entries = entries[:limit]
+		code, message = ldap.LDAPResultSizeLimitExceeded, "size limit exceeded"
+	}
+	for _, e := range entries {
+		if !sess.send(messageID, e.packet(req.attributes, req.typesOnly)) {
+			return false
+		}
+	}
+	return sess.send(messageID, result(ldap.ApplicationSearchResultDone, code, message))
+}
+
+// find returns up to limit entries under the base of req matching its filter.
+func (s *Server) find(ctx context.Context, req *searchRequest, limit int) ([]*entry, error) {
+	if req.base == "" && req.scope == ldap.ScopeBaseObject {
+		return filterEntries(req.filter, s.rootEntry()), nil
+	}
+	base, err := ldap.ParseDN(req.base)
+	if err != nil {
+		return nil, errNoSuchObject
+	}
+	switch {
+	case base.EqualFold(s.baseDN) && req.scope == ldap.ScopeBaseObject:
+		return filterEntries(req.filter, s.baseEntry()), nil
+	case base.EqualFold(s.baseDN) && req.scope == ldap.ScopeSingleLevel:
+		return s.findContacts(ctx, req.filter, limit)
+	case s.baseDN.AncestorOfFold(base):
+		id, ok := s.contactID(base)
+		if !ok {
+			return nil, errNoSuchObject
+		}
+		contact, err := s.getContact(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if contact == nil {
+			return nil, errNoSuchObject
+		}
+		if req.scope == ldap.ScopeSingleLevel {
+			return nil, nil
+		}
+		return filterEntries(req.filter, s.contactEntry(contact)), nil
+	case (base.EqualFold(s.baseDN) || base.AncestorOfFold(s.baseDN)) && req.scope == ldap.ScopeWholeSubtree:
+		contacts, err := s.findContacts(ctx, req.filter, limit)
+		return append(filterEntries(req.filter, s.baseEntry()), contacts...), err
+	case base.AncestorOfFold(s.baseDN):
+		return nil, nil
+	}
+	return nil, errNoSuchObject
+}
+
+// contactID returns the id of the contact entry dn names, if it's one.
+func (s *Server) contactID(dn *ldap.DN) (string, bool) {
+	if len(dn.RDNs) != len(s.baseDN.RDNs)+1 || len(dn.RDNs[0].Attributes) != 1 {
+		return "", false
+	}
+	attribute := dn.RDNs[0].Attributes[0]
+	return attribute.Value, strings.EqualFold(attribute.Type, "uid")
+}
+
+// getContact returns the contact with id, nil when there is none.
+func (s *Server) getContact(ctx context.Context, id string) (*definition.Contact, error) {
+	contact, status, err := s.phoneBook.GetContact(ctx, id)
+	if status == core.NotFound || status == core.BadRequest {
+		return nil, nil
+	}
+	return contact, err
+}
+
+// findContacts pushes the equality filters on contact fields down to
+// SearchContact and filters what it returns. Other filters scan the contacts
+// page by page.
+func (s *Server) findContacts(ctx context.Context, filter *ber.Packet, limit int) ([]*entry, error) {
+	query := url.Values{}
+	pushDown(filter, query)
+	if id := query.Get("uid"); id != "" {
+		contact, err := s.getContact(ctx, id)
+		if err != nil || contact == nil {
+			return nil, err
+		}
+		return filterEntries(filter, s.contactEntry(contact)), nil
+	}
+	if len(query) > 0 {
+		query.Set("pageSize", strconv.Itoa(limit))
+		query.Set("count", "false")
+		contacts, _, err := s.phoneBook.SearchContact(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		var entries []*entry
+		for _, contact := range contacts.Items {
+			entries = append(entries, filterEntries(filter, s.contactEntry(contact))...)
+		}
+		return entries, nil
+	}
+	return s.scanContacts(ctx, filter, limit)
+}
+
+// pushDown adds the equality filters on contact fields that every matching
+// entry satisfies to query.
+func pushDown(filter *ber.Packet, query url.Values) {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			pushDown(child, query)
+		}
+	case ldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return
+		}
+		attribute := strings.ToLower(filter.Children[0].Data.String())
+		value := filter.Children[1].Data.String()
+		if attribute == "uid" {
+			query.Set("uid", value)
+		} else if field, ok := searchFields[attribute]; ok {
+			query.Set(field, value)
+		}
+	}
+}
+
+func (s *Server) scanContacts(ctx context.Context, filter *ber.Packet, limit int) ([]*entry, error) {
+	query := url.Values{"cursor": {""}, "count": {"false"}, "pageSize": {strconv.Itoa(s.maxResults)}}
+	var entries []*entry
+	for {
+		page, _, err := s.phoneBook.GetContactWithPagination(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, contact := range page.Items {
+			entries = append(entries, filterEntries(filter, s.contactEntry(contact))...)
+			if len(entries) >= limit {
+				return entries, nil
+			}
+		}
+		if page.NextCursor == "" {
+			return entries, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+func filterEntries(filter *ber.Packet, e *entry) []*entry {
+	if !e.matches(filter) {
+		return nil
+	}
+	return []*entry{e}
+}
@@ -0,0 +1,220 @@
+package directory
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sirupsen/logrus"
+	"net"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+// idleTimeout closes connections that sent no request for that long.
+const idleTimeout = 5 * time.Minute
+
+// Server is a read-only LDAP gateway to a phone book. It serves the contacts
+// as inetOrgPerson entries under a base DN, for desk phones and mail clients
+// looking up a corporate directory. Writes are refused.
+type Server struct {
+	phoneBook    definition.IPhoneBook
+	baseDN       *ldap.DN
+	bindDN       *ldap.DN
+	bindPassword string
+	maxResults   int
+
+	listener net.Listener
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a gateway serving the contacts of phoneBook under baseDN,
+// e.g. ou=contacts,dc=example,dc=com, returning up to maxResults entries per
+// search. When bindDN is set, clients must bind with it and bindPassword
+// before searching, otherwise anonymous searches are allowed.
+func NewServer(phoneBook definition.IPhoneBook, baseDN, bindDN, bindPassword string, maxResults int) (*Server, error) {
+	base, err := ldap.ParseDN(baseDN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base dn: %w", err)
+	}
+	server := &Server{
+		phoneBook:    phoneBook,
+		baseDN:       base,
+		bindPassword: bindPassword,
+		maxResults:   maxResults,
+		conns:        map[net.Conn]struct{}{},
+	}
+	if bindDN != "" {
+		server.bindDN, err = ldap.ParseDN(bindDN)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bind dn: %w", err)
+		}
+	}
+	return server, nil
+}
+
+// Start listens on addr and serves the connections in the background.
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	logrus.Infof("Starting ldap server on addr %v", listener.Addr())
+	s.wg.Add(1)
+	go s.accept()
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Stop closes the listener and the open connections, and waits for their
+// requests to finish.
+func (s *Server) Stop() {
+	s.listener.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Warn("failed to accept ldap connection")
+			continue
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serve(conn)
+	}
+}
+
+// session is the state of a connection.
+type session struct {
+	conn  net.Conn
+	bound bool
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	sess := &session{conn: conn, bound: s.bindDN == nil}
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		messageID, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		if !s.handle(sess, messageID, packet.Children[1]) {
+			return
+		}
+	}
+}
+
+// handle answers a request, returning false when the connection should be
+// closed.
+func (s *Server) handle(sess *session, messageID int64, op *ber.Packet) bool {
+	if op.ClassType != ber.ClassApplication {
+		return false
+	}
+	switch op.Tag {
+	case ldap.ApplicationBindRequest:
+		var code uint16
+		var message string
+		sess.bound, code, message = s.bind(op)
+		return sess.send(messageID, result(ldap.ApplicationBindResponse, code, message))
+	case ldap.ApplicationUnbindRequest:
+		return false
+	case ldap.ApplicationSearchRequest:
+		if !sess.bound {
+			return sess.send(messageID, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights, "bind first"))
+		}
+		return s.search(sess, messageID, op)
+	case ldap.ApplicationAbandonRequest:
+		// searches are answered at once, there is nothing to abandon
+		return true
+	case ldap.ApplicationModifyRequest, ldap.ApplicationAddRequest, ldap.ApplicationDelRequest,
+		ldap.ApplicationModifyDNRequest, ldap.ApplicationCompareRequest:
+		return sess.send(messageID, result(op.Tag+1, ldap.LDAPResultUnwillingToPerform, "the directory is read-only"))
+	case ldap.ApplicationExtendedRequest:
+		return sess.send(messageID, result(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "unsupported extended operation"))
+	}
+	return false
+}
+
+// bind checks simple binds against the bind DN, reporting whether the
+// connection may search. Anonymous binds succeed, but may only search when no
+// bind DN is configured.
+func (s *Server) bind(op *ber.Packet) (bool, uint16, string) {
+	if len(op.Children) < 3 {
+		return false, ldap.LDAPResultProtocolError, "invalid bind request"
+	}
+	name := op.Children[1].Data.String()
+	auth := op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		return false, ldap.LDAPResultAuthMethodNotSupported, "only simple bind is supported"
+	}
+	password := auth.Data.String()
+	if name == "" && password == "" {
+		return s.bindDN == nil, ldap.LDAPResultSuccess, ""
+	}
+	dn, err := ldap.ParseDN(name)
+	if err != nil || s.bindDN == nil || !s.bindDN.EqualFold(dn) ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(s.bindPassword)) != 1 {
+		return false, ldap.LDAPResultInvalidCredentials, "invalid credentials"
+	}
+	return true, ldap.LDAPResultSuccess, ""
+}
+
+func (sess *session) send(messageID int64, op *ber.Packet) bool {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	_, err := sess.conn.Write(packet.Bytes())
+	return err == nil
+}
+
+// result builds an LDAPResult response of the given application tag.
+func result(tag ber.Tag, code uint16, message string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, ldap.ApplicationMap[uint8(tag)])
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return op
+}
@@ -0,0 +1,166 @@
+package directory
+
+import (
+	"context"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"sort"
+	"testing"
+)
+
+// directoryPhoneBook serves the contacts of a slice, recording the searches.
+type directoryPhoneBook struct {
+	definition.IPhoneBook
+	contacts []*definition.Contact
+	searches []url.Values
+}
+
+func (pb *directoryPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	for _, contact := range pb.contacts {
+		if contact.ID.Hex() == id {
+			return contact, "", nil
+		}
+	}
+	return nil, core.NotFound, core.ErrContactNotFound
+}
+
+func (pb *directoryPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	pb.searches = append(pb.searches, query)
+	var matches []*definition.Contact
+	for _, contact := range pb.contacts {
+		if (query.Get("firstName") == "" || contact.FirstName == query.Get("firstName")) &&
+			(query.Get("phone") == "" || contact.Phone == query.Get("phone")) {
+			matches = append(matches, contact)
+		}
+	}
+	return &definition.ContactPage{Items: matches}, "", nil
+}
+
+// GetContactWithPagination pages through the contacts one at a time.
+func (pb *directoryPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	i := 0
+	if cursor := query.Get("cursor"); cursor != "" {
+		for pb.contacts[i].ID.Hex() != cursor {
+			i++
+		}
+		i++
+	}
+	page := &definition.ContactPage{Items: pb.contacts[i : i+1]}
+	if i+1 < len(pb.contacts) {
+		page.NextCursor = pb.contacts[i].ID.Hex()
+	}
+	return page, "", nil
+}
+
+const baseDN = "ou=contacts,dc=example,dc=com"
+
+func startServer(t *testing.T, bindDN string, maxResults int) (*directoryPhoneBook, *ldap.Conn) {
+	phoneBook := &directoryPhoneBook{contacts: []*definition.Contact{
+		{ID: primitive.NewObjectID(), FirstName: "Dani", LastName: "Cohen", Phone: "0521234567", Email: "dani@example.com", Address: "Tel Aviv"},
+		{ID: primitive.NewObjectID(), FirstName: "Dana", LastName: "Levi", Phone: "0527654321"},
+		{ID: primitive.NewObjectID(), FirstName: "Moshe", Phone: "0501111111"},
+	}}
+	server, err := NewServer(phoneBook, baseDN, bindDN, "secret", maxResults)
+	assert.Nil(t, err)
+	assert.Nil(t, server.Start("127.0.0.1:0"))
+	t.Cleanup(server.Stop)
+	conn, err := ldap.DialURL("ldap://" + server.Addr().String())
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return phoneBook, conn
+}
+
+func search(t *testing.T, conn *ldap.Conn, filter string, attributes ...string) []*ldap.Entry {
+	result, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, attributes, nil))
+	assert.Nil(t, err)
+	return result.Entries
+}
+
+func givenNames(entries []*ldap.Entry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.GetAttributeValue("givenName"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("should map contacts to inetOrgPerson entries", func(t *testing.T) {
+		phoneBook, conn := startServer(t, "", 100)
+		entries := search(t, conn, "(&(objectClass=inetOrgPerson)(givenName=Dani))")
+		assert.Len(t, entries, 1)
+		dani := phoneBook.contacts[0]
+		assert.Equal(t, "uid="+dani.ID.Hex()+","+baseDN, entries[0].DN)
+		assert.Equal(t, "Dani Cohen", entries[0].GetAttributeValue("cn"))
+		assert.Equal(t, "Cohen", entries[0].GetAttributeValue("sn"))
+		assert.Equal(t, "0521234567", entries[0].GetAttributeValue("telephoneNumber"))
+		assert.Equal(t, "dani@example.com", entries[0].GetAttributeValue("mail"))
+		assert.Equal(t, "Tel Aviv", entries[0].GetAttributeValue("postalAddress"))
+		assert.Contains(t, entries[0].GetAttributeValues("objectClass"), "inetOrgPerson")
+		assert.Equal(t, []url.Values{{"firstName": {"Dani"}, "pageSize": {"101"}, "count": {"false"}}}, phoneBook.searches)
+	})
+
+	t.Run("should scan the contacts for filters search can't match", func(t *testing.T) {
+		phoneBook, conn := startServer(t, "", 100)
+		assert.Equal(t, []string{"Dana", "Dani"}, givenNames(search(t, conn, "(&(|(cn=da*)(telephoneNumber=*1111))(!(cn=*moshe*)))")))
+		assert.Equal(t, []string{"Dana", "Dani", "Moshe"}, givenNames(search(t, conn, "(objectClass=*)")[1:]))
+		assert.Empty(t, phoneBook.searches)
+	})
+
+	t.Run("should return the requested attributes only", func(t *testing.T) {
+		_, conn := startServer(t, "", 100)
+		entries := search(t, conn, "(telephoneNumber=0527654321)", "cn", "mail")
+		assert.Len(t, entries, 1)
+		assert.Len(t, entries[0].Attributes, 1)
+		assert.Equal(t, "Dana Levi", entries[0].GetAttributeValue("cn"))
+	})
+
+	t.Run("should read a contact by its dn", func(t *testing.T) {
+		phoneBook, conn := startServer(t, "", 100)
+		moshe := phoneBook.contacts[2]
+		result, err := conn.Search(ldap.NewSearchRequest("uid="+moshe.ID.Hex()+","+baseDN, ldap.ScopeBaseObject,
+			ldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", nil, nil))
+		assert.Nil(t, err)
+		assert.Len(t, result.Entries, 1)
+		assert.Equal(t, "Moshe", result.Entries[0].GetAttributeValue("sn"))
+
+		_, err = conn.Search(ldap.NewSearchRequest("uid="+primitive.NewObjectID().Hex()+","+baseDN, ldap.ScopeBaseObject,
+			ldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", nil, nil))
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject))
+	})
+
+	t.Run("should cap the entries returned", func(t *testing.T) {
+		_, conn := startServer(t, "", 2)
+		result, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 0, 0, false,
+			"(objectClass=inetOrgPerson)", nil, nil))
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded))
+		assert.Len(t, result.Entries, 2)
+	})
+
+	t.Run("should refuse writes", func(t *testing.T) {
+		_, conn := startServer(t, "", 100)
+		err := conn.Del(ldap.NewDelRequest("uid=1,"+baseDN, nil))
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform))
+	})
+}
+
+func TestBind(t *testing.T) {
+	t.Run("should search only after binding with the bind dn", func(t *testing.T) {
+		_, conn := startServer(t, "cn=phones,dc=example,dc=com", 100)
+		_, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			"(objectClass=*)", nil, nil))
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights))
+
+		err = conn.Bind("cn=phones,dc=example,dc=com", "wrong")
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials))
+
+		assert.Nil(t, conn.Bind("CN=phones, dc=example, dc=com", "secret"))
+		assert.Len(t, search(t, conn, "(givenName=Moshe)"), 1)
+	})
+}
@@ -15,71 +15,182 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/contact": {
-            "get": {
-                "description": "Retrieve contacts with pagination support, up to 10 contacts for each page",
+        "/admin/backup": {
+            "post": {
+                "description": "Streams every contact, ordered by ID, as newline delimited canonical extended JSON or as concatenated BSON documents like mongodump writes",
                 "produces": [
-                    "application/json"
+                    "application/x-ndjson",
+                    "application/bson"
                 ],
-                "summary": "Get contacts with pagination",
+                "summary": "Back up the contacts",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Page number (default 1)",
-                        "name": "page",
+                        "description": "Backup format: json (default) or bson",
+                        "name": "format",
                         "in": "query"
                     }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "the contacts backup",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid format",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/config": {
+            "get": {
+                "description": "Returns the configuration in use, including tunable settings reloaded on SIGHUP. The Mongo URI password is masked",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the effective configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/crm/dead-letters": {
+            "get": {
+                "description": "Lists the contacts that failed to sync to the CRM, after running out of attempts or being refused by the CRM, oldest first. They are kept in memory by each replica",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the CRM dead letters",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/definition.Contact"
+                                "$ref": "#/definitions/definition.CRMDeadLetter"
                             }
                         }
+                    },
+                    "404": {
+                        "description": "the crm sync is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/admin/crm/dead-letters/retry": {
             "post": {
-                "description": "Add a new contact to the phone book",
-                "consumes": [
+                "description": "Queues the dead lettered contacts to sync to the CRM again, as they are stored by then",
+                "produces": [
                     "application/json"
                 ],
+                "summary": "Retry the CRM dead letters",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.retryResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "the crm sync is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/indexes": {
+            "get": {
+                "description": "Returns the indexes of the contacts collection, including those created on startup",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Add a new contact",
-                "parameters": [
-                    {
-                        "description": "Contact object that needs to be added. If you include _id, ensure it is a 24-character string. Alternatively, omit this field from the object, as it will be automatically generated by the database",
-                        "name": "contact",
-                        "in": "body",
-                        "required": true,
+                "summary": "List the contacts collection indexes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/definition.Contact"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Index"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/admin/jobs": {
+            "get": {
+                "description": "Lists the admin jobs of the tenant, like the reindexes, most recent first: those queued, running and finished within the job retention. With MongoDB every replica records its jobs in a shared collection, with the other backends each replica keeps its last 100 jobs until it restarts",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Type of the jobs, e.g. reindex",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "queued, running, succeeded, failed or canceled",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of jobs (default 10), clamped to the server maximum",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Contact added successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Job"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid status or limit",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
                         }
                     }
                 }
             }
         },
-        "/contact/delete/{id}": {
-            "delete": {
-                "description": "Deletes a contact by its ID",
-                "summary": "Delete a contact by ID",
+        "/admin/jobs/{id}": {
+            "get": {
+                "description": "Reports the progress of an admin job running in the background, like a reindex: the step it is at and the items it went through of total, then its result or error",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the status of a job",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Contact ID (24 characters)",
+                        "description": "Job ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -87,120 +198,4400 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message indicating successful deletion",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/definition.Job"
                         }
                     },
-                    "500": {
-                        "description": "invalid contact",
+                    "404": {
+                        "description": "job not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancels a queued or running job, which stops at its next step or batch and ends canceled. Answers 202 with the job, still running until it stopped. Only the replica running a job can cancel it",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Cancel a job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "job not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "the job already finished, or runs on another replica",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
                         }
                     }
                 }
             }
         },
-        "/contact/edit/{id}": {
-            "put": {
-                "description": "Updates a contact by its ID",
-                "summary": "Update a contact by ID",
+        "/admin/jobs/{id}/report": {
+            "get": {
+                "description": "Downloads the report a job attached, like the CSV of the rows an import failed on, with their row, error code, field and message. The report is attached once the job finished",
+                "produces": [
+                    "text/csv"
+                ],
+                "summary": "Download the report of a job",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Contact ID (24 characters)",
+                        "description": "Job ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the report",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
+                    "404": {
+                        "description": "job or report not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/maintenance": {
+            "get": {
+                "description": "Tells whether the API is read-only for maintenance",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the maintenance mode",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.maintenanceStatus"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Makes the API read-only, for migrations and restores: reads keep working while mutations fail with 503, code MAINTENANCE, and the message if one is sent. Backups and restores keep working. Applies to this replica only, until it restarts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Switch the maintenance mode",
+                "parameters": [
                     {
-                        "description": "Contact details to update",
-                        "name": "contact",
+                        "description": "enabled, and the optional message of the rejected mutations",
+                        "name": "maintenance",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/definition.Contact"
+                            "$ref": "#/definitions/server.maintenanceStatus"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message indicating successful update",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.maintenanceStatus"
                         }
                     },
-                    "500": {
-                        "description": "invalid contact",
+                    "400": {
+                        "description": "invalid body",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.errorResponse"
                         }
                     }
                 }
             }
         },
-        "/contact/search": {
-            "get": {
-                "description": "Searches for contacts based on parameters (firstName, lastName, phone, address). If no parameters are provided, returns all contacts.",
-                "summary": "Search contacts",
+        "/admin/migrate": {
+            "post": {
+                "description": "Upgrades the contacts written at older schema versions by the migrations since, in order, like on startup with MONGO_MIGRATE_ON_STARTUP. With dryRun only reports the migrations pending and their contacts. Allowed during maintenance",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Migrate the contacts",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "firsName",
-                        "name": "firstName",
+                        "type": "boolean",
+                        "description": "Only report the pending migrations",
+                        "name": "dryRun",
                         "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.MigrationResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid dryRun",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
                     },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reindex": {
+            "post": {
+                "description": "Rebuilds in the background what is derived from the contacts: recomputes the display name, normalized phone and initial of every contact, drops and creates anew the text and secondary indexes, then rebuilds the indexes of the configured search engines. Answers 202 with the job, whose progress GET /admin/jobs/{id} reports. A single reindex of a tenant runs at a time on a replica. Allowed during maintenance. Derived data is rebuilt with MongoDB only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Rebuild the derived data",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Job"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the job"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "a reindex is already running",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/restore": {
+            "post": {
+                "description": "Writes the contacts of a backup made by /admin/backup. merge adds them and overwrites the contacts with the same ID, replace deletes every contact first. An invalid backup is rejected before any contact is written",
+                "consumes": [
+                    "application/x-ndjson",
+                    "application/bson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Restore the contacts",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "lastName",
-                        "name": "lastName",
+                        "description": "Backup format: json (default) or bson",
+                        "name": "format",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "phone",
-                        "name": "phone",
+                        "description": "Restore mode: merge (default) or replace",
+                        "name": "mode",
                         "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "address",
-                        "name": "address",
-                        "in": "query"
+                        "description": "the contacts backup",
+                        "name": "backup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/definition.Contact"
-                            }
+                            "$ref": "#/definitions/definition.RestoreResult"
                         }
-                    }
-                }
-            }
+                    },
+                    "400": {
+                        "description": "invalid backup, format or mode",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "backup larger than MAX_RESTORE_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retention": {
+            "post": {
+                "description": "Anonymizes or purges the contacts not updated for days days, the RETENTION_DAYS and RETENTION_ACTION policy unless given. Anonymizing clears every field but the company and job title, purging deletes the contacts. Either way their history, interactions and reminders are deleted. With dryRun only reports the contacts",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Apply a retention policy",
+                "parameters": [
+                    {
+                        "enum": [
+                            "anonymize",
+                            "purge"
+                        ],
+                        "type": "string",
+                        "description": "anonymize or purge",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Days since the last update",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only report the contacts",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.RetentionResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid action or days",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/search/reindex": {
+            "post": {
+                "description": "Indexes the contacts of the phone book anew in the search index of engine, then drops the contacts it no longer has. Searches keep answering meanwhile. Allowed during maintenance",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Rebuild a search index",
+                "parameters": [
+                    {
+                        "enum": [
+                            "es",
+                            "embedded"
+                        ],
+                        "type": "string",
+                        "description": "Search engine of the index, es or embedded",
+                        "name": "engine",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.reindexResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid engine",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "the search index of engine is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/seed": {
+            "post": {
+                "description": "Adds count realistic fake contacts, for demos and load tests. Generated contacts duplicating existing ones are skipped. Requires SEED_ENABLED",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Seed fake contacts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of contacts to generate, up to 10000",
+                        "name": "count",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.seedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid count",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "seeding is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants": {
+            "get": {
+                "description": "Returns every tenant, by id. Requires TENANCY_ENABLED",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the tenants",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Tenant"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "tenancy is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a tenant with a phone book of its own, in a database of its own. Requests are scoped to it by its id in the X-Tenant-ID header, or as the subdomain of TENANT_DOMAIN. Requires TENANCY_ENABLED",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Provision a tenant",
+                "parameters": [
+                    {
+                        "description": "id of the tenant, up to 32 lowercase letters, digits and dashes, its optional name and limits",
+                        "name": "tenant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Tenant"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Tenant"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid tenant id or limits",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "tenancy is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "tenant already exists",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}": {
+            "get": {
+                "description": "Returns the tenant with the id. Requires TENANCY_ENABLED",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Tenant"
+                        }
+                    },
+                    "404": {
+                        "description": "tenant not found or tenancy is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the tenant and drops the database of its phone book, with all its contacts. Other replicas may keep serving it for up to a minute. Requires TENANCY_ENABLED",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful deletion",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "tenant not found or tenancy is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}/limits": {
+            "put": {
+                "description": "Replaces the limits of the tenant. A zero or missing limit falls back to TENANT_MAX_CONTACTS or TENANT_MAX_REQUESTS_PER_MINUTE. Other replicas apply the new limits within a minute. Requires TENANCY_ENABLED",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Set the limits of a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "maxContacts and maxRequestsPerMinute of the tenant",
+                        "name": "limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.TenantLimits"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Tenant"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid limits",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "tenant not found or tenancy is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}/usage": {
+            "get": {
+                "description": "Returns the number of contacts, interactions, reminders and history entries of the tenant, the bytes its database takes, and the limits applying to it. Requires TENANCY_ENABLED",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the usage of a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.tenantUsage"
+                        }
+                    },
+                    "404": {
+                        "description": "tenant not found or tenancy is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/validate": {
+            "post": {
+                "description": "Checks every contact against the validation rules of new contacts, e.g. after an import from a legacy system, and reports the contacts breaking them, listing the first 1000 issues. With fix, repairs the issues normalizing resolves, like formatted phone numbers or surrounding blanks, as a patch of each contact; the others are left to fix by hand",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Validate the stored contacts",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Repair the fixable issues",
+                        "name": "fix",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ValidationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid fix",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/blocklist": {
+            "get": {
+                "description": "Returns the phones of the blocked contacts, archived ones included, in the order they were added. number is the phone normalized with its country code, as it is checked",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the blocked numbers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.BlockedNumber"
+                            }
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/blocklist/check": {
+            "get": {
+                "description": "Tells whether a phone number is the phone of a blocked contact, for telephony systems to check before dialing or when a call comes in. The number is normalized like for the phone lookup, and the checks are cached when CACHE_ENABLED is set",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Check a number against the blocklist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number, digits optionally formatted with +, spaces, dashes, dots or parentheses",
+                        "name": "number",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.BlockCheck"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid phone number",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/company": {
+            "get": {
+                "description": "Returns the distinct companies of the contacts in alphabetical order, for autocompletion, up to the server maximum page size",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List companies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the company names, ignoring case",
+                        "name": "prefix",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "prefix too long",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/company/{name}/contacts": {
+            "get": {
+                "description": "Returns everyone working at the company, paginated like the contacts listing",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the contacts of a company",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Company name, exactly as listed by /company",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Page number (default 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contacts per page (default 10), clamped to the server maximum",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page, empty for the first page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Listing order: updatedAt or createdAt, most recent first, or displayName, alphabetically. Without sort the pinned contacts are listed first, in their order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count the total contacts and pages (default true)",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the archived contacts (default false)",
+                        "name": "includeArchived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ContactPage"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the first, prev, next and last pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid company name, pagination parameters or region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact": {
+            "get": {
+                "description": "Retrieve contacts with pagination support, 10 contacts for each page unless pageSize is sent. Send cursor (empty for the first page) to page by the nextCursor of the previous page instead of by page number, in insertion order. Pages carry an ETag and Last-Modified of the last change to the contacts: send them back in If-None-Match or If-Modified-Since to get 304 Not Modified while the contacts are unchanged",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Get contacts with pagination",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Page number (default 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contacts per page (default 10), clamped to the server maximum",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page, empty for the first page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Listing order: updatedAt or createdAt, most recent first, or displayName, alphabetically. Without sort the pinned contacts are listed first, in their order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count the total contacts and pages (default true)",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Letter of the alphabetical index to list, e.g. B, or # for the names not starting with a letter",
+                        "name": "startsWith",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the archived contacts (default false)",
+                        "name": "includeArchived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached page",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of the cached page",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ContactPage"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the first, prev, next and last pages"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "contacts are unchanged"
+                    },
+                    "400": {
+                        "description": "invalid pagination, startsWith or region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a new contact to the phone book",
+                "consumes": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Add a new contact",
+                "parameters": [
+                    {
+                        "description": "Contact object that needs to be added. If you include _id, ensure it is a 24-character string. Alternatively, omit this field from the object, as it will be automatically generated by the database",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Contact added successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a contact with the same details already exists",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the contacts listed in ids, or those matching every field of filter exactly, up to 1000 at once. With dryRun only reports the contacts that would be deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete contacts in a batch",
+                "parameters": [
+                    {
+                        "description": "ids or filter of the contacts to delete",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.BatchDelete"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.BatchDeleteResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid batch",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "deletes blocked for deleting too many contacts",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/by-phone/{number}": {
+            "get": {
+                "description": "Returns the contacts with the phone number, for caller ID lookups. The number is normalized before matching, so +972 52-123-4567, 00972521234567 and 0521234567 find the same contacts",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Look up contacts by phone number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number, digits optionally formatted with +, spaces, dashes, dots or parentheses",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Contact"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid phone number or region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "no contact has this phone number",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates the contact with the phone number like PUT /contact/edit/{id}, or adds it when no contact has the number, for sync clients that don't keep our contact IDs. The number is normalized before matching like for the lookups, and is the phone of the contact unless the body sends it formatted otherwise. The contact is validated whole like a new contact. Contacts are upserted with MongoDB only",
+                "consumes": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Upsert a contact by phone number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number, digits optionally formatted with +, spaces, dashes, dots or parentheses",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Contact details",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "201": {
+                        "description": "ID of the contact added",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid phone number or contact, or a phone other than the number",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "the contact would exceed the contact limit of the tenant",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "a contact with the same details already exists",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/changes": {
+            "get": {
+                "description": "Returns the contacts created and updated since a time or a sync token, as they are now, and the tombstones of those deleted, with when and by whom, for offline clients to sync incrementally. Without since returns every contact as created. Send the syncToken of the response as since to get the next changes, right away while more is true. Like GET /sync, times older than SYNC_TOMBSTONE_TTL and tokens expired answer 410, and the client has to download every contact again",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the contacts changed since",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, e.g. 2024-01-12T10:00:00Z, or sync token of the last changes synced",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of changes",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.changesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid since or limit",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "since is too old or the sync token expired",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/count": {
+            "get": {
+                "description": "Returns the number of contacts matching the search parameters (firstName, lastName, phone, email, company, jobTitle, address, notes, namePrefix), counted by the database without reading them, for dashboards. Other parameters are rejected. Without parameters counts every contact. Contacts are counted with MongoDB only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Count contacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "firstName",
+                        "name": "firstName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "lastName",
+                        "name": "lastName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "phone",
+                        "name": "phone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "company",
+                        "name": "company",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "jobTitle",
+                        "name": "jobTitle",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "address",
+                        "name": "address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "notes",
+                        "name": "notes",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Country of the phone, an ISO code like IL, short for phoneCountry",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Type of the phone: mobile, fixed, fixedOrMobile, tollFree, sharedCost, premium, voip or other",
+                        "name": "phoneType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Area of a fixed line phone, e.g. Jerusalem",
+                        "name": "phoneArea",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the display name, ignoring case and accents",
+                        "name": "namePrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the archived contacts (default false)",
+                        "name": "includeArchived",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.countResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "unknown search field or invalid value",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/delete/{id}": {
+            "delete": {
+                "description": "Deletes a contact by its ID",
+                "summary": "Delete a contact by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful deletion",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "deletes blocked for deleting too many contacts",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "invalid contact",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/distinct": {
+            "get": {
+                "description": "Returns the values of field present in the contacts, with the number of contacts holding each, in alphabetical order ignoring case, for filter dropdowns. Values differing in case only are counted together. Up to the server maximum page size values are returned. Addresses can't be listed with field encryption. Values are listed with MongoDB only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the distinct values of a field",
+                "parameters": [
+                    {
+                        "enum": [
+                            "address",
+                            "company",
+                            "jobTitle",
+                            "lastName"
+                        ],
+                        "type": "string",
+                        "description": "Field to list the values of",
+                        "name": "field",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.ValueCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid or encrypted field",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/edit/{id}": {
+            "put": {
+                "description": "Updates a contact by its ID. Send the contact version in If-Match to reject the update if the contact was changed meanwhile.\nWith upsert the contact is added with the ID when it doesn't exist, for sync clients keeping the IDs they assigned, and is validated whole like a new contact. Upserts don't take If-Match",
+                "consumes": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Update a contact by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expected contact version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add the contact when it doesn't exist",
+                        "name": "upsert",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Contact details to update",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful update, or no changes when the contact already holds the values sent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "201": {
+                        "description": "ID of the contact upserted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid contact or upsert",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "an upsert would exceed the contact limit of the tenant",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "invalid contact",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/erase": {
+            "delete": {
+                "description": "Deletes every contact whose phone is the given number once both are normalized, for requests to be forgotten, with their history, interactions and reminders, and the history entries holding the number, like those of contacts deleted earlier. Contacts with the number are left out of later restores of older backups. Erasures aren't recorded in the history and can't be undone",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Erase the contacts of a phone number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number, e.g. +972 52-123-4567",
+                        "name": "phone",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ErasureResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid phone number",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "deletes blocked for deleting too many contacts",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/export/ndjson": {
+            "get": {
+                "description": "Streams the contacts matching the search parameters (firstName, lastName, phone, email, company, jobTitle, address, notes) as newline delimited JSON, ordered by ID. To resume an interrupted export, send the _id of the last contact received as after",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "summary": "Export contacts as JSON Lines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "firstName",
+                        "name": "firstName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "lastName",
+                        "name": "lastName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "phone",
+                        "name": "phone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "company",
+                        "name": "company",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "jobTitle",
+                        "name": "jobTitle",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "address",
+                        "name": "address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "notes",
+                        "name": "notes",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User who added the contact",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export the contacts after this contact ID (24 characters)",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "one contact per line",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "unknown search field, invalid value or invalid after",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/import": {
+            "post": {
+                "description": "Adds the contacts of a CSV file with a header row naming the contact fields, like the exports write, or of a vCard file, by the Content-Type. The id, createdAt and updatedAt columns are skipped, other unknown columns reject the file. Contacts duplicating existing ones are skipped, and invalid ones fail with their row, counted from 1 in the order of the file. Up to MAX_IMPORT_BYTES are read. With async=true the file is stored and imported by a background job: the response is 202 with the job, whose GET /admin/jobs/{id} reports the rows imported of total, then the result, and GET /admin/jobs/{id}/report downloads the CSV report of the failed rows. Contacts are imported with MongoDB only",
+                "consumes": [
+                    "text/csv",
+                    "text/vcard"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import a CSV or vCard file",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Import in the background and answer with the job (default false)",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "description": "The CSV or vCard file",
+                        "name": "contacts",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.importResult"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Job"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the job"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid content type, async or CSV header",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "the file is larger than MAX_IMPORT_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/index": {
+            "get": {
+                "description": "Returns the number of contacts per starting letter of their last name, or first name when they have none, ignoring case and diacritics, for jump to letter lists. Letters without contacts are left out and # counts the names not starting with a letter. List a letter with GET /contact?startsWith=",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the alphabetical index",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.LetterCount"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/order": {
+            "put": {
+                "description": "Pins the contacts of ids to the top of the contact listings in the order sent, up to 100, and unpins those pinned before and left out. Send no ids to unpin every contact. The listings sorted by a field keep the order of that field",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Pin contacts to the top of the listings",
+                "parameters": [
+                    {
+                        "description": "ids of the contacts to pin, in order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.ContactOrder"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ReorderResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid order or id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/random": {
+            "get": {
+                "description": "Returns a contact picked at random, for load tests reading across the whole phone book",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a random contact",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    },
+                    "404": {
+                        "description": "the phone book is empty",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/recent": {
+            "get": {
+                "description": "Returns the most recently added contacts, or the most recently updated ones with by=updated, most recent first, for a Recent tab",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Get the recent contacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "created (default) or updated",
+                        "name": "by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of contacts (default 10), clamped to the server maximum",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the archived contacts (default false)",
+                        "name": "includeArchived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Contact"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid by, limit, fields or region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/sample": {
+            "get": {
+                "description": "Returns contacts picked at random, fewer when the phone book holds fewer",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Sample random contacts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of contacts (default the page size), clamped to the server maximum",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Contact"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid size",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/schema": {
+            "get": {
+                "description": "The JSON Schema the contacts sent to add, update and upsert are validated against, for clients to validate them the same way before sending. It is built from the contact of this spec",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the JSON Schema of a contact",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/search": {
+            "get": {
+                "description": "Searches for contacts based on parameters (firstName, lastName, phone, email, company, jobTitle, address, notes, namePrefix). Other parameters are rejected. If no parameters are provided, returns the first page of contacts. The number of matching contacts is sent in the X-Total-Count header unless count=false.\nWith engine=es the search runs on the Elasticsearch index instead, and with engine=embedded on the index kept in memory: the fields, and q over all of them, match allowing typos, best matches first. The embedded engine also matches \"quoted phrases\" exactly.\nThe database results carry an ETag and Last-Modified of the last change to the contacts: send them back in If-None-Match or If-Modified-Since to get 304 Not Modified while the contacts are unchanged.",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Search contacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "firsName",
+                        "name": "firstName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "lastName",
+                        "name": "lastName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "phone",
+                        "name": "phone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "company",
+                        "name": "company",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "jobTitle",
+                        "name": "jobTitle",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "address",
+                        "name": "address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "notes",
+                        "name": "notes",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User who added the contact",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Country of the phone, an ISO code like IL, short for phoneCountry",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Type of the phone: mobile, fixed, fixedOrMobile, tollFree, sharedCost, premium, voip or other",
+                        "name": "phoneType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Area of a fixed line phone, e.g. Jerusalem",
+                        "name": "phoneArea",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the display name, ignoring case and accents, e.g. emi matches Émile. Results are sorted by display name",
+                        "name": "namePrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words to match in any field, with engine=es or embedded only",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "db",
+                            "es",
+                            "embedded"
+                        ],
+                        "type": "string",
+                        "description": "Search engine, db (default), es or embedded",
+                        "name": "engine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of contacts to return (default 10), clamped to the server maximum",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number of pageSize contacts (default 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count the matching contacts (default true)",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the archived contacts (default false)",
+                        "name": "includeArchived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached results, engine=db only",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of the cached results, engine=db only",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Contact"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the first, prev, next and last pages"
+                            },
+                            "X-Total-Count": {
+                                "type": "int",
+                                "description": "Number of matching contacts, unless count=false"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "contacts are unchanged"
+                    },
+                    "400": {
+                        "description": "unknown search field, invalid value or region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "engine=es or embedded without the search index configured",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/search/text": {
+            "get": {
+                "description": "Searches the words of q in firstName, lastName, address and notes, best matches first",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Full-text search contacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Words to search for, e.g. dani cohen tel aviv",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of contacts to return (default 10), clamped to the server maximum",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the archived contacts (default false)",
+                        "name": "includeArchived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Contact"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "missing search text or invalid region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/stream": {
+            "post": {
+                "description": "Adds the contacts of a newline delimited JSON body, one contact per line, for ETL pipelines pushing millions of contacts on a long-lived request. The contacts are validated and added in batches of up to 500 as they arrive, and the result of each line is streamed back as it is added, with the id of the contact or the error it was rejected with, followed by a summary line. Each line is bounded by MAX_BODY_BYTES, not the whole body. Contacts are streamed in with MongoDB only",
+                "consumes": [
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "summary": "Stream contacts in",
+                "parameters": [
+                    {
+                        "description": "One contact per line, as for POST /contact",
+                        "name": "contacts",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "one result per line, then a server.streamSummary",
+                        "schema": {
+                            "$ref": "#/definitions/server.streamResult"
+                        }
+                    },
+                    "402": {
+                        "description": "the contacts would exceed the contact limit of the tenant",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}": {
+            "get": {
+                "description": "Returns a contact with its ETag. Send the ETag back in If-None-Match to get 304 Not Modified while the contact is unchanged, or in If-Match to update it only if unchanged",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Get a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached contact",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    },
+                    "304": {
+                        "description": "contact is unchanged"
+                    },
+                    "400": {
+                        "description": "invalid region",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates only the fields present in the body. A field sent as null is cleared",
+                "consumes": [
+                    "application/json"
+                ],
+                "summary": "Partially update a contact by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expected contact version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Contact fields to update",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful update, or no changes when the contact already holds the values sent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid contact",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/archive": {
+            "post": {
+                "description": "Hides the contact from the listings and searches without deleting it. Archived contacts are still returned by their ID, and by the listings and searches sent includeArchived=true",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Archive a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful archive, or that the contact was archived already",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/block": {
+            "post": {
+                "description": "Puts the phone of the contact on the blocklist, the numbers not to call or to reject calls from",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Block a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful block, or that the contact was blocked already",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/history": {
+            "get": {
+                "description": "Returns every recorded add, update and delete of a contact, most recent first. Requires the audit log to be enabled",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get contact change history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.AuditEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "audit log is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/interactions": {
+            "get": {
+                "description": "Returns a page of the timeline of the contact, most recent first. The timeline of a deleted contact is kept until the contact is restored",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the interactions with a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Interactions per page, up to MAX_PAGE_SIZE (default LIMIT_PER_PAGE)",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to count the interactions for totalItems and totalPages (default true)",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.InteractionPage"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the first, prev, next and last pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id or pagination",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Records a call, meeting or note on the timeline of the contact. occurredAt defaults to now and can't be in the future",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Record an interaction with a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The interaction type (call, meeting or note), summary and optional occurredAt. The _id, contactId, actor and createdAt are set by the server",
+                        "name": "interaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Interaction"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ID of the recorded interaction",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid interaction",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/interactions/{interactionId}": {
+            "delete": {
+                "description": "Removes an interaction from the timeline of the contact",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete an interaction with a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Interaction ID (24 characters)",
+                        "name": "interactionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful deletion",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "interaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/related": {
+            "get": {
+                "description": "Returns the contacts linked to the contact with the type of each link, most recently linked first. Links to deleted contacts are left out",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the related contacts of a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.RelatedContact"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/relations": {
+            "post": {
+                "description": "Links the contact to another one by the type of their relation, e.g. manager when the other contact is the manager of this one. Linking contacts already linked replaces the type",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Link a contact to another",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The linked contact and the type of the relation: spouse, assistant, manager or colleague",
+                        "name": "relation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Relation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful link",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid relation",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/relations/{relatedId}": {
+            "delete": {
+                "description": "Removes the link of the contact to another one",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Unlink a contact from another",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the linked contact (24 characters)",
+                        "name": "relatedId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful unlink",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found or the contacts are not linked",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/reminder": {
+            "post": {
+                "description": "Schedules a reminder, sent to REMINDER_WEBHOOK_URL with the contact once it's due",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Schedule a reminder to follow up with a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The dueAt time and note of the reminder. The _id, contactId, actor, createdAt and firedAt are set by the server",
+                        "name": "reminder",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.Reminder"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ID of the scheduled reminder",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid reminder",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/unarchive": {
+            "post": {
+                "description": "Shows an archived contact in the listings and searches again",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Unarchive a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful unarchive, or that the contact wasn't archived",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/unblock": {
+            "post": {
+                "description": "Takes the phone of the contact off the blocklist",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Unblock a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating successful unblock, or that the contact wasn't blocked",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact was modified by another client",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contact/{id}/undo": {
+            "post": {
+                "description": "Reverts the most recent update of a contact, or restores it if it was deleted. Requires the audit log to be enabled",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Undo the last change of a contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Contact ID (24 characters)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.Contact"
+                        }
+                    },
+                    "404": {
+                        "description": "contact has no change to undo",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "contact already exists",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/integrations/callerid": {
+            "get": {
+                "description": "Returns the name of the contact with the phone number, for the caller ID lookup sources of PBX systems. format=json (default) returns {name, company} for 3CX style templates, format=text the name alone as FreePBX expects, empty when the number is unknown. Responses are cacheable for CALLERID_MAX_AGE",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "summary": "Caller ID lookup for PBX systems",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number of the caller, digits optionally formatted with +, spaces, dashes, dots or parentheses",
+                        "name": "number",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format: json (default) or text",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.callerIDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid phone number or format",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "unknown number, json format only",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/integrations/google/callback": {
+            "get": {
+                "description": "Google redirects here once the user allowed reading their contacts. Adds the names, phones and emails of the contacts, skipping those whose phone is in the phone book already",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import the contacts of a Google account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State of the flow started by /integrations/google/connect",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid state or authorization denied",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "google import is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "google rejected the code or failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/integrations/google/connect": {
+            "get": {
+                "description": "Redirects to the Google consent page, which redirects back to /integrations/google/callback to import the contacts of the account. Requires GOOGLE_CLIENT_ID",
+                "summary": "Connect a Google account",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "google import is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/export": {
+            "get": {
+                "description": "Streams a zip of the contacts the authenticated user added, as contacts.json, contacts.csv and contacts.vcf (vCard 3.0). The user is given by the X-User header, taken only from the TRUSTED_PROXIES",
+                "produces": [
+                    "application/zip"
+                ],
+                "summary": "Export my data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User authenticated by the trusted proxy",
+                        "name": "X-User",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "zip archive",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "no authenticated user",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reminders": {
+            "get": {
+                "description": "Returns the reminders not sent yet in due order, up to MAX_PAGE_SIZE",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the pending reminders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "today for the reminders due by the end of today, in the time zone of the server, overdue for those already due",
+                        "name": "due",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.Reminder"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid due",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/speed-dial": {
+            "get": {
+                "description": "Returns the assigned speed dial slots in order, each with its contact. The slots of deleted contacts are listed without a contact, and get it back when the delete is undone",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the speed dial slots",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/definition.SpeedDial"
+                            }
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/speed-dial/export": {
+            "get": {
+                "description": "Returns the speed dial slots in order, for IP phones to load. format=xml (default) returns a CiscoIPPhoneDirectory, read by Cisco IP phones and by the XML phonebooks of Yealink, Grandstream and Fanvil phones, each entry named by its slot and contact. format=csv returns the slot, name and phone of each slot with a header row. The slots of deleted contacts, and of contacts without a phone, are left out",
+                "produces": [
+                    "text/xml",
+                    "text/csv"
+                ],
+                "summary": "Export the speed dials for IP phones",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: xml (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The speed dials",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid format",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/speed-dial/{slot}": {
+            "put": {
+                "description": "Assigns a speed dial slot, from 1 to 99, to a contact. A slot holds a single contact and a contact a single slot: clear the slot, or the slot of the contact, before assigning them again. Assigning a slot to its contact again changes nothing",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Assign a speed dial slot",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Speed dial slot, from 1 to 99",
+                        "name": "slot",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The contactId of the slot. The slot, actor and assignedAt are set by the server",
+                        "name": "speedDial",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/definition.SpeedDial"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.SpeedDial"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid slot or contact id",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "slot assigned to another contact, or contact assigned to another slot",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Unassigns a speed dial slot",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Clear a speed dial slot",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Speed dial slot, from 1 to 99",
+                        "name": "slot",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message indicating the slot was cleared, or wasn't assigned",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid slot",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sync": {
+            "get": {
+                "description": "Returns the changes to the contacts since token, oldest first: the contacts added or updated, as they are now, and the tombstones of those deleted, with when and by whom. Without a token returns every contact, for a first sync. Send the syncToken of the response to get the next changes, right away while more is true. Tokens expire after SYNC_TOMBSTONE_TTL, or when a backup is restored, and the client has to sync every contact again without a token",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the changes to the contacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sync token of the last changes synced",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of changes",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/definition.ChangePage"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid token or limit",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "the sync token expired",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "not supported by the storage backend",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Applies the changes a client made to its copy of the contacts, in order, up to 1000 at once. Changes to existing contacts carry the version the client last synced, and are applied as is unless the contact was changed since. Then the lww policy applies the change only when modifiedAt is after the last update, and the merge policy applies the fields whose value is still their base value, keeping the value of the others. Deleting a contact changed since is a conflict under merge, changing a contact deleted since is a conflict under both. New contacts carry a clientId instead of an id. The policy is SYNC_CONFLICT_POLICY unless given",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Sync the changes of a client",
+                "parameters": [
+                    {
+                        "enum": [
+                            "lww",
+                            "merge"
+                        ],
+                        "type": "string",
+                        "description": "Conflict policy",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Changes of the client",
+                        "name": "changes",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server.syncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.syncResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid policy or changes",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ws": {
+            "get": {
+                "description": "Upgrades to a WebSocket exchanging JSON messages. Send {\"type\":\"subscribe\"} (optionally with contactId) to receive {\"type\":\"event\"} messages for every change, and {\"type\":\"unsubscribe\"} to stop. When enabled, add, update, patch and delete messages mutate contacts like the http endpoints, answered by a result or error message with the same id",
+                "summary": "Real-time changes over WebSocket",
+                "responses": {}
+            }
         }
     },
     "definitions": {
+        "definition.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string"
+                },
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "$ref": "#/definitions/definition.Contact"
+                },
+                "before": {
+                    "$ref": "#/definitions/definition.Contact"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.FieldChange"
+                    }
+                },
+                "contactId": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.BatchDelete": {
+            "type": "object",
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "filter": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "definition.BatchDeleteResult": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "definition.BlockCheck": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "type": "boolean"
+                },
+                "number": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.BlockedNumber": {
+            "type": "object",
+            "properties": {
+                "contactId": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.CRMDeadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "contactId": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ChangePage": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.ContactChange"
+                    }
+                },
+                "more": {
+                    "type": "boolean"
+                },
+                "syncToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ChangeResult": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "conflicts": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "contact": {
+                    "$ref": "#/definitions/definition.Contact"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ClientChange": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "clientId": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "fields": {
+                    "$ref": "#/definitions/definition.ContactPatch"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "modifiedAt": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
         "definition.Contact": {
             "type": "object",
             "properties": {
                 "_id": {
                     "type": "string"
                 },
-                "address": {
+                "address": {
+                    "type": "string"
+                },
+                "archived": {
+                    "description": "Archived hides the contact from the listings and searches, unless\nthey include the archived contacts. It is changed through the archive\nendpoints only, and ignored when sent.",
+                    "type": "boolean"
+                },
+                "blocked": {
+                    "description": "Blocked puts the phone of the contact on the blocklist, the numbers\nnot to call or to reject calls from. It is changed through the block\nendpoints only, and ignored when sent.",
+                    "type": "boolean"
+                },
+                "company": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "description": "The derived fields are set by the phone book on every write, and\nignored when sent. DisplayName is the names normalized for sorting and\nprefix search, NormalizedPhone the phone with its country code for\nreverse lookups and Initial the letter of the alphabetical index.",
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "jobTitle": {
+                    "type": "string"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "Owner is the user who added the contact, stamped by the phone book from\nthe request and ignored when sent.",
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "phoneArea": {
+                    "type": "string"
+                },
+                "phoneCountry": {
+                    "description": "PhoneCountry, PhoneType and PhoneArea are derived from the phone number\nmetadata too: the ISO 3166-1 alpha-2 code of the region of the phone,\nits type, like mobile or fixed, and the area of a fixed line. They are\nleft out for the numbers of an unknown region.",
+                    "type": "string"
+                },
+                "phoneE164": {
+                    "description": "PhoneE164 and PhoneFormatted are the phone in E.164 form and as\ndisplayed in the region of the request. They are set in the responses\nonly, never stored, and left out when the phone isn't a number of a\nknown region.",
+                    "type": "string"
+                },
+                "phoneFormatted": {
+                    "type": "string"
+                },
+                "phoneType": {
+                    "type": "string"
+                },
+                "relations": {
+                    "description": "Relations link the contact to others. They are changed through the\nrelations endpoints only, and ignored when sent.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.Relation"
+                    }
+                },
+                "sortWeight": {
+                    "description": "SortWeight pins the contact to the top of the listings, the heaviest\nfirst. It is changed through the order endpoint only, and ignored when\nsent.",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.ContactChange": {
+            "type": "object",
+            "properties": {
+                "contact": {
+                    "$ref": "#/definitions/definition.Contact"
+                },
+                "created": {
+                    "type": "boolean"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "deletedBy": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "syncToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ContactIssue": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "fixable": {
+                    "type": "boolean"
+                },
+                "fixed": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ContactOrder": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "definition.ContactPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.Contact"
+                    }
+                },
+                "nextCursor": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "totalItems": {
+                    "type": "integer"
+                },
+                "totalPages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.ContactPatch": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "string"
+            }
+        },
+        "definition.ErasureResult": {
+            "type": "object",
+            "properties": {
+                "auditEntries": {
+                    "type": "integer"
+                },
+                "contacts": {
+                    "type": "integer"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "interactions": {
+                    "type": "integer"
+                },
+                "reminders": {
+                    "type": "integer"
+                },
+                "speedDials": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.FieldChange": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {},
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ImportResult": {
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.Index": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.IndexKey"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "unique": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "definition.IndexKey": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "order": {}
+            }
+        },
+        "definition.Interaction": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "contactId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "occurredAt": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.InteractionPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.Interaction"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "totalItems": {
+                    "type": "integer"
+                },
+                "totalPages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.Job": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "integer"
+                },
+                "error": {
+                    "$ref": "#/definitions/definition.JobError"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "replica": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step": {
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.JobError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.LetterCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "letter": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.MigrationResult": {
+            "type": "object",
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "migrations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.MigrationStep"
+                    }
+                },
+                "schemaVersion": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.MigrationStep": {
+            "type": "object",
+            "properties": {
+                "contacts": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.RelatedContact": {
+            "type": "object",
+            "properties": {
+                "contact": {
+                    "$ref": "#/definitions/definition.Contact"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.Relation": {
+            "type": "object",
+            "properties": {
+                "contactId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.Reminder": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string"
+                },
+                "actor": {
                     "type": "string"
                 },
-                "firstName": {
+                "contactId": {
                     "type": "string"
                 },
-                "lastName": {
+                "createdAt": {
                     "type": "string"
                 },
-                "phone": {
+                "dueAt": {
+                    "type": "string"
+                },
+                "firedAt": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.ReorderResult": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.RestoreResult": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "erased": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "restored": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.RetentionResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "affected": {
+                    "type": "integer"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "definition.SpeedDial": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "assignedAt": {
+                    "type": "string"
+                },
+                "contact": {
+                    "$ref": "#/definitions/definition.Contact"
+                },
+                "contactId": {
+                    "type": "string"
+                },
+                "slot": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.Tenant": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "limits": {
+                    "$ref": "#/definitions/definition.TenantLimits"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "definition.TenantLimits": {
+            "type": "object",
+            "properties": {
+                "maxContacts": {
+                    "type": "integer"
+                },
+                "maxRequestsPerMinute": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.Usage": {
+            "type": "object",
+            "properties": {
+                "contacts": {
+                    "type": "integer"
+                },
+                "dataBytes": {
+                    "description": "DataBytes is the size of the documents uncompressed, StorageBytes and\nIndexBytes the disk space of the documents and of the indexes.",
+                    "type": "integer"
+                },
+                "historyEntries": {
+                    "type": "integer"
+                },
+                "indexBytes": {
+                    "type": "integer"
+                },
+                "interactions": {
+                    "type": "integer"
+                },
+                "reminders": {
+                    "type": "integer"
+                },
+                "storageBytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "definition.ValidationReport": {
+            "type": "object",
+            "properties": {
+                "fixed": {
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "issues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.ContactIssue"
+                    }
+                },
+                "scanned": {
+                    "type": "integer"
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "definition.ValueCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.callerIDResponse": {
+            "type": "object",
+            "properties": {
+                "company": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.changesResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.Contact"
+                    }
+                },
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.deletedContact"
+                    }
+                },
+                "more": {
+                    "type": "boolean"
+                },
+                "syncToken": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.Contact"
+                    }
+                }
+            }
+        },
+        "server.countResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.deletedContact": {
+            "type": "object",
+            "properties": {
+                "deletedAt": {
+                    "type": "string"
+                },
+                "deletedBy": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.errorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.fieldError"
+                    }
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.fieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.importResult": {
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "report": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.maintenanceStatus": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "description": "Message replaces the message of the errors the mutations fail with.",
+                    "type": "string"
+                },
+                "since": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.reindexResponse": {
+            "type": "object",
+            "properties": {
+                "indexed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.retryResponse": {
+            "type": "object",
+            "properties": {
+                "retried": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.seedResponse": {
+            "type": "object",
+            "properties": {
+                "seeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.streamResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.syncRequest": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.ClientChange"
+                    }
+                }
+            }
+        },
+        "server.syncResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/definition.ChangeResult"
+                    }
+                }
+            }
+        },
+        "server.tenantUsage": {
+            "type": "object",
+            "properties": {
+                "limits": {
+                    "$ref": "#/definitions/definition.TenantLimits"
+                },
+                "tenant": {
                     "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/definition.Usage"
                 }
             }
         }
@@ -211,10 +4602,10 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "",
 	Host:             "",
-	BasePath:         "",
+	BasePath:         "/api/v1",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "Phonebook API",
+	Description:      "Phonebook API allows users to manage contacts, including add, delete, edit, get with pagination and search.\nThe unversioned paths are deprecated aliases of /api/v1.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,8 @@
+package docs
+
+import "embed"
+
+// UI holds the Swagger UI and the spec generated by swag, served at /docs.
+//
+//go:embed swagger.json swagger-ui-index.html swagger-ui-index.css swagger-ui.css swagger-ui-bundle.js swagger-ui-standalone-preset.js swagger-initializer.js favicon-16x16.png favicon-32x32.png oauth2-redirect.html
+var UI embed.FS
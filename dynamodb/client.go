@@ -0,0 +1,269 @@
+// Package dynamodb is a minimal client of the DynamoDB JSON API, covering the
+// item and query operations the phone book stores its contacts with.
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiVersion prefixes the X-Amz-Target of every operation.
+const apiVersion = "DynamoDB_20120810"
+
+// Client calls the DynamoDB API of a region, or of a local endpoint like
+// DynamoDB Local, signing its requests with static credentials.
+type Client struct {
+	endpoint     string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	httpClient   *http.Client
+	now          func() time.Time
+}
+
+// NewClient returns a client of the DynamoDB API at endpoint, or of region
+// when endpoint is empty.
+func NewClient(endpoint, region, accessKey, secretKey, sessionToken string) *Client {
+	if endpoint == "" {
+		endpoint = "https://dynamodb." + region + ".amazonaws.com"
+	}
+	return &Client{
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		region:       region,
+		accessKey:    accessKey,
+		secretKey:    secretKey,
+		sessionToken: sessionToken,
+		httpClient:   http.DefaultClient,
+		now:          time.Now,
+	}
+}
+
+// AttributeValue is a DynamoDB attribute value, of which the phone book uses
+// strings and numbers only.
+type AttributeValue struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+}
+
+// String returns a string attribute value.
+func String(value string) AttributeValue {
+	return AttributeValue{S: &value}
+}
+
+// Number returns a number attribute value.
+func Number(value int64) AttributeValue {
+	n := strconv.FormatInt(value, 10)
+	return AttributeValue{N: &n}
+}
+
+// Item is an item, or the key of one, by attribute name.
+type Item map[string]AttributeValue
+
+// String returns the string attribute name of the item, empty when missing.
+func (item Item) String(name string) string {
+	if value := item[name].S; value != nil {
+		return *value
+	}
+	return ""
+}
+
+// Int returns the number attribute name of the item, 0 when missing.
+func (item Item) Int(name string) int64 {
+	if value := item[name].N; value != nil {
+		n, _ := strconv.ParseInt(*value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// Error is an error returned by DynamoDB. Type is the exception name, e.g.
+// ConditionalCheckFailedException.
+type Error struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("dynamodb %s: %s", e.Type, e.Message)
+}
+
+// IsConditionalCheckFailed reports whether err rejected a write whose
+// condition expression didn't hold.
+func IsConditionalCheckFailed(err error) bool {
+	return isErrorType(err, "ConditionalCheckFailedException")
+}
+
+// IsResourceInUse reports whether err rejected creating a table that
+// already exists.
+func IsResourceInUse(err error) bool {
+	return isErrorType(err, "ResourceInUseException")
+}
+
+func isErrorType(err error, errorType string) bool {
+	var dynamoErr *Error
+	return errors.As(err, &dynamoErr) && dynamoErr.Type == errorType
+}
+
+type GetItemInput struct {
+	TableName      string
+	Key            Item
+	ConsistentRead bool `json:",omitempty"`
+}
+
+type GetItemOutput struct {
+	Item Item
+}
+
+type PutItemInput struct {
+	TableName                 string
+	Item                      Item
+	ConditionExpression       string            `json:",omitempty"`
+	ExpressionAttributeNames  map[string]string `json:",omitempty"`
+	ExpressionAttributeValues Item              `json:",omitempty"`
+}
+
+type DeleteItemInput struct {
+	TableName    string
+	Key          Item
+	ReturnValues string `json:",omitempty"`
+}
+
+type DeleteItemOutput struct {
+	Attributes Item
+}
+
+type QueryInput struct {
+	TableName                 string
+	IndexName                 string `json:",omitempty"`
+	KeyConditionExpression    string
+	ExpressionAttributeNames  map[string]string `json:",omitempty"`
+	ExpressionAttributeValues Item              `json:",omitempty"`
+	ExclusiveStartKey         Item              `json:",omitempty"`
+	Limit                     int64             `json:",omitempty"`
+	Select                    string            `json:",omitempty"`
+}
+
+type QueryOutput struct {
+	Items            []Item
+	Count            int64
+	LastEvaluatedKey Item
+}
+
+type AttributeDefinition struct {
+	AttributeName string
+	AttributeType string
+}
+
+type KeySchemaElement struct {
+	AttributeName string
+	KeyType       string
+}
+
+type Projection struct {
+	ProjectionType string
+}
+
+type GlobalSecondaryIndex struct {
+	IndexName  string
+	KeySchema  []KeySchemaElement
+	Projection Projection
+}
+
+type CreateTableInput struct {
+	TableName              string
+	AttributeDefinitions   []AttributeDefinition
+	KeySchema              []KeySchemaElement
+	GlobalSecondaryIndexes []GlobalSecondaryIndex `json:",omitempty"`
+	BillingMode            string
+}
+
+func (c *Client) GetItem(ctx context.Context, input *GetItemInput) (*GetItemOutput, error) {
+	var output GetItemOutput
+	return &output, c.do(ctx, "GetItem", input, &output)
+}
+
+func (c *Client) PutItem(ctx context.Context, input *PutItemInput) error {
+	return c.do(ctx, "PutItem", input, nil)
+}
+
+func (c *Client) DeleteItem(ctx context.Context, input *DeleteItemInput) (*DeleteItemOutput, error) {
+	var output DeleteItemOutput
+	return &output, c.do(ctx, "DeleteItem", input, &output)
+}
+
+func (c *Client) Query(ctx context.Context, input *QueryInput) (*QueryOutput, error) {
+	var output QueryOutput
+	return &output, c.do(ctx, "Query", input, &output)
+}
+
+func (c *Client) CreateTable(ctx context.Context, input *CreateTableInput) error {
+	return c.do(ctx, "CreateTable", input, nil)
+}
+
+// do calls operation with input, decoding its response into output unless
+// output is nil.
+func (c *Client) do(ctx context.Context, operation string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	request.Header.Set("X-Amz-Target", apiVersion+"."+operation)
+	signV4(request, body, "dynamodb", c.region, c.accessKey, c.secretKey, c.sessionToken, c.now())
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return responseError(response.StatusCode, content)
+	}
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, output); err != nil {
+		return fmt.Errorf("invalid dynamodb %s response: %w", operation, err)
+	}
+	return nil
+}
+
+// responseError reads the error DynamoDB responded, whose type is namespaced,
+// e.g. com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException.
+func responseError(statusCode int, content []byte) error {
+	var body struct {
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	_ = json.Unmarshal(content, &body)
+	dynamoErr := &Error{StatusCode: statusCode, Type: body.Type, Message: body.Message}
+	if i := strings.LastIndex(body.Type, "#"); i >= 0 {
+		dynamoErr.Type = body.Type[i+1:]
+	}
+	if dynamoErr.Message == "" {
+		dynamoErr.Message = body.MessageUpper
+	}
+	if dynamoErr.Type == "" {
+		dynamoErr.Type = http.StatusText(statusCode)
+		dynamoErr.Message = string(content)
+	}
+	return dynamoErr
+}
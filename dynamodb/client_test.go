@@ -0,0 +1,57 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// the post-vanilla request of the AWS Signature Version 4 test suite
+	r := httptest.NewRequest(http.MethodPost, "https://example.amazonaws.com/", nil)
+	r.Header = http.Header{}
+	r.Host = "example.amazonaws.com"
+	signV4(r, nil, "service", "us-east-1", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		r.Header.Get("Authorization"))
+}
+
+func TestClient(t *testing.T) {
+	var target string
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		if target == "DynamoDB_20120810.PutItem" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`))
+			return
+		}
+		w.Write([]byte(`{"Item":{"pk":{"S":"_default"},"version":{"N":"3"}}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "us-east-1", "key", "secret", "")
+
+	t.Run("should call the operation and decode its output", func(t *testing.T) {
+		output, err := client.GetItem(context.Background(), &GetItemInput{TableName: "contacts", Key: Item{"pk": String("_default")}})
+		assert.Nil(t, err)
+		assert.Equal(t, "DynamoDB_20120810.GetItem", target)
+		assert.Equal(t, "contacts", input["TableName"])
+		assert.Equal(t, "_default", output.Item.String("pk"))
+		assert.Equal(t, int64(3), output.Item.Int("version"))
+		assert.Equal(t, "", output.Item.String("missing"))
+	})
+
+	t.Run("should report the type of the errors", func(t *testing.T) {
+		err := client.PutItem(context.Background(), &PutItemInput{TableName: "contacts", Item: Item{}})
+		assert.True(t, IsConditionalCheckFailed(err))
+		assert.False(t, IsResourceInUse(err))
+		assert.EqualError(t, err, "dynamodb ConditionalCheckFailedException: The conditional request failed")
+	})
+}
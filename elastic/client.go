@@ -0,0 +1,217 @@
+// Package elastic is a minimal client of the Elasticsearch REST API, also
+// served by OpenSearch, covering the index, document, bulk and search
+// operations the phone book mirrors its contacts with.
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls an Elasticsearch or OpenSearch cluster.
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the cluster at baseURL, e.g.
+// http://localhost:9200, authenticated with basic auth when username is set.
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		password:   password,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Error is an error returned by the cluster. Type is the exception, e.g.
+// resource_already_exists_exception.
+type Error struct {
+	StatusCode int
+	Type       string
+	Reason     string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("elasticsearch %s: %s", e.Type, e.Reason)
+}
+
+// IsNotFound reports whether err is about an index or document that doesn't
+// exist.
+func IsNotFound(err error) bool {
+	var elasticErr *Error
+	return errors.As(err, &elasticErr) && elasticErr.StatusCode == http.StatusNotFound
+}
+
+// IsIndexExists reports whether err rejected creating an index that exists.
+func IsIndexExists(err error) bool {
+	var elasticErr *Error
+	return errors.As(err, &elasticErr) && elasticErr.Type == "resource_already_exists_exception"
+}
+
+// CreateIndex creates the index with the given settings and mappings.
+func (c *Client) CreateIndex(ctx context.Context, index string, body interface{}) error {
+	return c.do(ctx, http.MethodPut, url.PathEscape(index), body, nil)
+}
+
+// IndexDocument creates or replaces the document with the given id.
+func (c *Client) IndexDocument(ctx context.Context, index, id string, document interface{}) error {
+	return c.do(ctx, http.MethodPut, url.PathEscape(index)+"/_doc/"+url.PathEscape(id), document, nil)
+}
+
+// DeleteDocument deletes the document with the given id, failing with a not
+// found error if there is none.
+func (c *Client) DeleteDocument(ctx context.Context, index, id string) error {
+	return c.do(ctx, http.MethodDelete, url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, nil)
+}
+
+// DeleteByQuery deletes the documents of index query matches.
+func (c *Client) DeleteByQuery(ctx context.Context, index string, query interface{}) error {
+	return c.do(ctx, http.MethodPost, url.PathEscape(index)+"/_delete_by_query?conflicts=proceed", map[string]interface{}{"query": query}, nil)
+}
+
+// BulkAction indexes Document under ID, or deletes the document with ID when
+// Document is nil.
+type BulkAction struct {
+	ID       string
+	Document interface{}
+}
+
+// Bulk runs actions on index in a single request, failing with the error of
+// the first action that failed, if any.
+func (c *Client) Bulk(ctx context.Context, index string, actions []BulkAction) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, action := range actions {
+		target := map[string]string{"_index": index, "_id": action.ID}
+		if action.Document == nil {
+			if err := encoder.Encode(map[string]interface{}{"delete": target}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := encoder.Encode(map[string]interface{}{"index": target}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(action.Document); err != nil {
+			return err
+		}
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int            `json:"status"`
+			Error  *errorResponse `json:"error"`
+		} `json:"items"`
+	}
+	if err := c.send(ctx, http.MethodPost, "_bulk", "application/x-ndjson", &body, &result); err != nil {
+		return err
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			// deleting a document that is already gone is fine
+			if outcome.Error != nil && outcome.Status != http.StatusNotFound {
+				return &Error{StatusCode: outcome.Status, Type: outcome.Error.Type, Reason: outcome.Error.Reason}
+			}
+		}
+	}
+	return nil
+}
+
+// Hit is a document matched by a search, its source kept raw.
+type Hit struct {
+	ID     string          `json:"_id"`
+	Score  float64         `json:"_score"`
+	Source json.RawMessage `json:"_source"`
+}
+
+// SearchResult is the page of hits of a search. Total is the number of
+// documents matched, when tracked.
+type SearchResult struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []Hit `json:"hits"`
+	} `json:"hits"`
+}
+
+// Search runs the search request body on index.
+func (c *Client) Search(ctx context.Context, index string, body interface{}) (*SearchResult, error) {
+	var result SearchResult
+	if err := c.do(ctx, http.MethodPost, url.PathEscape(index)+"/_search", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type errorResponse struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// do calls the API at path, sending input as JSON unless nil, and decodes the
+// response into output unless nil.
+func (c *Client) do(ctx context.Context, method, path string, input, output interface{}) error {
+	var body io.Reader
+	if input != nil {
+		content, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(content)
+	}
+	return c.send(ctx, method, path, "application/json", body, output)
+}
+
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader, output interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/"+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= http.StatusMultipleChoices {
+		var errorBody struct {
+			Error *errorResponse `json:"error"`
+		}
+		// documents not found answer a result instead of an error
+		if json.Unmarshal(content, &errorBody) != nil || errorBody.Error == nil {
+			return &Error{StatusCode: response.StatusCode, Type: http.StatusText(response.StatusCode), Reason: string(content)}
+		}
+		return &Error{StatusCode: response.StatusCode, Type: errorBody.Error.Type, Reason: errorBody.Error.Reason}
+	}
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, output); err != nil {
+		return fmt.Errorf("invalid elasticsearch response: %w", err)
+	}
+	return nil
+}
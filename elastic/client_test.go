@@ -0,0 +1,65 @@
+package elastic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "elastic", username)
+		assert.Equal(t, "secret", password)
+		switch {
+		case r.URL.Path == "/contacts" && r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"resource_already_exists_exception","reason":"index [contacts] already exists"},"status":400}`))
+		case r.URL.Path == "/contacts/_search":
+			w.Write([]byte(`{"hits":{"total":{"value":3,"relation":"eq"},"hits":[{"_id":"1","_score":2.5,"_source":{"firstName":"Dani"}}]}}`))
+		case r.URL.Path == "/_bulk":
+			w.Write([]byte(`{"errors":true,"items":[{"delete":{"status":404,"result":"not_found"}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"contacts","_id":"1","result":"not_found"}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL+"/", "elastic", "secret")
+	ctx := context.Background()
+
+	t.Run("should report the errors of the cluster", func(t *testing.T) {
+		err := client.CreateIndex(ctx, "contacts", map[string]interface{}{})
+		assert.True(t, IsIndexExists(err))
+		err = client.DeleteDocument(ctx, "contacts", "1")
+		assert.True(t, IsNotFound(err))
+		assert.False(t, IsIndexExists(err))
+	})
+
+	t.Run("should return the hits of a search", func(t *testing.T) {
+		result, err := client.Search(ctx, "contacts", map[string]interface{}{"query": map[string]interface{}{"match_all": struct{}{}}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), result.Hits.Total.Value)
+		assert.Len(t, result.Hits.Hits, 1)
+		assert.JSONEq(t, `{"firstName":"Dani"}`, string(result.Hits.Hits[0].Source))
+	})
+
+	t.Run("should send bulk actions as ndjson and ignore deleting missing documents", func(t *testing.T) {
+		err := client.Bulk(ctx, "contacts", []BulkAction{{ID: "1"}, {ID: "2", Document: map[string]string{"firstName": "Dani"}}})
+		assert.ErrorContains(t, err, "mapper_parsing_exception")
+		lines := strings.Split(strings.TrimSuffix(strings.TrimPrefix(requests[len(requests)-1], "POST /_bulk "), "\n"), "\n")
+		assert.Equal(t, []string{
+			`{"delete":{"_id":"1","_index":"contacts"}}`,
+			`{"index":{"_id":"2","_index":"contacts"}}`,
+			`{"firstName":"Dani"}`,
+		}, lines)
+	})
+}
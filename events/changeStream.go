@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+// Watch tails the changes of the contacts after resumeToken, or from now
+// when it's nil, calling handle with each in order until ctx is done or the
+// stream fails.
+type Watch func(ctx context.Context, resumeToken []byte, handle func(change *definition.StreamedChange)) error
+
+// ChangeStream sends an event to its sinks for every change of the contacts
+// seen on the change stream of the database, whichever writer made it: this
+// replica, another one, or a client of the database like the mongo shell.
+// Events of the change stream carry no actor, and report the updates and
+// patches alike as updates of the contact as it is now.
+type ChangeStream struct {
+	watch Watch
+	sinks []Sink
+	retry time.Duration
+
+	// resumeToken resumes the stream after the last change sent, once it
+	// failed.
+	resumeToken []byte
+	cancel      context.CancelFunc
+	done        sync.WaitGroup
+}
+
+// NewChangeStream returns a change stream reading the changes with watch,
+// and watching again retry after it failed, from where it stopped.
+func NewChangeStream(watch Watch, retry time.Duration, sinks ...Sink) *ChangeStream {
+	return &ChangeStream{watch: watch, sinks: sinks, retry: retry}
+}
+
+// Start tails the changes in the background until Stop.
+func (s *ChangeStream) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		for {
+			err := s.watch(ctx, s.resumeToken, s.publish)
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.WithError(err).Errorf("the contacts change stream stopped, watching again in %v", s.retry)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.retry):
+			}
+		}
+	}()
+}
+
+// Stop stops tailing the changes and waits for the event being sent, if
+// any.
+func (s *ChangeStream) Stop() {
+	s.cancel()
+	s.done.Wait()
+}
+
+func (s *ChangeStream) publish(change *definition.StreamedChange) {
+	s.resumeToken = change.ResumeToken
+	event := &Event{
+		ID:         newEventID(),
+		Tenant:     change.Tenant,
+		Type:       ContactUpdated,
+		ContactID:  change.ID,
+		OccurredAt: change.OccurredAt,
+		Contact:    change.Contact,
+	}
+	switch {
+	case change.Created:
+		event.Type = ContactCreated
+	case change.Deleted:
+		event.Type = ContactDeleted
+	}
+	if change.Contact != nil {
+		event.Version = change.Contact.Version
+	}
+	ctx := definition.WithTenant(context.Background(), change.Tenant)
+	for _, sink := range s.sinks {
+		sink.Send(ctx, event)
+	}
+}
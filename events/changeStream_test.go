@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestChangeStream(t *testing.T) {
+	var resumeTokens [][]byte
+	watch := func(ctx context.Context, resumeToken []byte, handle func(change *definition.StreamedChange)) error {
+		resumeTokens = append(resumeTokens, resumeToken)
+		if len(resumeTokens) > 1 {
+			handle(&definition.StreamedChange{Tenant: "acme", ID: "2", Deleted: true, ResumeToken: []byte("2")})
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		handle(&definition.StreamedChange{ID: "1", Created: true, Contact: &definition.Contact{Phone: "0521234567", Version: 1}, ResumeToken: []byte("1")})
+		handle(&definition.StreamedChange{ID: "1", ResumeToken: []byte("1b")})
+		return errors.New("stepped down")
+	}
+	hub := NewHub()
+	subscription := hub.Subscribe(10)
+	stream := NewChangeStream(watch, 10*time.Millisecond, hub)
+	stream.Start()
+
+	var received []*Event
+	for len(received) < 3 {
+		select {
+		case event := <-subscription.C:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatal("the changes weren't sent")
+		}
+	}
+	stream.Stop()
+
+	assert.Equal(t, ContactCreated, received[0].Type)
+	assert.Equal(t, int64(1), received[0].Version)
+	assert.Equal(t, "0521234567", received[0].Contact.Phone)
+	assert.Equal(t, ContactUpdated, received[1].Type)
+	assert.Equal(t, ContactDeleted, received[2].Type)
+	assert.Equal(t, "acme", received[2].Tenant)
+	assert.Equal(t, [][]byte{nil, []byte("1b")}, resumeTokens, "the stream should resume after the last change")
+}
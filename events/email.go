@@ -0,0 +1,172 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultEmailSubject and DefaultEmailBody are the templates of the event
+// emails unless others are configured. They are rendered with the Event.
+const (
+	DefaultEmailSubject = `Phone book: {{.Type}}{{with .ContactID}} {{.}}{{end}}`
+	DefaultEmailBody    = `{{.Type}}{{with .ContactID}} of contact {{.}}{{end}}{{with .Actor}} by {{.}}{{end}} at {{.OccurredAt.Format "2006-01-02 15:04:05 MST"}}
+{{with .Contact}}
+{{.FirstName}} {{.LastName}}, {{.Phone}}
+{{end}}{{with .Import}}
+{{.Restored}} contacts restored in {{.Mode}} mode, {{.Failed}} failed{{if .Deleted}}, {{.Deleted}} deleted first{{end}}
+{{end}}`
+)
+
+// eventTypes are the types of events emails can be sent for.
+var eventTypes = map[string]bool{
+	ContactCreated:    true,
+	ContactUpdated:    true,
+	ContactPatched:    true,
+	ContactDeleted:    true,
+	ContactRestored:   true,
+	ContactArchived:   true,
+	ContactUnarchived: true,
+	ContactReordered:  true,
+	ContactBlocked:    true,
+	ContactUnblocked:  true,
+	ContactsImported:  true,
+}
+
+// Mailer sends an email message, headers included, to the recipients.
+type Mailer interface {
+	SendMail(from string, to []string, message []byte) error
+}
+
+// smtpMailer sends the emails through an SMTP server, over STARTTLS when the
+// server offers it.
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer returns a mailer sending through the SMTP server at host and
+// port, authenticating with username and password unless username is empty.
+func NewSMTPMailer(host string, port int, username, password string) Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &smtpMailer{addr: net.JoinHostPort(host, fmt.Sprint(port)), auth: auth}
+}
+
+func (m *smtpMailer) SendMail(from string, to []string, message []byte) error {
+	return smtp.SendMail(m.addr, m.auth, from, to, message)
+}
+
+// EmailSink emails the events of the chosen types to the recipients,
+// rendered with the subject and body templates. The emails are queued and
+// sent one at a time in the background, so a slow SMTP server doesn't slow
+// the mutations down. Events arriving while the queue is full are dropped.
+type EmailSink struct {
+	mailer  Mailer
+	from    string
+	to      []string
+	types   map[string]bool
+	subject *template.Template
+	body    *template.Template
+	queue   chan *Event
+	done    sync.WaitGroup
+}
+
+// NewEmailSink returns a sink emailing the events of types from from to to,
+// queueing up to queueSize of them. It fails on unknown event types and
+// invalid templates.
+func NewEmailSink(mailer Mailer, from string, to []string, types []string, subject, body string, queueSize int) (*EmailSink, error) {
+	chosen := map[string]bool{}
+	for _, eventType := range types {
+		if !eventTypes[eventType] {
+			return nil, fmt.Errorf("unknown event type %q to email", eventType)
+		}
+		chosen[eventType] = true
+	}
+	subjectTemplate, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email subject template: %w", err)
+	}
+	bodyTemplate, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email body template: %w", err)
+	}
+	sink := &EmailSink{
+		mailer:  mailer,
+		from:    from,
+		to:      to,
+		types:   chosen,
+		subject: subjectTemplate,
+		body:    bodyTemplate,
+		queue:   make(chan *Event, queueSize),
+	}
+	sink.done.Add(1)
+	go sink.run()
+	return sink, nil
+}
+
+// Send queues the email of the event, if its type was chosen.
+func (s *EmailSink) Send(ctx context.Context, event *Event) {
+	if !s.types[event.Type] {
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		logrus.Warnf("email queue is full, dropping the email of event %s", event.ID)
+	}
+}
+
+// Close sends the queued emails and stops the sink. No event may be sent to
+// it afterwards.
+func (s *EmailSink) Close() {
+	close(s.queue)
+	s.done.Wait()
+}
+
+func (s *EmailSink) run() {
+	defer s.done.Done()
+	for event := range s.queue {
+		message, err := s.message(event)
+		if err == nil {
+			err = s.mailer.SendMail(s.from, s.to, message)
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to email event %s", event.ID)
+		}
+	}
+}
+
+// message renders the email of event, headers included.
+func (s *EmailSink) message(event *Event) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := s.subject.Execute(&subject, event); err != nil {
+		return nil, err
+	}
+	if err := s.body.Execute(&body, event); err != nil {
+		return nil, err
+	}
+	// the subject may hold contact fields, which must not add headers
+	subjectLine := strings.Join(strings.Fields(subject.String()), " ")
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subjectLine))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	lines := strings.Split(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n")
+	message.WriteString(strings.Join(lines, "\r\n"))
+	return message.Bytes(), nil
+}
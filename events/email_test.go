@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"phoneBook/definition"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMailer keeps the messages it was asked to send.
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []string
+	to       []string
+}
+
+func (m *recordingMailer) SendMail(from string, to []string, message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, string(message))
+	m.to = to
+	return nil
+}
+
+// restorePhoneBook restores two contacts.
+type restorePhoneBook struct {
+	definition.IPhoneBook
+}
+
+func (pb *restorePhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	return &definition.RestoreResult{Mode: mode, Restored: 2}, nil
+}
+
+func TestEmailSink(t *testing.T) {
+	ctx := context.Background()
+	id := "65a1b2c3d4e5f60718293a4b"
+
+	t.Run("should email the chosen events", func(t *testing.T) {
+		mailer := &recordingMailer{}
+		sink, err := NewEmailSink(mailer, "phonebook@example.com", []string{"ops@example.com"}, []string{ContactDeleted, ContactsImported}, DefaultEmailSubject, DefaultEmailBody, 10)
+		assert.Nil(t, err)
+		sink.Send(ctx, &Event{ID: "1", Type: ContactDeleted, ContactID: id, Actor: "dani", OccurredAt: time.Now()})
+		sink.Send(ctx, &Event{ID: "2", Type: ContactPatched, ContactID: id, OccurredAt: time.Now()})
+		phoneBook := NewPhoneBook(&restorePhoneBook{}, sink)
+		phoneBook.Restore(ctx, definition.BackupFormatJSON, definition.RestoreModeMerge, strings.NewReader(""))
+		sink.Close()
+
+		assert.Len(t, mailer.messages, 2)
+		assert.Equal(t, []string{"ops@example.com"}, mailer.to)
+		assert.Contains(t, mailer.messages[0], "Subject: Phone book: contact.deleted "+id+"\r\n")
+		assert.Contains(t, mailer.messages[0], "contact.deleted of contact "+id+" by dani at ")
+		assert.Contains(t, mailer.messages[1], "Subject: Phone book: contacts.imported\r\n")
+		assert.Contains(t, mailer.messages[1], "2 contacts restored in merge mode, 0 failed")
+	})
+
+	t.Run("should keep contact fields out of the headers", func(t *testing.T) {
+		mailer := &recordingMailer{}
+		sink, _ := NewEmailSink(mailer, "phonebook@example.com", []string{"ops@example.com"}, []string{ContactCreated}, "New contact {{.Contact.FirstName}}", DefaultEmailBody, 10)
+		sink.Send(ctx, &Event{ID: "1", Type: ContactCreated, ContactID: id, Contact: &definition.Contact{FirstName: "Dani\r\nBcc: all@example.com"}})
+		sink.Close()
+
+		assert.Len(t, mailer.messages, 1)
+		headers, body, _ := strings.Cut(mailer.messages[0], "\r\n\r\n")
+		assert.NotContains(t, headers, "\r\nBcc:")
+		assert.NotContains(t, body, "\r\r")
+	})
+
+	t.Run("should reject unknown event types and invalid templates", func(t *testing.T) {
+		_, err := NewEmailSink(&recordingMailer{}, "phonebook@example.com", nil, []string{"contact.read"}, DefaultEmailSubject, DefaultEmailBody, 10)
+		assert.ErrorContains(t, err, "contact.read")
+		_, err = NewEmailSink(&recordingMailer{}, "phonebook@example.com", nil, nil, "{{.Type", DefaultEmailBody, 10)
+		assert.ErrorContains(t, err, "subject template")
+	})
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	FormatJSON = "json"
+	FormatAvro = "avro"
+)
+
+// avroSchema is the schema of events published in Avro, for consumers to
+// decode them with.
+const avroSchema = `{
+	"type": "record",
+	"name": "ContactEvent",
+	"namespace": "phonebook",
+	"fields": [
+		{"name": "id", "type": "string"},
+		{"name": "type", "type": "string"},
+		{"name": "contactId", "type": "string"},
+		{"name": "version", "type": "long"},
+		{"name": "actor", "type": "string"},
+		{"name": "occurredAt", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "contact", "type": ["null", {
+			"type": "record",
+			"name": "Contact",
+			"fields": [
+				{"name": "firstName", "type": "string"},
+				{"name": "lastName", "type": "string"},
+				{"name": "phone", "type": "string"},
+				{"name": "email", "type": "string", "default": ""},
+				{"name": "company", "type": "string", "default": ""},
+				{"name": "jobTitle", "type": "string", "default": ""},
+				{"name": "address", "type": "string"},
+				{"name": "notes", "type": "string"}
+			]
+		}], "default": null},
+		{"name": "patch", "type": ["null", {"type": "map", "values": ["null", "string"]}], "default": null},
+		{"name": "import", "type": ["null", {
+			"type": "record",
+			"name": "Import",
+			"fields": [
+				{"name": "mode", "type": "string"},
+				{"name": "restored", "type": "long"},
+				{"name": "deleted", "type": "long"},
+				{"name": "failed", "type": "long"},
+				{"name": "erased", "type": "long", "default": 0}
+			]
+		}], "default": null},
+		{"name": "tenant", "type": "string", "default": ""}
+	]
+}`
+
+// Encoder serializes events into message payloads.
+type Encoder func(event *Event) ([]byte, error)
+
+// NewEncoder returns the encoder of the given format, json or avro.
+func NewEncoder(format string) (Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return encodeJSON, nil
+	case FormatAvro:
+		codec, err := goavro.NewCodec(avroSchema)
+		if err != nil {
+			return nil, err
+		}
+		return func(event *Event) ([]byte, error) {
+			return codec.BinaryFromNative(nil, avroNative(event))
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown event format %q, expected json or avro", format)
+}
+
+func encodeJSON(event *Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// avroNative returns the event in the form goavro encodes, with unions
+// wrapped by the name of their branch.
+func avroNative(event *Event) map[string]interface{} {
+	native := map[string]interface{}{
+		"id":         event.ID,
+		"type":       event.Type,
+		"contactId":  event.ContactID,
+		"version":    event.Version,
+		"actor":      event.Actor,
+		"occurredAt": event.OccurredAt,
+		"contact":    nil,
+		"patch":      nil,
+		"import":     nil,
+		"tenant":     event.Tenant,
+	}
+	if contact := event.Contact; contact != nil {
+		native["contact"] = goavro.Union("phonebook.Contact", map[string]interface{}{
+			"firstName": contact.FirstName,
+			"lastName":  contact.LastName,
+			"phone":     contact.Phone,
+			"email":     contact.Email,
+			"company":   contact.Company,
+			"jobTitle":  contact.JobTitle,
+			"address":   contact.Address,
+			"notes":     contact.Notes,
+		})
+	}
+	if event.Patch != nil {
+		patch := map[string]interface{}{}
+		for field, value := range event.Patch {
+			if value == nil {
+				patch[field] = nil
+			} else {
+				patch[field] = goavro.Union("string", *value)
+			}
+		}
+		native["patch"] = goavro.Union("map", patch)
+	}
+	if result := event.Import; result != nil {
+		native["import"] = goavro.Union("phonebook.Import", map[string]interface{}{
+			"mode":     result.Mode,
+			"restored": result.Restored,
+			"deleted":  result.Deleted,
+			"failed":   result.Failed,
+			"erased":   result.Erased,
+		})
+	}
+	return native
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"phoneBook/definition"
+	"time"
+)
+
+const (
+	ContactCreated  = "contact.created"
+	ContactUpdated  = "contact.updated"
+	ContactPatched  = "contact.patched"
+	ContactDeleted  = "contact.deleted"
+	ContactRestored = "contact.restored"
+	// ContactArchived and ContactUnarchived are sent when a contact is
+	// hidden from the listings, or shown again.
+	ContactArchived   = "contact.archived"
+	ContactUnarchived = "contact.unarchived"
+	// ContactReordered is sent when a contact is pinned to the top of the
+	// listings, moved among the pinned contacts or unpinned.
+	ContactReordered = "contact.reordered"
+	// ContactBlocked and ContactUnblocked are sent when the phone of a
+	// contact is put on the blocklist, or taken off.
+	ContactBlocked   = "contact.blocked"
+	ContactUnblocked = "contact.unblocked"
+	// ContactsImported is sent once a restore wrote a batch of contacts, in
+	// place of an event per contact.
+	ContactsImported = "contacts.imported"
+)
+
+// Event describes a single mutation of a contact. Contact holds the contact
+// as written by creations, updates and restorations, and Patch the fields a
+// partial update changed. Version is known for creations and restorations.
+// Import reports the contacts of an import, which has no contact ID. Tenant
+// is the tenant whose phone book changed, empty for the default one.
+type Event struct {
+	ID         string                    `json:"id"`
+	Tenant     string                    `json:"tenant,omitempty"`
+	Type       string                    `json:"type"`
+	ContactID  string                    `json:"contactId"`
+	Version    int64                     `json:"version,omitempty"`
+	Actor      string                    `json:"actor,omitempty"`
+	OccurredAt time.Time                 `json:"occurredAt"`
+	Contact    *definition.Contact       `json:"contact,omitempty"`
+	Patch      definition.ContactPatch   `json:"patch,omitempty"`
+	Import     *definition.RestoreResult `json:"import,omitempty"`
+}
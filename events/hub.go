@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Hub fans the events of this process out to in-process subscribers, like
+// the WebSocket clients. Subscribers that fall behind are dropped rather
+// than slowing the mutations down.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives events on C until it is closed, by Close or by the
+// hub when its buffer overflowed.
+type Subscription struct {
+	C       <-chan *Event
+	events  chan *Event
+	hub     *Hub
+	dropped bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[*Subscription]struct{}{}}
+}
+
+// Subscribe returns a subscription buffering up to buffer events.
+func (h *Hub) Subscribe(buffer int) *Subscription {
+	events := make(chan *Event, buffer)
+	subscription := &Subscription{C: events, events: events, hub: h}
+	h.mu.Lock()
+	h.subscribers[subscription] = struct{}{}
+	h.mu.Unlock()
+	return subscription
+}
+
+// Send hands the event to every subscriber, dropping those whose buffer is
+// full.
+func (h *Hub) Send(ctx context.Context, event *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for subscription := range h.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.dropped = true
+			h.remove(subscription)
+		}
+	}
+}
+
+func (h *Hub) remove(subscription *Subscription) {
+	delete(h.subscribers, subscription)
+	close(subscription.events)
+}
+
+// Close stops the subscription, closing C.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subscribers[s]; ok {
+		s.hub.remove(s)
+	}
+}
+
+// Dropped reports whether the hub closed the subscription because it fell
+// behind, once C is closed.
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
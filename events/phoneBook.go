@@ -0,0 +1,239 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/sirupsen/logrus"
+	"io"
+	"phoneBook/definition"
+	"time"
+)
+
+// Sink receives the events of the phone book. Sinks handle their own
+// failures, the mutation has already happened by then.
+type Sink interface {
+	Send(ctx context.Context, event *Event)
+}
+
+// PhoneBook sends an event to its sinks for every successful mutation of the
+// phone book it wraps. The other methods go straight to the phone book.
+type PhoneBook struct {
+	definition.IPhoneBook
+	sinks []Sink
+}
+
+// NewPhoneBook wraps phoneBook, sending its events to sinks.
+func NewPhoneBook(phoneBook definition.IPhoneBook, sinks ...Sink) *PhoneBook {
+	return &PhoneBook{
+		IPhoneBook: phoneBook,
+		sinks:      sinks,
+	}
+}
+
+func (pb *PhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.AddContact(ctx, contact, actor)
+	if err == nil {
+		pb.publish(ctx, &Event{Type: ContactCreated, ContactID: result.ID, Version: 1, Actor: actor, Contact: contact})
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	results, err := pb.IPhoneBook.AddContacts(ctx, contacts, actor)
+	for i, result := range results {
+		if result.ID != "" {
+			pb.publish(ctx, &Event{Type: ContactCreated, ContactID: result.ID, Version: 1, Actor: actor, Contact: contacts[i]})
+		}
+	}
+	return results, err
+}
+
+func (pb *PhoneBook) UpdateContact(ctx context.Context, id string, updatedContact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpdateContact(ctx, id, updatedContact, expectedVersion, actor)
+	if err == nil && result.Modified > 0 {
+		pb.publish(ctx, &Event{Type: ContactUpdated, ContactID: id, Actor: actor, Contact: updatedContact})
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.PatchContact(ctx, id, patch, expectedVersion, actor)
+	if err == nil && result.Modified > 0 {
+		pb.publish(ctx, &Event{Type: ContactPatched, ContactID: id, Actor: actor, Patch: patch})
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpsertContact(ctx, id, contact, actor)
+	pb.publishUpsert(ctx, result, err, contact, actor)
+	return result, err
+}
+
+func (pb *PhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpsertContactByPhone(ctx, number, contact, actor)
+	pb.publishUpsert(ctx, result, err, contact, actor)
+	return result, err
+}
+
+// publishUpsert publishes an upsert as the creation or the update of the
+// contact it wrote.
+func (pb *PhoneBook) publishUpsert(ctx context.Context, result *definition.WriteResult, err error, contact *definition.Contact, actor string) {
+	switch {
+	case err != nil:
+	case result.Created:
+		pb.publish(ctx, &Event{Type: ContactCreated, ContactID: result.ID, Version: 1, Actor: actor, Contact: contact})
+	case result.Modified > 0:
+		pb.publish(ctx, &Event{Type: ContactUpdated, ContactID: result.ID, Actor: actor, Contact: contact})
+	}
+}
+
+func (pb *PhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.DeleteContact(ctx, id, actor)
+	if err == nil && result.Modified > 0 {
+		pb.publish(ctx, &Event{Type: ContactDeleted, ContactID: id, Actor: actor})
+	}
+	return result, err
+}
+
+// DeleteContacts publishes a delete event per deleted contact.
+func (pb *PhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	result, err := pb.IPhoneBook.DeleteContacts(ctx, batch, actor)
+	if err == nil && !result.DryRun {
+		for _, id := range result.IDs {
+			pb.publish(ctx, &Event{Type: ContactDeleted, ContactID: id, Actor: actor})
+		}
+	}
+	return result, err
+}
+
+// ApplyRetention publishes an update event per anonymized contact, without
+// the contact, or a delete event per purged one.
+func (pb *PhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	result, err := pb.IPhoneBook.ApplyRetention(ctx, policy, actor)
+	if err == nil && !result.DryRun {
+		eventType := ContactUpdated
+		if result.Action == definition.RetentionActionPurge {
+			eventType = ContactDeleted
+		}
+		for _, id := range result.IDs {
+			pb.publish(ctx, &Event{Type: eventType, ContactID: id, Actor: actor})
+		}
+	}
+	return result, err
+}
+
+// EraseContacts publishes a delete event per erased contact.
+func (pb *PhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	result, err := pb.IPhoneBook.EraseContacts(ctx, phone, actor)
+	if err == nil {
+		for _, id := range result.IDs {
+			pb.publish(ctx, &Event{Type: ContactDeleted, ContactID: id, Actor: actor})
+		}
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	contact, err := pb.IPhoneBook.UndoContact(ctx, id, actor)
+	if err == nil {
+		pb.publish(ctx, &Event{Type: ContactRestored, ContactID: id, Version: contact.Version, Actor: actor, Contact: contact})
+	}
+	return contact, err
+}
+
+func (pb *PhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.ArchiveContact(ctx, id, archived, actor)
+	if err == nil && result.Modified > 0 {
+		eventType := ContactArchived
+		if !archived {
+			eventType = ContactUnarchived
+		}
+		pb.publish(ctx, &Event{Type: eventType, ContactID: id, Actor: actor})
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.BlockContact(ctx, id, blocked, actor)
+	if err == nil && result.Modified > 0 {
+		eventType := ContactBlocked
+		if !blocked {
+			eventType = ContactUnblocked
+		}
+		pb.publish(ctx, &Event{Type: eventType, ContactID: id, Actor: actor})
+	}
+	return result, err
+}
+
+// ReorderContacts publishes a reorder event per contact pinned, moved or
+// unpinned.
+func (pb *PhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	result, err := pb.IPhoneBook.ReorderContacts(ctx, order, actor)
+	if err == nil {
+		for _, id := range result.IDs {
+			pb.publish(ctx, &Event{Type: ContactReordered, ContactID: id, Actor: actor})
+		}
+	}
+	return result, err
+}
+
+// Restore publishes a single import event for the restored contacts.
+func (pb *PhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	result, err := pb.IPhoneBook.Restore(ctx, format, mode, r)
+	if err == nil {
+		pb.publish(ctx, &Event{Type: ContactsImported, Import: result})
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) publish(ctx context.Context, event *Event) {
+	event.ID = newEventID()
+	event.OccurredAt = time.Now().UTC()
+	event.Tenant = definition.TenantFromContext(ctx)
+	// the mutation happened even when the request was canceled
+	ctx = context.WithoutCancel(ctx)
+	for _, sink := range pb.sinks {
+		sink.Send(ctx, event)
+	}
+}
+
+// brokerSink publishes the events to a topic of a message broker.
+type brokerSink struct {
+	publisher Publisher
+	encode    Encoder
+	topic     string
+	timeout   time.Duration
+}
+
+// NewBrokerSink returns a sink publishing the events to topic, encoded by
+// encode, waiting up to timeout for each. Failures are logged.
+func NewBrokerSink(publisher Publisher, encode Encoder, topic string, timeout time.Duration) Sink {
+	return &brokerSink{
+		publisher: publisher,
+		encode:    encode,
+		topic:     topic,
+		timeout:   timeout,
+	}
+}
+
+func (s *brokerSink) Send(ctx context.Context, event *Event) {
+	logger := logrus.WithFields(logrus.Fields{"event": event.Type, "contactId": event.ContactID})
+	payload, err := s.encode(event)
+	if err != nil {
+		logger.WithError(err).Error("failed to encode contact event")
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.publisher.Publish(ctx, s.topic, event.ContactID, payload); err != nil {
+		logger.WithError(err).Error("failed to publish contact event")
+	}
+}
+
+func newEventID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+// recordingPublisher keeps the payloads published to it.
+type recordingPublisher struct {
+	payloads [][]byte
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	p.payloads = append(p.payloads, payload)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+// stubPhoneBook accepts every add and patch and rejects every delete.
+type stubPhoneBook struct {
+	definition.IPhoneBook
+}
+
+func (pb *stubPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{ID: "65a1b2c3d4e5f60718293a4b"}, nil
+}
+
+func (pb *stubPhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *stubPhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	return nil, definition.NewError("CONTACT_NOT_FOUND", "contact not found", "_id")
+}
+
+func TestPhoneBook(t *testing.T) {
+	ctx := context.Background()
+	id := "65a1b2c3d4e5f60718293a4b"
+	phone := "0521234567"
+	patch := definition.ContactPatch{"phone": &phone, "notes": nil}
+
+	t.Run("should publish json event of mutation", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		encode, _ := NewEncoder(FormatJSON)
+		phoneBook := NewPhoneBook(&stubPhoneBook{}, NewBrokerSink(publisher, encode, "contacts", time.Second))
+		phoneBook.PatchContact(ctx, id, patch, 0, "dani")
+		assert.Len(t, publisher.payloads, 1)
+		var event Event
+		assert.Nil(t, json.Unmarshal(publisher.payloads[0], &event))
+		assert.Equal(t, ContactPatched, event.Type)
+		assert.Equal(t, id, event.ContactID)
+		assert.Equal(t, "dani", event.Actor)
+		assert.Equal(t, phone, *event.Patch["phone"])
+	})
+
+	t.Run("should publish the id of created contact", func(t *testing.T) {
+		hub := NewHub()
+		subscription := hub.Subscribe(1)
+		NewPhoneBook(&stubPhoneBook{}, hub).AddContact(ctx, &definition.Contact{Phone: phone}, "dani")
+		event := <-subscription.C
+		assert.Equal(t, ContactCreated, event.Type)
+		assert.Equal(t, id, event.ContactID)
+	})
+
+	t.Run("should not publish failed mutation", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		encode, _ := NewEncoder(FormatJSON)
+		phoneBook := NewPhoneBook(&stubPhoneBook{}, NewBrokerSink(publisher, encode, "contacts", time.Second))
+		phoneBook.DeleteContact(ctx, id, "dani")
+		assert.Empty(t, publisher.payloads)
+	})
+
+	t.Run("should publish avro event of mutation", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		encode, err := NewEncoder(FormatAvro)
+		assert.Nil(t, err)
+		phoneBook := NewPhoneBook(&stubPhoneBook{}, NewBrokerSink(publisher, encode, "contacts", time.Second))
+		phoneBook.PatchContact(definition.WithTenant(ctx, "acme"), id, patch, 0, "dani")
+		codec, _ := goavro.NewCodec(avroSchema)
+		native, _, err := codec.NativeFromBinary(publisher.payloads[0])
+		assert.Nil(t, err)
+		event := native.(map[string]interface{})
+		assert.Equal(t, ContactPatched, event["type"])
+		assert.Equal(t, "acme", event["tenant"])
+		assert.Nil(t, event["contact"])
+	})
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	subscription := hub.Subscribe(1)
+	slowSubscription := hub.Subscribe(1)
+	phoneBook := NewPhoneBook(&stubPhoneBook{}, hub)
+
+	phoneBook.PatchContact(context.Background(), "id", definition.ContactPatch{}, 0, "dani")
+	event := <-subscription.C
+	assert.Equal(t, ContactPatched, event.Type)
+
+	// the slow subscription still holds the first event
+	phoneBook.PatchContact(context.Background(), "id", definition.ContactPatch{}, 0, "dani")
+	<-slowSubscription.C
+	_, open := <-slowSubscription.C
+	assert.False(t, open)
+	assert.True(t, slowSubscription.Dropped())
+	assert.False(t, subscription.Dropped())
+
+	subscription.Close()
+	<-subscription.C
+	_, open = <-subscription.C
+	assert.False(t, open)
+}
+
+func TestKafkaRESTPublisher(t *testing.T) {
+	var path, contentType string
+	var records kafkaRESTRecords
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &records)
+	}))
+	defer proxy.Close()
+
+	publisher, err := NewPublisher(BackendKafkaREST, proxy.URL)
+	assert.Nil(t, err)
+	assert.Nil(t, publisher.Publish(context.Background(), "contacts", "id", []byte(`{"type":"contact.deleted"}`)))
+	assert.Equal(t, "/topics/contacts", path)
+	assert.Equal(t, "application/vnd.kafka.binary.v2+json", contentType)
+	assert.Equal(t, `{"type":"contact.deleted"}`, string(records.Records[0].Value))
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	BackendNATS      = "nats"
+	BackendKafkaREST = "kafka-rest"
+)
+
+// Publisher sends message payloads to a topic of a message broker. Key
+// identifies the contact, so brokers that partition keep its events in order.
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, payload []byte) error
+	Close() error
+}
+
+// NewPublisher connects to the broker of the given backend at brokerURL.
+func NewPublisher(backend, brokerURL string) (Publisher, error) {
+	switch backend {
+	case BackendNATS:
+		conn, err := nats.Connect(brokerURL)
+		if err != nil {
+			return nil, err
+		}
+		return &natsPublisher{conn: conn}, nil
+	case BackendKafkaREST:
+		if _, err := url.ParseRequestURI(brokerURL); err != nil {
+			return nil, err
+		}
+		return &kafkaRESTPublisher{baseURL: strings.TrimSuffix(brokerURL, "/"), client: http.DefaultClient}, nil
+	}
+	return nil, fmt.Errorf("unknown events backend %q, expected nats or kafka-rest", backend)
+}
+
+// natsPublisher publishes every event on the NATS subject named by the topic.
+type natsPublisher struct {
+	conn *nats.Conn
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	return p.conn.Publish(topic, payload)
+}
+
+// Close flushes the pending messages before closing the connection.
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// kafkaRESTPublisher produces to a Kafka topic through the REST Proxy API v2.
+type kafkaRESTPublisher struct {
+	baseURL string
+	client  *http.Client
+}
+
+type kafkaRESTRecords struct {
+	Records []kafkaRESTRecord `json:"records"`
+}
+
+// kafkaRESTRecord holds a binary record, the key and value are base64
+// encoded by encoding/json.
+type kafkaRESTRecord struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+func (p *kafkaRESTPublisher) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	body, _ := json.Marshal(kafkaRESTRecords{Records: []kafkaRESTRecord{{Key: []byte(key), Value: payload}}})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/vnd.kafka.binary.v2+json")
+	request.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("kafka rest proxy responded %s: %s", response.Status, message)
+	}
+	return nil
+}
+
+func (p *kafkaRESTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,364 @@
+// Package firestore is a minimal client of the Firestore REST API, covering the
+// document and query operations the phone book stores its contacts with.
+package firestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2/jwt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datastoreScope allows reading and writing the Firestore databases of the
+// project.
+const datastoreScope = "https://www.googleapis.com/auth/datastore"
+
+// Client calls the Firestore API on a database of a project, or the Firestore
+// emulator.
+type Client struct {
+	baseURL    string
+	database   string
+	httpClient *http.Client
+}
+
+// serviceAccount is the part of a service account key file the client
+// authenticates with.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewClient returns a client of database of projectID, authenticated as the
+// service account of credentialsFile. With emulatorHost, e.g.
+// localhost:8081, it calls the unauthenticated emulator instead.
+func NewClient(ctx context.Context, projectID, database, credentialsFile, emulatorHost string) (*Client, error) {
+	client := &Client{
+		baseURL:    "https://firestore.googleapis.com/v1/",
+		database:   "projects/" + projectID + "/databases/" + database,
+		httpClient: http.DefaultClient,
+	}
+	if emulatorHost != "" {
+		client.baseURL = "http://" + emulatorHost + "/v1/"
+		return client, nil
+	}
+	content, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read firestore credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(content, &account); err != nil || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("invalid firestore credentials %s, expected a service account key", credentialsFile)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	jwtConfig := &jwt.Config{
+		Email:      account.ClientEmail,
+		PrivateKey: []byte(account.PrivateKey),
+		TokenURL:   account.TokenURI,
+		Scopes:     []string{datastoreScope},
+	}
+	client.httpClient = jwtConfig.Client(ctx)
+	return client, nil
+}
+
+// Documents returns the name of the root of the documents, or of the
+// document at path under it, e.g. Documents("tenants", "acme").
+func (c *Client) Documents(path ...string) string {
+	return strings.Join(append([]string{c.database, "documents"}, path...), "/")
+}
+
+// Value is a Firestore value, of which the phone book uses strings,
+// integers, timestamps and document references only.
+type Value struct {
+	StringValue    *string `json:"stringValue,omitempty"`
+	IntegerValue   *string `json:"integerValue,omitempty"`
+	TimestampValue *string `json:"timestampValue,omitempty"`
+	ReferenceValue *string `json:"referenceValue,omitempty"`
+}
+
+// String returns a string value.
+func String(value string) Value {
+	return Value{StringValue: &value}
+}
+
+// Integer returns an integer value.
+func Integer(value int64) Value {
+	n := strconv.FormatInt(value, 10)
+	return Value{IntegerValue: &n}
+}
+
+// Timestamp returns a timestamp value.
+func Timestamp(value time.Time) Value {
+	t := value.UTC().Format(time.RFC3339Nano)
+	return Value{TimestampValue: &t}
+}
+
+// Reference returns a value referencing the document named name.
+func Reference(name string) Value {
+	return Value{ReferenceValue: &name}
+}
+
+// Document is a document, its fields by name. UpdateTime is set by Firestore.
+type Document struct {
+	Name       string           `json:"name,omitempty"`
+	Fields     map[string]Value `json:"fields"`
+	UpdateTime string           `json:"updateTime,omitempty"`
+}
+
+// ID returns the id of the document, the last segment of its name.
+func (d *Document) ID() string {
+	return d.Name[strings.LastIndex(d.Name, "/")+1:]
+}
+
+// String returns the string field name, empty when missing.
+func (d *Document) String(name string) string {
+	if value := d.Fields[name].StringValue; value != nil {
+		return *value
+	}
+	return ""
+}
+
+// Int returns the integer field name, 0 when missing.
+func (d *Document) Int(name string) int64 {
+	if value := d.Fields[name].IntegerValue; value != nil {
+		n, _ := strconv.ParseInt(*value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// Time returns the timestamp field name, nil when missing.
+func (d *Document) Time(name string) *time.Time {
+	if value := d.Fields[name].TimestampValue; value != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// StructuredQuery is a query of the documents of a collection.
+type StructuredQuery struct {
+	Select  *Projection          `json:"select,omitempty"`
+	From    []CollectionSelector `json:"from"`
+	Where   *Filter              `json:"where,omitempty"`
+	OrderBy []Order              `json:"orderBy,omitempty"`
+	StartAt *Cursor              `json:"startAt,omitempty"`
+	Offset  int64                `json:"offset,omitempty"`
+	Limit   int64                `json:"limit,omitempty"`
+}
+
+type Projection struct {
+	Fields []FieldReference `json:"fields"`
+}
+
+type CollectionSelector struct {
+	CollectionID string `json:"collectionId"`
+}
+
+type FieldReference struct {
+	FieldPath string `json:"fieldPath"`
+}
+
+// Filter is either a field filter or a composite of filters.
+type Filter struct {
+	CompositeFilter *CompositeFilter `json:"compositeFilter,omitempty"`
+	FieldFilter     *FieldFilter     `json:"fieldFilter,omitempty"`
+}
+
+type CompositeFilter struct {
+	Op      string   `json:"op"`
+	Filters []Filter `json:"filters"`
+}
+
+type FieldFilter struct {
+	Field FieldReference `json:"field"`
+	Op    string         `json:"op"`
+	Value Value          `json:"value"`
+}
+
+// Where returns the filter of field compared to value with op, e.g. EQUAL or
+// GREATER_THAN_OR_EQUAL.
+func Where(field, op string, value Value) Filter {
+	return Filter{FieldFilter: &FieldFilter{Field: FieldReference{FieldPath: field}, Op: op, Value: value}}
+}
+
+// And returns the filter matching every filter of filters, nil without any.
+func And(filters ...Filter) *Filter {
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return &filters[0]
+	}
+	return &Filter{CompositeFilter: &CompositeFilter{Op: "AND", Filters: filters}}
+}
+
+// Order sorts by a field, ASCENDING or DESCENDING. The document name is the
+// __name__ field.
+type Order struct {
+	Field     FieldReference `json:"field"`
+	Direction string         `json:"direction"`
+}
+
+// Cursor starts a query at the values of its order fields, or right after
+// them unless Before is set.
+type Cursor struct {
+	Values []Value `json:"values"`
+	Before bool    `json:"before"`
+}
+
+// Error is an error returned by Firestore. Status is the canonical code, e.g.
+// FAILED_PRECONDITION.
+type Error struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("firestore %s: %s", e.Status, e.Message)
+}
+
+// IsNotFound reports whether err is about a document that doesn't exist.
+func IsNotFound(err error) bool {
+	return isStatus(err, "NOT_FOUND")
+}
+
+// IsAlreadyExists reports whether err rejected creating a document that
+// exists.
+func IsAlreadyExists(err error) bool {
+	return isStatus(err, "ALREADY_EXISTS")
+}
+
+// IsFailedPrecondition reports whether err rejected a write whose
+// precondition didn't hold.
+func IsFailedPrecondition(err error) bool {
+	return isStatus(err, "FAILED_PRECONDITION")
+}
+
+func isStatus(err error, status string) bool {
+	var firestoreErr *Error
+	return errors.As(err, &firestoreErr) && firestoreErr.Status == status
+}
+
+// Get returns the document named name.
+func (c *Client) Get(ctx context.Context, name string) (*Document, error) {
+	var document Document
+	return &document, c.do(ctx, http.MethodGet, name, nil, &document)
+}
+
+// Create creates document with the given id in collection of parent, failing
+// with ALREADY_EXISTS if there is one.
+func (c *Client) Create(ctx context.Context, parent, collection, id string, document *Document) error {
+	return c.do(ctx, http.MethodPost, parent+"/"+collection+"?documentId="+url.QueryEscape(id), document, nil)
+}
+
+// Replace replaces the fields of the document named name, provided it wasn't
+// updated since updateTime, failing with FAILED_PRECONDITION otherwise.
+func (c *Client) Replace(ctx context.Context, name string, document *Document, updateTime string) error {
+	return c.do(ctx, http.MethodPatch, name+"?currentDocument.updateTime="+url.QueryEscape(updateTime), document, nil)
+}
+
+// Delete deletes the document named name, failing with NOT_FOUND if there is
+// none.
+func (c *Client) Delete(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, name+"?currentDocument.exists=true", nil, nil)
+}
+
+// RunQuery returns the documents of parent query matches.
+func (c *Client) RunQuery(ctx context.Context, parent string, query *StructuredQuery) ([]*Document, error) {
+	var results []struct {
+		Document *Document `json:"document"`
+	}
+	if err := c.do(ctx, http.MethodPost, parent+":runQuery", map[string]interface{}{"structuredQuery": query}, &results); err != nil {
+		return nil, err
+	}
+	var documents []*Document
+	for _, result := range results {
+		// results without a document only report the read time
+		if result.Document != nil {
+			documents = append(documents, result.Document)
+		}
+	}
+	return documents, nil
+}
+
+// Count returns the number of documents of parent query matches.
+func (c *Client) Count(ctx context.Context, parent string, query *StructuredQuery) (int64, error) {
+	body := map[string]interface{}{"structuredAggregationQuery": map[string]interface{}{
+		"structuredQuery": query,
+		"aggregations":    []interface{}{map[string]interface{}{"alias": "count", "count": map[string]interface{}{}}},
+	}}
+	var results []struct {
+		Result *struct {
+			AggregateFields map[string]Value `json:"aggregateFields"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, parent+":runAggregationQuery", body, &results); err != nil {
+		return 0, err
+	}
+	for _, result := range results {
+		if result.Result != nil {
+			count := &Document{Fields: result.Result.AggregateFields}
+			return count.Int("count"), nil
+		}
+	}
+	return 0, nil
+}
+
+// do calls the API at path, sending input as JSON unless nil, and decodes the
+// response into output unless nil.
+func (c *Client) do(ctx context.Context, method, path string, input, output interface{}) error {
+	var body io.Reader
+	if input != nil {
+		content, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(content)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if input != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		var errorBody struct {
+			Error *Error `json:"error"`
+		}
+		if json.Unmarshal(content, &errorBody) != nil || errorBody.Error == nil {
+			return &Error{Code: response.StatusCode, Status: http.StatusText(response.StatusCode), Message: string(content)}
+		}
+		return errorBody.Error
+	}
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, output); err != nil {
+		return fmt.Errorf("invalid firestore response: %w", err)
+	}
+	return nil
+}
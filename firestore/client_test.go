@@ -0,0 +1,58 @@
+package firestore
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":runQuery"):
+			w.Write([]byte(`[{"document":{"name":"projects/p/databases/(default)/documents/contacts/1","fields":{"version":{"integerValue":"2"}}}},{"readTime":"2024-01-01T00:00:00Z"}]`))
+		case strings.HasSuffix(r.URL.Path, ":runAggregationQuery"):
+			w.Write([]byte(`[{"result":{"aggregateFields":{"count":{"integerValue":"12"}}},"readTime":"2024-01-01T00:00:00Z"}]`))
+		default:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":{"code":409,"message":"Document already exists","status":"ALREADY_EXISTS"}}`))
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(context.Background(), "p", "(default)", "", strings.TrimPrefix(server.URL, "http://"))
+	assert.Nil(t, err)
+	contacts := client.Documents("tenants", "acme")
+	assert.Equal(t, "projects/p/databases/(default)/documents/tenants/acme", contacts)
+
+	t.Run("should return the documents matched", func(t *testing.T) {
+		documents, err := client.RunQuery(context.Background(), client.Documents(), &StructuredQuery{
+			From:  []CollectionSelector{{CollectionID: "contacts"}},
+			Where: And(Where("company", "EQUAL", String("Acme"))),
+		})
+		assert.Nil(t, err)
+		assert.Len(t, documents, 1)
+		assert.Equal(t, "1", documents[0].ID())
+		assert.Equal(t, int64(2), documents[0].Int("version"))
+
+		count, err := client.Count(context.Background(), client.Documents(), &StructuredQuery{From: []CollectionSelector{{CollectionID: "contacts"}}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(12), count)
+	})
+
+	t.Run("should report the status of the errors", func(t *testing.T) {
+		err := client.Create(context.Background(), contacts, "contacts", "1", &Document{})
+		assert.True(t, IsAlreadyExists(err))
+		assert.False(t, IsNotFound(err))
+		assert.Equal(t, "POST /v1/projects/p/databases/(default)/documents/tenants/acme/contacts?documentId=1", paths[len(paths)-1])
+	})
+
+	t.Run("should require service account credentials", func(t *testing.T) {
+		_, err := NewClient(context.Background(), "p", "(default)", "missing.json", "")
+		assert.ErrorContains(t, err, "failed to read firestore credentials")
+	})
+}
@@ -0,0 +1,333 @@
+// Package fulltext is an embedded, in-memory full-text index of documents
+// made of text fields, answering fuzzy word and exact phrase queries ranked
+// by relevance, for deployments without a search engine.
+package fulltext
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index keeps the words of the fields of every document, in order. It is
+// safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	boosts map[string]float64
+	// documents are the words of each field of each document, by id.
+	documents map[string]map[string][]string
+	// postings count the times each word is found in each field of each
+	// document.
+	postings map[string]map[string]map[string]int
+}
+
+// New returns an empty index weighing the matches of each field by boosts,
+// 1 for the fields it leaves out.
+func New(boosts map[string]float64) *Index {
+	return &Index{
+		boosts:    boosts,
+		documents: map[string]map[string][]string{},
+		postings:  map[string]map[string]map[string]int{},
+	}
+}
+
+// Put indexes the fields of the document with the given id, replacing the
+// document indexed under it, if any.
+func (ix *Index) Put(id string, fields map[string]string) {
+	document := map[string][]string{}
+	for field, text := range fields {
+		if words := Tokenize(text); len(words) > 0 {
+			document[field] = words
+		}
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+	ix.documents[id] = document
+	for field, words := range document {
+		for _, word := range words {
+			if ix.postings[word] == nil {
+				ix.postings[word] = map[string]map[string]int{}
+			}
+			if ix.postings[word][id] == nil {
+				ix.postings[word][id] = map[string]int{}
+			}
+			ix.postings[word][id][field]++
+		}
+	}
+}
+
+// Delete removes the document with the given id, if any.
+func (ix *Index) Delete(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	for _, words := range ix.documents[id] {
+		for _, word := range words {
+			delete(ix.postings[word], id)
+			if len(ix.postings[word]) == 0 {
+				delete(ix.postings, word)
+			}
+		}
+	}
+	delete(ix.documents, id)
+}
+
+// Len returns the number of documents indexed.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.documents)
+}
+
+// Query selects the documents matching every clause of Text, in any field,
+// and every clause of the text of each of Fields, in that field. A clause is
+// either a word, matching words a few typos away, or a "quoted phrase",
+// matching its words in a row.
+type Query struct {
+	Text   string
+	Fields map[string]string
+}
+
+// Hit is a document matched by a query, with its relevance.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// clause is a word, or the words of a phrase, and the field it has to be
+// found in, any when empty.
+type clause struct {
+	field  string
+	words  []string
+	phrase bool
+}
+
+// Search returns the documents matching query, most relevant first, or every
+// document, by id, for a query without clauses.
+func (ix *Index) Search(query Query) []Hit {
+	clauses := parseClauses("", query.Text)
+	for field, text := range query.Fields {
+		clauses = append(clauses, parseClauses(field, text)...)
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var scores map[string]float64
+	for _, c := range clauses {
+		matches := ix.match(c)
+		if scores == nil {
+			scores = matches
+			continue
+		}
+		for id, score := range scores {
+			if match, ok := matches[id]; ok {
+				scores[id] = score + match
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	if scores == nil {
+		scores = map[string]float64{}
+		for id := range ix.documents {
+			scores[id] = 0
+		}
+	}
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// parseClauses splits text into its quoted phrases and its other words.
+func parseClauses(field, text string) []clause {
+	var clauses []clause
+	for i, part := range strings.Split(text, `"`) {
+		words := Tokenize(part)
+		if len(words) == 0 {
+			continue
+		}
+		// the odd parts are quoted
+		if i%2 == 1 && len(words) > 1 {
+			clauses = append(clauses, clause{field: field, words: words, phrase: true})
+			continue
+		}
+		for _, word := range words {
+			clauses = append(clauses, clause{field: field, words: []string{word}})
+		}
+	}
+	return clauses
+}
+
+// match returns the score of every document matching c.
+func (ix *Index) match(c clause) map[string]float64 {
+	if c.phrase {
+		return ix.matchPhrase(c)
+	}
+	scores := map[string]float64{}
+	for word, distance := range ix.similarWords(c.words[0]) {
+		idf := ix.idf(word)
+		for id, fields := range ix.postings[word] {
+			for field, count := range fields {
+				if c.field != "" && field != c.field {
+					continue
+				}
+				// typos count less than the exact word
+				score := ix.boost(field) * idf * (1 + math.Log(float64(count))) / float64(1+distance)
+				if score > scores[id] {
+					scores[id] = score
+				}
+			}
+		}
+	}
+	return scores
+}
+
+// matchPhrase returns the score of the documents with the words of c in a
+// row, in a single field.
+func (ix *Index) matchPhrase(c clause) map[string]float64 {
+	scores := map[string]float64{}
+	var idf float64
+	for _, word := range c.words {
+		idf += ix.idf(word)
+	}
+	for id := range ix.postings[c.words[0]] {
+		for field, words := range ix.documents[id] {
+			if c.field != "" && field != c.field {
+				continue
+			}
+			if count := countPhrase(words, c.words); count > 0 {
+				score := ix.boost(field) * idf * (1 + math.Log(float64(count)))
+				if score > scores[id] {
+					scores[id] = score
+				}
+			}
+		}
+	}
+	return scores
+}
+
+func countPhrase(words, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		found := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				found = false
+				break
+			}
+		}
+		if found {
+			count++
+		}
+	}
+	return count
+}
+
+// similarWords returns the indexed words within the typos allowed for word,
+// by their distance to it.
+func (ix *Index) similarWords(word string) map[string]int {
+	allowed := allowedTypos(word)
+	similar := map[string]int{}
+	if _, ok := ix.postings[word]; ok {
+		similar[word] = 0
+	}
+	if allowed == 0 {
+		return similar
+	}
+	target := []rune(word)
+	for candidate := range ix.postings {
+		if candidate == word {
+			continue
+		}
+		if distance := editDistance(target, []rune(candidate), allowed); distance <= allowed {
+			similar[candidate] = distance
+		}
+	}
+	return similar
+}
+
+// allowedTypos is the edit distance a word may be matched from: none for up
+// to 2 letters, 1 for up to 5 and 2 beyond.
+func allowedTypos(word string) int {
+	switch length := len([]rune(word)); {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	}
+	return 2
+}
+
+// editDistance returns the Damerau-Levenshtein distance of a and b, counting
+// a swap of adjacent letters as one typo, or max+1 once it exceeds max.
+func editDistance(a, b []rune, max int) int {
+	if diff := len(a) - len(b); diff > max || -diff > max {
+		return max + 1
+	}
+	previous2 := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(b)]
+}
+
+// idf weighs rare words over common ones.
+func (ix *Index) idf(word string) float64 {
+	return 1 + math.Log(float64(len(ix.documents)+1)/float64(len(ix.postings[word])+1))
+}
+
+func (ix *Index) boost(field string) float64 {
+	if boost, ok := ix.boosts[field]; ok {
+		return boost
+	}
+	return 1
+}
+
+// Tokenize returns the words of text, lower cased and without accents, e.g.
+// "Émile Zola-Cohen" gives emile, zola and cohen.
+func Tokenize(text string) []string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+	if err != nil {
+		stripped = text
+	}
+	return strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,51 @@
+package fulltext
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func hitIDs(hits []Hit) []string {
+	ids := []string{}
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestIndex(t *testing.T) {
+	index := New(map[string]float64{"name": 3})
+	index.Put("1", map[string]string{"name": "Dani Cohen", "address": "Herzl 5, Tel Aviv"})
+	index.Put("2", map[string]string{"name": "Émile Zola", "address": "Aviv Tel 3, Haifa"})
+	index.Put("3", map[string]string{"name": "Noa Levi", "notes": "met Dani at the conference"})
+
+	t.Run("should match words a few typos away, best matches first", func(t *testing.T) {
+		assert.Equal(t, []string{"1", "3"}, hitIDs(index.Search(Query{Text: "dnai"})))
+		assert.Equal(t, []string{"2"}, hitIDs(index.Search(Query{Text: "emil zola"})))
+		assert.Empty(t, index.Search(Query{Text: "dani zola"}))
+	})
+
+	t.Run("should match quoted phrases in a row", func(t *testing.T) {
+		assert.Equal(t, []string{"1", "2"}, hitIDs(index.Search(Query{Text: "tel aviv"})))
+		assert.Equal(t, []string{"1"}, hitIDs(index.Search(Query{Text: `"tel aviv"`})))
+	})
+
+	t.Run("should match the words of a field in it only", func(t *testing.T) {
+		assert.Equal(t, []string{"1"}, hitIDs(index.Search(Query{Fields: map[string]string{"name": "dani"}})))
+	})
+
+	t.Run("should forget replaced and deleted documents", func(t *testing.T) {
+		index.Put("1", map[string]string{"name": "Dana Cohen"})
+		index.Delete("3")
+		assert.Empty(t, index.Search(Query{Text: "tel aviv herzl"}))
+		assert.Equal(t, []string{"1"}, hitIDs(index.Search(Query{Text: "dana"})))
+		assert.Equal(t, []string{"1", "2"}, hitIDs(index.Search(Query{})))
+		assert.Equal(t, 2, index.Len())
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 1, editDistance([]rune("dani"), []rune("dnai"), 2))
+	assert.Equal(t, 2, editDistance([]rune("cohen"), []rune("kohn"), 2))
+	assert.Equal(t, 3, editDistance([]rune("dani"), []rune("noa"), 2))
+}
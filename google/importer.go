@@ -0,0 +1,118 @@
+package google
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strings"
+)
+
+// contactsScope allows reading the contacts of the user.
+const contactsScope = "https://www.googleapis.com/auth/contacts.readonly"
+
+// maxImportErrors caps the errors an import reports.
+const maxImportErrors = 10
+
+// Importer imports the contacts of a Google account into a phone book, once
+// the user authorized reading them through the OAuth2 flow.
+type Importer struct {
+	phoneBook definition.IPhoneBook
+	oauth     *oauth2.Config
+	peopleURL string
+}
+
+// NewImporter returns an importer into phoneBook for the OAuth2 client of a
+// Google Cloud project. Google redirects the user to redirectURL with the
+// authorization code.
+func NewImporter(phoneBook definition.IPhoneBook, clientID, clientSecret, redirectURL string) *Importer {
+	return &Importer{
+		phoneBook: phoneBook,
+		oauth: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     endpoints.Google,
+			Scopes:       []string{contactsScope},
+		},
+		peopleURL: peopleURL,
+	}
+}
+
+// AuthCodeURL returns the Google consent page the user is sent to, which
+// redirects back with state.
+func (i *Importer) AuthCodeURL(state string) string {
+	return i.oauth.AuthCodeURL(state, oauth2.AccessTypeOnline)
+}
+
+// Import exchanges the authorization code for a token and adds the contacts
+// of the account, as actor. Contacts whose phone is already in the phone book,
+// or repeats an earlier contact of the account, are skipped.
+func (i *Importer) Import(ctx context.Context, code string, actor string) (*definition.ImportResult, error) {
+	token, err := i.oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authorize with google: %w", err)
+	}
+	people, err := fetchConnections(ctx, i.oauth.Client(ctx, token), i.peopleURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read google contacts: %w", err)
+	}
+	result := &definition.ImportResult{}
+	seen := map[string]bool{}
+	for _, p := range people {
+		contact := p.toContact()
+		if contact.Phone != "" && seen[contact.Phone] {
+			result.Duplicates++
+			continue
+		}
+		seen[contact.Phone] = true
+		duplicate, err := i.exists(ctx, contact)
+		if err == nil && !duplicate {
+			_, err = i.phoneBook.AddContact(ctx, contact, actor)
+			duplicate = isDuplicate(err)
+		}
+		switch {
+		case duplicate:
+			result.Duplicates++
+		case err != nil:
+			result.Failed++
+			if len(result.Errors) < maxImportErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", contactName(contact), err))
+			}
+		default:
+			result.Imported++
+		}
+	}
+	return result, nil
+}
+
+// exists reports whether a contact with the phone of contact is in the phone
+// book already.
+func (i *Importer) exists(ctx context.Context, contact *definition.Contact) (bool, error) {
+	if contact.Phone == "" {
+		return false, nil
+	}
+	matches, _, err := i.phoneBook.SearchContact(ctx, url.Values{"phone": {contact.Phone}, "fields": {"phone"}, "pageSize": {"1"}, "count": {"false"}, "includeArchived": {"true"}})
+	if err != nil {
+		return false, err
+	}
+	return len(matches.Items) > 0, nil
+}
+
+// isDuplicate reports whether the phone book rejected a contact as a
+// duplicate, when it was added meanwhile.
+func isDuplicate(err error) bool {
+	return errors.Is(err, core.ErrDuplicateContact)
+}
+
+func contactName(contact *definition.Contact) string {
+	name := strings.TrimSpace(contact.FirstName + " " + contact.LastName)
+	if name == "" {
+		return "unnamed contact"
+	}
+	return name
+}
@@ -0,0 +1,110 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"testing"
+)
+
+// importPhoneBook keeps the contacts added in a slice, validating them like
+// the Mongo phone book.
+type importPhoneBook struct {
+	definition.IPhoneBook
+	contacts []*definition.Contact
+}
+
+func (pb *importPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	var matches []*definition.Contact
+	for _, contact := range pb.contacts {
+		if contact.Phone == query.Get("phone") {
+			matches = append(matches, contact)
+		}
+	}
+	return &definition.ContactPage{Items: matches}, "", nil
+}
+
+func (pb *importPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	if contact.Phone == "" {
+		return nil, core.ErrMissingPhone
+	}
+	pb.contacts = append(pb.contacts, contact)
+	return &definition.WriteResult{}, nil
+}
+
+// fakeGoogle serves the token endpoint and two pages of the People API.
+func fakeGoogle(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "the-code", r.FormValue("code"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "the-token", "token_type": "Bearer", "expires_in": 3600}`)
+	})
+	mux.HandleFunc("GET /connections", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer the-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "names,phoneNumbers,emailAddresses,organizations", r.URL.Query().Get("personFields"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"connections": [
+				{"names": [{"givenName": "Dani", "familyName": "Cohen"}],
+				 "phoneNumbers": [{"value": "+972 52-123-4567", "canonicalForm": "+972521234567"}, {"value": "03-1234567"}],
+				 "emailAddresses": [{"value": "dani@example.com"}, {"value": "dani@work.example.com"}],
+				 "organizations": [{"name": "Acme", "title": "CTO"}]},
+				{"names": [{"givenName": "Existing"}], "phoneNumbers": [{"value": "0501111111"}]}
+			], "nextPageToken": "second"}`)
+			return
+		}
+		fmt.Fprint(w, `{"connections": [
+			{"names": [{"givenName": "Dan"}], "phoneNumbers": [{"value": "+972521234567"}]},
+			{"names": [{"givenName": "Nophone"}]}
+		]}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestImport(t *testing.T) {
+	t.Run("should import mapped contacts and skip duplicates", func(t *testing.T) {
+		server := fakeGoogle(t)
+		phoneBook := &importPhoneBook{contacts: []*definition.Contact{{FirstName: "Existing", Phone: "0501111111"}}}
+		importer := NewImporter(phoneBook, "id", "secret", "http://localhost/callback")
+		importer.oauth.Endpoint.TokenURL = server.URL + "/token"
+		importer.peopleURL = server.URL + "/connections"
+
+		result, err := importer.Import(context.Background(), "the-code", "tester")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), result.Imported)
+		assert.Equal(t, int64(2), result.Duplicates)
+		assert.Equal(t, int64(1), result.Failed)
+		assert.Equal(t, []string{"Nophone: " + core.ErrorMissingPhone}, result.Errors)
+		assert.Equal(t, &definition.Contact{
+			FirstName: "Dani",
+			LastName:  "Cohen",
+			Phone:     "972521234567",
+			Email:     "dani@example.com",
+			Company:   "Acme",
+			JobTitle:  "CTO",
+			Notes:     "Also: 031234567, dani@work.example.com",
+		}, phoneBook.contacts[1])
+	})
+
+	t.Run("should fail when the code is rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+		}))
+		defer server.Close()
+		importer := NewImporter(&importPhoneBook{}, "id", "secret", "http://localhost/callback")
+		importer.oauth.Endpoint.TokenURL = server.URL
+
+		_, err := importer.Import(context.Background(), "expired", "tester")
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+}
@@ -0,0 +1,132 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"phoneBook/definition"
+	"strings"
+)
+
+// peopleURL lists the contacts of the signed in user in the People API.
+const peopleURL = "https://people.googleapis.com/v1/people/me/connections"
+
+// person is the part of a People API person the import maps.
+type person struct {
+	Names []struct {
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"names"`
+	PhoneNumbers []struct {
+		Value         string `json:"value"`
+		CanonicalForm string `json:"canonicalForm"`
+	} `json:"phoneNumbers"`
+	EmailAddresses []struct {
+		Value string `json:"value"`
+	} `json:"emailAddresses"`
+	Organizations []struct {
+		Name  string `json:"name"`
+		Title string `json:"title"`
+	} `json:"organizations"`
+}
+
+type connectionsPage struct {
+	Connections   []person `json:"connections"`
+	NextPageToken string   `json:"nextPageToken"`
+}
+
+// fetchConnections reads every page of the contacts of the user httpClient
+// is authorized for.
+func fetchConnections(ctx context.Context, httpClient *http.Client, endpoint string) ([]person, error) {
+	var people []person
+	pageToken := ""
+	for {
+		query := url.Values{
+			"personFields": {"names,phoneNumbers,emailAddresses,organizations"},
+			"pageSize":     {"1000"},
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		page, err := fetchConnectionsPage(ctx, httpClient, endpoint+"?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, page.Connections...)
+		if page.NextPageToken == "" {
+			return people, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func fetchConnectionsPage(ctx context.Context, httpClient *http.Client, pageURL string) (*connectionsPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("people api responded %s", resp.Status)
+	}
+	var page connectionsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("invalid people api response: %w", err)
+	}
+	return &page, nil
+}
+
+// toContact maps the first name, phone, email and organization of p to a
+// contact. Further phones and emails are kept in the notes.
+func (p person) toContact() *definition.Contact {
+	contact := &definition.Contact{}
+	if len(p.Names) > 0 {
+		contact.FirstName = p.Names[0].GivenName
+		contact.LastName = p.Names[0].FamilyName
+	}
+	var others []string
+	for i, phone := range p.PhoneNumbers {
+		number := phone.CanonicalForm
+		if number == "" {
+			number = phone.Value
+		}
+		number = digitsOnly(number)
+		if i == 0 {
+			contact.Phone = number
+		} else if number != "" {
+			others = append(others, number)
+		}
+	}
+	for i, email := range p.EmailAddresses {
+		if i == 0 {
+			contact.Email = email.Value
+		} else {
+			others = append(others, email.Value)
+		}
+	}
+	if len(p.Organizations) > 0 {
+		contact.Company = strings.TrimSpace(p.Organizations[0].Name)
+		contact.JobTitle = strings.TrimSpace(p.Organizations[0].Title)
+	}
+	if len(others) > 0 {
+		contact.Notes = "Also: " + strings.Join(others, ", ")
+	}
+	return contact
+}
+
+// digitsOnly drops the plus sign, spaces and dashes phone numbers are written
+// with, as contact phones are digits only.
+func digitsOnly(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
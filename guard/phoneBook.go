@@ -0,0 +1,199 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+const (
+	// window is how long the contacts a client deleted are counted.
+	window = time.Minute
+	// alertTimeout bounds the posting of an alert.
+	alertTimeout = 10 * time.Second
+)
+
+// Alert reports a client blocked for deleting too many contacts.
+type Alert struct {
+	Client       string    `json:"client"`
+	Deleted      int64     `json:"deleted"`
+	Limit        int64     `json:"limit"`
+	BlockedAt    time.Time `json:"blockedAt"`
+	BlockedUntil time.Time `json:"blockedUntil"`
+}
+
+// Alerter sends the alerts of the blocked clients.
+type Alerter interface {
+	Alert(ctx context.Context, alert *Alert) error
+}
+
+// PhoneBook blocks the deletes of a client once it deleted more than limit
+// contacts in a minute, for block, protecting the phone book from buggy
+// scripts wiping it. The clients are those of the requests, the deletes
+// made outside of one, like those of the retention scheduler, aren't
+// guarded. The counts are kept in memory, each replica guards the requests
+// it serves.
+type PhoneBook struct {
+	definition.IPhoneBook
+	limit   int64
+	block   time.Duration
+	alerter Alerter
+	now     func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+// client is what the guard knows of a client.
+type client struct {
+	deletes      []deletes
+	blockedUntil time.Time
+}
+
+// deletes are the contacts a client deleted at once.
+type deletes struct {
+	at    time.Time
+	count int64
+}
+
+// NewPhoneBook wraps phoneBook, blocking the deletes of the clients deleting
+// more than limit contacts a minute for block, and sending an alert with
+// alerter, if not nil, when it does.
+func NewPhoneBook(phoneBook definition.IPhoneBook, limit int64, block time.Duration, alerter Alerter) *PhoneBook {
+	return &PhoneBook{
+		IPhoneBook: phoneBook,
+		limit:      limit,
+		block:      block,
+		alerter:    alerter,
+		now:        time.Now,
+		clients:    map[string]*client{},
+		lastSweep:  time.Now(),
+	}
+}
+
+func (pb *PhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	if err := pb.check(ctx); err != nil {
+		return nil, err
+	}
+	result, err := pb.IPhoneBook.DeleteContact(ctx, id, actor)
+	if err == nil {
+		pb.record(ctx, result.Modified)
+	}
+	return result, err
+}
+
+// DeleteContacts guards the batch deletes, but not their dry runs.
+func (pb *PhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	if !batch.DryRun {
+		if err := pb.check(ctx); err != nil {
+			return nil, err
+		}
+	}
+	result, err := pb.IPhoneBook.DeleteContacts(ctx, batch, actor)
+	if err == nil && !result.DryRun {
+		pb.record(ctx, result.Deleted)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	if err := pb.check(ctx); err != nil {
+		return nil, err
+	}
+	result, err := pb.IPhoneBook.EraseContacts(ctx, phone, actor)
+	if err == nil {
+		pb.record(ctx, result.Contacts)
+	}
+	return result, err
+}
+
+// check returns ErrDeletesBlocked while the client of ctx is blocked.
+func (pb *PhoneBook) check(ctx context.Context) error {
+	key := definition.ClientFromContext(ctx)
+	if key == "" {
+		return nil
+	}
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	c, ok := pb.clients[key]
+	if !ok || !pb.now().Before(c.blockedUntil) {
+		return nil
+	}
+	message := fmt.Sprintf("%s until %s", core.ErrorDeletesBlocked, c.blockedUntil.UTC().Format(time.RFC3339))
+	return core.ErrDeletesBlocked.WithMessage(message)
+}
+
+// record counts count contacts deleted by the client of ctx, blocking it
+// once it deleted more than the limit within the window.
+func (pb *PhoneBook) record(ctx context.Context, count int64) {
+	key := definition.ClientFromContext(ctx)
+	if key == "" || count == 0 {
+		return
+	}
+	now := pb.now()
+	pb.mu.Lock()
+	pb.sweep(now)
+	c, ok := pb.clients[key]
+	if !ok {
+		c = &client{}
+		pb.clients[key] = c
+	}
+	c.deletes = append(c.deletes, deletes{at: now, count: count})
+	deleted := c.deleted(now)
+	if deleted <= pb.limit || now.Before(c.blockedUntil) {
+		pb.mu.Unlock()
+		return
+	}
+	c.blockedUntil = now.Add(pb.block)
+	c.deletes = nil
+	alert := &Alert{Client: key, Deleted: deleted, Limit: pb.limit, BlockedAt: now, BlockedUntil: c.blockedUntil}
+	pb.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{"client": key, "deleted": deleted}).
+		Warnf("blocking the deletes of a client deleting over %d contacts a minute until %s", pb.limit, alert.BlockedUntil.Format(time.RFC3339))
+	if pb.alerter != nil {
+		go pb.sendAlert(alert)
+	}
+}
+
+func (pb *PhoneBook) sendAlert(alert *Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	if err := pb.alerter.Alert(ctx, alert); err != nil {
+		logrus.WithError(err).WithField("client", alert.Client).Error("failed to send the alert of a blocked client")
+	}
+}
+
+// deleted forgets the deletes older than the window and returns the
+// contacts deleted within it.
+func (c *client) deleted(now time.Time) int64 {
+	recent := c.deletes[:0]
+	var deleted int64
+	for _, d := range c.deletes {
+		if now.Sub(d.at) < window {
+			recent = append(recent, d)
+			deleted += d.count
+		}
+	}
+	c.deletes = recent
+	return deleted
+}
+
+// sweep forgets the clients neither blocked nor deleting within the window,
+// at most once per window.
+func (pb *PhoneBook) sweep(now time.Time) {
+	if now.Sub(pb.lastSweep) < window {
+		return
+	}
+	for key, c := range pb.clients {
+		if c.deleted(now) == 0 && !now.Before(c.blockedUntil) {
+			delete(pb.clients, key)
+		}
+	}
+	pb.lastSweep = now
+}
@@ -0,0 +1,126 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+// stubPhoneBook deletes every contact it's asked to.
+type stubPhoneBook struct {
+	definition.IPhoneBook
+	deleted int
+}
+
+func (pb *stubPhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	pb.deleted++
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *stubPhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	if !batch.DryRun {
+		pb.deleted += len(batch.IDs)
+	}
+	return &definition.BatchDeleteResult{Deleted: int64(len(batch.IDs)), IDs: batch.IDs, DryRun: batch.DryRun}, nil
+}
+
+// channelAlerter sends the alerts to a channel.
+type channelAlerter chan *Alert
+
+func (a channelAlerter) Alert(ctx context.Context, alert *Alert) error {
+	a <- alert
+	return nil
+}
+
+func TestPhoneBook(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	script := definition.WithClient(context.Background(), "key:3f2a")
+	other := definition.WithClient(context.Background(), "ip:10.0.0.7")
+	newGuard := func(alerter Alerter) (*PhoneBook, *stubPhoneBook) {
+		stub := &stubPhoneBook{}
+		guard := NewPhoneBook(stub, 3, 15*time.Minute, alerter)
+		guard.now = func() time.Time { return now }
+		return guard, stub
+	}
+
+	t.Run("should block a client deleting over the limit and alert", func(t *testing.T) {
+		alerts := make(channelAlerter, 1)
+		guard, stub := newGuard(alerts)
+		for i := 0; i < 4; i++ {
+			_, err := guard.DeleteContact(script, "65a1b2c3d4e5f60718293a4b", "script")
+			assert.Nil(t, err)
+		}
+		_, err := guard.DeleteContact(script, "65a1b2c3d4e5f60718293a4b", "script")
+		assert.True(t, errors.Is(err, core.ErrDeletesBlocked))
+		assert.Equal(t, core.TooManyRequests, core.StatusOf(err))
+		_, err = guard.DeleteContacts(script, &definition.BatchDelete{IDs: []string{"65a1b2c3d4e5f60718293a4c"}}, "script")
+		assert.True(t, errors.Is(err, core.ErrDeletesBlocked))
+		assert.Equal(t, core.TooManyRequests, core.StatusOf(err))
+		assert.Equal(t, 4, stub.deleted)
+
+		select {
+		case alert := <-alerts:
+			assert.Equal(t, &Alert{Client: "key:3f2a", Deleted: 4, Limit: 3, BlockedAt: now, BlockedUntil: now.Add(15 * time.Minute)}, alert)
+		case <-time.After(time.Second):
+			t.Fatal("the alert wasn't sent")
+		}
+
+		_, err = guard.DeleteContact(other, "65a1b2c3d4e5f60718293a4b", "someone")
+		assert.Nil(t, err, "the other clients shouldn't be blocked")
+		_, err = guard.DeleteContact(context.Background(), "65a1b2c3d4e5f60718293a4b", "retention")
+		assert.Nil(t, err, "the deletes outside of a request shouldn't be guarded")
+	})
+
+	t.Run("should unblock a client after the block", func(t *testing.T) {
+		guard, _ := newGuard(nil)
+		guard.DeleteContacts(script, &definition.BatchDelete{IDs: []string{"1", "2", "3", "4"}}, "script")
+		_, err := guard.DeleteContact(script, "65a1b2c3d4e5f60718293a4b", "script")
+		assert.True(t, errors.Is(err, core.ErrDeletesBlocked))
+		now = now.Add(15 * time.Minute)
+		_, err = guard.DeleteContact(script, "65a1b2c3d4e5f60718293a4b", "script")
+		assert.Nil(t, err)
+	})
+
+	t.Run("should only count the deletes of the last minute", func(t *testing.T) {
+		guard, _ := newGuard(nil)
+		for i := 0; i < 6; i++ {
+			_, err := guard.DeleteContact(script, "65a1b2c3d4e5f60718293a4b", "script")
+			assert.Nil(t, err)
+			now = now.Add(30 * time.Second)
+		}
+	})
+
+	t.Run("should not count dry runs", func(t *testing.T) {
+		guard, stub := newGuard(nil)
+		batch := &definition.BatchDelete{IDs: []string{"1", "2", "3", "4"}, DryRun: true}
+		guard.DeleteContacts(script, batch, "script")
+		_, err := guard.DeleteContact(script, "65a1b2c3d4e5f60718293a4b", "script")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, stub.deleted)
+	})
+}
+
+func TestWebhookAlerter(t *testing.T) {
+	var received Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+	alert := &Alert{Client: "key:3f2a", Deleted: 120, Limit: 100}
+	assert.Nil(t, NewWebhookAlerter(server.URL).Alert(context.Background(), alert))
+	assert.Equal(t, *alert, received)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	assert.NotNil(t, NewWebhookAlerter(failing.URL).Alert(context.Background(), alert))
+}
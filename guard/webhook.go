@@ -0,0 +1,44 @@
+package guard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// webhookAlerter posts the alerts as JSON to a url.
+type webhookAlerter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAlerter returns an alerter posting every alert to url, failing
+// unless it responds with a 2xx status.
+func NewWebhookAlerter(url string) Alerter {
+	return &webhookAlerter{url: url, client: http.DefaultClient}
+}
+
+func (a *webhookAlerter) Alert(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("alert webhook responded %s: %s", response.Status, message)
+	}
+	return nil
+}
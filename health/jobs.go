@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"phoneBook/definition"
+	"sync/atomic"
+)
+
+// Jobs forwards to jobs that can be swapped while requests are served, like
+// PhoneBook.
+type Jobs struct {
+	current atomic.Pointer[definition.IJobs]
+}
+
+// NewJobs returns jobs forwarding to jobs until swapped.
+func NewJobs(jobs definition.IJobs) *Jobs {
+	j := &Jobs{}
+	j.Swap(jobs)
+	return j
+}
+
+// Swap forwards the following calls to jobs.
+func (j *Jobs) Swap(jobs definition.IJobs) {
+	j.current.Store(&jobs)
+}
+
+func (j *Jobs) get() definition.IJobs {
+	return *j.current.Load()
+}
+
+func (j *Jobs) SaveJob(ctx context.Context, job *definition.Job) error {
+	return j.get().SaveJob(ctx, job)
+}
+
+func (j *Jobs) GetJob(ctx context.Context, id string) (*definition.Job, string, error) {
+	return j.get().GetJob(ctx, id)
+}
+
+func (j *Jobs) ListJobs(ctx context.Context, filter definition.JobFilter) ([]*definition.Job, string, error) {
+	return j.get().ListJobs(ctx, filter)
+}
@@ -0,0 +1,246 @@
+package health
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"phoneBook/definition"
+	"sync/atomic"
+	"time"
+)
+
+// PhoneBook forwards to a phone book that can be swapped while requests are
+// served, so a reconnection can replace the phone book bound to the old
+// database client.
+type PhoneBook struct {
+	current atomic.Pointer[definition.IPhoneBook]
+}
+
+// NewPhoneBook returns a phone book forwarding to phoneBook until swapped.
+func NewPhoneBook(phoneBook definition.IPhoneBook) *PhoneBook {
+	pb := &PhoneBook{}
+	pb.Swap(phoneBook)
+	return pb
+}
+
+// Swap forwards the following calls to phoneBook. Calls in progress finish
+// on the previous phone book.
+func (pb *PhoneBook) Swap(phoneBook definition.IPhoneBook) {
+	pb.current.Store(&phoneBook)
+}
+
+func (pb *PhoneBook) get() definition.IPhoneBook {
+	return *pb.current.Load()
+}
+
+func (pb *PhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	return pb.get().GetContact(ctx, id)
+}
+
+func (pb *PhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.get().GetContactWithPagination(ctx, query)
+}
+
+func (pb *PhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return pb.get().AddContact(ctx, contact, actor)
+}
+
+func (pb *PhoneBook) UpdateContact(ctx context.Context, id string, updatedContact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	return pb.get().UpdateContact(ctx, id, updatedContact, expectedVersion, actor)
+}
+
+func (pb *PhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	return pb.get().PatchContact(ctx, id, patch, expectedVersion, actor)
+}
+
+func (pb *PhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	return pb.get().DeleteContact(ctx, id, actor)
+}
+
+func (pb *PhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	return pb.get().DeleteContacts(ctx, batch, actor)
+}
+
+func (pb *PhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.get().SearchContact(ctx, query)
+}
+
+func (pb *PhoneBook) SearchContactText(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return pb.get().SearchContactText(ctx, query)
+}
+
+func (pb *PhoneBook) GetContactIndex(ctx context.Context) ([]*definition.LetterCount, string, error) {
+	return pb.get().GetContactIndex(ctx)
+}
+
+func (pb *PhoneBook) GetRecentContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return pb.get().GetRecentContacts(ctx, query)
+}
+
+func (pb *PhoneBook) GetRandomContact(ctx context.Context) (*definition.Contact, string, error) {
+	return pb.get().GetRandomContact(ctx)
+}
+
+func (pb *PhoneBook) SampleContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return pb.get().SampleContacts(ctx, query)
+}
+
+func (pb *PhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	return pb.get().GetContactsByPhone(ctx, number)
+}
+
+func (pb *PhoneBook) GetCompanyContacts(ctx context.Context, name string, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.get().GetCompanyContacts(ctx, name, query)
+}
+
+func (pb *PhoneBook) ListCompanies(ctx context.Context, prefix string) ([]string, string, error) {
+	return pb.get().ListCompanies(ctx, prefix)
+}
+
+func (pb *PhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	return pb.get().ExportContacts(ctx, query, w)
+}
+
+func (pb *PhoneBook) GetContactHistory(ctx context.Context, id string) ([]*definition.AuditEntry, string, error) {
+	return pb.get().GetContactHistory(ctx, id)
+}
+
+func (pb *PhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	return pb.get().UndoContact(ctx, id, actor)
+}
+
+func (pb *PhoneBook) LinkContact(ctx context.Context, id string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	return pb.get().LinkContact(ctx, id, relation, actor)
+}
+
+func (pb *PhoneBook) UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*definition.WriteResult, error) {
+	return pb.get().UnlinkContact(ctx, id, relatedID, actor)
+}
+
+func (pb *PhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	return pb.get().ArchiveContact(ctx, id, archived, actor)
+}
+
+func (pb *PhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	return pb.get().ReorderContacts(ctx, order, actor)
+}
+
+func (pb *PhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	return pb.get().BlockContact(ctx, id, blocked, actor)
+}
+
+func (pb *PhoneBook) GetBlocklist(ctx context.Context) ([]*definition.BlockedNumber, string, error) {
+	return pb.get().GetBlocklist(ctx)
+}
+
+func (pb *PhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	return pb.get().CheckBlocked(ctx, number)
+}
+
+func (pb *PhoneBook) GetRelatedContacts(ctx context.Context, id string) ([]*definition.RelatedContact, string, error) {
+	return pb.get().GetRelatedContacts(ctx, id)
+}
+
+func (pb *PhoneBook) AddInteraction(ctx context.Context, id string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	return pb.get().AddInteraction(ctx, id, interaction, actor)
+}
+
+func (pb *PhoneBook) GetInteractions(ctx context.Context, id string, query url.Values) (*definition.InteractionPage, string, error) {
+	return pb.get().GetInteractions(ctx, id, query)
+}
+
+func (pb *PhoneBook) DeleteInteraction(ctx context.Context, id string, interactionID string) (*definition.WriteResult, error) {
+	return pb.get().DeleteInteraction(ctx, id, interactionID)
+}
+
+func (pb *PhoneBook) AddReminder(ctx context.Context, id string, reminder *definition.Reminder, actor string) (*definition.WriteResult, error) {
+	return pb.get().AddReminder(ctx, id, reminder, actor)
+}
+
+func (pb *PhoneBook) GetReminders(ctx context.Context, due string) ([]*definition.Reminder, string, error) {
+	return pb.get().GetReminders(ctx, due)
+}
+
+func (pb *PhoneBook) ClaimDueReminder(ctx context.Context, now time.Time) (*definition.Reminder, error) {
+	return pb.get().ClaimDueReminder(ctx, now)
+}
+
+func (pb *PhoneBook) AssignSpeedDial(ctx context.Context, slot string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	return pb.get().AssignSpeedDial(ctx, slot, dial, actor)
+}
+
+func (pb *PhoneBook) GetSpeedDials(ctx context.Context) ([]*definition.SpeedDial, string, error) {
+	return pb.get().GetSpeedDials(ctx)
+}
+
+func (pb *PhoneBook) ClearSpeedDial(ctx context.Context, slot string) (*definition.WriteResult, error) {
+	return pb.get().ClearSpeedDial(ctx, slot)
+}
+
+func (pb *PhoneBook) ListIndexes(ctx context.Context) ([]*definition.Index, string, error) {
+	return pb.get().ListIndexes(ctx)
+}
+
+func (pb *PhoneBook) Backup(ctx context.Context, format string, w io.Writer) (int64, string, error) {
+	return pb.get().Backup(ctx, format, w)
+}
+
+func (pb *PhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	return pb.get().Restore(ctx, format, mode, r)
+}
+
+func (pb *PhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	return pb.get().SeedContacts(ctx, count, actor)
+}
+
+func (pb *PhoneBook) GetUsage(ctx context.Context) (*definition.Usage, string, error) {
+	return pb.get().GetUsage(ctx)
+}
+
+func (pb *PhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	return pb.get().ApplyRetention(ctx, policy, actor)
+}
+
+func (pb *PhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	return pb.get().EraseContacts(ctx, phone, actor)
+}
+
+func (pb *PhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	return pb.get().Migrate(ctx, dryRun)
+}
+
+func (pb *PhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	return pb.get().ValidateContacts(ctx, fix, actor)
+}
+
+func (pb *PhoneBook) GetChanges(ctx context.Context, query url.Values) (*definition.ChangePage, string, error) {
+	return pb.get().GetChanges(ctx, query)
+}
+
+func (pb *PhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	return pb.get().GetLastModified(ctx)
+}
+
+func (pb *PhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	return pb.get().CountContacts(ctx, query)
+}
+
+func (pb *PhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	return pb.get().GetDistinctValues(ctx, field)
+}
+
+func (pb *PhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return pb.get().UpsertContact(ctx, id, contact, actor)
+}
+
+func (pb *PhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return pb.get().UpsertContactByPhone(ctx, number, contact, actor)
+}
+
+func (pb *PhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	return pb.get().RebuildDerivedData(ctx, progress)
+}
+
+func (pb *PhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	return pb.get().AddContacts(ctx, contacts, actor)
+}
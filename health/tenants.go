@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"phoneBook/definition"
+	"sync/atomic"
+)
+
+// Tenants forwards to tenants that can be swapped while requests are served,
+// like PhoneBook.
+type Tenants struct {
+	current atomic.Pointer[definition.ITenants]
+}
+
+// NewTenants returns tenants forwarding to tenants until swapped.
+func NewTenants(tenants definition.ITenants) *Tenants {
+	t := &Tenants{}
+	t.Swap(tenants)
+	return t
+}
+
+// Swap forwards the following calls to tenants.
+func (t *Tenants) Swap(tenants definition.ITenants) {
+	t.current.Store(&tenants)
+}
+
+func (t *Tenants) get() definition.ITenants {
+	return *t.current.Load()
+}
+
+func (t *Tenants) CreateTenant(ctx context.Context, tenant *definition.Tenant) (*definition.Tenant, string, error) {
+	return t.get().CreateTenant(ctx, tenant)
+}
+
+func (t *Tenants) GetTenant(ctx context.Context, id string) (*definition.Tenant, string, error) {
+	return t.get().GetTenant(ctx, id)
+}
+
+func (t *Tenants) ListTenants(ctx context.Context) ([]*definition.Tenant, string, error) {
+	return t.get().ListTenants(ctx)
+}
+
+func (t *Tenants) DeleteTenant(ctx context.Context, id string) (int64, string, error) {
+	return t.get().DeleteTenant(ctx, id)
+}
+
+func (t *Tenants) SetTenantLimits(ctx context.Context, id string, limits *definition.TenantLimits) (*definition.Tenant, string, error) {
+	return t.get().SetTenantLimits(ctx, id, limits)
+}
+
+func (t *Tenants) LookupTenant(ctx context.Context, id string) (*definition.Tenant, error) {
+	return t.get().LookupTenant(ctx, id)
+}
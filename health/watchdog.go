@@ -0,0 +1,152 @@
+// Package health watches the connection to MongoDB, reporting whether the
+// service is ready and reconnecting when the database stops answering.
+package health
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	up = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "phonebook_mongo_up",
+		Help: "Whether MongoDB answers the health pings, 1 if it does.",
+	})
+	pingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "phonebook_mongo_ping_duration_seconds",
+		Help:    "How long the MongoDB health pings took.",
+		Buckets: prometheus.DefBuckets,
+	})
+	pingFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_mongo_ping_failures_total",
+		Help: "MongoDB health pings that failed.",
+	})
+	reconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "phonebook_mongo_reconnects_total",
+		Help: "Reconnections to MongoDB after failed health pings, by result.",
+	}, []string{"result"})
+)
+
+// Watchdog pings the database every interval. After failures consecutive
+// failed pings the service is reported unready and the watchdog reconnects,
+// retrying with a backoff doubled from the interval up to maxBackoff until
+// a reconnection succeeds. The first successful ping reports it ready again.
+type Watchdog struct {
+	ping       func(ctx context.Context) error
+	reconnect  func(ctx context.Context) error
+	interval   time.Duration
+	timeout    time.Duration
+	failures   int
+	maxBackoff time.Duration
+
+	ready atomic.Bool
+	// failed counts the consecutive failed pings, backoff is the wait before
+	// the next reconnection and nextReconnect when it's due. Only the
+	// watchdog goroutine uses them.
+	failed        int
+	backoff       time.Duration
+	nextReconnect time.Time
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewWatchdog returns a watchdog calling ping every interval and reconnect
+// after failures consecutive failed pings, each bound by timeout. The
+// service is reported ready until the pings fail.
+func NewWatchdog(ping, reconnect func(ctx context.Context) error, interval, timeout time.Duration, failures int, maxBackoff time.Duration) *Watchdog {
+	w := &Watchdog{
+		ping:       ping,
+		reconnect:  reconnect,
+		interval:   interval,
+		timeout:    timeout,
+		failures:   failures,
+		maxBackoff: maxBackoff,
+		stop:       make(chan struct{}),
+	}
+	w.ready.Store(true)
+	up.Set(1)
+	return w
+}
+
+// Ready reports whether the database answers, for readiness probes.
+func (w *Watchdog) Ready() bool {
+	return w.ready.Load()
+}
+
+// Start pings in the background, the first time after an interval.
+func (w *Watchdog) Start() {
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case now := <-ticker.C:
+				w.check(now)
+			}
+		}
+	}()
+}
+
+// Stop waits for the ping or reconnection in progress, if any, and for the
+// watchdog to exit.
+func (w *Watchdog) Stop() {
+	close(w.stop)
+	w.done.Wait()
+}
+
+// check pings the database and reconnects when it failed too many times in
+// a row and the backoff has elapsed.
+func (w *Watchdog) check(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	started := time.Now()
+	err := w.ping(ctx)
+	cancel()
+	pingDuration.Observe(time.Since(started).Seconds())
+	if err == nil {
+		if !w.ready.Load() {
+			logrus.Info("MongoDB answers again, the service is ready")
+		}
+		w.failed = 0
+		w.backoff = 0
+		w.ready.Store(true)
+		up.Set(1)
+		return
+	}
+	pingFailures.Inc()
+	w.failed++
+	logrus.WithError(err).Warnf("MongoDB health ping failed (%d in a row)", w.failed)
+	if w.failed < w.failures {
+		return
+	}
+	if w.ready.Load() {
+		logrus.Errorf("MongoDB failed %d health pings in a row, the service is unready", w.failed)
+	}
+	w.ready.Store(false)
+	up.Set(0)
+	if now.Before(w.nextReconnect) {
+		return
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), w.timeout)
+	err = w.reconnect(ctx)
+	cancel()
+	if err == nil {
+		reconnects.WithLabelValues("success").Inc()
+		logrus.Info("reconnected to MongoDB")
+		w.backoff = 0
+		return
+	}
+	reconnects.WithLabelValues("failure").Inc()
+	w.backoff = min(max(2*w.backoff, w.interval), w.maxBackoff)
+	w.nextReconnect = now.Add(w.backoff)
+	logrus.WithError(err).Errorf("failed to reconnect to MongoDB, retrying in %v", w.backoff)
+}
@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	errDown := errors.New("server selection timeout")
+	var pingErr, reconnectErr error
+	reconnects := 0
+	watchdog := NewWatchdog(
+		func(ctx context.Context) error { return pingErr },
+		func(ctx context.Context) error { reconnects++; return reconnectErr },
+		time.Second, time.Second, 3, 4*time.Second)
+	now := time.Now()
+	tick := func() {
+		now = now.Add(time.Second)
+		watchdog.check(now)
+	}
+
+	t.Run("should stay ready until the pings fail too many times in a row", func(t *testing.T) {
+		assert.True(t, watchdog.Ready())
+		pingErr = errDown
+		tick()
+		tick()
+		assert.True(t, watchdog.Ready())
+		assert.Zero(t, reconnects)
+	})
+
+	t.Run("should turn unready and reconnect with backoff", func(t *testing.T) {
+		reconnectErr = errDown
+		tick()
+		assert.False(t, watchdog.Ready())
+		assert.Equal(t, 1, reconnects)
+		// retried after a second, then two, then four, the maximum
+		for _, expected := range []int{2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5} {
+			tick()
+			assert.Equal(t, expected, reconnects)
+		}
+	})
+
+	t.Run("should be ready once the pings succeed", func(t *testing.T) {
+		reconnectErr = nil
+		pingErr = nil
+		tick()
+		assert.True(t, watchdog.Ready())
+		assert.Equal(t, 5, reconnects)
+	})
+}
+
+func TestPhoneBookSwap(t *testing.T) {
+	phoneBook := NewPhoneBook(&namedPhoneBook{name: "old"})
+	contact, _, _ := phoneBook.GetContact(context.Background(), "1")
+	assert.Equal(t, "old", contact.FirstName)
+
+	phoneBook.Swap(&namedPhoneBook{name: "new"})
+	contact, _, _ = phoneBook.GetContact(context.Background(), "1")
+	assert.Equal(t, "new", contact.FirstName)
+}
+
+// namedPhoneBook answers every contact with its name.
+type namedPhoneBook struct {
+	definition.IPhoneBook
+	name string
+}
+
+func (pb *namedPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	return &definition.Contact{FirstName: pb.name}, "", nil
+}
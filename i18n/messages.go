@@ -0,0 +1,74 @@
+// Package i18n translates the messages of the API errors to the language
+// the clients accept, from the catalogs of each language. The error codes
+// stay as they are, for clients to branch on whatever the language.
+package i18n
+
+import (
+	"embed"
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+	"path"
+	"strings"
+)
+
+// DefaultLanguage is the language the errors are written in, and answered
+// in when the client accepts none of the others.
+const DefaultLanguage = "en"
+
+//go:embed catalogs/*.toml
+var catalogFiles embed.FS
+
+var (
+	// catalogs are the messages of each language by error code, but of
+	// DefaultLanguage, whose messages are those of the errors.
+	catalogs = loadCatalogs()
+	matcher  = language.NewMatcher(supportedTags())
+)
+
+// loadCatalogs reads a catalog of each file of catalogs, named after its
+// language.
+func loadCatalogs() map[string]map[string]string {
+	files, err := catalogFiles.ReadDir("catalogs")
+	if err != nil {
+		panic(err)
+	}
+	loaded := map[string]map[string]string{}
+	for _, file := range files {
+		var catalog map[string]string
+		if _, err := toml.DecodeFS(catalogFiles, path.Join("catalogs", file.Name()), &catalog); err != nil {
+			panic(err)
+		}
+		loaded[strings.TrimSuffix(file.Name(), ".toml")] = catalog
+	}
+	return loaded
+}
+
+// supportedTags lists DefaultLanguage first, for the matcher to fall back to.
+func supportedTags() []language.Tag {
+	tags := []language.Tag{language.MustParse(DefaultLanguage)}
+	for lang := range catalogs {
+		tags = append(tags, language.MustParse(lang))
+	}
+	return tags
+}
+
+// Language returns the language acceptLanguage, an Accept-Language header,
+// prefers among those supported, e.g. he for he-IL.
+func Language(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	tag, _, _ := matcher.Match(tags...)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// Message returns the message of the error with code in lang, or message
+// when lang has none, like for DefaultLanguage.
+func Message(lang string, code string, message string) string {
+	if translated, ok := catalogs[lang][code]; ok {
+		return translated
+	}
+	return message
+}
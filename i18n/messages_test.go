@@ -0,0 +1,26 @@
+package i18n
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLanguage(t *testing.T) {
+	assert.Equal(t, "he", Language("he-IL,he;q=0.9,en;q=0.8"))
+	assert.Equal(t, "he", Language("fr, he;q=0.5"))
+	assert.Equal(t, "en", Language("en-US"))
+	assert.Equal(t, "en", Language("fr"))
+	assert.Equal(t, "en", Language(""))
+	assert.Equal(t, "en", Language("not a language;q=x"))
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "איש הקשר לא נמצא", Message("he", "CONTACT_NOT_FOUND", "contact not found"))
+	assert.Equal(t, "contact not found", Message("en", "CONTACT_NOT_FOUND", "contact not found"))
+	assert.Equal(t, "migrating, back at 10:00", Message("he", "MAINTENANCE", "migrating, back at 10:00"))
+	for lang, catalog := range catalogs {
+		for code, message := range catalog {
+			assert.NotEmpty(t, message, "%s message of %s", lang, code)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package logging keeps personal data out of the logs, masking phone numbers
+// and the values of the contact fields in every log line, and in the error
+// messages of the failures reported to clients.
+package logging
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Masked replaces the values of the redacted fields.
+const Masked = "[REDACTED]"
+
+var (
+	// phoneRegex matches runs of digits that may be phone numbers, optionally
+	// formatted with spaces, dashes and parentheses. Dots are left out, so IP
+	// addresses aren't taken for numbers.
+	phoneRegex = regexp.MustCompile(`\+?\b\d[\d ()-]{6,}\d\b`)
+	dateRegex  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+)
+
+// Redactor masks personal data in text: the numbers of 8 to 15 digits, but
+// their last two digits, and the values of the given fields, written as
+// field: value or field=value, quoted or not, like JSON, Mongo errors, Go
+// structs and query strings write them.
+type Redactor struct {
+	fields *regexp.Regexp
+}
+
+// NewRedactor returns a redactor masking the values of fields, matched case
+// insensitively.
+func NewRedactor(fields []string) *Redactor {
+	redactor := &Redactor{}
+	if len(fields) > 0 {
+		quoted := make([]string, len(fields))
+		for i, field := range fields {
+			quoted[i] = regexp.QuoteMeta(field)
+		}
+		redactor.fields = regexp.MustCompile(`(?i)("?\b(?:` + strings.Join(quoted, "|") + `)"?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|[^\s"&,;})\]]+)`)
+	}
+	return redactor
+}
+
+// Redact returns text with its personal data masked.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	if r.fields != nil {
+		text = r.fields.ReplaceAllStringFunc(text, func(match string) string {
+			groups := r.fields.FindStringSubmatch(match)
+			if strings.HasPrefix(groups[2], `"`) {
+				return groups[1] + `"` + Masked + `"`
+			}
+			return groups[1] + Masked
+		})
+	}
+	return phoneRegex.ReplaceAllStringFunc(text, maskPhone)
+}
+
+// maskPhone masks the digits of number but the last two, keeping its
+// formatting.
+func maskPhone(number string) string {
+	digits := 0
+	for _, c := range number {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits < 8 || digits > 15 || dateRegex.MatchString(number) {
+		return number
+	}
+	masked := []byte(number)
+	seen := 0
+	for i, c := range masked {
+		if c >= '0' && c <= '9' {
+			seen++
+			if seen <= digits-2 {
+				masked[i] = '*'
+			}
+		}
+	}
+	return string(masked)
+}
+
+// Formatter redacts the log lines written by another formatter.
+type Formatter struct {
+	logrus.Formatter
+	Redactor *Redactor
+}
+
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	line, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(f.Redactor.Redact(string(line))), nil
+}
+
+// Writer redacts what is written to another writer, a log line at a time as
+// the log package writes them.
+type Writer struct {
+	io.Writer
+	Redactor *Redactor
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := w.Writer.Write([]byte(w.Redactor.Redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+var current atomic.Pointer[Redactor]
+
+// Setup redacts the values of fields and the phone numbers from the logrus
+// and log package logs, and from the messages Redact is given, unless
+// disabled.
+func Setup(enabled bool, fields []string) {
+	if !enabled {
+		current.Store(nil)
+		return
+	}
+	redactor := NewRedactor(fields)
+	current.Store(redactor)
+	logrus.SetFormatter(&Formatter{Formatter: logrus.StandardLogger().Formatter, Redactor: redactor})
+	log.SetOutput(&Writer{Writer: os.Stderr, Redactor: redactor})
+}
+
+// Redact masks the personal data of an error message sent to a client, once
+// redaction is set up.
+func Redact(text string) string {
+	return current.Load().Redact(text)
+}
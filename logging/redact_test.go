@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	redactor := NewRedactor([]string{"firstName", "phone", "address"})
+
+	t.Run("should mask phone numbers but their last digits", func(t *testing.T) {
+		assert.Equal(t, "call ***-***-**67 back", redactor.Redact("call 052-123-4567 back"))
+		assert.Equal(t, "+*** ** *** **67", redactor.Redact("+972 52 123 4567"))
+	})
+
+	t.Run("should keep dates, addresses and short numbers", func(t *testing.T) {
+		for _, text := range []string{"backed up 1000 contacts", "at 2024-01-12T10:00:00Z", "from 192.168.100.200:8080", "id 65a1b2c3d4e5f60718293a4b"} {
+			assert.Equal(t, text, redactor.Redact(text))
+		}
+	})
+
+	t.Run("should mask the values of the fields", func(t *testing.T) {
+		assert.Equal(t, `{"firstName":"[REDACTED]","company":"Acme"}`, redactor.Redact(`{"firstName":"Dani","company":"Acme"}`))
+		assert.Equal(t, `dup key: { phone: "[REDACTED]" }`, redactor.Redact(`dup key: { phone: "ext-12" }`))
+		assert.Equal(t, `GET /contact/search?address=[REDACTED]&page=2`, redactor.Redact(`GET /contact/search?address=Tel%20Aviv&page=2`))
+		assert.Equal(t, `{FirstName:[REDACTED] LastName:Cohen}`, redactor.Redact(`{FirstName:Dani LastName:Cohen}`))
+		assert.Equal(t, `normalizedPhone=**********67`, redactor.Redact(`normalizedPhone=972521234567`))
+	})
+
+	t.Run("should redact the log lines", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&out)
+		logger.SetFormatter(&Formatter{Formatter: &logrus.TextFormatter{DisableTimestamp: true}, Redactor: redactor})
+		logger.WithField("phone", "ext-12").Error("invalid contact {FirstName:Dani Phone:0521234567}")
+		assert.Equal(t, "level=error msg=\"invalid contact {FirstName:[REDACTED] Phone:[REDACTED]}\" phone=[REDACTED]\n", out.String())
+	})
+}
@@ -1,27 +1,82 @@
+//go:generate swag init -g server/http.go
+
 package main
 
 import (
 	"context"
+	"flag"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"log"
 	"os"
 	"os/signal"
+	"phoneBook/backup"
+	"phoneBook/cache"
 	"phoneBook/config"
 	"phoneBook/core"
+	"phoneBook/crm"
 	"phoneBook/definition"
+	"phoneBook/directory"
+	"phoneBook/dynamodb"
+	"phoneBook/elastic"
+	"phoneBook/events"
+	"phoneBook/firestore"
+	"phoneBook/guard"
+	"phoneBook/health"
+	"phoneBook/logging"
+	"phoneBook/reminders"
+	"phoneBook/retention"
+	"phoneBook/search"
 	"phoneBook/server"
+	"phoneBook/tenancy"
 	"syscall"
 	"time"
 )
 
-var client *mongo.Client
+// app wires the phone book service together: its MongoDB client, trace
+// exporter and http server.
+type app struct {
+	cfg            config.Config
+	client         *mongo.Client
+	tracerProvider *sdktrace.TracerProvider
+	redisClient    *redis.Client
+	publisher      events.Publisher
+	emails         *events.EmailSink
+	crmSync        *crm.Sync
+	backups        *backup.Scheduler
+	reminders      *reminders.Scheduler
+	retention      *retention.Scheduler
+	directory      *directory.Server
+	// changeStream sends the changes of the contacts by any writer to the
+	// changes hub, nil unless CHANGE_STREAM_ENABLED.
+	changeStream *events.ChangeStream
+	// watchdog pings MongoDB and reconnects the phone book, nil when
+	// MONGO_WATCHDOG_INTERVAL is 0.
+	watchdog  *health.Watchdog
+	phoneBook *health.PhoneBook
+	// tenants provisions the tenants, nil unless TENANCY_ENABLED.
+	tenants *health.Tenants
+	// jobs records the admin jobs with MongoDB, nil with the other backends,
+	// whose replicas keep their jobs in memory.
+	jobs *health.Jobs
+	// searchIndexes mirror the contacts for the searches of each engine but
+	// the database, es with SEARCH_URL and embedded with
+	// EMBEDDED_SEARCH_ENABLED.
+	searchIndexes map[string]definition.ISearchIndex
+	server        *server.Server
+}
 
 func main() {
-	mongoClient := initDB()
-	phoneBook := initPhoneBook(mongoClient)
-
-	server.StartHTTP(&phoneBook)
+	cfg := initConfig()
+	service := newApp(cfg)
+	service.start()
 
 	// Handle OS signals for graceful shutdown
 	gracefulShutdown := make(chan os.Signal, 1)
@@ -29,37 +84,630 @@ func main() {
 
 	// Block until a signal is received
 	_ = <-gracefulShutdown
-	shutDown()
+	service.stop()
+}
+
+// newApp connects to the storage backend and builds the http server of the phone book
+// with cfg.
+func newApp(cfg config.Config) *app {
+	a := &app{cfg: cfg}
+	a.initTracing()
+	var phoneBook definition.IPhoneBook
+	switch cfg.StorageBackend {
+	case "dynamodb":
+		phoneBook = a.initDynamoPhoneBook()
+	case "firestore":
+		phoneBook = a.initFirestorePhoneBook()
+	default:
+		a.initDB()
+		phoneBook = a.initPhoneBook()
+		if cfg.MongoWatchdogInterval > 0 {
+			phoneBook = a.initWatchdog(phoneBook)
+		}
+	}
+	if cfg.SearchURL != "" {
+		phoneBook = a.initSearch(phoneBook)
+	}
+	if cfg.EmbeddedSearchEnabled {
+		phoneBook = a.initEmbeddedSearch(phoneBook)
+	}
+	changes := events.NewHub()
+	phoneBook = a.initEvents(phoneBook, changes)
+	if cfg.CacheEnabled {
+		phoneBook = a.initCache(phoneBook)
+	}
+	if cfg.DeleteGuardLimit > 0 {
+		phoneBook = a.initDeleteGuard(phoneBook)
+	}
+	if cfg.BackupInterval > 0 {
+		a.initBackups(phoneBook)
+	}
+	if cfg.ReminderWebhookURL != "" {
+		a.initReminders(phoneBook)
+	}
+	if cfg.RetentionDays > 0 {
+		a.initRetention(phoneBook)
+	}
+	if cfg.LDAPEnabled {
+		a.initDirectory(phoneBook)
+	}
+	a.server = server.NewServer(cfg, phoneBook, changes)
+	a.server.SetTunables(config.Tunables)
+	if a.watchdog != nil {
+		a.server.SetReadiness(a.watchdog.Ready)
+	}
+	if a.tenants != nil {
+		a.server.SetTenants(a.tenants)
+	}
+	if a.jobs != nil {
+		a.server.SetJobs(a.jobs)
+	}
+	if a.crmSync != nil {
+		a.server.SetCRMSync(a.crmSync)
+	}
+	for engine, index := range a.searchIndexes {
+		a.server.SetSearchIndex(engine, index)
+	}
+	return a
 }
 
-func shutDown() {
+func (a *app) start() {
+	if a.watchdog != nil {
+		a.watchdog.Start()
+	}
+	if a.backups != nil {
+		a.backups.Start()
+	}
+	if a.reminders != nil {
+		a.reminders.Start()
+	}
+	if a.retention != nil {
+		a.retention.Start()
+	}
+	if a.crmSync != nil {
+		a.crmSync.Start()
+	}
+	if a.changeStream != nil {
+		a.changeStream.Start()
+	}
+	if a.directory != nil {
+		if err := a.directory.Start(a.cfg.LDAPAddr); err != nil {
+			log.Fatal("Could not start the ldap server: ", err)
+		}
+	}
+	a.server.Start()
+}
+
+// initConfig loads the configuration from the file given by the -config flag
+// or the CONFIG_FILE environment variable, if any, and the environment. SIGHUP
+// reloads its tunable settings.
+func initConfig() config.Config {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML or TOML config file")
+	flag.Parse()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal("Could not load configuration: ", err)
+	}
+	config.Static = cfg
+	logging.Setup(cfg.LogRedaction, cfg.LogRedactFields)
+	config.SetTunables(cfg.Tunables())
+	config.ReloadOnSignal(*configFile)
+	return cfg
+}
+
+// stop tears the service down in order within SHUTDOWN_TIMEOUT: the http
+// server first, so in-flight requests finish their Mongo work, then the ldap
+// server, the backup and reminder schedulers, the change stream, Redis, the
+// events broker and email queue, the MongoDB watchdog, MongoDB and the trace exporter.
+func (a *app) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
+	defer cancel()
+
 	log.Println("Shutting down server...")
-	server.Shutdown()
+	if err := a.server.Stop(ctx); err != nil {
+		log.Println("Failed to drain in-flight requests:", err)
+	}
+
+	if a.directory != nil {
+		a.directory.Stop()
+	}
+	if a.backups != nil {
+		a.backups.Stop()
+	}
+	if a.reminders != nil {
+		a.reminders.Stop()
+	}
+	if a.retention != nil {
+		a.retention.Stop()
+	}
+	if a.changeStream != nil {
+		a.changeStream.Stop()
+	}
+	a.closeCache()
+	a.closeEvents()
+	if a.watchdog != nil {
+		a.watchdog.Stop()
+	}
 
 	log.Println("Disconnecting MongoDB client...")
-	disconnectDB()
+	a.disconnectDB(ctx)
+
+	a.shutdownTracing(ctx)
 
 	log.Println("Server gracefully stopped")
 }
 
-func initDB() *mongo.Client {
-	var err error
-	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
-	client, err = mongo.Connect(ctx, options.Client().ApplyURI(config.Static.MongoURI))
+// initDB connects to MongoDB, retrying with exponential backoff up to
+// MONGO_CONNECT_ATTEMPTS times, since MongoDB often starts slower than the API.
+func (a *app) initDB() {
+	// Connect doesn't reach the server, it only fails on invalid options
+	client, err := mongo.Connect(context.Background(), a.mongoOptions())
 	if err != nil {
 		log.Fatal(err)
 	}
-	return client
+	ping := func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	}
+	if err := a.pingWithRetry(ping, time.Sleep); err != nil {
+		log.Fatalf("Could not connect to MongoDB after %d attempts: %v", a.cfg.MongoConnectAttempts, err)
+	}
+	a.client = client
 }
 
-func disconnectDB() {
-	if client != nil {
-		if err := client.Disconnect(context.Background()); err != nil {
+// pingWithRetry calls ping until it succeeds, each call bounded by
+// MONGO_SERVER_SELECTION_TIMEOUT, sleeping the connectBackoff between the
+// attempts. It returns the error of the last attempt once
+// MONGO_CONNECT_ATTEMPTS failed.
+func (a *app) pingWithRetry(ping func(ctx context.Context) error, sleep func(time.Duration)) error {
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.MongoServerSelectionTimeout)
+		err := ping(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= a.cfg.MongoConnectAttempts {
+			return err
+		}
+		wait := connectBackoff(attempt, a.cfg.MongoConnectMaxBackoff)
+		log.Printf("Failed to connect to MongoDB (attempt %d of %d), retrying in %v: %v", attempt, a.cfg.MongoConnectAttempts, wait, err)
+		sleep(wait)
+	}
+}
+
+// mongoOptions returns the options of the MongoDB client: those of
+// MONGO_URI, then the configured ones over them. The pool bounds are only
+// set when configured, so those of the URI apply otherwise.
+func (a *app) mongoOptions() *options.ClientOptions {
+	clientOptions := options.Client().
+		ApplyURI(a.cfg.MongoURI).
+		SetServerSelectionTimeout(a.cfg.MongoServerSelectionTimeout).
+		SetRetryWrites(a.cfg.MongoRetryWrites).
+		SetMonitor(core.NewCommandMonitor())
+	if a.cfg.MongoMaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(a.cfg.MongoMaxPoolSize)
+	}
+	if a.cfg.MongoMinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(a.cfg.MongoMinPoolSize)
+	}
+	if a.cfg.MongoSocketTimeout > 0 {
+		clientOptions.SetSocketTimeout(a.cfg.MongoSocketTimeout)
+	}
+	return clientOptions
+}
+
+// connectBackoff returns how long to wait after the given failed attempt:
+// one second doubled on every attempt, up to maxBackoff.
+func connectBackoff(attempt int, maxBackoff time.Duration) time.Duration {
+	wait := time.Second
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+func (a *app) disconnectDB(ctx context.Context) {
+	if a.client != nil {
+		if err := a.client.Disconnect(ctx); err != nil {
 			log.Println("Failed to disconnect from MongoDB:", err)
 		}
 	}
 }
 
-func initPhoneBook(mongoClient *mongo.Client) definition.IPhoneBook {
-	return core.NewMongoPhoneBook(mongoClient)
+func (a *app) initPhoneBook() definition.IPhoneBook {
+	mongoPhoneBook := core.NewMongoPhoneBook(a.client)
+	phoneBook := a.scopeToTenants(mongoPhoneBook, a.client)
+	if a.cfg.TenancyEnabled {
+		a.tenants = health.NewTenants(core.NewMongoTenants(a.client))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := mongoPhoneBook.EnsureIndexes(ctx); err != nil {
+		log.Println("Failed to create contacts indexes:", err)
+	}
+	jobs := core.NewMongoJobs(a.client)
+	if err := jobs.EnsureIndexes(ctx); err != nil {
+		log.Println("Failed to create jobs indexes:", err)
+	}
+	a.jobs = health.NewJobs(jobs)
+	a.migrate(ctx, mongoPhoneBook)
+	if encrypted, err := mongoPhoneBook.EncryptFields(ctx); err != nil {
+		log.Println("Failed to encrypt the contacts stored before field encryption was enabled:", err)
+	} else if encrypted > 0 {
+		log.Printf("Encrypted the fields of %d contacts", encrypted)
+	}
+	return phoneBook
+}
+
+// migrate upgrades the contacts written at older schema versions, or with
+// MONGO_MIGRATE_ON_STARTUP off only warns about them.
+func (a *app) migrate(ctx context.Context, phoneBook *core.MongoPhoneBook) {
+	result, err := phoneBook.Migrate(ctx, !a.cfg.MongoMigrateOnStartup)
+	if err != nil {
+		log.Println("Failed to migrate the contacts:", err)
+		return
+	}
+	for _, step := range result.Migrations {
+		if result.DryRun {
+			log.Printf("%d contacts are waiting for migration %d (%s), POST /admin/migrate to apply it", step.Contacts, step.Version, step.Description)
+		} else {
+			log.Printf("Applied migration %d (%s) to %d contacts", step.Version, step.Description, step.Contacts)
+		}
+	}
+}
+
+// initDynamoPhoneBook keeps the contacts in DYNAMODB_TABLE, creating it
+// unless it exists.
+func (a *app) initDynamoPhoneBook() definition.IPhoneBook {
+	client := dynamodb.NewClient(a.cfg.DynamoDBEndpoint, a.cfg.AWSRegion, a.cfg.AWSAccessKeyID, a.cfg.AWSSecretAccessKey, a.cfg.AWSSessionToken)
+	phoneBook := core.NewDynamoPhoneBook(client, a.cfg.DynamoDBTable)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := phoneBook.EnsureTable(ctx); err != nil {
+		log.Println("Failed to create the contacts table:", err)
+	}
+	return phoneBook
+}
+
+// initFirestorePhoneBook keeps the contacts in the FIRESTORE_COLLECTION
+// collection of the FIRESTORE_DATABASE database of FIRESTORE_PROJECT_ID.
+func (a *app) initFirestorePhoneBook() definition.IPhoneBook {
+	client, err := firestore.NewClient(context.Background(), a.cfg.FirestoreProjectID, a.cfg.FirestoreDatabase, a.cfg.FirestoreCredentialsFile, a.cfg.FirestoreEmulatorHost)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return core.NewFirestorePhoneBook(client, a.cfg.FirestoreCollection)
+}
+
+// scopeToTenants returns phoneBook, or with TENANCY_ENABLED a phone book
+// serving each tenant from its own database of client, and phoneBook to the
+// calls of no tenant.
+func (a *app) scopeToTenants(phoneBook definition.IPhoneBook, client *mongo.Client) definition.IPhoneBook {
+	if !a.cfg.TenancyEnabled {
+		return phoneBook
+	}
+	return tenancy.NewPhoneBook(phoneBook, func(tenant string) definition.IPhoneBook {
+		return core.NewMongoPhoneBookIn(client, core.TenantDatabase(tenant))
+	})
+}
+
+// initWatchdog pings MongoDB every MONGO_WATCHDOG_INTERVAL, and after
+// MONGO_WATCHDOG_FAILURES failed pings in a row marks the service unready and
+// reconnects, swapping the phone book for one bound to the new client.
+func (a *app) initWatchdog(phoneBook definition.IPhoneBook) definition.IPhoneBook {
+	a.phoneBook = health.NewPhoneBook(phoneBook)
+	ping := func(ctx context.Context) error {
+		return a.client.Ping(ctx, nil)
+	}
+	a.watchdog = health.NewWatchdog(ping, a.reconnectDB, a.cfg.MongoWatchdogInterval, a.cfg.MongoServerSelectionTimeout,
+		a.cfg.MongoWatchdogFailures, a.cfg.MongoConnectMaxBackoff)
+	return a.phoneBook
+}
+
+// reconnectDB connects a new MongoDB client and swaps the phone book, the
+// tenants and the jobs for ones using it. The old client is disconnected once the queries still running on
+// it timed out.
+func (a *app) reconnectDB(ctx context.Context) error {
+	client, err := mongo.Connect(ctx, a.mongoOptions())
+	if err != nil {
+		return err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return err
+	}
+	a.phoneBook.Swap(a.scopeToTenants(core.NewMongoPhoneBook(client), client))
+	if a.tenants != nil {
+		a.tenants.Swap(core.NewMongoTenants(client))
+	}
+	a.jobs.Swap(core.NewMongoJobs(client))
+	old := a.client
+	a.client = client
+	time.AfterFunc(a.cfg.QueryTimeout, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
+		defer cancel()
+		if err := old.Disconnect(ctx); err != nil {
+			log.Println("Failed to disconnect the previous MongoDB client:", err)
+		}
+	})
+	return nil
+}
+
+// initSearch writes the mutations of phoneBook through to the SEARCH_INDEX
+// index of the Elasticsearch or OpenSearch cluster at SEARCH_URL, filling
+// the index from phoneBook when it's created.
+func (a *app) initSearch(phoneBook definition.IPhoneBook) definition.IPhoneBook {
+	client := elastic.NewClient(a.cfg.SearchURL, a.cfg.SearchUsername, a.cfg.SearchPassword)
+	index := core.NewElasticIndex(client, a.cfg.SearchIndex)
+	mirrored := a.addSearchIndex("es", phoneBook, index)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	created, err := index.EnsureIndex(ctx)
+	if err != nil {
+		log.Println("Failed to create the search index:", err)
+	}
+	if created {
+		mirrored.RebuildInBackground(context.Background())
+	}
+	return mirrored
+}
+
+// initEmbeddedSearch writes the mutations of phoneBook through to an index
+// kept in memory, built from phoneBook on startup, with the phone book of
+// every tenant.
+func (a *app) initEmbeddedSearch(phoneBook definition.IPhoneBook) definition.IPhoneBook {
+	mirrored := a.addSearchIndex("embedded", phoneBook, core.NewEmbeddedIndex())
+	mirrored.RebuildInBackground(context.Background())
+	if a.tenants != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		tenants, _, err := a.tenants.ListTenants(ctx)
+		if err != nil {
+			log.Println("Failed to list the tenants to index, index them with POST /admin/search/reindex:", err)
+		}
+		for _, tenant := range tenants {
+			mirrored.RebuildInBackground(definition.WithTenant(context.Background(), tenant.ID))
+		}
+	}
+	return mirrored
+}
+
+// addSearchIndex mirrors phoneBook to index, serving the searches of engine
+// from it.
+func (a *app) addSearchIndex(engine string, phoneBook definition.IPhoneBook, index search.Index) *search.PhoneBook {
+	mirrored := search.NewPhoneBook(phoneBook, index)
+	if a.searchIndexes == nil {
+		a.searchIndexes = map[string]definition.ISearchIndex{}
+	}
+	a.searchIndexes[engine] = mirrored
+	return mirrored
+}
+
+// initEvents wraps phoneBook with a publisher of an event per mutation to
+// the changes hub, to EVENTS_TOPIC of the configured broker, if any, by
+// email to EMAIL_TO when an SMTP server is configured and to the CRM of
+// CRM_PROVIDER. With CHANGE_STREAM_ENABLED the changes hub gets the changes
+// of the MongoDB change stream instead, made by any writer.
+func (a *app) initEvents(phoneBook definition.IPhoneBook, changes *events.Hub) definition.IPhoneBook {
+	var sinks []events.Sink
+	if a.cfg.ChangeStreamEnabled {
+		a.initChangeStream(changes)
+	} else {
+		sinks = append(sinks, changes)
+	}
+	if a.cfg.EventsBackend != "" {
+		encode, err := events.NewEncoder(a.cfg.EventsFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a.publisher, err = events.NewPublisher(a.cfg.EventsBackend, a.cfg.EventsURL)
+		if err != nil {
+			log.Fatal("Could not connect to the events broker: ", err)
+		}
+		sinks = append(sinks, events.NewBrokerSink(a.publisher, encode, a.cfg.EventsTopic, a.cfg.QueryTimeout))
+	}
+	if a.cfg.SMTPHost != "" {
+		subject, body := a.cfg.EmailSubjectTemplate, a.cfg.EmailBodyTemplate
+		if subject == "" {
+			subject = events.DefaultEmailSubject
+		}
+		if body == "" {
+			body = events.DefaultEmailBody
+		}
+		mailer := events.NewSMTPMailer(a.cfg.SMTPHost, a.cfg.SMTPPort, a.cfg.SMTPUsername, a.cfg.SMTPPassword)
+		var err error
+		a.emails, err = events.NewEmailSink(mailer, a.cfg.EmailFrom, a.cfg.EmailTo, a.cfg.EmailEvents, subject, body, a.cfg.EmailQueueSize)
+		if err != nil {
+			log.Fatal("Invalid email configuration: ", err)
+		}
+		sinks = append(sinks, a.emails)
+	}
+	if a.cfg.CRMProvider != "" {
+		a.initCRMSync(phoneBook)
+		sinks = append(sinks, a.crmSync)
+	}
+	return events.NewPhoneBook(phoneBook, sinks...)
+}
+
+// initChangeStream tails the changes of the contacts, of every tenant with
+// TENANCY_ENABLED, sending them to the changes hub. Every replica tails them,
+// so the broker, email and CRM sinks keep getting the mutations of the API
+// only, once.
+func (a *app) initChangeStream(changes *events.Hub) {
+	watch := func(ctx context.Context, resumeToken []byte, handle func(change *definition.StreamedChange)) error {
+		return core.WatchContacts(ctx, a.client, a.cfg.TenancyEnabled, resumeToken, handle)
+	}
+	a.changeStream = events.NewChangeStream(watch, a.cfg.ChangeStreamRetryInterval, changes)
+}
+
+// initCRMSync pushes the changes of the contacts of phoneBook to the CRM of
+// CRM_PROVIDER, mapping the fields by CRM_FIELD_MAPPING or the defaults of
+// the CRM.
+func (a *app) initCRMSync(phoneBook definition.IPhoneBook) {
+	mapping, err := config.ParseCRMFieldMapping(a.cfg.CRMFieldMapping)
+	if err != nil {
+		log.Fatal("Invalid CRM_FIELD_MAPPING: ", err)
+	}
+	if len(mapping) == 0 {
+		mapping = crm.DefaultFieldMappings[a.cfg.CRMProvider]
+	}
+	idProperty := a.cfg.CRMIDProperty
+	if idProperty == "" {
+		idProperty = crm.DefaultIDProperties[a.cfg.CRMProvider]
+	}
+	var connector crm.Connector
+	switch a.cfg.CRMProvider {
+	case crm.ProviderSalesforce:
+		connector = crm.NewSalesforce(a.cfg.CRMURL, a.cfg.CRMClientID, a.cfg.CRMClientSecret, idProperty)
+	default:
+		baseURL := a.cfg.CRMURL
+		if baseURL == "" {
+			baseURL = crm.DefaultHubSpotURL
+		}
+		connector = crm.NewHubSpot(baseURL, a.cfg.CRMToken, idProperty)
+	}
+	a.crmSync = crm.NewSync(phoneBook, connector, crm.Options{
+		Mapping:           mapping,
+		RequestsPerSecond: a.cfg.CRMRequestsPerSecond,
+		QueueSize:         a.cfg.CRMQueueSize,
+		MaxAttempts:       a.cfg.CRMMaxAttempts,
+		Backoff:           a.cfg.CRMRetryBackoff,
+		Timeout:           a.cfg.QueryTimeout,
+		MaxDeadLetters:    a.cfg.CRMMaxDeadLetters,
+	})
+}
+
+func (a *app) closeEvents() {
+	if a.emails != nil {
+		a.emails.Close()
+	}
+	if a.crmSync != nil {
+		a.crmSync.Stop()
+	}
+	if a.publisher != nil {
+		if err := a.publisher.Close(); err != nil {
+			log.Println("Failed to flush contact events:", err)
+		}
+	}
+}
+
+// initCache wraps phoneBook with the configured cache. With REDIS_URI set the
+// replicas also evict the entries each other's mutations change.
+func (a *app) initCache(phoneBook definition.IPhoneBook) definition.IPhoneBook {
+	if a.cfg.RedisURI != "" {
+		redisOptions, err := redis.ParseURL(a.cfg.RedisURI)
+		if err != nil {
+			log.Fatal("Invalid REDIS_URI: ", err)
+		}
+		a.redisClient = redis.NewClient(redisOptions)
+	}
+	store := cache.NewMemoryStore(a.cfg.CacheSize, a.cfg.CacheTTL)
+	if a.cfg.CacheBackend == "redis" {
+		store = cache.NewRedisStore(a.redisClient, a.cfg.CacheTTL)
+	}
+	cached := cache.NewPhoneBook(phoneBook, store)
+	if a.redisClient != nil {
+		if err := cached.ShareInvalidations(context.Background(), a.redisClient); err != nil {
+			log.Println("Failed to subscribe to cache invalidations, other replicas' changes may be served stale until the cache TTL:", err)
+		}
+	}
+	return cached
+}
+
+func (a *app) closeCache() {
+	if a.redisClient != nil {
+		if err := a.redisClient.Close(); err != nil {
+			log.Println("Failed to close Redis client:", err)
+		}
+	}
+}
+
+// initBackups schedules backups of the contacts, of every tenant, to the
+// configured S3 bucket every BACKUP_INTERVAL.
+func (a *app) initBackups(phoneBook definition.IPhoneBook) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	storage, err := backup.NewS3Storage(ctx, a.cfg.BackupS3Endpoint, a.cfg.BackupS3Region, a.cfg.BackupS3Bucket,
+		a.cfg.BackupS3AccessKey, a.cfg.BackupS3SecretKey, a.cfg.BackupS3Insecure)
+	if err != nil {
+		log.Fatal("Could not reach the backups bucket: ", err)
+	}
+	var tenants backup.Tenants
+	if a.tenants != nil {
+		tenants = a.tenants
+	}
+	a.backups = backup.NewScheduler(phoneBook, storage, tenants, a.cfg.BackupInterval, a.cfg.BackupTimeout,
+		a.cfg.BackupFormat, a.cfg.BackupPrefix, a.cfg.BackupKeep, a.cfg.BackupMaxAge)
+}
+
+// initDeleteGuard blocks the deletes of the clients deleting over
+// DELETE_GUARD_LIMIT contacts a minute for DELETE_GUARD_BLOCK, alerting
+// DELETE_GUARD_WEBHOOK_URL, if set, when it does.
+func (a *app) initDeleteGuard(phoneBook definition.IPhoneBook) definition.IPhoneBook {
+	var alerter guard.Alerter
+	if a.cfg.DeleteGuardWebhookURL != "" {
+		alerter = guard.NewWebhookAlerter(a.cfg.DeleteGuardWebhookURL)
+	}
+	return guard.NewPhoneBook(phoneBook, a.cfg.DeleteGuardLimit, a.cfg.DeleteGuardBlock, alerter)
+}
+
+// initReminders posts the due reminders of every tenant to
+// REMINDER_WEBHOOK_URL, looking for them every REMINDER_INTERVAL.
+func (a *app) initReminders(phoneBook definition.IPhoneBook) {
+	notifier := reminders.NewWebhookNotifier(a.cfg.ReminderWebhookURL)
+	var tenants reminders.Tenants
+	if a.tenants != nil {
+		tenants = a.tenants
+	}
+	a.reminders = reminders.NewScheduler(phoneBook, notifier, tenants, a.cfg.ReminderInterval, a.cfg.ReminderInterval)
+}
+
+// initRetention applies the RETENTION_ACTION policy to the contacts not
+// updated for RETENTION_DAYS, of every tenant, every RETENTION_INTERVAL.
+func (a *app) initRetention(phoneBook definition.IPhoneBook) {
+	policy := definition.RetentionPolicy{Action: a.cfg.RetentionAction, Days: a.cfg.RetentionDays}
+	var tenants retention.Tenants
+	if a.tenants != nil {
+		tenants = a.tenants
+	}
+	a.retention = retention.NewScheduler(phoneBook, policy, tenants, a.cfg.RetentionInterval, a.cfg.RetentionInterval)
+}
+
+// initDirectory serves the contacts over LDAP, read-only.
+func (a *app) initDirectory(phoneBook definition.IPhoneBook) {
+	directoryServer, err := directory.NewServer(phoneBook, a.cfg.LDAPBaseDN, a.cfg.LDAPBindDN, a.cfg.LDAPBindPassword, int(a.cfg.MaxPageSize))
+	if err != nil {
+		log.Fatal("Invalid ldap configuration: ", err)
+	}
+	a.directory = directoryServer
+}
+
+// initTracing exports the spans of requests and Mongo commands to the OTLP
+// endpoint when tracing is enabled. Otherwise the global tracer drops them.
+func (a *app) initTracing() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !a.cfg.TracingEnabled {
+		return
+	}
+	exporterOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(a.cfg.OTLPEndpoint)}
+	if a.cfg.OTLPInsecure {
+		exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), exporterOptions...)
+	if err != nil {
+		log.Fatal("Failed to create trace exporter: ", err)
+	}
+	a.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("phoneBook"))),
+	)
+	otel.SetTracerProvider(a.tracerProvider)
+}
+
+func (a *app) shutdownTracing(ctx context.Context) {
+	if a.tracerProvider != nil {
+		if err := a.tracerProvider.Shutdown(ctx); err != nil {
+			log.Println("Failed to flush traces:", err)
+		}
+	}
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net"
+	"net/http"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"phoneBook/server"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnectBackoff(t *testing.T) {
+	var waits []time.Duration
+	for attempt := 1; attempt <= 7; attempt++ {
+		waits = append(waits, connectBackoff(attempt, 30*time.Second))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		16 * time.Second, 30 * time.Second, 30 * time.Second}, waits)
+}
+
+func TestPingWithRetry(t *testing.T) {
+	cfg := config.Default()
+	cfg.MongoConnectAttempts = 4
+	cfg.MongoConnectMaxBackoff = 3 * time.Second
+	a := &app{cfg: cfg}
+	unreachable := errors.New("server selection timeout")
+
+	t.Run("should retry until the ping succeeds", func(t *testing.T) {
+		var waits []time.Duration
+		pings := 0
+		err := a.pingWithRetry(func(ctx context.Context) error {
+			pings++
+			if pings < 3 {
+				return unreachable
+			}
+			return nil
+		}, func(wait time.Duration) { waits = append(waits, wait) })
+		assert.Nil(t, err)
+		assert.Equal(t, 3, pings)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits)
+	})
+
+	t.Run("should give up after the configured attempts", func(t *testing.T) {
+		var waits []time.Duration
+		pings := 0
+		err := a.pingWithRetry(func(ctx context.Context) error {
+			pings++
+			return unreachable
+		}, func(wait time.Duration) { waits = append(waits, wait) })
+		assert.ErrorIs(t, err, unreachable)
+		assert.Equal(t, 4, pings)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, waits)
+	})
+
+	t.Run("should bound every ping by the server selection timeout", func(t *testing.T) {
+		err := a.pingWithRetry(func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(cfg.MongoServerSelectionTimeout), deadline, time.Second)
+			return nil
+		}, func(time.Duration) {})
+		assert.Nil(t, err)
+	})
+}
+
+func TestMongoOptions(t *testing.T) {
+	t.Run("should keep the pool size of the uri", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.MongoURI = "mongodb://mongo:27017/?maxPoolSize=7&minPoolSize=2"
+		clientOptions := (&app{cfg: cfg}).mongoOptions()
+		assert.Equal(t, uint64(7), *clientOptions.MaxPoolSize)
+		assert.Equal(t, uint64(2), *clientOptions.MinPoolSize)
+	})
+
+	t.Run("should set the configured pool size over the uri", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.MongoURI = "mongodb://mongo:27017/?maxPoolSize=7"
+		cfg.MongoMaxPoolSize = 50
+		clientOptions := (&app{cfg: cfg}).mongoOptions()
+		assert.Equal(t, uint64(50), *clientOptions.MaxPoolSize)
+	})
+
+	t.Run("should leave the driver default without pool size", func(t *testing.T) {
+		clientOptions := (&app{cfg: config.Default()}).mongoOptions()
+		assert.Nil(t, clientOptions.MaxPoolSize)
+		assert.Nil(t, clientOptions.MinPoolSize)
+	})
+}
+
+// slowPhoneBook answers GetContact after delay, recording when it is done.
+type slowPhoneBook struct {
+	definition.IPhoneBook
+	delay   time.Duration
+	started chan struct{}
+	record  func(step string)
+}
+
+func (p *slowPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	close(p.started)
+	time.Sleep(p.delay)
+	p.record("request")
+	return &definition.Contact{FirstName: "Dani", Version: 1}, "", nil
+}
+
+// recordedConn records when it is closed.
+type recordedConn struct {
+	net.Conn
+	once   sync.Once
+	record func(step string)
+}
+
+func (c *recordedConn) Close() error {
+	c.once.Do(func() { c.record("redis") })
+	return c.Conn.Close()
+}
+
+// closingPublisher records when it is closed.
+type closingPublisher struct {
+	record func(step string)
+}
+
+func (p *closingPublisher) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	return nil
+}
+
+func (p *closingPublisher) Close() error {
+	p.record("events")
+	return nil
+}
+
+func TestAppStop(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	cfg := config.Default()
+	cfg.HTTPServerPort = addr
+	cfg.ShutdownTimeout = 5 * time.Second
+
+	var mu sync.Mutex
+	var steps []string
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisServer.Addr(),
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &recordedConn{Conn: conn, record: record}, nil
+		},
+	})
+	assert.Nil(t, redisClient.Ping(context.Background()).Err())
+	// the client connects lazily, so no MongoDB is needed until it is used
+	mongoClient, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetPoolMonitor(&event.PoolMonitor{Event: func(poolEvent *event.PoolEvent) {
+			if poolEvent.Type == event.PoolClosedEvent {
+				record("mongo")
+			}
+		}}))
+	assert.Nil(t, err)
+	phoneBook := &slowPhoneBook{delay: 300 * time.Millisecond, started: make(chan struct{}), record: record}
+	a := &app{
+		cfg:         cfg,
+		client:      mongoClient,
+		redisClient: redisClient,
+		publisher:   &closingPublisher{record: record},
+		server:      server.NewServer(cfg, phoneBook, events.NewHub()),
+	}
+	a.start()
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	responses := make(chan int, 1)
+	go func() {
+		response, err := http.Get("http://" + addr + "/api/v1/contact/1")
+		if err != nil {
+			responses <- 0
+			return
+		}
+		response.Body.Close()
+		responses <- response.StatusCode
+	}()
+	<-phoneBook.started
+	a.stop()
+
+	assert.Equal(t, http.StatusOK, <-responses, "the in-flight request should finish")
+	assert.Equal(t, []string{"request", "redis", "events", "mongo"}, steps)
+	assert.ErrorIs(t, redisClient.Ping(context.Background()).Err(), redis.ErrClosed)
+	assert.ErrorIs(t, mongoClient.Disconnect(context.Background()), mongo.ErrClientDisconnected)
+	_, err = http.Get("http://" + addr + "/api/v1/contact/1")
+	assert.NotNil(t, err, "the server should not accept requests anymore")
+}
@@ -0,0 +1,149 @@
+package phonenumber
+
+import "regexp"
+
+// format lays out the national numbers matched by pattern, grouping the
+// digits it captures like libphonenumber does.
+type format struct {
+	pattern *regexp.Regexp
+	// national is the layout within the region, trunk prefix included, and
+	// international the layout following the calling code.
+	national, international string
+}
+
+// numberType is the type of the national numbers matched by pattern.
+type numberType struct {
+	pattern *regexp.Regexp
+	name    string
+}
+
+// metadata is what is known of the numbers of a region.
+type metadata struct {
+	callingCode string
+	// leadingDigits match the national numbers of the region, its area
+	// codes, when its calling code is shared with other regions. The main
+	// region of the calling code has none.
+	leadingDigits *regexp.Regexp
+	// formats are tried in order, a national number matching none of them
+	// isn't a number of the region.
+	formats []format
+	// types are tried in order, the numbers matching none of them are of
+	// TypeOther.
+	types []numberType
+	// areas are the geographic areas of the fixed line numbers, by the
+	// leading digits of their national number.
+	areas map[string]string
+}
+
+// nanpFormats and nanpTypes are the formats and types of the North American
+// Numbering Plan regions, which don't tell the mobile numbers apart.
+var (
+	nanpFormats = []format{
+		{regexp.MustCompile(`^([2-9]\d{2})([2-9]\d{2})(\d{4})$`), "($1) $2-$3", "$1-$2-$3"},
+	}
+	nanpTypes = []numberType{
+		{regexp.MustCompile(`^8(00|33|44|55|66|77|88)`), TypeTollFree},
+		{regexp.MustCompile(`^900`), TypePremium},
+		{regexp.MustCompile(`^`), TypeFixedOrMobile},
+	}
+)
+
+// regions are the regions known, by ISO 3166-1 alpha-2 code. Their metadata
+// is a subset of the libphonenumber one, the formats, types and areas of the
+// numbers of the regions the phone book is mostly used in.
+var regions = map[string]*metadata{
+	"IL": {
+		callingCode: "972",
+		formats: []format{
+			{regexp.MustCompile(`^([57]\d)(\d{3})(\d{4})$`), "0$1-$2-$3", "$1-$2-$3"},
+			{regexp.MustCompile(`^([2-489])(\d{3})(\d{4})$`), "0$1-$2-$3", "$1-$2-$3"},
+			{regexp.MustCompile(`^(1)([78]00)(\d{3})(\d{3})$`), "$1-$2-$3-$4", "$1-$2-$3-$4"},
+		},
+		types: []numberType{
+			{regexp.MustCompile(`^5`), TypeMobile},
+			{regexp.MustCompile(`^7`), TypeVoIP},
+			{regexp.MustCompile(`^[2-489]`), TypeFixed},
+			{regexp.MustCompile(`^1800`), TypeTollFree},
+			{regexp.MustCompile(`^1700`), TypeSharedCost},
+		},
+		areas: map[string]string{
+			"2": "Jerusalem",
+			"3": "Tel Aviv",
+			"4": "Haifa and the North",
+			"8": "The South",
+			"9": "Hasharon",
+		},
+	},
+	"US": {callingCode: "1", formats: nanpFormats, types: nanpTypes},
+	"CA": {
+		callingCode:   "1",
+		leadingDigits: regexp.MustCompile(`^(204|226|236|249|250|257|263|289|306|343|354|365|367|368|382|403|416|418|428|431|437|438|450|468|474|506|514|519|548|579|581|584|587|604|613|639|647|672|683|705|709|742|753|778|780|782|807|819|825|867|873|879|902|905)`),
+		formats:       nanpFormats,
+		types:         nanpTypes,
+	},
+	"GB": {
+		callingCode: "44",
+		formats: []format{
+			{regexp.MustCompile(`^(7\d{3})(\d{6})$`), "0$1 $2", "$1 $2"},
+			{regexp.MustCompile(`^(2\d)(\d{4})(\d{4})$`), "0$1 $2 $3", "$1 $2 $3"},
+			{regexp.MustCompile(`^(1\d{3})(\d{5,6})$`), "0$1 $2", "$1 $2"},
+			{regexp.MustCompile(`^([389]\d{2})(\d{3})(\d{4})$`), "0$1 $2 $3", "$1 $2 $3"},
+		},
+		types: []numberType{
+			{regexp.MustCompile(`^7[1-57-9]`), TypeMobile},
+			{regexp.MustCompile(`^[12]`), TypeFixed},
+			{regexp.MustCompile(`^80[08]`), TypeTollFree},
+			{regexp.MustCompile(`^8[47]`), TypeSharedCost},
+			{regexp.MustCompile(`^9`), TypePremium},
+		},
+		areas: map[string]string{
+			"20":  "London",
+			"23":  "Southampton and Portsmouth",
+			"24":  "Coventry",
+			"28":  "Northern Ireland",
+			"29":  "Cardiff",
+			"113": "Leeds",
+			"114": "Sheffield",
+			"115": "Nottingham",
+			"116": "Leicester",
+			"117": "Bristol",
+			"118": "Reading",
+			"121": "Birmingham",
+			"131": "Edinburgh",
+			"141": "Glasgow",
+			"151": "Liverpool",
+			"161": "Manchester",
+			"191": "Tyneside",
+		},
+	},
+	"FR": {
+		callingCode: "33",
+		formats: []format{
+			{regexp.MustCompile(`^([1-9])(\d{2})(\d{2})(\d{2})(\d{2})$`), "0$1 $2 $3 $4 $5", "$1 $2 $3 $4 $5"},
+		},
+		types: []numberType{
+			{regexp.MustCompile(`^[67]`), TypeMobile},
+			{regexp.MustCompile(`^[1-5]`), TypeFixed},
+			{regexp.MustCompile(`^80`), TypeTollFree},
+			{regexp.MustCompile(`^8[1-4]`), TypeSharedCost},
+			{regexp.MustCompile(`^89`), TypePremium},
+			{regexp.MustCompile(`^9`), TypeVoIP},
+		},
+		areas: map[string]string{
+			"1": "Île-de-France",
+			"2": "Northwest France",
+			"3": "Northeast France",
+			"4": "Southeast France",
+			"5": "Southwest France",
+		},
+	},
+}
+
+// callingCodes are the regions of each calling code, the regions matched by
+// their leading digits first.
+var callingCodes = map[string][]string{
+	"972": {"IL"},
+	"1":   {"CA", "US"},
+	"44":  {"GB"},
+	"33":  {"FR"},
+}
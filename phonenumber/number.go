@@ -0,0 +1,120 @@
+// Package phonenumber parses phone numbers in their E.164 form, tells their
+// region, type and area, and formats them for display, nationally within
+// their region and internationally outside of it, from embedded metadata so
+// no service is called.
+package phonenumber
+
+import (
+	"sort"
+	"strings"
+)
+
+// The types of the numbers.
+const (
+	TypeMobile = "mobile"
+	TypeFixed  = "fixed"
+	// TypeFixedOrMobile is the type of the numbers of the regions whose
+	// mobile and fixed line numbers share their ranges, like the US.
+	TypeFixedOrMobile = "fixedOrMobile"
+	TypeTollFree      = "tollFree"
+	TypeSharedCost    = "sharedCost"
+	TypePremium       = "premium"
+	TypeVoIP          = "voip"
+	TypeOther         = "other"
+)
+
+// Number is a phone number of a known region.
+type Number struct {
+	// Region is the ISO 3166-1 alpha-2 code of the region of the number.
+	Region string
+	// CallingCode is the country calling code, without its + prefix.
+	CallingCode string
+	// National is the national significant number, without the calling code
+	// and the trunk prefix.
+	National string
+	// Type is the type of the number, one of the Type constants.
+	Type string
+	// Area is the geographic area of a fixed line number, empty when
+	// unknown.
+	Area   string
+	format format
+}
+
+// Parse reads the digits of a number in E.164 form, without the + prefix,
+// e.g. 972521234567. It reports false when the number isn't a valid number
+// of a known region.
+func Parse(digits string) (*Number, bool) {
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return nil, false
+	}
+	for length := 1; length <= 3 && length < len(digits); length++ {
+		callingCode, national := digits[:length], digits[length:]
+		for _, region := range callingCodes[callingCode] {
+			data := regions[region]
+			if data.leadingDigits != nil && !data.leadingDigits.MatchString(national) {
+				continue
+			}
+			for _, format := range data.formats {
+				if format.pattern.MatchString(national) {
+					number := &Number{Region: region, CallingCode: callingCode, National: national, format: format}
+					number.Type, number.Area = data.classify(national)
+					return number, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// classify returns the type of the national number of the region, and its
+// area when a fixed line, the longest leading digits known telling it.
+func (data *metadata) classify(national string) (string, string) {
+	kind := TypeOther
+	for _, t := range data.types {
+		if t.pattern.MatchString(national) {
+			kind = t.name
+			break
+		}
+	}
+	if kind != TypeFixed {
+		return kind, ""
+	}
+	for length := min(len(national), 4); length > 0; length-- {
+		if area, ok := data.areas[national[:length]]; ok {
+			return kind, area
+		}
+	}
+	return kind, ""
+}
+
+// E164 returns the number in E.164 form, e.g. +972521234567.
+func (n *Number) E164() string {
+	return "+" + n.CallingCode + n.National
+}
+
+// Format returns the number as displayed in region: in the national format
+// when dialed from a region sharing its calling code, e.g. 052-123-4567, and
+// in the international format otherwise, e.g. +972 52-123-4567.
+func (n *Number) Format(region string) string {
+	if data, ok := regions[region]; ok && data.callingCode == n.CallingCode {
+		return n.format.pattern.ReplaceAllString(n.National, n.format.national)
+	}
+	return "+" + n.CallingCode + " " + n.format.pattern.ReplaceAllString(n.National, n.format.international)
+}
+
+// KnownRegion reports whether numbers can be formatted for region, an ISO
+// 3166-1 alpha-2 code in upper case.
+func KnownRegion(region string) bool {
+	_, ok := regions[region]
+	return ok
+}
+
+// Regions returns the known regions, sorted.
+func Regions() []string {
+	codes := make([]string, 0, len(regions))
+	for region := range regions {
+		codes = append(codes, region)
+	}
+	sort.Strings(codes)
+	return codes
+}
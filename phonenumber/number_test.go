@@ -0,0 +1,79 @@
+package phonenumber
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		digits, region, e164, national, international string
+	}{
+		{"972521234567", "IL", "+972521234567", "052-123-4567", "+972 52-123-4567"},
+		{"97221234567", "IL", "+97221234567", "02-123-4567", "+972 2-123-4567"},
+		{"9721800123456", "IL", "+9721800123456", "1-800-123-456", "+972 1-800-123-456"},
+		{"12015550123", "US", "+12015550123", "(201) 555-0123", "+1 201-555-0123"},
+		{"14165550123", "CA", "+14165550123", "(416) 555-0123", "+1 416-555-0123"},
+		{"447400123456", "GB", "+447400123456", "07400 123456", "+44 7400 123456"},
+		{"442079460958", "GB", "+442079460958", "020 7946 0958", "+44 20 7946 0958"},
+		{"33123456789", "FR", "+33123456789", "01 23 45 67 89", "+33 1 23 45 67 89"},
+	}
+	for _, c := range cases {
+		number, ok := Parse(c.digits)
+		if !assert.True(t, ok, c.digits) {
+			continue
+		}
+		assert.Equal(t, c.region, number.Region)
+		assert.Equal(t, c.e164, number.E164())
+		assert.Equal(t, c.national, number.Format(c.region))
+		international := "IL"
+		if c.region == "IL" {
+			international = "FR"
+		}
+		assert.Equal(t, c.international, number.Format(international))
+	}
+
+	number, _ := Parse("12015550123")
+	assert.Equal(t, "(201) 555-0123", number.Format("CA"), "the regions sharing a calling code dial each other nationally")
+
+	for _, digits := range []string{"", "123", "97252123", "4912345678901", "+972521234567", "9720521234567"} {
+		_, ok := Parse(digits)
+		assert.False(t, ok, digits)
+	}
+}
+
+func TestKnownRegion(t *testing.T) {
+	assert.True(t, KnownRegion("IL"))
+	assert.False(t, KnownRegion("il"))
+	assert.False(t, KnownRegion("ZZ"))
+	assert.Equal(t, []string{"CA", "FR", "GB", "IL", "US"}, Regions())
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		digits, kind, area string
+	}{
+		{"972521234567", TypeMobile, ""},
+		{"97221234567", TypeFixed, "Jerusalem"},
+		{"97239876543", TypeFixed, "Tel Aviv"},
+		{"972721234567", TypeVoIP, ""},
+		{"9721800123456", TypeTollFree, ""},
+		{"12015550123", TypeFixedOrMobile, ""},
+		{"18005550123", TypeTollFree, ""},
+		{"447400123456", TypeMobile, ""},
+		{"442079460958", TypeFixed, "London"},
+		{"441614960000", TypeFixed, "Manchester"},
+		{"441632960123", TypeFixed, ""},
+		{"33612345678", TypeMobile, ""},
+		{"33123456789", TypeFixed, "Île-de-France"},
+		{"33800123456", TypeTollFree, ""},
+	}
+	for _, c := range cases {
+		number, ok := Parse(c.digits)
+		if !assert.True(t, ok, c.digits) {
+			continue
+		}
+		assert.Equal(t, c.kind, number.Type, c.digits)
+		assert.Equal(t, c.area, number.Area, c.digits)
+	}
+}
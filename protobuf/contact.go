@@ -0,0 +1,257 @@
+// Package protobuf encodes the contacts in the protobuf wire format of
+// phonebook.proto, for the internal callers reading contacts in bulk. The
+// messages are few and flat, so they are written and read with protowire
+// directly rather than with generated code.
+package protobuf
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/encoding/protowire"
+	"phoneBook/definition"
+	"time"
+)
+
+// ContentType is the media type of the protobuf request and response bodies.
+const ContentType = "application/x-protobuf"
+
+// MarshalContact encodes contact as a Contact message.
+func MarshalContact(contact *definition.Contact) []byte {
+	return appendContact(nil, contact)
+}
+
+// MarshalContacts encodes contacts as a ContactList message.
+func MarshalContacts(contacts []*definition.Contact) []byte {
+	var b []byte
+	for _, contact := range contacts {
+		b = appendMessage(b, 1, appendContact(nil, contact))
+	}
+	return b
+}
+
+// MarshalContactPage encodes page as a ContactPage message.
+func MarshalContactPage(page *definition.ContactPage) []byte {
+	var b []byte
+	for _, contact := range page.Items {
+		b = appendMessage(b, 1, appendContact(nil, contact))
+	}
+	b = appendVarint(b, 2, uint64(page.Page))
+	b = appendVarint(b, 3, uint64(page.PageSize))
+	if page.TotalItems != nil {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*page.TotalItems))
+	}
+	if page.TotalPages != nil {
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*page.TotalPages))
+	}
+	return appendString(b, 6, page.NextCursor)
+}
+
+// UnmarshalContact decodes a Contact message. Unknown fields are skipped, as
+// protobuf parsers do, and the derived fields are read like the JSON ones,
+// for the phone book to ignore.
+func UnmarshalContact(b []byte) (*definition.Contact, error) {
+	contact := &definition.Contact{}
+	fields := map[protowire.Number]*string{
+		2: &contact.FirstName, 3: &contact.LastName, 4: &contact.Phone, 5: &contact.Email, 6: &contact.Company,
+		7: &contact.JobTitle, 8: &contact.Address, 9: &contact.Notes, 14: &contact.Owner, 15: &contact.DisplayName,
+		19: &contact.PhoneE164, 20: &contact.PhoneFormatted, 21: &contact.PhoneCountry, 22: &contact.PhoneType, 23: &contact.PhoneArea,
+	}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return n, nil
+			}
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				return 0, fmt.Errorf("invalid id %q", value)
+			}
+			contact.ID = id
+			return n, nil
+		case fields[num] != nil && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(b)
+			*fields[num] = value
+			return n, nil
+		case num == 10 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(b)
+			contact.Version = int64(value)
+			return n, nil
+		case num == 17 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(b)
+			contact.SortWeight = int64(value)
+			return n, nil
+		case num == 16 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(b)
+			contact.Archived = value != 0
+			return n, nil
+		case num == 18 && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(b)
+			contact.Blocked = value != 0
+			return n, nil
+		case (num == 11 || num == 12) && typ == protowire.BytesType:
+			value, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			at, err := unmarshalTimestamp(value)
+			if err != nil {
+				return 0, err
+			}
+			if num == 11 {
+				contact.CreatedAt = &at
+			} else {
+				contact.UpdatedAt = &at
+			}
+			return n, nil
+		case num == 13 && typ == protowire.BytesType:
+			value, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			relation, err := unmarshalRelation(value)
+			if err != nil {
+				return 0, err
+			}
+			contact.Relations = append(contact.Relations, relation)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+func appendContact(b []byte, contact *definition.Contact) []byte {
+	if !contact.ID.IsZero() {
+		b = appendString(b, 1, contact.ID.Hex())
+	}
+	b = appendString(b, 2, contact.FirstName)
+	b = appendString(b, 3, contact.LastName)
+	b = appendString(b, 4, contact.Phone)
+	b = appendString(b, 5, contact.Email)
+	b = appendString(b, 6, contact.Company)
+	b = appendString(b, 7, contact.JobTitle)
+	b = appendString(b, 8, contact.Address)
+	b = appendString(b, 9, contact.Notes)
+	b = appendVarint(b, 10, uint64(contact.Version))
+	if contact.CreatedAt != nil {
+		b = appendMessage(b, 11, appendTimestamp(nil, *contact.CreatedAt))
+	}
+	if contact.UpdatedAt != nil {
+		b = appendMessage(b, 12, appendTimestamp(nil, *contact.UpdatedAt))
+	}
+	for _, relation := range contact.Relations {
+		b = appendMessage(b, 13, appendString(appendString(nil, 1, relation.ContactID.Hex()), 2, relation.Type))
+	}
+	b = appendString(b, 14, contact.Owner)
+	b = appendString(b, 15, contact.DisplayName)
+	if contact.Archived {
+		b = appendVarint(b, 16, 1)
+	}
+	b = appendVarint(b, 17, uint64(contact.SortWeight))
+	if contact.Blocked {
+		b = appendVarint(b, 18, 1)
+	}
+	b = appendString(b, 19, contact.PhoneE164)
+	b = appendString(b, 20, contact.PhoneFormatted)
+	b = appendString(b, 21, contact.PhoneCountry)
+	b = appendString(b, 22, contact.PhoneType)
+	return appendString(b, 23, contact.PhoneArea)
+}
+
+// appendTimestamp appends the fields of a google.protobuf.Timestamp.
+func appendTimestamp(b []byte, at time.Time) []byte {
+	b = appendVarint(b, 1, uint64(at.Unix()))
+	return appendVarint(b, 2, uint64(at.Nanosecond()))
+}
+
+func unmarshalTimestamp(b []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if (num == 1 || num == 2) && typ == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(b)
+			if num == 1 {
+				seconds = int64(value)
+			} else {
+				nanos = int64(int32(value))
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return time.Unix(seconds, nanos).UTC(), err
+}
+
+func unmarshalRelation(b []byte) (definition.Relation, error) {
+	var relation definition.Relation
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if (num == 1 || num == 2) && typ == protowire.BytesType {
+			value, n := protowire.ConsumeString(b)
+			if num == 2 {
+				relation.Type = value
+				return n, nil
+			}
+			if n < 0 {
+				return n, nil
+			}
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				return 0, fmt.Errorf("invalid relation contact id %q", value)
+			}
+			relation.ContactID = id
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return relation, err
+}
+
+// consumeFields calls consume with the number, type and remaining bytes of
+// each field of message b, which returns the length of the field value it
+// consumed, negative when it's malformed.
+func consumeFields(b []byte, consume func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := consume(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// appendString appends a string field, left out when empty like proto3 does.
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// appendVarint appends an integer field, left out when zero like proto3 does.
+func appendVarint(b []byte, num protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
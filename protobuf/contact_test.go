@@ -0,0 +1,52 @@
+package protobuf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/encoding/protowire"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+func TestContact(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 12, 10, 0, 0, 123000000, time.UTC)
+	contact := &definition.Contact{
+		ID: primitive.NewObjectID(), FirstName: "Dani", LastName: "Cohen", Phone: "0521234567", Notes: "VIP", Version: 3,
+		UpdatedAt: &updatedAt, Relations: []definition.Relation{{ContactID: primitive.NewObjectID(), Type: "spouse"}}, Archived: true, SortWeight: 2, Blocked: true,
+		PhoneE164: "+972521234567", PhoneFormatted: "052-123-4567", PhoneCountry: "IL", PhoneType: "mobile",
+	}
+
+	t.Run("should decode the contact it encoded", func(t *testing.T) {
+		decoded, err := UnmarshalContact(MarshalContact(contact))
+		assert.Nil(t, err)
+		assert.Equal(t, contact, decoded)
+	})
+
+	t.Run("should leave the empty fields out", func(t *testing.T) {
+		assert.Equal(t, []byte{0x12, 0x04, 'D', 'a', 'n', 'i', 0x50, 0x02}, MarshalContact(&definition.Contact{FirstName: "Dani", Version: 2}))
+		assert.Empty(t, MarshalContact(&definition.Contact{}))
+	})
+
+	t.Run("should skip the unknown fields", func(t *testing.T) {
+		message := protowire.AppendTag(nil, 99, protowire.BytesType)
+		message = protowire.AppendString(message, "unknown")
+		message = append(message, MarshalContact(&definition.Contact{Phone: "0521234567"})...)
+		decoded, err := UnmarshalContact(message)
+		assert.Nil(t, err)
+		assert.Equal(t, &definition.Contact{Phone: "0521234567"}, decoded)
+	})
+
+	t.Run("should reject a malformed message", func(t *testing.T) {
+		_, err := UnmarshalContact([]byte{0x12, 0x10, 'D'})
+		assert.NotNil(t, err)
+		_, err = UnmarshalContact(appendString(nil, 1, "not an id"))
+		assert.EqualError(t, err, `invalid id "not an id"`)
+	})
+
+	t.Run("should encode the page totals even when zero", func(t *testing.T) {
+		total := int64(0)
+		page := MarshalContactPage(&definition.ContactPage{Items: []*definition.Contact{{Phone: "1"}}, PageSize: 10, TotalItems: &total})
+		assert.Equal(t, []byte{0x0a, 0x03, 0x22, 0x01, '1', 0x18, 0x0a, 0x20, 0x00}, page)
+	})
+}
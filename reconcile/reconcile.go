@@ -0,0 +1,198 @@
+// Package reconcile applies the changes sync clients made to their copy of
+// the contacts, such as CardDAV or mobile clients syncing offline, resolving
+// the conflicts with the changes made on the server meanwhile.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"sort"
+)
+
+// maxAttempts bounds the attempts to apply a change to a contact updated
+// concurrently, each resolving the conflicts against the contact anew.
+const maxAttempts = 3
+
+// Apply applies changes in order with the conflict policy, lww or merge, and
+// returns the result of each. A change to a contact the client synced the
+// current version of is applied as is. Otherwise the contact was changed on
+// the server meanwhile: last writer wins applies the change only when it was
+// made after the server one, and merge applies the fields the server didn't
+// change, keeping the server value of the others. Deletions of contacts
+// changed on the server are conflicts under merge, and changes to contacts
+// deleted on the server are conflicts under both, the server winning.
+func Apply(ctx context.Context, phoneBook definition.IPhoneBook, changes []*definition.ClientChange, policy string, actor string) []*definition.ChangeResult {
+	results := make([]*definition.ChangeResult, len(changes))
+	for i, change := range changes {
+		result := &definition.ChangeResult{ID: change.ID, ClientID: change.ClientID}
+		var err error
+		if change.ID == "" {
+			err = create(ctx, phoneBook, change, actor, result)
+		} else {
+			for attempt := 1; ; attempt++ {
+				err = apply(ctx, phoneBook, change, policy, actor, result)
+				if !errors.Is(err, core.ErrVersionConflict) || attempt == maxAttempts {
+					break
+				}
+			}
+		}
+		if err != nil {
+			result.Status = definition.SyncStatusRejected
+			result.Conflicts = nil
+			result.Contact = nil
+			result.Error = err.Error()
+			var typedErr *definition.Error
+			if errors.As(err, &typedErr) {
+				result.Code = typedErr.Code
+			}
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// create adds the contact a client created.
+func create(ctx context.Context, phoneBook definition.IPhoneBook, change *definition.ClientChange, actor string, result *definition.ChangeResult) error {
+	if change.Deleted {
+		return core.ErrMissingID
+	}
+	contact := &definition.Contact{}
+	fields := contactFields(contact)
+	for field, value := range change.Fields {
+		target, ok := fields[field]
+		if !ok {
+			return core.ErrUnknownField.WithField(field).WithMessage(core.ErrorUnknownField + ": " + field)
+		}
+		if value != nil {
+			*target = *value
+		}
+	}
+	added, err := phoneBook.AddContact(ctx, contact, actor)
+	if err != nil {
+		return err
+	}
+	result.ID = added.ID
+	result.Status = definition.SyncStatusApplied
+	result.Contact, _, err = phoneBook.GetContact(ctx, result.ID)
+	return err
+}
+
+// apply applies a change to an existing contact, resolving the conflicts with
+// policy when the contact was changed on the server since the client synced
+// it.
+func apply(ctx context.Context, phoneBook definition.IPhoneBook, change *definition.ClientChange, policy string, actor string, result *definition.ChangeResult) error {
+	current, status, err := phoneBook.GetContact(ctx, change.ID)
+	if status == core.NotFound {
+		result.Status = definition.SyncStatusApplied
+		if !change.Deleted {
+			// deleted on the server, which wins
+			result.Status = definition.SyncStatusConflict
+			result.Conflicts = changedFields(change.Fields)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	stale := change.Version == 0 || change.Version != current.Version
+	if change.Deleted {
+		if stale && !(policy == definition.SyncPolicyLastWriterWins && changedLast(change, current)) {
+			result.Status = definition.SyncStatusConflict
+			result.Contact = current
+			return nil
+		}
+		result.Status = definition.SyncStatusApplied
+		_, err := phoneBook.DeleteContact(ctx, change.ID, actor)
+		return err
+	}
+	patch, conflicts := change.Fields, []string(nil)
+	if stale {
+		patch, conflicts = resolve(current, change, policy)
+	}
+	switch {
+	case len(conflicts) == 0:
+		result.Status = definition.SyncStatusApplied
+	case len(patch) == 0:
+		result.Status = definition.SyncStatusConflict
+	default:
+		result.Status = definition.SyncStatusMerged
+	}
+	result.Conflicts = conflicts
+	if len(patch) > 0 {
+		if _, err := phoneBook.PatchContact(ctx, change.ID, patch, current.Version, actor); err != nil {
+			return err
+		}
+		if current, _, err = phoneBook.GetContact(ctx, change.ID); err != nil {
+			return err
+		}
+	}
+	result.Contact = current
+	return nil
+}
+
+// resolve returns the fields of a change to apply to current, which was
+// changed on the server since the client synced it, and the fields the
+// server keeps its value of.
+func resolve(current *definition.Contact, change *definition.ClientChange, policy string) (definition.ContactPatch, []string) {
+	if policy == definition.SyncPolicyLastWriterWins {
+		if changedLast(change, current) {
+			return change.Fields, nil
+		}
+		return nil, changedFields(change.Fields)
+	}
+	values := contactFields(current)
+	patch := definition.ContactPatch{}
+	var conflicts []string
+	for field, value := range change.Fields {
+		serverValue := ""
+		if target, ok := values[field]; ok {
+			serverValue = *target
+		}
+		clientValue := ""
+		if value != nil {
+			clientValue = *value
+		}
+		switch {
+		case serverValue == clientValue:
+			// the same change on both sides
+		case serverValue == change.Base[field]:
+			patch[field] = value
+		default:
+			conflicts = append(conflicts, field)
+		}
+	}
+	sort.Strings(conflicts)
+	return patch, conflicts
+}
+
+// changedLast reports whether the client made change after the last change
+// to current on the server.
+func changedLast(change *definition.ClientChange, current *definition.Contact) bool {
+	return current.UpdatedAt == nil || change.ModifiedAt.After(*current.UpdatedAt)
+}
+
+// contactFields returns the editable fields of contact by their json name.
+func contactFields(contact *definition.Contact) map[string]*string {
+	return map[string]*string{
+		"firstName": &contact.FirstName,
+		"lastName":  &contact.LastName,
+		"phone":     &contact.Phone,
+		"email":     &contact.Email,
+		"company":   &contact.Company,
+		"jobTitle":  &contact.JobTitle,
+		"address":   &contact.Address,
+		"notes":     &contact.Notes,
+	}
+}
+
+// changedFields returns the fields of patch in order.
+func changedFields(patch definition.ContactPatch) []string {
+	var fields []string
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
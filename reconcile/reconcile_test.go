@@ -0,0 +1,153 @@
+package reconcile
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+// memoryPhoneBook keeps the contacts in memory, failing the first patches
+// with conflicts.
+type memoryPhoneBook struct {
+	definition.IPhoneBook
+	contacts  map[string]*definition.Contact
+	conflicts int
+}
+
+func (pb *memoryPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	contact, ok := pb.contacts[id]
+	if !ok {
+		return nil, core.NotFound, core.ErrContactNotFound
+	}
+	copied := *contact
+	return &copied, "", nil
+}
+
+func (pb *memoryPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	if contact.Phone == "" {
+		return nil, core.ErrMissingPhone
+	}
+	contact.ID = primitive.NewObjectID()
+	contact.Version = 1
+	pb.contacts[contact.ID.Hex()] = contact
+	return &definition.WriteResult{ID: contact.ID.Hex()}, nil
+}
+
+func (pb *memoryPhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	contact := pb.contacts[id]
+	if pb.conflicts > 0 {
+		pb.conflicts--
+		contact.Version++
+	}
+	if contact.Version != expectedVersion {
+		return nil, core.ErrVersionConflict
+	}
+	fields := contactFields(contact)
+	for field, value := range patch {
+		*fields[field] = ""
+		if value != nil {
+			*fields[field] = *value
+		}
+	}
+	contact.Version++
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *memoryPhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	delete(pb.contacts, id)
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func value(s string) *string {
+	return &s
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	synced := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	updated := synced.Add(time.Hour)
+	newPhoneBook := func() (*memoryPhoneBook, string) {
+		id := primitive.NewObjectID()
+		contact := &definition.Contact{ID: id, FirstName: "Dani", Phone: "0521234567", Company: "Acme", Version: 3, UpdatedAt: &updated}
+		return &memoryPhoneBook{contacts: map[string]*definition.Contact{id.Hex(): contact}}, id.Hex()
+	}
+
+	t.Run("should apply a change to the synced version", func(t *testing.T) {
+		pb, id := newPhoneBook()
+		results := Apply(ctx, pb, []*definition.ClientChange{{ID: id, Version: 3, Fields: definition.ContactPatch{"company": value("Globex")}}}, definition.SyncPolicyMerge, "dani")
+		assert.Equal(t, definition.SyncStatusApplied, results[0].Status)
+		assert.Equal(t, "Globex", results[0].Contact.Company)
+		assert.Equal(t, int64(4), results[0].Contact.Version)
+	})
+
+	t.Run("should merge the fields the server didn't change", func(t *testing.T) {
+		pb, id := newPhoneBook()
+		change := &definition.ClientChange{ID: id, Version: 2, Fields: definition.ContactPatch{
+			"firstName": value("Daniel"), "company": value("Globex"), "phone": value("0521234567"),
+		}, Base: map[string]string{"firstName": "Dani", "company": "Initech", "phone": "0541111111"}}
+		results := Apply(ctx, pb, []*definition.ClientChange{change}, definition.SyncPolicyMerge, "dani")
+		assert.Equal(t, definition.SyncStatusMerged, results[0].Status)
+		assert.Equal(t, []string{"company"}, results[0].Conflicts)
+		assert.Equal(t, "Daniel", results[0].Contact.FirstName)
+		assert.Equal(t, "Acme", results[0].Contact.Company)
+	})
+
+	t.Run("should keep the last writer", func(t *testing.T) {
+		pb, id := newPhoneBook()
+		changes := []*definition.ClientChange{
+			{ID: id, Version: 2, Fields: definition.ContactPatch{"company": value("Globex")}, ModifiedAt: synced},
+			{ID: id, Version: 2, Fields: definition.ContactPatch{"company": value("Initech")}, ModifiedAt: updated.Add(time.Minute)},
+		}
+		results := Apply(ctx, pb, changes, definition.SyncPolicyLastWriterWins, "dani")
+		assert.Equal(t, definition.SyncStatusConflict, results[0].Status)
+		assert.Equal(t, []string{"company"}, results[0].Conflicts)
+		assert.Equal(t, "Acme", results[0].Contact.Company)
+		assert.Equal(t, definition.SyncStatusApplied, results[1].Status)
+		assert.Equal(t, "Initech", results[1].Contact.Company)
+	})
+
+	t.Run("should keep the contacts changed on the server from deletion when merging", func(t *testing.T) {
+		pb, id := newPhoneBook()
+		results := Apply(ctx, pb, []*definition.ClientChange{{ID: id, Version: 2, Deleted: true}}, definition.SyncPolicyMerge, "dani")
+		assert.Equal(t, definition.SyncStatusConflict, results[0].Status)
+		assert.Equal(t, id, results[0].Contact.ID.Hex())
+		assert.Len(t, pb.contacts, 1)
+
+		results = Apply(ctx, pb, []*definition.ClientChange{{ID: id, Version: 3, Deleted: true}}, definition.SyncPolicyMerge, "dani")
+		assert.Equal(t, definition.SyncStatusApplied, results[0].Status)
+		assert.Empty(t, pb.contacts)
+	})
+
+	t.Run("should let the server deletion win", func(t *testing.T) {
+		pb, _ := newPhoneBook()
+		deleted := primitive.NewObjectID().Hex()
+		results := Apply(ctx, pb, []*definition.ClientChange{{ID: deleted, Version: 1, Fields: definition.ContactPatch{"notes": nil}}}, definition.SyncPolicyLastWriterWins, "dani")
+		assert.Equal(t, &definition.ChangeResult{ID: deleted, Status: definition.SyncStatusConflict, Conflicts: []string{"notes"}}, results[0])
+	})
+
+	t.Run("should add the contacts created by the client", func(t *testing.T) {
+		pb, _ := newPhoneBook()
+		results := Apply(ctx, pb, []*definition.ClientChange{
+			{ClientID: "local-1", Fields: definition.ContactPatch{"firstName": value("Noa"), "phone": value("0541111111")}},
+			{ClientID: "local-2", Fields: definition.ContactPatch{"firstName": value("Noa")}},
+			{ClientID: "local-3", Fields: definition.ContactPatch{"nickname": value("Noa")}},
+		}, definition.SyncPolicyMerge, "dani")
+		assert.Equal(t, definition.SyncStatusApplied, results[0].Status)
+		assert.Equal(t, "local-1", results[0].ClientID)
+		assert.Equal(t, results[0].ID, results[0].Contact.ID.Hex())
+		assert.Equal(t, &definition.ChangeResult{ClientID: "local-2", Status: definition.SyncStatusRejected, Code: "MISSING_PHONE", Error: core.ErrorMissingPhone}, results[1])
+		assert.Equal(t, "UNKNOWN_FIELD", results[2].Code)
+	})
+
+	t.Run("should resolve the conflicts again when the contact changed meanwhile", func(t *testing.T) {
+		pb, id := newPhoneBook()
+		pb.conflicts = 1
+		results := Apply(ctx, pb, []*definition.ClientChange{{ID: id, Version: 3, Fields: definition.ContactPatch{"company": value("Globex")}, Base: map[string]string{"company": "Acme"}}}, definition.SyncPolicyMerge, "dani")
+		assert.Equal(t, definition.SyncStatusApplied, results[0].Status)
+		assert.Equal(t, "Globex", results[0].Contact.Company)
+	})
+}
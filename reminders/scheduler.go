@@ -0,0 +1,165 @@
+// Package reminders sends the reminders of the phone book once they are due.
+package reminders
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+var (
+	sent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_reminders_sent_total",
+		Help: "Due reminders sent.",
+	})
+	failures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_reminder_failures_total",
+		Help: "Due reminders that failed to be sent.",
+	})
+)
+
+// Notification is what is sent for a due reminder. Contact is nil when the
+// contact was deleted meanwhile, and Tenant empty for the reminders of no
+// tenant.
+type Notification struct {
+	Reminder *definition.Reminder `json:"reminder"`
+	Contact  *definition.Contact  `json:"contact,omitempty"`
+	Tenant   string               `json:"tenant,omitempty"`
+}
+
+// Notifier sends the notification of a due reminder.
+type Notifier interface {
+	Notify(ctx context.Context, notification *Notification) error
+}
+
+// Tenants lists the tenants whose reminders are sent, on top of those of the
+// phone book of no tenant.
+type Tenants interface {
+	ListTenants(ctx context.Context) ([]*definition.Tenant, string, error)
+}
+
+// Scheduler sends the due reminders of a phone book every interval.
+type Scheduler struct {
+	phoneBook definition.IPhoneBook
+	notifier  Notifier
+	tenants   Tenants
+	interval  time.Duration
+	timeout   time.Duration
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler sending the due reminders with notifier
+// every interval, each bound by timeout. With tenants, the due reminders of
+// every tenant are sent too.
+func NewScheduler(phoneBook definition.IPhoneBook, notifier Notifier, tenants Tenants, interval, timeout time.Duration) *Scheduler {
+	return &Scheduler{
+		phoneBook: phoneBook,
+		notifier:  notifier,
+		tenants:   tenants,
+		interval:  interval,
+		timeout:   timeout,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start sends the due reminders in the background, the first time after an
+// interval.
+func (s *Scheduler) Start() {
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Run()
+			}
+		}
+	}()
+}
+
+// Stop waits for the reminder being sent, if any, and for the scheduler to
+// exit.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.done.Wait()
+}
+
+// Run sends the reminders due by now of the phone book of no tenant, then of
+// every tenant. A tenant failing to be claimed from doesn't stop the others.
+func (s *Scheduler) Run() {
+	s.sendDue(context.Background())
+	if s.tenants == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	tenants, _, err := s.tenants.ListTenants(ctx)
+	cancel()
+	if err != nil {
+		logrus.WithError(err).Error("failed to list the tenants to send the due reminders of")
+		return
+	}
+	for _, tenant := range tenants {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.sendDue(definition.WithTenant(context.Background(), tenant.ID))
+	}
+}
+
+// sendDue sends the reminders due by now of the phone book of ctx, one at a
+// time, until none is left or the scheduler is stopped. A reminder is claimed
+// before it's sent, so one that fails to be sent is logged and not retried.
+func (s *Scheduler) sendDue(ctx context.Context) {
+	tenant := definition.TenantFromContext(ctx)
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		reminder, err := s.claim(ctx)
+		if err != nil {
+			logrus.WithError(err).WithField("tenant", tenant).Error("failed to claim the due reminders")
+			return
+		}
+		if reminder == nil {
+			return
+		}
+		if err := s.send(ctx, reminder); err != nil {
+			failures.Inc()
+			logrus.WithError(err).WithField("tenant", tenant).Errorf("failed to send reminder %s", reminder.ID.Hex())
+			continue
+		}
+		sent.Inc()
+	}
+}
+
+func (s *Scheduler) claim(ctx context.Context) (*definition.Reminder, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.phoneBook.ClaimDueReminder(ctx, time.Now())
+}
+
+func (s *Scheduler) send(ctx context.Context, reminder *definition.Reminder) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	// the reminder is sent for deleted contacts too, it was asked for
+	contact, _, err := s.phoneBook.GetContact(ctx, reminder.ContactID.Hex())
+	if err != nil {
+		contact = nil
+	}
+	notification := &Notification{Reminder: reminder, Contact: contact, Tenant: definition.TenantFromContext(ctx)}
+	return s.notifier.Notify(ctx, notification)
+}
@@ -0,0 +1,151 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reminderPhoneBook has a queue of due reminders, for the contact Dani, and
+// one of every tenant.
+type reminderPhoneBook struct {
+	definition.IPhoneBook
+	mu        sync.Mutex
+	due       []*definition.Reminder
+	tenantDue map[string][]*definition.Reminder
+}
+
+func (pb *reminderPhoneBook) ClaimDueReminder(ctx context.Context, now time.Time) (*definition.Reminder, error) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	tenant := definition.TenantFromContext(ctx)
+	if tenant == "failing" {
+		return nil, errors.New("tenant database is down")
+	}
+	if tenant != "" {
+		due := pb.tenantDue[tenant]
+		if len(due) == 0 {
+			return nil, nil
+		}
+		pb.tenantDue[tenant] = due[1:]
+		return due[0], nil
+	}
+	if len(pb.due) == 0 {
+		return nil, nil
+	}
+	reminder := pb.due[0]
+	pb.due = pb.due[1:]
+	return reminder, nil
+}
+
+func (pb *reminderPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	return &definition.Contact{FirstName: "Dani"}, "", nil
+}
+
+// recordingNotifier keeps the notifications it was asked to send.
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []*Notification
+	err           error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification *Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.notifications)
+}
+
+type stubTenants []string
+
+func (tenants stubTenants) ListTenants(ctx context.Context) ([]*definition.Tenant, string, error) {
+	var list []*definition.Tenant
+	for _, id := range tenants {
+		list = append(list, &definition.Tenant{ID: id})
+	}
+	return list, "", nil
+}
+
+func newReminder(note string) *definition.Reminder {
+	return &definition.Reminder{ID: primitive.NewObjectID(), ContactID: primitive.NewObjectID(), Note: note}
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("should send every due reminder with its contact", func(t *testing.T) {
+		phoneBook := &reminderPhoneBook{due: []*definition.Reminder{newReminder("Call back"), newReminder("Send the offer")}}
+		notifier := &recordingNotifier{}
+		NewScheduler(phoneBook, notifier, nil, time.Hour, time.Minute).Run()
+		assert.Equal(t, 2, notifier.count())
+		assert.Equal(t, "Call back", notifier.notifications[0].Reminder.Note)
+		assert.Equal(t, "Dani", notifier.notifications[0].Contact.FirstName)
+	})
+
+	t.Run("should go on after a failure", func(t *testing.T) {
+		phoneBook := &reminderPhoneBook{due: []*definition.Reminder{newReminder("Call back"), newReminder("Send the offer")}}
+		notifier := &recordingNotifier{err: errors.New("webhook is down")}
+		NewScheduler(phoneBook, notifier, nil, time.Hour, time.Minute).Run()
+		assert.Equal(t, 2, notifier.count())
+		assert.Empty(t, phoneBook.due)
+	})
+
+	t.Run("should send the due reminders of every tenant despite failures", func(t *testing.T) {
+		phoneBook := &reminderPhoneBook{
+			due: []*definition.Reminder{newReminder("Call back")},
+			tenantDue: map[string][]*definition.Reminder{
+				"acme":   {newReminder("Send the offer")},
+				"globex": {newReminder("Renew the contract")},
+			},
+		}
+		notifier := &recordingNotifier{}
+		NewScheduler(phoneBook, notifier, stubTenants{"acme", "failing", "globex"}, time.Hour, time.Minute).Run()
+		assert.Equal(t, 3, notifier.count())
+		assert.Equal(t, "", notifier.notifications[0].Tenant)
+		assert.Equal(t, "Send the offer", notifier.notifications[1].Reminder.Note)
+		assert.Equal(t, "acme", notifier.notifications[1].Tenant)
+		assert.Equal(t, "globex", notifier.notifications[2].Tenant)
+	})
+
+	t.Run("should send the reminders every interval until stopped", func(t *testing.T) {
+		phoneBook := &reminderPhoneBook{due: []*definition.Reminder{newReminder("Call back")}}
+		notifier := &recordingNotifier{}
+		scheduler := NewScheduler(phoneBook, notifier, nil, 10*time.Millisecond, time.Minute)
+		scheduler.Start()
+		assert.Eventually(t, func() bool { return notifier.count() == 1 }, time.Second, 5*time.Millisecond)
+		scheduler.Stop()
+	})
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Notification
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier(server.URL)
+
+	err := notifier.Notify(context.Background(), &Notification{Reminder: newReminder("Call back"), Contact: &definition.Contact{FirstName: "Dani"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Call back", received.Reminder.Note)
+	assert.Equal(t, "Dani", received.Contact.FirstName)
+
+	status = http.StatusBadGateway
+	err = notifier.Notify(context.Background(), &Notification{Reminder: newReminder("Call back")})
+	assert.ErrorContains(t, err, "502")
+}
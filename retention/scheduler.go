@@ -0,0 +1,123 @@
+// Package retention applies the retention policy of the phone book
+// periodically, anonymizing or purging the contacts untouched for too long.
+package retention
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+var (
+	affected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "phonebook_retention_contacts_total",
+		Help: "Contacts anonymized or purged by the retention policy, by action.",
+	}, []string{"action"})
+	failures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "phonebook_retention_failures_total",
+		Help: "Retention policy runs that failed.",
+	})
+)
+
+// Tenants lists the tenants whose phone books the policy applies to, on top
+// of the phone book of no tenant.
+type Tenants interface {
+	ListTenants(ctx context.Context) ([]*definition.Tenant, string, error)
+}
+
+// Scheduler applies a retention policy to a phone book every interval.
+type Scheduler struct {
+	phoneBook definition.IPhoneBook
+	policy    definition.RetentionPolicy
+	tenants   Tenants
+	interval  time.Duration
+	timeout   time.Duration
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler applying policy to phoneBook every
+// interval, each run of a phone book bound by timeout. With tenants, the
+// policy applies to the phone book of every tenant too.
+func NewScheduler(phoneBook definition.IPhoneBook, policy definition.RetentionPolicy, tenants Tenants, interval, timeout time.Duration) *Scheduler {
+	return &Scheduler{
+		phoneBook: phoneBook,
+		policy:    policy,
+		tenants:   tenants,
+		interval:  interval,
+		timeout:   timeout,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start applies the policy in the background, the first time after an
+// interval.
+func (s *Scheduler) Start() {
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Run()
+			}
+		}
+	}()
+}
+
+// Stop waits for the run in progress, if any, and for the scheduler to exit.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.done.Wait()
+}
+
+// Run applies the policy to the phone book of no tenant, then of every
+// tenant. A failure is logged and doesn't stop the other phone books.
+func (s *Scheduler) Run() {
+	s.apply(context.Background())
+	if s.tenants == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	tenants, _, err := s.tenants.ListTenants(ctx)
+	cancel()
+	if err != nil {
+		failures.Inc()
+		logrus.WithError(err).Error("failed to list the tenants to apply the retention policy to")
+		return
+	}
+	for _, tenant := range tenants {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.apply(definition.WithTenant(context.Background(), tenant.ID))
+	}
+}
+
+func (s *Scheduler) apply(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	tenant := definition.TenantFromContext(ctx)
+	policy := s.policy
+	result, err := s.phoneBook.ApplyRetention(ctx, &policy, "retention")
+	if err != nil {
+		failures.Inc()
+		logrus.WithError(err).WithField("tenant", tenant).Error("failed to apply the retention policy")
+		return
+	}
+	affected.WithLabelValues(result.Action).Add(float64(result.Affected))
+	if result.Affected > 0 {
+		logrus.WithField("tenant", tenant).Infof("retention policy applied: %s %d contacts not updated for %d days", result.Action, result.Affected, s.policy.Days)
+	}
+}
@@ -0,0 +1,55 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"phoneBook/definition"
+	"testing"
+	"time"
+)
+
+// policyPhoneBook keeps the tenants and policies the retention was applied
+// with, failing for the tenant named failing.
+type policyPhoneBook struct {
+	definition.IPhoneBook
+	tenants  []string
+	policies []definition.RetentionPolicy
+}
+
+func (pb *policyPhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	tenant := definition.TenantFromContext(ctx)
+	if tenant == "failing" {
+		return nil, errors.New("database is down")
+	}
+	pb.tenants = append(pb.tenants, tenant)
+	pb.policies = append(pb.policies, *policy)
+	return &definition.RetentionResult{Action: policy.Action, Affected: 2}, nil
+}
+
+type stubTenants []string
+
+func (tenants stubTenants) ListTenants(ctx context.Context) ([]*definition.Tenant, string, error) {
+	var list []*definition.Tenant
+	for _, id := range tenants {
+		list = append(list, &definition.Tenant{ID: id})
+	}
+	return list, "", nil
+}
+
+func TestScheduler(t *testing.T) {
+	policy := definition.RetentionPolicy{Action: definition.RetentionActionPurge, Days: 365}
+
+	t.Run("should apply the policy to the phone book of no tenant", func(t *testing.T) {
+		phoneBook := &policyPhoneBook{}
+		NewScheduler(phoneBook, policy, nil, time.Hour, time.Minute).Run()
+		assert.Equal(t, []string{""}, phoneBook.tenants)
+		assert.Equal(t, []definition.RetentionPolicy{policy}, phoneBook.policies)
+	})
+
+	t.Run("should apply the policy to every tenant despite failures", func(t *testing.T) {
+		phoneBook := &policyPhoneBook{}
+		NewScheduler(phoneBook, policy, stubTenants{"acme", "failing", "globex"}, time.Hour, time.Minute).Run()
+		assert.Equal(t, []string{"", "acme", "globex"}, phoneBook.tenants)
+	})
+}
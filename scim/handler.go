@@ -0,0 +1,418 @@
+// Package scim provisions contacts from identity providers like Okta and
+// Microsoft Entra ID over SCIM 2.0 (RFC 7643 and 7644). It serves the subset
+// of the Users resource they push employees with: create, read, filter by
+// userName, replace, patch and delete. Deactivating a user deletes its
+// contact.
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// BasePath is where the SCIM endpoints are served.
+	BasePath = "/scim/v2"
+
+	userSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	enterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	listSchema       = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	patchSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	errorSchema      = "urn:ietf:params:scim:api:messages:2.0:Error"
+	configSchema     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	contentType      = "application/scim+json"
+
+	// actor names the provider in the contacts history.
+	actor = "scim"
+	// defaultCount is the page size of listings that don't set count.
+	defaultCount = 100
+)
+
+// filterRegex matches the only filter supported, on the userName.
+var filterRegex = regexp.MustCompile(`(?i)^\s*userName\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimError is a failure answered as a SCIM Error message.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func newError(status int, scimType string, detail string) *scimError {
+	return &scimError{status: status, scimType: scimType, detail: detail}
+}
+
+func (e *scimError) Error() string {
+	return e.detail
+}
+
+var errNotFound = newError(http.StatusNotFound, "", "user not found")
+
+// Handler serves the Users of a phone book to a provider authenticated with
+// a bearer token.
+type Handler struct {
+	phoneBook definition.IPhoneBook
+	token     string
+}
+
+// Register serves the SCIM endpoints on router, the subrouter of BasePath,
+// to providers sending token.
+func Register(router *mux.Router, phoneBook definition.IPhoneBook, token string) {
+	h := &Handler{phoneBook: phoneBook, token: token}
+	router.Use(h.authenticate)
+	router.HandleFunc("/ServiceProviderConfig", h.serviceProviderConfig).Methods("GET")
+	router.HandleFunc("/Users", h.listUsers).Methods("GET")
+	router.HandleFunc("/Users", h.createUser).Methods("POST")
+	router.HandleFunc("/Users/{id}", h.getUser).Methods("GET")
+	router.HandleFunc("/Users/{id}", h.replaceUser).Methods("PUT")
+	router.HandleFunc("/Users/{id}", h.patchUser).Methods("PATCH")
+	router.HandleFunc("/Users/{id}", h.deleteUser).Methods("DELETE")
+}
+
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeError(w, newError(http.StatusUnauthorized, "", "invalid or missing bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	unsupported := map[string]bool{"supported": false}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{configSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": config.Tunables().MaxPageSize},
+		"changePassword": unsupported,
+		"sort":           unsupported,
+		"etag":           unsupported,
+		"authenticationSchemes": []map[string]string{{
+			"type": "oauthbearertoken", "name": "Bearer token", "description": "The SCIM_TOKEN of the phone book",
+		}},
+	})
+}
+
+// listUsers pages through the users, or finds the one with a userName.
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	startIndex, count := 1, defaultCount
+	if value := query.Get("startIndex"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, newError(http.StatusBadRequest, "invalidValue", "invalid startIndex"))
+			return
+		}
+		startIndex = max(parsed, 1)
+	}
+	if value := query.Get("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, newError(http.StatusBadRequest, "invalidValue", "invalid count"))
+			return
+		}
+		count = max(parsed, 0)
+	}
+	// pages of users are pages of contacts
+	count = min(count, int(config.Tunables().MaxPageSize))
+	var users []*user
+	var total int64
+	if filter := query.Get("filter"); filter != "" {
+		match := filterRegex.FindStringSubmatch(filter)
+		if match == nil {
+			writeError(w, newError(http.StatusBadRequest, "invalidFilter", `only userName eq "..." filters are supported`))
+			return
+		}
+		var userName string
+		json.Unmarshal([]byte(`"`+match[1]+`"`), &userName)
+		contact, err := h.findByUserName(r, userName)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if contact != nil {
+			total = 1
+			if startIndex == 1 && count > 0 {
+				users = append(users, newUser(contact, baseURL(r)))
+			}
+		}
+	} else {
+		// pages start at multiples of count
+		pageSize := max(count, 1)
+		page := (startIndex-1)/pageSize + 1
+		startIndex = (page-1)*pageSize + 1
+		contacts, status, err := h.phoneBook.GetContactWithPagination(r.Context(), url.Values{
+			"page": {strconv.Itoa(page)}, "pageSize": {strconv.Itoa(pageSize)}, "includeArchived": {"true"},
+		})
+		if err != nil {
+			writeError(w, phoneBookError(status, err))
+			return
+		}
+		if contacts.TotalItems != nil {
+			total = *contacts.TotalItems
+		}
+		if count > 0 {
+			for _, contact := range contacts.Items {
+				users = append(users, newUser(contact, baseURL(r)))
+			}
+		}
+	}
+	if users == nil {
+		users = []*user{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{listSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": len(users),
+		"Resources":    users,
+	})
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var u user
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeError(w, newError(http.StatusBadRequest, "invalidSyntax", "invalid user"))
+		return
+	}
+	if u.UserName == "" {
+		writeError(w, newError(http.StatusBadRequest, "invalidValue", "userName is required"))
+		return
+	}
+	existing, err := h.findByUserName(r, u.UserName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if existing != nil {
+		writeError(w, newError(http.StatusConflict, "uniqueness", "a user with userName "+u.UserName+" exists"))
+		return
+	}
+	added, err := h.phoneBook.AddContact(r.Context(), u.contact(), actor)
+	if err != nil {
+		writeError(w, phoneBookError(core.StatusOf(err), err))
+		return
+	}
+	contact, err := h.contact(r, added.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	created := newUser(contact, baseURL(r))
+	w.Header().Set("Location", created.Meta.Location)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	contact, err := h.contact(r, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUser(contact, baseURL(r)))
+}
+
+// replaceUser sets the user as sent, clearing the fields left out.
+func (h *Handler) replaceUser(w http.ResponseWriter, r *http.Request) {
+	current, err := h.contact(r, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var u user
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeError(w, newError(http.StatusBadRequest, "invalidSyntax", "invalid user"))
+		return
+	}
+	if u.UserName == "" {
+		writeError(w, newError(http.StatusBadRequest, "invalidValue", "userName is required"))
+		return
+	}
+	if u.Active != nil && !*u.Active {
+		h.deactivate(w, r, current)
+		return
+	}
+	h.update(w, r, current, u.contact())
+}
+
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	current, err := h.contact(r, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var patch patchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, newError(http.StatusBadRequest, "invalidSyntax", "invalid patch"))
+		return
+	}
+	next := *current
+	active, err := applyPatch(&next, patch.Operations)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !active {
+		h.deactivate(w, r, current)
+		return
+	}
+	h.update(w, r, current, &next)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	current, err := h.contact(r, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.deactivate(w, r, current)
+}
+
+// update writes the provisioned fields of next that differ from current, as
+// a patch applying only to the version read.
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, current *definition.Contact, next *definition.Contact) {
+	id := current.ID.Hex()
+	if next.UserName != "" && next.UserName != current.UserName {
+		existing, err := h.findByUserName(r, next.UserName)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if existing != nil && existing.ID != current.ID {
+			writeError(w, newError(http.StatusConflict, "uniqueness", "a user with userName "+next.UserName+" exists"))
+			return
+		}
+	}
+	patch := definition.ContactPatch{}
+	currentFields := provisionedFields(current)
+	for field, value := range provisionedFields(next) {
+		if value == currentFields[field] {
+			continue
+		}
+		if value == "" {
+			patch[field] = nil
+		} else {
+			patch[field] = &value
+		}
+	}
+	if len(patch) > 0 {
+		if _, err := h.phoneBook.PatchContact(r.Context(), id, patch, current.Version, actor); err != nil {
+			writeError(w, phoneBookError(core.StatusOf(err), err))
+			return
+		}
+	}
+	if next.UserName != "" && next.UserName != current.UserName {
+		// the user name isn't a field of patches
+		if _, err := h.phoneBook.UpdateContact(r.Context(), id, &definition.Contact{UserName: next.UserName}, 0, actor); err != nil {
+			writeError(w, phoneBookError(core.StatusOf(err), err))
+			return
+		}
+	}
+	updated, err := h.contact(r, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUser(updated, baseURL(r)))
+}
+
+// deactivate deletes the contact of a user leaving the organization.
+func (h *Handler) deactivate(w http.ResponseWriter, r *http.Request, current *definition.Contact) {
+	if _, err := h.phoneBook.DeleteContact(r.Context(), current.ID.Hex(), actor); err != nil {
+		writeError(w, phoneBookError(core.StatusOf(err), err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// contact returns the contact of the user with id.
+func (h *Handler) contact(r *http.Request, id string) (*definition.Contact, error) {
+	contact, status, err := h.phoneBook.GetContact(r.Context(), id)
+	if status == core.NotFound || errors.Is(err, core.ErrInvalidID) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, phoneBookError(status, err)
+	}
+	return contact, nil
+}
+
+// findByUserName returns the contact provisioned for userName, nil if none
+// was.
+func (h *Handler) findByUserName(r *http.Request, userName string) (*definition.Contact, error) {
+	page, status, err := h.phoneBook.SearchContact(r.Context(), url.Values{"userName": {userName}, "pageSize": {"1"}, "includeArchived": {"true"}})
+	if err != nil {
+		return nil, phoneBookError(status, err)
+	}
+	if len(page.Items) == 0 {
+		return nil, nil
+	}
+	return page.Items[0], nil
+}
+
+// phoneBookError answers a failure of the phone book with its status: the
+// contact validation errors as invalid values and the duplicates as
+// uniqueness conflicts.
+func phoneBookError(status string, err error) *scimError {
+	switch status {
+	case core.BadRequest:
+		return newError(http.StatusBadRequest, "invalidValue", err.Error())
+	case core.Conflict:
+		if errors.Is(err, core.ErrVersionConflict) {
+			return newError(http.StatusPreconditionFailed, "", err.Error())
+		}
+		return newError(http.StatusConflict, "uniqueness", err.Error())
+	case core.NotFound:
+		return errNotFound
+	case core.PaymentRequired:
+		return newError(http.StatusForbidden, "", err.Error())
+	case core.NotImplemented:
+		return newError(http.StatusNotImplemented, "", err.Error())
+	}
+	logrus.WithError(err).Error("scim request failed")
+	return newError(http.StatusInternalServerError, "", "internal error")
+}
+
+// baseURL returns the URL of the SCIM endpoints as the request reached them.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	prefix, _, _ := strings.Cut(r.URL.Path, BasePath)
+	return scheme + "://" + r.Host + prefix + BasePath
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var scimErr *scimError
+	if !errors.As(err, &scimErr) {
+		scimErr = newError(http.StatusInternalServerError, "", err.Error())
+	}
+	body := map[string]interface{}{"schemas": []string{errorSchema}, "status": strconv.Itoa(scimErr.status), "detail": scimErr.detail}
+	if scimErr.scimType != "" {
+		body["scimType"] = scimErr.scimType
+	}
+	writeJSON(w, scimErr.status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	response, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,237 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strings"
+	"testing"
+)
+
+// scimPhoneBook keeps the contacts in memory, recording the patches.
+type scimPhoneBook struct {
+	definition.IPhoneBook
+	contacts []*definition.Contact
+	patches  []definition.ContactPatch
+}
+
+func (pb *scimPhoneBook) find(id string) *definition.Contact {
+	for _, contact := range pb.contacts {
+		if contact.ID.Hex() == id {
+			return contact
+		}
+	}
+	return nil
+}
+
+func (pb *scimPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	contact := pb.find(id)
+	if contact == nil {
+		return nil, core.NotFound, core.ErrContactNotFound
+	}
+	copied := *contact
+	return &copied, "", nil
+}
+
+func (pb *scimPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	var matches []*definition.Contact
+	for _, contact := range pb.contacts {
+		if contact.UserName == query.Get("userName") {
+			matches = append(matches, contact)
+		}
+	}
+	return &definition.ContactPage{Items: matches}, "", nil
+}
+
+func (pb *scimPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	total := int64(len(pb.contacts))
+	return &definition.ContactPage{Items: pb.contacts, TotalItems: &total}, "", nil
+}
+
+func (pb *scimPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	if contact.Phone == "" {
+		return nil, core.ErrMissingPhone
+	}
+	contact.ID = primitive.NewObjectID()
+	contact.Version = 1
+	pb.contacts = append(pb.contacts, contact)
+	return &definition.WriteResult{ID: contact.ID.Hex()}, nil
+}
+
+func (pb *scimPhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	contact := pb.find(id)
+	if contact.Version != expectedVersion {
+		return nil, core.ErrVersionConflict
+	}
+	pb.patches = append(pb.patches, patch)
+	for field, value := range patch {
+		text := ""
+		if value != nil {
+			text = *value
+		}
+		switch field {
+		case "phone":
+			contact.Phone = text
+		case "lastName":
+			contact.LastName = text
+		case "jobTitle":
+			contact.JobTitle = text
+		}
+	}
+	contact.Version++
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *scimPhoneBook) UpdateContact(ctx context.Context, id string, contact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	pb.find(id).UserName = contact.UserName
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *scimPhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	for i, contact := range pb.contacts {
+		if contact.ID.Hex() == id {
+			pb.contacts = append(pb.contacts[:i], pb.contacts[i+1:]...)
+			return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+		}
+	}
+	return &definition.WriteResult{}, nil
+}
+
+const token = "s3cret"
+
+func serve(pb *scimPhoneBook, method string, path string, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	Register(router.PathPrefix(BasePath).Subrouter(), pb, token)
+	request := httptest.NewRequest(method, "http://phonebook.example"+BasePath+path, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+token)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func decode(t *testing.T, response *httptest.ResponseRecorder) map[string]interface{} {
+	body := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	return body
+}
+
+func TestUsers(t *testing.T) {
+	dana := &definition.Contact{ID: primitive.NewObjectID(), UserName: "dana@example.com", FirstName: "Dana", LastName: "Levi",
+		Phone: "0521234567", JobTitle: "Engineer", Version: 3}
+
+	t.Run("should reject requests without the token", func(t *testing.T) {
+		router := mux.NewRouter()
+		Register(router.PathPrefix(BasePath).Subrouter(), &scimPhoneBook{}, token)
+		request := httptest.NewRequest("GET", BasePath+"/Users", nil)
+		request.Header.Set("Authorization", "Bearer wrong")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Equal(t, "401", decode(t, response)["status"])
+	})
+
+	t.Run("should create a user as a contact", func(t *testing.T) {
+		pb := &scimPhoneBook{}
+		response := serve(pb, "POST", "/Users", `{"schemas":["`+userSchema+`"],"userName":"avi@example.com",
+			"name":{"givenName":"Avi","familyName":"Cohen"},"title":"Sales",
+			"phoneNumbers":[{"value":"+972 (52) 765-4321","type":"work","primary":true}],
+			"emails":[{"value":"avi@example.com"}],
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"organization":"Acme"}}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, contentType, response.Header().Get("Content-Type"))
+		contact := pb.contacts[0]
+		assert.Equal(t, definition.Contact{ID: contact.ID, UserName: "avi@example.com", FirstName: "Avi", LastName: "Cohen", Phone: "972527654321",
+			Email: "avi@example.com", Company: "Acme", JobTitle: "Sales", Version: 1}, *contact)
+		assert.Equal(t, "http://phonebook.example/scim/v2/Users/"+contact.ID.Hex(), response.Header().Get("Location"))
+		assert.Equal(t, contact.ID.Hex(), decode(t, response)["id"])
+	})
+
+	t.Run("should reject an existing userName", func(t *testing.T) {
+		copied := *dana
+		response := serve(&scimPhoneBook{contacts: []*definition.Contact{&copied}}, "POST", "/Users", `{"userName":"dana@example.com","phoneNumbers":[{"value":"1"}]}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assert.Equal(t, "uniqueness", decode(t, response)["scimType"])
+	})
+
+	t.Run("should answer the validation errors as invalid values", func(t *testing.T) {
+		response := serve(&scimPhoneBook{}, "POST", "/Users", `{"userName":"avi@example.com"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, "invalidValue", decode(t, response)["scimType"])
+	})
+
+	t.Run("should find a user by userName", func(t *testing.T) {
+		copied := *dana
+		pb := &scimPhoneBook{contacts: []*definition.Contact{&copied}}
+		response := serve(pb, "GET", "/Users?filter="+url.QueryEscape(`userName eq "dana@example.com"`), "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		body := decode(t, response)
+		assert.Equal(t, float64(1), body["totalResults"])
+		user := body["Resources"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "dana@example.com", user["userName"])
+		assert.Equal(t, `W/"3"`, user["meta"].(map[string]interface{})["version"])
+
+		response = serve(pb, "GET", "/Users?filter="+url.QueryEscape(`userName eq "avi@example.com"`), "")
+		assert.Equal(t, float64(0), decode(t, response)["totalResults"])
+
+		response = serve(pb, "GET", "/Users?filter="+url.QueryEscape(`title eq "Engineer"`), "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, "invalidFilter", decode(t, response)["scimType"])
+	})
+
+	t.Run("should list the contacts as users", func(t *testing.T) {
+		other := &definition.Contact{ID: primitive.NewObjectID(), FirstName: "Noa", Phone: "0501111111"}
+		copied := *dana
+		response := serve(&scimPhoneBook{contacts: []*definition.Contact{&copied, other}}, "GET", "/Users", "")
+		body := decode(t, response)
+		assert.Equal(t, float64(2), body["totalResults"])
+		users := body["Resources"].([]interface{})
+		assert.Equal(t, other.ID.Hex(), users[1].(map[string]interface{})["userName"])
+	})
+
+	t.Run("should patch the changed fields", func(t *testing.T) {
+		copied := *dana
+		pb := &scimPhoneBook{contacts: []*definition.Contact{&copied}}
+		response := serve(pb, "PATCH", "/Users/"+dana.ID.Hex(), `{"schemas":["`+patchSchema+`"],"Operations":[
+			{"op":"replace","path":"phoneNumbers[type eq \"work\"].value","value":"050-999-8888"},
+			{"op":"remove","path":"title"},
+			{"op":"replace","value":{"name.familyName":"Levi","userName":"dana.levi@example.com"}}]}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		phone := "0509998888"
+		assert.Equal(t, []definition.ContactPatch{{"phone": &phone, "jobTitle": nil}}, pb.patches)
+		assert.Equal(t, "dana.levi@example.com", copied.UserName)
+		assert.Equal(t, "dana.levi@example.com", decode(t, response)["userName"])
+	})
+
+	t.Run("should replace a user", func(t *testing.T) {
+		copied := *dana
+		pb := &scimPhoneBook{contacts: []*definition.Contact{&copied}}
+		response := serve(pb, "PUT", "/Users/"+dana.ID.Hex(), `{"userName":"dana@example.com","name":{"givenName":"Dana","familyName":"Levi"},
+			"phoneNumbers":[{"value":"0521234567"}]}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, []definition.ContactPatch{{"jobTitle": nil}}, pb.patches)
+	})
+
+	t.Run("should delete deactivated users", func(t *testing.T) {
+		copied := *dana
+		pb := &scimPhoneBook{contacts: []*definition.Contact{&copied}}
+		response := serve(pb, "PATCH", "/Users/"+dana.ID.Hex(), `{"Operations":[{"op":"replace","path":"active","value":"False"}]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		assert.Empty(t, pb.contacts)
+
+		response = serve(pb, "DELETE", "/Users/"+dana.ID.Hex(), "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("should answer unknown ids as not found", func(t *testing.T) {
+		response := serve(&scimPhoneBook{}, "GET", "/Users/not-an-id", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Equal(t, []interface{}{errorSchema}, decode(t, response)["schemas"])
+	})
+}
@@ -0,0 +1,169 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/definition"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// patchRequest is a SCIM PatchOp message.
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// valueFilterRegex matches the value filters of the paths to multi-valued
+// attributes, e.g. phoneNumbers[type eq "work"].value. The phone book keeps
+// a value of each, so the filters are dropped.
+var valueFilterRegex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// applyPatch applies the operations of a patch to contact, and returns
+// whether the user is left active. Attributes the phone book doesn't keep
+// are ignored, so providers can patch whole profiles.
+func applyPatch(contact *definition.Contact, operations []patchOperation) (bool, error) {
+	active := true
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return false, newError(http.StatusBadRequest, "invalidSyntax", "unknown patch op "+operation.Op)
+		}
+		if operation.Path != "" {
+			value := operation.Value
+			if op == "remove" {
+				value = nil
+			}
+			if err := applyAttribute(contact, &active, operation.Path, value); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if op == "remove" {
+			return false, newError(http.StatusBadRequest, "noTarget", "remove needs a path")
+		}
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return false, newError(http.StatusBadRequest, "invalidValue", "a patch without a path needs an object value")
+		}
+		for path, value := range attributes {
+			if err := applyAttribute(contact, &active, path, value); err != nil {
+				return false, err
+			}
+		}
+	}
+	return active, nil
+}
+
+// applyAttribute sets the attribute at path to value, or clears it when
+// value is nil.
+func applyAttribute(contact *definition.Contact, active *bool, path string, value json.RawMessage) error {
+	path = strings.ToLower(valueFilterRegex.ReplaceAllString(path, ""))
+	path = strings.TrimPrefix(path, strings.ToLower(userSchema)+":")
+	var target *string
+	switch path {
+	case "username":
+		target = &contact.UserName
+	case "name.givenname":
+		target = &contact.FirstName
+	case "name.familyname":
+		target = &contact.LastName
+	case "title":
+		target = &contact.JobTitle
+	case "emails.value":
+		target = &contact.Email
+	case "phonenumbers.value":
+		target = &contact.Phone
+	case "addresses.formatted":
+		target = &contact.Address
+	case strings.ToLower(enterpriseSchema) + ":organization":
+		target = &contact.Company
+	case "active":
+		if value == nil {
+			return nil
+		}
+		parsed, err := decodeBool(value)
+		if err != nil {
+			return err
+		}
+		*active = parsed
+		return nil
+	case "name", "emails", "phonenumbers", "addresses", strings.ToLower(enterpriseSchema):
+		// a complex or multi-valued attribute as a whole
+		var u user
+		document := []byte(`{}`)
+		if value != nil {
+			document = []byte(`{"` + complexAttributes[path] + `":` + string(value) + `}`)
+		}
+		if err := json.Unmarshal(document, &u); err != nil {
+			return newError(http.StatusBadRequest, "invalidValue", "invalid "+path)
+		}
+		setComplexAttribute(contact, path, u.contact())
+		return nil
+	default:
+		return nil
+	}
+	if value == nil {
+		*target = ""
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return newError(http.StatusBadRequest, "invalidValue", "invalid "+path+", should be a string")
+	}
+	if target == &contact.Phone {
+		text = normalizePhone(text)
+	}
+	*target = text
+	return nil
+}
+
+// complexAttributes are the names of the complex and multi-valued
+// attributes in a User resource, by their lower cased path.
+var complexAttributes = map[string]string{
+	"name":                            "name",
+	"emails":                          "emails",
+	"phonenumbers":                    "phoneNumbers",
+	"addresses":                       "addresses",
+	strings.ToLower(enterpriseSchema): enterpriseSchema,
+}
+
+// setComplexAttribute sets the fields of contact the complex attribute at
+// path maps to from those of provisioned.
+func setComplexAttribute(contact *definition.Contact, path string, provisioned *definition.Contact) {
+	switch path {
+	case "name":
+		contact.FirstName = provisioned.FirstName
+		contact.LastName = provisioned.LastName
+	case "emails":
+		contact.Email = provisioned.Email
+	case "phonenumbers":
+		contact.Phone = provisioned.Phone
+	case "addresses":
+		contact.Address = provisioned.Address
+	default:
+		contact.Company = provisioned.Company
+	}
+}
+
+// decodeBool decodes a boolean, sent as a string by some providers.
+func decodeBool(value json.RawMessage) (bool, error) {
+	var parsed bool
+	if err := json.Unmarshal(value, &parsed); err == nil {
+		return parsed, nil
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		if parsed, err := strconv.ParseBool(text); err == nil {
+			return parsed, nil
+		}
+	}
+	return false, newError(http.StatusBadRequest, "invalidValue", "invalid active, should be a boolean")
+}
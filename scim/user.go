@@ -0,0 +1,189 @@
+package scim
+
+import (
+	"phoneBook/definition"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formattedPhoneRegex matches phone numbers formatted with a country code
+// prefix, spaces, dashes, dots or parentheses, as identity providers keep
+// them.
+var formattedPhoneRegex = regexp.MustCompile(`^\+?[0-9 ().-]+$`)
+
+// user is the SCIM User resource of a contact.
+type user struct {
+	Schemas      []string      `json:"schemas"`
+	ID           string        `json:"id,omitempty"`
+	UserName     string        `json:"userName"`
+	Name         *name         `json:"name,omitempty"`
+	DisplayName  string        `json:"displayName,omitempty"`
+	Title        string        `json:"title,omitempty"`
+	Active       *bool         `json:"active,omitempty"`
+	Emails       []multiValue  `json:"emails,omitempty"`
+	PhoneNumbers []multiValue  `json:"phoneNumbers,omitempty"`
+	Addresses    []address     `json:"addresses,omitempty"`
+	Enterprise   *enterprise   `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta         *resourceMeta `json:"meta,omitempty"`
+}
+
+type name struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+// multiValue is a value of the emails and phoneNumbers attributes.
+type multiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type address struct {
+	Formatted     string `json:"formatted,omitempty"`
+	StreetAddress string `json:"streetAddress,omitempty"`
+	Locality      string `json:"locality,omitempty"`
+	Region        string `json:"region,omitempty"`
+	PostalCode    string `json:"postalCode,omitempty"`
+	Country       string `json:"country,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Primary       bool   `json:"primary,omitempty"`
+}
+
+// text returns the address on a line, formatted by the provider or joined
+// from its parts.
+func (a address) text() string {
+	if a.Formatted != "" {
+		return a.Formatted
+	}
+	var parts []string
+	for _, part := range []string{a.StreetAddress, a.Locality, a.Region, a.PostalCode, a.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type enterprise struct {
+	Organization string `json:"organization,omitempty"`
+}
+
+type resourceMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// newUser returns the User resource of contact, located under base. Contacts
+// not provisioned over SCIM are named by their id.
+func newUser(contact *definition.Contact, base string) *user {
+	id := contact.ID.Hex()
+	active := true
+	u := &user{
+		Schemas:     []string{userSchema},
+		ID:          id,
+		UserName:    contact.UserName,
+		Name:        &name{GivenName: contact.FirstName, FamilyName: contact.LastName},
+		DisplayName: strings.TrimSpace(contact.FirstName + " " + contact.LastName),
+		Title:       contact.JobTitle,
+		Active:      &active,
+		Meta: &resourceMeta{
+			ResourceType: "User",
+			Version:      `W/"` + strconv.FormatInt(contact.Version, 10) + `"`,
+			Location:     base + "/Users/" + id,
+		},
+	}
+	u.Name.Formatted = u.DisplayName
+	if u.UserName == "" {
+		u.UserName = id
+	}
+	if contact.Email != "" {
+		u.Emails = []multiValue{{Value: contact.Email, Type: "work", Primary: true}}
+	}
+	if contact.Phone != "" {
+		u.PhoneNumbers = []multiValue{{Value: contact.Phone, Type: "work", Primary: true}}
+	}
+	if contact.Address != "" {
+		u.Addresses = []address{{Formatted: contact.Address, Type: "work", Primary: true}}
+	}
+	if contact.Company != "" {
+		u.Schemas = append(u.Schemas, enterpriseSchema)
+		u.Enterprise = &enterprise{Organization: contact.Company}
+	}
+	if contact.CreatedAt != nil {
+		u.Meta.Created = contact.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if contact.UpdatedAt != nil {
+		u.Meta.LastModified = contact.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return u
+}
+
+// contact returns the contact fields u provisions. The phone book keeps a
+// phone, email and address per contact, the primary ones or else the first.
+func (u *user) contact() *definition.Contact {
+	contact := &definition.Contact{UserName: u.UserName, JobTitle: u.Title}
+	if u.Name != nil {
+		contact.FirstName = u.Name.GivenName
+		contact.LastName = u.Name.FamilyName
+	}
+	contact.Email = primary(u.Emails)
+	contact.Phone = normalizePhone(primary(u.PhoneNumbers))
+	for i, a := range u.Addresses {
+		if a.Primary || i == 0 {
+			contact.Address = a.text()
+		}
+	}
+	if u.Enterprise != nil {
+		contact.Company = u.Enterprise.Organization
+	}
+	return contact
+}
+
+// primary returns the primary value of values, or the first.
+func primary(values []multiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// normalizePhone keeps the digits of a formatted phone number, which the
+// phone book stores.
+func normalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	if !formattedPhoneRegex.MatchString(phone) {
+		return phone
+	}
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, phone)
+}
+
+// provisionedFields are the contact fields provisioned over SCIM, by the
+// name of their patch.
+func provisionedFields(contact *definition.Contact) map[string]string {
+	return map[string]string{
+		"firstName": contact.FirstName,
+		"lastName":  contact.LastName,
+		"phone":     contact.Phone,
+		"email":     contact.Email,
+		"company":   contact.Company,
+		"jobTitle":  contact.JobTitle,
+		"address":   contact.Address,
+	}
+}
@@ -0,0 +1,253 @@
+// Package search mirrors the phone book to search indexes, for searches the
+// database can't answer well.
+package search
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+)
+
+// Index is the search index the phone book is mirrored to.
+type Index interface {
+	SearchContacts(ctx context.Context, query url.Values) (*definition.ContactPage, string, error)
+	IndexContact(ctx context.Context, contact *definition.Contact) error
+	RemoveContacts(ctx context.Context, ids ...string) error
+	Reindex(ctx context.Context, phoneBook definition.IPhoneBook) (int64, error)
+}
+
+// PhoneBook writes every successful mutation of the phone book it wraps
+// through to the index before answering. The phone book stays the source of
+// truth: failures to index are logged, and the next change of the contact or
+// a rebuild repairs its document. The other methods go straight to the phone
+// book. It searches the index as a definition.ISearchIndex.
+type PhoneBook struct {
+	definition.IPhoneBook
+	index Index
+}
+
+// NewPhoneBook wraps phoneBook, mirroring it to index.
+func NewPhoneBook(phoneBook definition.IPhoneBook, index Index) *PhoneBook {
+	return &PhoneBook{
+		IPhoneBook: phoneBook,
+		index:      index,
+	}
+}
+
+func (pb *PhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.AddContact(ctx, contact, actor)
+	if err == nil {
+		pb.sync(ctx, result.ID)
+	}
+	return result, err
+}
+
+// AddContacts indexes the added contacts as the phone book derived them,
+// rather than reading each back.
+func (pb *PhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	results, err := pb.IPhoneBook.AddContacts(ctx, contacts, actor)
+	indexCtx := context.WithoutCancel(ctx)
+	for i, result := range results {
+		if result.ID == "" {
+			continue
+		}
+		if err := pb.index.IndexContact(indexCtx, contacts[i]); err != nil {
+			logrus.WithError(err).WithField("contactId", result.ID).Error("failed to index contact")
+		}
+	}
+	return results, err
+}
+
+func (pb *PhoneBook) UpdateContact(ctx context.Context, id string, updatedContact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpdateContact(ctx, id, updatedContact, expectedVersion, actor)
+	if err == nil && result.Modified > 0 {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.PatchContact(ctx, id, patch, expectedVersion, actor)
+	if err == nil && result.Modified > 0 {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpsertContact(ctx, id, contact, actor)
+	if err == nil && (result.Created || result.Modified > 0) {
+		pb.sync(ctx, result.ID)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UpsertContactByPhone(ctx, number, contact, actor)
+	if err == nil && (result.Created || result.Modified > 0) {
+		pb.sync(ctx, result.ID)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.DeleteContact(ctx, id, actor)
+	if err == nil && result.Modified > 0 {
+		pb.remove(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	result, err := pb.IPhoneBook.DeleteContacts(ctx, batch, actor)
+	if err == nil && !result.DryRun && len(result.IDs) > 0 {
+		pb.remove(ctx, result.IDs...)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	result, err := pb.IPhoneBook.ApplyRetention(ctx, policy, actor)
+	if err != nil || result.DryRun || len(result.IDs) == 0 {
+		return result, err
+	}
+	if result.Action == definition.RetentionActionPurge {
+		pb.remove(ctx, result.IDs...)
+		return result, err
+	}
+	for _, id := range result.IDs {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	result, err := pb.IPhoneBook.EraseContacts(ctx, phone, actor)
+	if err == nil && len(result.IDs) > 0 {
+		pb.remove(ctx, result.IDs...)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	contact, err := pb.IPhoneBook.UndoContact(ctx, id, actor)
+	if err == nil {
+		pb.sync(ctx, id)
+	}
+	return contact, err
+}
+
+func (pb *PhoneBook) LinkContact(ctx context.Context, id string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.LinkContact(ctx, id, relation, actor)
+	if err == nil && result.Modified > 0 {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.UnlinkContact(ctx, id, relatedID, actor)
+	if err == nil && result.Modified > 0 {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.ArchiveContact(ctx, id, archived, actor)
+	if err == nil && result.Modified > 0 {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	result, err := pb.IPhoneBook.BlockContact(ctx, id, blocked, actor)
+	if err == nil && result.Modified > 0 {
+		pb.sync(ctx, id)
+	}
+	return result, err
+}
+
+func (pb *PhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	result, err := pb.IPhoneBook.ReorderContacts(ctx, order, actor)
+	if err == nil {
+		for _, id := range result.IDs {
+			pb.sync(ctx, id)
+		}
+	}
+	return result, err
+}
+
+// Restore reindexes the phone book in the background, as a restore may
+// replace all of it.
+func (pb *PhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	result, err := pb.IPhoneBook.Restore(ctx, format, mode, r)
+	if err == nil {
+		pb.RebuildInBackground(ctx)
+	}
+	return result, err
+}
+
+// SeedContacts reindexes the phone book in the background, rather than
+// indexing the seeded contacts one by one.
+func (pb *PhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	seeded, err := pb.IPhoneBook.SeedContacts(ctx, count, actor)
+	if err == nil && seeded > 0 {
+		pb.RebuildInBackground(ctx)
+	}
+	return seeded, err
+}
+
+// SearchContacts searches the index.
+func (pb *PhoneBook) SearchContacts(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.index.SearchContacts(ctx, query)
+}
+
+// Rebuild indexes the phone book ctx is scoped to anew.
+func (pb *PhoneBook) Rebuild(ctx context.Context) (int64, error) {
+	return pb.index.Reindex(ctx, pb.IPhoneBook)
+}
+
+// RebuildInBackground indexes the phone book ctx is scoped to anew in the
+// background, logging the outcome.
+func (pb *PhoneBook) RebuildInBackground(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	tenant := definition.TenantFromContext(ctx)
+	go func() {
+		indexed, err := pb.index.Reindex(ctx, pb.IPhoneBook)
+		if err != nil {
+			logrus.WithError(err).WithField("tenant", tenant).Error("failed to reindex the contacts")
+			return
+		}
+		logrus.WithField("tenant", tenant).Infof("reindexed %d contacts", indexed)
+	}()
+}
+
+// sync indexes the contact with the given id as the phone book now has it,
+// with the fields it derived, or removes it when the phone book has none.
+func (pb *PhoneBook) sync(ctx context.Context, id string) {
+	// the mutation happened even when the request was canceled
+	ctx = context.WithoutCancel(ctx)
+	contact, status, err := pb.IPhoneBook.GetContact(ctx, id)
+	if status == core.NotFound {
+		pb.remove(ctx, id)
+		return
+	}
+	if err == nil {
+		err = pb.index.IndexContact(ctx, contact)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("contactId", id).Error("failed to index contact")
+	}
+}
+
+func (pb *PhoneBook) remove(ctx context.Context, ids ...string) {
+	ctx = context.WithoutCancel(ctx)
+	if err := pb.index.RemoveContacts(ctx, ids...); err != nil {
+		logrus.WithError(err).WithField("contactIds", ids).Error("failed to remove contacts from the search index")
+	}
+}
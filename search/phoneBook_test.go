@@ -0,0 +1,83 @@
+package search
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"testing"
+)
+
+// recordingIndex keeps the contacts indexed and the ids removed.
+type recordingIndex struct {
+	indexed []*definition.Contact
+	removed []string
+}
+
+func (ix *recordingIndex) SearchContacts(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return &definition.ContactPage{}, "", nil
+}
+
+func (ix *recordingIndex) IndexContact(ctx context.Context, contact *definition.Contact) error {
+	ix.indexed = append(ix.indexed, contact)
+	return nil
+}
+
+func (ix *recordingIndex) RemoveContacts(ctx context.Context, ids ...string) error {
+	ix.removed = append(ix.removed, ids...)
+	return nil
+}
+
+func (ix *recordingIndex) Reindex(ctx context.Context, phoneBook definition.IPhoneBook) (int64, error) {
+	return 0, nil
+}
+
+// stubPhoneBook serves GetContact from a map and accepts every mutation.
+type stubPhoneBook struct {
+	definition.IPhoneBook
+	contacts map[string]*definition.Contact
+}
+
+func (pb *stubPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	if contact, ok := pb.contacts[id]; ok {
+		return contact, "", nil
+	}
+	return nil, core.NotFound, core.ErrContactNotFound
+}
+
+func (pb *stubPhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *stubPhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	return &definition.BatchDeleteResult{Deleted: int64(len(batch.IDs)), IDs: batch.IDs, DryRun: batch.DryRun}, nil
+}
+
+func TestPhoneBook(t *testing.T) {
+	ctx := context.Background()
+	stored := &definition.Contact{FirstName: "Dani", DisplayName: "dani", Version: 3}
+	phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{"1": stored}}
+
+	t.Run("should index the contact as stored after a change", func(t *testing.T) {
+		index := &recordingIndex{}
+		NewPhoneBook(phoneBook, index).PatchContact(ctx, "1", definition.ContactPatch{"notes": nil}, 2, "dani")
+		assert.Equal(t, []*definition.Contact{stored}, index.indexed)
+	})
+
+	t.Run("should remove a contact the phone book no longer has", func(t *testing.T) {
+		index := &recordingIndex{}
+		NewPhoneBook(phoneBook, index).PatchContact(ctx, "2", definition.ContactPatch{"notes": nil}, 2, "dani")
+		assert.Empty(t, index.indexed)
+		assert.Equal(t, []string{"2"}, index.removed)
+	})
+
+	t.Run("should remove the contacts of a batch delete unless dry run", func(t *testing.T) {
+		index := &recordingIndex{}
+		mirrored := NewPhoneBook(phoneBook, index)
+		mirrored.DeleteContacts(ctx, &definition.BatchDelete{IDs: []string{"1", "2"}, DryRun: true}, "dani")
+		assert.Empty(t, index.removed)
+		mirrored.DeleteContacts(ctx, &definition.BatchDelete{IDs: []string{"1", "2"}}, "dani")
+		assert.Equal(t, []string{"1", "2"}, index.removed)
+	})
+}
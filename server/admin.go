@@ -0,0 +1,26 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// adminMiddleware guards the /admin routes with ADMIN_TOKEN, sent as a bearer
+// token. Without a token configured the admin API is off and its routes
+// answer 404, as they can dump and wipe the whole phone book.
+func (h *httpHandlerStruct) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.cfg.AdminToken == "" {
+			h.handleError(ErrAdminDisabled, w, r, http.StatusNotFound)
+			return
+		}
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			h.handleError(ErrAdminToken, w, r, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+const testAdminToken = "admin-secret"
+
+// adminConfig is the default configuration with the admin API on.
+func adminConfig() config.Config {
+	cfg := config.Default()
+	cfg.AdminToken = testAdminToken
+	return cfg
+}
+
+// asAdmin authenticates r to the admin API.
+func asAdmin(r *http.Request) *http.Request {
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return r
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{"1": {FirstName: "Dani"}}}
+	serve := func(server *Server, r *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, r)
+		return recorder
+	}
+	backup := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/api/v1/admin/backup", nil)
+	}
+	restore := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/api/v1/admin/restore?mode=replace", strings.NewReader("{}\n"))
+	}
+
+	t.Run("should reject backups and restores without the token", func(t *testing.T) {
+		server := NewServer(adminConfig(), phoneBook, events.NewHub())
+		for _, r := range []*http.Request{backup(), restore()} {
+			recorder := serve(server, r)
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code, r.URL.Path)
+			assert.Contains(t, recorder.Body.String(), `"code":"UNAUTHORIZED"`)
+			assert.Equal(t, `Bearer realm="admin"`, recorder.Header().Get("WWW-Authenticate"))
+		}
+		wrong := restore()
+		wrong.Header.Set("Authorization", "Bearer guess")
+		assert.Equal(t, http.StatusUnauthorized, serve(server, wrong).Code)
+	})
+
+	t.Run("should reject the legacy paths without the token", func(t *testing.T) {
+		server := NewServer(adminConfig(), phoneBook, events.NewHub())
+		r := httptest.NewRequest(http.MethodPost, "/admin/restore", strings.NewReader("{}\n"))
+		assert.Equal(t, http.StatusUnauthorized, serve(server, r).Code)
+	})
+
+	t.Run("should serve the admin API with the token", func(t *testing.T) {
+		server := NewServer(adminConfig(), phoneBook, events.NewHub())
+		assert.Equal(t, http.StatusOK, serve(server, asAdmin(restore())).Code)
+		assert.Equal(t, http.StatusOK, serve(server, asAdmin(httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil))).Code)
+	})
+
+	t.Run("should turn the admin API off without a token", func(t *testing.T) {
+		server := NewServer(config.Default(), phoneBook, events.NewHub())
+		recorder := serve(server, asAdmin(restore()))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"ADMIN_DISABLED"`)
+	})
+
+	t.Run("should leave the other routes open", func(t *testing.T) {
+		server := NewServer(adminConfig(), phoneBook, events.NewHub())
+		assert.Equal(t, http.StatusOK, serve(server, httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil)).Code)
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/core"
+)
+
+// @Summary Archive a contact
+// @Description Hides the contact from the listings and searches without deleting it. Archived contacts are still returned by their ID, and by the listings and searches sent includeArchived=true
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {string} string "Message indicating successful archive, or that the contact was archived already"
+// @Failure 400 {object} server.errorResponse "invalid id"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/{id}/archive [post]
+func (h *httpHandlerStruct) ArchiveContact(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// @Summary Unarchive a contact
+// @Description Shows an archived contact in the listings and searches again
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {string} string "Message indicating successful unarchive, or that the contact wasn't archived"
+// @Failure 400 {object} server.errorResponse "invalid id"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/{id}/unarchive [post]
+func (h *httpHandlerStruct) UnarchiveContact(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *httpHandlerStruct) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	params := mux.Vars(r)
+	result, err := h.phoneBook.ArchiveContact(r.Context(), params["id"], archived, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	var response []byte
+	switch {
+	case result.Modified == 0 && archived:
+		response, _ = json.Marshal("contact is archived already")
+	case result.Modified == 0:
+		response, _ = json.Marshal("contact is not archived")
+	case archived:
+		response, _ = json.Marshal("archived contact successfully")
+	default:
+		response, _ = json.Marshal("unarchived contact successfully")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/core"
+)
+
+// @Summary Block a contact
+// @Description Puts the phone of the contact on the blocklist, the numbers not to call or to reject calls from
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {string} string "Message indicating successful block, or that the contact was blocked already"
+// @Failure 400 {object} server.errorResponse "invalid id"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/{id}/block [post]
+func (h *httpHandlerStruct) BlockContact(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, true)
+}
+
+// @Summary Unblock a contact
+// @Description Takes the phone of the contact off the blocklist
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {string} string "Message indicating successful unblock, or that the contact wasn't blocked"
+// @Failure 400 {object} server.errorResponse "invalid id"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/{id}/unblock [post]
+func (h *httpHandlerStruct) UnblockContact(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, false)
+}
+
+func (h *httpHandlerStruct) setBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	params := mux.Vars(r)
+	result, err := h.phoneBook.BlockContact(r.Context(), params["id"], blocked, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	var response []byte
+	switch {
+	case result.Modified == 0 && blocked:
+		response, _ = json.Marshal("contact is blocked already")
+	case result.Modified == 0:
+		response, _ = json.Marshal("contact is not blocked")
+	case blocked:
+		response, _ = json.Marshal("blocked contact successfully")
+	default:
+		response, _ = json.Marshal("unblocked contact successfully")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary List the blocked numbers
+// @Description Returns the phones of the blocked contacts, archived ones included, in the order they were added. number is the phone normalized with its country code, as it is checked
+// @Produce json
+// @Success 200 {array} definition.BlockedNumber
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /blocklist [get]
+func (h *httpHandlerStruct) GetBlocklist(w http.ResponseWriter, r *http.Request) {
+	numbers, status, err := h.phoneBook.GetBlocklist(r.Context())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(numbers)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Check a number against the blocklist
+// @Description Tells whether a phone number is the phone of a blocked contact, for telephony systems to check before dialing or when a call comes in. The number is normalized like for the phone lookup, and the checks are cached when CACHE_ENABLED is set
+// @Produce json
+// @Param number query string true "Phone number, digits optionally formatted with +, spaces, dashes, dots or parentheses"
+// @Success 200 {object} definition.BlockCheck
+// @Failure 400 {object} server.errorResponse "invalid phone number"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /blocklist/check [get]
+func (h *httpHandlerStruct) CheckBlocked(w http.ResponseWriter, r *http.Request) {
+	check, status, err := h.phoneBook.CheckBlocked(r.Context(), r.URL.Query().Get("number"))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(check)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
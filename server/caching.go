@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// listNotModified sets the caching headers of a list of contacts, validated
+// by when the contacts last changed, and answers 304 Not Modified when the
+// copy of the client is still fresh, reporting whether it did. Lists of the
+// storage backends that don't tell when the contacts last changed aren't
+// cached.
+func (h *httpHandlerStruct) listNotModified(w http.ResponseWriter, r *http.Request) bool {
+	lastModified, status, err := h.phoneBook.GetLastModified(r.Context())
+	if err != nil {
+		if extractStatus(status) != http.StatusNotImplemented {
+			logrus.WithError(err).Warn("failed to get when the contacts last changed, serving the list uncached")
+		}
+		return false
+	}
+	cacheControl := "no-cache"
+	if maxAge := int(h.cfg.ListCacheMaxAge.Seconds()); maxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", maxAge)
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	if h.tenants != nil {
+		w.Header().Add("Vary", tenantHeader)
+	}
+	// weak, the proxies may compress the page
+	etag := fmt.Sprintf(`"%d"`, lastModified.UnixMilli())
+	w.Header().Set("ETag", "W/"+etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	notModified := false
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		notModified = etagMatches(ifNoneMatch, etag)
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		// Last-Modified has a precision of a second
+		notModified = !lastModified.Truncate(time.Second).After(since)
+	}
+	if notModified {
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
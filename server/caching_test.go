@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+// changedPhoneBook lists no contacts, last changed at lastModified.
+type changedPhoneBook struct {
+	stubPhoneBook
+	lastModified time.Time
+	listed       int
+}
+
+func (pb *changedPhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	return pb.lastModified, "", nil
+}
+
+func (pb *changedPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	pb.listed++
+	return &definition.ContactPage{Items: []*definition.Contact{}}, "", nil
+}
+
+func (pb *changedPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	pb.listed++
+	return &definition.ContactPage{Items: []*definition.Contact{}}, "", nil
+}
+
+func TestListCaching(t *testing.T) {
+	lastModified := time.Date(2024, 1, 12, 10, 0, 0, 500_000_000, time.UTC)
+	get := func(handler http.Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	t.Run("should validate the lists by the last change", func(t *testing.T) {
+		phoneBook := &changedPhoneBook{lastModified: lastModified}
+		handler := NewServer(config.Default(), phoneBook, events.NewHub()).Handler()
+		for _, target := range []string{"/api/v1/contact", "/api/v1/contact/search?firstName=Dani"} {
+			response := get(handler, target, nil)
+			assert.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "no-cache", response.Header().Get("Cache-Control"))
+			assert.Equal(t, `W/"1705053600500"`, response.Header().Get("ETag"))
+			assert.Equal(t, "Fri, 12 Jan 2024 10:00:00 GMT", response.Header().Get("Last-Modified"))
+		}
+		assert.Equal(t, 2, phoneBook.listed)
+	})
+
+	t.Run("should answer 304 while the contacts are unchanged", func(t *testing.T) {
+		phoneBook := &changedPhoneBook{lastModified: lastModified}
+		handler := NewServer(config.Default(), phoneBook, events.NewHub()).Handler()
+		response := get(handler, "/api/v1/contact", map[string]string{"If-None-Match": `W/"1705053600500"`})
+		assert.Equal(t, http.StatusNotModified, response.Code)
+		assert.Empty(t, response.Body.String())
+		response = get(handler, "/api/v1/contact", map[string]string{"If-Modified-Since": "Fri, 12 Jan 2024 10:00:00 GMT"})
+		assert.Equal(t, http.StatusNotModified, response.Code)
+		assert.Equal(t, 0, phoneBook.listed)
+
+		phoneBook.lastModified = lastModified.Add(time.Second)
+		response = get(handler, "/api/v1/contact", map[string]string{"If-None-Match": `W/"1705053600500"`})
+		assert.Equal(t, http.StatusOK, response.Code)
+		response = get(handler, "/api/v1/contact", map[string]string{"If-Modified-Since": "Fri, 12 Jan 2024 10:00:00 GMT"})
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("should let the proxies cache for the max age", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.ListCacheMaxAge = time.Minute
+		response := get(NewServer(cfg, &changedPhoneBook{lastModified: lastModified}, events.NewHub()).Handler(), "/api/v1/contact", nil)
+		assert.Equal(t, "public, max-age=60", response.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should not cache the lists of the backends not telling the last change", func(t *testing.T) {
+		response := get(NewServer(config.Default(), &slowPhoneBook{}, events.NewHub()).Handler(), "/api/v1/contact/search", nil)
+		assert.Empty(t, response.Header().Get("ETag"))
+		assert.Empty(t, response.Header().Get("Cache-Control"))
+	})
+}
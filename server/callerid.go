@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"phoneBook/definition"
+	"strings"
+)
+
+// callerIDResponse is the caller ID of a number, as PBX phonebook lookups
+// like those of 3CX expect it.
+type callerIDResponse struct {
+	Name    string `json:"name"`
+	Company string `json:"company"`
+}
+
+// @Summary Caller ID lookup for PBX systems
+// @Description Returns the name of the contact with the phone number, for the caller ID lookup sources of PBX systems. format=json (default) returns {name, company} for 3CX style templates, format=text the name alone as FreePBX expects, empty when the number is unknown. Responses are cacheable for CALLERID_MAX_AGE
+// @Produce json
+// @Produce plain
+// @Param number query string true "Phone number of the caller, digits optionally formatted with +, spaces, dashes, dots or parentheses"
+// @Param format query string false "Response format: json (default) or text"
+// @Success 200 {object} server.callerIDResponse
+// @Failure 400 {object} server.errorResponse "invalid phone number or format"
+// @Failure 404 {object} server.errorResponse "unknown number, json format only"
+// @Router /integrations/callerid [get]
+func (h *httpHandlerStruct) CallerID(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "text" {
+		h.handleError(ErrCallerIDFormat, w, r, http.StatusBadRequest)
+		return
+	}
+	contacts, status, err := h.phoneBook.GetContactsByPhone(r.Context(), query.Get("number"))
+	httpStatus := extractStatus(status)
+	if err != nil && httpStatus != http.StatusNotFound {
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	// unknown numbers are looked up as often as known ones, cache both
+	if maxAge := int(h.cfg.CallerIDMaxAge.Seconds()); maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	}
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err == nil {
+			w.Write([]byte(callerID(contacts[0]).Name))
+		}
+		return
+	}
+	if err != nil {
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(callerID(contacts[0]))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// callerID is the caller ID of contact, on a single line.
+func callerID(contact *definition.Contact) *callerIDResponse {
+	name := strings.Join(strings.Fields(contact.FirstName+" "+contact.LastName), " ")
+	return &callerIDResponse{Name: name, Company: contact.Company}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+// callerIDPhoneBook knows the phone of a single contact.
+type callerIDPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *callerIDPhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	if number == "+972521234567" {
+		return []*definition.Contact{{FirstName: "Dani", LastName: "Cohen", Phone: "0521234567"}}, "", nil
+	}
+	return nil, core.NotFound, core.ErrContactNotFound
+}
+
+func TestCallerID(t *testing.T) {
+	lookup := func(query string) *httptest.ResponseRecorder {
+		server := NewServer(config.Default(), &callerIDPhoneBook{}, events.NewHub())
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/integrations/callerid?"+query, nil))
+		return recorder
+	}
+
+	t.Run("should return the name of the caller", func(t *testing.T) {
+		recorder := lookup("number=%2B972521234567")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"name":"Dani Cohen","company":""}`, recorder.Body.String())
+		assert.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should return the name alone as text", func(t *testing.T) {
+		recorder := lookup("number=%2B972521234567&format=text")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "Dani Cohen", recorder.Body.String())
+	})
+
+	t.Run("should return an empty text for unknown numbers", func(t *testing.T) {
+		recorder := lookup("number=0500000000&format=text")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Body.String())
+		assert.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+
+		recorder = lookup("number=0500000000")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		recorder := lookup("number=0500000000&format=xml")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"INVALID_FORMAT"`)
+	})
+}
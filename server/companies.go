@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// @Summary List companies
+// @Description Returns the distinct companies of the contacts in alphabetical order, for autocompletion, up to the server maximum page size
+// @Produce json
+// @Param prefix query string false "Start of the company names, ignoring case"
+// @Success 200 {array} string
+// @Failure 400 {object} server.errorResponse "prefix too long"
+// @Router /company [get]
+func (h *httpHandlerStruct) ListCompanies(w http.ResponseWriter, r *http.Request) {
+	companies, status, err := h.phoneBook.ListCompanies(r.Context(), r.URL.Query().Get("prefix"))
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(companies)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get the contacts of a company
+// @Description Returns everyone working at the company, paginated like the contacts listing
+// @Produce json
+// @Param name path string true "Company name, exactly as listed by /company"
+// @Param page query string false "Page number (default 1)"
+// @Param pageSize query int false "Contacts per page (default 10), clamped to the server maximum"
+// @Param cursor query string false "nextCursor of the previous page, empty for the first page"
+// @Param sort query string false "Listing order: updatedAt or createdAt, most recent first, or displayName, alphabetically. Without sort the pinned contacts are listed first, in their order"
+// @Param count query bool false "Count the total contacts and pages (default true)"
+// @Param fields query string false "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned"
+// @Param includeArchived query bool false "Include the archived contacts (default false)"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Success 200 {object} definition.ContactPage
+// @Header 200 {string} Link "Links to the first, prev, next and last pages"
+// @Failure 400 {object} server.errorResponse "invalid company name, pagination parameters or region"
+// @Router /company/{name}/contacts [get]
+func (h *httpHandlerStruct) GetCompanyContacts(w http.ResponseWriter, r *http.Request) {
+	region, err := h.phoneRegion(r)
+	if err != nil {
+		h.handleError(err, w, r, http.StatusBadRequest)
+		return
+	}
+	params := mux.Vars(r)
+	page, status, err := h.phoneBook.GetCompanyContacts(r.Context(), params["name"], r.URL.Query())
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	formatPhones(region, page.Items...)
+	setLinkHeader(w, r, contactPageLinks(page))
+	response, _ := json.Marshal(page)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
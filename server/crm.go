@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/definition"
+)
+
+var ErrCRMSyncDisabled = definition.NewError("CRM_SYNC_DISABLED", "the crm sync is disabled, set CRM_PROVIDER", "")
+
+// retryResponse is the number of dead letters queued to sync again.
+type retryResponse struct {
+	Retried int `json:"retried"`
+}
+
+// SetCRMSync serves the dead letters of sync on the admin routes. Call it
+// before Start.
+func (s *Server) SetCRMSync(sync definition.ICRMSync) {
+	s.handler.crmSync = sync
+}
+
+// @Summary List the CRM dead letters
+// @Description Lists the contacts that failed to sync to the CRM, after running out of attempts or being refused by the CRM, oldest first. They are kept in memory by each replica
+// @Produce json
+// @Success 200 {array} definition.CRMDeadLetter
+// @Failure 404 {object} server.errorResponse "the crm sync is disabled"
+// @Router /admin/crm/dead-letters [get]
+func (h *httpHandlerStruct) GetCRMDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if h.crmSync == nil {
+		h.handleError(ErrCRMSyncDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	response, _ := json.Marshal(h.crmSync.DeadLetters())
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Retry the CRM dead letters
+// @Description Queues the dead lettered contacts to sync to the CRM again, as they are stored by then
+// @Produce json
+// @Success 200 {object} server.retryResponse
+// @Failure 404 {object} server.errorResponse "the crm sync is disabled"
+// @Router /admin/crm/dead-letters/retry [post]
+func (h *httpHandlerStruct) RetryCRMDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if h.crmSync == nil {
+		h.handleError(ErrCRMSyncDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	response, _ := json.Marshal(retryResponse{Retried: h.crmSync.RetryDeadLetters()})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
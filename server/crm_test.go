@@ -0,0 +1,51 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+// deadLetters is a CRM sync holding dead letters until they are retried.
+type deadLetters struct {
+	letters []*definition.CRMDeadLetter
+}
+
+func (d *deadLetters) DeadLetters() []*definition.CRMDeadLetter {
+	return d.letters
+}
+
+func (d *deadLetters) RetryDeadLetters() int {
+	retried := len(d.letters)
+	d.letters = []*definition.CRMDeadLetter{}
+	return retried
+}
+
+func TestCRMDeadLetters(t *testing.T) {
+	t.Run("should answer 404 when the crm sync is disabled", func(t *testing.T) {
+		server := NewServer(adminConfig(), &stubPhoneBook{}, events.NewHub())
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, asAdmin(httptest.NewRequest("GET", "/api/v1/admin/crm/dead-letters", nil)))
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Contains(t, response.Body.String(), "CRM_SYNC_DISABLED")
+	})
+
+	t.Run("should list and retry the dead letters", func(t *testing.T) {
+		server := NewServer(adminConfig(), &stubPhoneBook{}, events.NewHub())
+		failedAt := time.Date(2024, 1, 12, 10, 0, 0, 0, time.UTC)
+		server.SetCRMSync(&deadLetters{letters: []*definition.CRMDeadLetter{{ContactID: "65a1", Error: "crm responded 400: INVALID_EMAIL", Attempts: 1, FailedAt: failedAt}}})
+
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, asAdmin(httptest.NewRequest("GET", "/api/v1/admin/crm/dead-letters", nil)))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"contactId":"65a1","error":"crm responded 400: INVALID_EMAIL","attempts":1,"failedAt":"2024-01-12T10:00:00Z"}]`, response.Body.String())
+
+		response = httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, asAdmin(httptest.NewRequest("POST", "/api/v1/admin/crm/dead-letters/retry", nil)))
+		assert.JSONEq(t, `{"retried":1}`, response.Body.String())
+	})
+}
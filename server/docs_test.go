@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/docs"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	content, err := docs.UI.ReadFile("swagger.json")
+	assert.Nil(t, err)
+	var spec struct {
+		BasePath string                            `json:"basePath"`
+		Paths    map[string]map[string]interface{} `json:"paths"`
+	}
+	assert.Nil(t, json.Unmarshal(content, &spec))
+	var documented []string
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+spec.BasePath+path)
+		}
+	}
+
+	var registered []string
+	router := NewServer(config.Default(), &stubPhoneBook{}, events.NewHub()).Handler().(*mux.Router)
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, pathErr := route.GetPathTemplate()
+		methods, methodsErr := route.GetMethods()
+		// only the current version is documented, the legacy paths alias it
+		if pathErr != nil || methodsErr != nil || !strings.HasPrefix(path, apiPrefix(legacyVersion)+"/") {
+			return nil
+		}
+		for _, method := range methods {
+			registered = append(registered, method+" "+path)
+		}
+		return nil
+	})
+	assert.ElementsMatch(t, documented, registered, "regenerate the spec with go generate")
+}
+
+func TestDocs(t *testing.T) {
+	server := NewServer(config.Default(), &stubPhoneBook{}, events.NewHub())
+	for path, contentType := range map[string]string{
+		"/":                    "text/html",
+		"/docs/":               "text/html",
+		"/docs/swagger.json":   "application/json",
+		"/docs/swagger-ui.css": "text/css",
+		"/swagger.json":        "application/json",
+	} {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code, path)
+		assert.Contains(t, recorder.Header().Get("Content-Type"), contentType, path)
+	}
+}
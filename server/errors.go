@@ -0,0 +1,120 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"phoneBook/definition"
+	"phoneBook/i18n"
+	"phoneBook/logging"
+	"phoneBook/phonenumber"
+	"strings"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+var (
+	ErrInvalidBody    = definition.NewError("INVALID_BODY", "invalid request body", "")
+	ErrFieldTooLong   = definition.NewError("FIELD_TOO_LONG", "too big contact field", "")
+	ErrInvalidHeader  = definition.NewError("INVALID_HEADER", "invalid request header", "")
+	ErrBodyTooLarge   = definition.NewError("BODY_TOO_LARGE", "request body too large", "")
+	ErrGoogleImport   = definition.NewError("GOOGLE_IMPORT_DISABLED", "google import is not configured", "")
+	ErrOAuthState     = definition.NewError("INVALID_OAUTH_STATE", "invalid or expired oauth state, connect again", "state")
+	ErrOAuthDenied    = definition.NewError("OAUTH_DENIED", "google authorization was denied", "")
+	ErrCallerIDFormat = definition.NewError("INVALID_FORMAT", "invalid format. format should be one of: json, text", "format")
+	ErrSeedDisabled   = definition.NewError("SEED_DISABLED", "seeding is disabled, set SEED_ENABLED", "")
+	ErrInvalidEngine  = definition.NewError("INVALID_ENGINE", "invalid engine. engine should be one of: db, es, embedded", "engine")
+	ErrSearchDisabled = definition.NewError("SEARCH_ENGINE_DISABLED", "the search engine is not configured", "engine")
+	ErrInvalidDryRun  = definition.NewError("INVALID_DRY_RUN", "invalid dryRun. dryRun should be true or false", "dryRun")
+	ErrInvalidUpsert  = definition.NewError("INVALID_UPSERT", "invalid upsert. upsert should be true or false", "upsert")
+	ErrDialFormat     = definition.NewError("INVALID_SPEED_DIAL_FORMAT", "invalid format. format should be one of: xml, csv", "format")
+	ErrInvalidRegion  = definition.NewError("INVALID_REGION", "invalid region. region should be one of: "+strings.Join(phonenumber.Regions(), ", "), "region")
+	ErrAdminDisabled  = definition.NewError("ADMIN_DISABLED", "the admin API is disabled, set ADMIN_TOKEN", "")
+	ErrAdminToken     = definition.NewError("UNAUTHORIZED", "invalid or missing admin token", "")
+)
+
+// errorResponse is the body of every failed request. A request failing on
+// several fields reports the first one, and lists them all in errors.
+type errorResponse struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Field     string        `json:"field,omitempty"`
+	Errors    []*fieldError `json:"errors,omitempty"`
+	RequestID string        `json:"requestId,omitempty"`
+}
+
+// fieldError is the error of a single field, among several.
+type fieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// newErrorResponse reports err with the messages of the typed errors in
+// lang, when its catalog has them.
+func newErrorResponse(err error, status int, requestID string, lang string) *errorResponse {
+	response := &errorResponse{
+		Code:      codeFromStatus(status),
+		Message:   err.Error(),
+		RequestID: requestID,
+	}
+	var typedErr *definition.Error
+	if errors.As(err, &typedErr) {
+		response.Code = typedErr.Code
+		response.Field = typedErr.Field
+		response.Message = i18n.Message(lang, typedErr.Code, response.Message)
+		var errs definition.Errors
+		if errors.As(err, &errs) {
+			messages := make([]string, len(errs))
+			for i, fieldErr := range errs {
+				messages[i] = i18n.Message(lang, fieldErr.Code, fieldErr.Message)
+				response.Errors = append(response.Errors, &fieldError{Code: fieldErr.Code, Message: messages[i], Field: fieldErr.Field})
+			}
+			response.Message = strings.Join(messages, "; ")
+		}
+	} else {
+		// the errors of the database and other services may echo contact data
+		response.Message = logging.Redact(response.Message)
+	}
+	return response
+}
+
+// requestLanguage is the language of the messages answered to r, the one
+// its Accept-Language header prefers among those of the catalogs.
+func requestLanguage(r *http.Request) string {
+	return i18n.Language(r.Header.Get("Accept-Language"))
+}
+
+// codeFromStatus gives errors without a code of their own a generic one.
+func codeFromStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "BAD_REQUEST"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
+	}
+	return "INTERNAL_ERROR"
+}
+
+// requestIDMiddleware tags every request with an ID, taken from the
+// X-Request-ID header when the client sends one, and echoes it back.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+			r.Header.Set(requestIDHeader, requestID)
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// googleStateCookie holds the state of an OAuth2 flow in progress, so the
+// callback can tell it was started by the same browser.
+const googleStateCookie = "google_oauth_state"
+
+// @Summary Connect a Google account
+// @Description Redirects to the Google consent page, which redirects back to /integrations/google/callback to import the contacts of the account. Requires GOOGLE_CLIENT_ID
+// @Success 302
+// @Failure 404 {object} server.errorResponse "google import is not configured"
+// @Router /integrations/google/connect [get]
+func (h *httpHandlerStruct) ConnectGoogle(w http.ResponseWriter, r *http.Request) {
+	if h.google == nil {
+		h.handleError(ErrGoogleImport, w, r, http.StatusNotFound)
+		return
+	}
+	state := newRequestID()
+	http.SetCookie(w, &http.Cookie{
+		Name:     googleStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.google.AuthCodeURL(state), http.StatusFound)
+}
+
+// @Summary Import the contacts of a Google account
+// @Description Google redirects here once the user allowed reading their contacts. Adds the names, phones and emails of the contacts, skipping those whose phone is in the phone book already
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State of the flow started by /integrations/google/connect"
+// @Success 200 {object} definition.ImportResult
+// @Failure 400 {object} server.errorResponse "invalid state or authorization denied"
+// @Failure 404 {object} server.errorResponse "google import is not configured"
+// @Failure 502 {object} server.errorResponse "google rejected the code or failed"
+// @Router /integrations/google/callback [get]
+func (h *httpHandlerStruct) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+	if h.google == nil {
+		h.handleError(ErrGoogleImport, w, r, http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	cookie, err := r.Cookie(googleStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(query.Get("state"))) != 1 {
+		h.handleError(ErrOAuthState, w, r, http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: googleStateCookie, Path: "/", MaxAge: -1})
+	if denied := query.Get("error"); denied != "" {
+		h.handleError(ErrOAuthDenied.WithMessage(ErrOAuthDenied.Message+": "+denied), w, r, http.StatusBadRequest)
+		return
+	}
+	// reading and adding a large address book takes longer than regular requests
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	result, err := h.google.Import(r.Context(), query.Get("code"), extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, http.StatusBadGateway)
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/events"
+	"testing"
+)
+
+func TestGoogleImport(t *testing.T) {
+	cfg := config.Default()
+	cfg.GoogleClientID = "client-id"
+	cfg.GoogleClientSecret = "secret"
+	cfg.GoogleRedirectURL = "http://localhost:8080/api/v1/integrations/google/callback"
+
+	t.Run("should redirect to google with the state of the cookie", func(t *testing.T) {
+		server := NewServer(cfg, &stubPhoneBook{}, events.NewHub())
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/integrations/google/connect", nil))
+		assert.Equal(t, http.StatusFound, recorder.Code)
+		location, err := url.Parse(recorder.Header().Get("Location"))
+		assert.Nil(t, err)
+		assert.Equal(t, "accounts.google.com", location.Host)
+		assert.Equal(t, "client-id", location.Query().Get("client_id"))
+		cookies := recorder.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, googleStateCookie, cookies[0].Name)
+		assert.Equal(t, cookies[0].Value, location.Query().Get("state"))
+	})
+
+	t.Run("should reject a callback with another state", func(t *testing.T) {
+		server := NewServer(cfg, &stubPhoneBook{}, events.NewHub())
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/integrations/google/callback?code=the-code&state=forged", nil)
+		request.AddCookie(&http.Cookie{Name: googleStateCookie, Value: "started"})
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"INVALID_OAUTH_STATE"`)
+	})
+
+	t.Run("should report a denied authorization", func(t *testing.T) {
+		server := NewServer(cfg, &stubPhoneBook{}, events.NewHub())
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/integrations/google/callback?error=access_denied&state=started", nil)
+		request.AddCookie(&http.Cookie{Name: googleStateCookie, Value: "started"})
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"OAUTH_DENIED"`)
+	})
+
+	t.Run("should not be found unless configured", func(t *testing.T) {
+		server := NewServer(config.Default(), &stubPhoneBook{}, events.NewHub())
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/integrations/google/connect", nil))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"GOOGLE_IMPORT_DISABLED"`)
+	})
+}
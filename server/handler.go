@@ -1,185 +1,920 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"io"
+	"net"
 	"net/http"
+	"net/netip"
 	"phoneBook/config"
+	"phoneBook/core"
 	"phoneBook/definition"
+	"phoneBook/events"
+	"phoneBook/google"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type httpHandlerStruct struct {
-	phoneBook *definition.IPhoneBook
+	phoneBook definition.IPhoneBook
+	changes   *events.Hub
+	cfg       config.Config
+	// tunables returns the rate limit and page sizes in use, those of cfg
+	// unless the server follows the reloads.
+	tunables func() config.Tunable
+	limiter  *rateLimiter
+	// google imports Google contacts, nil unless GOOGLE_CLIENT_ID is set.
+	google *google.Importer
+	// ready reports whether the database answers, nil when not watched.
+	ready func() bool
+	// routeTimeouts are the timeouts of ROUTE_TIMEOUTS by route path.
+	routeTimeouts map[string]time.Duration
+	maintenance   atomic.Pointer[maintenanceStatus]
+	// tenants scopes the requests to tenants, nil unless TENANCY_ENABLED.
+	tenants definition.ITenants
+	// tenantLimiter keeps the request quota of each tenant.
+	tenantLimiter *rateLimiter
+	// searchIndexes answer the searches of the engines but the database, es
+	// when SEARCH_URL is set and embedded when EMBEDDED_SEARCH_ENABLED is.
+	searchIndexes map[string]definition.ISearchIndex
+	// crmSync pushes the contact changes to a CRM, nil unless CRM_PROVIDER
+	// is set.
+	crmSync definition.ICRMSync
+	// contactSchema is the JSON Schema the contacts sent are validated
+	// against.
+	contactSchema *schema
+	// jobs runs the admin jobs in the background.
+	jobs *jobRunner
+	// trustedProxies are the TRUSTED_PROXIES, whose X-User is trusted.
+	trustedProxies []netip.Prefix
+	// apiKeys are the API_KEYS clients are told apart by.
+	apiKeys map[string]bool
 }
 
-var httpHandler httpHandlerStruct
-
-func initHttpHandler(phoneBook *definition.IPhoneBook) {
-	if phoneBook == nil {
-		logrus.Fatal("can not init httpHandler - phoneBook is nil")
+func newHttpHandler(cfg config.Config, phoneBook definition.IPhoneBook, changes *events.Hub) *httpHandlerStruct {
+	handler := &httpHandlerStruct{
+		phoneBook:      phoneBook,
+		changes:        changes,
+		cfg:            cfg,
+		tunables:       cfg.Tunables,
+		limiter:        newRateLimiter(),
+		tenantLimiter:  newRateLimiter(),
+		routeTimeouts:  newRouteTimeouts(cfg),
+		jobs:           newJobRunner(cfg.JobConcurrency),
+		trustedProxies: newTrustedProxies(cfg),
+		apiKeys:        newAPIKeys(cfg),
+	}
+	if cfg.GoogleClientID != "" {
+		handler.google = google.NewImporter(phoneBook, cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL)
 	}
-	httpHandler = httpHandlerStruct{
-		phoneBook: phoneBook,
+	handler.maintenance.Store(newMaintenanceStatus(cfg.MaintenanceMode))
+	contactSchema, err := newContactSchema(cfg.MaxSizeProperty)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to build the contact schema from the spec")
 	}
+	handler.contactSchema = contactSchema
+	return handler
 }
 
 // @Summary Get contacts with pagination
-// @Description Retrieve contacts with pagination support, up to 10 contacts for each page
+// @Description Retrieve contacts with pagination support, 10 contacts for each page unless pageSize is sent. Send cursor (empty for the first page) to page by the nextCursor of the previous page instead of by page number, in insertion order. Pages carry an ETag and Last-Modified of the last change to the contacts: send them back in If-None-Match or If-Modified-Since to get 304 Not Modified while the contacts are unchanged
 // @Produce json
+// @Produce application/x-protobuf
 // @Param page query string false "Page number (default 1)"
-// @Success 200 {array} definition.Contact
+// @Param pageSize query int false "Contacts per page (default 10), clamped to the server maximum"
+// @Param cursor query string false "nextCursor of the previous page, empty for the first page"
+// @Param sort query string false "Listing order: updatedAt or createdAt, most recent first, or displayName, alphabetically. Without sort the pinned contacts are listed first, in their order"
+// @Param count query bool false "Count the total contacts and pages (default true)"
+// @Param fields query string false "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned"
+// @Param startsWith query string false "Letter of the alphabetical index to list, e.g. B, or # for the names not starting with a letter"
+// @Param includeArchived query bool false "Include the archived contacts (default false)"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Param If-None-Match header string false "ETag of the cached page"
+// @Param If-Modified-Since header string false "Last-Modified of the cached page"
+// @Success 200 {object} definition.ContactPage
+// @Success 304 "contacts are unchanged"
+// @Header 200 {string} Link "Links to the first, prev, next and last pages"
+// @Failure 400 {object} server.errorResponse "invalid pagination, startsWith or region"
 // @Router /contact [get]
 func (h *httpHandlerStruct) GetContactWithPagination(w http.ResponseWriter, r *http.Request) {
+	if h.listNotModified(w, r) {
+		return
+	}
 	query := r.URL.Query()
-	pageParam := query["page"]
-	result, status, err := (*h.phoneBook).GetContactWithPagination(pageParam)
+	result, status, err := h.phoneBook.GetContactWithPagination(r.Context(), query)
 	if err != nil {
 		httpStatus := extractStatus(status)
-		h.handleError(err, w, httpStatus)
+		h.handleError(err, w, r, httpStatus)
 		return
 	}
-	var response []byte
-	if len(result) == 0 {
-		response, _ = json.Marshal("no contacts were found on this page")
+	setLinkHeader(w, r, contactPageLinks(result))
+	h.writeContacts(w, r, result)
+}
 
-	} else {
-		response, _ = json.Marshal(result)
+// @Summary Get the alphabetical index
+// @Description Returns the number of contacts per starting letter of their last name, or first name when they have none, ignoring case and diacritics, for jump to letter lists. Letters without contacts are left out and # counts the names not starting with a letter. List a letter with GET /contact?startsWith=
+// @Produce json
+// @Success 200 {array} definition.LetterCount
+// @Router /contact/index [get]
+func (h *httpHandlerStruct) GetContactIndex(w http.ResponseWriter, r *http.Request) {
+	letters, status, err := h.phoneBook.GetContactIndex(r.Context())
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
 	}
+	response, _ := json.Marshal(letters)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(response)
 }
 
+// @Summary Get the recent contacts
+// @Description Returns the most recently added contacts, or the most recently updated ones with by=updated, most recent first, for a Recent tab
+// @Produce json
+// @Produce application/x-protobuf
+// @Param by query string false "created (default) or updated"
+// @Param limit query int false "Number of contacts (default 10), clamped to the server maximum"
+// @Param fields query string false "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned"
+// @Param includeArchived query bool false "Include the archived contacts (default false)"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Success 200 {array} definition.Contact
+// @Failure 400 {object} server.errorResponse "invalid by, limit, fields or region"
+// @Router /contact/recent [get]
+func (h *httpHandlerStruct) GetRecentContacts(w http.ResponseWriter, r *http.Request) {
+	contacts, status, err := h.phoneBook.GetRecentContacts(r.Context(), r.URL.Query())
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	h.writeContacts(w, r, contacts)
+}
+
 // @Summary Add a new contact
 // @Description Add a new contact to the phone book
 // @Accept json
+// @Accept application/x-protobuf
 // @Produce json
 // @Param contact body definition.Contact true "Contact object that needs to be added. If you include _id, ensure it is a 24-character string. Alternatively, omit this field from the object, as it will be automatically generated by the database"
 // @Success 200 {string} string "Contact added successfully"
+// @Failure 409 {object} server.errorResponse "a contact with the same details already exists"
+// @Failure 413 {object} server.errorResponse "request body too large"
 // @Router /contact [post]
 func (h *httpHandlerStruct) AddContact(w http.ResponseWriter, r *http.Request) {
-	contact, err := h.decodeContact(r.Body)
+	contact, err := h.decodeContact(r)
 	if err != nil {
-		h.handleError(err, w, http.StatusBadRequest)
+		h.handleError(err, w, r, decodeErrorStatus(err))
 		return
 	}
-	result, status, err := (*h.phoneBook).AddContact(contact)
+	result, err := h.phoneBook.AddContact(r.Context(), contact, extractActor(r))
 	if err != nil {
-		httpStatus := extractStatus(status)
-		h.handleError(err, w, httpStatus)
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
 		return
 	}
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode("Inserted ID: " + result.ID)
 }
 
 // @Summary Delete a contact by ID
 // @Description Deletes a contact by its ID
 // @Param id path string true "Contact ID (24 characters)"
 // @Success 200 {string} string "Message indicating successful deletion"
-// @Failure 500 {string} string "invalid contact"
+// @Failure 429 {object} server.errorResponse "deletes blocked for deleting too many contacts"
+// @Failure 500 {object} server.errorResponse "invalid contact"
 // @Router /contact/delete/{id} [delete]
 func (h *httpHandlerStruct) DeleteContact(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	deleteCount, status, err := (*h.phoneBook).DeleteContact(params["id"])
+	result, err := h.phoneBook.DeleteContact(r.Context(), params["id"], extractActor(r))
 	if err != nil {
-		httpStatus := extractStatus(status)
-		h.handleError(err, w, httpStatus)
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
 		return
 	}
 	var response []byte
-	if deleteCount == 0 {
+	if result.Modified == 0 {
 		response, _ = json.Marshal("not found document to delete")
 	} else {
-		response, _ = json.Marshal(fmt.Sprintf("deleted %d document successfully", deleteCount))
+		response, _ = json.Marshal(fmt.Sprintf("deleted %d document successfully", result.Modified))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Delete contacts in a batch
+// @Description Deletes the contacts listed in ids, or those matching every field of filter exactly, up to 1000 at once. With dryRun only reports the contacts that would be deleted
+// @Accept json
+// @Produce json
+// @Param batch body definition.BatchDelete true "ids or filter of the contacts to delete"
+// @Success 200 {object} definition.BatchDeleteResult
+// @Failure 400 {object} server.errorResponse "invalid batch"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Failure 429 {object} server.errorResponse "deletes blocked for deleting too many contacts"
+// @Router /contact [delete]
+func (h *httpHandlerStruct) DeleteContacts(w http.ResponseWriter, r *http.Request) {
+	var batch definition.BatchDelete
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	result, err := h.phoneBook.DeleteContacts(r.Context(), &batch, extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
 	}
+	response, _ := json.Marshal(result)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(response)
 }
 
 // @Summary Update a contact by ID
-// @Description Updates a contact by its ID
+// @Description Updates a contact by its ID. Send the contact version in If-Match to reject the update if the contact was changed meanwhile.
+// @Description With upsert the contact is added with the ID when it doesn't exist, for sync clients keeping the IDs they assigned, and is validated whole like a new contact. Upserts don't take If-Match
+// @Accept json
+// @Accept application/x-protobuf
 // @Param id path string true "Contact ID (24 characters)"
+// @Param If-Match header string false "Expected contact version"
+// @Param upsert query bool false "Add the contact when it doesn't exist"
 // @Param contact body definition.Contact true "Contact details to update"
-// @Success 200 {string} string "Message indicating successful update"
-// @Failure 500 {string} string "invalid contact"
+// @Success 200 {string} string "Message indicating successful update, or no changes when the contact already holds the values sent"
+// @Success 201 {string} string "ID of the contact upserted"
+// @Failure 400 {object} server.errorResponse "invalid contact or upsert"
+// @Failure 402 {object} server.errorResponse "an upsert would exceed the contact limit of the tenant"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Failure 500 {object} server.errorResponse "invalid contact"
+// @Failure 413 {object} server.errorResponse "request body too large"
 // @Router /contact/edit/{id} [put]
 func (h *httpHandlerStruct) UpdateContact(w http.ResponseWriter, r *http.Request) {
-	updatedContact, err := h.decodeContact(r.Body)
+	expectedVersion, err := extractExpectedVersion(r)
 	if err != nil {
-		h.handleError(err, w, http.StatusBadRequest)
+		h.handleError(err, w, r, http.StatusBadRequest)
 		return
 	}
+	updatedContact, err := h.decodeContact(r)
+	if err != nil {
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	upsert := false
+	if value := r.URL.Query().Get("upsert"); value != "" {
+		upsert, err = strconv.ParseBool(value)
+		if err != nil || upsert && expectedVersion > 0 {
+			h.handleError(ErrInvalidUpsert, w, r, http.StatusBadRequest)
+			return
+		}
+	}
 	params := mux.Vars(r)
-	updatedCount, status, err := (*h.phoneBook).UpdateContact(params["id"], updatedContact)
+	var result *definition.WriteResult
+	if upsert {
+		result, err = h.phoneBook.UpsertContact(r.Context(), params["id"], updatedContact, extractActor(r))
+	} else {
+		result, err = h.phoneBook.UpdateContact(r.Context(), params["id"], updatedContact, expectedVersion, extractActor(r))
+	}
 	if err != nil {
-		httpStatus := extractStatus(status)
-		h.handleError(err, w, httpStatus)
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	h.writeEdited(w, r, result)
+}
+
+// @Summary Partially update a contact by ID
+// @Description Updates only the fields present in the body. A field sent as null is cleared
+// @Accept json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param If-Match header string false "Expected contact version"
+// @Param contact body definition.Contact true "Contact fields to update"
+// @Success 200 {string} string "Message indicating successful update, or no changes when the contact already holds the values sent"
+// @Failure 400 {object} server.errorResponse "invalid contact"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Router /contact/{id} [patch]
+func (h *httpHandlerStruct) PatchContact(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, err := extractExpectedVersion(r)
+	if err != nil {
+		h.handleError(err, w, r, http.StatusBadRequest)
+		return
+	}
+	patch, err := h.decodeContactPatch(r.Body)
+	if err != nil {
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	params := mux.Vars(r)
+	result, err := h.phoneBook.PatchContact(r.Context(), params["id"], patch, expectedVersion, extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	h.writeEdited(w, r, result)
+}
+
+// writeEdited confirms an update or patch. Only a contact that doesn't exist
+// is not found: a contact already holding the values sent is matched but not
+// modified, which is no change rather than an error. Upserts adding the
+// contact are confirmed like a new contact.
+func (h *httpHandlerStruct) writeEdited(w http.ResponseWriter, r *http.Request, result *definition.WriteResult) {
+	if result.Created {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode("Inserted ID: " + result.ID)
+		return
+	}
+	if result.Matched == 0 {
+		h.handleError(core.ErrContactNotFound, w, r, http.StatusNotFound)
 		return
 	}
 	var response []byte
-	if updatedCount == 0 {
-		response, _ = json.Marshal("not found document to edit")
+	if result.Modified == 0 {
+		response, _ = json.Marshal("no changes")
 	} else {
-		response, _ = json.Marshal(fmt.Sprintf("edited %d document successfully", updatedCount))
+		response, _ = json.Marshal(fmt.Sprintf("edited %d document successfully", result.Modified))
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(response)
 }
 
 // @Summary Search contacts
-// @Description Searches for contacts based on parameters (firstName, lastName, phone, address). If no parameters are provided, returns all contacts.
+// @Description Searches for contacts based on parameters (firstName, lastName, phone, email, company, jobTitle, address, notes, namePrefix). Other parameters are rejected. If no parameters are provided, returns the first page of contacts. The number of matching contacts is sent in the X-Total-Count header unless count=false.
+// @Description With engine=es the search runs on the Elasticsearch index instead, and with engine=embedded on the index kept in memory: the fields, and q over all of them, match allowing typos, best matches first. The embedded engine also matches "quoted phrases" exactly.
+// @Description The database results carry an ETag and Last-Modified of the last change to the contacts: send them back in If-None-Match or If-Modified-Since to get 304 Not Modified while the contacts are unchanged.
+// @Produce json
+// @Produce application/x-protobuf
 // @Param firstName query string false "firsName"
 // @Param lastName query string false "lastName"
 // @Param phone query string false "phone"
+// @Param email query string false "email"
+// @Param company query string false "company"
+// @Param jobTitle query string false "jobTitle"
 // @Param address query string false "address"
+// @Param notes query string false "notes"
+// @Param owner query string false "User who added the contact"
+// @Param country query string false "Country of the phone, an ISO code like IL, short for phoneCountry"
+// @Param phoneType query string false "Type of the phone: mobile, fixed, fixedOrMobile, tollFree, sharedCost, premium, voip or other"
+// @Param phoneArea query string false "Area of a fixed line phone, e.g. Jerusalem"
+// @Param namePrefix query string false "Start of the display name, ignoring case and accents, e.g. emi matches Émile. Results are sorted by display name"
+// @Param q query string false "Words to match in any field, with engine=es or embedded only"
+// @Param engine query string false "Search engine, db (default), es or embedded" Enums(db, es, embedded)
+// @Param pageSize query int false "Maximum number of contacts to return (default 10), clamped to the server maximum"
+// @Param page query int false "Page number of pageSize contacts (default 1)"
+// @Param count query bool false "Count the matching contacts (default true)"
+// @Param fields query string false "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned"
+// @Param includeArchived query bool false "Include the archived contacts (default false)"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Param If-None-Match header string false "ETag of the cached results, engine=db only"
+// @Param If-Modified-Since header string false "Last-Modified of the cached results, engine=db only"
 // @Success 200 {array} definition.Contact
+// @Success 304 "contacts are unchanged"
+// @Header 200 {int} X-Total-Count "Number of matching contacts, unless count=false"
+// @Header 200 {string} Link "Links to the first, prev, next and last pages"
+// @Failure 400 {object} server.errorResponse "unknown search field, invalid value or region"
+// @Failure 404 {object} server.errorResponse "engine=es or embedded without the search index configured"
 // @Router /contact/search [get]
 func (h *httpHandlerStruct) SearchContact(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	contacts, status, err := (*h.phoneBook).SearchContact(query)
+	engine := query.Get("engine")
+	query.Del("engine")
+	// region formats the response, it isn't searched
+	query.Del("region")
+	expandSearchAliases(query)
+	var page *definition.ContactPage
+	var status string
+	var err error
+	switch engine {
+	case "", "db":
+		// the search indexes catch up with the changes later, only the
+		// database results are cached
+		if h.listNotModified(w, r) {
+			return
+		}
+		page, status, err = h.phoneBook.SearchContact(r.Context(), query)
+	case "es", "embedded":
+		index, ok := h.searchIndexes[engine]
+		if !ok {
+			h.handleError(searchDisabledError(engine), w, r, http.StatusNotFound)
+			return
+		}
+		page, status, err = index.SearchContacts(r.Context(), query)
+	default:
+		h.handleError(ErrInvalidEngine, w, r, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	// the body stays a plain array for the clients of earlier releases, the
+	// headers telling the totals and pages
+	setLinkHeader(w, r, contactPageLinks(page))
+	if page.TotalItems != nil {
+		w.Header().Set("X-Total-Count", strconv.FormatInt(*page.TotalItems, 10))
+	}
+	h.writeContacts(w, r, page.Items)
+}
+
+// @Summary Full-text search contacts
+// @Description Searches the words of q in firstName, lastName, address and notes, best matches first
+// @Produce json
+// @Produce application/x-protobuf
+// @Param q query string true "Words to search for, e.g. dani cohen tel aviv"
+// @Param pageSize query int false "Maximum number of contacts to return (default 10), clamped to the server maximum"
+// @Param fields query string false "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned"
+// @Param includeArchived query bool false "Include the archived contacts (default false)"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Success 200 {array} definition.Contact
+// @Failure 400 {object} server.errorResponse "missing search text or invalid region"
+// @Router /contact/search/text [get]
+func (h *httpHandlerStruct) SearchContactText(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	contacts, status, err := h.phoneBook.SearchContactText(r.Context(), query)
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	h.writeContacts(w, r, contacts)
+}
+
+// @Summary Count contacts
+// @Description Returns the number of contacts matching the search parameters (firstName, lastName, phone, email, company, jobTitle, address, notes, namePrefix), counted by the database without reading them, for dashboards. Other parameters are rejected. Without parameters counts every contact. Contacts are counted with MongoDB only
+// @Produce json
+// @Param firstName query string false "firstName"
+// @Param lastName query string false "lastName"
+// @Param phone query string false "phone"
+// @Param email query string false "email"
+// @Param company query string false "company"
+// @Param jobTitle query string false "jobTitle"
+// @Param address query string false "address"
+// @Param notes query string false "notes"
+// @Param country query string false "Country of the phone, an ISO code like IL, short for phoneCountry"
+// @Param phoneType query string false "Type of the phone: mobile, fixed, fixedOrMobile, tollFree, sharedCost, premium, voip or other"
+// @Param phoneArea query string false "Area of a fixed line phone, e.g. Jerusalem"
+// @Param namePrefix query string false "Start of the display name, ignoring case and accents"
+// @Param includeArchived query bool false "Include the archived contacts (default false)"
+// @Success 200 {object} server.countResponse
+// @Failure 400 {object} server.errorResponse "unknown search field or invalid value"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/count [get]
+func (h *httpHandlerStruct) CountContacts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	expandSearchAliases(query)
+	count, status, err := h.phoneBook.CountContacts(r.Context(), query)
 	if err != nil {
 		httpStatus := extractStatus(status)
-		h.handleError(err, w, httpStatus)
+		h.handleError(err, w, r, httpStatus)
 		return
 	}
-	response, _ := json.Marshal(contacts)
+	response, _ := json.Marshal(countResponse{Count: count})
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(response)
 }
 
-func (h *httpHandlerStruct) handleError(err error, w http.ResponseWriter, status int) {
-	logrus.WithError(err).Error()
-	w.WriteHeader(status)
-	response, _ := json.Marshal(err.Error())
+// @Summary Get the distinct values of a field
+// @Description Returns the values of field present in the contacts, with the number of contacts holding each, in alphabetical order ignoring case, for filter dropdowns. Values differing in case only are counted together. Up to the server maximum page size values are returned. Addresses can't be listed with field encryption. Values are listed with MongoDB only
+// @Produce json
+// @Param field query string true "Field to list the values of" Enums(address, company, jobTitle, lastName)
+// @Success 200 {array} definition.ValueCount
+// @Failure 400 {object} server.errorResponse "invalid or encrypted field"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/distinct [get]
+func (h *httpHandlerStruct) GetDistinctValues(w http.ResponseWriter, r *http.Request) {
+	values, status, err := h.phoneBook.GetDistinctValues(r.Context(), r.URL.Query().Get("field"))
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(values)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(response)
-	return
 }
 
-func (h *httpHandlerStruct) decodeContact(r io.ReadCloser) (*definition.Contact, error) {
+// @Summary Export contacts as JSON Lines
+// @Description Streams the contacts matching the search parameters (firstName, lastName, phone, email, company, jobTitle, address, notes) as newline delimited JSON, ordered by ID. To resume an interrupted export, send the _id of the last contact received as after
+// @Produce application/x-ndjson
+// @Param firstName query string false "firstName"
+// @Param lastName query string false "lastName"
+// @Param phone query string false "phone"
+// @Param email query string false "email"
+// @Param company query string false "company"
+// @Param jobTitle query string false "jobTitle"
+// @Param address query string false "address"
+// @Param notes query string false "notes"
+// @Param owner query string false "User who added the contact"
+// @Param after query string false "Export the contacts after this contact ID (24 characters)"
+// @Param fields query string false "Comma separated contact fields to return, e.g. firstName,phone. The id is always returned"
+// @Success 200 {file} file "one contact per line"
+// @Failure 400 {object} server.errorResponse "unknown search field, invalid value or invalid after"
+// @Router /contact/export/ndjson [get]
+func (h *httpHandlerStruct) ExportContacts(w http.ResponseWriter, r *http.Request) {
+	// a large export takes longer than the write timeout of regular requests
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	body := &startedWriter{Writer: w}
+	count, status, err := h.phoneBook.ExportContacts(r.Context(), r.URL.Query(), body)
+	if err != nil && !body.started {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	if err != nil {
+		// the status was sent already, drop the connection so the client
+		// can tell the export is incomplete and resume it
+		logrus.WithError(err).WithField("requestId", r.Header.Get(requestIDHeader)).
+			Errorf("export failed after %d contacts", count)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// @Summary Get a contact
+// @Description Returns a contact with its ETag. Send the ETag back in If-None-Match to get 304 Not Modified while the contact is unchanged, or in If-Match to update it only if unchanged
+// @Produce json
+// @Produce application/x-protobuf
+// @Param id path string true "Contact ID (24 characters)"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Param If-None-Match header string false "ETag of the cached contact"
+// @Success 200 {object} definition.Contact
+// @Success 304 "contact is unchanged"
+// @Failure 400 {object} server.errorResponse "invalid region"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Router /contact/{id} [get]
+func (h *httpHandlerStruct) GetContact(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	contact, status, err := h.phoneBook.GetContact(r.Context(), params["id"])
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	etag := contactETag(contact)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.writeContacts(w, r, contact)
+}
+
+// @Summary Look up contacts by phone number
+// @Description Returns the contacts with the phone number, for caller ID lookups. The number is normalized before matching, so +972 52-123-4567, 00972521234567 and 0521234567 find the same contacts
+// @Produce json
+// @Produce application/x-protobuf
+// @Param number path string true "Phone number, digits optionally formatted with +, spaces, dashes, dots or parentheses"
+// @Param region query string false "Region the phones are formatted for as phoneFormatted, e.g. IL or US (default PHONE_REGION)"
+// @Success 200 {array} definition.Contact
+// @Failure 400 {object} server.errorResponse "invalid phone number or region"
+// @Failure 404 {object} server.errorResponse "no contact has this phone number"
+// @Router /contact/by-phone/{number} [get]
+func (h *httpHandlerStruct) GetContactsByPhone(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	contacts, status, err := h.phoneBook.GetContactsByPhone(r.Context(), params["number"])
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	h.writeContacts(w, r, contacts)
+}
+
+// @Summary Upsert a contact by phone number
+// @Description Updates the contact with the phone number like PUT /contact/edit/{id}, or adds it when no contact has the number, for sync clients that don't keep our contact IDs. The number is normalized before matching like for the lookups, and is the phone of the contact unless the body sends it formatted otherwise. The contact is validated whole like a new contact. Contacts are upserted with MongoDB only
+// @Accept json
+// @Accept application/x-protobuf
+// @Param number path string true "Phone number, digits optionally formatted with +, spaces, dashes, dots or parentheses"
+// @Param contact body definition.Contact true "Contact details"
+// @Success 200 {string} string "Message indicating successful update"
+// @Success 201 {string} string "ID of the contact added"
+// @Failure 400 {object} server.errorResponse "invalid phone number or contact, or a phone other than the number"
+// @Failure 402 {object} server.errorResponse "the contact would exceed the contact limit of the tenant"
+// @Failure 409 {object} server.errorResponse "a contact with the same details already exists"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/by-phone/{number} [put]
+func (h *httpHandlerStruct) UpsertContactByPhone(w http.ResponseWriter, r *http.Request) {
+	contact, err := h.decodeContact(r)
+	if err != nil {
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	params := mux.Vars(r)
+	result, err := h.phoneBook.UpsertContactByPhone(r.Context(), params["number"], contact, extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	h.writeEdited(w, r, result)
+}
+
+// @Summary Get the effective configuration
+// @Description Returns the configuration in use, including tunable settings reloaded on SIGHUP. The Mongo URI password is masked
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/config [get]
+func (h *httpHandlerStruct) GetConfig(w http.ResponseWriter, r *http.Request) {
+	response, _ := json.Marshal(config.Effective())
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary List the contacts collection indexes
+// @Description Returns the indexes of the contacts collection, including those created on startup
+// @Produce json
+// @Success 200 {array} definition.Index
+// @Router /admin/indexes [get]
+func (h *httpHandlerStruct) ListIndexes(w http.ResponseWriter, r *http.Request) {
+	indexes, status, err := h.phoneBook.ListIndexes(r.Context())
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(indexes)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Back up the contacts
+// @Description Streams every contact, ordered by ID, as newline delimited canonical extended JSON or as concatenated BSON documents like mongodump writes
+// @Produce application/x-ndjson,application/bson
+// @Param format query string false "Backup format: json (default) or bson"
+// @Success 200 {file} file "the contacts backup"
+// @Failure 400 {object} server.errorResponse "invalid format"
+// @Router /admin/backup [post]
+func (h *httpHandlerStruct) Backup(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = definition.BackupFormatJSON
+	}
+	contentType := "application/x-ndjson"
+	if format == definition.BackupFormatBSON {
+		contentType = "application/bson"
+	}
+	// a large backup takes longer than the write timeout of regular requests
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="contacts-%s.%s"`, time.Now().UTC().Format("20060102T150405Z"), format))
+	body := &startedWriter{Writer: w}
+	count, status, err := h.phoneBook.Backup(r.Context(), format, body)
+	if err != nil && !body.started {
+		w.Header().Del("Content-Disposition")
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	if err != nil {
+		// the status was sent already, drop the connection so the client
+		// can tell the backup is incomplete
+		logrus.WithError(err).WithField("requestId", r.Header.Get(requestIDHeader)).
+			Errorf("backup failed after %d contacts", count)
+		panic(http.ErrAbortHandler)
+	}
+	logrus.Infof("backed up %d contacts", count)
+}
+
+// @Summary Restore the contacts
+// @Description Writes the contacts of a backup made by /admin/backup. merge adds them and overwrites the contacts with the same ID, replace deletes every contact first. An invalid backup is rejected before any contact is written
+// @Accept application/x-ndjson,application/bson
+// @Produce json
+// @Param format query string false "Backup format: json (default) or bson"
+// @Param mode query string false "Restore mode: merge (default) or replace"
+// @Param backup body string true "the contacts backup"
+// @Success 200 {object} definition.RestoreResult
+// @Failure 400 {object} server.errorResponse "invalid backup, format or mode"
+// @Failure 413 {object} server.errorResponse "backup larger than MAX_RESTORE_BYTES"
+// @Router /admin/restore [post]
+func (h *httpHandlerStruct) Restore(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format, mode := query.Get("format"), query.Get("mode")
+	if format == "" {
+		format = definition.BackupFormatJSON
+	}
+	if mode == "" {
+		mode = definition.RestoreModeMerge
+	}
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+	body := &readErrorRecorder{Reader: r.Body}
+	result, err := h.phoneBook.Restore(r.Context(), format, mode, body)
+	var maxBytesErr *http.MaxBytesError
+	if err != nil && errors.As(body.err, &maxBytesErr) {
+		h.handleError(ErrBodyTooLarge, w, r, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// startedWriter remembers whether anything was written.
+type startedWriter struct {
+	io.Writer
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.Writer.Write(p)
+}
+
+// readErrorRecorder remembers the last error reading failed with, so it can
+// be told apart from invalid content.
+type readErrorRecorder struct {
+	io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// @Summary Get contact change history
+// @Description Returns every recorded add, update and delete of a contact, most recent first. Requires the audit log to be enabled
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {array} definition.AuditEntry
+// @Failure 400 {object} server.errorResponse "audit log is disabled"
+// @Router /contact/{id}/history [get]
+func (h *httpHandlerStruct) GetContactHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	entries, status, err := h.phoneBook.GetContactHistory(r.Context(), params["id"])
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	var response []byte
+	if len(entries) == 0 {
+		response, _ = json.Marshal("no history was found for this contact")
+	} else {
+		response, _ = json.Marshal(entries)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Undo the last change of a contact
+// @Description Reverts the most recent update of a contact, or restores it if it was deleted. Requires the audit log to be enabled
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {object} definition.Contact
+// @Failure 404 {object} server.errorResponse "contact has no change to undo"
+// @Failure 409 {object} server.errorResponse "contact already exists"
+// @Router /contact/{id}/undo [post]
+func (h *httpHandlerStruct) UndoContact(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	contact, err := h.phoneBook.UndoContact(r.Context(), params["id"], extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(contact)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+func (h *httpHandlerStruct) handleError(err error, w http.ResponseWriter, r *http.Request, status int) {
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	requestID := r.Header.Get(requestIDHeader)
+	logrus.WithError(err).WithField("requestId", requestID).Error()
+	// whatever the call failed with, it ran out of the route timeout
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		err, status = ErrRequestTimeout, http.StatusGatewayTimeout
+	}
+	lang := requestLanguage(r)
+	response, _ := json.Marshal(newErrorResponse(err, status, requestID, lang))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// decodeContact reads the contact sent in the body of r, as JSON or as
+// protobuf.
+func (h *httpHandlerStruct) decodeContact(r *http.Request) (*definition.Contact, error) {
 	var contact *definition.Contact
-	err := json.NewDecoder(r).Decode(&contact)
+	var err error
+	if sentProtobuf(r) {
+		if contact, err = decodeProtobufContact(r.Body); err != nil {
+			return nil, err
+		}
+	} else if contact, err = decodeJSONContact(r.Body); err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, ErrInvalidBody
+	}
+	err = h.validateContactSizeInput(contact)
 	if err != nil {
 		return nil, err
 	}
-	isValidInput := h.validateContactSizeInput(contact)
-	if !isValidInput {
-		return nil, errors.New("too big contact field")
+	return contact, nil
+}
+
+func (h *httpHandlerStruct) decodeContactPatch(r io.ReadCloser) (definition.ContactPatch, error) {
+	var patch definition.ContactPatch
+	err := json.NewDecoder(r).Decode(&patch)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	err = h.validatePatchSizeInput(patch)
+	if err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// decodeJSONContact decodes a contact sent as JSON, rejecting the fields a
+// contact doesn't have, like misspelled ones, rather than dropping them.
+func decodeJSONContact(r io.Reader) (*definition.Contact, error) {
+	var contact *definition.Contact
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&contact); err != nil {
+		return nil, bodyError(err)
 	}
 	return contact, nil
 }
 
-func (h *httpHandlerStruct) validateContactSizeInput(contact *definition.Contact) bool {
-	if contact == nil {
-		return false
+// bodyError wraps a request body decoding failure in a typed error, naming
+// the field it failed on when there's one.
+func bodyError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrBodyTooLarge
+	}
+	// the json package doesn't type the errors of unknown fields
+	if quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, _ := strconv.Unquote(quoted)
+		return unknownFieldError(field)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return ErrInvalidBody.WithField(typeErr.Field).WithMessage(err.Error())
+	}
+	return ErrInvalidBody.WithMessage(err.Error())
+}
+
+func unknownFieldError(field string) *definition.Error {
+	return core.ErrUnknownField.WithField(field).WithMessage(fmt.Sprintf("%s: %s", core.ErrorUnknownField, field))
+}
+
+func decodeErrorStatus(err error) int {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// contactTextFields are the contact fields sent as text, which are capped at
+// MAX_SIZE_PROPERTY.
+var contactTextFields = []string{"firstName", "lastName", "phone", "email", "company", "jobTitle", "address", "notes"}
+
+func (h *httpHandlerStruct) validateContactSizeInput(contact *definition.Contact) error {
+	// in the order of contactTextFields
+	values := []string{contact.FirstName, contact.LastName, contact.Phone, contact.Email, contact.Company, contact.JobTitle, contact.Address, contact.Notes}
+	var errs definition.Errors
+	for i, value := range values {
+		if len(value) > h.cfg.MaxSizeProperty {
+			errs = append(errs, ErrFieldTooLong.WithField(contactTextFields[i]))
+		}
+	}
+	return errs.Err()
+}
+
+func (h *httpHandlerStruct) validatePatchSizeInput(patch definition.ContactPatch) error {
+	var errs definition.Errors
+	for field, value := range patch {
+		if value != nil && len(*value) > h.cfg.MaxSizeProperty {
+			errs = append(errs, ErrFieldTooLong.WithField(field))
+		}
 	}
-	return len(contact.FirstName) <= config.Static.MaxSizeProperty &&
-		len(contact.LastName) <= config.Static.MaxSizeProperty &&
-		len(contact.Phone) <= config.Static.MaxSizeProperty &&
-		len(contact.Address) <= config.Static.MaxSizeProperty
+	slices.SortFunc(errs, func(a, b *definition.Error) int { return strings.Compare(a.Field, b.Field) })
+	return errs.Err()
 }
 
 func extractStatus(status string) int {
@@ -188,6 +923,70 @@ func extractStatus(status string) int {
 		return http.StatusBadRequest
 	case "InternalServerError":
 		return http.StatusInternalServerError
+	case "Conflict":
+		return http.StatusConflict
+	case "NotFound":
+		return http.StatusNotFound
+	case "PaymentRequired":
+		return http.StatusPaymentRequired
+	case "NotImplemented":
+		return http.StatusNotImplemented
+	case "Gone":
+		return http.StatusGone
+	case "TooManyRequests":
+		return http.StatusTooManyRequests
 	}
 	return -1
 }
+
+// extractActor identifies who performs a mutation, for the audit log: the
+// user a trusted proxy authenticated, otherwise the address of the client.
+// The X-User a client names itself with isn't trusted as the actor.
+func extractActor(r *http.Request) string {
+	if user := definition.UserFromContext(r.Context()); user != "" {
+		return user
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// contactETag identifies a state of a contact by its version and update time.
+func contactETag(contact *definition.Contact) string {
+	var updatedAt int64
+	if contact.UpdatedAt != nil {
+		updatedAt = contact.UpdatedAt.UnixMilli()
+	}
+	return fmt.Sprintf(`"%d-%d"`, contact.Version, updatedAt)
+}
+
+// etagMatches reports whether the If-None-Match header lists etag, ignoring
+// the weak validator prefix.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// extractExpectedVersion reads the contact version the client expects to
+// modify from the If-Match header. 0 means the update is unconditional.
+func extractExpectedVersion(r *http.Request) (int64, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+	ifMatch = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	// an ETag carries the version before the update time
+	ifMatch, _, _ = strings.Cut(ifMatch, "-")
+	version, err := strconv.ParseInt(ifMatch, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalidHeader.WithField("If-Match").WithMessage("invalid If-Match header. expected a contact version or ETag")
+	}
+	return version, nil
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+// editPhoneBook holds contact 1, with the first name Dani.
+type editPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *editPhoneBook) UpdateContact(ctx context.Context, id string, contact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	if id != "1" {
+		return &definition.WriteResult{}, nil
+	}
+	if contact.FirstName == "Dani" {
+		return &definition.WriteResult{Matched: 1}, nil
+	}
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func (pb *editPhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	if id != "1" {
+		return &definition.WriteResult{ID: id, Created: true}, nil
+	}
+	return pb.UpdateContact(ctx, id, contact, 0, actor)
+}
+
+func (pb *editPhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{ID: "2", Created: true}, nil
+}
+
+func TestUpdateContact(t *testing.T) {
+	server := NewServer(config.Default(), &editPhoneBook{}, events.NewHub())
+	request := func(method string, target string, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return response
+	}
+	update := func(id string, body string) *httptest.ResponseRecorder {
+		return request(http.MethodPut, "/api/v1/contact/edit/"+id, body)
+	}
+
+	t.Run("should edit the contact", func(t *testing.T) {
+		response := update("1", `{"firstName": "Dan"}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `"edited 1 document successfully"`, response.Body.String())
+	})
+
+	t.Run("should report no changes to a contact holding the values sent", func(t *testing.T) {
+		response := update("1", `{"firstName": "Dani"}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `"no changes"`, response.Body.String())
+	})
+
+	t.Run("should not find a missing contact", func(t *testing.T) {
+		response := update("2", `{"firstName": "Dan"}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"CONTACT_NOT_FOUND"`)
+	})
+
+	t.Run("should add a missing contact on upsert", func(t *testing.T) {
+		response := update("2?upsert=true", `{"firstName": "Dan"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `"Inserted ID: 2"`, response.Body.String())
+
+		response = update("1?upsert=true", `{"firstName": "Dan"}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `"edited 1 document successfully"`, response.Body.String())
+	})
+
+	t.Run("should reject an upsert expecting a version", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "/api/v1/contact/edit/2?upsert=true", strings.NewReader(`{"firstName": "Dan"}`))
+		r.Header.Set("If-Match", "3")
+		server.Handler().ServeHTTP(response, r)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"INVALID_UPSERT"`)
+		assert.Equal(t, http.StatusBadRequest, update("2?upsert=maybe", `{"firstName": "Dan"}`).Code)
+	})
+
+	t.Run("should upsert by phone", func(t *testing.T) {
+		response := request(http.MethodPut, "/api/v1/contact/by-phone/0521234567", `{"firstName": "Dan"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `"Inserted ID: 2"`, response.Body.String())
+	})
+}
+
+func TestDecodeContact(t *testing.T) {
+	cfg := config.Default()
+	server := NewServer(cfg, &editPhoneBook{}, events.NewHub())
+	update := func(body string) (int, *errorResponse) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodPut, "/api/v1/contact/edit/1", strings.NewReader(body)))
+		var errResponse *errorResponse
+		json.Unmarshal(response.Body.Bytes(), &errResponse)
+		return response.Code, errResponse
+	}
+
+	t.Run("should reject an unknown field", func(t *testing.T) {
+		code, response := update(`{"fristName": "Dan"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "UNKNOWN_FIELD", response.Code)
+		assert.Equal(t, "fristName", response.Field)
+	})
+
+	t.Run("should name the field of the wrong type", func(t *testing.T) {
+		code, response := update(`{"firstName": 7}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "INVALID_BODY", response.Code)
+		assert.Equal(t, "firstName", response.Field)
+	})
+
+	t.Run("should report every field too long", func(t *testing.T) {
+		long := strings.Repeat("a", cfg.MaxSizeProperty+1)
+		code, response := update(`{"firstName": "` + long + `", "notes": "` + long + `"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "FIELD_TOO_LONG", response.Code)
+		assert.Equal(t, "firstName", response.Field)
+		assert.Equal(t, []*fieldError{
+			{Code: "FIELD_TOO_LONG", Message: "too big contact field", Field: "firstName"},
+			{Code: "FIELD_TOO_LONG", Message: "too big contact field", Field: "notes"},
+		}, response.Errors)
+	})
+}
+
+func TestErrorLanguage(t *testing.T) {
+	server := NewServer(config.Default(), &editPhoneBook{}, events.NewHub())
+	update := func(acceptLanguage string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "/api/v1/contact/edit/2", strings.NewReader(`{"firstName": "Dan"}`))
+		r.Header.Set("Accept-Language", acceptLanguage)
+		server.Handler().ServeHTTP(response, r)
+		return response
+	}
+
+	response := update("he-IL,he;q=0.9")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Equal(t, "he", response.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{"code":"CONTACT_NOT_FOUND","message":"איש הקשר לא נמצא","field":"_id","requestId":"`+response.Header().Get(requestIDHeader)+`"}`, response.Body.String())
+
+	response = update("fr")
+	assert.Equal(t, "en", response.Header().Get("Content-Language"))
+	assert.Contains(t, response.Body.String(), `"message":"contact not found"`)
+}
@@ -2,54 +2,182 @@ package server
 
 import (
 	"context"
+	"errors"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
-	"log"
 	"net/http"
 	"phoneBook/config"
 	"phoneBook/definition"
+	"phoneBook/docs"
+	"phoneBook/events"
+	"phoneBook/scim"
+	"phoneBook/web"
 )
 
-var httpServer *http.Server
+// Server is the http API of a phone book. Every server holds its own
+// configuration, handlers and listeners, so several can run in one process.
+type Server struct {
+	cfg     config.Config
+	handler *httpHandlerStruct
+	// httpServer serves the API, redirectServer redirects plain http to it
+	// when TLS and HTTP_REDIRECT_PORT are configured.
+	httpServer     *http.Server
+	redirectServer *http.Server
+}
 
-func StartHTTP(phoneBook *definition.IPhoneBook) *http.Server {
+// NewServer builds the http API serving phoneBook with cfg, streaming the
+// changes of the hub to WebSocket clients. It doesn't listen until Start is
+// called.
+func NewServer(cfg config.Config, phoneBook definition.IPhoneBook, changes *events.Hub) *Server {
+	if phoneBook == nil {
+		logrus.Fatal("can not create http server - phoneBook is nil")
+	}
+	handler := newHttpHandler(cfg, phoneBook, changes)
 	router := mux.NewRouter()
-	initHttpHandler(phoneBook)
-	registerRoutes(router)
-	httpServer = &http.Server{
-		Addr:    config.Static.HTTPServerPort,
-		Handler: router,
+	router.Use(requestIDMiddleware)
+	router.Use(handler.userMiddleware)
+	router.Use(handler.clientMiddleware)
+	router.Use(tracingMiddleware)
+	router.Use(handler.rateLimitMiddleware)
+	router.Use(handler.bodyLimitMiddleware)
+	router.Use(handler.timeoutMiddleware)
+	router.Use(handler.maintenanceMiddleware)
+	router.Use(handler.tenantMiddleware)
+	router.Use(handler.schemaMiddleware)
+	registerRoutes(router, handler)
+	return &Server{
+		cfg:     cfg,
+		handler: handler,
+		httpServer: &http.Server{
+			Addr:         cfg.HTTPServerPort,
+			Handler:      router,
+			ReadTimeout:  cfg.HTTPReadTimeout,
+			WriteTimeout: cfg.HTTPWriteTimeout,
+			IdleTimeout:  cfg.HTTPIdleTimeout,
+		},
 	}
-	go listenAndServe(httpServer)
-	return httpServer
 }
 
-func listenAndServe(server *http.Server) {
-	logrus.Infof("Starting http server on addr %v", config.Static.HTTPServerPort)
-	err := server.ListenAndServe()
+// Handler returns the router of the API, to serve requests without listening.
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// SetTunables makes the server take its rate limit and page sizes from
+// tunables rather than from its configuration, e.g. config.Tunables to
+// follow the reloads. Call it before Start.
+func (s *Server) SetTunables(tunables func() config.Tunable) {
+	s.handler.tunables = tunables
+}
+
+// Start listens on HTTP_SERVER_PORT in the background, with TLS when
+// certificates are configured.
+func (s *Server) Start() {
+	useTLS := s.tlsEnabled()
+	if useTLS {
+		if err := s.configureTLS(); err != nil {
+			logrus.WithError(err).Fatal("failed to configure tls")
+		}
+	}
+	go s.listenAndServe(s.httpServer, useTLS)
+}
+
+func (s *Server) listenAndServe(server *http.Server, useTLS bool) {
+	var err error
+	if useTLS {
+		logrus.Infof("Starting https server on addr %v", server.Addr)
+		// the certificates are already in the tls config of the server
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		logrus.Infof("Starting http server on addr %v", server.Addr)
+		err = server.ListenAndServe()
+	}
 	if err != nil && err != http.ErrServerClosed {
 		logrus.WithError(err).Fatal("failed to start http server")
 	}
 }
 
+// restoreRoute names the restore route, whose body is a whole backup.
+const restoreRoute = "restore"
+
+// bodyLimitMiddleware caps the request body of writes at MAX_BODY_BYTES, of
+// restores at MAX_RESTORE_BYTES and of imports at MAX_IMPORT_BYTES. Streams
+// cap each of their lines instead.
+func (h *httpHandlerStruct) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := h.cfg.MaxBodyBytes
+		if route := mux.CurrentRoute(r); route != nil {
+			switch route.GetName() {
+			case restoreRoute:
+				limit = h.cfg.MaxRestoreBytes
+			case importRoute:
+				limit = h.cfg.MaxImportBytes
+			case streamRoute:
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // @title Phonebook API
-// @description Phonebook API allows users to manage contacts, including add, delete, edit, get with pagination and search
-func registerRoutes(router *mux.Router) {
-	router.HandleFunc("/contact", httpHandler.GetContactWithPagination).Methods("GET")
-	router.HandleFunc("/contact", httpHandler.AddContact).Methods("POST")
-	router.HandleFunc("/contact/edit/{id}", httpHandler.UpdateContact).Methods("PUT")
-	router.HandleFunc("/contact/delete/{id}", httpHandler.DeleteContact).Methods("DELETE")
-	router.HandleFunc("/contact/search", httpHandler.SearchContact).Methods("GET")
-	router.PathPrefix("/docs/").Handler(http.StripPrefix("/docs/", http.FileServer(http.Dir("./docs"))))
+// @description Phonebook API allows users to manage contacts, including add, delete, edit, get with pagination and search.
+// @description The unversioned paths are deprecated aliases of /api/v1.
+// @BasePath /api/v1
+func registerRoutes(router *mux.Router, handler *httpHandlerStruct) {
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	router.HandleFunc("/readyz", handler.Readyz).Methods("GET")
+	registerDocs(router)
+	registerUI(router)
+	if handler.cfg.SCIMToken != "" {
+		scim.Register(router.PathPrefix(scim.BasePath).Subrouter(), handler.phoneBook, handler.cfg.SCIMToken)
+	}
+	// last, as the legacy routes have no path prefix
+	registerAPIVersions(router, handler)
+}
+
+// registerDocs serves the Swagger UI at /docs and the spec at /swagger.json,
+// both embedded in the binary.
+func registerDocs(router *mux.Router) {
+	router.Handle("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently))
+	router.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, docs.UI, "swagger-ui-index.html")
+	})
+	router.PathPrefix("/docs/").Handler(http.StripPrefix("/docs/", http.FileServerFS(docs.UI)))
 	router.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "./docs/swagger.json")
+		http.ServeFileFS(w, r, docs.UI, "swagger.json")
 	})
 }
 
-func Shutdown() {
-	if httpServer != nil {
-		if err := httpServer.Shutdown(context.Background()); err != nil {
-			log.Fatalf("Server shutdown failed: %v", err)
+// registerUI serves the web UI at /, embedded in the binary.
+func registerUI(router *mux.Router) {
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, web.UI, "index.html")
+	}).Methods("GET")
+}
+
+// Stop stops accepting new connections and waits for in-flight requests
+// to finish until ctx is done, then closes the connections still open. The
+// jobs still running are canceled then.
+func (s *Server) Stop(ctx context.Context) error {
+	var shutdownErr error
+	for _, server := range []*http.Server{s.redirectServer, s.httpServer} {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			shutdownErr = errors.Join(shutdownErr, err)
+			server.Close()
 		}
 	}
+	if err := s.handler.jobs.stop(ctx); err != nil {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+	return shutdownErr
 }
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubPhoneBook serves GetContact from a map and doesn't tell when the
+// contacts last changed, the other methods are not implemented.
+type stubPhoneBook struct {
+	definition.IPhoneBook
+	contacts map[string]*definition.Contact
+}
+
+func (pb *stubPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	contact, ok := pb.contacts[id]
+	if !ok {
+		return nil, "NotFound", definition.NewError("CONTACT_NOT_FOUND", "contact not found", "_id")
+	}
+	return contact, "", nil
+}
+
+func (pb *stubPhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	return time.Time{}, "NotImplemented", definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	return nil, definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	return 0, "NotImplemented", definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	return nil, "NotImplemented", definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return nil, definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return nil, definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	return nil, definition.NewError("UNSUPPORTED", "not supported", "")
+}
+
+func (pb *stubPhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	backup, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &definition.RestoreResult{Mode: mode, Restored: int64(bytes.Count(backup, []byte("\n")))}, nil
+}
+
+func TestNewServer(t *testing.T) {
+	t.Run("should serve handlers without listening", func(t *testing.T) {
+		phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{"1": {FirstName: "Dani", Version: 1}}}
+		server := NewServer(config.Default(), phoneBook, events.NewHub())
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/contact/1", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"firstName":"Dani"`)
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/contact/2", nil))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("should keep the configuration of each server apart", func(t *testing.T) {
+		small := config.Default()
+		small.MaxBodyBytes = 8
+		smallServer := NewServer(small, &stubPhoneBook{}, events.NewHub())
+		defaultServer := NewServer(config.Default(), &stubPhoneBook{}, events.NewHub())
+		// malformed, so neither request reaches the phone book
+		body := `{"firstName": "Dani", "lastName": `
+
+		recorder := httptest.NewRecorder()
+		smallServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPatch, "/contact/1", strings.NewReader(body)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+		recorder = httptest.NewRecorder()
+		defaultServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPatch, "/contact/1", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("should keep the rate limit of each server apart", func(t *testing.T) {
+		limited := config.Default()
+		limited.RateLimitPerSecond = 0.01
+		limited.RateLimitBurst = 1
+		unlimited := config.Default()
+		phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{"1": {FirstName: "Dani", Version: 1}}}
+		limitedServer := NewServer(limited, phoneBook, events.NewHub())
+		unlimitedServer := NewServer(unlimited, phoneBook, events.NewHub())
+
+		for i, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			recorder := httptest.NewRecorder()
+			limitedServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/contact/1", nil))
+			assert.Equal(t, expected, recorder.Code, "request %d", i)
+		}
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			unlimitedServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/contact/1", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code, "request %d", i)
+		}
+	})
+
+	t.Run("should serve the api under /api/v1 and deprecate the legacy paths", func(t *testing.T) {
+		phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{"1": {FirstName: "Dani", Version: 1}}}
+		server := NewServer(config.Default(), phoneBook, events.NewHub())
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"firstName":"Dani"`)
+		assert.Empty(t, recorder.Header().Get("Deprecation"))
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/contact/1", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "true", recorder.Header().Get("Deprecation"))
+		assert.Equal(t, `</api/v1/contact/1>; rel="successor-version"`, recorder.Header().Get("Link"))
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Deprecation"))
+	})
+	t.Run("should limit restores by MAX_RESTORE_BYTES", func(t *testing.T) {
+		cfg := adminConfig()
+		cfg.MaxBodyBytes = 8
+		cfg.MaxRestoreBytes = 64
+		server := NewServer(cfg, &stubPhoneBook{}, events.NewHub())
+		backup := strings.Repeat("{}\n", 10)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, asAdmin(httptest.NewRequest(http.MethodPost, "/api/v1/admin/restore?mode=replace", strings.NewReader(backup))))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"mode": "replace", "restored": 10, "failed": 0}`, recorder.Body.String())
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, asAdmin(httptest.NewRequest(http.MethodPost, "/api/v1/admin/restore", strings.NewReader(backup+backup+backup))))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+}
+
+func TestReadyz(t *testing.T) {
+	probe := func(server *Server) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return recorder
+	}
+
+	t.Run("should be ready when not watched", func(t *testing.T) {
+		recorder := probe(NewServer(config.Default(), &stubPhoneBook{}, events.NewHub()))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"status":"ready"}`, recorder.Body.String())
+	})
+
+	t.Run("should follow the readiness", func(t *testing.T) {
+		server := NewServer(config.Default(), &stubPhoneBook{}, events.NewHub())
+		ready := false
+		server.SetReadiness(func() bool { return ready })
+		recorder := probe(server)
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.JSONEq(t, `{"status":"unavailable"}`, recorder.Body.String())
+
+		ready = true
+		assert.Equal(t, http.StatusOK, probe(server).Code)
+	})
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxBodyBytes = 32
+	server := NewServer(cfg, &stubPhoneBook{}, events.NewHub())
+	patch := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPatch, "/api/v1/contact/1", strings.NewReader(body)))
+		return recorder
+	}
+
+	t.Run("should answer 413 to bodies over MAX_BODY_BYTES", func(t *testing.T) {
+		recorder := patch(`{"notes": "` + strings.Repeat("a", 64) + `"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"BODY_TOO_LARGE"`)
+	})
+
+	t.Run("should read bodies up to MAX_BODY_BYTES", func(t *testing.T) {
+		// malformed, so it doesn't reach the phone book once read
+		assert.Equal(t, http.StatusBadRequest, patch(`{"notes": "`+strings.Repeat("a", 16)).Code)
+	})
+}
+
+// delayedPhoneBook takes delay to get a contact.
+type delayedPhoneBook struct {
+	stubPhoneBook
+	delay time.Duration
+}
+
+func (pb *delayedPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	time.Sleep(pb.delay)
+	return &definition.Contact{FirstName: "Dani", Version: 1}, "", nil
+}
+
+func TestServerTimeouts(t *testing.T) {
+	serve := func(t *testing.T, cfg config.Config, phoneBook definition.IPhoneBook) string {
+		server := NewServer(cfg, phoneBook, events.NewHub())
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		go server.httpServer.Serve(listener)
+		t.Cleanup(func() { server.Stop(context.Background()) })
+		return listener.Addr().String()
+	}
+
+	t.Run("should close connections not sending the request within HTTP_READ_TIMEOUT", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.HTTPReadTimeout = 100 * time.Millisecond
+		addr := serve(t, cfg, &stubPhoneBook{})
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		// the headers are never ended
+		_, err = conn.Write([]byte("GET /api/v1/contact/1 HTTP/1.1\r\nHost: phonebook\r\n"))
+		assert.Nil(t, err)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = io.ReadAll(conn)
+		assert.Nil(t, err, "the server should close the connection before the deadline")
+	})
+
+	t.Run("should not answer requests outlasting HTTP_WRITE_TIMEOUT", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.HTTPWriteTimeout = 100 * time.Millisecond
+		addr := serve(t, cfg, &delayedPhoneBook{delay: 300 * time.Millisecond})
+		_, err := http.Get("http://" + addr + "/api/v1/contact/1")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("should answer requests within the timeouts", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.HTTPWriteTimeout = time.Second
+		addr := serve(t, cfg, &delayedPhoneBook{delay: 10 * time.Millisecond})
+		response, err := http.Get("http://" + addr + "/api/v1/contact/1")
+		assert.Nil(t, err)
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	})
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importRoute names the route importing CSV and vCard files, whose body is
+// bounded by MAX_IMPORT_BYTES.
+const importRoute = "import"
+
+// importJob is the type of the jobs importing a file.
+const importJob = "import"
+
+const (
+	// maxImportErrors is the number of errors listed in the result of an
+	// import, the report of the asynchronous ones listing them all.
+	maxImportErrors = 10
+	// maxReportRows bounds the rows of the error report of an import.
+	maxReportRows = 10000
+)
+
+var ErrInvalidAsync = definition.NewError("INVALID_ASYNC", "invalid async. async should be true or false", "async")
+
+// importReportColumns are the columns of the error report of an import.
+var importReportColumns = []string{"row", "code", "field", "error"}
+
+// importResult reports an import, with the number of contacts read from the
+// file. Report links the error report of the asynchronous imports.
+type importResult struct {
+	Rows int64 `json:"rows"`
+	*definition.ImportResult
+	Report string `json:"report,omitempty"`
+}
+
+// contactImport adds the contacts of an imported file batch by batch,
+// counting the rows of each outcome and writing those failed to the report.
+type contactImport struct {
+	h        *httpHandlerStruct
+	actor    string
+	result   *importResult
+	rows     []int64
+	contacts []*definition.Contact
+	report   bytes.Buffer
+	writer   *csv.Writer
+	// reported is the number of rows of the report.
+	reported int
+	// status is that of the phone book when it failed to add a batch.
+	status string
+}
+
+func (h *httpHandlerStruct) newContactImport(actor string) *contactImport {
+	i := &contactImport{h: h, actor: actor, result: &importResult{ImportResult: &definition.ImportResult{}}}
+	i.writer = csv.NewWriter(&i.report)
+	i.writer.Write(importReportColumns)
+	return i
+}
+
+// run imports the contacts of reader, telling progress the rows it went
+// through after each batch.
+func (i *contactImport) run(ctx context.Context, reader importReader, progress func(rows int64)) error {
+	for {
+		contact, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return err
+		}
+		i.result.Rows++
+		if err == nil {
+			err = i.h.validateContactSizeInput(contact)
+		}
+		if err != nil {
+			i.fail(i.result.Rows, err)
+			continue
+		}
+		i.rows = append(i.rows, i.result.Rows)
+		i.contacts = append(i.contacts, contact)
+		if len(i.contacts) == streamBatchSize {
+			if err := i.flush(ctx); err != nil {
+				return err
+			}
+			progress(i.result.Rows)
+		}
+	}
+	if err := i.flush(ctx); err != nil {
+		return err
+	}
+	progress(i.result.Rows)
+	return nil
+}
+
+// flush adds the queued contacts.
+func (i *contactImport) flush(ctx context.Context) error {
+	if len(i.contacts) == 0 {
+		return nil
+	}
+	results, err := i.h.phoneBook.AddContacts(ctx, i.contacts, i.actor)
+	if err != nil {
+		i.status = core.StatusOf(err)
+		return err
+	}
+	for n, result := range results {
+		switch {
+		case result.ID != "":
+			i.result.Imported++
+		case result.Code == core.ErrDuplicateContact.Code:
+			i.result.Duplicates++
+		default:
+			i.fail(i.rows[n], definition.NewError(result.Code, result.Error, ""))
+		}
+	}
+	i.rows, i.contacts = i.rows[:0], i.contacts[:0]
+	return nil
+}
+
+// fail counts the row as failed with err, listing it in the report, and in
+// the result while it has room.
+func (i *contactImport) fail(row int64, err error) {
+	i.result.Failed++
+	if len(i.result.Errors) < maxImportErrors {
+		i.result.Errors = append(i.result.Errors, fmt.Sprintf("row %d: %v", row, err))
+	}
+	if i.reported == maxReportRows {
+		return
+	}
+	i.reported++
+	errs := definition.Errors{definition.NewError("", err.Error(), "")}
+	var typedErrs definition.Errors
+	var typedErr *definition.Error
+	if errors.As(err, &typedErrs) {
+		errs = typedErrs
+	} else if errors.As(err, &typedErr) {
+		errs = definition.Errors{typedErr}
+	}
+	for _, fieldErr := range errs {
+		i.writer.Write([]string{strconv.FormatInt(row, 10), fieldErr.Code, fieldErr.Field, fieldErr.Message})
+	}
+}
+
+// errorReport returns the CSV report of the rows that failed.
+func (i *contactImport) errorReport() []byte {
+	i.writer.Flush()
+	return i.report.Bytes()
+}
+
+// errorStatus is the status of the import failing with err: that of the
+// phone book when it failed to add a batch, of the file otherwise.
+func (i *contactImport) errorStatus(err error) int {
+	if i.status != "" {
+		return extractStatus(i.status)
+	}
+	return decodeErrorStatus(err)
+}
+
+// @Summary Import a CSV or vCard file
+// @Description Adds the contacts of a CSV file with a header row naming the contact fields, like the exports write, or of a vCard file, by the Content-Type. The id, createdAt and updatedAt columns are skipped, other unknown columns reject the file. Contacts duplicating existing ones are skipped, and invalid ones fail with their row, counted from 1 in the order of the file. Up to MAX_IMPORT_BYTES are read. With async=true the file is stored and imported by a background job: the response is 202 with the job, whose GET /admin/jobs/{id} reports the rows imported of total, then the result, and GET /admin/jobs/{id}/report downloads the CSV report of the failed rows. Contacts are imported with MongoDB only
+// @Accept text/csv
+// @Accept text/vcard
+// @Produce json
+// @Param async query bool false "Import in the background and answer with the job (default false)"
+// @Param contacts body string true "The CSV or vCard file"
+// @Success 200 {object} server.importResult
+// @Success 202 {object} definition.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} server.errorResponse "invalid content type, async or CSV header"
+// @Failure 413 {object} server.errorResponse "the file is larger than MAX_IMPORT_BYTES"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/import [post]
+func (h *httpHandlerStruct) ImportContacts(w http.ResponseWriter, r *http.Request) {
+	async := false
+	if value := r.URL.Query().Get("async"); value != "" {
+		var err error
+		if async, err = strconv.ParseBool(value); err != nil {
+			h.handleError(ErrInvalidAsync, w, r, http.StatusBadRequest)
+			return
+		}
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	// the upload, and the import of the synchronous ones, take as long as
+	// the file is large
+	controller := http.NewResponseController(w)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+	if async {
+		h.importInBackground(w, r, contentType)
+		return
+	}
+	reader, err := newImportReader(contentType, r.Body)
+	if err != nil {
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	contactImport := h.newContactImport(extractActor(r))
+	if err := contactImport.run(r.Context(), reader, func(rows int64) {}); err != nil {
+		h.handleError(err, w, r, contactImport.errorStatus(err))
+		return
+	}
+	response, _ := json.Marshal(contactImport.result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// importInBackground stores the file of r in IMPORT_UPLOAD_DIR and starts a
+// job importing it, which deletes it once done.
+func (h *httpHandlerStruct) importInBackground(w http.ResponseWriter, r *http.Request, contentType string) {
+	if _, err := newImportReader(contentType, strings.NewReader("")); errors.Is(err, ErrImportFormat) {
+		h.handleError(err, w, r, http.StatusBadRequest)
+		return
+	}
+	upload, err := os.CreateTemp(h.cfg.ImportUploadDir, "import-*")
+	if err != nil {
+		h.handleError(err, w, r, http.StatusInternalServerError)
+		return
+	}
+	_, err = io.Copy(upload, r.Body)
+	upload.Close()
+	if err != nil {
+		os.Remove(upload.Name())
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	// the header is checked before answering, like the synchronous imports
+	reader, err := h.openImport(upload.Name(), contentType)
+	if err != nil {
+		os.Remove(upload.Name())
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	reader.Close()
+	reportPath := strings.TrimSuffix(r.URL.Path, "/contact/import") + "/admin/jobs/"
+	actor := extractActor(r)
+	job, err := h.jobs.start(r.Context(), importJob, false, func(ctx context.Context, progress *jobProgress) (any, error) {
+		defer os.Remove(upload.Name())
+		return h.importUpload(ctx, progress, upload.Name(), contentType, actor, reportPath+progress.job.ID+"/report")
+	})
+	if err != nil {
+		os.Remove(upload.Name())
+		h.handleError(err, w, r, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", reportPath+job.ID)
+	writeJob(w, r, job, http.StatusAccepted)
+}
+
+// importUpload counts the contacts of the stored file at path, then imports
+// them, attaching the report of the rows that failed to the job.
+func (h *httpHandlerStruct) importUpload(ctx context.Context, progress *jobProgress, path string, contentType string, actor string, reportURL string) (any, error) {
+	progress.step("count")
+	total, err := h.countImport(path, contentType)
+	if err != nil {
+		return nil, err
+	}
+	progress.step("import")
+	progress.count(0, total)
+	reader, err := h.openImport(path, contentType)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	contactImport := h.newContactImport(actor)
+	contactImport.result.Report = reportURL
+	defer func() { progress.attach(contactImport.errorReport()) }()
+	err = contactImport.run(ctx, reader, func(rows int64) { progress.count(rows, total) })
+	if err != nil {
+		return nil, err
+	}
+	return contactImport.result, nil
+}
+
+// countImport counts the contacts of the stored file at path.
+func (h *httpHandlerStruct) countImport(path string, contentType string) (int64, error) {
+	reader, err := h.openImport(path, contentType)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	var total int64
+	for {
+		_, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		var rowErr *rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return 0, err
+		}
+		total++
+	}
+}
+
+// fileImportReader reads the contacts of a stored file.
+type fileImportReader struct {
+	importReader
+	*os.File
+}
+
+func (h *httpHandlerStruct) openImport(path string, contentType string) (*fileImportReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newImportReader(contentType, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileImportReader{importReader: reader, File: file}, nil
+}
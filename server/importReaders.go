@@ -0,0 +1,281 @@
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"phoneBook/definition"
+	"strings"
+)
+
+// The media types of the imported files.
+const (
+	csvContentType   = "text/csv"
+	vCardContentType = "text/vcard"
+	// vCardLegacyContentType is the type of vCard 2.1 and 3.0 files.
+	vCardLegacyContentType = "text/x-vcard"
+)
+
+var (
+	ErrImportFormat     = definition.NewError("INVALID_IMPORT_FORMAT", "invalid content type. send text/csv or text/vcard", "Content-Type")
+	ErrMissingCSVHeader = definition.NewError("MISSING_CSV_HEADER", "the csv file should start with a header row naming the contact fields", "")
+	ErrInvalidRow       = definition.NewError("INVALID_ROW", "invalid row", "")
+)
+
+// maxVCardLine bounds the lines of the vCard files, once unfolded.
+const maxVCardLine = 1 << 20
+
+// importReader reads the contacts of an imported file one at a time.
+type importReader interface {
+	// next returns the next contact, or the error it can't be read with,
+	// wrapped in a rowError when the following contacts can still be read.
+	// It returns io.EOF after the last.
+	next() (*definition.Contact, error)
+}
+
+// rowError is the error of a single contact of an imported file.
+type rowError struct {
+	err error
+}
+
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rowError) Unwrap() error {
+	return e.err
+}
+
+// importFields set the contact fields by the names of the CSV columns, lower
+// cased.
+var importFields = map[string]func(contact *definition.Contact, value string){
+	"firstname": func(contact *definition.Contact, value string) { contact.FirstName = value },
+	"lastname":  func(contact *definition.Contact, value string) { contact.LastName = value },
+	"phone":     func(contact *definition.Contact, value string) { contact.Phone = value },
+	"email":     func(contact *definition.Contact, value string) { contact.Email = value },
+	"company":   func(contact *definition.Contact, value string) { contact.Company = value },
+	"jobtitle":  func(contact *definition.Contact, value string) { contact.JobTitle = value },
+	"address":   func(contact *definition.Contact, value string) { contact.Address = value },
+	"notes":     func(contact *definition.Contact, value string) { contact.Notes = value },
+}
+
+// ignoredImportColumns are written by the exports but set by the phone book,
+// so they're skipped.
+var ignoredImportColumns = map[string]bool{"_id": true, "id": true, "createdat": true, "updatedat": true}
+
+// newImportReader returns the reader of the files of contentType, reading
+// the header of the CSV files already.
+func newImportReader(contentType string, r io.Reader) (importReader, error) {
+	switch contentType {
+	case csvContentType:
+		return newCSVImportReader(r)
+	case vCardContentType, vCardLegacyContentType:
+		return newVCardImportReader(r), nil
+	}
+	return nil, ErrImportFormat
+}
+
+// csvImportReader reads a contact per row of a CSV file, under a header row
+// naming the contact field of each column, like the exports write.
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns []func(contact *definition.Contact, value string)
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrMissingCSVHeader
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns := make([]func(contact *definition.Contact, value string), len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if ignoredImportColumns[strings.ToLower(name)] {
+			continue
+		}
+		set, ok := importFields[strings.ToLower(name)]
+		if !ok {
+			return nil, unknownFieldError(name)
+		}
+		columns[i] = set
+	}
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvImportReader) next() (*definition.Contact, error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &rowError{err: csvError(err)}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return nil, bodyError(err)
+	}
+	contact := &definition.Contact{}
+	for i, value := range record {
+		if set := c.columns[i]; set != nil {
+			set(contact, strings.TrimSpace(value))
+		}
+	}
+	return contact, nil
+}
+
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return ErrInvalidRow.WithMessage(fmt.Sprintf("invalid csv row at line %d: %v", parseErr.StartLine, parseErr.Err))
+	}
+	return bodyError(err)
+}
+
+// vCardImportReader reads the cards of a vCard file, of version 2.1, 3.0 or
+// 4.0, by the properties the exports write: the name, the first phone, email
+// and address, the organization, title and note. The others are skipped.
+type vCardImportReader struct {
+	scanner *bufio.Scanner
+	// unfolded is the line read ahead while unfolding the previous one.
+	unfolded *string
+	// pushed is the line to read again, starting the next card.
+	pushed *string
+}
+
+func newVCardImportReader(r io.Reader) *vCardImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxVCardLine)
+	return &vCardImportReader{scanner: scanner}
+}
+
+// line returns the next line, with the lines folded after it, or io.EOF.
+func (v *vCardImportReader) line() (string, error) {
+	if v.pushed != nil {
+		line := *v.pushed
+		v.pushed = nil
+		return line, nil
+	}
+	var line string
+	if v.unfolded != nil {
+		line, v.unfolded = *v.unfolded, nil
+	} else if v.scanner.Scan() {
+		line = v.scanner.Text()
+	} else if err := v.scanner.Err(); err != nil {
+		return "", bodyError(err)
+	} else {
+		return "", io.EOF
+	}
+	for v.scanner.Scan() {
+		next := v.scanner.Text()
+		if !strings.HasPrefix(next, " ") && !strings.HasPrefix(next, "\t") {
+			v.unfolded = &next
+			break
+		}
+		line += next[1:]
+	}
+	if err := v.scanner.Err(); err != nil {
+		return "", bodyError(err)
+	}
+	return strings.TrimRight(line, "\r"), nil
+}
+
+func (v *vCardImportReader) next() (*definition.Contact, error) {
+	var contact *definition.Contact
+	var fullName string
+	for {
+		line, err := v.line()
+		if errors.Is(err, io.EOF) && contact != nil {
+			return nil, &rowError{err: ErrInvalidRow.WithMessage("the last card has no END:VCARD")}
+		}
+		if err != nil {
+			return nil, err
+		}
+		property, value, ok := strings.Cut(line, ":")
+		name, _, _ := strings.Cut(property, ";")
+		name = strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		// properties may be grouped, e.g. item1.TEL
+		if _, grouped, ok := strings.Cut(name, "."); ok {
+			name = grouped
+		}
+		switch {
+		case !ok:
+			continue
+		case name == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			if contact != nil {
+				v.pushed = &line
+				return nil, &rowError{err: ErrInvalidRow.WithMessage("a card has no END:VCARD")}
+			}
+			contact = &definition.Contact{}
+		case contact == nil:
+			continue
+		case name == "END" && strings.EqualFold(value, "VCARD"):
+			if contact.FirstName == "" && contact.LastName == "" && fullName != "" {
+				contact.FirstName, contact.LastName, _ = strings.Cut(fullName, " ")
+			}
+			return contact, nil
+		case name == "N":
+			components := vCardComponents(value)
+			contact.LastName = components[0]
+			if len(components) > 1 {
+				contact.FirstName = components[1]
+			}
+		case name == "FN":
+			fullName = vCardUnescape(value)
+		case name == "TEL" && contact.Phone == "":
+			contact.Phone = strings.TrimPrefix(vCardUnescape(value), "tel:")
+		case name == "EMAIL" && contact.Email == "":
+			contact.Email = vCardUnescape(value)
+		case name == "ORG":
+			contact.Company = vCardComponents(value)[0]
+		case name == "TITLE":
+			contact.JobTitle = vCardUnescape(value)
+		case name == "NOTE":
+			contact.Notes = vCardUnescape(value)
+		case name == "ADR" && contact.Address == "":
+			var parts []string
+			for _, component := range vCardComponents(value) {
+				if component != "" {
+					parts = append(parts, component)
+				}
+			}
+			contact.Address = strings.Join(parts, ", ")
+		}
+	}
+}
+
+// vCardComponents splits a structured value, like a name, by its unescaped
+// semicolons, unescaping the components.
+func vCardComponents(value string) []string {
+	var components []string
+	var component strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			component.WriteRune('\\')
+			component.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ';':
+			components = append(components, vCardUnescape(component.String()))
+			component.Reset()
+		default:
+			component.WriteRune(r)
+		}
+	}
+	return append(components, vCardUnescape(component.String()))
+}
+
+var vCardUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func vCardUnescape(value string) string {
+	return strings.TrimSpace(vCardUnescaper.Replace(value))
+}
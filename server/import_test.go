@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"phoneBook/config"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+// importPhoneBook adds the contacts with a phone, skipping those of the
+// phone 0500000000 as duplicates.
+type importPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *importPhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	results := make([]*definition.AddResult, len(contacts))
+	for i, contact := range contacts {
+		switch contact.Phone {
+		case "":
+			results[i] = &definition.AddResult{Code: "MISSING_PHONE", Error: core.ErrorMissingPhone}
+		case "0500000000":
+			results[i] = &definition.AddResult{Code: "DUPLICATE_CONTACT", Error: core.ErrorDuplicateContact}
+		default:
+			results[i] = &definition.AddResult{ID: "65a" + contact.Phone}
+		}
+	}
+	return results, nil
+}
+
+// readAll reads the contacts of reader, the errors of the rows in place of
+// their contact.
+func readAll(t *testing.T, reader importReader) ([]*definition.Contact, []error) {
+	var contacts []*definition.Contact
+	var errs []error
+	for {
+		contact, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return contacts, errs
+		}
+		var rowErr *rowError
+		if !assert.True(t, err == nil || errors.As(err, &rowErr), "unexpected error %v", err) {
+			return contacts, errs
+		}
+		contacts = append(contacts, contact)
+		errs = append(errs, err)
+	}
+}
+
+func TestCSVImportReader(t *testing.T) {
+	t.Run("should read the columns of the header", func(t *testing.T) {
+		file := "\ufeffid,Phone,firstName,notes,createdAt\n1,0521234567,Dani,\"a, b\",2024-01-01\n2,05276\"54321,Noa\n3,0529999999,Noa,,\n"
+		reader, err := newImportReader(csvContentType, strings.NewReader(file))
+		assert.Nil(t, err)
+		contacts, errs := readAll(t, reader)
+		assert.Len(t, contacts, 3)
+		assert.Equal(t, &definition.Contact{FirstName: "Dani", Phone: "0521234567", Notes: "a, b"}, contacts[0])
+		assert.ErrorIs(t, errs[1], ErrInvalidRow)
+		assert.Nil(t, errs[2])
+		assert.Equal(t, "0529999999", contacts[2].Phone)
+	})
+
+	t.Run("should reject unknown columns", func(t *testing.T) {
+		_, err := newImportReader(csvContentType, strings.NewReader("firstName,nickname\n"))
+		assert.ErrorIs(t, err, core.ErrUnknownField)
+		assert.Equal(t, "nickname", err.(*definition.Error).Field)
+	})
+
+	t.Run("should require the header", func(t *testing.T) {
+		_, err := newImportReader(csvContentType, strings.NewReader(""))
+		assert.ErrorIs(t, err, ErrMissingCSVHeader)
+		_, err = newImportReader("application/json", strings.NewReader(""))
+		assert.ErrorIs(t, err, ErrImportFormat)
+	})
+}
+
+func TestVCardImportReader(t *testing.T) {
+	file := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"N:Cohen;Dani;;;",
+		"FN:Dani Cohen",
+		"item1.TEL;TYPE=CELL:0521234567",
+		"TEL;TYPE=WORK:031234567",
+		"EMAIL:dani@example.com",
+		"ORG:Acme\\, Inc.;R&D",
+		"TITLE:Engineer",
+		"NOTE:first line\\nsecond",
+		"  line",
+		"ADR;TYPE=HOME:;;1 Herzl St;Tel Aviv;;;Israel",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"FN:Noa Levi",
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"FN:Yael",
+		"TEL;VALUE=uri:tel:0527654321",
+		"END:VCARD",
+	}, "\r\n")
+	contacts, errs := readAll(t, newVCardImportReader(strings.NewReader(file)))
+	assert.Len(t, contacts, 3)
+	assert.Equal(t, &definition.Contact{
+		FirstName: "Dani",
+		LastName:  "Cohen",
+		Phone:     "0521234567",
+		Email:     "dani@example.com",
+		Company:   "Acme, Inc.",
+		JobTitle:  "Engineer",
+		Address:   "1 Herzl St, Tel Aviv, Israel",
+		Notes:     "first line\nsecond line",
+	}, contacts[0])
+	assert.ErrorIs(t, errs[1], ErrInvalidRow, "the card with no END should fail alone")
+	assert.Equal(t, &definition.Contact{FirstName: "Yael", Phone: "0527654321"}, contacts[2])
+}
+
+func TestImportContacts(t *testing.T) {
+	importFile := func(server *Server, target string, contentType string, file string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(file))
+		request.Header.Set("Content-Type", contentType)
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, request)
+		return response
+	}
+	file := "firstName,phone\nDani,0521234567\nNoa,\nYael,0500000000\n"
+
+	t.Run("should import the file and report the failed rows", func(t *testing.T) {
+		server := NewServer(config.Default(), &importPhoneBook{}, events.NewHub())
+		response := importFile(server, "/api/v1/contact/import", "text/csv; charset=utf-8", file)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"rows":3,"imported":1,"duplicates":1,"failed":1,"errors":["row 2: `+core.ErrorMissingPhone+`"]}`, response.Body.String())
+	})
+
+	t.Run("should reject the invalid files before importing", func(t *testing.T) {
+		server := NewServer(config.Default(), &importPhoneBook{}, events.NewHub())
+		response := importFile(server, "/api/v1/contact/import", "application/json", file)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"INVALID_IMPORT_FORMAT"`)
+
+		response = importFile(server, "/api/v1/contact/import?async=true", "text/csv", "firstName,nickname\n")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"UNKNOWN_FIELD"`)
+
+		response = importFile(server, "/api/v1/contact/import?async=yes", "text/csv", file)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"INVALID_ASYNC"`)
+
+		cfg := config.Default()
+		cfg.MaxImportBytes = 16
+		response = importFile(NewServer(cfg, &importPhoneBook{}, events.NewHub()), "/api/v1/contact/import?async=true", "text/csv", file)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	})
+
+	t.Run("should import in the background with async", func(t *testing.T) {
+		cfg := adminConfig()
+		cfg.ImportUploadDir = t.TempDir()
+		server := NewServer(cfg, &importPhoneBook{}, events.NewHub())
+		client := jobsClient{t: t, server: server}
+		response := importFile(server, "/api/v1/contact/import?async=true", "text/vcard", "BEGIN:VCARD\nFN:Dani\nTEL:0521234567\nEND:VCARD\nBEGIN:VCARD\nFN:Noa\nEND:VCARD\n")
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		var job *definition.Job
+		json.Unmarshal(response.Body.Bytes(), &job)
+		assert.Equal(t, importJob, job.Type)
+		assert.Equal(t, "/api/v1/admin/jobs/"+job.ID, response.Header().Get("Location"))
+
+		job = client.await(job.ID, definition.JobSucceeded)
+		assert.Equal(t, "import", job.Step)
+		assert.Equal(t, int64(2), job.Done)
+		assert.Equal(t, int64(2), job.Total)
+		var result importResult
+		json.Unmarshal(job.Result, &result)
+		assert.Equal(t, int64(1), result.Imported)
+		assert.Equal(t, int64(1), result.Failed)
+		assert.Equal(t, "/api/v1/admin/jobs/"+job.ID+"/report", result.Report)
+
+		response = client.request(http.MethodGet, result.Report, nil)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "text/csv", response.Header().Get("Content-Type"))
+		assert.Equal(t, "row,code,field,error\n2,MISSING_PHONE,,"+core.ErrorMissingPhone+"\n", response.Body.String())
+		uploads, _ := os.ReadDir(cfg.ImportUploadDir)
+		assert.Empty(t, uploads, "the upload should be deleted")
+	})
+
+	t.Run("should not find the report of jobs without one", func(t *testing.T) {
+		server := NewServer(adminConfig(), &rebuildPhoneBook{release: make(chan struct{})}, events.NewHub())
+		client := jobsClient{t: t, server: server}
+		var job *definition.Job
+		client.request(http.MethodPost, "/api/v1/admin/reindex", &job)
+		response := client.request(http.MethodGet, "/api/v1/admin/jobs/"+job.ID+"/report", nil)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"JOB_REPORT_NOT_FOUND"`)
+		server.Stop(context.Background())
+	})
+}
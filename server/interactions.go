@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+)
+
+// @Summary Record an interaction with a contact
+// @Description Records a call, meeting or note on the timeline of the contact. occurredAt defaults to now and can't be in the future
+// @Accept json
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param interaction body definition.Interaction true "The interaction type (call, meeting or note), summary and optional occurredAt. The _id, contactId, actor and createdAt are set by the server"
+// @Success 200 {string} string "ID of the recorded interaction"
+// @Failure 400 {object} server.errorResponse "invalid interaction"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Router /contact/{id}/interactions [post]
+func (h *httpHandlerStruct) AddInteraction(w http.ResponseWriter, r *http.Request) {
+	var interaction *definition.Interaction
+	if err := json.NewDecoder(r.Body).Decode(&interaction); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	if interaction == nil {
+		h.handleError(ErrInvalidBody, w, r, http.StatusBadRequest)
+		return
+	}
+	if len(interaction.Summary) > h.cfg.MaxSizeProperty {
+		h.handleError(ErrFieldTooLong.WithField("summary"), w, r, http.StatusBadRequest)
+		return
+	}
+	params := mux.Vars(r)
+	result, err := h.phoneBook.AddInteraction(r.Context(), params["id"], interaction, extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result.ID)
+}
+
+// @Summary Get the interactions with a contact
+// @Description Returns a page of the timeline of the contact, most recent first. The timeline of a deleted contact is kept until the contact is restored
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param page query int false "Page number (default 1)"
+// @Param pageSize query int false "Interactions per page, up to MAX_PAGE_SIZE (default LIMIT_PER_PAGE)"
+// @Param count query bool false "Whether to count the interactions for totalItems and totalPages (default true)"
+// @Success 200 {object} definition.InteractionPage
+// @Header 200 {string} Link "Links to the first, prev, next and last pages"
+// @Failure 400 {object} server.errorResponse "invalid id or pagination"
+// @Router /contact/{id}/interactions [get]
+func (h *httpHandlerStruct) GetInteractions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	page, status, err := h.phoneBook.GetInteractions(r.Context(), params["id"], r.URL.Query())
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	setLinkHeader(w, r, interactionPageLinks(page))
+	response, _ := json.Marshal(page)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Delete an interaction with a contact
+// @Description Removes an interaction from the timeline of the contact
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param interactionId path string true "Interaction ID (24 characters)"
+// @Success 200 {string} string "Message indicating successful deletion"
+// @Failure 400 {object} server.errorResponse "invalid id"
+// @Failure 404 {object} server.errorResponse "interaction not found"
+// @Router /contact/{id}/interactions/{interactionId} [delete]
+func (h *httpHandlerStruct) DeleteInteraction(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	_, err := h.phoneBook.DeleteInteraction(r.Context(), params["id"], params["interactionId"])
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal("deleted interaction successfully")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,429 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/i18n"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrJobRunning       = definition.NewError("JOB_RUNNING", "a job of this type is already running, wait for it to finish", "")
+	ErrJobFinished      = definition.NewError("JOB_FINISHED", "the job already finished", "id")
+	ErrJobElsewhere     = definition.NewError("JOB_ON_OTHER_REPLICA", "the job runs on another replica, which alone can cancel it", "id")
+	ErrInvalidJobStatus = definition.NewError("INVALID_JOB_STATUS", "invalid status. status should be one of: queued, running, succeeded, failed, canceled", "status")
+	ErrNoJobReport      = definition.NewError("JOB_REPORT_NOT_FOUND", "the job has no report", "id")
+)
+
+// jobStatuses are the states the jobs can be listed by.
+var jobStatuses = []string{definition.JobQueued, definition.JobRunning, definition.JobSucceeded, definition.JobFailed, definition.JobCanceled}
+
+// reindexJob is the type of the jobs rebuilding the derived data.
+const reindexJob = "reindex"
+
+// jobFunc runs a job until ctx is canceled, reporting its progress on
+// progress, and returns its result.
+type jobFunc func(ctx context.Context, progress *jobProgress) (any, error)
+
+// jobRunner runs the admin jobs in the background, up to JOB_CONCURRENCY at
+// once while the others wait queued, and records them in jobs as they go.
+type jobRunner struct {
+	jobs definition.IJobs
+	// replica names the replica running the jobs, its host name.
+	replica string
+	slots   chan struct{}
+	mu      sync.Mutex
+	// running are the jobs queued and running on this replica, by id.
+	running map[string]*runningJob
+	wg      sync.WaitGroup
+}
+
+type runningJob struct {
+	job    *definition.Job
+	cancel context.CancelFunc
+}
+
+func newJobRunner(concurrency int) *jobRunner {
+	replica, _ := os.Hostname()
+	return &jobRunner{
+		jobs:    core.NewMemoryJobs(),
+		replica: replica,
+		slots:   make(chan struct{}, max(concurrency, 1)),
+		running: map[string]*runningJob{},
+	}
+}
+
+// start queues a job of jobType running run on behalf of the tenant and user
+// of ctx, and returns its record. An exclusive job isn't started while
+// another of its type runs for the tenant on this replica.
+func (jr *jobRunner) start(ctx context.Context, jobType string, exclusive bool, run jobFunc) (*definition.Job, error) {
+	tenant := definition.TenantFromContext(ctx)
+	jr.mu.Lock()
+	if exclusive {
+		for _, running := range jr.running {
+			if running.job.Type == jobType && running.job.Tenant == tenant {
+				jr.mu.Unlock()
+				return nil, ErrJobRunning
+			}
+		}
+	}
+	job := &definition.Job{
+		ID:        newRequestID(),
+		Type:      jobType,
+		Tenant:    tenant,
+		User:      definition.UserFromContext(ctx),
+		Replica:   jr.replica,
+		Status:    definition.JobQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	// the job outlives the request, but not its tenant and user
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	jr.running[job.ID] = &runningJob{job: job, cancel: cancel}
+	jr.wg.Add(1)
+	queued := *job
+	jr.mu.Unlock()
+	if err := jr.jobs.SaveJob(ctx, &queued); err != nil {
+		jr.forget(job.ID)
+		cancel()
+		jr.wg.Done()
+		return nil, err
+	}
+	go jr.run(ctx, job, run)
+	return &queued, nil
+}
+
+// run runs job once a slot frees, unless it's canceled meanwhile.
+func (jr *jobRunner) run(ctx context.Context, job *definition.Job, run jobFunc) {
+	defer jr.wg.Done()
+	defer jr.forget(job.ID)
+	select {
+	case jr.slots <- struct{}{}:
+		defer func() { <-jr.slots }()
+	case <-ctx.Done():
+		jr.finish(ctx, job, nil, ctx.Err())
+		return
+	}
+	jr.update(job, func(job *definition.Job) {
+		now := time.Now().UTC()
+		job.Status = definition.JobRunning
+		job.StartedAt = &now
+	})
+	result, err := run(ctx, &jobProgress{runner: jr, job: job})
+	jr.finish(ctx, job, result, err)
+}
+
+// finish records the end of job: canceled when ctx was, failed with err, or
+// succeeded with result.
+func (jr *jobRunner) finish(ctx context.Context, job *definition.Job, result any, err error) {
+	var encoded json.RawMessage
+	if err == nil && result != nil {
+		encoded, err = json.Marshal(result)
+	}
+	if err != nil && ctx.Err() == nil {
+		logrus.WithError(err).Errorf("%s job %s failed", job.Type, job.ID)
+	}
+	jr.update(job, func(job *definition.Job) {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		switch {
+		case ctx.Err() != nil:
+			job.Status = definition.JobCanceled
+		case err != nil:
+			job.Status = definition.JobFailed
+			failure := newErrorResponse(err, extractStatus(core.StatusOf(err)), "", i18n.DefaultLanguage)
+			job.Error = &definition.JobError{Code: failure.Code, Message: failure.Message, Field: failure.Field}
+		default:
+			job.Status = definition.JobSucceeded
+			job.Result = encoded
+		}
+	})
+}
+
+// update applies change to job and records it. Only the goroutine running
+// job updates it, so its records are written in order.
+func (jr *jobRunner) update(job *definition.Job, change func(job *definition.Job)) {
+	jr.mu.Lock()
+	change(job)
+	updated := *job
+	jr.mu.Unlock()
+	// the record is written even when the job is canceled
+	if err := jr.jobs.SaveJob(context.Background(), &updated); err != nil {
+		logrus.WithError(err).Errorf("failed to record the progress of %s job %s", job.Type, job.ID)
+	}
+}
+
+func (jr *jobRunner) forget(id string) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	delete(jr.running, id)
+}
+
+// cancel cancels the job with id when it's queued or running on this
+// replica, and returns whether it was.
+func (jr *jobRunner) cancel(id string) bool {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	running, ok := jr.running[id]
+	if ok {
+		running.cancel()
+	}
+	return ok
+}
+
+// stop cancels the jobs of this replica and waits for them to record it,
+// until ctx is done.
+func (jr *jobRunner) stop(ctx context.Context) error {
+	jr.mu.Lock()
+	for _, running := range jr.running {
+		running.cancel()
+	}
+	jr.mu.Unlock()
+	stopped := make(chan struct{})
+	go func() {
+		jr.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// jobProgress reports the progress of a running job.
+type jobProgress struct {
+	runner *jobRunner
+	job    *definition.Job
+}
+
+// step records that the job moved on to step, counting its items anew.
+func (p *jobProgress) step(step string) {
+	p.runner.update(p.job, func(job *definition.Job) {
+		job.Step, job.Done, job.Total = step, 0, 0
+	})
+}
+
+// count records that the job went through done items of total.
+func (p *jobProgress) count(done int64, total int64) {
+	p.runner.update(p.job, func(job *definition.Job) {
+		job.Done, job.Total = done, total
+	})
+}
+
+// attach attaches report to the job, downloaded with GET
+// /admin/jobs/{id}/report.
+func (p *jobProgress) attach(report []byte) {
+	p.runner.update(p.job, func(job *definition.Job) {
+		job.Report = report
+	})
+}
+
+// SetJobs records the admin jobs in jobs, shared by the replicas, rather
+// than in the memory of this one. Call it before Start.
+func (s *Server) SetJobs(jobs definition.IJobs) {
+	s.handler.jobs.jobs = jobs
+}
+
+// reindexResult is the result of a reindex: the rebuild of the derived data,
+// and the contacts indexed by each search engine.
+type reindexResult struct {
+	*definition.RebuildResult
+	Search map[string]int64 `json:"search,omitempty"`
+}
+
+// @Summary Rebuild the derived data
+// @Description Rebuilds in the background what is derived from the contacts: recomputes the display name, normalized phone and initial of every contact, drops and creates anew the text and secondary indexes, then rebuilds the indexes of the configured search engines. Answers 202 with the job, whose progress GET /admin/jobs/{id} reports. A single reindex of a tenant runs at a time on a replica. Allowed during maintenance. Derived data is rebuilt with MongoDB only
+// @Produce json
+// @Success 202 {object} definition.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 409 {object} server.errorResponse "a reindex is already running"
+// @Router /admin/reindex [post]
+func (h *httpHandlerStruct) Reindex(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.start(r.Context(), reindexJob, true, h.reindex)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errors.Is(err, ErrJobRunning) {
+			httpStatus = http.StatusConflict
+		}
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/reindex")+"/jobs/"+job.ID)
+	writeJob(w, r, job, http.StatusAccepted)
+}
+
+// reindex rebuilds the derived data of the phone book, then the search
+// indexes.
+func (h *httpHandlerStruct) reindex(ctx context.Context, progress *jobProgress) (any, error) {
+	progress.step("derived")
+	rebuilt, err := h.phoneBook.RebuildDerivedData(ctx, progress.count)
+	if err != nil {
+		return nil, err
+	}
+	result := &reindexResult{RebuildResult: rebuilt}
+	engines := make([]string, 0, len(h.searchIndexes))
+	for engine := range h.searchIndexes {
+		engines = append(engines, engine)
+	}
+	slices.Sort(engines)
+	for _, engine := range engines {
+		progress.step("search:" + engine)
+		indexed, err := h.searchIndexes[engine].Rebuild(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if result.Search == nil {
+			result.Search = map[string]int64{}
+		}
+		result.Search[engine] = indexed
+	}
+	return result, nil
+}
+
+// @Summary List the jobs
+// @Description Lists the admin jobs of the tenant, like the reindexes, most recent first: those queued, running and finished within the job retention. With MongoDB every replica records its jobs in a shared collection, with the other backends each replica keeps its last 100 jobs until it restarts
+// @Produce json
+// @Param type query string false "Type of the jobs, e.g. reindex"
+// @Param status query string false "queued, running, succeeded, failed or canceled"
+// @Param limit query int false "Number of jobs (default 10), clamped to the server maximum"
+// @Success 200 {array} definition.Job
+// @Failure 400 {object} server.errorResponse "invalid status or limit"
+// @Router /admin/jobs [get]
+func (h *httpHandlerStruct) ListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := definition.JobFilter{
+		Tenant: definition.TenantFromContext(r.Context()),
+		Type:   query.Get("type"),
+		Status: query.Get("status"),
+		Limit:  h.tunables().LimitPerPage,
+	}
+	if filter.Status != "" && !slices.Contains(jobStatuses, filter.Status) {
+		h.handleError(ErrInvalidJobStatus, w, r, http.StatusBadRequest)
+		return
+	}
+	if query.Has("limit") {
+		limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+		if err != nil || limit <= 0 {
+			h.handleError(core.ErrInvalidLimit, w, r, http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(limit, h.tunables().MaxPageSize)
+	}
+	jobs, status, err := h.jobs.jobs.ListJobs(r.Context(), filter)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	lang := requestLanguage(r)
+	for _, job := range jobs {
+		localizeJob(job, lang)
+	}
+	response, _ := json.Marshal(jobs)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get the status of a job
+// @Description Reports the progress of an admin job running in the background, like a reindex: the step it is at and the items it went through of total, then its result or error
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} definition.Job
+// @Failure 404 {object} server.errorResponse "job not found"
+// @Router /admin/jobs/{id} [get]
+func (h *httpHandlerStruct) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, status, err := h.tenantJob(r)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	writeJob(w, r, job, http.StatusOK)
+}
+
+// @Summary Download the report of a job
+// @Description Downloads the report a job attached, like the CSV of the rows an import failed on, with their row, error code, field and message. The report is attached once the job finished
+// @Produce text/csv
+// @Param id path string true "Job ID"
+// @Success 200 {string} string "the report"
+// @Failure 404 {object} server.errorResponse "job or report not found"
+// @Router /admin/jobs/{id}/report [get]
+func (h *httpHandlerStruct) GetJobReport(w http.ResponseWriter, r *http.Request) {
+	job, status, err := h.tenantJob(r)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	if job.Report == nil {
+		h.handleError(ErrNoJobReport, w, r, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, job.Type, job.ID))
+	w.Write(job.Report)
+}
+
+// @Summary Cancel a job
+// @Description Cancels a queued or running job, which stops at its next step or batch and ends canceled. Answers 202 with the job, still running until it stopped. Only the replica running a job can cancel it
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} definition.Job
+// @Failure 404 {object} server.errorResponse "job not found"
+// @Failure 409 {object} server.errorResponse "the job already finished, or runs on another replica"
+// @Router /admin/jobs/{id} [delete]
+func (h *httpHandlerStruct) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, status, err := h.tenantJob(r)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	if job.Finished() {
+		h.handleError(ErrJobFinished, w, r, http.StatusConflict)
+		return
+	}
+	if !h.jobs.cancel(job.ID) {
+		h.handleError(ErrJobElsewhere.WithMessage("the job runs on replica "+job.Replica+", which alone can cancel it"), w, r, http.StatusConflict)
+		return
+	}
+	writeJob(w, r, job, http.StatusAccepted)
+}
+
+// tenantJob returns the job of the id of r, unless it's of another tenant.
+func (h *httpHandlerStruct) tenantJob(r *http.Request) (*definition.Job, string, error) {
+	job, status, err := h.jobs.jobs.GetJob(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		return nil, status, err
+	}
+	if job.Tenant != definition.TenantFromContext(r.Context()) {
+		return nil, core.NotFound, core.ErrJobNotFound
+	}
+	return job, "", nil
+}
+
+func writeJob(w http.ResponseWriter, r *http.Request, job *definition.Job, httpStatus int) {
+	localizeJob(job, requestLanguage(r))
+	response, _ := json.Marshal(job)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(response)
+}
+
+// localizeJob writes the error of job in lang, as the errors of the requests.
+func localizeJob(job *definition.Job, lang string) {
+	if job.Error != nil {
+		localized := *job.Error
+		localized.Message = i18n.Message(lang, localized.Code, localized.Message)
+		job.Error = &localized
+	}
+}
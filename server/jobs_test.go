@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+// rebuildPhoneBook rebuilds its two contacts once release is closed, unless
+// canceled first.
+type rebuildPhoneBook struct {
+	stubPhoneBook
+	release chan struct{}
+}
+
+func (pb *rebuildPhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	progress(1, 2)
+	select {
+	case <-pb.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	progress(2, 2)
+	return &definition.RebuildResult{Contacts: 2, Updated: 1, Indexes: []string{"contacts_text"}}, nil
+}
+
+// jobsClient calls the job routes of server.
+type jobsClient struct {
+	t      *testing.T
+	server *Server
+}
+
+func (c jobsClient) request(method string, target string, out any) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	c.server.Handler().ServeHTTP(response, asAdmin(httptest.NewRequest(method, target, nil)))
+	if out != nil {
+		json.Unmarshal(response.Body.Bytes(), out)
+	}
+	return response
+}
+
+// await waits for the job with id to reach status.
+func (c jobsClient) await(id string, status string) *definition.Job {
+	var job *definition.Job
+	assert.Eventually(c.t, func() bool {
+		c.request(http.MethodGet, "/api/v1/admin/jobs/"+id, &job)
+		return job.Status == status
+	}, time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestReindex(t *testing.T) {
+	phoneBook := &rebuildPhoneBook{release: make(chan struct{})}
+	client := jobsClient{t: t, server: NewServer(adminConfig(), phoneBook, events.NewHub())}
+
+	var job *definition.Job
+	response := client.request(http.MethodPost, "/api/v1/admin/reindex", &job)
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	assert.Equal(t, "/api/v1/admin/jobs/"+job.ID, response.Header().Get("Location"))
+	assert.Equal(t, reindexJob, job.Type)
+	client.await(job.ID, definition.JobRunning)
+
+	response = client.request(http.MethodPost, "/api/v1/admin/reindex", nil)
+	assert.Equal(t, http.StatusConflict, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"JOB_RUNNING"`)
+
+	close(phoneBook.release)
+	job = client.await(job.ID, definition.JobSucceeded)
+	assert.Equal(t, "derived", job.Step)
+	assert.Equal(t, int64(2), job.Done)
+	assert.Equal(t, int64(2), job.Total)
+	assert.JSONEq(t, `{"contacts":2,"updated":1,"indexes":["contacts_text"]}`, string(job.Result))
+	assert.NotNil(t, job.FinishedAt)
+
+	response = client.request(http.MethodGet, "/api/v1/admin/jobs/unknown", nil)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"JOB_NOT_FOUND"`)
+}
+
+func TestJobs(t *testing.T) {
+	cfg := adminConfig()
+	cfg.JobConcurrency = 1
+	phoneBook := &rebuildPhoneBook{release: make(chan struct{})}
+	server := NewServer(cfg, phoneBook, events.NewHub())
+	client := jobsClient{t: t, server: server}
+	blocked := make(chan struct{})
+	block := func(ctx context.Context, progress *jobProgress) (any, error) {
+		select {
+		case <-blocked:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	t.Run("should queue the jobs over the concurrency", func(t *testing.T) {
+		first, err := server.handler.jobs.start(context.Background(), "block", false, block)
+		assert.Nil(t, err)
+		client.await(first.ID, definition.JobRunning)
+		second, err := server.handler.jobs.start(context.Background(), "block", false, block)
+		assert.Nil(t, err)
+		time.Sleep(50 * time.Millisecond)
+		client.await(second.ID, definition.JobQueued)
+
+		close(blocked)
+		client.await(first.ID, definition.JobSucceeded)
+		client.await(second.ID, definition.JobSucceeded)
+	})
+
+	t.Run("should cancel a running job", func(t *testing.T) {
+		var job *definition.Job
+		client.request(http.MethodPost, "/api/v1/admin/reindex", &job)
+		client.await(job.ID, definition.JobRunning)
+		response := client.request(http.MethodDelete, "/api/v1/admin/jobs/"+job.ID, nil)
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		job = client.await(job.ID, definition.JobCanceled)
+		assert.Nil(t, job.Error)
+
+		response = client.request(http.MethodDelete, "/api/v1/admin/jobs/"+job.ID, nil)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assert.Contains(t, response.Body.String(), `"code":"JOB_FINISHED"`)
+	})
+
+	t.Run("should list the jobs most recent first", func(t *testing.T) {
+		var jobs []*definition.Job
+		response := client.request(http.MethodGet, "/api/v1/admin/jobs?type=reindex", &jobs)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Len(t, jobs, 1)
+		assert.Equal(t, definition.JobCanceled, jobs[0].Status)
+
+		client.request(http.MethodGet, "/api/v1/admin/jobs?status=succeeded&limit=1", &jobs)
+		assert.Len(t, jobs, 1)
+		assert.Equal(t, "block", jobs[0].Type)
+
+		assert.Equal(t, http.StatusBadRequest, client.request(http.MethodGet, "/api/v1/admin/jobs?status=done", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, client.request(http.MethodGet, "/api/v1/admin/jobs?limit=0", nil).Code)
+	})
+
+	t.Run("should cancel the jobs on stop", func(t *testing.T) {
+		job, err := server.handler.jobs.start(context.Background(), "block", false, func(ctx context.Context, progress *jobProgress) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		assert.Nil(t, err)
+		client.await(job.ID, definition.JobRunning)
+		assert.Nil(t, server.Stop(context.Background()))
+		client.await(job.ID, definition.JobCanceled)
+	})
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"phoneBook/definition"
+	"strconv"
+	"strings"
+)
+
+// pageLinks is where a page stands in a listing, for the Link header to tell
+// the pages around it. Pages by cursor have no page number.
+type pageLinks struct {
+	page       int
+	items      int
+	pageSize   int64
+	totalPages *int64
+	nextCursor string
+}
+
+func contactPageLinks(page *definition.ContactPage) pageLinks {
+	return pageLinks{page: page.Page, items: len(page.Items), pageSize: page.PageSize, totalPages: page.TotalPages, nextCursor: page.NextCursor}
+}
+
+func interactionPageLinks(page *definition.InteractionPage) pageLinks {
+	return pageLinks{page: page.Page, items: len(page.Items), pageSize: page.PageSize, totalPages: page.TotalPages}
+}
+
+// setLinkHeader links the first, prev, next and last pages of a listing in
+// the Link header (RFC 8288, formerly RFC 5988), for the clients paginating
+// without reading the body. The links keep the query of r, only the page or
+// cursor changing. Pages by cursor link the first and next pages only, and
+// without the totals the last page is unknown and the next page is linked
+// while the page is full. The links are added next to the ones already set,
+// like the successor version of the legacy paths.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, links pageLinks) {
+	link := func(rel string, key string, value string) string {
+		query := r.URL.Query()
+		if key == "page" {
+			query.Del("cursor")
+		}
+		query.Set(key, value)
+		target := url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: query.Encode()}
+		return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+	}
+	var relations []string
+	if links.page == 0 {
+		relations = append(relations, link("first", "cursor", ""))
+		if links.nextCursor != "" {
+			relations = append(relations, link("next", "cursor", links.nextCursor))
+		}
+	} else {
+		relations = append(relations, link("first", "page", "1"))
+		if links.page > 1 {
+			relations = append(relations, link("prev", "page", strconv.Itoa(links.page-1)))
+		}
+		hasNext := int64(links.items) == links.pageSize && links.items > 0
+		if links.totalPages != nil {
+			hasNext = int64(links.page) < *links.totalPages
+		}
+		if hasNext {
+			relations = append(relations, link("next", "page", strconv.Itoa(links.page+1)))
+		}
+		if links.totalPages != nil && *links.totalPages > 0 {
+			relations = append(relations, link("last", "page", strconv.FormatInt(*links.totalPages, 10)))
+		}
+	}
+	w.Header().Add("Link", strings.Join(relations, ", "))
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strconv"
+	"testing"
+)
+
+// pagedPhoneBook lists 3 pages of 2 contacts, by page number or by cursor.
+type pagedPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *pagedPhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	items := []*definition.Contact{{FirstName: "Dani"}, {FirstName: "Noa"}}
+	if _, ok := query["cursor"]; ok {
+		return &definition.ContactPage{Items: items, PageSize: 2, NextCursor: "ZWE"}, "", nil
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	totalItems, totalPages := int64(6), int64(3)
+	return &definition.ContactPage{Items: items, Page: max(page, 1), PageSize: 2, TotalItems: &totalItems, TotalPages: &totalPages}, "", nil
+}
+
+func (pb *pagedPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return &definition.ContactPage{Items: []*definition.Contact{{FirstName: "Dani"}, {FirstName: "Dan"}}, Page: 1, PageSize: 2}, "", nil
+}
+
+func TestLinkHeader(t *testing.T) {
+	server := NewServer(config.Default(), &pagedPhoneBook{}, events.NewHub())
+	link := func(target string) string {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		return response.Header().Get("Link")
+	}
+
+	t.Run("should link the pages around a page", func(t *testing.T) {
+		assert.Equal(t, `</api/v1/contact?page=1&pageSize=2>; rel="first", `+
+			`</api/v1/contact?page=1&pageSize=2>; rel="prev", `+
+			`</api/v1/contact?page=3&pageSize=2>; rel="next", `+
+			`</api/v1/contact?page=3&pageSize=2>; rel="last"`, link("/api/v1/contact?page=2&pageSize=2"))
+	})
+
+	t.Run("should not link past the last page", func(t *testing.T) {
+		assert.Equal(t, `</api/v1/contact?page=1>; rel="first", </api/v1/contact?page=2>; rel="prev", `+
+			`</api/v1/contact?page=3>; rel="last"`, link("/api/v1/contact?page=3"))
+	})
+
+	t.Run("should link the next cursor", func(t *testing.T) {
+		assert.Equal(t, `</api/v1/contact?cursor=>; rel="first", </api/v1/contact?cursor=ZWE>; rel="next"`, link("/api/v1/contact?cursor="))
+	})
+
+	t.Run("should link the next page of a full page without totals", func(t *testing.T) {
+		assert.Equal(t, `</api/v1/contact/search?count=false&firstName=Dan&page=1>; rel="first", `+
+			`</api/v1/contact/search?count=false&firstName=Dan&page=2>; rel="next"`, link("/api/v1/contact/search?firstName=Dan&count=false"))
+	})
+	t.Run("should keep the successor version link of a legacy path", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/contact?page=3", nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, []string{`</api/v1/contact>; rel="successor-version"`,
+			`</contact?page=1>; rel="first", </contact?page=2>; rel="prev", </contact?page=3>; rel="last"`}, response.Header().Values("Link"))
+	})
+}
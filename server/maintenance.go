@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"phoneBook/definition"
+	"time"
+)
+
+var ErrMaintenance = definition.NewError("MAINTENANCE", "the phone book is read-only during maintenance, retry later", "")
+
+// maintenanceStatus tells whether the API is read-only for maintenance.
+type maintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+	// Message replaces the message of the errors the mutations fail with.
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// maintenanceExemptRoutes keep working during maintenance: toggling it,
+// backing up and restoring, migrating and rebuilding the derived data, which
+// maintenance is needed for, and rebuilding the search indexes and canceling
+// jobs, which leave the phone book alone.
+var maintenanceExemptRoutes = map[string]bool{
+	"/admin/maintenance":    true,
+	"/admin/backup":         true,
+	"/admin/restore":        true,
+	"/admin/search/reindex": true,
+	"/admin/migrate":        true,
+	"/admin/reindex":        true,
+	"/admin/jobs/{id}":      true,
+}
+
+// mutatingGetRoutes write to the phone book although they are GETs.
+var mutatingGetRoutes = map[string]bool{
+	"/integrations/google/callback": true,
+}
+
+// newMaintenanceStatus returns the status the server starts in, read-only
+// when MAINTENANCE_MODE is set.
+func newMaintenanceStatus(enabled bool) *maintenanceStatus {
+	if !enabled {
+		return &maintenanceStatus{}
+	}
+	now := time.Now().UTC()
+	return &maintenanceStatus{Enabled: true, Since: &now}
+}
+
+// maintenanceError is the error mutations fail with during maintenance, nil
+// otherwise.
+func (h *httpHandlerStruct) maintenanceError() error {
+	status := h.maintenance.Load()
+	if !status.Enabled {
+		return nil
+	}
+	if status.Message != "" {
+		return ErrMaintenance.WithMessage(status.Message)
+	}
+	return ErrMaintenance
+}
+
+// maintenanceMiddleware rejects the mutations with 503 during maintenance.
+// Reads keep working.
+func (h *httpHandlerStruct) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.maintenanceError()
+		if err == nil || !mutates(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.handleError(err, w, r, http.StatusServiceUnavailable)
+	})
+}
+
+// mutates reports whether a request may write to the phone book.
+func mutates(r *http.Request) bool {
+	path := routePath(r)
+	if maintenanceExemptRoutes[path] {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return mutatingGetRoutes[path]
+	}
+	return true
+}
+
+// @Summary Get the maintenance mode
+// @Description Tells whether the API is read-only for maintenance
+// @Produce json
+// @Success 200 {object} server.maintenanceStatus
+// @Router /admin/maintenance [get]
+func (h *httpHandlerStruct) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	response, _ := json.Marshal(h.maintenance.Load())
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Switch the maintenance mode
+// @Description Makes the API read-only, for migrations and restores: reads keep working while mutations fail with 503, code MAINTENANCE, and the message if one is sent. Backups and restores keep working. Applies to this replica only, until it restarts
+// @Accept json
+// @Produce json
+// @Param maintenance body server.maintenanceStatus true "enabled, and the optional message of the rejected mutations"
+// @Success 200 {object} server.maintenanceStatus
+// @Failure 400 {object} server.errorResponse "invalid body"
+// @Router /admin/maintenance [put]
+func (h *httpHandlerStruct) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var requested maintenanceStatus
+	if err := json.NewDecoder(r.Body).Decode(&requested); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	status := newMaintenanceStatus(requested.Enabled)
+	if requested.Enabled {
+		status.Message = requested.Message
+		if previous := h.maintenance.Load(); previous.Enabled {
+			status.Since = previous.Since
+		}
+	}
+	h.maintenance.Store(status)
+	logrus.WithField("actor", extractActor(r)).Infof("maintenance mode enabled: %v", status.Enabled)
+	response, _ := json.Marshal(status)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+// maintenancePhoneBook deletes any contact.
+type maintenancePhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *maintenancePhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{Matched: 1, Modified: 1}, nil
+}
+
+func TestMaintenance(t *testing.T) {
+	cfg := adminConfig()
+	cfg.MaintenanceMode = true
+	phoneBook := &maintenancePhoneBook{stubPhoneBook{contacts: map[string]*definition.Contact{"1": {FirstName: "Dani"}}}}
+	server := NewServer(cfg, phoneBook, events.NewHub())
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, asAdmin(httptest.NewRequest(method, path, strings.NewReader(body))))
+		return recorder
+	}
+
+	t.Run("should start read-only with MAINTENANCE_MODE", func(t *testing.T) {
+		recorder := request(http.MethodDelete, "/api/v1/contact/delete/1", "")
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"MAINTENANCE"`)
+
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/contact/1", "").Code)
+		assert.Contains(t, request(http.MethodGet, "/api/v1/admin/maintenance", "").Body.String(), `"enabled":true`)
+	})
+
+	t.Run("should keep restores working", func(t *testing.T) {
+		recorder := request(http.MethodPost, "/api/v1/admin/restore", "{}\n")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("should reject the mutations with the message", func(t *testing.T) {
+		recorder := request(http.MethodPut, "/api/v1/admin/maintenance", `{"enabled": true, "message": "migrating, back at 10:00"}`)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		recorder = request(http.MethodDelete, "/contact/delete/1", "")
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"message":"migrating, back at 10:00"`)
+		recorder = request(http.MethodGet, "/api/v1/integrations/google/callback", "")
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("should accept mutations once disabled", func(t *testing.T) {
+		recorder := request(http.MethodPut, "/api/v1/admin/maintenance", `{"enabled": false}`)
+		assert.JSONEq(t, `{"enabled":false}`, recorder.Body.String())
+		assert.Equal(t, http.StatusOK, request(http.MethodDelete, "/api/v1/contact/delete/1", "").Code)
+	})
+}
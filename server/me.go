@@ -0,0 +1,285 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"strings"
+	"time"
+)
+
+// userHeader names the user the authenticating proxy in front of the service
+// verified. It is only taken from the TRUSTED_PROXIES, as any other client
+// could name whoever they like with it.
+const userHeader = "X-User"
+
+var ErrUnauthenticated = definition.NewError("UNAUTHENTICATED", "no authenticated user, send the X-User header through a trusted proxy", "")
+
+// newTrustedProxies parses the TRUSTED_PROXIES, validated with the config.
+func newTrustedProxies(cfg config.Config) []netip.Prefix {
+	proxies, _ := config.ParseTrustedProxies(cfg.TrustedProxies)
+	return proxies
+}
+
+// userMiddleware scopes the requests of the user a trusted proxy
+// authenticated to them, so the contacts they add are owned by them. The
+// X-User header of other clients is only kept as their claim, for the audit
+// log.
+func (h *httpHandlerStruct) userMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get(userHeader)
+		if user != "" && h.fromTrustedProxy(r) {
+			r = r.WithContext(definition.WithUser(r.Context(), user))
+		} else if user != "" {
+			r = r.WithContext(definition.WithClaimedUser(r.Context(), user))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fromTrustedProxy reports whether r comes from one of the TRUSTED_PROXIES.
+func (h *httpHandlerStruct) fromTrustedProxy(r *http.Request) bool {
+	address, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(address.Addr().Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// bundleFile is a file of the data export bundle, written from the exported
+// contacts.
+type bundleFile struct {
+	name      string
+	newWriter func(w io.Writer) contactFormatter
+}
+
+var bundleFiles = []bundleFile{
+	{name: "contacts.json", newWriter: newJSONFormatter},
+	{name: "contacts.csv", newWriter: newCSVFormatter},
+	{name: "contacts.vcf", newWriter: newVCardFormatter},
+}
+
+// @Summary Export my data
+// @Description Streams a zip of the contacts the authenticated user added, as contacts.json, contacts.csv and contacts.vcf (vCard 3.0). The user is given by the X-User header, taken only from the TRUSTED_PROXIES
+// @Produce application/zip
+// @Param X-User header string true "User authenticated by the trusted proxy"
+// @Success 200 {file} file "zip archive"
+// @Failure 401 {object} server.errorResponse "no authenticated user"
+// @Router /me/export [get]
+func (h *httpHandlerStruct) ExportMine(w http.ResponseWriter, r *http.Request) {
+	user := definition.UserFromContext(r.Context())
+	if user == "" {
+		h.handleError(ErrUnauthenticated, w, r, http.StatusUnauthorized)
+		return
+	}
+	// a large export takes longer than the write timeout of regular requests
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="phonebook-export.zip"`)
+	body := &startedWriter{Writer: w}
+	archive := zip.NewWriter(body)
+	query := url.Values{"owner": {user}}
+	for _, file := range bundleFiles {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			h.abortExportMine(w, r, body, err, "", file.name)
+			return
+		}
+		formatter := file.newWriter(entry)
+		_, status, err := h.phoneBook.ExportContacts(r.Context(), query, &lineWriter{write: formatter.write})
+		if err == nil {
+			err = formatter.close()
+		}
+		if err != nil {
+			h.abortExportMine(w, r, body, err, status, file.name)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		h.abortExportMine(w, r, body, err, "", "")
+	}
+}
+
+// abortExportMine reports the failure of a data export, or drops the
+// connection once the archive started streaming so the client doesn't take
+// it for complete.
+func (h *httpHandlerStruct) abortExportMine(w http.ResponseWriter, r *http.Request, body *startedWriter, err error, status string, file string) {
+	if !body.started {
+		w.Header().Del("Content-Disposition")
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	logrus.WithError(err).WithField("requestId", r.Header.Get(requestIDHeader)).
+		Errorf("data export failed writing %s", file)
+	panic(http.ErrAbortHandler)
+}
+
+// lineWriter splits what is written to it into lines, the contacts of an
+// ndjson export.
+type lineWriter struct {
+	pending []byte
+	write   func(line []byte) error
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		line := w.pending[:end]
+		w.pending = w.pending[end+1:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := w.write(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// contactFormatter writes exported contacts, one ndjson line at a time, in a
+// format of the bundle.
+type contactFormatter interface {
+	write(line []byte) error
+	close() error
+}
+
+// jsonFormatter writes the contacts as a JSON array, a contact per line.
+type jsonFormatter struct {
+	w       io.Writer
+	written bool
+}
+
+func newJSONFormatter(w io.Writer) contactFormatter {
+	return &jsonFormatter{w: w}
+}
+
+func (f *jsonFormatter) write(line []byte) error {
+	separator := ",\n"
+	if !f.written {
+		separator = "[\n"
+		f.written = true
+	}
+	if _, err := io.WriteString(f.w, separator); err != nil {
+		return err
+	}
+	_, err := f.w.Write(line)
+	return err
+}
+
+func (f *jsonFormatter) close() error {
+	closing := "\n]\n"
+	if !f.written {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(f.w, closing)
+	return err
+}
+
+// csvColumns are the columns of the CSV file of the bundle.
+var csvColumns = []string{"id", "firstName", "lastName", "phone", "email", "company", "jobTitle", "address", "notes", "createdAt", "updatedAt"}
+
+// csvFormatter writes the contacts as CSV, under a header row.
+type csvFormatter struct {
+	w *csv.Writer
+}
+
+func newCSVFormatter(w io.Writer) contactFormatter {
+	writer := csv.NewWriter(w)
+	writer.Write(csvColumns)
+	return &csvFormatter{w: writer}
+}
+
+func (f *csvFormatter) write(line []byte) error {
+	contact, err := decodeExportedContact(line)
+	if err != nil {
+		return err
+	}
+	f.w.Write([]string{contact.ID.Hex(), contact.FirstName, contact.LastName, contact.Phone, contact.Email, contact.Company,
+		contact.JobTitle, contact.Address, contact.Notes, formatTime(contact.CreatedAt), formatTime(contact.UpdatedAt)})
+	return f.w.Error()
+}
+
+func (f *csvFormatter) close() error {
+	f.w.Flush()
+	return f.w.Error()
+}
+
+// vCardFormatter writes the contacts as vCard 3.0 cards.
+type vCardFormatter struct {
+	w io.Writer
+}
+
+func newVCardFormatter(w io.Writer) contactFormatter {
+	return &vCardFormatter{w: w}
+}
+
+var vCardEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func (f *vCardFormatter) write(line []byte) error {
+	contact, err := decodeExportedContact(line)
+	if err != nil {
+		return err
+	}
+	escape := vCardEscaper.Replace
+	var card strings.Builder
+	card.WriteString("BEGIN:VCARD\r\nVERSION:3.0\r\n")
+	card.WriteString("N:" + escape(contact.LastName) + ";" + escape(contact.FirstName) + ";;;\r\n")
+	card.WriteString("FN:" + escape(strings.TrimSpace(contact.FirstName+" "+contact.LastName)) + "\r\n")
+	for _, property := range []struct{ name, value string }{
+		{"TEL;TYPE=CELL", contact.Phone},
+		{"EMAIL", contact.Email},
+		{"ORG", contact.Company},
+		{"TITLE", contact.JobTitle},
+		{"NOTE", contact.Notes},
+	} {
+		if property.value != "" {
+			card.WriteString(property.name + ":" + escape(property.value) + "\r\n")
+		}
+	}
+	if contact.Address != "" {
+		// the address is kept whole, as the street of the card
+		card.WriteString("ADR;TYPE=HOME:;;" + escape(contact.Address) + ";;;;\r\n")
+	}
+	card.WriteString("UID:" + contact.ID.Hex() + "\r\n")
+	if contact.UpdatedAt != nil {
+		card.WriteString("REV:" + contact.UpdatedAt.UTC().Format("20060102T150405Z") + "\r\n")
+	}
+	card.WriteString("END:VCARD\r\n")
+	_, err = io.WriteString(f.w, card.String())
+	return err
+}
+
+func (f *vCardFormatter) close() error {
+	return nil
+}
+
+func decodeExportedContact(line []byte) (*definition.Contact, error) {
+	contact := &definition.Contact{}
+	if err := json.Unmarshal(line, contact); err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+// exportPhoneBook exports a contact per call, and keeps the query and the
+// user of the last one.
+type exportPhoneBook struct {
+	stubPhoneBook
+	query url.Values
+	user  string
+}
+
+func (pb *exportPhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	pb.query = query
+	pb.user = definition.UserFromContext(ctx)
+	_, err := io.WriteString(w, `{"_id":"65a1b2c3d4e5f60718293a4b","firstName":"Dani","lastName":"Cohen","phone":"0521234567","address":"Herzl 1, Tel Aviv","updatedAt":"2024-01-12T10:00:00Z"}`+"\n")
+	return 1, "", err
+}
+
+func TestExportMine(t *testing.T) {
+	phoneBook := &exportPhoneBook{}
+	cfg := config.Default()
+	// the address of the httptest requests
+	cfg.TrustedProxies = []string{"192.0.2.0/24"}
+	server := NewServer(cfg, phoneBook, events.NewHub())
+
+	t.Run("should require an authenticated user", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"UNAUTHENTICATED"`)
+	})
+
+	t.Run("should ignore the user named by a client but the trusted proxies", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil)
+		request.RemoteAddr = "203.0.113.9:4711"
+		request.Header.Set("X-User", "noy")
+		server.Handler().ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"UNAUTHENTICATED"`)
+	})
+
+	t.Run("should zip the contacts of the user as json, csv and vcf", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil)
+		request.Header.Set("X-User", "noy")
+		server.Handler().ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, url.Values{"owner": {"noy"}}, phoneBook.query)
+		assert.Equal(t, "noy", phoneBook.user)
+
+		archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+		assert.Nil(t, err)
+		files := map[string]string{}
+		for _, file := range archive.File {
+			reader, _ := file.Open()
+			content, _ := io.ReadAll(reader)
+			files[file.Name] = string(content)
+		}
+		assert.JSONEq(t, `[{"_id":"65a1b2c3d4e5f60718293a4b","firstName":"Dani","lastName":"Cohen","phone":"0521234567","address":"Herzl 1, Tel Aviv","updatedAt":"2024-01-12T10:00:00Z"}]`, files["contacts.json"])
+		assert.Equal(t, "id,firstName,lastName,phone,email,company,jobTitle,address,notes,createdAt,updatedAt\n"+
+			"65a1b2c3d4e5f60718293a4b,Dani,Cohen,0521234567,,,,\"Herzl 1, Tel Aviv\",,,2024-01-12T10:00:00Z\n", files["contacts.csv"])
+		assert.Equal(t, "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Cohen;Dani;;;\r\nFN:Dani Cohen\r\nTEL;TYPE=CELL:0521234567\r\n"+
+			"ADR;TYPE=HOME:;;Herzl 1\\, Tel Aviv;;;;\r\nUID:65a1b2c3d4e5f60718293a4b\r\nREV:20240112T100000Z\r\nEND:VCARD\r\n", files["contacts.vcf"])
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/phonenumber"
+	"strings"
+)
+
+// phoneRegion returns the region the phones of the response are formatted
+// for: the region parameter, or PHONE_REGION without it.
+func (h *httpHandlerStruct) phoneRegion(r *http.Request) (string, error) {
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		return h.cfg.PhoneRegion, nil
+	}
+	region = strings.ToUpper(region)
+	if !phonenumber.KnownRegion(region) {
+		return "", ErrInvalidRegion
+	}
+	return region, nil
+}
+
+// formatPhones sets the E.164 and display forms of the phones of contacts,
+// displayed in region.
+func formatPhones(region string, contacts ...*definition.Contact) {
+	for _, contact := range contacts {
+		if contact != nil {
+			contact.PhoneE164, contact.PhoneFormatted = core.FormatPhone(contact.Phone, region)
+		}
+	}
+}
+
+// expandSearchAliases rewrites the search parameters short for a contact
+// field: country for phoneCountry, upper cased like the stored codes.
+func expandSearchAliases(query url.Values) {
+	if country, ok := query["country"]; ok {
+		query.Del("country")
+		query.Set("phoneCountry", strings.ToUpper(country[0]))
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+func TestPhoneFormat(t *testing.T) {
+	phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{
+		"1": {FirstName: "Dani", Phone: "052-123-4567"},
+		"2": {FirstName: "Noa", Phone: "+1 (201) 555-0123"},
+		"3": {FirstName: "Reception", Phone: "120"},
+	}}
+	server := NewServer(config.Default(), phoneBook, events.NewHub())
+	get := func(target string) (*httptest.ResponseRecorder, *definition.Contact) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, target, nil))
+		var contact *definition.Contact
+		json.Unmarshal(response.Body.Bytes(), &contact)
+		return response, contact
+	}
+
+	t.Run("should format the phones for PHONE_REGION", func(t *testing.T) {
+		_, contact := get("/api/v1/contact/1")
+		assert.Equal(t, "+972521234567", contact.PhoneE164)
+		assert.Equal(t, "052-123-4567", contact.PhoneFormatted)
+
+		_, contact = get("/api/v1/contact/2")
+		assert.Equal(t, "+12015550123", contact.PhoneE164)
+		assert.Equal(t, "+1 201-555-0123", contact.PhoneFormatted)
+	})
+
+	t.Run("should format the phones for the region parameter", func(t *testing.T) {
+		_, contact := get("/api/v1/contact/1?region=us")
+		assert.Equal(t, "+972521234567", contact.PhoneE164)
+		assert.Equal(t, "+972 52-123-4567", contact.PhoneFormatted)
+
+		_, contact = get("/api/v1/contact/2?region=US")
+		assert.Equal(t, "(201) 555-0123", contact.PhoneFormatted)
+	})
+
+	t.Run("should leave out the numbers of no known region", func(t *testing.T) {
+		response, contact := get("/api/v1/contact/3")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "120", contact.Phone)
+		assert.NotContains(t, response.Body.String(), "phoneE164")
+		assert.NotContains(t, response.Body.String(), "phoneFormatted")
+	})
+
+	t.Run("should reject an unknown region", func(t *testing.T) {
+		response, _ := get("/api/v1/contact/1?region=XX")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), "INVALID_REGION")
+	})
+}
+
+func TestExpandSearchAliases(t *testing.T) {
+	query := url.Values{"country": {"il"}, "lastName": {"Cohen"}}
+	expandSearchAliases(query)
+	assert.Equal(t, url.Values{"phoneCountry": {"IL"}, "lastName": {"Cohen"}}, query)
+}
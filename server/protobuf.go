@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"phoneBook/definition"
+	"phoneBook/protobuf"
+	"strings"
+)
+
+// acceptsProtobuf reports whether the client asked for protobuf responses.
+func acceptsProtobuf(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accepted); err == nil && mediaType == protobuf.ContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// sentProtobuf reports whether the body of the request is protobuf.
+func sentProtobuf(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == protobuf.ContentType
+}
+
+// writeContacts writes a contact, a list or a page of contacts as protobuf to
+// the clients accepting it, and as JSON otherwise, their phones formatted for
+// the region of the request.
+func (h *httpHandlerStruct) writeContacts(w http.ResponseWriter, r *http.Request, value any) {
+	region, err := h.phoneRegion(r)
+	if err != nil {
+		h.handleError(err, w, r, http.StatusBadRequest)
+		return
+	}
+	switch value := value.(type) {
+	case *definition.Contact:
+		formatPhones(region, value)
+	case []*definition.Contact:
+		formatPhones(region, value...)
+	case *definition.ContactPage:
+		formatPhones(region, value.Items...)
+	}
+	w.Header().Add("Vary", "Accept")
+	var response []byte
+	if acceptsProtobuf(r) {
+		switch value := value.(type) {
+		case *definition.Contact:
+			response = protobuf.MarshalContact(value)
+		case []*definition.Contact:
+			response = protobuf.MarshalContacts(value)
+		case *definition.ContactPage:
+			response = protobuf.MarshalContactPage(value)
+		}
+		w.Header().Set("Content-Type", protobuf.ContentType)
+	} else {
+		response, _ = json.Marshal(value)
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(response)
+}
+
+// decodeProtobufContact reads a contact sent as protobuf.
+func decodeProtobufContact(r io.Reader) (*definition.Contact, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	contact, err := protobuf.UnmarshalContact(body)
+	if err != nil {
+		return nil, ErrInvalidBody.WithMessage("invalid protobuf body: " + err.Error())
+	}
+	return contact, nil
+}
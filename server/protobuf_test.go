@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"phoneBook/protobuf"
+	"testing"
+)
+
+// addedPhoneBook keeps the contact added last.
+type addedPhoneBook struct {
+	stubPhoneBook
+	added *definition.Contact
+}
+
+func (pb *addedPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	pb.added = contact
+	return &definition.WriteResult{ID: "65a1b2c3d4e5f60718293a4b"}, nil
+}
+
+func TestProtobuf(t *testing.T) {
+	contact := &definition.Contact{FirstName: "Dani", Phone: "0521234567", Version: 1}
+	phoneBook := &addedPhoneBook{stubPhoneBook: stubPhoneBook{contacts: map[string]*definition.Contact{"1": contact}}}
+	server := NewServer(config.Default(), phoneBook, events.NewHub())
+
+	t.Run("should answer protobuf to the clients accepting it", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil)
+		request.Header.Set("Accept", "application/x-protobuf, application/json;q=0.5")
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, request)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, protobuf.ContentType, response.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", response.Header().Get("Vary"))
+		assert.Equal(t, protobuf.MarshalContact(contact), response.Body.Bytes())
+	})
+
+	t.Run("should answer JSON otherwise", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil))
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"_id":"000000000000000000000000","firstName":"Dani","phone":"0521234567","phoneE164":"+972521234567","phoneFormatted":"052-123-4567","version":1}`, response.Body.String())
+	})
+
+	t.Run("should read the contacts sent as protobuf", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/contact", bytes.NewReader(protobuf.MarshalContact(contact)))
+		request.Header.Set("Content-Type", protobuf.ContentType)
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, request)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, contact, phoneBook.added)
+	})
+
+	t.Run("should reject a malformed protobuf body", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/contact", bytes.NewReader([]byte{0x12, 0x10}))
+		request.Header.Set("Content-Type", protobuf.ContentType)
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, request)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), "invalid protobuf body")
+	})
+}
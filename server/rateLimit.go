@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"golang.org/x/time/rate"
+	"math"
+	"net"
+	"net/http"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	// clientIdleTimeout is how long an idle client keeps its bucket.
+	clientIdleTimeout = 5 * time.Minute
+)
+
+var ErrRateLimited = definition.NewError("RATE_LIMITED", "too many requests, retry later", "")
+
+// rateLimiter keeps a token bucket per client, keyed by API key or client IP.
+type rateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*rateLimitedClient
+	lastSweep time.Time
+}
+
+type rateLimitedClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		clients:   map[string]*rateLimitedClient{},
+		lastSweep: time.Now(),
+	}
+}
+
+// rateLimitMiddleware rejects requests over the configured rate with 429 and
+// a Retry-After header. A zero RATE_LIMIT_PER_SECOND disables it. The rate is
+// read on every request so reloads, when followed, apply to existing clients.
+func (h *httpHandlerStruct) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tunable := h.tunables()
+		if tunable.RateLimitPerSecond <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit := rate.Limit(tunable.RateLimitPerSecond)
+		if wait := h.limiter.reserve(h.clientKey(r), time.Now(), limit, tunable.RateLimitBurst); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			h.handleError(ErrRateLimited, w, r, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reserve takes a token from the client's bucket at the given rate, returning how long to wait
+// for the next one when the bucket is empty.
+func (rl *rateLimiter) reserve(key string, now time.Time, limit rate.Limit, burst int) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.sweep(now)
+	client, ok := rl.clients[key]
+	if !ok {
+		client = &rateLimitedClient{limiter: rate.NewLimiter(limit, burst)}
+		rl.clients[key] = client
+	}
+	if client.limiter.Limit() != limit || client.limiter.Burst() != burst {
+		client.limiter.SetLimitAt(now, limit)
+		client.limiter.SetBurstAt(now, burst)
+	}
+	client.lastSeen = now
+	reservation := client.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Second
+	}
+	wait := reservation.DelayFrom(now)
+	if wait > 0 {
+		reservation.CancelAt(now)
+	}
+	return wait
+}
+
+// sweep forgets clients idle for longer than clientIdleTimeout, at most once
+// per timeout.
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < clientIdleTimeout {
+		return
+	}
+	for key, client := range rl.clients {
+		if now.Sub(client.lastSeen) > clientIdleTimeout {
+			delete(rl.clients, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// newAPIKeys returns the API_KEYS, the only keys clients are told apart by.
+func newAPIKeys(cfg config.Config) map[string]bool {
+	keys := map[string]bool{}
+	for _, key := range cfg.APIKeys {
+		if key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// apiKey returns the API key of r when it is one of the API_KEYS, so that
+// clients can't get a bucket of their own by sending made up keys.
+func (h *httpHandlerStruct) apiKey(r *http.Request) (string, bool) {
+	apiKey := r.Header.Get(apiKeyHeader)
+	return apiKey, h.apiKeys[apiKey]
+}
+
+// clientKey identifies the client of r by its API key, or by its IP when it
+// sends none of the API_KEYS.
+func (h *httpHandlerStruct) clientKey(r *http.Request) string {
+	if apiKey, ok := h.apiKey(r); ok {
+		return "key:" + apiKey
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// clientMiddleware scopes the requests to their client, identified like the
+// rate limited ones, so the delete guard counts the deletes of each.
+func (h *httpHandlerStruct) clientMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(definition.WithClient(r.Context(), h.clientID(r))))
+	})
+}
+
+// clientID identifies the client of r like clientKey, by a digest of its API
+// key rather than the key, so it can be logged and sent in alerts.
+func (h *httpHandlerStruct) clientID(r *http.Request) string {
+	if apiKey, ok := h.apiKey(r); ok {
+		digest := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(digest[:6])
+	}
+	return h.clientKey(r)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimitPerSecond = 0.5
+	cfg.RateLimitBurst = 2
+	cfg.APIKeys = []string{"secret"}
+	newLimitedServer := func() *Server {
+		phoneBook := &stubPhoneBook{contacts: map[string]*definition.Contact{"1": {FirstName: "Dani", Version: 1}}}
+		return NewServer(cfg, phoneBook, events.NewHub())
+	}
+	get := func(server *Server, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil)
+		request.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			request.Header.Set(apiKeyHeader, apiKey)
+		}
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("should refuse requests once the burst is used up", func(t *testing.T) {
+		server := newLimitedServer()
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.1:1234", "").Code)
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.1:1234", "").Code)
+		recorder := get(server, "10.0.0.1:1234", "")
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"RATE_LIMITED"`)
+		// a token comes every 2 seconds
+		assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("should keep the limit of each client apart", func(t *testing.T) {
+		server := newLimitedServer()
+		get(server, "10.0.0.1:1234", "")
+		get(server, "10.0.0.1:1234", "")
+		assert.Equal(t, http.StatusTooManyRequests, get(server, "10.0.0.1:5678", "").Code, "same ip, other port")
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.2:1234", "").Code)
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.1:1234", "secret").Code, "api key over ip")
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.3:1234", "secret").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get(server, "10.0.0.4:1234", "secret").Code)
+	})
+
+	t.Run("should limit clients rotating unknown api keys by ip", func(t *testing.T) {
+		server := newLimitedServer()
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.1:1234", "random-1").Code)
+		assert.Equal(t, http.StatusOK, get(server, "10.0.0.1:1234", "random-2").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get(server, "10.0.0.1:1234", "random-3").Code)
+	})
+
+	t.Run("should not limit without a rate", func(t *testing.T) {
+		server := NewServer(config.Default(), &stubPhoneBook{contacts: map[string]*definition.Contact{"1": {}}}, events.NewHub())
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, get(server, "10.0.0.1:1234", "").Code)
+		}
+	})
+}
+
+func TestClientID(t *testing.T) {
+	cfg := config.Default()
+	cfg.APIKeys = []string{"secret"}
+	handler := newHttpHandler(cfg, &stubPhoneBook{}, events.NewHub())
+	request := func(apiKey string) *http.Request {
+		request := httptest.NewRequest(http.MethodDelete, "/api/v1/contact/1", nil)
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set(apiKeyHeader, apiKey)
+		return request
+	}
+
+	t.Run("should identify a client by a digest of its api key", func(t *testing.T) {
+		assert.Equal(t, "key:2bb80d537b1d", handler.clientID(request("secret")))
+	})
+
+	t.Run("should identify a client sending an unknown api key by ip", func(t *testing.T) {
+		assert.Equal(t, "ip:10.0.0.1", handler.clientID(request("random-1")))
+		assert.Equal(t, "ip:10.0.0.1", handler.clientID(request("random-2")))
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+
+	t.Run("should refill the bucket at the rate", func(t *testing.T) {
+		limiter := newRateLimiter()
+		assert.Zero(t, limiter.reserve("ip:10.0.0.1", now, rate.Limit(1), 1))
+		assert.Equal(t, time.Second, limiter.reserve("ip:10.0.0.1", now, rate.Limit(1), 1))
+		assert.Zero(t, limiter.reserve("ip:10.0.0.1", now.Add(time.Second), rate.Limit(1), 1))
+	})
+
+	t.Run("should forget idle clients", func(t *testing.T) {
+		limiter := newRateLimiter()
+		limiter.reserve("ip:10.0.0.1", now, rate.Limit(1), 1)
+		limiter.reserve("ip:10.0.0.2", now.Add(time.Minute), rate.Limit(1), 1)
+		assert.Len(t, limiter.clients, 2)
+		limiter.reserve("ip:10.0.0.2", now.Add(clientIdleTimeout+time.Second), rate.Limit(1), 1)
+		assert.Len(t, limiter.clients, 1)
+		assert.Contains(t, limiter.clients, "ip:10.0.0.2")
+	})
+}
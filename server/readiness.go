@@ -0,0 +1,31 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// readinessResponse tells whether the service can serve requests.
+type readinessResponse struct {
+	Status string `json:"status"`
+}
+
+// SetReadiness makes /readyz report ready as long as ready returns true. The
+// service is always ready otherwise. Call it before Start.
+func (s *Server) SetReadiness(ready func() bool) {
+	s.handler.ready = ready
+}
+
+// Readyz answers readiness probes: 200 when the database answers, 503 when
+// it doesn't, so load balancers stop routing requests to this replica.
+func (h *httpHandlerStruct) Readyz(w http.ResponseWriter, r *http.Request) {
+	status, body := http.StatusOK, readinessResponse{Status: "ready"}
+	if h.ready != nil && !h.ready() {
+		status, body = http.StatusServiceUnavailable, readinessResponse{Status: "unavailable"}
+	}
+	response, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+)
+
+// @Summary Link a contact to another
+// @Description Links the contact to another one by the type of their relation, e.g. manager when the other contact is the manager of this one. Linking contacts already linked replaces the type
+// @Accept json
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param relation body definition.Relation true "The linked contact and the type of the relation: spouse, assistant, manager or colleague"
+// @Success 200 {string} string "Message indicating successful link"
+// @Failure 400 {object} server.errorResponse "invalid relation"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "contact was modified by another client"
+// @Router /contact/{id}/relations [post]
+func (h *httpHandlerStruct) LinkContact(w http.ResponseWriter, r *http.Request) {
+	var relation definition.Relation
+	if err := json.NewDecoder(r.Body).Decode(&relation); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	params := mux.Vars(r)
+	result, err := h.phoneBook.LinkContact(r.Context(), params["id"], &relation, extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	var response []byte
+	if result.Modified == 0 {
+		response, _ = json.Marshal("contacts are linked already")
+	} else {
+		response, _ = json.Marshal("linked contacts successfully")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Unlink a contact from another
+// @Description Removes the link of the contact to another one
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param relatedId path string true "ID of the linked contact (24 characters)"
+// @Success 200 {string} string "Message indicating successful unlink"
+// @Failure 400 {object} server.errorResponse "invalid id"
+// @Failure 404 {object} server.errorResponse "contact not found or the contacts are not linked"
+// @Router /contact/{id}/relations/{relatedId} [delete]
+func (h *httpHandlerStruct) UnlinkContact(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	_, err := h.phoneBook.UnlinkContact(r.Context(), params["id"], params["relatedId"], extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal("unlinked contacts successfully")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get the related contacts of a contact
+// @Description Returns the contacts linked to the contact with the type of each link, most recently linked first. Links to deleted contacts are left out
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Success 200 {array} definition.RelatedContact
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Router /contact/{id}/related [get]
+func (h *httpHandlerStruct) GetRelatedContacts(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	related, status, err := h.phoneBook.GetRelatedContacts(r.Context(), params["id"])
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(related)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
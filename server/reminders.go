@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+)
+
+// @Summary Schedule a reminder to follow up with a contact
+// @Description Schedules a reminder, sent to REMINDER_WEBHOOK_URL with the contact once it's due
+// @Accept json
+// @Produce json
+// @Param id path string true "Contact ID (24 characters)"
+// @Param reminder body definition.Reminder true "The dueAt time and note of the reminder. The _id, contactId, actor, createdAt and firedAt are set by the server"
+// @Success 200 {string} string "ID of the scheduled reminder"
+// @Failure 400 {object} server.errorResponse "invalid reminder"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Router /contact/{id}/reminder [post]
+func (h *httpHandlerStruct) AddReminder(w http.ResponseWriter, r *http.Request) {
+	var reminder *definition.Reminder
+	if err := json.NewDecoder(r.Body).Decode(&reminder); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	if reminder == nil {
+		h.handleError(ErrInvalidBody, w, r, http.StatusBadRequest)
+		return
+	}
+	if len(reminder.Note) > h.cfg.MaxSizeProperty {
+		h.handleError(ErrFieldTooLong.WithField("note"), w, r, http.StatusBadRequest)
+		return
+	}
+	params := mux.Vars(r)
+	result, err := h.phoneBook.AddReminder(r.Context(), params["id"], reminder, extractActor(r))
+	if err != nil {
+		httpStatus := extractStatus(core.StatusOf(err))
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result.ID)
+}
+
+// @Summary List the pending reminders
+// @Description Returns the reminders not sent yet in due order, up to MAX_PAGE_SIZE
+// @Produce json
+// @Param due query string false "today for the reminders due by the end of today, in the time zone of the server, overdue for those already due"
+// @Success 200 {array} definition.Reminder
+// @Failure 400 {object} server.errorResponse "invalid due"
+// @Router /reminders [get]
+func (h *httpHandlerStruct) GetReminders(w http.ResponseWriter, r *http.Request) {
+	reminders, status, err := h.phoneBook.GetReminders(r.Context(), r.URL.Query().Get("due"))
+	if err != nil {
+		httpStatus := extractStatus(status)
+		h.handleError(err, w, r, httpStatus)
+		return
+	}
+	response, _ := json.Marshal(reminders)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
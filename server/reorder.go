@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+)
+
+// @Summary Pin contacts to the top of the listings
+// @Description Pins the contacts of ids to the top of the contact listings in the order sent, up to 100, and unpins those pinned before and left out. Send no ids to unpin every contact. The listings sorted by a field keep the order of that field
+// @Accept json
+// @Produce json
+// @Param order body definition.ContactOrder true "ids of the contacts to pin, in order"
+// @Success 200 {object} definition.ReorderResult
+// @Failure 400 {object} server.errorResponse "invalid order or id"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/order [put]
+func (h *httpHandlerStruct) ReorderContacts(w http.ResponseWriter, r *http.Request) {
+	var order definition.ContactOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	result, err := h.phoneBook.ReorderContacts(r.Context(), &order, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strconv"
+)
+
+// @Summary Erase the contacts of a phone number
+// @Description Deletes every contact whose phone is the given number once both are normalized, for requests to be forgotten, with their history, interactions and reminders, and the history entries holding the number, like those of contacts deleted earlier. Contacts with the number are left out of later restores of older backups. Erasures aren't recorded in the history and can't be undone
+// @Produce json
+// @Param phone query string true "Phone number, e.g. +972 52-123-4567"
+// @Success 200 {object} definition.ErasureResult
+// @Failure 400 {object} server.errorResponse "invalid phone number"
+// @Failure 429 {object} server.errorResponse "deletes blocked for deleting too many contacts"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/erase [delete]
+func (h *httpHandlerStruct) EraseContacts(w http.ResponseWriter, r *http.Request) {
+	result, err := h.phoneBook.EraseContacts(r.Context(), r.URL.Query().Get("phone"), extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Apply a retention policy
+// @Description Anonymizes or purges the contacts not updated for days days, the RETENTION_DAYS and RETENTION_ACTION policy unless given. Anonymizing clears every field but the company and job title, purging deletes the contacts. Either way their history, interactions and reminders are deleted. With dryRun only reports the contacts
+// @Produce json
+// @Param action query string false "anonymize or purge" Enums(anonymize, purge)
+// @Param days query int false "Days since the last update"
+// @Param dryRun query bool false "Only report the contacts"
+// @Success 200 {object} definition.RetentionResult
+// @Failure 400 {object} server.errorResponse "invalid action or days"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /admin/retention [post]
+func (h *httpHandlerStruct) ApplyRetention(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	policy := &definition.RetentionPolicy{Action: h.cfg.RetentionAction, Days: h.cfg.RetentionDays}
+	if action := query.Get("action"); action != "" {
+		policy.Action = action
+	}
+	if days := query.Get("days"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil {
+			h.handleError(core.ErrRetentionDays, w, r, http.StatusBadRequest)
+			return
+		}
+		policy.Days = parsed
+	}
+	if dryRun := query.Get("dryRun"); dryRun != "" {
+		parsed, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			h.handleError(ErrInvalidDryRun, w, r, http.StatusBadRequest)
+			return
+		}
+		policy.DryRun = parsed
+	}
+	result, err := h.phoneBook.ApplyRetention(r.Context(), policy, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Migrate the contacts
+// @Description Upgrades the contacts written at older schema versions by the migrations since, in order, like on startup with MONGO_MIGRATE_ON_STARTUP. With dryRun only reports the migrations pending and their contacts. Allowed during maintenance
+// @Produce json
+// @Param dryRun query bool false "Only report the pending migrations"
+// @Success 200 {object} definition.MigrationResult
+// @Failure 400 {object} server.errorResponse "invalid dryRun"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /admin/migrate [post]
+func (h *httpHandlerStruct) Migrate(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.handleError(ErrInvalidDryRun, w, r, http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+	result, err := h.phoneBook.Migrate(r.Context(), dryRun)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
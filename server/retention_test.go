@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+// retentionPhoneBook keeps the policy it applied, the phone it erased and
+// whether it migrated in a dry run.
+type retentionPhoneBook struct {
+	stubPhoneBook
+	policy *definition.RetentionPolicy
+	erased string
+	dryRun bool
+}
+
+func (pb *retentionPhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	pb.policy = policy
+	return &definition.RetentionResult{Action: policy.Action, IDs: []string{}, DryRun: policy.DryRun}, nil
+}
+
+func (pb *retentionPhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	pb.erased = phone
+	return &definition.ErasureResult{Contacts: 1, IDs: []string{"1"}}, nil
+}
+
+func (pb *retentionPhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	pb.dryRun = dryRun
+	return &definition.MigrationResult{SchemaVersion: 1, Migrations: []definition.MigrationStep{}, DryRun: dryRun}, nil
+}
+
+func TestRetention(t *testing.T) {
+	cfg := adminConfig()
+	cfg.RetentionDays = 365
+	phoneBook := &retentionPhoneBook{}
+	server := NewServer(cfg, phoneBook, events.NewHub())
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, asAdmin(httptest.NewRequest(method, target, nil)))
+		return recorder
+	}
+
+	t.Run("should apply the configured policy unless overridden", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/v1/admin/retention").Code)
+		assert.Equal(t, &definition.RetentionPolicy{Action: "anonymize", Days: 365}, phoneBook.policy)
+		recorder := serve(http.MethodPost, "/api/v1/admin/retention?action=purge&days=30&dryRun=true")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, &definition.RetentionPolicy{Action: "purge", Days: 30, DryRun: true}, phoneBook.policy)
+		assert.JSONEq(t, `{"action":"purge","affected":0,"ids":[],"dryRun":true}`, recorder.Body.String())
+	})
+
+	t.Run("should reject invalid days and dryRun", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/admin/retention?days=year").Code)
+		recorder := serve(http.MethodPost, "/api/v1/admin/retention?dryRun=maybe")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"INVALID_DRY_RUN"`)
+	})
+
+	t.Run("should erase the contacts of the phone", func(t *testing.T) {
+		recorder := serve(http.MethodDelete, "/api/v1/contact/erase?phone=%2B972521234567")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "+972521234567", phoneBook.erased)
+		assert.Contains(t, recorder.Body.String(), `"contacts":1`)
+	})
+	t.Run("should migrate the contacts, or only report in a dry run", func(t *testing.T) {
+		recorder := serve(http.MethodPost, "/api/v1/admin/migrate?dryRun=true")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, phoneBook.dryRun)
+		assert.JSONEq(t, `{"schemaVersion":1,"migrations":[],"dryRun":true}`, recorder.Body.String())
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/v1/admin/migrate").Code)
+		assert.False(t, phoneBook.dryRun)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/admin/migrate?dryRun=maybe").Code)
+	})
+}
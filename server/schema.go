@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"phoneBook/definition"
+	"phoneBook/docs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The routes whose body is a contact, validated against the contact schema.
+const (
+	addContactRoute           = "addContact"
+	updateContactRoute        = "updateContact"
+	upsertContactByPhoneRoute = "upsertContactByPhone"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// contactDefinition names the contact among the definitions of the spec.
+const contactDefinition = "definition.Contact"
+
+// schema is the part of JSON Schema the definitions of the spec use.
+type schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Definitions          map[string]*schema `json:"definitions,omitempty"`
+}
+
+// newContactSchema builds the JSON Schema of a contact from its definition
+// in the embedded spec, so both describe the same contact: the objects
+// don't take other fields, and the text fields are capped at maxLength.
+func newContactSchema(maxLength int) (*schema, error) {
+	content, err := docs.UI.ReadFile("swagger.json")
+	if err != nil {
+		return nil, err
+	}
+	// the definitions are decoded as they're referred to, as the others may
+	// use more of JSON Schema
+	var spec struct {
+		Definitions map[string]json.RawMessage `json:"definitions"`
+	}
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, err
+	}
+	root, err := specDefinition(spec.Definitions, contactDefinition)
+	if err != nil {
+		return nil, err
+	}
+	root.Schema = jsonSchemaDraft
+	root.Title = "Contact"
+	root.Definitions = map[string]*schema{}
+	closed := false
+	root.AdditionalProperties = &closed
+	for _, field := range contactTextFields {
+		if property, ok := root.Properties[field]; ok {
+			property.MaxLength = &maxLength
+		}
+	}
+	if err := root.addDefinitions(root, spec.Definitions); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func specDefinition(definitions map[string]json.RawMessage, name string) (*schema, error) {
+	content, ok := definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("the spec has no %s definition", name)
+	}
+	var definition *schema
+	if err := json.Unmarshal(content, &definition); err != nil {
+		return nil, fmt.Errorf("failed to decode the %s definition: %w", name, err)
+	}
+	return definition, nil
+}
+
+// addDefinitions copies the definitions s refers to, and those they refer
+// to, to the definitions of root, closing their objects too.
+func (s *schema) addDefinitions(root *schema, definitions map[string]json.RawMessage) error {
+	if s.Ref != "" {
+		name, ok := definitionName(s.Ref)
+		if !ok {
+			return fmt.Errorf("unknown reference %s", s.Ref)
+		}
+		if _, ok := root.Definitions[name]; !ok {
+			definition, err := specDefinition(definitions, name)
+			if err != nil {
+				return err
+			}
+			closed := false
+			definition.AdditionalProperties = &closed
+			root.Definitions[name] = definition
+			if err := definition.addDefinitions(root, definitions); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.addDefinitions(root, definitions); err != nil {
+			return err
+		}
+	}
+	for _, property := range s.Properties {
+		if err := property.addDefinitions(root, definitions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func definitionName(ref string) (string, bool) {
+	return strings.CutPrefix(ref, "#/definitions/")
+}
+
+// validate checks value, decoded with json.Number numbers, against s,
+// reporting the issues of every field at path. Nulls pass like absent
+// fields, as the decoder of the handlers leaves them empty.
+func (s *schema) validate(root *schema, path string, value any) definition.Errors {
+	if s.Ref != "" {
+		name, _ := definitionName(s.Ref)
+		return root.Definitions[name].validate(root, path, value)
+	}
+	if value == nil {
+		return nil
+	}
+	var errs definition.Errors
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return definition.Errors{typeError(path, s.Type)}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			field := joinPath(path, name)
+			property, known := s.Properties[name]
+			if !known {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, unknownFieldError(field))
+				}
+				continue
+			}
+			errs = append(errs, property.validate(root, field, object[name])...)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return definition.Errors{typeError(path, s.Type)}
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				errs = append(errs, s.Items.validate(root, path+"["+strconv.Itoa(i)+"]", item)...)
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return definition.Errors{typeError(path, s.Type)}
+		}
+		if s.MaxLength != nil && len(text) > *s.MaxLength {
+			errs = append(errs, ErrFieldTooLong.WithField(path))
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return definition.Errors{typeError(path, s.Type)}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return definition.Errors{typeError(path, s.Type)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return definition.Errors{typeError(path, s.Type)}
+		}
+	}
+	return errs
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func typeError(path string, schemaType string) *definition.Error {
+	if path == "" {
+		return ErrInvalidBody.WithMessage(fmt.Sprintf("the body should be of type %s", schemaType))
+	}
+	return ErrInvalidBody.WithField(path).WithMessage(fmt.Sprintf("invalid %s. %s should be of type %s", path, path, schemaType))
+}
+
+// @Summary Get the JSON Schema of a contact
+// @Description The JSON Schema the contacts sent to add, update and upsert are validated against, for clients to validate them the same way before sending. It is built from the contact of this spec
+// @Produce json
+// @Success 200 {object} object
+// @Router /contact/schema [get]
+func (h *httpHandlerStruct) GetContactSchema(w http.ResponseWriter, r *http.Request) {
+	response, _ := json.Marshal(h.contactSchema)
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(response)
+}
+
+// schemaMiddleware validates the contacts sent as JSON to the contact body
+// routes against the contact schema, reporting every field breaking it at
+// once before the handlers decode them.
+func (h *httpHandlerStruct) schemaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || sentProtobuf(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch route.GetName() {
+		case addContactRoute, updateContactRoute, upsertContactByPhoneRoute:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			err = bodyError(err)
+			h.handleError(err, w, r, decodeErrorStatus(err))
+			return
+		}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			h.handleError(bodyError(err), w, r, http.StatusBadRequest)
+			return
+		}
+		if err := h.contactSchema.validate(h.contactSchema, "", value).Err(); err != nil {
+			h.handleError(err, w, r, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
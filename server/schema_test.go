@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContactSchema(t *testing.T) {
+	cfg := config.Default()
+	response := httptest.NewRecorder()
+	NewServer(cfg, &stubPhoneBook{}, events.NewHub()).Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/contact/schema", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/schema+json", response.Header().Get("Content-Type"))
+	var contactSchema *schema
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &contactSchema))
+	assert.Equal(t, jsonSchemaDraft, contactSchema.Schema)
+	assert.False(t, *contactSchema.AdditionalProperties)
+	assert.Equal(t, cfg.MaxSizeProperty, *contactSchema.Properties["firstName"].MaxLength)
+	assert.False(t, *contactSchema.Definitions["definition.Relation"].AdditionalProperties)
+
+	var fields []string
+	contactType := reflect.TypeOf(definition.Contact{})
+	for i := 0; i < contactType.NumField(); i++ {
+		if name, _, _ := strings.Cut(contactType.Field(i).Tag.Get("json"), ","); name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	var properties []string
+	for name := range contactSchema.Properties {
+		properties = append(properties, name)
+	}
+	assert.ElementsMatch(t, fields, properties, "regenerate the spec with go generate")
+}
+
+func TestSchemaMiddleware(t *testing.T) {
+	server := NewServer(config.Default(), &editPhoneBook{}, events.NewHub())
+	send := func(method string, target string, body string) (int, *errorResponse) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var errResponse *errorResponse
+		json.Unmarshal(response.Body.Bytes(), &errResponse)
+		return response.Code, errResponse
+	}
+
+	t.Run("should report every field breaking the schema", func(t *testing.T) {
+		code, response := send(http.MethodPost, "/api/v1/contact", `{"firstName": 1, "fristName": "Dan", "version": "2", "relations": [{"type": true}]}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, []*fieldError{
+			{Code: "INVALID_BODY", Message: "invalid firstName. firstName should be of type string", Field: "firstName"},
+			{Code: "UNKNOWN_FIELD", Message: "unknown contact field: fristName", Field: "fristName"},
+			{Code: "INVALID_BODY", Message: "invalid relations[0].type. relations[0].type should be of type string", Field: "relations[0].type"},
+			{Code: "INVALID_BODY", Message: "invalid version. version should be of type integer", Field: "version"},
+		}, response.Errors)
+	})
+
+	t.Run("should reject a body that isn't a contact", func(t *testing.T) {
+		code, response := send(http.MethodPut, "/api/v1/contact/by-phone/0521234567", `["Dan"]`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "INVALID_BODY", response.Code)
+	})
+
+	t.Run("should pass a valid contact on", func(t *testing.T) {
+		code, _ := send(http.MethodPut, "/api/v1/contact/edit/1", `{"firstName": "Dan", "version": 2, "createdAt": null}`)
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/definition"
+)
+
+// searchEngineSettings are the settings enabling each search engine but the
+// database.
+var searchEngineSettings = map[string]string{
+	"es":       "SEARCH_URL",
+	"embedded": "EMBEDDED_SEARCH_ENABLED",
+}
+
+// reindexResponse is the number of contacts a reindex indexed.
+type reindexResponse struct {
+	Indexed int64 `json:"indexed"`
+}
+
+// countResponse is the number of contacts matching a search.
+type countResponse struct {
+	Count int64 `json:"count"`
+}
+
+// SetSearchIndex answers the searches of engine, es or embedded, from index.
+// They answer 404 otherwise. Call it before Start.
+func (s *Server) SetSearchIndex(engine string, index definition.ISearchIndex) {
+	if s.handler.searchIndexes == nil {
+		s.handler.searchIndexes = map[string]definition.ISearchIndex{}
+	}
+	s.handler.searchIndexes[engine] = index
+}
+
+// searchDisabledError is ErrSearchDisabled naming the setting enabling
+// engine.
+func searchDisabledError(engine string) error {
+	return ErrSearchDisabled.WithMessage("the " + engine + " search engine is not configured, set " + searchEngineSettings[engine])
+}
+
+// @Summary Rebuild a search index
+// @Description Indexes the contacts of the phone book anew in the search index of engine, then drops the contacts it no longer has. Searches keep answering meanwhile. Allowed during maintenance
+// @Produce json
+// @Param engine query string true "Search engine of the index, es or embedded" Enums(es, embedded)
+// @Success 200 {object} server.reindexResponse
+// @Failure 400 {object} server.errorResponse "invalid engine"
+// @Failure 404 {object} server.errorResponse "the search index of engine is not configured"
+// @Router /admin/search/reindex [post]
+func (h *httpHandlerStruct) ReindexSearch(w http.ResponseWriter, r *http.Request) {
+	engine := r.URL.Query().Get("engine")
+	if _, ok := searchEngineSettings[engine]; !ok {
+		h.handleError(ErrInvalidEngine.WithMessage("invalid engine. engine should be one of: es, embedded"), w, r, http.StatusBadRequest)
+		return
+	}
+	index, ok := h.searchIndexes[engine]
+	if !ok {
+		h.handleError(searchDisabledError(engine), w, r, http.StatusNotFound)
+		return
+	}
+	indexed, err := index.Rebuild(r.Context())
+	if err != nil {
+		h.handleError(err, w, r, http.StatusInternalServerError)
+		return
+	}
+	response, _ := json.Marshal(reindexResponse{Indexed: indexed})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+// stubSearchIndex finds a single contact, keeping the query it searched, and
+// rebuilds from two.
+type stubSearchIndex struct {
+	query url.Values
+}
+
+func (ix *stubSearchIndex) Rebuild(ctx context.Context) (int64, error) {
+	return 2, nil
+}
+
+func (ix *stubSearchIndex) SearchContacts(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	ix.query = query
+	total := int64(1)
+	return &definition.ContactPage{Items: []*definition.Contact{{FirstName: "Dani"}}, TotalItems: &total}, "", nil
+}
+
+// countPhoneBook counts the contacts of a single address.
+type countPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *countPhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	if query.Get("address") == "Tel Aviv" {
+		return 1284, "", nil
+	}
+	return 0, "", nil
+}
+
+func TestCountContacts(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewServer(config.Default(), &countPhoneBook{}, events.NewHub()).Handler().
+		ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/contact/count?address=Tel+Aviv", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"count":1284}`, recorder.Body.String())
+}
+
+// distinctPhoneBook lists the companies of the contacts.
+type distinctPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *distinctPhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	return []*definition.ValueCount{{Value: "Acme", Count: 3}}, "", nil
+}
+
+func TestGetDistinctValues(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewServer(config.Default(), &distinctPhoneBook{}, events.NewHub()).Handler().
+		ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/contact/distinct?field=company", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[{"value":"Acme","count":3}]`, recorder.Body.String())
+}
+
+func TestSearchEngine(t *testing.T) {
+	server := NewServer(adminConfig(), &stubPhoneBook{}, events.NewHub())
+	search := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/contact/search?engine=es&q=dnai", nil))
+		return recorder
+	}
+
+	t.Run("should answer 404 without a search index", func(t *testing.T) {
+		recorder := search()
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"SEARCH_ENGINE_DISABLED"`)
+		assert.Contains(t, recorder.Body.String(), "SEARCH_URL")
+	})
+
+	t.Run("should search the index with engine=es", func(t *testing.T) {
+		index := &stubSearchIndex{}
+		server.SetSearchIndex("es", index)
+		recorder := search()
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "1", recorder.Header().Get("X-Total-Count"))
+		assert.Contains(t, recorder.Body.String(), `"firstName":"Dani"`)
+		assert.Equal(t, url.Values{"q": {"dnai"}}, index.query)
+	})
+
+	t.Run("should reject unknown engines", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/contact/search?engine=solr", nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"INVALID_ENGINE"`)
+	})
+
+	t.Run("should rebuild the index of the engine", func(t *testing.T) {
+		reindex := func(engine string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, asAdmin(httptest.NewRequest(http.MethodPost, "/api/v1/admin/search/reindex?engine="+engine, nil)))
+			return recorder
+		}
+		recorder := reindex("es")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"indexed":2}`, recorder.Body.String())
+		recorder = reindex("embedded")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "EMBEDDED_SEARCH_ENABLED")
+		assert.Equal(t, http.StatusBadRequest, reindex("db").Code)
+	})
+}
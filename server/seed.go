@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/core"
+	"strconv"
+)
+
+// seedResponse is the number of contacts a seed added.
+type seedResponse struct {
+	Seeded int64 `json:"seeded"`
+}
+
+// @Summary Seed fake contacts
+// @Description Adds count realistic fake contacts, for demos and load tests. Generated contacts duplicating existing ones are skipped. Requires SEED_ENABLED
+// @Produce json
+// @Param count query int true "Number of contacts to generate, up to 10000"
+// @Success 200 {object} server.seedResponse
+// @Failure 400 {object} server.errorResponse "invalid count"
+// @Failure 404 {object} server.errorResponse "seeding is disabled"
+// @Router /admin/seed [post]
+func (h *httpHandlerStruct) SeedContacts(w http.ResponseWriter, r *http.Request) {
+	if !h.cfg.SeedEnabled {
+		h.handleError(ErrSeedDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil {
+		h.handleError(core.ErrInvalidSeedCount, w, r, http.StatusBadRequest)
+		return
+	}
+	seeded, err := h.phoneBook.SeedContacts(r.Context(), count, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(seedResponse{Seeded: seeded})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get a random contact
+// @Description Returns a contact picked at random, for load tests reading across the whole phone book
+// @Produce json
+// @Success 200 {object} definition.Contact
+// @Failure 404 {object} server.errorResponse "the phone book is empty"
+// @Router /contact/random [get]
+func (h *httpHandlerStruct) GetRandomContact(w http.ResponseWriter, r *http.Request) {
+	contact, status, err := h.phoneBook.GetRandomContact(r.Context())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(contact)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Sample random contacts
+// @Description Returns contacts picked at random, fewer when the phone book holds fewer
+// @Produce json
+// @Param size query int false "Number of contacts (default the page size), clamped to the server maximum"
+// @Success 200 {array} definition.Contact
+// @Failure 400 {object} server.errorResponse "invalid size"
+// @Router /contact/sample [get]
+func (h *httpHandlerStruct) SampleContacts(w http.ResponseWriter, r *http.Request) {
+	contacts, status, err := h.phoneBook.SampleContacts(r.Context(), r.URL.Query())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(contacts)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strconv"
+)
+
+// speedDialDirectory is the speed dials as a CiscoIPPhoneDirectory XML
+// object, the directory format of Cisco IP phones, also read by the XML
+// phonebooks of Yealink, Grandstream and Fanvil phones.
+type speedDialDirectory struct {
+	XMLName xml.Name            `xml:"CiscoIPPhoneDirectory"`
+	Title   string              `xml:"Title"`
+	Prompt  string              `xml:"Prompt"`
+	Entries []speedDialDirEntry `xml:"DirectoryEntry"`
+}
+
+type speedDialDirEntry struct {
+	Name      string `xml:"Name"`
+	Telephone string `xml:"Telephone"`
+}
+
+// @Summary Assign a speed dial slot
+// @Description Assigns a speed dial slot, from 1 to 99, to a contact. A slot holds a single contact and a contact a single slot: clear the slot, or the slot of the contact, before assigning them again. Assigning a slot to its contact again changes nothing
+// @Accept json
+// @Produce json
+// @Param slot path int true "Speed dial slot, from 1 to 99"
+// @Param speedDial body definition.SpeedDial true "The contactId of the slot. The slot, actor and assignedAt are set by the server"
+// @Success 200 {object} definition.SpeedDial
+// @Failure 400 {object} server.errorResponse "invalid slot or contact id"
+// @Failure 404 {object} server.errorResponse "contact not found"
+// @Failure 409 {object} server.errorResponse "slot assigned to another contact, or contact assigned to another slot"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /speed-dial/{slot} [put]
+func (h *httpHandlerStruct) AssignSpeedDial(w http.ResponseWriter, r *http.Request) {
+	var dial *definition.SpeedDial
+	if err := json.NewDecoder(r.Body).Decode(&dial); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	if dial == nil {
+		h.handleError(ErrInvalidBody, w, r, http.StatusBadRequest)
+		return
+	}
+	params := mux.Vars(r)
+	result, err := h.phoneBook.AssignSpeedDial(r.Context(), params["slot"], dial, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary List the speed dial slots
+// @Description Returns the assigned speed dial slots in order, each with its contact. The slots of deleted contacts are listed without a contact, and get it back when the delete is undone
+// @Produce json
+// @Success 200 {array} definition.SpeedDial
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /speed-dial [get]
+func (h *httpHandlerStruct) GetSpeedDials(w http.ResponseWriter, r *http.Request) {
+	dials, status, err := h.phoneBook.GetSpeedDials(r.Context())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(dials)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Clear a speed dial slot
+// @Description Unassigns a speed dial slot
+// @Produce json
+// @Param slot path int true "Speed dial slot, from 1 to 99"
+// @Success 200 {string} string "Message indicating the slot was cleared, or wasn't assigned"
+// @Failure 400 {object} server.errorResponse "invalid slot"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /speed-dial/{slot} [delete]
+func (h *httpHandlerStruct) ClearSpeedDial(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	result, err := h.phoneBook.ClearSpeedDial(r.Context(), params["slot"])
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	var response []byte
+	if result.Modified == 0 {
+		response, _ = json.Marshal("slot is not assigned")
+	} else {
+		response, _ = json.Marshal("cleared slot successfully")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Export the speed dials for IP phones
+// @Description Returns the speed dial slots in order, for IP phones to load. format=xml (default) returns a CiscoIPPhoneDirectory, read by Cisco IP phones and by the XML phonebooks of Yealink, Grandstream and Fanvil phones, each entry named by its slot and contact. format=csv returns the slot, name and phone of each slot with a header row. The slots of deleted contacts, and of contacts without a phone, are left out
+// @Produce xml
+// @Produce text/csv
+// @Param format query string false "Export format: xml (default) or csv"
+// @Success 200 {string} string "The speed dials"
+// @Failure 400 {object} server.errorResponse "invalid format"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /speed-dial/export [get]
+func (h *httpHandlerStruct) ExportSpeedDials(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "xml"
+	}
+	if format != "xml" && format != "csv" {
+		h.handleError(ErrDialFormat, w, r, http.StatusBadRequest)
+		return
+	}
+	dials, status, err := h.phoneBook.GetSpeedDials(r.Context())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	var dialed []*definition.SpeedDial
+	for _, dial := range dials {
+		if dial.Contact != nil && dial.Contact.Phone != "" {
+			dialed = append(dialed, dial)
+		}
+	}
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"slot", "name", "phone"})
+		for _, dial := range dialed {
+			writer.Write([]string{strconv.Itoa(dial.Slot), callerID(dial.Contact).Name, dial.Contact.Phone})
+		}
+		writer.Flush()
+		return
+	}
+	directory := speedDialDirectory{Title: "Speed dial", Prompt: "Select a contact", Entries: []speedDialDirEntry{}}
+	for _, dial := range dialed {
+		name := strconv.Itoa(dial.Slot) + " " + callerID(dial.Contact).Name
+		directory.Entries = append(directory.Entries, speedDialDirEntry{Name: name, Telephone: dial.Contact.Phone})
+	}
+	response, _ := xml.MarshalIndent(directory, "", "  ")
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(response)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+// speedDialPhoneBook has a slot of a contact, and a slot of a deleted one.
+type speedDialPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *speedDialPhoneBook) GetSpeedDials(ctx context.Context) ([]*definition.SpeedDial, string, error) {
+	return []*definition.SpeedDial{
+		{Slot: 1, Contact: &definition.Contact{FirstName: "Security", Phone: "100"}},
+		{Slot: 2},
+		{Slot: 12, Contact: &definition.Contact{FirstName: "Dana", LastName: "Levi & Co", Phone: "0521234567"}},
+	}, "", nil
+}
+
+func TestExportSpeedDials(t *testing.T) {
+	export := func(query string) *httptest.ResponseRecorder {
+		server := NewServer(config.Default(), &speedDialPhoneBook{}, events.NewHub())
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/speed-dial/export?"+query, nil))
+		return recorder
+	}
+
+	t.Run("should export a directory for IP phones", func(t *testing.T) {
+		recorder := export("")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+		body := recorder.Body.String()
+		assert.Contains(t, body, "<CiscoIPPhoneDirectory>")
+		assert.Contains(t, body, "<DirectoryEntry>\n    <Name>1 Security</Name>\n    <Telephone>100</Telephone>\n  </DirectoryEntry>")
+		assert.Contains(t, body, "<Name>12 Dana Levi &amp; Co</Name>")
+		assert.NotContains(t, body, "<Name>2")
+	})
+
+	t.Run("should export csv", func(t *testing.T) {
+		recorder := export("format=csv")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "slot,name,phone\n1,Security,100\n12,Dana Levi & Co,0521234567\n", recorder.Body.String())
+	})
+
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		recorder := export("format=json")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "INVALID_SPEED_DIAL_FORMAT")
+	})
+}
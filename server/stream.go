@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"time"
+)
+
+// streamBatchSize bounds the contacts of a stream added in a single write.
+const streamBatchSize = 500
+
+// streamRoute names the streaming ingestion route, whose body is unbounded,
+// each line being bounded instead.
+const streamRoute = "stream"
+
+var ErrLineTooLong = definition.NewError("LINE_TOO_LONG", "the line is too long. send one contact per line", "")
+
+// streamResult reports a line of a contacts stream, by its number, counted
+// from 1.
+type streamResult struct {
+	Line int `json:"line"`
+	*definition.AddResult
+}
+
+// streamSummary ends the results of a contacts stream. Complete is false when
+// the stream was cut short by Error, the lines past the last result not being
+// read.
+type streamSummary struct {
+	Added    int64          `json:"added"`
+	Rejected int64          `json:"rejected"`
+	Complete bool           `json:"complete"`
+	Error    *errorResponse `json:"error,omitempty"`
+}
+
+// contactStream adds the contacts of a stream batch by batch, writing the
+// result of each line as it goes.
+type contactStream struct {
+	h          *httpHandlerStruct
+	w          http.ResponseWriter
+	r          *http.Request
+	controller *http.ResponseController
+	encoder    *json.Encoder
+	body       *startedWriter
+	lines      []int
+	contacts   []*definition.Contact
+	summary    streamSummary
+}
+
+// @Summary Stream contacts in
+// @Description Adds the contacts of a newline delimited JSON body, one contact per line, for ETL pipelines pushing millions of contacts on a long-lived request. The contacts are validated and added in batches of up to 500 as they arrive, and the result of each line is streamed back as it is added, with the id of the contact or the error it was rejected with, followed by a summary line. Each line is bounded by MAX_BODY_BYTES, not the whole body. Contacts are streamed in with MongoDB only
+// @Accept application/x-ndjson
+// @Produce application/x-ndjson
+// @Param contacts body string true "One contact per line, as for POST /contact"
+// @Success 200 {object} server.streamResult "one result per line, then a server.streamSummary"
+// @Failure 402 {object} server.errorResponse "the contacts would exceed the contact limit of the tenant"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/stream [post]
+func (h *httpHandlerStruct) StreamContacts(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+	// the results are written while the contacts are still read, for as long
+	// as the client sends them
+	controller.EnableFullDuplex()
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	body := &startedWriter{Writer: w}
+	stream := &contactStream{h: h, w: w, r: r, controller: controller, encoder: json.NewEncoder(body), body: body}
+	reader := bufio.NewReaderSize(r.Body, 64<<10)
+	for number := 1; ; number++ {
+		line, err := readLine(reader, h.cfg.MaxBodyBytes)
+		if errors.Is(err, ErrLineTooLong) {
+			stream.reject(number, err)
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			stream.fail(bodyError(err), http.StatusBadRequest)
+			return
+		} else if len(bytes.TrimSpace(line)) > 0 {
+			stream.decode(number, line)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		// a batch is added once full, or when the client paused sending
+		if len(stream.contacts) == streamBatchSize || reader.Buffered() == 0 {
+			if !stream.flush() {
+				return
+			}
+		}
+	}
+	if !stream.flush() {
+		return
+	}
+	stream.summary.Complete = true
+	stream.encoder.Encode(stream.summary)
+}
+
+// decode queues the contact of a line, or rejects the line when it isn't a
+// valid contact.
+func (s *contactStream) decode(number int, line []byte) {
+	contact, err := decodeJSONContact(bytes.NewReader(line))
+	if err != nil {
+		s.reject(number, err)
+		return
+	}
+	if contact == nil {
+		s.reject(number, ErrInvalidBody)
+		return
+	}
+	if err := s.h.validateContactSizeInput(contact); err != nil {
+		s.reject(number, err)
+		return
+	}
+	s.lines = append(s.lines, number)
+	s.contacts = append(s.contacts, contact)
+}
+
+// flush adds the queued contacts and writes their results, reporting whether
+// the stream goes on.
+func (s *contactStream) flush() bool {
+	if len(s.contacts) > 0 {
+		results, err := s.h.phoneBook.AddContacts(s.r.Context(), s.contacts, extractActor(s.r))
+		if err != nil {
+			s.fail(err, extractStatus(core.StatusOf(err)))
+			return false
+		}
+		for i, result := range results {
+			if result.ID != "" {
+				s.summary.Added++
+			} else {
+				s.summary.Rejected++
+			}
+			s.encoder.Encode(streamResult{Line: s.lines[i], AddResult: result})
+		}
+		s.lines, s.contacts = s.lines[:0], s.contacts[:0]
+	}
+	if err := s.controller.Flush(); err != nil {
+		logrus.WithError(err).WithField("requestId", s.r.Header.Get(requestIDHeader)).Warn("failed to flush the stream results")
+	}
+	return true
+}
+
+func (s *contactStream) reject(number int, err error) {
+	s.summary.Rejected++
+	result := &definition.AddResult{Error: err.Error()}
+	var typedErr *definition.Error
+	if errors.As(err, &typedErr) {
+		result.Code = typedErr.Code
+	}
+	s.encoder.Encode(streamResult{Line: number, AddResult: result})
+}
+
+// fail ends the stream with err, answering it as a regular error when no
+// result was written yet.
+func (s *contactStream) fail(err error, status int) {
+	if !s.body.started {
+		s.h.handleError(err, s.w, s.r, status)
+		return
+	}
+	requestID := s.r.Header.Get(requestIDHeader)
+	logrus.WithError(err).WithField("requestId", requestID).Error("contacts stream failed")
+	s.summary.Error = newErrorResponse(err, status, requestID, requestLanguage(s.r))
+	s.encoder.Encode(s.summary)
+}
+
+// readLine reads a line of r without its line break. A line longer than max
+// bytes is skipped and fails with ErrLineTooLong. The last line may have no
+// line break, and is returned with io.EOF, as is the empty line after it.
+func readLine(r *bufio.Reader, max int64) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong && int64(len(line)+len(chunk)) > max+1 {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if tooLong && (err == nil || errors.Is(err, io.EOF)) {
+			return nil, ErrLineTooLong
+		}
+		return bytes.TrimRight(line, "\r\n"), err
+	}
+}
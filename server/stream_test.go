@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+// batchPhoneBook adds the contacts with a phone, counting the batches.
+type batchPhoneBook struct {
+	stubPhoneBook
+	batches int
+}
+
+func (pb *batchPhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	pb.batches++
+	results := make([]*definition.AddResult, len(contacts))
+	for i, contact := range contacts {
+		results[i] = &definition.AddResult{ID: fmt.Sprintf("65a%d", pb.batches*10+i)}
+		if contact.Phone == "" {
+			results[i] = &definition.AddResult{Code: "MISSING_PHONE", Error: core.ErrorMissingPhone}
+		}
+	}
+	return results, nil
+}
+
+func TestStreamContacts(t *testing.T) {
+	stream := func(cfg config.Config, phoneBook definition.IPhoneBook, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		NewServer(cfg, phoneBook, events.NewHub()).Handler().ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/v1/contact/stream", strings.NewReader(body)))
+		return response
+	}
+
+	t.Run("should report the result of each line", func(t *testing.T) {
+		body := `{"firstName":"Dani","phone":"0521234567"}` + "\n" + `{"firstName":` + "\n\n" + `{"firstName":"Noa"}`
+		response := stream(config.Default(), &batchPhoneBook{}, body)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "application/x-ndjson", response.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(response.Body.String()), "\n")
+		assert.Len(t, lines, 4)
+		assert.JSONEq(t, `{"line":2,"code":"INVALID_BODY","error":"unexpected EOF"}`, lines[0])
+		assert.JSONEq(t, `{"line":1,"id":"65a10"}`, lines[1])
+		assert.JSONEq(t, `{"line":4,"code":"MISSING_PHONE","error":"`+core.ErrorMissingPhone+`"}`, lines[2])
+		assert.JSONEq(t, `{"added":1,"rejected":2,"complete":true}`, lines[3])
+	})
+
+	t.Run("should bound each line rather than the body", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.MaxBodyBytes = 64
+		line := `{"firstName":"Dani","phone":"0521234567"}` + "\n"
+		body := strings.Repeat(line, 3) + `{"notes":"` + strings.Repeat("a", 100) + `"}` + "\n" + line
+		response := stream(cfg, &batchPhoneBook{}, body)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `{"line":4,"code":"LINE_TOO_LONG"`)
+		assert.Contains(t, response.Body.String(), `{"added":4,"rejected":1,"complete":true}`)
+	})
+
+	t.Run("should answer an error before any result", func(t *testing.T) {
+		response := stream(config.Default(), &stubPhoneBook{}, `{"firstName":"Dani","phone":"0521234567"}`)
+		assert.Equal(t, http.StatusNotImplemented, response.Code)
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	})
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"phoneBook/definition"
+	"phoneBook/reconcile"
+	"time"
+)
+
+// maxSyncChanges bounds the changes a client sends in one sync.
+const maxSyncChanges = 1000
+
+var (
+	ErrInvalidSyncPolicy = definition.NewError("INVALID_SYNC_POLICY", "invalid policy. policy should be one of: lww, merge", "policy")
+	ErrTooManyChanges    = definition.NewError("TOO_MANY_CHANGES", "too many changes. send up to 1000 changes at once", "changes")
+)
+
+// syncRequest is the changes a client made since it last synced.
+type syncRequest struct {
+	Changes []*definition.ClientChange `json:"changes"`
+}
+
+// syncResponse reports how each change of a sync was applied, in order.
+type syncResponse struct {
+	Results []*definition.ChangeResult `json:"results"`
+}
+
+// deletedContact is the tombstone of a contact deleted since a time or sync
+// token.
+type deletedContact struct {
+	ID        string     `json:"id"`
+	DeletedAt *time.Time `json:"deletedAt"`
+	DeletedBy string     `json:"deletedBy,omitempty"`
+}
+
+// changesResponse is the contacts created, updated and deleted since a time
+// or sync token.
+type changesResponse struct {
+	Created   []*definition.Contact `json:"created"`
+	Updated   []*definition.Contact `json:"updated"`
+	Deleted   []*deletedContact     `json:"deleted"`
+	SyncToken string                `json:"syncToken"`
+	More      bool                  `json:"more"`
+}
+
+// @Summary Get the changes to the contacts
+// @Description Returns the changes to the contacts since token, oldest first: the contacts added or updated, as they are now, and the tombstones of those deleted, with when and by whom. Without a token returns every contact, for a first sync. Send the syncToken of the response to get the next changes, right away while more is true. Tokens expire after SYNC_TOMBSTONE_TTL, or when a backup is restored, and the client has to sync every contact again without a token
+// @Produce json
+// @Param token query string false "Sync token of the last changes synced"
+// @Param limit query int false "Maximum number of changes"
+// @Success 200 {object} definition.ChangePage
+// @Failure 400 {object} server.errorResponse "invalid token or limit"
+// @Failure 410 {object} server.errorResponse "the sync token expired"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /sync [get]
+func (h *httpHandlerStruct) GetChanges(w http.ResponseWriter, r *http.Request) {
+	page, status, err := h.phoneBook.GetChanges(r.Context(), r.URL.Query())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(page)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Sync the changes of a client
+// @Description Applies the changes a client made to its copy of the contacts, in order, up to 1000 at once. Changes to existing contacts carry the version the client last synced, and are applied as is unless the contact was changed since. Then the lww policy applies the change only when modifiedAt is after the last update, and the merge policy applies the fields whose value is still their base value, keeping the value of the others. Deleting a contact changed since is a conflict under merge, changing a contact deleted since is a conflict under both. New contacts carry a clientId instead of an id. The policy is SYNC_CONFLICT_POLICY unless given
+// @Accept json
+// @Produce json
+// @Param policy query string false "Conflict policy" Enums(lww, merge)
+// @Param changes body server.syncRequest true "Changes of the client"
+// @Success 200 {object} server.syncResponse
+// @Failure 400 {object} server.errorResponse "invalid policy or changes"
+// @Failure 413 {object} server.errorResponse "request body too large"
+// @Router /sync [post]
+func (h *httpHandlerStruct) SyncChanges(w http.ResponseWriter, r *http.Request) {
+	policy := h.cfg.SyncConflictPolicy
+	if value := r.URL.Query().Get("policy"); value != "" {
+		policy = value
+	}
+	if policy != definition.SyncPolicyLastWriterWins && policy != definition.SyncPolicyMerge {
+		h.handleError(ErrInvalidSyncPolicy, w, r, http.StatusBadRequest)
+		return
+	}
+	var request syncRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	if len(request.Changes) > maxSyncChanges {
+		h.handleError(ErrTooManyChanges, w, r, http.StatusBadRequest)
+		return
+	}
+	for _, change := range request.Changes {
+		if change == nil {
+			h.handleError(ErrInvalidBody, w, r, http.StatusBadRequest)
+			return
+		}
+		if err := h.validatePatchSizeInput(change.Fields); err != nil {
+			h.handleError(err, w, r, http.StatusBadRequest)
+			return
+		}
+	}
+	results := reconcile.Apply(r.Context(), h.phoneBook, request.Changes, policy, extractActor(r))
+	response, _ := json.Marshal(syncResponse{Results: results})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get the contacts changed since
+// @Description Returns the contacts created and updated since a time or a sync token, as they are now, and the tombstones of those deleted, with when and by whom, for offline clients to sync incrementally. Without since returns every contact as created. Send the syncToken of the response as since to get the next changes, right away while more is true. Like GET /sync, times older than SYNC_TOMBSTONE_TTL and tokens expired answer 410, and the client has to download every contact again
+// @Produce json
+// @Param since query string false "RFC 3339 time, e.g. 2024-01-12T10:00:00Z, or sync token of the last changes synced"
+// @Param limit query int false "Maximum number of changes"
+// @Success 200 {object} server.changesResponse
+// @Failure 400 {object} server.errorResponse "invalid since or limit"
+// @Failure 410 {object} server.errorResponse "since is too old or the sync token expired"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /contact/changes [get]
+func (h *httpHandlerStruct) GetContactChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, status, err := h.phoneBook.GetChanges(r.Context(), url.Values{"since": {query.Get("since")}, "limit": query["limit"]})
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	changes := changesResponse{
+		Created:   []*definition.Contact{},
+		Updated:   []*definition.Contact{},
+		Deleted:   []*deletedContact{},
+		SyncToken: page.SyncToken,
+		More:      page.More,
+	}
+	for _, change := range page.Changes {
+		switch {
+		case change.Deleted:
+			changes.Deleted = append(changes.Deleted, &deletedContact{ID: change.ID, DeletedAt: change.DeletedAt, DeletedBy: change.DeletedBy})
+		case change.Created:
+			changes.Created = append(changes.Created, change.Contact)
+		default:
+			changes.Updated = append(changes.Updated, change.Contact)
+		}
+	}
+	response, _ := json.Marshal(changes)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
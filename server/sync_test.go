@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syncPhoneBook serves the changes since the token "t1", the others having
+// expired.
+type syncPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *syncPhoneBook) GetChanges(ctx context.Context, query url.Values) (*definition.ChangePage, string, error) {
+	if query.Get("since") == "t1" {
+		deletedAt := time.Date(2024, 1, 12, 10, 0, 0, 0, time.UTC)
+		return &definition.ChangePage{Changes: []*definition.ContactChange{
+			{ID: "65a2", Created: true, Contact: &definition.Contact{FirstName: "Noa"}, SyncToken: "t2"},
+			{ID: "65a3", Contact: &definition.Contact{FirstName: "Dani"}, SyncToken: "t3"},
+			{ID: "65a1", Deleted: true, DeletedAt: &deletedAt, DeletedBy: "dani", SyncToken: "t4"},
+		}, SyncToken: "t4", More: true}, "", nil
+	}
+	if query.Get("token") != "t1" {
+		return nil, core.Gone, core.ErrSyncTokenExpired
+	}
+	return &definition.ChangePage{Changes: []*definition.ContactChange{{ID: "65a1", Deleted: true, SyncToken: "t2"}}, SyncToken: "t2"}, "", nil
+}
+
+func TestSync(t *testing.T) {
+	server := NewServer(config.Default(), &syncPhoneBook{stubPhoneBook{contacts: map[string]*definition.Contact{
+		"65a1": {FirstName: "Dani", Phone: "0521234567", Version: 2},
+	}}}, events.NewHub())
+
+	t.Run("should return the changes since the token", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/sync?token=t1", nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"changes":[{"id":"65a1","deleted":true,"syncToken":"t2"}],"syncToken":"t2","more":false}`, response.Body.String())
+	})
+
+	t.Run("should answer 410 when the token expired", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/sync?token=t0", nil))
+		assert.Equal(t, http.StatusGone, response.Code)
+		assert.Contains(t, response.Body.String(), "SYNC_TOKEN_EXPIRED")
+	})
+
+	t.Run("should group the changes since by kind", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/contact/changes?since=t1", nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"created":[{"_id":"000000000000000000000000","firstName":"Noa"}],"updated":[{"_id":"000000000000000000000000","firstName":"Dani"}],"deleted":[{"id":"65a1","deletedAt":"2024-01-12T10:00:00Z","deletedBy":"dani"}],"syncToken":"t4","more":true}`, response.Body.String())
+	})
+
+	t.Run("should report the conflicts of the changes", func(t *testing.T) {
+		body := `{"changes":[{"id":"65a1","version":1,"fields":{"firstName":"Daniel"},"base":{"firstName":"Dan"}}]}`
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest("POST", "/api/v1/sync", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"results":[{"id":"65a1","status":"conflict","conflicts":["firstName"],"contact":{"_id":"000000000000000000000000","firstName":"Dani","phone":"0521234567","version":2}}]}`, response.Body.String())
+	})
+
+	t.Run("should reject an unknown policy", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest("POST", "/api/v1/sync?policy=newest", strings.NewReader(`{"changes":[]}`)))
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), "INVALID_SYNC_POLICY")
+	})
+}
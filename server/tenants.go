@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+	"math"
+	"net"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tenantHeader = "X-Tenant-ID"
+
+var (
+	ErrMissingTenant     = definition.NewError("MISSING_TENANT", "missing tenant. send its id in the "+tenantHeader+" header or as the subdomain", "")
+	ErrTenancyDisabled   = definition.NewError("TENANCY_DISABLED", "tenancy is disabled, set TENANCY_ENABLED", "")
+	ErrTenantRateLimited = definition.NewError("TENANT_RATE_LIMITED", "the tenant exceeded its requests per minute, retry later", "")
+)
+
+// tenantUsage is what a tenant stores, with the limits applying to it.
+type tenantUsage struct {
+	Tenant string                  `json:"tenant"`
+	Usage  *definition.Usage       `json:"usage"`
+	Limits definition.TenantLimits `json:"limits"`
+}
+
+// tenantFreeRoutes serve the whole service rather than the phone book of a
+// tenant, so they are called without one.
+var tenantFreeRoutes = map[string]bool{
+	"/":                             true,
+	"/metrics":                      true,
+	"/readyz":                       true,
+	"/docs":                         true,
+	"/docs/":                        true,
+	"/swagger.json":                 true,
+	"/admin/config":                 true,
+	"/admin/crm/dead-letters":       true,
+	"/admin/crm/dead-letters/retry": true,
+	"/admin/maintenance":            true,
+	"/admin/tenants":                true,
+	"/admin/tenants/{id}":           true,
+	"/admin/tenants/{id}/limits":    true,
+	"/admin/tenants/{id}/usage":     true,
+}
+
+// SetTenants scopes the requests to the tenants provisioned in tenants: each
+// has to name a known tenant, which the phone book then serves. Call it
+// before Start.
+func (s *Server) SetTenants(tenants definition.ITenants) {
+	s.handler.tenants = tenants
+}
+
+// tenantMiddleware scopes the context of each request to the tenant it
+// names, answering 400 when it names none and 404 when the tenant is unknown.
+// Tenants over their requests per minute get 429, and their phone book is
+// limited to their maximum contacts.
+func (h *httpHandlerStruct) tenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.tenants == nil || tenantFreeRoutes[routePath(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenant := h.requestTenant(r)
+		if tenant == "" {
+			h.handleError(ErrMissingTenant, w, r, http.StatusBadRequest)
+			return
+		}
+		found, err := h.tenants.LookupTenant(r.Context(), tenant)
+		if err != nil {
+			h.handleError(err, w, r, http.StatusInternalServerError)
+			return
+		}
+		if found == nil {
+			h.handleError(core.ErrTenantNotFound, w, r, http.StatusNotFound)
+			return
+		}
+		limits := h.tenantLimits(found)
+		if perMinute := limits.MaxRequestsPerMinute; perMinute > 0 {
+			// the bucket holds a minute of requests, refilled evenly
+			limit := rate.Limit(float64(perMinute) / 60)
+			if wait := h.tenantLimiter.reserve(tenant, time.Now(), limit, perMinute); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				h.handleError(ErrTenantRateLimited, w, r, http.StatusTooManyRequests)
+				return
+			}
+		}
+		ctx := definition.WithTenant(r.Context(), tenant)
+		if limits.MaxContacts > 0 {
+			ctx = definition.WithContactLimit(ctx, limits.MaxContacts)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// tenantLimits returns the limits of the tenant, the configured defaults in
+// place of those it has none of.
+func (h *httpHandlerStruct) tenantLimits(tenant *definition.Tenant) definition.TenantLimits {
+	limits := tenant.Limits
+	if limits.MaxContacts == 0 {
+		limits.MaxContacts = h.cfg.TenantMaxContacts
+	}
+	if limits.MaxRequestsPerMinute == 0 {
+		limits.MaxRequestsPerMinute = h.cfg.TenantMaxRequestsPerMinute
+	}
+	return limits
+}
+
+// requestTenant returns the tenant of the X-Tenant-ID header, or else the
+// subdomain of TENANT_DOMAIN the request was sent to, if any.
+func (h *httpHandlerStruct) requestTenant(r *http.Request) string {
+	if tenant := r.Header.Get(tenantHeader); tenant != "" {
+		return tenant
+	}
+	if h.cfg.TenantDomain == "" {
+		return ""
+	}
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	subdomain, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(h.cfg.TenantDomain))
+	if !ok || strings.Contains(subdomain, ".") {
+		return ""
+	}
+	return subdomain
+}
+
+// routePath returns the path template of the route the request matched,
+// without its version prefix.
+func routePath(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	path, _ := route.GetPathTemplate()
+	return versionlessPath(path)
+}
+
+// @Summary Provision a tenant
+// @Description Creates a tenant with a phone book of its own, in a database of its own. Requests are scoped to it by its id in the X-Tenant-ID header, or as the subdomain of TENANT_DOMAIN. Requires TENANCY_ENABLED
+// @Accept json
+// @Produce json
+// @Param tenant body definition.Tenant true "id of the tenant, up to 32 lowercase letters, digits and dashes, its optional name and limits"
+// @Success 200 {object} definition.Tenant
+// @Failure 400 {object} server.errorResponse "invalid tenant id or limits"
+// @Failure 404 {object} server.errorResponse "tenancy is disabled"
+// @Failure 409 {object} server.errorResponse "tenant already exists"
+// @Router /admin/tenants [post]
+func (h *httpHandlerStruct) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	if h.tenants == nil {
+		h.handleError(ErrTenancyDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	var tenant definition.Tenant
+	if err := json.NewDecoder(r.Body).Decode(&tenant); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	created, status, err := h.tenants.CreateTenant(r.Context(), &tenant)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(created)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary List the tenants
+// @Description Returns every tenant, by id. Requires TENANCY_ENABLED
+// @Produce json
+// @Success 200 {array} definition.Tenant
+// @Failure 404 {object} server.errorResponse "tenancy is disabled"
+// @Router /admin/tenants [get]
+func (h *httpHandlerStruct) ListTenants(w http.ResponseWriter, r *http.Request) {
+	if h.tenants == nil {
+		h.handleError(ErrTenancyDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	tenants, status, err := h.tenants.ListTenants(r.Context())
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(tenants)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get a tenant
+// @Description Returns the tenant with the id. Requires TENANCY_ENABLED
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} definition.Tenant
+// @Failure 404 {object} server.errorResponse "tenant not found or tenancy is disabled"
+// @Router /admin/tenants/{id} [get]
+func (h *httpHandlerStruct) GetTenant(w http.ResponseWriter, r *http.Request) {
+	if h.tenants == nil {
+		h.handleError(ErrTenancyDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	tenant, status, err := h.tenants.GetTenant(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(tenant)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Delete a tenant
+// @Description Deletes the tenant and drops the database of its phone book, with all its contacts. Other replicas may keep serving it for up to a minute. Requires TENANCY_ENABLED
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {string} string "Message indicating successful deletion"
+// @Failure 404 {object} server.errorResponse "tenant not found or tenancy is disabled"
+// @Router /admin/tenants/{id} [delete]
+func (h *httpHandlerStruct) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	if h.tenants == nil {
+		h.handleError(ErrTenancyDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	_, status, err := h.tenants.DeleteTenant(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal("deleted tenant successfully")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Set the limits of a tenant
+// @Description Replaces the limits of the tenant. A zero or missing limit falls back to TENANT_MAX_CONTACTS or TENANT_MAX_REQUESTS_PER_MINUTE. Other replicas apply the new limits within a minute. Requires TENANCY_ENABLED
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param limits body definition.TenantLimits true "maxContacts and maxRequestsPerMinute of the tenant"
+// @Success 200 {object} definition.Tenant
+// @Failure 400 {object} server.errorResponse "invalid limits"
+// @Failure 404 {object} server.errorResponse "tenant not found or tenancy is disabled"
+// @Router /admin/tenants/{id}/limits [put]
+func (h *httpHandlerStruct) SetTenantLimits(w http.ResponseWriter, r *http.Request) {
+	if h.tenants == nil {
+		h.handleError(ErrTenancyDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	var limits definition.TenantLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		err = bodyError(err)
+		h.handleError(err, w, r, decodeErrorStatus(err))
+		return
+	}
+	tenant, status, err := h.tenants.SetTenantLimits(r.Context(), mux.Vars(r)["id"], &limits)
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(tenant)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// @Summary Get the usage of a tenant
+// @Description Returns the number of contacts, interactions, reminders and history entries of the tenant, the bytes its database takes, and the limits applying to it. Requires TENANCY_ENABLED
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} server.tenantUsage
+// @Failure 404 {object} server.errorResponse "tenant not found or tenancy is disabled"
+// @Router /admin/tenants/{id}/usage [get]
+func (h *httpHandlerStruct) GetTenantUsage(w http.ResponseWriter, r *http.Request) {
+	if h.tenants == nil {
+		h.handleError(ErrTenancyDisabled, w, r, http.StatusNotFound)
+		return
+	}
+	tenant, status, err := h.tenants.GetTenant(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	usage, status, err := h.phoneBook.GetUsage(definition.WithTenant(r.Context(), tenant.ID))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(status))
+		return
+	}
+	response, _ := json.Marshal(tenantUsage{Tenant: tenant.ID, Usage: usage, Limits: h.tenantLimits(tenant)})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
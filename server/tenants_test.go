@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+)
+
+// tenantPhoneBook knows a contact per tenant, by the tenant's name, and is
+// full for the tenants with a contact limit.
+type tenantPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *tenantPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	return &definition.Contact{FirstName: definition.TenantFromContext(ctx)}, "", nil
+}
+
+func (pb *tenantPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	if definition.ContactLimitFromContext(ctx) > 0 {
+		return nil, core.ErrContactLimit
+	}
+	return &definition.WriteResult{ID: "1"}, nil
+}
+
+func (pb *tenantPhoneBook) GetUsage(ctx context.Context) (*definition.Usage, string, error) {
+	return &definition.Usage{Contacts: int64(len(definition.TenantFromContext(ctx)))}, "", nil
+}
+
+// stubTenants knows the acme tenant, unlimited, and the tiny one, limited to
+// a contact and 2 requests a minute.
+type stubTenants struct {
+	definition.ITenants
+}
+
+func (t *stubTenants) LookupTenant(ctx context.Context, id string) (*definition.Tenant, error) {
+	tenant, _, _ := t.GetTenant(ctx, id)
+	return tenant, nil
+}
+
+func (t *stubTenants) GetTenant(ctx context.Context, id string) (*definition.Tenant, string, error) {
+	switch id {
+	case "acme":
+		return &definition.Tenant{ID: id}, "", nil
+	case "tiny":
+		return &definition.Tenant{ID: id, Limits: definition.TenantLimits{MaxContacts: 1, MaxRequestsPerMinute: 2}}, "", nil
+	}
+	return nil, core.NotFound, core.ErrTenantNotFound
+}
+
+func (t *stubTenants) CreateTenant(ctx context.Context, tenant *definition.Tenant) (*definition.Tenant, string, error) {
+	if tenant.ID == "acme" {
+		return nil, core.Conflict, core.ErrTenantExists
+	}
+	return tenant, "", nil
+}
+
+func TestTenants(t *testing.T) {
+	cfg := adminConfig()
+	cfg.TenancyEnabled = true
+	cfg.TenantDomain = "phonebook.example.com"
+	server := NewServer(cfg, &tenantPhoneBook{}, events.NewHub())
+	server.SetTenants(&stubTenants{})
+	request := func(r *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, asAdmin(r))
+		return recorder
+	}
+
+	t.Run("should scope requests to the tenant of the header", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil)
+		r.Header.Set(tenantHeader, "acme")
+		recorder := request(r)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"firstName":"acme"`)
+	})
+
+	t.Run("should scope requests to the tenant of the subdomain", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://acme.phonebook.example.com:8080/api/v1/contact/1", nil)
+		recorder := request(r)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"firstName":"acme"`)
+	})
+
+	t.Run("should reject requests without a known tenant", func(t *testing.T) {
+		recorder := request(httptest.NewRequest(http.MethodGet, "/api/v1/contact/1", nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"MISSING_TENANT"`)
+
+		r := httptest.NewRequest(http.MethodGet, "http://globex.phonebook.example.com/api/v1/contact/1", nil)
+		recorder = request(r)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"TENANT_NOT_FOUND"`)
+	})
+
+	t.Run("should provision tenants without one", func(t *testing.T) {
+		recorder := request(httptest.NewRequest(http.MethodPost, "/api/v1/admin/tenants", strings.NewReader(`{"id": "globex", "name": "Globex"}`)))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"id":"globex"`)
+
+		recorder = request(httptest.NewRequest(http.MethodPost, "/api/v1/admin/tenants", strings.NewReader(`{"id": "acme"}`)))
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"TENANT_EXISTS"`)
+	})
+
+	t.Run("should enforce the limits of the tenant", func(t *testing.T) {
+		add := func(tenant string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/contact", strings.NewReader(`{"firstName": "Dani", "phone": "0521234567"}`))
+			r.Header.Set(tenantHeader, tenant)
+			return request(r)
+		}
+		assert.Equal(t, http.StatusOK, add("acme").Code)
+		recorder := add("tiny")
+		assert.Equal(t, http.StatusPaymentRequired, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"CONTACT_LIMIT_REACHED"`)
+
+		add("tiny")
+		recorder = add("tiny")
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"TENANT_RATE_LIMITED"`)
+		assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusOK, add("acme").Code)
+	})
+
+	t.Run("should report the usage of a tenant", func(t *testing.T) {
+		recorder := request(httptest.NewRequest(http.MethodGet, "/api/v1/admin/tenants/tiny/usage", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"tenant":"tiny"`)
+		assert.Contains(t, recorder.Body.String(), `"contacts":4`)
+		assert.Contains(t, recorder.Body.String(), `"limits":{"maxContacts":1,"maxRequestsPerMinute":2}`)
+
+		recorder = request(httptest.NewRequest(http.MethodGet, "/api/v1/admin/tenants/globex/usage", nil))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("should not provision tenants when tenancy is disabled", func(t *testing.T) {
+		server := NewServer(adminConfig(), &tenantPhoneBook{}, events.NewHub())
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, asAdmin(httptest.NewRequest(http.MethodGet, "/api/v1/admin/tenants", nil)))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"TENANCY_DISABLED"`)
+	})
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"net/http"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"strings"
+	"time"
+)
+
+var ErrRequestTimeout = definition.NewError("REQUEST_TIMEOUT", "the request took too long, narrow it down or retry later", "")
+
+// untimedRoutes stream their responses or bodies for as long as the
+// transfer takes, so REQUEST_TIMEOUT doesn't apply to them unless they are
+// given a timeout in ROUTE_TIMEOUTS.
+var untimedRoutes = map[string]bool{
+	"/contact/export/ndjson": true,
+	"/contact/stream":        true,
+	"/contact/import":        true,
+	"/me/export":             true,
+	"/admin/backup":          true,
+	"/admin/restore":         true,
+	"/admin/migrate":         true,
+	"/admin/validate":        true,
+	"/ws":                    true,
+}
+
+// newRouteTimeouts returns the timeouts of the routes given one in
+// ROUTE_TIMEOUTS, by path. The config is validated on load.
+func newRouteTimeouts(cfg config.Config) map[string]time.Duration {
+	timeouts, _ := config.ParseRouteTimeouts(cfg.RouteTimeouts)
+	return timeouts
+}
+
+// timeoutMiddleware bounds the context of a request by the timeout of its
+// route, REQUEST_TIMEOUT unless ROUTE_TIMEOUTS overrides it, so the storage
+// calls of slow requests are canceled instead of piling up. handleError
+// answers the requests that ran out of time with 504.
+func (h *httpHandlerStruct) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := h.routeTimeout(r)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *httpHandlerStruct) routeTimeout(r *http.Request) time.Duration {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return h.cfg.RequestTimeout
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return h.cfg.RequestTimeout
+	}
+	path = versionlessPath(path)
+	if timeout, ok := h.routeTimeouts[path]; ok {
+		return timeout
+	}
+	if untimedRoutes[path] {
+		return 0
+	}
+	return h.cfg.RequestTimeout
+}
+
+// versionlessPath strips the API version prefix from a route path, so the
+// versions and the legacy paths share their timeouts.
+func versionlessPath(path string) string {
+	for version := range apiVersions {
+		if trimmed, ok := strings.CutPrefix(path, apiPrefix(version)); ok && strings.HasPrefix(trimmed, "/") {
+			return trimmed
+		}
+	}
+	return path
+}
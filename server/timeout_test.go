@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"phoneBook/config"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+// slowPhoneBook answers searches once their context is done, like a slow
+// regex search, and exports right away.
+type slowPhoneBook struct {
+	stubPhoneBook
+}
+
+func (pb *slowPhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	<-ctx.Done()
+	return nil, core.InternalServerError, ctx.Err()
+}
+
+func (pb *slowPhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	if _, ok := ctx.Deadline(); ok {
+		return 0, core.InternalServerError, context.DeadlineExceeded
+	}
+	return 0, "", nil
+}
+
+func TestRequestTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.RequestTimeout = 10 * time.Millisecond
+	server := NewServer(cfg, &slowPhoneBook{}, events.NewHub())
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("should answer 504 once the request timed out", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/contact/search?firstName=Dani", "/contact/search?firstName=Dani"} {
+			recorder := request(path)
+			assert.Equal(t, http.StatusGatewayTimeout, recorder.Code, path)
+			assert.Contains(t, recorder.Body.String(), `"code":"REQUEST_TIMEOUT"`, path)
+		}
+	})
+
+	t.Run("should not time streams out", func(t *testing.T) {
+		recorder := request("/api/v1/contact/export/ndjson")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("should apply the route timeouts", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.RequestTimeout = time.Hour
+		cfg.RouteTimeouts = []string{"/contact/search=10ms"}
+		handler := NewServer(cfg, &slowPhoneBook{}, events.NewHub()).Handler()
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/contact/search?firstName=Dani", nil))
+		assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+	})
+}
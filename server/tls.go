@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"golang.org/x/crypto/acme/autocert"
+	"net"
+	"net/http"
+)
+
+func (s *Server) tlsEnabled() bool {
+	return s.autocertEnabled() || (s.cfg.TLSCertFile != "" && s.cfg.TLSKeyFile != "")
+}
+
+func (s *Server) autocertEnabled() bool {
+	return len(s.cfg.AutocertDomains) > 0
+}
+
+// configureTLS sets up the certificates of the server, loaded from the
+// configured files or automatic when autocert domains are configured, and
+// starts the redirect listener, which answers plain http requests with a
+// redirect to https and serves the ACME http challenges when certificates
+// are automatic.
+func (s *Server) configureTLS() error {
+	redirect := http.Handler(http.HandlerFunc(s.redirectToHTTPS))
+	if s.autocertEnabled() {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(s.cfg.AutocertCacheDir),
+		}
+		s.httpServer.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	} else {
+		certificate, err := tls.LoadX509KeyPair(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load tls certificate: %w", err)
+		}
+		s.httpServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	}
+	s.httpServer.TLSConfig.MinVersion = tls.VersionTLS12
+	if s.cfg.HTTPRedirectPort == "" {
+		return nil
+	}
+	s.redirectServer = &http.Server{
+		Addr:         s.cfg.HTTPRedirectPort,
+		Handler:      redirect,
+		ReadTimeout:  s.cfg.HTTPReadTimeout,
+		WriteTimeout: s.cfg.HTTPWriteTimeout,
+		IdleTimeout:  s.cfg.HTTPIdleTimeout,
+	}
+	go s.listenAndServe(s.redirectServer, false)
+	return nil
+}
+
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, err := net.SplitHostPort(s.cfg.HTTPServerPort); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
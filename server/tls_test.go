@@ -0,0 +1,99 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self signed certificate and its key to dir.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestConfigureTLS(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+	newTLSServer := func(certFile, keyFile string) *Server {
+		cfg := config.Default()
+		cfg.TLSCertFile = certFile
+		cfg.TLSKeyFile = keyFile
+		return NewServer(cfg, &stubPhoneBook{contacts: map[string]*definition.Contact{}}, events.NewHub())
+	}
+
+	t.Run("should load the configured certificate", func(t *testing.T) {
+		server := newTLSServer(certFile, keyFile)
+		assert.True(t, server.tlsEnabled())
+		assert.Nil(t, server.configureTLS())
+		assert.Len(t, server.httpServer.TLSConfig.Certificates, 1)
+		assert.Equal(t, uint16(tls.VersionTLS12), server.httpServer.TLSConfig.MinVersion)
+		assert.Nil(t, server.redirectServer)
+	})
+
+	t.Run("should refuse a missing certificate or key", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.pem")
+		assert.NotNil(t, newTLSServer(missing, keyFile).configureTLS())
+		assert.NotNil(t, newTLSServer(certFile, missing).configureTLS())
+	})
+
+	t.Run("should refuse a key of another certificate", func(t *testing.T) {
+		_, otherKeyFile := writeCertificate(t, t.TempDir())
+		assert.NotNil(t, newTLSServer(certFile, otherKeyFile).configureTLS())
+	})
+
+	t.Run("should set the minimum version of automatic certificates", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.AutocertDomains = []string{"phonebook.example.com"}
+		cfg.AutocertCacheDir = t.TempDir()
+		server := NewServer(cfg, &stubPhoneBook{contacts: map[string]*definition.Contact{}}, events.NewHub())
+		assert.Nil(t, server.configureTLS())
+		assert.NotNil(t, server.httpServer.TLSConfig.GetCertificate)
+		assert.Equal(t, uint16(tls.VersionTLS12), server.httpServer.TLSConfig.MinVersion)
+	})
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	redirect := func(serverPort string) string {
+		cfg := config.Default()
+		cfg.HTTPServerPort = serverPort
+		server := NewServer(cfg, &stubPhoneBook{contacts: map[string]*definition.Contact{}}, events.NewHub())
+		recorder := httptest.NewRecorder()
+		server.redirectToHTTPS(recorder, httptest.NewRequest(http.MethodGet, "http://phonebook.example.com:80/api/v1/contact?page=2", nil))
+		assert.Equal(t, http.StatusPermanentRedirect, recorder.Code)
+		return recorder.Header().Get("Location")
+	}
+
+	assert.Equal(t, "https://phonebook.example.com/api/v1/contact?page=2", redirect(":443"))
+	assert.Equal(t, "https://phonebook.example.com:8443/api/v1/contact?page=2", redirect(":8443"))
+}
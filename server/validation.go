@@ -0,0 +1,39 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"strconv"
+)
+
+var ErrInvalidFix = definition.NewError("INVALID_FIX", "invalid fix. fix should be true or false", "fix")
+
+// @Summary Validate the stored contacts
+// @Description Checks every contact against the validation rules of new contacts, e.g. after an import from a legacy system, and reports the contacts breaking them, listing the first 1000 issues. With fix, repairs the issues normalizing resolves, like formatted phone numbers or surrounding blanks, as a patch of each contact; the others are left to fix by hand
+// @Produce json
+// @Param fix query bool false "Repair the fixable issues"
+// @Success 200 {object} definition.ValidationReport
+// @Failure 400 {object} server.errorResponse "invalid fix"
+// @Failure 501 {object} server.errorResponse "not supported by the storage backend"
+// @Router /admin/validate [post]
+func (h *httpHandlerStruct) ValidateContacts(w http.ResponseWriter, r *http.Request) {
+	fix := false
+	if value := r.URL.Query().Get("fix"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.handleError(ErrInvalidFix, w, r, http.StatusBadRequest)
+			return
+		}
+		fix = parsed
+	}
+	report, err := h.phoneBook.ValidateContacts(r.Context(), fix, extractActor(r))
+	if err != nil {
+		h.handleError(err, w, r, extractStatus(core.StatusOf(err)))
+		return
+	}
+	response, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
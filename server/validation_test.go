@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"testing"
+)
+
+// validationPhoneBook keeps whether it was asked to fix and by whom.
+type validationPhoneBook struct {
+	stubPhoneBook
+	fix   bool
+	actor string
+}
+
+func (pb *validationPhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	pb.fix = fix
+	pb.actor = actor
+	return &definition.ValidationReport{Scanned: 2, Invalid: 1, Issues: []definition.ContactIssue{
+		{ID: "1", Field: "phone", Code: "INVALID_PHONE", Message: "invalid phone number", Fixable: true, Fixed: fix},
+	}}, nil
+}
+
+func TestValidateContacts(t *testing.T) {
+	phoneBook := &validationPhoneBook{}
+	cfg := adminConfig()
+	// the address of the httptest requests
+	cfg.TrustedProxies = []string{"192.0.2.1"}
+	server := NewServer(cfg, phoneBook, events.NewHub())
+	serve := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := asAdmin(httptest.NewRequest(http.MethodPost, target, nil))
+		request.Header.Set("X-User", "admin")
+		server.Handler().ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("should report the invalid contacts", func(t *testing.T) {
+		recorder := serve("/api/v1/admin/validate")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.False(t, phoneBook.fix)
+		assert.JSONEq(t, `{"scanned":2,"invalid":1,"fixed":0,"issues":[{"id":"1","field":"phone","code":"INVALID_PHONE","message":"invalid phone number","fixable":true}]}`, recorder.Body.String())
+	})
+
+	t.Run("should fix as the actor", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("/api/v1/admin/validate?fix=true").Code)
+		assert.True(t, phoneBook.fix)
+		assert.Equal(t, "admin", phoneBook.actor)
+		recorder := serve("/api/v1/admin/validate?fix=please")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"INVALID_FIX"`)
+	})
+
+	t.Run("should record an untrusted client by its address", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		request := asAdmin(httptest.NewRequest(http.MethodPost, "/api/v1/admin/validate?fix=true", nil))
+		request.RemoteAddr = "203.0.113.9:4711"
+		request.Header.Set("X-User", "admin")
+		server.Handler().ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "203.0.113.9", phoneBook.actor)
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// apiVersion registers the routes of one version of the API. Every version is
+// mounted under /api/{version} and shares the handler, and with it the phone
+// book, so a new version only needs handlers for what it changes.
+type apiVersion func(router *mux.Router, handler *httpHandlerStruct)
+
+// apiVersions are the versions served, by the path segment they're mounted on.
+var apiVersions = map[string]apiVersion{
+	"v1": registerV1Routes,
+}
+
+// legacyVersion is the version the unversioned paths alias.
+const legacyVersion = "v1"
+
+func registerV1Routes(router *mux.Router, handler *httpHandlerStruct) {
+	router.HandleFunc("/contact", handler.GetContactWithPagination).Methods("GET")
+	router.HandleFunc("/contact", handler.AddContact).Methods("POST").Name(addContactRoute)
+	router.HandleFunc("/contact", handler.DeleteContacts).Methods("DELETE")
+	router.HandleFunc("/contact/stream", handler.StreamContacts).Methods("POST").Name(streamRoute)
+	router.HandleFunc("/contact/import", handler.ImportContacts).Methods("POST").Name(importRoute)
+	router.HandleFunc("/contact/order", handler.ReorderContacts).Methods("PUT")
+	router.HandleFunc("/contact/edit/{id}", handler.UpdateContact).Methods("PUT").Name(updateContactRoute)
+	router.HandleFunc("/contact/{id}", handler.PatchContact).Methods("PATCH")
+	router.HandleFunc("/contact/{id}/history", handler.GetContactHistory).Methods("GET")
+	router.HandleFunc("/contact/{id}/undo", handler.UndoContact).Methods("POST")
+	router.HandleFunc("/contact/{id}/archive", handler.ArchiveContact).Methods("POST")
+	router.HandleFunc("/contact/{id}/unarchive", handler.UnarchiveContact).Methods("POST")
+	router.HandleFunc("/contact/{id}/block", handler.BlockContact).Methods("POST")
+	router.HandleFunc("/contact/{id}/unblock", handler.UnblockContact).Methods("POST")
+	router.HandleFunc("/contact/{id}/relations", handler.LinkContact).Methods("POST")
+	router.HandleFunc("/contact/{id}/relations/{relatedId}", handler.UnlinkContact).Methods("DELETE")
+	router.HandleFunc("/contact/{id}/related", handler.GetRelatedContacts).Methods("GET")
+	router.HandleFunc("/contact/{id}/interactions", handler.AddInteraction).Methods("POST")
+	router.HandleFunc("/contact/{id}/interactions", handler.GetInteractions).Methods("GET")
+	router.HandleFunc("/contact/{id}/interactions/{interactionId}", handler.DeleteInteraction).Methods("DELETE")
+	router.HandleFunc("/contact/{id}/reminder", handler.AddReminder).Methods("POST")
+	router.HandleFunc("/contact/delete/{id}", handler.DeleteContact).Methods("DELETE")
+	router.HandleFunc("/contact/erase", handler.EraseContacts).Methods("DELETE")
+	router.HandleFunc("/contact/search", handler.SearchContact).Methods("GET")
+	router.HandleFunc("/contact/search/text", handler.SearchContactText).Methods("GET")
+	router.HandleFunc("/contact/count", handler.CountContacts).Methods("GET")
+	router.HandleFunc("/contact/distinct", handler.GetDistinctValues).Methods("GET")
+	router.HandleFunc("/contact/export/ndjson", handler.ExportContacts).Methods("GET")
+	router.HandleFunc("/contact/by-phone/{number}", handler.GetContactsByPhone).Methods("GET")
+	router.HandleFunc("/contact/by-phone/{number}", handler.UpsertContactByPhone).Methods("PUT").Name(upsertContactByPhoneRoute)
+	router.HandleFunc("/contact/index", handler.GetContactIndex).Methods("GET")
+	router.HandleFunc("/contact/recent", handler.GetRecentContacts).Methods("GET")
+	router.HandleFunc("/contact/random", handler.GetRandomContact).Methods("GET")
+	router.HandleFunc("/contact/sample", handler.SampleContacts).Methods("GET")
+	router.HandleFunc("/contact/changes", handler.GetContactChanges).Methods("GET")
+	router.HandleFunc("/contact/schema", handler.GetContactSchema).Methods("GET")
+	router.HandleFunc("/contact/{id}", handler.GetContact).Methods("GET")
+	router.HandleFunc("/company", handler.ListCompanies).Methods("GET")
+	router.HandleFunc("/company/{name}/contacts", handler.GetCompanyContacts).Methods("GET")
+	router.HandleFunc("/reminders", handler.GetReminders).Methods("GET")
+	router.HandleFunc("/blocklist", handler.GetBlocklist).Methods("GET")
+	router.HandleFunc("/blocklist/check", handler.CheckBlocked).Methods("GET")
+	router.HandleFunc("/speed-dial", handler.GetSpeedDials).Methods("GET")
+	router.HandleFunc("/speed-dial/export", handler.ExportSpeedDials).Methods("GET")
+	router.HandleFunc("/speed-dial/{slot}", handler.AssignSpeedDial).Methods("PUT")
+	router.HandleFunc("/speed-dial/{slot}", handler.ClearSpeedDial).Methods("DELETE")
+	router.HandleFunc("/sync", handler.GetChanges).Methods("GET")
+	router.HandleFunc("/sync", handler.SyncChanges).Methods("POST")
+	router.HandleFunc("/me/export", handler.ExportMine).Methods("GET")
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(handler.adminMiddleware)
+	admin.HandleFunc("/config", handler.GetConfig).Methods("GET")
+	admin.HandleFunc("/indexes", handler.ListIndexes).Methods("GET")
+	admin.HandleFunc("/backup", handler.Backup).Methods("POST")
+	admin.HandleFunc("/restore", handler.Restore).Methods("POST").Name(restoreRoute)
+	admin.HandleFunc("/seed", handler.SeedContacts).Methods("POST")
+	admin.HandleFunc("/search/reindex", handler.ReindexSearch).Methods("POST")
+	admin.HandleFunc("/reindex", handler.Reindex).Methods("POST")
+	admin.HandleFunc("/jobs", handler.ListJobs).Methods("GET")
+	admin.HandleFunc("/jobs/{id}", handler.GetJob).Methods("GET")
+	admin.HandleFunc("/jobs/{id}", handler.CancelJob).Methods("DELETE")
+	admin.HandleFunc("/jobs/{id}/report", handler.GetJobReport).Methods("GET")
+	admin.HandleFunc("/retention", handler.ApplyRetention).Methods("POST")
+	admin.HandleFunc("/migrate", handler.Migrate).Methods("POST")
+	admin.HandleFunc("/validate", handler.ValidateContacts).Methods("POST")
+	admin.HandleFunc("/crm/dead-letters", handler.GetCRMDeadLetters).Methods("GET")
+	admin.HandleFunc("/crm/dead-letters/retry", handler.RetryCRMDeadLetters).Methods("POST")
+	admin.HandleFunc("/maintenance", handler.GetMaintenance).Methods("GET")
+	admin.HandleFunc("/maintenance", handler.SetMaintenance).Methods("PUT")
+	admin.HandleFunc("/tenants", handler.CreateTenant).Methods("POST")
+	admin.HandleFunc("/tenants", handler.ListTenants).Methods("GET")
+	admin.HandleFunc("/tenants/{id}", handler.GetTenant).Methods("GET")
+	admin.HandleFunc("/tenants/{id}", handler.DeleteTenant).Methods("DELETE")
+	admin.HandleFunc("/tenants/{id}/limits", handler.SetTenantLimits).Methods("PUT")
+	admin.HandleFunc("/tenants/{id}/usage", handler.GetTenantUsage).Methods("GET")
+	router.HandleFunc("/integrations/google/connect", handler.ConnectGoogle).Methods("GET")
+	router.HandleFunc("/integrations/google/callback", handler.GoogleCallback).Methods("GET")
+	router.HandleFunc("/integrations/callerid", handler.CallerID).Methods("GET")
+	router.HandleFunc("/ws", handler.WebSocket).Methods("GET")
+}
+
+// registerAPIVersions mounts every version under /api, then the legacy
+// unversioned paths as deprecated aliases of legacyVersion.
+func registerAPIVersions(router *mux.Router, handler *httpHandlerStruct) {
+	for version, register := range apiVersions {
+		register(router.PathPrefix(apiPrefix(version)).Subrouter(), handler)
+	}
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(deprecationMiddleware(apiPrefix(legacyVersion)))
+	apiVersions[legacyVersion](legacy, handler)
+}
+
+func apiPrefix(version string) string {
+	return "/api/" + version
+}
+
+// deprecationMiddleware marks responses of the legacy paths as deprecated,
+// linking the same path under successorPrefix.
+func deprecationMiddleware(successorPrefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successorPrefix+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
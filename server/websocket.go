@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"phoneBook/core"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"time"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	wsPongTimeout  = 60 * time.Second
+	wsPingInterval = wsPongTimeout * 9 / 10
+	// wsEventBuffer is how many events a client may fall behind before it's
+	// unsubscribed.
+	wsEventBuffer = 256
+)
+
+// The types of WebSocket messages.
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsEvent        = "event"
+	wsAdd          = "add"
+	wsUpdate       = "update"
+	wsPatch        = "patch"
+	wsDelete       = "delete"
+	wsResult       = "result"
+	wsError        = "error"
+)
+
+var (
+	ErrUnknownMessage    = definition.NewError("UNKNOWN_MESSAGE", "unknown message type", "type")
+	ErrMutationsDisabled = definition.NewError("MUTATIONS_DISABLED", "mutations over websocket are disabled", "type")
+	ErrEventsDropped     = definition.NewError("EVENTS_DROPPED", "client fell behind and missed events, subscribe again and reload", "")
+)
+
+// wsMessage is a message of the WebSocket protocol in either direction.
+// Responses carry the ID of the request they answer.
+type wsMessage struct {
+	ID        string                  `json:"id,omitempty"`
+	Type      string                  `json:"type"`
+	ContactID string                  `json:"contactId,omitempty"`
+	Version   int64                   `json:"version,omitempty"`
+	Contact   *definition.Contact     `json:"contact,omitempty"`
+	Patch     definition.ContactPatch `json:"patch,omitempty"`
+	Result    interface{}             `json:"result,omitempty"`
+	Error     *errorResponse          `json:"error,omitempty"`
+	Event     *events.Event           `json:"event,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// wsClient is a WebSocket connection. Only the writer goroutine writes to
+// the connection, everything else queues messages on out.
+type wsClient struct {
+	h            *httpHandlerStruct
+	conn         *websocket.Conn
+	request      *http.Request
+	out          chan *wsMessage
+	done         chan struct{}
+	subscription *events.Subscription
+}
+
+// @Summary Real-time changes over WebSocket
+// @Description Upgrades to a WebSocket exchanging JSON messages. Send {"type":"subscribe"} (optionally with contactId) to receive {"type":"event"} messages for every change, and {"type":"unsubscribe"} to stop. When enabled, add, update, patch and delete messages mutate contacts like the http endpoints, answered by a result or error message with the same id
+// @Router /ws [get]
+func (h *httpHandlerStruct) WebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already answered with an error status
+		logrus.WithError(err).Debug("websocket upgrade failed")
+		return
+	}
+	client := &wsClient{
+		h:       h,
+		conn:    conn,
+		request: r,
+		out:     make(chan *wsMessage, wsEventBuffer),
+		done:    make(chan struct{}),
+	}
+	go client.writeLoop()
+	client.readLoop()
+}
+
+// readLoop handles the client messages in order until the connection fails.
+func (c *wsClient) readLoop() {
+	defer func() {
+		c.unsubscribe()
+		close(c.done)
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(c.h.cfg.MaxBodyBytes)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	for {
+		var message wsMessage
+		if err := c.conn.ReadJSON(&message); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				c.send(&wsMessage{Type: wsError, Error: c.errorResponse(ErrInvalidBody.WithMessage(err.Error()), http.StatusBadRequest)})
+				continue
+			}
+			return
+		}
+		c.handle(&message)
+	}
+}
+
+// writeLoop writes the queued messages and keeps the connection alive with
+// pings until the read loop ends.
+func (c *wsClient) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteJSON(message); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *wsClient) send(message *wsMessage) {
+	select {
+	case c.out <- message:
+	case <-c.done:
+	}
+}
+
+func (c *wsClient) handle(message *wsMessage) {
+	switch message.Type {
+	case wsSubscribe:
+		c.subscribe(message.ContactID)
+		c.send(&wsMessage{ID: message.ID, Type: wsSubscribed, ContactID: message.ContactID})
+	case wsUnsubscribe:
+		c.unsubscribe()
+		c.send(&wsMessage{ID: message.ID, Type: wsUnsubscribed})
+	case wsAdd, wsUpdate, wsPatch, wsDelete:
+		if !c.h.cfg.WSMutationsEnabled {
+			c.send(&wsMessage{ID: message.ID, Type: wsError, Error: c.errorResponse(ErrMutationsDisabled, http.StatusForbidden)})
+			return
+		}
+		if err := c.h.maintenanceError(); err != nil {
+			c.send(&wsMessage{ID: message.ID, Type: wsError, Error: c.errorResponse(err, http.StatusServiceUnavailable)})
+			return
+		}
+		result, status, err := c.mutate(message)
+		if err != nil {
+			c.send(&wsMessage{ID: message.ID, Type: wsError, Error: c.errorResponse(err, status)})
+			return
+		}
+		c.send(&wsMessage{ID: message.ID, Type: wsResult, Result: result})
+	default:
+		c.send(&wsMessage{ID: message.ID, Type: wsError, Error: c.errorResponse(ErrUnknownMessage, http.StatusBadRequest)})
+	}
+}
+
+// mutate applies a mutation message like the matching http endpoint, as the
+// actor of the upgrade request.
+func (c *wsClient) mutate(message *wsMessage) (interface{}, int, error) {
+	ctx := c.request.Context()
+	actor := extractActor(c.request)
+	phoneBook := c.h.phoneBook
+	var result *definition.WriteResult
+	var err error
+	switch message.Type {
+	case wsAdd, wsUpdate:
+		if message.Contact == nil {
+			return nil, http.StatusBadRequest, ErrInvalidBody.WithField("contact")
+		}
+		if err := c.h.validateContactSizeInput(message.Contact); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if message.Type == wsAdd {
+			result, err = phoneBook.AddContact(ctx, message.Contact, actor)
+		} else {
+			result, err = phoneBook.UpdateContact(ctx, message.ContactID, message.Contact, message.Version, actor)
+		}
+	case wsPatch:
+		if err := c.h.validatePatchSizeInput(message.Patch); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		result, err = phoneBook.PatchContact(ctx, message.ContactID, message.Patch, message.Version, actor)
+	case wsDelete:
+		result, err = phoneBook.DeleteContact(ctx, message.ContactID, actor)
+	}
+	if err == nil && (message.Type == wsUpdate || message.Type == wsPatch) && result.Matched == 0 {
+		return nil, http.StatusNotFound, core.ErrContactNotFound
+	}
+	return result, extractStatus(core.StatusOf(err)), err
+}
+
+// subscribe forwards the events of the contact with the given id, or of every
+// contact, to the client, replacing its previous subscription. Only the
+// events of the client's tenant are forwarded.
+func (c *wsClient) subscribe(contactID string) {
+	c.unsubscribe()
+	tenant := definition.TenantFromContext(c.request.Context())
+	subscription := c.h.changes.Subscribe(wsEventBuffer)
+	c.subscription = subscription
+	go func() {
+		for event := range subscription.C {
+			if event.Tenant != tenant {
+				continue
+			}
+			if contactID == "" || event.ContactID == contactID {
+				c.send(&wsMessage{Type: wsEvent, Event: event})
+			}
+		}
+		if subscription.Dropped() {
+			c.send(&wsMessage{Type: wsError, Error: c.errorResponse(ErrEventsDropped, http.StatusServiceUnavailable)})
+		}
+	}()
+}
+
+func (c *wsClient) unsubscribe() {
+	if c.subscription != nil {
+		c.subscription.Close()
+		c.subscription = nil
+	}
+}
+
+func (c *wsClient) errorResponse(err error, status int) *errorResponse {
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	requestID := c.request.Header.Get(requestIDHeader)
+	logrus.WithError(err).WithField("requestId", requestID).Error()
+	return newErrorResponse(err, status, requestID, requestLanguage(c.request))
+}
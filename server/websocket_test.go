@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"phoneBook/config"
+	"phoneBook/definition"
+	"phoneBook/events"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addingPhoneBook accepts every new contact.
+type addingPhoneBook struct {
+	definition.IPhoneBook
+}
+
+func (pb *addingPhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return &definition.WriteResult{ID: "65a1b2c3d4e5f60718293a4b"}, nil
+}
+
+func TestWebSocket(t *testing.T) {
+	dial := func(t *testing.T, cfg config.Config) *websocket.Conn {
+		changes := events.NewHub()
+		server := httptest.NewServer(NewServer(cfg, events.NewPhoneBook(&addingPhoneBook{}, changes), changes).Handler())
+		t.Cleanup(server.Close)
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatalf("Error dialing websocket: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+
+	t.Run("should stream events of mutations made over the socket", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.WSMutationsEnabled = true
+		conn := dial(t, cfg)
+
+		var response wsMessage
+		conn.WriteJSON(wsMessage{ID: "1", Type: wsSubscribe})
+		assert.Nil(t, conn.ReadJSON(&response))
+		assert.Equal(t, wsSubscribed, response.Type)
+
+		conn.WriteJSON(wsMessage{ID: "2", Type: wsAdd, Contact: &definition.Contact{FirstName: "dani", Phone: "0521234567"}})
+		// the event and the result may arrive in either order
+		received := map[string]wsMessage{}
+		for i := 0; i < 2; i++ {
+			response = wsMessage{}
+			assert.Nil(t, conn.ReadJSON(&response))
+			received[response.Type] = response
+		}
+		assert.Equal(t, "2", received[wsResult].ID)
+		assert.Equal(t, events.ContactCreated, received[wsEvent].Event.Type)
+		assert.Equal(t, "dani", received[wsEvent].Event.Contact.FirstName)
+	})
+
+	t.Run("should reject mutations when disabled", func(t *testing.T) {
+		conn := dial(t, config.Default())
+		conn.WriteJSON(wsMessage{ID: "1", Type: wsDelete, ContactID: "65a1b2c3d4e5f60718293a4b"})
+		var response wsMessage
+		assert.Nil(t, conn.ReadJSON(&response))
+		assert.Equal(t, wsError, response.Type)
+		assert.Equal(t, "MUTATIONS_DISABLED", response.Error.Code)
+	})
+}
@@ -0,0 +1,253 @@
+package tenancy
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"phoneBook/definition"
+	"sync"
+	"time"
+)
+
+// PhoneBook forwards every call to the phone book of the tenant its context
+// is scoped to, or to the default phone book when it's scoped to none, as
+// the calls of the schedulers are.
+type PhoneBook struct {
+	fallback definition.IPhoneBook
+	open     func(tenant string) definition.IPhoneBook
+	// tenants holds the phone books opened so far, by tenant. They only hold
+	// collection handles, a deleted tenant's is reused if it's created again.
+	tenants sync.Map
+}
+
+// NewPhoneBook returns a phone book forwarding to fallback outside of
+// tenants, and to the phone book open returns for each tenant otherwise.
+func NewPhoneBook(fallback definition.IPhoneBook, open func(tenant string) definition.IPhoneBook) *PhoneBook {
+	return &PhoneBook{fallback: fallback, open: open}
+}
+
+// get returns the phone book of the tenant of ctx, opening it on its first
+// call.
+func (pb *PhoneBook) get(ctx context.Context) definition.IPhoneBook {
+	tenant := definition.TenantFromContext(ctx)
+	if tenant == "" {
+		return pb.fallback
+	}
+	if phoneBook, ok := pb.tenants.Load(tenant); ok {
+		return phoneBook.(definition.IPhoneBook)
+	}
+	phoneBook, _ := pb.tenants.LoadOrStore(tenant, pb.open(tenant))
+	return phoneBook.(definition.IPhoneBook)
+}
+
+func (pb *PhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	return pb.get(ctx).GetContact(ctx, id)
+}
+
+func (pb *PhoneBook) GetContactWithPagination(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.get(ctx).GetContactWithPagination(ctx, query)
+}
+
+func (pb *PhoneBook) AddContact(ctx context.Context, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).AddContact(ctx, contact, actor)
+}
+
+func (pb *PhoneBook) UpdateContact(ctx context.Context, id string, updatedContact *definition.Contact, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).UpdateContact(ctx, id, updatedContact, expectedVersion, actor)
+}
+
+func (pb *PhoneBook) PatchContact(ctx context.Context, id string, patch definition.ContactPatch, expectedVersion int64, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).PatchContact(ctx, id, patch, expectedVersion, actor)
+}
+
+func (pb *PhoneBook) DeleteContact(ctx context.Context, id string, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).DeleteContact(ctx, id, actor)
+}
+
+func (pb *PhoneBook) DeleteContacts(ctx context.Context, batch *definition.BatchDelete, actor string) (*definition.BatchDeleteResult, error) {
+	return pb.get(ctx).DeleteContacts(ctx, batch, actor)
+}
+
+func (pb *PhoneBook) SearchContact(ctx context.Context, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.get(ctx).SearchContact(ctx, query)
+}
+
+func (pb *PhoneBook) SearchContactText(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return pb.get(ctx).SearchContactText(ctx, query)
+}
+
+func (pb *PhoneBook) GetContactIndex(ctx context.Context) ([]*definition.LetterCount, string, error) {
+	return pb.get(ctx).GetContactIndex(ctx)
+}
+
+func (pb *PhoneBook) GetRecentContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return pb.get(ctx).GetRecentContacts(ctx, query)
+}
+
+func (pb *PhoneBook) GetRandomContact(ctx context.Context) (*definition.Contact, string, error) {
+	return pb.get(ctx).GetRandomContact(ctx)
+}
+
+func (pb *PhoneBook) SampleContacts(ctx context.Context, query url.Values) ([]*definition.Contact, string, error) {
+	return pb.get(ctx).SampleContacts(ctx, query)
+}
+
+func (pb *PhoneBook) GetContactsByPhone(ctx context.Context, number string) ([]*definition.Contact, string, error) {
+	return pb.get(ctx).GetContactsByPhone(ctx, number)
+}
+
+func (pb *PhoneBook) GetCompanyContacts(ctx context.Context, name string, query url.Values) (*definition.ContactPage, string, error) {
+	return pb.get(ctx).GetCompanyContacts(ctx, name, query)
+}
+
+func (pb *PhoneBook) ListCompanies(ctx context.Context, prefix string) ([]string, string, error) {
+	return pb.get(ctx).ListCompanies(ctx, prefix)
+}
+
+func (pb *PhoneBook) ExportContacts(ctx context.Context, query url.Values, w io.Writer) (int64, string, error) {
+	return pb.get(ctx).ExportContacts(ctx, query, w)
+}
+
+func (pb *PhoneBook) GetContactHistory(ctx context.Context, id string) ([]*definition.AuditEntry, string, error) {
+	return pb.get(ctx).GetContactHistory(ctx, id)
+}
+
+func (pb *PhoneBook) UndoContact(ctx context.Context, id string, actor string) (*definition.Contact, error) {
+	return pb.get(ctx).UndoContact(ctx, id, actor)
+}
+
+func (pb *PhoneBook) LinkContact(ctx context.Context, id string, relation *definition.Relation, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).LinkContact(ctx, id, relation, actor)
+}
+
+func (pb *PhoneBook) UnlinkContact(ctx context.Context, id string, relatedID string, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).UnlinkContact(ctx, id, relatedID, actor)
+}
+
+func (pb *PhoneBook) ArchiveContact(ctx context.Context, id string, archived bool, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).ArchiveContact(ctx, id, archived, actor)
+}
+
+func (pb *PhoneBook) ReorderContacts(ctx context.Context, order *definition.ContactOrder, actor string) (*definition.ReorderResult, error) {
+	return pb.get(ctx).ReorderContacts(ctx, order, actor)
+}
+
+func (pb *PhoneBook) BlockContact(ctx context.Context, id string, blocked bool, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).BlockContact(ctx, id, blocked, actor)
+}
+
+func (pb *PhoneBook) GetBlocklist(ctx context.Context) ([]*definition.BlockedNumber, string, error) {
+	return pb.get(ctx).GetBlocklist(ctx)
+}
+
+func (pb *PhoneBook) CheckBlocked(ctx context.Context, number string) (*definition.BlockCheck, string, error) {
+	return pb.get(ctx).CheckBlocked(ctx, number)
+}
+
+func (pb *PhoneBook) GetRelatedContacts(ctx context.Context, id string) ([]*definition.RelatedContact, string, error) {
+	return pb.get(ctx).GetRelatedContacts(ctx, id)
+}
+
+func (pb *PhoneBook) AddInteraction(ctx context.Context, id string, interaction *definition.Interaction, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).AddInteraction(ctx, id, interaction, actor)
+}
+
+func (pb *PhoneBook) GetInteractions(ctx context.Context, id string, query url.Values) (*definition.InteractionPage, string, error) {
+	return pb.get(ctx).GetInteractions(ctx, id, query)
+}
+
+func (pb *PhoneBook) DeleteInteraction(ctx context.Context, id string, interactionID string) (*definition.WriteResult, error) {
+	return pb.get(ctx).DeleteInteraction(ctx, id, interactionID)
+}
+
+func (pb *PhoneBook) AddReminder(ctx context.Context, id string, reminder *definition.Reminder, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).AddReminder(ctx, id, reminder, actor)
+}
+
+func (pb *PhoneBook) GetReminders(ctx context.Context, due string) ([]*definition.Reminder, string, error) {
+	return pb.get(ctx).GetReminders(ctx, due)
+}
+
+func (pb *PhoneBook) ClaimDueReminder(ctx context.Context, now time.Time) (*definition.Reminder, error) {
+	return pb.get(ctx).ClaimDueReminder(ctx, now)
+}
+
+func (pb *PhoneBook) AssignSpeedDial(ctx context.Context, slot string, dial *definition.SpeedDial, actor string) (*definition.SpeedDial, error) {
+	return pb.get(ctx).AssignSpeedDial(ctx, slot, dial, actor)
+}
+
+func (pb *PhoneBook) GetSpeedDials(ctx context.Context) ([]*definition.SpeedDial, string, error) {
+	return pb.get(ctx).GetSpeedDials(ctx)
+}
+
+func (pb *PhoneBook) ClearSpeedDial(ctx context.Context, slot string) (*definition.WriteResult, error) {
+	return pb.get(ctx).ClearSpeedDial(ctx, slot)
+}
+
+func (pb *PhoneBook) ListIndexes(ctx context.Context) ([]*definition.Index, string, error) {
+	return pb.get(ctx).ListIndexes(ctx)
+}
+
+func (pb *PhoneBook) Backup(ctx context.Context, format string, w io.Writer) (int64, string, error) {
+	return pb.get(ctx).Backup(ctx, format, w)
+}
+
+func (pb *PhoneBook) Restore(ctx context.Context, format string, mode string, r io.Reader) (*definition.RestoreResult, error) {
+	return pb.get(ctx).Restore(ctx, format, mode, r)
+}
+
+func (pb *PhoneBook) SeedContacts(ctx context.Context, count int, actor string) (int64, error) {
+	return pb.get(ctx).SeedContacts(ctx, count, actor)
+}
+
+func (pb *PhoneBook) GetUsage(ctx context.Context) (*definition.Usage, string, error) {
+	return pb.get(ctx).GetUsage(ctx)
+}
+
+func (pb *PhoneBook) ApplyRetention(ctx context.Context, policy *definition.RetentionPolicy, actor string) (*definition.RetentionResult, error) {
+	return pb.get(ctx).ApplyRetention(ctx, policy, actor)
+}
+
+func (pb *PhoneBook) EraseContacts(ctx context.Context, phone string, actor string) (*definition.ErasureResult, error) {
+	return pb.get(ctx).EraseContacts(ctx, phone, actor)
+}
+
+func (pb *PhoneBook) Migrate(ctx context.Context, dryRun bool) (*definition.MigrationResult, error) {
+	return pb.get(ctx).Migrate(ctx, dryRun)
+}
+
+func (pb *PhoneBook) ValidateContacts(ctx context.Context, fix bool, actor string) (*definition.ValidationReport, error) {
+	return pb.get(ctx).ValidateContacts(ctx, fix, actor)
+}
+
+func (pb *PhoneBook) GetChanges(ctx context.Context, query url.Values) (*definition.ChangePage, string, error) {
+	return pb.get(ctx).GetChanges(ctx, query)
+}
+
+func (pb *PhoneBook) GetLastModified(ctx context.Context) (time.Time, string, error) {
+	return pb.get(ctx).GetLastModified(ctx)
+}
+
+func (pb *PhoneBook) CountContacts(ctx context.Context, query url.Values) (int64, string, error) {
+	return pb.get(ctx).CountContacts(ctx, query)
+}
+
+func (pb *PhoneBook) GetDistinctValues(ctx context.Context, field string) ([]*definition.ValueCount, string, error) {
+	return pb.get(ctx).GetDistinctValues(ctx, field)
+}
+
+func (pb *PhoneBook) UpsertContact(ctx context.Context, id string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).UpsertContact(ctx, id, contact, actor)
+}
+
+func (pb *PhoneBook) UpsertContactByPhone(ctx context.Context, number string, contact *definition.Contact, actor string) (*definition.WriteResult, error) {
+	return pb.get(ctx).UpsertContactByPhone(ctx, number, contact, actor)
+}
+
+func (pb *PhoneBook) RebuildDerivedData(ctx context.Context, progress definition.RebuildProgress) (*definition.RebuildResult, error) {
+	return pb.get(ctx).RebuildDerivedData(ctx, progress)
+}
+
+func (pb *PhoneBook) AddContacts(ctx context.Context, contacts []*definition.Contact, actor string) ([]*definition.AddResult, error) {
+	return pb.get(ctx).AddContacts(ctx, contacts, actor)
+}
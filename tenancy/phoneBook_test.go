@@ -0,0 +1,35 @@
+package tenancy
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"phoneBook/definition"
+	"testing"
+)
+
+// namedPhoneBook knows a single contact, named after the phone book.
+type namedPhoneBook struct {
+	definition.IPhoneBook
+	name string
+}
+
+func (pb *namedPhoneBook) GetContact(ctx context.Context, id string) (*definition.Contact, string, error) {
+	return &definition.Contact{FirstName: pb.name}, "", nil
+}
+
+func TestPhoneBook(t *testing.T) {
+	opened := 0
+	phoneBook := NewPhoneBook(&namedPhoneBook{name: "default"}, func(tenant string) definition.IPhoneBook {
+		opened++
+		return &namedPhoneBook{name: tenant}
+	})
+	ctx := context.Background()
+
+	contact, _, _ := phoneBook.GetContact(ctx, "1")
+	assert.Equal(t, "default", contact.FirstName)
+	for _, tenant := range []string{"acme", "globex", "acme"} {
+		contact, _, _ = phoneBook.GetContact(definition.WithTenant(ctx, tenant), "1")
+		assert.Equal(t, tenant, contact.FirstName)
+	}
+	assert.Equal(t, 2, opened)
+}
@@ -0,0 +1,9 @@
+package web
+
+import "embed"
+
+// UI holds the single page listing, searching, adding and editing the
+// contacts through the API, served at /.
+//
+//go:embed index.html
+var UI embed.FS